@@ -0,0 +1,264 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ast
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ApplyFunc is called by Apply for each node with a Cursor on it, its result controls the traversal.
+type ApplyFunc func(c *Cursor) bool
+
+// Apply traverses a copy of an AST value and returns it, calling pre for each node before its children and post
+// after them. The nodes are the non-zero values of node types held by the value, as Walk visits them: operator
+// tokens and the imports and comments of files are not traversed, the imports are recomputed.
+//
+// If pre returns false, the children of the node are skipped and post is not called for it. If post returns
+// false, the traversal stops. Nodes inserted by the cursor are not traversed, a replacement is, unless
+// it replaces a node in post.
+func Apply[T any](root T, pre, post ApplyFunc) (result T) {
+	holder := struct{ Root T }{Clone(root)}
+	a := applier{pre: pre, post: post}
+
+	defer func() {
+		if r := recover(); r != nil && r != errAbort {
+			panic(r)
+		}
+		result = holder.Root
+	}()
+	a.field(nil, "Root", reflect.ValueOf(&holder).Elem().Field(0))
+	return
+}
+
+var (
+	errAbort = new(int)
+
+	nodeType  = reflect.TypeOf((*Node)(nil)).Elem()
+	tokenType = reflect.TypeOf(Token{})
+	fileType  = reflect.TypeOf(File{})
+)
+
+func isNode(t reflect.Type) bool {
+	return t.Implements(nodeType) && t != posRangeType && t != tokenType
+}
+
+// Cursor describes a node during Apply and replaces, deletes or inserts nodes next to it.
+type Cursor struct {
+	parent  Node
+	name    string
+	index   int           // in list, -1 outside lists
+	list    reflect.Value // the settable slice holding the node
+	slot    reflect.Value // the settable value of the node, possibly an interface
+	deleted bool
+
+	iter *iterator
+}
+
+type iterator struct {
+	index, step int
+}
+
+// Node returns the current node.
+func (c *Cursor) Node() Node { return c.slot.Interface().(Node) }
+
+// Parent returns the node holding the current one, nil for the root.
+func (c *Cursor) Parent() Node { return c.parent }
+
+// Name returns the name of the field of the parent holding the current node, Value for the nodes held by the
+// Expr, Type, Stmt and Pattern wrappers.
+func (c *Cursor) Name() string { return c.name }
+
+// Index returns the index of the current node in the list holding it, -1 outside lists.
+func (c *Cursor) Index() int { return c.index }
+
+// Replace replaces the current node, a node without a valid position takes the range of the replaced one.
+// Replacing the node held by a wrapper keeps the kind of the wrapper, replace the wrapper to change it.
+func (c *Cursor) Replace(n Node) {
+	v := reflect.ValueOf(n)
+	if c.slot.Kind() != reflect.Interface && !v.Type().AssignableTo(c.slot.Type()) {
+		panic(fmt.Sprintf("ast.Apply: cannot replace %s with %T", c.slot.Type(), n))
+	}
+	if !n.GetPosRange().From.IsValid() && !n.GetPosRange().To.IsValid() {
+		v = withPos(v, c.Node().GetPosRange())
+	}
+	c.slot.Set(v)
+}
+
+// Delete deletes the current node from the list holding it.
+func (c *Cursor) Delete() {
+	c.checkList("Delete")
+	c.list.Set(reflect.AppendSlice(c.list.Slice(0, c.index), c.list.Slice(c.index+1, c.list.Len())))
+	c.iter.step--
+	c.deleted = true
+}
+
+// InsertBefore inserts n before the current node in the list holding it, a node without a valid position
+// takes an empty range at the start of the current one.
+func (c *Cursor) InsertBefore(n Node) {
+	c.checkList("InsertBefore")
+	pos := c.Node().GetPosRange()
+	c.insert(c.index, n, PosRange{From: pos.From, To: pos.From})
+	c.index++
+	c.iter.index++
+	c.slot = c.list.Index(c.index)
+	if c.slot.Kind() == reflect.Pointer {
+		c.slot = c.slot.Elem()
+	}
+}
+
+// InsertAfter inserts n after the current node in the list holding it, a node without a valid position
+// takes an empty range at the end of the current one.
+func (c *Cursor) InsertAfter(n Node) {
+	c.checkList("InsertAfter")
+	pos := c.Node().GetPosRange()
+	c.insert(c.index+1, n, PosRange{From: pos.To, To: pos.To})
+	c.iter.step++
+}
+
+func (c *Cursor) checkList(op string) {
+	if c.index < 0 || c.deleted {
+		panic(fmt.Sprintf("ast.Apply: %s of %s outside a list", op, c.name))
+	}
+}
+
+func (c *Cursor) insert(i int, n Node, pos PosRange) {
+	v := reflect.ValueOf(n)
+	if !n.GetPosRange().From.IsValid() && !n.GetPosRange().To.IsValid() {
+		v = withPos(v, pos)
+	}
+	list := reflect.Append(c.list, reflect.Zero(c.list.Type().Elem()))
+	reflect.Copy(list.Slice(i+1, list.Len()), list.Slice(i, list.Len()-1))
+	if elem := c.list.Type().Elem(); elem.Kind() == reflect.Pointer {
+		p := reflect.New(elem.Elem())
+		p.Elem().Set(v)
+		v = p
+	}
+	list.Index(i).Set(v)
+	c.list.Set(list)
+}
+
+// withPos returns a copy of the node v with the range pos, the node held by a wrapper is updated.
+func withPos(v reflect.Value, pos PosRange) reflect.Value {
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	if held := c.FieldByName("Value"); held.IsValid() && held.Kind() == reflect.Interface && !held.IsNil() {
+		held.Set(withPos(held.Elem(), pos))
+	} else if r := c.FieldByName("PosRange"); r.IsValid() && r.CanSet() {
+		r.Set(reflect.ValueOf(pos))
+	}
+	return c
+}
+
+type applier struct {
+	pre, post ApplyFunc
+	iter      iterator
+}
+
+// field traverses the nodes held by a settable field of parent.
+func (a *applier) field(parent Node, name string, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		if isNode(v.Type()) {
+			if !v.IsZero() {
+				a.apply(parent, name, -1, reflect.Value{}, v)
+			}
+			return
+		}
+		a.fields(parent, v)
+	case reflect.Pointer:
+		if !v.IsNil() {
+			a.field(parent, name, v.Elem())
+		}
+	case reflect.Interface:
+		if !v.IsNil() && isNode(v.Elem().Type()) {
+			a.apply(parent, name, -1, reflect.Value{}, v)
+		}
+	case reflect.Slice:
+		a.list(parent, name, v)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			a.field(parent, name, v.Index(i))
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, key := range keys {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			a.field(parent, name, elem)
+			v.SetMapIndex(key, elem)
+		}
+	}
+}
+
+// fields traverses the fields of a settable struct, parent being the node it is or belongs to.
+func (a *applier) fields(parent Node, v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		switch {
+		case !f.IsExported(), f.Type == posRangeType, f.Type == tokenType:
+		case v.Type() == fileType && (f.Name == "Imports" || f.Name == "Comments"):
+		default:
+			a.field(parent, f.Name, v.Field(i))
+		}
+	}
+	if v.Type() == fileType {
+		file := v.Addr().Interface().(*File)
+		file.Imports = Imports(file.Decls)
+	}
+}
+
+// list traverses the nodes of a settable slice, which the cursor may shrink or grow.
+func (a *applier) list(parent Node, name string, v reflect.Value) {
+	elem := v.Type().Elem()
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if !isNode(elem) && elem != tokenType {
+		for i := 0; i < v.Len(); i++ {
+			a.field(parent, name, v.Index(i))
+		}
+		return
+	}
+
+	saved := a.iter
+	for a.iter.index = 0; a.iter.index < v.Len(); a.iter.index += a.iter.step {
+		a.iter.step = 1
+		item := v.Index(a.iter.index)
+		if item.Kind() == reflect.Pointer {
+			if item.IsNil() {
+				continue
+			}
+			item = item.Elem()
+		}
+		a.apply(parent, name, a.iter.index, v, item)
+	}
+	a.iter = saved
+}
+
+// apply calls pre and post for the node in the settable slot and traverses its children in between.
+func (a *applier) apply(parent Node, name string, index int, list, slot reflect.Value) {
+	c := &Cursor{parent: parent, name: name, index: index, list: list, slot: slot, iter: &a.iter}
+	if a.pre != nil && !a.pre(c) || c.deleted {
+		return
+	}
+
+	node := c.Node()
+	if c.slot.Kind() == reflect.Interface {
+		// Values held by interfaces are not addressable, traverse a copy and store it back.
+		elem := reflect.New(c.slot.Elem().Type()).Elem()
+		elem.Set(c.slot.Elem())
+		a.fields(node, elem)
+		c.slot.Set(elem)
+	} else {
+		a.fields(node, c.slot)
+	}
+
+	if a.post != nil && !a.post(c) {
+		panic(errAbort)
+	}
+}
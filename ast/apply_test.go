@@ -0,0 +1,64 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ast
+
+import (
+	"cee"
+	"fmt"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	decl := func(name string, value Expr) Stmt {
+		return Stmt{Union: cee.Union[StmtKind]{Tag: StmtValDecl, Value: ValDecl{
+			Name:  Ident{Token: Token{Literal: name}},
+			Value: value,
+		}}}
+	}
+	stmts := []Stmt{decl("x", ident(10, "a")), decl("y", ident(20, "b")), decl("z", ident(30, "a"))}
+
+	out := Apply(stmts, func(c *Cursor) bool {
+		switch n := c.Node().(type) {
+		case Stmt:
+			// The statements of the list are the wrappers of the declarations.
+			switch n.Value.(ValDecl).Name.Literal {
+			case "y":
+				c.Delete()
+			case "z":
+				c.InsertBefore(decl("w", ident(25, "c")))
+			}
+		case Ident:
+			if n.Literal == "a" && c.Name() == "Value" {
+				c.Replace(Ident{Token: Token{Literal: "renamed"}})
+			}
+		}
+		return true
+	}, nil)
+
+	var names, values []string
+	for _, stmt := range out {
+		d := stmt.Value.(ValDecl)
+		names = append(names, d.Name.Literal)
+		values = append(values, d.Value.Value.(Ident).Literal)
+	}
+	if got := fmt.Sprint(names, values); got != "[x w z] [renamed c renamed]" {
+		t.Errorf("applied: %s", got)
+	}
+	if pos := out[0].Value.(ValDecl).Value.GetPosRange(); pos.From != 10 || pos.To != 11 {
+		t.Errorf("replacement at %v, want the range of the replaced identifier", pos)
+	}
+	if stmts[0].Value.(ValDecl).Value.Value.(Ident).Literal != "a" || len(stmts) != 3 {
+		t.Error("the original was modified")
+	}
+
+	visited := 0
+	Apply(stmts, nil, func(c *Cursor) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Errorf("%d nodes visited after post returned false", visited)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ast
+
+import "reflect"
+
+// Clone returns a deep copy of an AST value, sharing no slice, pointer or map with v.
+func Clone[T any](v T) T {
+	var c T
+	cloner{}.clone(reflect.ValueOf(&c).Elem(), reflect.ValueOf(v))
+	return c
+}
+
+// Rewrite returns a deep copy of an AST value in which every expression is replaced by f of its copy.
+// The children of an expression are rewritten before it.
+func Rewrite[T any](v T, f func(e Expr) Expr) T {
+	var c T
+	cloner{rewrite: f}.clone(reflect.ValueOf(&c).Elem(), reflect.ValueOf(v))
+	return c
+}
+
+var exprType = reflect.TypeOf(Expr{})
+
+type cloner struct {
+	rewrite func(e Expr) Expr
+}
+
+func (c cloner) clone(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Pointer:
+		if !src.IsNil() {
+			dst.Set(reflect.New(src.Type().Elem()))
+			c.clone(dst.Elem(), src.Elem())
+		}
+	case reflect.Interface:
+		if !src.IsNil() {
+			elem := reflect.New(src.Elem().Type()).Elem()
+			c.clone(elem, src.Elem())
+			dst.Set(elem)
+		}
+	case reflect.Struct:
+		dst.Set(src) // unexported fields
+		for i := 0; i < src.NumField(); i++ {
+			if src.Type().Field(i).IsExported() {
+				c.clone(dst.Field(i), src.Field(i))
+			}
+		}
+		if c.rewrite != nil && src.Type() == exprType {
+			dst.Set(reflect.ValueOf(c.rewrite(dst.Interface().(Expr))))
+		}
+	case reflect.Slice:
+		if !src.IsNil() {
+			dst.Set(reflect.MakeSlice(src.Type(), src.Len(), src.Len()))
+			for i := 0; i < src.Len(); i++ {
+				c.clone(dst.Index(i), src.Index(i))
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			c.clone(dst.Index(i), src.Index(i))
+		}
+	case reflect.Map:
+		if !src.IsNil() {
+			dst.Set(reflect.MakeMapWithSize(src.Type(), src.Len()))
+			for _, key := range src.MapKeys() {
+				elem := reflect.New(src.Type().Elem()).Elem()
+				c.clone(elem, src.MapIndex(key))
+				dst.SetMapIndex(key, elem)
+			}
+		}
+	default:
+		dst.Set(src)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ast
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ToDot writes the tree of an AST value as a Graphviz digraph. Structs become nodes labelled with their type
+// and scalar fields, edges are labelled with the field, or index, leading to the child. Unions are transparent
+// and positions are left out.
+func ToDot(w io.Writer, node any) error {
+	d := dotWriter{}
+	d.b.WriteString("digraph ast {\n\tnode [shape=box, fontname=monospace];\n")
+	d.node(reflect.ValueOf(node))
+	d.b.WriteString("}\n")
+	_, err := io.WriteString(w, d.b.String())
+	return err
+}
+
+type dotWriter struct {
+	b strings.Builder
+	n int
+}
+
+// node writes v and its children, it returns the id of the node, -1 for nil and empty values.
+func (d *dotWriter) node(v reflect.Value) int {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return -1
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct {
+		if union := v.FieldByName("Union"); union.IsValid() && v.Type().Field(0).Anonymous {
+			return d.node(union.FieldByName("Value"))
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			return -1
+		}
+		id := d.add(fmt.Sprintf("%s (%d)", v.Type(), v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			d.edge(id, d.node(v.Index(i)), strconv.Itoa(i))
+		}
+		return id
+	default:
+		return d.add(fmt.Sprint(v.Interface()))
+	}
+
+	type child struct {
+		name string
+		v    reflect.Value
+	}
+	var (
+		label    = []string{v.Type().String()}
+		children []child
+	)
+	var fields func(v reflect.Value)
+	fields = func(v reflect.Value) {
+		for i := 0; i < v.NumField(); i++ {
+			field, value := v.Type().Field(i), v.Field(i)
+			switch {
+			case !field.IsExported() || field.Type == posRangeType:
+			case field.Anonymous && value.Kind() == reflect.Struct:
+				fields(value)
+			case value.Kind() == reflect.String || value.Kind() == reflect.Bool || value.CanInt():
+				label = append(label, fmt.Sprintf("%s: %v", field.Name, value.Interface()))
+			default:
+				children = append(children, child{field.Name, value})
+			}
+		}
+	}
+	fields(v)
+
+	id := d.add(strings.Join(label, "\n"))
+	for _, c := range children {
+		d.edge(id, d.node(c.v), c.name)
+	}
+	return id
+}
+
+func (d *dotWriter) add(label string) int {
+	id := d.n
+	d.n++
+	fmt.Fprintf(&d.b, "\tn%d [label=%s];\n", id, dotQuote(label))
+	return id
+}
+
+func (d *dotWriter) edge(from, to int, label string) {
+	if to >= 0 {
+		fmt.Fprintf(&d.b, "\tn%d -> n%d [label=%s];\n", from, to, dotQuote(label))
+	}
+}
+
+// dotQuote quotes a label, newlines break its lines.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
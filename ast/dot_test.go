@@ -0,0 +1,34 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ast
+
+import (
+	"cee"
+	"strings"
+	"testing"
+)
+
+func TestToDot(t *testing.T) {
+	call := Expr{Union: cee.Union[ExprKind]{Tag: ExprCall, Value: CallExpr{Callee: ident(1, "f"), Params: []Expr{ident(3, "a")}}}}
+
+	var b strings.Builder
+	if err := ToDot(&b, call); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph ast {
+	node [shape=box, fontname=monospace];
+	n0 [label="ast.CallExpr"];
+	n1 [label="ast.Ident\nKind: 0\nLiteral: f"];
+	n0 -> n1 [label="Callee"];
+	n2 [label="[]ast.Expr (1)"];
+	n3 [label="ast.Ident\nKind: 0\nLiteral: a"];
+	n2 -> n3 [label="0"];
+	n0 -> n2 [label="Params"];
+}
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ast
+
+import (
+	"cee/schema"
+	"cee/token"
+	"encoding/gob"
+	"io"
+	"reflect"
+)
+
+func init() {
+	// Every concrete node that may be stored in a Union must be registered.
+	for _, node := range []any{
+		Token{}, Ident{}, LiteralValue{},
+
+		StructType{}, TraitType{}, TraitMethod{}, TypeAlias{}, FuncType{}, MapType{}, ChanType{}, PointerType{}, ArrayType{},
+
+		Expr{}, UnaryExpr{}, BinaryExpr{}, EllipsisExpr{}, CallExpr{}, IndexExpr{}, CastExpr{},
+		BranchExpr{}, MatchExpr{}, StmtBlockExpr{}, MemberSelectExpr{}, TryExpr{},
+		IntrinsicExpr{}, QualifiedIdent{}, GenericInstantiationExpr{}, TypeExpr{},
+		CompositeLit{}, KeyedElement{}, FuncLitExpr{}, InterpolatedString{}, BadExpr{},
+
+		Pattern{}, TuplePattern{}, StructPattern{}, LiteralPattern{}, MatchArm{},
+
+		ImportDecl{}, ValDecl{}, GenDecl{}, TypeDecl{}, TypeParam{}, FuncDecl{}, ExternDecl{}, MacroDecl{},
+		ReturnStmt{}, AssignStmt{}, BreakStmt{}, ContinueStmt{},
+		LoopStmt{}, ForeachStmt{}, EndlessForStmt{}, DeferStmt{},
+		SwitchStmt{}, SelectStmt{}, FallthroughStmt{}, GoStmt{}, SendStmt{}, GotoStmt{}, LabeledStmt{},
+		BadStmt{}, BadDecl{},
+	} {
+		gob.Register(node)
+	}
+}
+
+// Schema versions the binary encoding of AST values. Its version is bumped by every change to the nodes gob
+// does not decode compatibly, e.g. a field changing type or meaning, and a migration may be registered.
+var Schema = schema.New("cee-ast", 5)
+
+// Encode writes the binary encoding of an AST value, e.g. a node or a slice of declarations.
+func Encode(w io.Writer, v any) error {
+	if err := Schema.WriteHeader(w); err != nil {
+		return err
+	}
+	return gob.NewEncoder(w).Encode(v)
+}
+
+// Decode reads an AST value written by Encode into the value pointed to by v.
+// Encodings of another version of the schema are migrated, or reported as a *schema.VersionError.
+func Decode(r io.Reader, v any) error {
+	payload, err := Schema.Open(r)
+	if err != nil {
+		return err
+	}
+	return gob.NewDecoder(payload).Decode(v)
+}
+
+var posType = reflect.TypeOf(token.NoPos)
+
+// Shift moves every valid position in the AST value pointed to by v by delta,
+// e.g. to rebase decoded declarations onto the file they are reused for.
+func Shift(v any, delta token.Pos) {
+	shift(reflect.ValueOf(v).Elem(), delta)
+}
+
+func shift(v reflect.Value, delta token.Pos) {
+	if v.Type() == posType {
+		if pos := token.Pos(v.Int()); pos.IsValid() {
+			v.SetInt(int64(pos + delta))
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			shift(v.Elem(), delta)
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		// Values held by interfaces are not addressable, shift a copy and store it back.
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		shift(elem, delta)
+		v.Set(elem)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				shift(v.Field(i), delta)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			shift(v.Index(i), delta)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ast
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Diff compares two AST values ignoring their positions, it returns the path to the first difference
+// such as `[2].Value.(ast.FuncDecl).Ident.Literal`, or "" when they are equal.
+func Diff(a, b any) string {
+	path, ok := diff(reflect.ValueOf(a), reflect.ValueOf(b), "")
+	if ok {
+		return ""
+	}
+	if path == "" {
+		return "."
+	}
+	return path
+}
+
+func diff(a, b reflect.Value, path string) (string, bool) {
+	if !a.IsValid() || !b.IsValid() {
+		return path, a.IsValid() == b.IsValid()
+	}
+	if a.Type() != b.Type() {
+		return path, false
+	}
+	if a.Type() == posRangeType {
+		return "", true
+	}
+
+	switch a.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return path, a.IsNil() == b.IsNil()
+		}
+		if a.Kind() == reflect.Interface {
+			if a.Elem().Type() != b.Elem().Type() {
+				return path, false
+			}
+			path = fmt.Sprintf("%s.(%s)", path, a.Elem().Type())
+		}
+		return diff(a.Elem(), b.Elem(), path)
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			field := a.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			// Fields of embedded structs are promoted, as in selectors.
+			name := path + "." + field.Name
+			if field.Anonymous {
+				name = path
+			}
+			if p, ok := diff(a.Field(i), b.Field(i), name); !ok {
+				return p, false
+			}
+		}
+		return "", true
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return path, false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if p, ok := diff(a.Index(i), b.Index(i), fmt.Sprintf("%s[%d]", path, i)); !ok {
+				return p, false
+			}
+		}
+		return "", true
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return path, false
+		}
+		for _, key := range a.MapKeys() {
+			if p, ok := diff(a.MapIndex(key), b.MapIndex(key), fmt.Sprintf("%s[%v]", path, key)); !ok {
+				return p, false
+			}
+		}
+		return "", true
+	}
+	return path, a.Interface() == b.Interface()
+}
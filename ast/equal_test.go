@@ -0,0 +1,36 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ast
+
+import (
+	"cee"
+	"cee/token"
+	"testing"
+)
+
+func ident(from int, lit string) Expr {
+	pos := PosRange{From: token.Pos(from), To: token.Pos(from + len(lit))}
+	return Expr{Union: cee.Union[ExprKind]{Tag: ExprIdent, Value: Ident{Token: Token{PosRange: pos, Literal: lit}}}}
+}
+
+func TestDiff(t *testing.T) {
+	call := func(from int, params ...string) []Expr {
+		var exprs []Expr
+		for i, lit := range params {
+			exprs = append(exprs, ident(from+i*4, lit))
+		}
+		return []Expr{{Union: cee.Union[ExprKind]{Tag: ExprCall, Value: CallExpr{Callee: ident(from, "f"), Params: exprs}}}}
+	}
+
+	if d := Diff(call(1, "a", "b"), call(100, "a", "b")); d != "" {
+		t.Errorf("positions are compared: %s", d)
+	}
+	if d, want := Diff(call(1, "a", "b"), call(1, "a", "c")), "[0].Value.(ast.CallExpr).Params[1].Value.(ast.Ident).Literal"; d != want {
+		t.Errorf("Diff = %q, want %q", d, want)
+	}
+	if d := Diff(call(1, "a"), call(1, "a", "b")); d != "[0].Value.(ast.CallExpr).Params" {
+		t.Errorf("Diff = %q", d)
+	}
+}
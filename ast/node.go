@@ -6,7 +6,7 @@ package ast
 
 import (
 	"cee"
-	"github.com/langvm/go-cee-scanner"
+	"cee/token"
 )
 
 const (
@@ -17,18 +17,67 @@ type Node interface {
 	GetPosRange() PosRange
 }
 
+// PosRange spans [From, To) in compact positions, decoded through the token.FileSet of the file.
 type PosRange struct {
-	From, To scanner.Position
+	From, To token.Pos
 }
 
 func (pos PosRange) GetPosRange() PosRange { return pos }
 
+// Len returns the number of runes spanned, positions of a file are rune offsets shifted by its base.
+func (pos PosRange) Len() int { return int(pos.To - pos.From) }
+
+// Contains reports whether p lies in [From, To).
+func (pos PosRange) Contains(p token.Pos) bool { return pos.From <= p && p < pos.To }
+
+// Overlaps reports whether the ranges share a position, empty ranges overlap nothing.
+func (pos PosRange) Overlaps(other PosRange) bool {
+	return pos.From < pos.To && other.From < other.To && pos.From < other.To && other.From < pos.To
+}
+
+// Union returns the smallest range covering both, a range of NoPos ends is ignored.
+func (pos PosRange) Union(other PosRange) PosRange {
+	switch {
+	case !pos.From.IsValid() && !pos.To.IsValid():
+		return other
+	case !other.From.IsValid() && !other.To.IsValid():
+		return pos
+	}
+	return PosRange{From: min(pos.From, other.From), To: max(pos.To, other.To)}
+}
+
+// Offsets returns the rune offsets of the range within its file.
+func (pos PosRange) Offsets(f *token.File) (from, to int) {
+	return f.Offset(pos.From), f.Offset(pos.To)
+}
+
 type Token struct {
 	PosRange
 	Kind    int
 	Literal string
 }
 
+// CompactToken is a Token without its literal, which is materialized on demand from the content
+// the file of the token retains. It saves the literal allocation of every scanned token.
+type CompactToken struct {
+	PosRange
+	Kind int
+}
+
+func Compact(tok Token) CompactToken { return CompactToken{PosRange: tok.PosRange, Kind: tok.Kind} }
+
+// Literal returns the source text of the token, tokens whose literal is implied by the kind need no content.
+func (tok CompactToken) Literal(fset *token.FileSet) string {
+	if token.IsKeyword(tok.Kind) && token.KeywordLiterals[tok.Kind] != "" {
+		return token.KeywordLiterals[tok.Kind]
+	}
+	return fset.Text(tok.From, tok.To)
+}
+
+func (tok CompactToken) Token(fset *token.FileSet) Token {
+	return Token{PosRange: tok.PosRange, Kind: tok.Kind, Literal: tok.Literal(fset)}
+}
+
 type List[T any] struct {
 	PosRange
 	List []T
@@ -42,6 +91,8 @@ const (
 	TypeNone
 	TypeStruct
 	TypeTrait
+	TypeIdent
+	TypeFunc
 
 	TypeI8 // builtin
 	TypeI16
@@ -51,6 +102,12 @@ const (
 	TypeU16
 	TypeU32
 	TypeU64
+
+	TypeInstance // a generic type instantiated, held as a GenericInstantiationExpr
+	TypeMap
+	TypeChan
+	TypePointer
+	TypeArray
 )
 
 type Type struct {
@@ -63,9 +120,19 @@ type (
 		Fields []GenDecl
 	}
 
+	// TraitType is `trait { methods }`, the method signatures of the trait and the traits it embeds,
+	// whose methods it has too.
 	TraitType struct {
 		PosRange
-		// TODO
+		Methods []TraitMethod
+		Embeds  []TypeAlias
+	}
+
+	// TraitMethod is the signature `name(params) results` of a method of a trait.
+	TraitMethod struct {
+		PosRange
+		Ident Ident
+		Type  FuncType
 	}
 
 	TypeAlias struct {
@@ -77,6 +144,42 @@ type (
 		Params  []GenDecl
 		Results []Type
 	}
+
+	// MapType is `map[Key]Value`.
+	MapType struct {
+		PosRange
+		Key   Type
+		Value Type
+	}
+
+	// ArrayType is `[Len]Elem`, or the slice `[]Elem` when Len is zero.
+	ArrayType struct {
+		PosRange
+		Len  Expr
+		Elem Type
+	}
+
+	// PointerType is `*Elem`, a reference to a value of type Elem.
+	PointerType struct {
+		PosRange
+		Elem Type
+	}
+
+	// ChanType is `chan Elem`, `chan<- Elem` or `<-chan Elem`.
+	ChanType struct {
+		PosRange
+		Dir  ChanDir
+		Elem Type
+	}
+)
+
+// ChanDir is the direction of a channel type, the operations its values allow.
+type ChanDir byte
+
+const (
+	ChanBoth ChanDir = iota // chan T
+	ChanSend                // chan<- T
+	ChanRecv                // <-chan T
 )
 
 type ExprKind int
@@ -88,6 +191,23 @@ const (
 	ExprLiteralValue
 	ExprUnary
 	ExprBinary
+	ExprEllipsis
+	ExprCall
+	ExprIndex
+	ExprCast
+	ExprBranch
+	ExprMatch
+	ExprStmtBlock
+	ExprMemberSelect
+	ExprTry
+	ExprIntrinsic
+	ExprQualifiedIdent
+	ExprGenericInstantiation
+	ExprType
+	ExprCompositeLit
+	ExprFuncLit
+	ExprInterpolatedString
+	ExprBad
 )
 
 type Expr struct {
@@ -143,10 +263,20 @@ type (
 		ElseBranch StmtBlockExpr
 	}
 
+	// MatchExpr runs the first arm whose pattern matches the value of Subject and whose guard holds.
 	MatchExpr struct {
 		PosRange
-		Subject  Expr
-		Patterns []StmtBlockExpr
+		Subject Expr
+		Arms    []MatchArm
+	}
+
+	// MatchArm is `case pattern [if guard]:` followed by its statements, up to the next arm. The names bound
+	// by the pattern are visible from the guard and the body.
+	MatchArm struct {
+		PosRange
+		Pattern Pattern
+		Guard   Expr // zero without guard
+		Body    StmtBlockExpr
 	}
 
 	StmtBlockExpr struct {
@@ -160,15 +290,263 @@ type (
 		Member Ident
 		Expr   Expr
 	}
+
+	// TryExpr is `try expr` or `expr?`, it yields the value of expr or returns its error
+	// from the enclosing function.
+	TryExpr struct {
+		PosRange
+		Expr Expr
+	}
+
+	// QualifiedIdent is `pkg.Member`, a member of an imported package. The parser reads it as a selection,
+	// resolve.Qualify tells it apart from the selection of a field.
+	QualifiedIdent struct {
+		PosRange
+		Package Ident
+		Member  Ident
+	}
+
+	// GenericInstantiationExpr is `f[T, U]`, a generic function or type instantiated with type arguments.
+	// It is also the value of instantiated types.
+	GenericInstantiationExpr struct {
+		PosRange
+		Expr     Expr
+		TypeArgs []Type
+	}
+
+	// IntrinsicExpr is `@namespace.name(params)`, an operation implemented by the backend.
+	IntrinsicExpr struct {
+		PosRange
+		Namespace Ident
+		Name      Ident
+		Params    []Expr
+	}
+
+	// CompositeLit is `Type{elems}`, e.g. `Point{x: 1, y: 2}` or `[3]i64{1, 2, 3}`. The type of a literal nested
+	// in another is elided, as in `[]Point{{x: 1}, {x: 2}}`, and is then zero.
+	CompositeLit struct {
+		PosRange
+		Type  Type
+		Elems []KeyedElement
+	}
+
+	// KeyedElement is `key: value` or a positional `value` of a composite literal, a field name keys a struct.
+	KeyedElement struct {
+		PosRange
+		Key   Expr // zero for positional elements
+		Value Expr
+	}
+
+	// FuncLitExpr is the closure `fun [captures](params) results { body }`. Without a capture list it captures
+	// the variables of the enclosing functions its body uses, resolve records them in Info.Captures.
+	FuncLitExpr struct {
+		PosRange
+		Captures []Capture
+		Type     FuncType
+		Body     StmtBlockExpr
+	}
+
+	// InterpolatedString is `"text${expr}text"`. Its literals alternate with its expressions, starting and ending
+	// the string, so there is one more of them. Each is the quoted string of the text around the expressions,
+	// possibly empty, positioned at the segment of source holding it.
+	InterpolatedString struct {
+		PosRange
+		Lits  []LiteralValue
+		Exprs []Expr
+	}
+
+	// TypeExpr is a type in operand position, e.g. the argument of `make(map[K]V)`.
+	TypeExpr struct {
+		PosRange
+		Type Type
+	}
+
+	// BadExpr stands in for source that could not be parsed as an expression, spanning the skipped tokens.
+	BadExpr struct {
+		PosRange
+	}
+)
+
+type PatternKind int
+
+const (
+	_ = iota
+
+	PatternIdent
+	PatternTuple
+	PatternStruct
+	PatternLiteral
 )
 
+// Pattern destructures a value, in bindings and match arms. An identifier pattern binds the whole value,
+// `_` discards it, a literal pattern matches an equal value.
+type Pattern struct {
+	cee.Union[PatternKind]
+}
+
+func (p Pattern) GetPosRange() PosRange { return p.Value.(Node).GetPosRange() }
+
+type (
+	// TuplePattern matches `(a, b)` element by element.
+	TuplePattern struct {
+		PosRange
+		Elems []Pattern
+	}
+
+	// StructPattern matches `T{x, y: (a, b)}` field by field, the type is optional.
+	StructPattern struct {
+		PosRange
+		Type   *Ident
+		Fields []FieldPattern
+	}
+
+	// LiteralPattern matches a value equal to the literal, negated by a leading `-` for numbers.
+	LiteralPattern struct {
+		PosRange
+		Value    LiteralValue
+		Negative bool
+	}
+
+	// FieldPattern matches a field, the shorthand `x` without a pattern binds the field to its name.
+	FieldPattern struct {
+		PosRange
+		Field   Ident
+		Pattern *Pattern
+	}
+)
+
+// Idents returns the identifiers the pattern binds in source order, `_` excluded.
+func (p Pattern) Idents() []Ident {
+	var idents []Ident
+	p.idents(&idents)
+	return idents
+}
+
+// Refutable returns the first literal pattern within the pattern, which makes it fail to match other values.
+func (p Pattern) Refutable() (LiteralPattern, bool) {
+	switch v := p.Value.(type) {
+	case LiteralPattern:
+		return v, true
+	case TuplePattern:
+		for _, elem := range v.Elems {
+			if lit, ok := elem.Refutable(); ok {
+				return lit, true
+			}
+		}
+	case StructPattern:
+		for _, field := range v.Fields {
+			if field.Pattern != nil {
+				if lit, ok := field.Pattern.Refutable(); ok {
+					return lit, true
+				}
+			}
+		}
+	}
+	return LiteralPattern{}, false
+}
+
+func (p Pattern) idents(idents *[]Ident) {
+	switch v := p.Value.(type) {
+	case Ident:
+		if v.Literal != "_" {
+			*idents = append(*idents, v)
+		}
+	case TuplePattern:
+		for _, elem := range v.Elems {
+			elem.idents(idents)
+		}
+	case StructPattern:
+		for _, field := range v.Fields {
+			if field.Pattern != nil {
+				field.Pattern.idents(idents)
+			} else {
+				Pattern{Union: cee.Union[PatternKind]{Tag: PatternIdent, Value: field.Field}}.idents(idents)
+			}
+		}
+	}
+}
+
 type StmtKind byte
 
 const (
 	_ = iota
+
+	StmtExpr
+	StmtImportDecl
+	StmtValDecl
+	StmtGenDecl
+	StmtTypeDecl
+	StmtFuncDecl
+	StmtExternDecl
+	StmtReturn
+	StmtAssign
+	StmtBreak
+	StmtContinue
+	StmtLoop
+	StmtForeach
+	StmtEndlessFor
+	StmtDefer
+	StmtMacroDecl
+	StmtSwitch
+	StmtSelect
+	StmtGo
+	StmtSend
+	StmtGoto
+	StmtLabeled
+	StmtFallthrough
+	StmtBad
+	StmtBadDecl
 )
 
+// Stmt is a statement or a declaration, declarations may carry attributes.
+// Pub top-level declarations are accessible from other packages.
 type Stmt struct {
+	cee.Union[StmtKind]
+	Attrs []Attribute
+	Pub   bool
+}
+
+// Attribute is `@name` or `@name(args)` preceding a declaration, where args are `key = value`
+// or positional values.
+type Attribute struct {
+	PosRange
+	Name Ident
+	Args []AttributeArg
+}
+
+type AttributeArg struct {
+	PosRange
+	Key   Ident // zero for positional arguments
+	Value LiteralValue
+}
+
+// Attr returns the first attribute of the declaration with the name.
+func (s Stmt) Attr(name string) (Attribute, bool) {
+	for _, attr := range s.Attrs {
+		if attr.Name.Literal == name {
+			return attr, true
+		}
+	}
+	return Attribute{}, false
+}
+
+func (t Type) GetPosRange() PosRange { return t.Value.(Node).GetPosRange() }
+
+func (e Expr) GetPosRange() PosRange { return e.Value.(Node).GetPosRange() }
+
+func (s Stmt) GetPosRange() PosRange { return s.Value.(Node).GetPosRange() }
+
+// ElseIf returns the branch of `else if`, which the parser holds alone in an else block spanning just the branch.
+// A branch alone in an else block written with braces is not an else-if.
+func (e BranchExpr) ElseIf() (BranchExpr, bool) {
+	if stmts := e.ElseBranch.Stmts; len(stmts) == 1 {
+		if nested, ok := stmts[0].Value.(Expr); ok {
+			if branch, ok := nested.Value.(BranchExpr); ok && branch.PosRange == e.ElseBranch.PosRange {
+				return branch, true
+			}
+		}
+	}
+	return BranchExpr{}, false
 }
 
 type (
@@ -178,10 +556,17 @@ type (
 		Alias         *Ident
 	}
 
+	// ValDecl binds Name, or the names of Pattern when it destructures the value.
+	// Bindings declared with var are Mutable, those declared with const take a constant value.
+	// Type is the zero Type when the type of the binding is inferred from Value.
 	ValDecl struct {
 		PosRange
-		Name  Ident
-		Value Expr
+		Mutable bool
+		Const   bool
+		Name    Ident
+		Pattern *Pattern
+		Type    Type
+		Value   Expr
 	}
 
 	GenDecl struct {
@@ -190,11 +575,53 @@ type (
 		Type   Type
 	}
 
+	TypeDecl struct {
+		PosRange
+		Ident      Ident
+		TypeParams []TypeParam
+		Type       Type
+	}
+
+	// TypeParam declares the type parameters Idents of a generic function or type, satisfying the
+	// Constraint, a trait. The constraint is zero when any type is accepted.
+	TypeParam struct {
+		PosRange
+		Idents     []Ident
+		Constraint Type
+	}
+
+	// FuncDecl is a named function or a closure, only closures list their Captures.
 	FuncDecl struct {
 		PosRange
+		Captures   []Capture
+		TypeParams []TypeParam
+		Type       FuncType
+		Ident      *Ident
+		Stmt       *StmtBlockExpr
+	}
+
+	// MacroDecl is `macro name(params) { body }`, the body is kept as tokens, newlines included,
+	// and parsed after expansion.
+	MacroDecl struct {
+		PosRange
+		Ident  Ident
+		Params []Ident
+		Body   []Token
+	}
+
+	// Capture is `x`, capturing the variable by value when the closure is created, or `&x` by reference.
+	Capture struct {
+		PosRange
+		Ident Ident
+		ByRef bool
+	}
+
+	// ExternDecl declares a function implemented natively and called through the given ABI.
+	ExternDecl struct {
+		PosRange
+		ABI   LiteralValue // "C" when omitted
+		Ident Ident
 		Type  FuncType
-		Ident *Ident
-		Stmt  *StmtBlockExpr
 	}
 
 	ReturnStmt struct {
@@ -204,15 +631,20 @@ type (
 
 	AssignStmt struct {
 		PosRange
+		Operator     Token // ASSIGN or one of the compound *_ASSIGN operators
 		ExprL, ExprR Expr
 	}
 
+	// BreakStmt leaves the innermost loop, switch or select, or the enclosing one labeled Label.
 	BreakStmt struct {
 		PosRange
+		Label *Ident
 	}
 
+	// ContinueStmt starts the next iteration of the innermost loop, or of the enclosing one labeled Label.
 	ContinueStmt struct {
 		PosRange
+		Label *Ident
 	}
 
 	LoopStmt struct {
@@ -225,9 +657,99 @@ type (
 		PosRange
 		IdentList []Ident
 		Expr      Expr
+		Stmt      StmtBlockExpr
 	}
 
 	EndlessForStmt struct {
+		PosRange
 		Stmt StmtBlockExpr
 	}
+
+	// DeferStmt runs Expr when the enclosing function exits. With OnError, declared by `errdefer`,
+	// it only runs when the function returns a non-nil error or panics.
+	DeferStmt struct {
+		PosRange
+		OnError bool
+		Expr    Expr
+	}
+
+	// SwitchStmt runs the first clause with an expression equal to Tag, or whose expression is true
+	// without a Tag. The default clause runs when no other matches, wherever it is placed.
+	SwitchStmt struct {
+		PosRange
+		Tag   Expr // zero without tag
+		Cases []CaseClause
+	}
+
+	// CaseClause is `case exprs:` or `default:` followed by its statements, up to the next clause.
+	CaseClause struct {
+		PosRange
+		Default bool
+		Exprs   []Expr
+		Stmts   []Stmt
+	}
+
+	// SelectStmt runs the clause of the first communication ready, or the default clause when none is.
+	SelectStmt struct {
+		PosRange
+		Cases []CommClause
+	}
+
+	// CommClause is `case comm:` or `default:` followed by its statements, comm being a send, or a receive
+	// as an expression, an assignment or a val declaration receiving the value.
+	CommClause struct {
+		PosRange
+		Default bool
+		Comm    Stmt // zero for default
+		Stmts   []Stmt
+	}
+
+	// FallthroughStmt ends a case clause, continuing with the statements of the next one.
+	FallthroughStmt struct {
+		PosRange
+	}
+
+	// BadStmt stands in for source that could not be parsed as a statement, spanning the skipped tokens.
+	BadStmt struct {
+		PosRange
+	}
+
+	// BadDecl stands in for source that could not be parsed as a top-level declaration.
+	BadDecl struct {
+		PosRange
+	}
+
+	// GoStmt runs the call Expr concurrently.
+	GoStmt struct {
+		PosRange
+		Expr Expr
+	}
+
+	// SendStmt is `ch <- value`, sending Value on the channel Chan.
+	SendStmt struct {
+		PosRange
+		Chan  Expr
+		Value Expr
+	}
+
+	GotoStmt struct {
+		PosRange
+		Label Ident
+	}
+
+	// LabeledStmt is `label: stmt`, the target of goto, and of break and continue when Stmt is a loop.
+	// Stmt is zero for a label ending a block.
+	LabeledStmt struct {
+		PosRange
+		Label Ident
+		Stmt  Stmt
+	}
 )
+
+// Idents returns the identifiers the declaration binds.
+func (d ValDecl) Idents() []Ident {
+	if d.Pattern != nil {
+		return d.Pattern.Idents()
+	}
+	return []Ident{d.Name}
+}
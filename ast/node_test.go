@@ -0,0 +1,33 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ast
+
+import (
+	"cee/token"
+	"testing"
+)
+
+func TestPosRange(t *testing.T) {
+	a := PosRange{From: 10, To: 20}
+	b := PosRange{From: 20, To: 25}
+
+	if a.Len() != 10 {
+		t.Errorf("Len = %d, want 10", a.Len())
+	}
+	for p, want := range map[token.Pos]bool{9: false, 10: true, 19: true, 20: false} {
+		if a.Contains(p) != want {
+			t.Errorf("Contains(%d) = %v, want %v", p, !want, want)
+		}
+	}
+	if a.Overlaps(b) || !a.Overlaps(PosRange{From: 19, To: 21}) || a.Overlaps(PosRange{From: 15, To: 15}) {
+		t.Error("Overlaps disagrees with half-open ranges")
+	}
+	if u := a.Union(b); u != (PosRange{From: 10, To: 25}) {
+		t.Errorf("Union = %v", u)
+	}
+	if u := (PosRange{}).Union(b); u != b {
+		t.Errorf("Union with a synthesized range = %v, want %v", u, b)
+	}
+}
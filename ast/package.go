@@ -0,0 +1,39 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ast
+
+// File is the syntax of a source file, spanning its whole content.
+type File struct {
+	PosRange
+	Name     string       // path of the file
+	Package  *Ident       // the package clause, nil when the file has none
+	Imports  []ImportDecl // the import declarations among Decls
+	Decls    []Stmt
+	Comments []Token
+}
+
+// Package is the syntax of the source files of a directory.
+type Package struct {
+	Name  string
+	Files map[string]*File // by path
+
+	// Scope is the package scope of the resolved package, a *resolve.Scope, nil before resolution.
+	// It is untyped as the resolver depends on the syntax.
+	Scope any
+}
+
+// GetPosRange returns an empty range, the files of a package span ranges of their own.
+func (p *Package) GetPosRange() PosRange { return PosRange{} }
+
+// Imports returns the import declarations among decls.
+func Imports(decls []Stmt) []ImportDecl {
+	var imports []ImportDecl
+	for _, decl := range decls {
+		if d, ok := decl.Value.(ImportDecl); ok {
+			imports = append(imports, d)
+		}
+	}
+	return imports
+}
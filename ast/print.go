@@ -4,39 +4,127 @@
 
 package ast
 
-import . "cee/internal"
+import (
+	. "cee/internal"
+	"cee/token"
+	"io"
+)
+
+// Printer is implemented by nodes printing themselves as source, blocks indented a level deeper.
+type Printer interface {
+	Print(b *StringBuffer)
+}
+
+// Fprint prints a node as source to w.
+func Fprint(w io.Writer, node Printer) error {
+	b := NewStringBuffer(w)
+	node.Print(b)
+	return b.Err()
+}
+
+func printNode(b *StringBuffer, v any) {
+	if p, ok := v.(Printer); ok {
+		p.Print(b)
+		return
+	}
+	b.Printf("/* %T */", v)
+}
+
+func printList[T Printer](b *StringBuffer, list []T) {
+	for i, v := range list {
+		if i != 0 {
+			b.Print(", ")
+		}
+		v.Print(b)
+	}
+}
 
 func (t Token) Print(b *StringBuffer) {
 	b.Print(t.Literal)
 }
 
+func (t Type) Print(b *StringBuffer) {
+	switch v := t.Value.(type) {
+	case StructType:
+		v.Print(b)
+	case TraitType:
+		v.Print(b)
+	case GenericInstantiationExpr:
+		v.Print(b)
+	default:
+		b.Print(TypeString(t))
+	}
+}
+
+func (e Expr) Print(b *StringBuffer) { printNode(b, e.Value) }
+
+// Print prints the statement on lines of its own, after the notes of the buffer positioned before it.
+func (s Stmt) Print(b *StringBuffer) {
+	from := s.GetPosRange().From
+	if len(s.Attrs) != 0 {
+		from = s.Attrs[0].From
+	}
+	b.Flush(int(from))
+	for _, attr := range s.Attrs {
+		attr.Print(b)
+		b.Println()
+	}
+	if s.Pub {
+		b.Print("pub ")
+	}
+	printNode(b, s.Value)
+	b.Println()
+}
+
 func (t StructType) Print(b *StringBuffer) {
 	b.Println("struct {")
+	b.Indent()
 	for _, field := range t.Fields {
 		field.Print(b)
+		b.Println()
 	}
-	b.Println("}")
+	b.Dedent()
+	b.Print("}")
 }
 
 func (t TraitType) Print(b *StringBuffer) {
+	if len(t.Embeds) == 0 && len(t.Methods) == 0 {
+		b.Print("trait {}")
+		return
+	}
 	b.Println("trait {")
-	// TODO
-	b.Println("}")
+	b.Indent()
+	for _, embed := range t.Embeds {
+		embed.Print(b)
+		b.Println()
+	}
+	for _, method := range t.Methods {
+		method.Print(b)
+		b.Println()
+	}
+	b.Dedent()
+	b.Print("}")
+}
+
+func (m TraitMethod) Print(b *StringBuffer) {
+	m.Ident.Print(b)
+	m.Type.Print(b)
 }
 
 func (t FuncType) Print(b *StringBuffer) {
-	b.Println("(")
-	for _, param := range t.Params {
-		for _, ident := range param.Idents {
-			b.Println(ident.Literal, ",")
-		}
-		param.Type.Print(b)
-	}
-	b.Println(")(")
-	for _, result := range t.Results {
-		result.Print(b)
+	b.Print("(")
+	printList(b, t.Params)
+	b.Print(")")
+	switch len(t.Results) {
+	case 0:
+	case 1:
+		b.Print(" ")
+		t.Results[0].Print(b)
+	default:
+		b.Print(" (")
+		printList(b, t.Results)
+		b.Print(")")
 	}
-	b.Println(")")
 }
 
 func (e LiteralValue) Print(b *StringBuffer) {
@@ -47,61 +135,535 @@ func (i Ident) Print(b *StringBuffer) {
 	i.Token.Print(b)
 }
 
+// printOperand prints an operand of an operator binding with prec, parenthesized when it binds looser.
+// Left-associative operators parenthesize right operands of the same precedence, which bind looser.
+func printOperand(b *StringBuffer, e Expr, prec int) {
+	var inner int
+	switch v := e.Value.(type) {
+	case BinaryExpr:
+		inner = token.Precedences[v.Operator.Kind]
+	case UnaryExpr, TryExpr:
+		// Prefix operators bind tighter than any binary operator.
+		inner = unaryPrec
+	default:
+		e.Print(b)
+		return
+	}
+	if inner < prec {
+		b.Print("(")
+		e.Print(b)
+		b.Print(")")
+		return
+	}
+	e.Print(b)
+}
+
+// unaryPrec binds tighter than the binary operators and looser than the postfix ones.
+const unaryPrec = 6
+
+// postfixPrec is the precedence of calls, indexing, selections and postfix operators.
+const postfixPrec = 7
+
 func (e UnaryExpr) Print(b *StringBuffer) {
+	if token.PostfixUnaryOperators[e.Operator.Kind] {
+		printOperand(b, e.Expr, postfixPrec)
+		e.Operator.Print(b)
+		return
+	}
 	e.Operator.Print(b)
-	e.Expr.Print(b)
+	if inner, ok := e.Expr.Value.(UnaryExpr); ok && token.Merges(e.Operator.Kind, inner.Operator.Kind) {
+		// `- -1` is not `--1`.
+		b.Print(" ")
+	}
+	printOperand(b, e.Expr, unaryPrec)
 }
 
 func (e BinaryExpr) Print(b *StringBuffer) {
-	e.Exprs[0].Print(b)
+	prec := token.Precedences[e.Operator.Kind]
+	printOperand(b, e.Exprs[0], prec)
+	b.Print(" ")
 	e.Operator.Print(b)
-	e.Exprs[1].Print(b)
+	b.Print(" ")
+	printOperand(b, e.Exprs[1], prec+1)
+}
+
+func (e EllipsisExpr) Print(b *StringBuffer) {
+	printOperand(b, e.Array, postfixPrec)
+	b.Print("...")
 }
 
 func (e CallExpr) Print(b *StringBuffer) {
-	e.Callee.Print(b)
-	b.Println("(")
-	for _, param := range e.Params {
-		param.Print(b)
-		b.Println(",")
-	}
-	b.Println(")")
+	printOperand(b, e.Callee, postfixPrec)
+	b.Print("(")
+	printList(b, e.Params)
+	b.Print(")")
 }
 
 func (e IndexExpr) Print(b *StringBuffer) {
-	e.Expr.Print(b)
+	printOperand(b, e.Expr, postfixPrec)
 	b.Print("[")
 	e.Index.Print(b)
 	b.Print("]")
 }
 
 func (e MemberSelectExpr) Print(b *StringBuffer) {
+	printOperand(b, e.Expr, postfixPrec)
+	b.Print(".")
+	e.Member.Print(b)
+}
+
+func (e TypeExpr) Print(b *StringBuffer) { e.Type.Print(b) }
+
+func (e CompositeLit) Print(b *StringBuffer) {
+	if e.Type.Value != nil {
+		e.Type.Print(b)
+	}
+	b.Print("{")
+	printList(b, e.Elems)
+	b.Print("}")
+}
+
+func (e InterpolatedString) Print(b *StringBuffer) {
+	b.Print(`"`)
+	for i, lit := range e.Lits {
+		b.Print(lit.Literal[1 : len(lit.Literal)-1])
+		if i < len(e.Exprs) {
+			b.Print("${")
+			e.Exprs[i].Print(b)
+			b.Print("}")
+		}
+	}
+	b.Print(`"`)
+}
+
+func (e FuncLitExpr) Print(b *StringBuffer) {
+	b.Print("fun ")
+	if len(e.Captures) != 0 {
+		b.Print("[")
+		printList(b, e.Captures)
+		b.Print("]")
+	}
+	e.Type.Print(b)
+	b.Print(" ")
+	e.Body.Print(b)
+}
+
+func (e KeyedElement) Print(b *StringBuffer) {
+	if e.Key.Value != nil {
+		e.Key.Print(b)
+		b.Print(": ")
+	}
+	e.Value.Print(b)
+}
+
+func (e GenericInstantiationExpr) Print(b *StringBuffer) {
+	printOperand(b, e.Expr, postfixPrec)
+	b.Print("[")
+	printList(b, e.TypeArgs)
+	b.Print("]")
+}
+
+func (e BranchExpr) Print(b *StringBuffer) {
+	b.Print("if ")
+	e.Cond.Print(b)
+	b.Print(" ")
+	e.Branch.Print(b)
+	if len(e.ElseBranch.Stmts) == 0 && !e.ElseBranch.From.IsValid() {
+		return
+	}
+	b.Print(" else ")
+	if branch, ok := e.ElseIf(); ok {
+		branch.Print(b)
+		return
+	}
+	e.ElseBranch.Print(b)
+}
+
+func (e TryExpr) Print(b *StringBuffer) {
+	b.Print("try ")
 	e.Expr.Print(b)
+}
+
+func (e IntrinsicExpr) Print(b *StringBuffer) {
+	b.Print("@")
+	e.Namespace.Print(b)
+	b.Print(".")
+	e.Name.Print(b)
+	b.Print("(")
+	printList(b, e.Params)
+	b.Print(")")
+}
+
+func (p Pattern) Print(b *StringBuffer) { printNode(b, p.Value) }
+
+func (p LiteralPattern) Print(b *StringBuffer) {
+	if p.Negative {
+		b.Print("-")
+	}
+	p.Value.Print(b)
+}
+
+func (e MatchExpr) Print(b *StringBuffer) {
+	b.Print("match ")
+	e.Subject.Print(b)
+	b.Println(" {")
+	for _, arm := range e.Arms {
+		b.Flush(int(arm.From))
+		b.Print("case ")
+		arm.Pattern.Print(b)
+		if arm.Guard.Value != nil {
+			b.Print(" if ")
+			arm.Guard.Print(b)
+		}
+		b.Println(":")
+		printClause(b, arm.Body.Stmts)
+	}
+	b.Print("}")
+}
+
+func (p TuplePattern) Print(b *StringBuffer) {
+	b.Print("(")
+	printList(b, p.Elems)
+	b.Print(")")
+}
+
+func (p StructPattern) Print(b *StringBuffer) {
+	if p.Type != nil {
+		p.Type.Print(b)
+	}
+	b.Print("{")
+	printList(b, p.Fields)
+	b.Print("}")
+}
+
+func (p FieldPattern) Print(b *StringBuffer) {
+	p.Field.Print(b)
+	if p.Pattern != nil {
+		b.Print(": ")
+		p.Pattern.Print(b)
+	}
+}
+
+func (e QualifiedIdent) Print(b *StringBuffer) {
+	e.Package.Print(b)
 	b.Print(".")
 	e.Member.Print(b)
 }
 
 func (d GenDecl) Print(b *StringBuffer) {
-	for _, ident := range d.Idents {
-		b.Println(ident.Literal, ",")
+	for i, ident := range d.Idents {
+		if i != 0 {
+			b.Print(", ")
+		}
+		ident.Print(b)
+	}
+	if len(d.Idents) != 0 {
+		b.Print(" ")
+	}
+	d.Type.Print(b)
+}
+
+func (p TypeParam) Print(b *StringBuffer) {
+	printList(b, p.Idents)
+	if p.Constraint.Value != nil {
+		b.Print(" ")
+		p.Constraint.Print(b)
 	}
+}
+
+func printTypeParams(b *StringBuffer, params []TypeParam) {
+	if len(params) != 0 {
+		b.Print("[")
+		printList(b, params)
+		b.Print("]")
+	}
+}
+
+func (d TypeDecl) Print(b *StringBuffer) {
+	b.Print("type ")
+	d.Ident.Print(b)
+	printTypeParams(b, d.TypeParams)
+	b.Print(" ")
 	d.Type.Print(b)
 }
 
 func (d FuncDecl) Print(b *StringBuffer) {
-	if d.Ident == nil {
-		b.Print("fun ")
-	} else {
-		b.Println("fun ", d.Ident.Literal, " ")
+	b.Print("fun ")
+	if len(d.Captures) != 0 {
+		b.Print("[")
+		printList(b, d.Captures)
+		b.Print("]")
+	}
+	if d.Ident != nil {
+		d.Ident.Print(b)
+	}
+	printTypeParams(b, d.TypeParams)
+	d.Type.Print(b)
+	if d.Stmt != nil {
+		b.Print(" ")
+		d.Stmt.Print(b)
+	}
+}
+
+func (c Capture) Print(b *StringBuffer) {
+	if c.ByRef {
+		b.Print("&")
 	}
+	c.Ident.Print(b)
+}
+
+func (d MacroDecl) Print(b *StringBuffer) {
+	b.Print("macro ")
+	d.Ident.Print(b)
+	b.Print("(")
+	printList(b, d.Params)
+	b.Print(") {")
+	for i, tok := range d.Body {
+		if i != 0 && tok.Kind != token.NEWLINE && d.Body[i-1].Kind != token.NEWLINE {
+			b.Print(" ")
+		}
+		if tok.Kind == token.NEWLINE {
+			b.Println()
+			continue
+		}
+		tok.Print(b)
+	}
+	b.Print("}")
+}
+
+func (d ExternDecl) Print(b *StringBuffer) {
+	b.Print("extern ")
+	d.ABI.Print(b)
+	b.Print(" fun ")
+	d.Ident.Print(b)
 	d.Type.Print(b)
-	d.Stmt.Print(b)
+}
+
+func (d ImportDecl) Print(b *StringBuffer) {
+	b.Print("import ")
+	if d.Alias != nil {
+		d.Alias.Print(b)
+		b.Print(" ")
+	}
+	d.CanonicalName.Print(b)
+}
+
+func (a Attribute) Print(b *StringBuffer) {
+	b.Print("@")
+	a.Name.Print(b)
+	if len(a.Args) != 0 {
+		b.Print("(")
+		printList(b, a.Args)
+		b.Print(")")
+	}
+}
+
+func (a AttributeArg) Print(b *StringBuffer) {
+	if a.Key.Kind != 0 {
+		a.Key.Print(b)
+		b.Print(" = ")
+	}
+	a.Value.Print(b)
+}
+
+func (d ValDecl) Print(b *StringBuffer) {
+	if d.Mutable {
+		b.Print("var ")
+	} else if d.Const {
+		b.Print("const ")
+	} else {
+		b.Print("val ")
+	}
+	if d.Pattern != nil {
+		printNode(b, d.Pattern.Value)
+	} else {
+		d.Name.Print(b)
+	}
+	if d.Type.Value != nil {
+		b.Print(" ")
+		d.Type.Print(b)
+	}
+	if d.Value.Value != nil {
+		b.Print(" = ")
+		d.Value.Print(b)
+	}
+}
+
+func (s ReturnStmt) Print(b *StringBuffer) {
+	b.Print("return")
+	if len(s.Exprs) != 0 {
+		b.Print(" ")
+		printList(b, s.Exprs)
+	}
+}
+
+func (s AssignStmt) Print(b *StringBuffer) {
+	s.ExprL.Print(b)
+	b.Print(" ")
+	s.Operator.Print(b)
+	b.Print(" ")
+	s.ExprR.Print(b)
 }
 
 func (e StmtBlockExpr) Print(b *StringBuffer) {
 	b.Println("{")
+	b.Indent()
 	for _, stmt := range e.Stmts {
 		stmt.Print(b)
 	}
-	b.Println("}")
+	b.Flush(int(e.To))
+	b.Dedent()
+	b.Print("}")
+}
+
+func (s LoopStmt) Print(b *StringBuffer) {
+	b.Print("for ")
+	s.Cond.Print(b)
+	b.Print(" ")
+	s.Stmt.Print(b)
+}
+
+func (s ForeachStmt) Print(b *StringBuffer) {
+	if len(s.IdentList) == 0 {
+		b.Print("for range ")
+	} else {
+		b.Print("for ")
+		printList(b, s.IdentList)
+		b.Print(" in ")
+	}
+	s.Expr.Print(b)
+	b.Print(" ")
+	s.Stmt.Print(b)
+}
+
+func (s EndlessForStmt) Print(b *StringBuffer) {
+	b.Print("for ")
+	s.Stmt.Print(b)
+}
+
+func (s DeferStmt) Print(b *StringBuffer) {
+	if s.OnError {
+		b.Print("errdefer ")
+	} else {
+		b.Print("defer ")
+	}
+	s.Expr.Print(b)
+}
+
+func (s BreakStmt) Print(b *StringBuffer) {
+	b.Print("break")
+	if s.Label != nil {
+		b.Print(" ")
+		s.Label.Print(b)
+	}
+}
+
+func (s ContinueStmt) Print(b *StringBuffer) {
+	b.Print("continue")
+	if s.Label != nil {
+		b.Print(" ")
+		s.Label.Print(b)
+	}
+}
+
+func (s SwitchStmt) Print(b *StringBuffer) {
+	b.Print("switch ")
+	if s.Tag.Value != nil {
+		s.Tag.Print(b)
+		b.Print(" ")
+	}
+	b.Println("{")
+	for _, clause := range s.Cases {
+		b.Flush(int(clause.From))
+		if clause.Default {
+			b.Println("default:")
+		} else {
+			b.Print("case ")
+			printList(b, clause.Exprs)
+			b.Println(":")
+		}
+		printClause(b, clause.Stmts)
+	}
+	b.Print("}")
+}
+
+func (s SelectStmt) Print(b *StringBuffer) {
+	b.Println("select {")
+	for _, clause := range s.Cases {
+		b.Flush(int(clause.From))
+		if clause.Default {
+			b.Println("default:")
+		} else {
+			b.Print("case ")
+			printNode(b, clause.Comm.Value)
+			b.Println(":")
+		}
+		printClause(b, clause.Stmts)
+	}
+	b.Print("}")
+}
+
+func printClause(b *StringBuffer, stmts []Stmt) {
+	b.Indent()
+	for _, stmt := range stmts {
+		stmt.Print(b)
+	}
+	b.Dedent()
+}
+
+func (s FallthroughStmt) Print(b *StringBuffer) { b.Print("fallthrough") }
+
+func (e BadExpr) Print(b *StringBuffer) { b.Print("BadExpr") }
+
+func (s BadStmt) Print(b *StringBuffer) { b.Print("BadStmt") }
+
+func (d BadDecl) Print(b *StringBuffer) { b.Print("BadDecl") }
+
+func (s GoStmt) Print(b *StringBuffer) {
+	b.Print("go ")
+	s.Expr.Print(b)
+}
+
+func (s SendStmt) Print(b *StringBuffer) {
+	s.Chan.Print(b)
+	b.Print(" <- ")
+	s.Value.Print(b)
+}
+
+func (s GotoStmt) Print(b *StringBuffer) {
+	b.Print("goto ")
+	s.Label.Print(b)
+}
+
+func (s LabeledStmt) Print(b *StringBuffer) {
+	s.Label.Print(b)
+	b.Println(":")
+	if s.Stmt.Value != nil {
+		printNode(b, s.Stmt.Value)
+	}
+}
+
+// Print prints the package clause and the declarations separated by blank lines. The comments of the file
+// are printed on lines of their own before the statement, clause or closing brace following them, those
+// inside an expression after its statement.
+func (f *File) Print(b *StringBuffer) {
+	b.Notes = b.Notes[:0]
+	for _, c := range f.Comments {
+		b.Notes = append(b.Notes, Note{Pos: int(c.From), Text: c.Literal})
+	}
+
+	if f.Package != nil {
+		b.Flush(int(f.Package.From))
+		b.Print("package ")
+		f.Package.Print(b)
+		b.Println()
+	}
+	for i, decl := range f.Decls {
+		if i != 0 || f.Package != nil {
+			b.Println()
+		}
+		decl.Print(b)
+		b.Flush(int(decl.GetPosRange().To))
+	}
+	b.Flush(-1)
 }
@@ -0,0 +1,41 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ast
+
+import (
+	"cee"
+	"cee/token"
+	"strings"
+	"testing"
+)
+
+func TestPrintOperands(t *testing.T) {
+	binary := func(op int, lit string, x, y Expr) Expr {
+		return Expr{Union: cee.Union[ExprKind]{Tag: ExprBinary, Value: BinaryExpr{
+			Operator: Token{Kind: op, Literal: lit},
+			Exprs:    [2]Expr{x, y},
+		}}}
+	}
+	a, b, c := ident(0, "a"), ident(0, "b"), ident(0, "c")
+
+	for _, test := range []struct {
+		expr Expr
+		want string
+	}{
+		{binary(token.MUL, "*", binary(token.ADD, "+", a, b), c), "(a + b) * c"},
+		{binary(token.ADD, "+", binary(token.MUL, "*", a, b), c), "a * b + c"},
+		{binary(token.SUB, "-", a, binary(token.SUB, "-", b, c)), "a - (b - c)"},
+		{binary(token.SUB, "-", binary(token.SUB, "-", a, b), c), "a - b - c"},
+		{binary(token.LOR, "||", a, binary(token.LAND, "&&", b, c)), "a || b && c"},
+	} {
+		var out strings.Builder
+		if err := Fprint(&out, test.expr); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != test.want {
+			t.Errorf("printed %q, want %q", out.String(), test.want)
+		}
+	}
+}
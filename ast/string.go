@@ -0,0 +1,172 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ast
+
+import "strings"
+
+// TypeString renders a type on a single line, as used in signatures and hover texts.
+func TypeString(t Type) string {
+	var b strings.Builder
+	writeType(&b, t)
+	return b.String()
+}
+
+// FuncString renders `fun name(params) results`, name may be empty.
+func FuncString(name string, t FuncType) string {
+	var b strings.Builder
+	b.WriteString("fun ")
+	b.WriteString(name)
+	writeFuncType(&b, t)
+	return b.String()
+}
+
+var builtinTypeNames = [...]string{
+	TypeI8:  "i8",
+	TypeI16: "i16",
+	TypeI32: "i32",
+	TypeI64: "i64",
+	TypeU8:  "u8",
+	TypeU16: "u16",
+	TypeU32: "u32",
+	TypeU64: "u64",
+}
+
+func writeType(b *strings.Builder, t Type) {
+	if t.Value == nil {
+		// Builtin types synthesized by later phases carry no node.
+		if int(t.Tag) < len(builtinTypeNames) {
+			b.WriteString(builtinTypeNames[t.Tag])
+		}
+		return
+	}
+
+	switch v := t.Value.(type) {
+	case TypeAlias:
+		b.WriteString(v.Literal)
+	case GenericInstantiationExpr:
+		writeTypeName(b, v.Expr)
+		b.WriteString("[")
+		for i, arg := range v.TypeArgs {
+			if i != 0 {
+				b.WriteString(", ")
+			}
+			writeType(b, arg)
+		}
+		b.WriteString("]")
+	case StructType:
+		b.WriteString("struct {")
+		for i, field := range v.Fields {
+			if i != 0 {
+				b.WriteString(";")
+			}
+			b.WriteString(" ")
+			writeGenDecl(b, field)
+		}
+		b.WriteString(" }")
+	case TraitType:
+		if len(v.Embeds) == 0 && len(v.Methods) == 0 {
+			b.WriteString("trait {}")
+			break
+		}
+		b.WriteString("trait {")
+		for i, embed := range v.Embeds {
+			if i != 0 {
+				b.WriteString(";")
+			}
+			b.WriteString(" ")
+			b.WriteString(embed.Literal)
+		}
+		for i, method := range v.Methods {
+			if i != 0 || len(v.Embeds) != 0 {
+				b.WriteString(";")
+			}
+			b.WriteString(" ")
+			b.WriteString(method.Ident.Literal)
+			writeFuncType(b, method.Type)
+		}
+		b.WriteString(" }")
+	case FuncType:
+		b.WriteString("fun")
+		writeFuncType(b, v)
+	case MapType:
+		b.WriteString("map[")
+		writeType(b, v.Key)
+		b.WriteString("]")
+		writeType(b, v.Value)
+	case ChanType:
+		switch v.Dir {
+		case ChanSend:
+			b.WriteString("chan<- ")
+		case ChanRecv:
+			b.WriteString("<-chan ")
+		default:
+			b.WriteString("chan ")
+		}
+		writeType(b, v.Elem)
+	case PointerType:
+		b.WriteString("*")
+		writeType(b, v.Elem)
+	case ArrayType:
+		b.WriteString("[")
+		if v.Len.Value != nil {
+			Fprint(b, v.Len)
+		}
+		b.WriteString("]")
+		writeType(b, v.Elem)
+	}
+}
+
+// writeTypeName writes the name of an instantiated generic type, possibly qualified by its package.
+func writeTypeName(b *strings.Builder, e Expr) {
+	switch v := e.Value.(type) {
+	case Ident:
+		b.WriteString(v.Literal)
+	case QualifiedIdent:
+		b.WriteString(v.Package.Literal + "." + v.Member.Literal)
+	case MemberSelectExpr:
+		writeTypeName(b, v.Expr)
+		b.WriteString("." + v.Member.Literal)
+	}
+}
+
+func writeGenDecl(b *strings.Builder, d GenDecl) {
+	for i, ident := range d.Idents {
+		if i != 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(ident.Literal)
+	}
+	if len(d.Idents) != 0 {
+		b.WriteString(" ")
+	}
+	writeType(b, d.Type)
+}
+
+func writeFuncType(b *strings.Builder, t FuncType) {
+	b.WriteString("(")
+	for i, param := range t.Params {
+		if i != 0 {
+			b.WriteString(", ")
+		}
+		writeGenDecl(b, param)
+	}
+	b.WriteString(")")
+
+	switch len(t.Results) {
+	case 0:
+	case 1:
+		b.WriteString(" ")
+		writeType(b, t.Results[0])
+	default:
+		b.WriteString(" (")
+		for i, result := range t.Results {
+			if i != 0 {
+				b.WriteString(", ")
+			}
+			writeType(b, result)
+		}
+		b.WriteString(")")
+	}
+}
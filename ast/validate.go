@@ -0,0 +1,138 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ast
+
+import (
+	"cee/token"
+	"fmt"
+	"reflect"
+)
+
+// PositionError is a violated position invariant, parsers validating positions panic with it.
+type PositionError struct {
+	File   *token.File
+	Node   string // type of the offending token or node
+	Range  PosRange
+	Reason string
+}
+
+func (e *PositionError) Error() string {
+	// Positions out of the file cannot be decoded.
+	at := fmt.Sprint(e.File.Name(), ": pos ", e.Range.From)
+	if e.File.Base() <= int(e.Range.From) && int(e.Range.From) <= e.File.Base()+e.File.Size() {
+		at = fmt.Sprint(e.File.Name(), ":", e.File.Position(e.Range.From))
+	}
+	return fmt.Sprintf("%s: %s [%d, %d): %s", at, e.Node, e.Range.From, e.Range.To, e.Reason)
+}
+
+// CheckRange checks that a range lies in file with From <= To. Both ends are NoPos for synthesized nodes.
+func CheckRange(file *token.File, node string, r PosRange) error {
+	fail := func(reason string) error { return &PositionError{File: file, Node: node, Range: r, Reason: reason} }
+	switch {
+	case !r.From.IsValid() && !r.To.IsValid():
+		return nil
+	case !r.From.IsValid() || !r.To.IsValid():
+		return fail("one end only is NoPos")
+	case r.From > r.To:
+		return fail("From after To")
+	case int(r.From) < file.Base() || int(r.To) > file.Base()+file.Size():
+		return fail("out of the file")
+	}
+	return nil
+}
+
+var posRangeType = reflect.TypeOf(PosRange{})
+
+// Validate checks every range of the AST value pointed to by v with CheckRange, and that each node lies within
+// its parent and starts after the end of the node preceding it in a list. The first violation is returned.
+// Synthesized nodes, whose ranges are NoPos, constrain neither their children nor their siblings.
+func Validate(file *token.File, v any) error {
+	return validate(file, reflect.ValueOf(v).Elem(), "", PosRange{})
+}
+
+// validate checks v, a part of owner, within the range of the innermost node around it.
+func validate(file *token.File, v reflect.Value, owner string, parent PosRange) error {
+	if v.Type() == posRangeType {
+		return CheckRange(file, owner, v.Interface().(PosRange))
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			return validate(file, v.Elem(), owner, parent)
+		}
+	case reflect.Struct:
+		if r, ok := ownRange(v); ok && r.From.IsValid() {
+			node := v.Type().String()
+			if err := CheckRange(file, node, r); err != nil {
+				return err
+			}
+			if parent.From.IsValid() && (r.From < parent.From || r.To > parent.To) {
+				return &PositionError{File: file, Node: node, Range: r, Reason: fmt.Sprintf("outside its parent [%d, %d)", parent.From, parent.To)}
+			}
+			parent = r
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				if err := validate(file, v.Field(i), v.Type().String(), parent); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		var prev PosRange
+		for i := 0; i < v.Len(); i++ {
+			if err := validate(file, v.Index(i), owner, parent); err != nil {
+				return err
+			}
+			r, ok := rangeOf(v.Index(i))
+			if !ok || !r.From.IsValid() {
+				continue
+			}
+			if prev.To.IsValid() && r.From < prev.To {
+				reason := fmt.Sprintf("before the end of the previous element [%d, %d)", prev.From, prev.To)
+				return &PositionError{File: file, Node: v.Index(i).Type().String(), Range: r, Reason: reason}
+			}
+			prev = r
+		}
+	}
+	return nil
+}
+
+// ownRange returns the range a struct has as a field or through its embedded fields, as Ident through Token.
+func ownRange(v reflect.Value) (PosRange, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		switch {
+		case f.Type == posRangeType:
+			return v.Field(i).Interface().(PosRange), true
+		case f.Anonymous && f.Type.Kind() == reflect.Struct:
+			if r, ok := ownRange(v.Field(i)); ok {
+				return r, true
+			}
+		}
+	}
+	return PosRange{}, false
+}
+
+// rangeOf returns the range of the node in v, looking through pointers and the values of unions such as Expr.
+func rangeOf(v reflect.Value) (PosRange, bool) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return PosRange{}, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return PosRange{}, false
+	}
+	if r, ok := ownRange(v); ok {
+		return r, true
+	}
+	if value := v.FieldByName("Value"); value.Kind() == reflect.Interface {
+		return rangeOf(value)
+	}
+	return PosRange{}, false
+}
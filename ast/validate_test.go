@@ -0,0 +1,51 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ast
+
+import (
+	"cee"
+	"cee/token"
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	file := token.NewFileSet().AddFile("a.cee", -1, 10)
+	ident := func(from, to int) Ident {
+		return Ident{Token: Token{PosRange: PosRange{From: token.Pos(file.Base() + from), To: token.Pos(file.Base() + to)}}}
+	}
+
+	ok := ImportDecl{Alias: &Ident{}, CanonicalName: LiteralValue{Token: ident(2, 5).Token}}
+	if err := Validate(file, &ok); err != nil {
+		t.Error(err)
+	}
+
+	for _, bad := range []Ident{ident(5, 2), ident(8, 12), {Token: Token{PosRange: PosRange{From: token.Pos(file.Base())}}}} {
+		var perr *PositionError
+		if err := Validate(file, &bad); !errors.As(err, &perr) {
+			t.Errorf("%v: expected a position error, got %v", bad.PosRange, err)
+		}
+	}
+
+	// A node lies within its parent, and the elements of a list follow each other.
+	rng := func(from, to int) PosRange {
+		return PosRange{From: token.Pos(file.Base() + from), To: token.Pos(file.Base() + to)}
+	}
+	for name, bad := range map[string]GenDecl{
+		"child outside": {PosRange: rng(2, 5), Idents: []Ident{ident(1, 3)}},
+		"siblings":      {PosRange: rng(0, 9), Idents: []Ident{ident(4, 6), ident(2, 3)}},
+		"overlap":       {PosRange: rng(0, 9), Idents: []Ident{ident(2, 5), ident(4, 6)}},
+		"union outside": {PosRange: rng(0, 4), Type: Type{Union: cee.Union[TypeKind]{Tag: TypeIdent, Value: TypeAlias{Ident: ident(3, 6)}}}},
+	} {
+		var perr *PositionError
+		if err := Validate(file, &bad); !errors.As(err, &perr) {
+			t.Errorf("%s: expected a position error, got %v", name, err)
+		}
+	}
+	nested := GenDecl{PosRange: rng(0, 9), Idents: []Ident{ident(0, 1), ident(3, 4)}, Type: Type{Union: cee.Union[TypeKind]{Tag: TypeIdent, Value: TypeAlias{Ident: ident(5, 9)}}}}
+	if err := Validate(file, &nested); err != nil {
+		t.Error(err)
+	}
+}
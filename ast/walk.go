@@ -4,8 +4,307 @@
 
 package ast
 
+import (
+	"fmt"
+	"sort"
+)
+
 type (
 	Visitor interface {
 		Visit(node Node) (w Visitor)
 	}
 )
+
+// Walk traverses an AST in depth-first order: it starts by calling v.Visit(node). If the visitor w returned
+// by v.Visit(node) is not nil, Walk is invoked recursively with w for each child of node in source order,
+// followed by a call of w.Visit(nil).
+//
+// The Expr, Type, Stmt and Pattern wrappers are visited before the node they hold, zero wrappers and
+// absent optional children are skipped.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case Token, Ident, LiteralValue, CastExpr, FallthroughStmt, BadExpr, BadStmt, BadDecl:
+		// leaves
+
+	// Wrappers
+	case Expr:
+		Walk(v, n.Value.(Node))
+	case Type:
+		Walk(v, n.Value.(Node))
+	case Pattern:
+		Walk(v, n.Value.(Node))
+	case Stmt:
+		walkList(v, n.Attrs)
+		Walk(v, n.Value.(Node))
+
+	// Types
+	case StructType:
+		walkList(v, n.Fields)
+	case TypeAlias:
+		Walk(v, n.Ident)
+	case TraitType:
+		walkList(v, n.Embeds)
+		walkList(v, n.Methods)
+	case TraitMethod:
+		Walk(v, n.Ident)
+		Walk(v, n.Type)
+	case FuncType:
+		walkList(v, n.Params)
+		walkList(v, n.Results)
+	case MapType:
+		walkType(v, n.Key)
+		walkType(v, n.Value)
+	case ChanType:
+		walkType(v, n.Elem)
+	case PointerType:
+		walkType(v, n.Elem)
+	case ArrayType:
+		walkExpr(v, n.Len)
+		walkType(v, n.Elem)
+
+	// Expressions
+	case UnaryExpr:
+		walkExpr(v, n.Expr)
+	case BinaryExpr:
+		walkExpr(v, n.Exprs[0])
+		walkExpr(v, n.Exprs[1])
+	case EllipsisExpr:
+		walkExpr(v, n.Array)
+	case CallExpr:
+		walkExpr(v, n.Callee)
+		walkList(v, n.Params)
+	case IndexExpr:
+		walkExpr(v, n.Expr)
+		walkExpr(v, n.Index)
+	case BranchExpr:
+		walkExpr(v, n.Cond)
+		Walk(v, n.Branch)
+		if n.ElseBranch.From.IsValid() || len(n.ElseBranch.Stmts) != 0 {
+			Walk(v, n.ElseBranch)
+		}
+	case MatchExpr:
+		walkExpr(v, n.Subject)
+		walkList(v, n.Arms)
+	case MatchArm:
+		Walk(v, n.Pattern)
+		walkExpr(v, n.Guard)
+		Walk(v, n.Body)
+	case StmtBlockExpr:
+		walkType(v, n.Type)
+		walkList(v, n.Stmts)
+	case MemberSelectExpr:
+		walkExpr(v, n.Expr)
+		Walk(v, n.Member)
+	case TryExpr:
+		walkExpr(v, n.Expr)
+	case QualifiedIdent:
+		Walk(v, n.Package)
+		Walk(v, n.Member)
+	case TypeExpr:
+		walkType(v, n.Type)
+	case CompositeLit:
+		walkType(v, n.Type)
+		walkList(v, n.Elems)
+	case KeyedElement:
+		walkExpr(v, n.Key)
+		walkExpr(v, n.Value)
+	case InterpolatedString:
+		for i, lit := range n.Lits {
+			Walk(v, lit)
+			if i < len(n.Exprs) {
+				walkExpr(v, n.Exprs[i])
+			}
+		}
+	case FuncLitExpr:
+		walkList(v, n.Captures)
+		Walk(v, n.Type)
+		Walk(v, n.Body)
+	case GenericInstantiationExpr:
+		walkExpr(v, n.Expr)
+		walkList(v, n.TypeArgs)
+	case IntrinsicExpr:
+		Walk(v, n.Namespace)
+		Walk(v, n.Name)
+		walkList(v, n.Params)
+
+	// Patterns
+	case LiteralPattern:
+		Walk(v, n.Value)
+	case TuplePattern:
+		walkList(v, n.Elems)
+	case StructPattern:
+		if n.Type != nil {
+			Walk(v, *n.Type)
+		}
+		walkList(v, n.Fields)
+	case FieldPattern:
+		Walk(v, n.Field)
+		if n.Pattern != nil {
+			Walk(v, *n.Pattern)
+		}
+
+	// Declarations and statements
+	case Attribute:
+		Walk(v, n.Name)
+		walkList(v, n.Args)
+	case AttributeArg:
+		if n.Key.Kind != 0 {
+			Walk(v, n.Key)
+		}
+		Walk(v, n.Value)
+	case ImportDecl:
+		if n.Alias != nil {
+			Walk(v, *n.Alias)
+		}
+		Walk(v, n.CanonicalName)
+	case ValDecl:
+		if n.Pattern != nil {
+			Walk(v, *n.Pattern)
+		} else {
+			Walk(v, n.Name)
+		}
+		walkType(v, n.Type)
+		walkExpr(v, n.Value)
+	case GenDecl:
+		walkList(v, n.Idents)
+		walkType(v, n.Type)
+	case TypeDecl:
+		Walk(v, n.Ident)
+		walkList(v, n.TypeParams)
+		walkType(v, n.Type)
+	case TypeParam:
+		walkList(v, n.Idents)
+		walkType(v, n.Constraint)
+	case FuncDecl:
+		walkList(v, n.Captures)
+		if n.Ident != nil {
+			Walk(v, *n.Ident)
+		}
+		walkList(v, n.TypeParams)
+		Walk(v, n.Type)
+		if n.Stmt != nil {
+			Walk(v, *n.Stmt)
+		}
+	case Capture:
+		Walk(v, n.Ident)
+	case MacroDecl:
+		Walk(v, n.Ident)
+		walkList(v, n.Params)
+		walkList(v, n.Body)
+	case ExternDecl:
+		Walk(v, n.ABI)
+		Walk(v, n.Ident)
+		Walk(v, n.Type)
+	case BreakStmt:
+		if n.Label != nil {
+			Walk(v, *n.Label)
+		}
+	case ContinueStmt:
+		if n.Label != nil {
+			Walk(v, *n.Label)
+		}
+	case ReturnStmt:
+		walkList(v, n.Exprs)
+	case AssignStmt:
+		walkExpr(v, n.ExprL)
+		walkExpr(v, n.ExprR)
+	case LoopStmt:
+		walkExpr(v, n.Cond)
+		Walk(v, n.Stmt)
+	case ForeachStmt:
+		walkList(v, n.IdentList)
+		walkExpr(v, n.Expr)
+		Walk(v, n.Stmt)
+	case EndlessForStmt:
+		Walk(v, n.Stmt)
+	case DeferStmt:
+		walkExpr(v, n.Expr)
+	case SwitchStmt:
+		walkExpr(v, n.Tag)
+		walkList(v, n.Cases)
+	case CaseClause:
+		walkList(v, n.Exprs)
+		walkList(v, n.Stmts)
+	case SelectStmt:
+		walkList(v, n.Cases)
+	case CommClause:
+		walkStmt(v, n.Comm)
+		walkList(v, n.Stmts)
+	case GoStmt:
+		walkExpr(v, n.Expr)
+	case SendStmt:
+		walkExpr(v, n.Chan)
+		walkExpr(v, n.Value)
+	case GotoStmt:
+		Walk(v, n.Label)
+	case LabeledStmt:
+		Walk(v, n.Label)
+		walkStmt(v, n.Stmt)
+
+	// Containers
+	case *File:
+		if n.Package != nil {
+			Walk(v, *n.Package)
+		}
+		walkList(v, n.Decls)
+	case *Package:
+		paths := make([]string, 0, len(n.Files))
+		for path := range n.Files {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			Walk(v, n.Files[path])
+		}
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+// walkExpr, walkType and walkStmt skip zero wrappers, which stand for absent children.
+
+func walkExpr(v Visitor, e Expr) {
+	if e.Value != nil {
+		Walk(v, e)
+	}
+}
+
+func walkType(v Visitor, t Type) {
+	if t.Value != nil {
+		Walk(v, t)
+	}
+}
+
+func walkStmt(v Visitor, s Stmt) {
+	if s.Value != nil {
+		Walk(v, s)
+	}
+}
+
+func walkList[N Node](v Visitor, list []N) {
+	for _, node := range list {
+		Walk(v, node)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: it starts by calling f(node), node must not be nil.
+// If f returns true, Inspect invokes f recursively for each child of node, followed by a call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
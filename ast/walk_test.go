@@ -0,0 +1,58 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ast
+
+import (
+	"cee"
+	"cee/token"
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	named := func(name string) Ident { return Ident{Token: Token{Kind: token.IDENT, Literal: name}} }
+	name := named("f")
+	expr := func(kind ExprKind, v Node) Expr { return Expr{Union: cee.Union[ExprKind]{Tag: kind, Value: v}} }
+	stmt := func(kind StmtKind, v Node) Stmt { return Stmt{Union: cee.Union[StmtKind]{Tag: kind, Value: v}} }
+
+	// fun f() { val x = a + b; var y; for { break outer } }
+	label := named("outer")
+	decl := stmt(StmtFuncDecl, FuncDecl{Ident: &name, Stmt: &StmtBlockExpr{Stmts: []Stmt{
+		stmt(StmtValDecl, ValDecl{Name: named("x"), Value: expr(ExprBinary, BinaryExpr{
+			Exprs: [2]Expr{ident(0, "a"), ident(2, "b")},
+		})}),
+		stmt(StmtValDecl, ValDecl{Mutable: true, Name: named("y")}),
+		stmt(StmtEndlessFor, EndlessForStmt{Stmt: StmtBlockExpr{Stmts: []Stmt{
+			stmt(StmtBreak, BreakStmt{Label: &label}),
+		}}}),
+	}}})
+
+	var (
+		idents []string
+		depth  int
+	)
+	Inspect(decl, func(n Node) bool {
+		if n == nil {
+			depth--
+			return false
+		}
+		depth++
+		if id, ok := n.(Ident); ok {
+			idents = append(idents, id.Literal)
+		}
+		// The operands of binary expressions are skipped.
+		_, binary := n.(BinaryExpr)
+		if binary {
+			depth--
+		}
+		return !binary
+	})
+	if got := strings.Join(idents, " "); got != "f x y outer" {
+		t.Errorf("idents = %s", got)
+	}
+	if depth != 0 {
+		t.Errorf("%d visits not closed", depth)
+	}
+}
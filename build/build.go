@@ -0,0 +1,752 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package build
+
+import (
+	"bytes"
+	"cee/ast"
+	"cee/cache"
+	"cee/cfg"
+	"cee/constant"
+	"cee/diagnosis"
+	"cee/edition"
+	"cee/ffi"
+	"cee/gogen"
+	"cee/hir"
+	"cee/loader"
+	"cee/macro"
+	"cee/metrics"
+	"cee/parser"
+	"cee/profile"
+	"cee/resolve"
+	"cee/token"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+const SourceExt = ".cee"
+
+type OutputKind byte
+
+const (
+	_ OutputKind = iota
+
+	OutputNone // check only
+	OutputGo
+	OutputC // C headers of extern declarations
+)
+
+func (k OutputKind) String() string {
+	switch k {
+	case OutputNone:
+		return "none"
+	case OutputGo:
+		return "go"
+	case OutputC:
+		return "c"
+	}
+	return fmt.Sprintf("OutputKind(%d)", k)
+}
+
+type Options struct {
+	Output      OutputKind
+	OutDir      string
+	Parallelism int // defaults to GOMAXPROCS
+	Format      DiagnosticsFormat
+	Cache       *cache.Cache         // nil disables caching
+	Source      loader.FileSource    // defaults to the disk
+	Cfg         cfg.Env              // conditional compilation target, defaults to the host
+	ModCache    string               // holds the required modules, defaults to loader.DefaultModCache
+	MaxErrors   int                  // diagnostics of a file after which its parsing stops, see parser.Options
+	Validate    bool                 // check the positions of every token and declaration, see parser.Options
+	Confusables bool                 // report declarations whose names look like others visible from them
+	Severities  diagnosis.Severities // overrides the severity of diagnostics by kind, ignored ones are dropped
+
+	Metrics       metrics.Metrics  // receives phase durations and cache hits, defaults to metrics.Nop
+	Profiler      profile.Profiler // nil disables profiling
+	ProfileLabels bool             // tag pprof samples with the phase and the file or package
+
+	Events EventSink // receives the progress of builds, nil disables events
+}
+
+type File struct {
+	Path      string
+	TokenFile *token.File // decodes the positions of the file, nil if it could not be read
+	Package   *ast.Ident  // the package clause, nil when the file has none
+	Decls     []ast.Stmt
+	Comments  []ast.Token
+	Diagnosis []diagnosis.Diagnosis
+	Err       error           // fatal error, e.g. I/O or a scanner panic
+	Macros    *macro.Expander // traces the positions of the expanded code back to the file, nil if it was not parsed
+}
+
+type Package struct {
+	Dir   string
+	Path  string // canonical name
+	Name  string
+	Files []*File
+}
+
+// Result is the same for the same sources and options, however the work was scheduled: packages are in
+// dependency order, independent packages by canonical name, the files of a package by path and the diagnostics
+// of a file by position. Artifacts follow the order of the packages.
+type Result struct {
+	Packages  []*Package
+	Artifacts []string
+	ByPackage map[string][]string // artifacts by canonical package name
+}
+
+func (r *Result) addArtifacts(pkg *Package, artifacts []string) {
+	if len(artifacts) == 0 {
+		return
+	}
+	if r.ByPackage == nil {
+		r.ByPackage = map[string][]string{}
+	}
+	r.Artifacts = append(r.Artifacts, artifacts...)
+	r.ByPackage[pkg.Path] = append(r.ByPackage[pkg.Path], artifacts...)
+}
+
+func (r *Result) HasErrors() bool {
+	for _, pkg := range r.Packages {
+		if pkg.HasErrors() {
+			return true
+		}
+	}
+	return false
+}
+
+type Driver struct {
+	Options Options
+	FileSet *token.FileSet
+
+	plugins []Plugin
+}
+
+func NewDriver(opts Options) Driver {
+	if opts.Parallelism <= 0 {
+		opts.Parallelism = runtime.GOMAXPROCS(0)
+	}
+	if opts.Output == 0 {
+		opts.Output = OutputNone
+	}
+	if opts.Source == nil {
+		opts.Source = loader.DiskSource{}
+	}
+	if opts.Cfg == nil {
+		opts.Cfg = cfg.DefaultEnv()
+	}
+	if opts.Metrics == nil {
+		opts.Metrics = metrics.Nop{}
+	}
+	if opts.ModCache == "" {
+		opts.ModCache = loader.DefaultModCache()
+	}
+	return Driver{Options: opts, FileSet: token.NewFileSet()}
+}
+
+// ParseFile parses the top-level declarations of a source file, allocating its positions in fset.
+// The edition pragma of the file overrides the edition of opts. The invocations of the macros it declares are expanded.
+// Invalid UTF-8 and NULs are replaced by U+FFFD and reported. Input the scanner rejects is skipped as ILLEGAL tokens with a diagnostic each. Remaining panics are turned
+// into a fatal error so one broken file does not take the whole build down, the declarations and diagnostics
+// preceding the panic are kept, as they are when the error budget of opts is spent.
+func ParseFile(fset *token.FileSet, path string, src []byte, opts parser.Options) (f *File) {
+	buffer, bad := parser.Decode(src)
+	f = &File{Path: path, TokenFile: fset.AddFile(path, -1, len(buffer))}
+
+	if e := edition.FilePragma(src); e != "" {
+		if !edition.Valid(e) {
+			f.Err = fmt.Errorf("%s: unknown edition %s", path, e)
+			return f
+		}
+		opts.Edition = e
+	}
+
+	p := parser.NewFileParser(f.TokenFile, buffer)
+	p.Options = opts
+	p.Options.Recover = true
+	p.Macros = macro.NewExpander(fset)
+	f.Macros = p.Macros
+
+	defer func() {
+		if r := recover(); r != nil {
+			switch r.(type) {
+			case parser.Bailout:
+			case *ast.PositionError:
+				// Position bugs of the parser are not masked in validation mode.
+				panic(r)
+			default:
+				f.Err = fmt.Errorf("%s: %v", path, r)
+			}
+		}
+		f.Comments = p.Comments
+		f.Diagnosis = p.Diagnosis
+		diagnosis.Sort(f.Diagnosis)
+	}()
+
+	p.ReportBadRunes(bad)
+	p.Scan()
+	f.Package = p.ExpectPackageClause()
+	for {
+		p.SkipNewlines()
+		if p.ReachedEOF {
+			break
+		}
+		for _, decl := range p.ExpectDecls() {
+			p.ValidateDecl(&decl)
+			f.Decls = append(f.Decls, decl)
+		}
+	}
+
+	return f
+}
+
+// Collect finds the packages under root, one per directory containing source files.
+// Files only present in an overlay source are included.
+func Collect(root string, src loader.FileSource) ([]*Package, error) {
+	pkgs := map[string]*Package{}
+	seen := map[string]bool{}
+
+	add := func(path string) {
+		if seen[path] {
+			return
+		}
+		seen[path] = true
+		dir := filepath.Dir(path)
+		pkg, ok := pkgs[dir]
+		if !ok {
+			pkg = &Package{Dir: dir, Name: dirName(dir)}
+			pkgs[dir] = pkg
+		}
+		pkg.Files = append(pkg.Files, &File{Path: path})
+	}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(path) == SourceExt {
+			add(filepath.Clean(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Unsaved buffers may not exist on disk yet.
+	if overlay, ok := src.(*loader.Overlay); ok {
+		root := filepath.Clean(root)
+		for _, path := range overlay.Paths() {
+			rel, err := filepath.Rel(root, path)
+			if err == nil && filepath.IsLocal(rel) && filepath.Ext(path) == SourceExt {
+				add(path)
+			}
+		}
+	}
+
+	var result []*Package
+	for _, pkg := range pkgs {
+		sort.Slice(pkg.Files, func(i, j int) bool { return pkg.Files[i].Path < pkg.Files[j].Path })
+		result = append(result, pkg)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Dir < result[j].Dir })
+	return result, nil
+}
+
+type cachedFile struct {
+	Base     int // of the file the positions were allocated in
+	Package  *ast.Ident
+	Decls    []ast.Stmt
+	Comments []ast.Token
+}
+
+// parseCached reuses the encoded declarations of unchanged files.
+// Only files without diagnostics are cached, so diagnostics are always reported afresh, and without expansions,
+// whose files are not kept.
+// Entries that do not decode, e.g. of another version of ast.Schema, are parsed again and overwritten.
+func (d *Driver) parseCached(path string, src []byte, edition string) *File {
+	opts := parser.Options{Edition: edition, MaxErrors: d.Options.MaxErrors, Validate: d.Options.Validate}
+	c := d.Options.Cache
+	if c == nil {
+		return ParseFile(d.FileSet, path, src, opts)
+	}
+
+	key := cache.NewKey("ast", src, opts.Edition)
+	if data, ok, err := c.Get(key); ok && err == nil {
+		var entry cachedFile
+		if err := ast.Decode(bytes.NewReader(data), &entry); err == nil {
+			d.Options.Metrics.Add(metrics.CacheHits, 1)
+			buffer, _ := parser.Decode(src)
+			file := d.FileSet.AddFile(path, -1, len(buffer))
+			file.SetLinesForContent(buffer)
+			ast.Shift(&entry, token.Pos(file.Base()-entry.Base))
+			return &File{Path: path, TokenFile: file, Package: entry.Package, Decls: entry.Decls, Comments: entry.Comments}
+		}
+	}
+
+	d.Options.Metrics.Add(metrics.CacheMisses, 1)
+	f := ParseFile(d.FileSet, path, src, opts)
+	if f.Err == nil && len(f.Diagnosis) == 0 && len(f.Macros.Expansions) == 0 {
+		var buf bytes.Buffer
+		if ast.Encode(&buf, cachedFile{Base: f.TokenFile.Base(), Package: f.Package, Decls: f.Decls, Comments: f.Comments}) == nil {
+			_ = c.Put(key, buf.Bytes())
+		}
+	}
+	return f
+}
+
+// configure drops the declarations excluded by conditional compilation and embeds files.
+// It runs after the cache, which holds every declaration whatever the target and the embedded files.
+func (d *Driver) configure(f *File) {
+	decls, diags := cfg.Filter(f.Decls, d.Options.Cfg)
+	f.Decls = decls
+	f.Diagnosis = append(f.Diagnosis, diags...)
+	f.Diagnosis = append(f.Diagnosis, loader.Embed(d.Options.Source, f.Path, f.Decls)...)
+}
+
+// run runs fn as a phase of the pipeline on a unit, a file path or a package directory.
+// Each run is reported to the event sink with its duration.
+func (d *Driver) run(phase profile.Phase, unit string, fn func()) {
+	defer metrics.Since(d.Options.Metrics, metrics.PhaseSeconds+phase.String(), time.Now())
+	if d.Options.Events != nil {
+		start := time.Now()
+		defer func() {
+			d.emitEvent(Event{Kind: EventPhase, Phase: phase.String(), Unit: unit, Duration: time.Since(start)})
+		}()
+	}
+	profile.Run(context.Background(), d.Options.Profiler, d.Options.ProfileLabels, phase, unit, func(context.Context) { fn() })
+}
+
+// parse parses the files of the packages in parallel in edition, the one of the module. Files whose
+// build constraint does not hold for the target are dropped unparsed.
+func (d *Driver) parse(pkgs []*Package, edition string) {
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, d.Options.Parallelism)
+	)
+
+	for _, pkg := range pkgs {
+		for i, file := range pkg.Files {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(files []*File, i int, path string) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				d.emitEvent(Event{Kind: EventFileStarted, File: path})
+				src, err := loader.ReadFile(d.Options.Source, path)
+				if err != nil {
+					files[i] = &File{Path: path, Err: err}
+					return
+				}
+				match, err := cfg.Match(src, d.Options.Cfg)
+				if err != nil {
+					files[i] = &File{Path: path, Err: fmt.Errorf("%s: %w", path, err)}
+					return
+				}
+				if !match {
+					files[i] = nil
+					return
+				}
+				d.run(profile.PhaseParse, path, func() {
+					files[i] = d.parseCached(path, src, edition)
+					d.configure(files[i])
+				})
+			}(pkg.Files, i, file.Path)
+		}
+	}
+
+	wg.Wait()
+
+	// Files excluded by their constraint are dropped. The package is named by the clause of its first file.
+	for _, pkg := range pkgs {
+		files := pkg.Files[:0]
+		for _, file := range pkg.Files {
+			if file != nil {
+				files = append(files, file)
+			}
+		}
+		pkg.Files = files
+		for _, file := range files {
+			if file.Package != nil {
+				pkg.Name = file.Package.Literal
+				break
+			}
+		}
+	}
+}
+
+// CheckFile lowers every function body and package-level val and var initializer, surfacing the diagnostics
+// of the lowering, e.g. literals overflowing their type.
+// Nodes the lowering does not support are left to the backends, which report the ones they cannot emit:
+// a gap in the lowering is not an error in the program.
+func CheckFile(file *File) {
+	for _, decl := range file.Decls {
+		l := hir.NewLowerer()
+		switch decl.Tag {
+		case ast.StmtFuncDecl:
+			fn := decl.Value.(ast.FuncDecl)
+			if fn.Stmt == nil {
+				continue
+			}
+			l.LowerFunc(fn)
+		case ast.StmtValDecl:
+			l.LowerStmt(decl)
+		default:
+			continue
+		}
+		for _, d := range l.Diagnosis {
+			if d.Kind != diagnosis.UnsupportedNode {
+				file.Diagnosis = append(file.Diagnosis, d)
+			}
+		}
+	}
+}
+
+// QualifyFile rewrites the selections of members of imported packages in the file into qualified identifiers.
+func QualifyFile(file *File) {
+	info := resolve.Resolve([]resolve.File{{Path: file.Path, TokenFile: file.TokenFile, Decls: file.Decls}})
+	file.Decls = resolve.Qualify(file.Decls, info)
+}
+
+// CheckConfusables reports the declarations of the package whose names look like the names of others
+// visible from them, as `pаy`, with a Cyrillic а, and `pay`.
+func CheckConfusables(pkg *Package) {
+	var files []resolve.File
+	byPath := map[string]*File{}
+	for _, file := range pkg.Files {
+		if file.TokenFile != nil {
+			files = append(files, resolve.File{Path: file.Path, TokenFile: file.TokenFile, Decls: file.Decls})
+			byPath[file.Path] = file
+		}
+	}
+	for _, pair := range resolve.Confusables(resolve.Resolve(files)) {
+		file := byPath[pair.Object.File]
+		file.Diagnosis = append(file.Diagnosis, diagnosis.Diagnosis{
+			Kind:  diagnosis.ConfusableIdent,
+			Error: diagnosis.ConfusableIdentError{Ident: pair.Object.Ident, Name: pair.Object.Name, Other: pair.Other.Name},
+		})
+	}
+}
+
+// CheckConstants folds the const declarations of the package, reporting values which are not constant,
+// invalid operations and initialization cycles in the files declaring them.
+func CheckConstants(pkg *Package) {
+	var decls []ast.Stmt
+	for _, file := range pkg.Files {
+		decls = append(decls, file.Decls...)
+	}
+	_, diags := constant.Decls(decls, nil)
+	for _, d := range diags {
+		pkg.report(d)
+	}
+}
+
+func (d *Driver) check(pkg *Package, byName map[string]*Package) {
+	for _, file := range pkg.Files {
+		QualifyFile(file)
+		CheckFile(file)
+	}
+	CheckImports(pkg, byName)
+	CheckConstants(pkg)
+	if d.Options.Confusables {
+		CheckConfusables(pkg)
+	}
+	for _, file := range pkg.Files {
+		file.Diagnosis = d.Options.Severities.Apply(file.Diagnosis)
+		diagnosis.Sort(file.Diagnosis)
+	}
+}
+
+func importsOf(pkg *Package) []string {
+	var imports []string
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			if decl.Tag == ast.StmtImportDecl {
+				name, err := token.Unquote(decl.Value.(ast.ImportDecl).CanonicalName.Literal)
+				if err == nil {
+					imports = append(imports, name)
+				}
+			}
+		}
+	}
+	return imports
+}
+
+// graph builds the import graph of the packages. Canonical names are given by the manifest of the module,
+// without one they are package paths relative to root.
+func graph(root string, res *loader.Resolver, pkgs []*Package) (loader.Graph, map[string]*Package, error) {
+	g := loader.NewGraph()
+	byName := map[string]*Package{}
+	for _, pkg := range pkgs {
+		var err error
+		if res != nil {
+			pkg.Path, err = res.Name(pkg.Dir)
+		} else {
+			pkg.Path, err = filepath.Rel(root, pkg.Dir)
+			pkg.Path = filepath.ToSlash(pkg.Path)
+		}
+		if err != nil {
+			return g, nil, err
+		}
+		byName[pkg.Path] = pkg
+		g.AddPackage(pkg.Path, importsOf(pkg)...)
+	}
+	return g, byName, nil
+}
+
+// resolver loads the manifest of the module enclosing root, nil if there is none.
+func (d *Driver) resolver(root string) (*loader.Resolver, error) {
+	path, err := loader.FindManifest(d.Options.Source, root)
+	if err != nil || path == "" {
+		return nil, err
+	}
+	return loader.LoadResolver(d.Options.Source, path, d.Options.ModCache)
+}
+
+// editionOf returns the edition of the module res resolves, empty without manifest.
+func editionOf(res *loader.Resolver) string {
+	if res == nil {
+		return ""
+	}
+	return res.Manifest.Edition
+}
+
+// dirName names the package of dir after the directory, until a package clause names it.
+func dirName(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return filepath.Base(dir)
+}
+
+// collectDir returns the package made of the source files directly in dir.
+func collectDir(dir string) (*Package, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	pkg := &Package{Dir: dir, Name: dirName(dir)}
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == SourceExt {
+			pkg.Files = append(pkg.Files, &File{Path: filepath.Join(dir, entry.Name())})
+		}
+	}
+	if len(pkg.Files) == 0 {
+		return nil, fmt.Errorf("%s: no source files", dir)
+	}
+	return pkg, nil
+}
+
+// loadImports parses the imported packages missing from the graph until every import is resolved,
+// which pulls in the required modules. Imports the manifest cannot resolve are left out of the graph
+// and reported by the checker.
+func (d *Driver) loadImports(res *loader.Resolver, g *loader.Graph, byName map[string]*Package) {
+	failed := map[string]bool{}
+	for {
+		var (
+			missing []string
+			seen    = map[string]bool{}
+		)
+		for _, imports := range g.Imports {
+			for _, name := range imports {
+				if _, ok := byName[name]; !ok && !failed[name] && !seen[name] {
+					seen[name] = true
+					missing = append(missing, name)
+				}
+			}
+		}
+		if len(missing) == 0 {
+			return
+		}
+		sort.Strings(missing)
+
+		var pkgs []*Package
+		for _, name := range missing {
+			dir, ok := res.Dir(name)
+			if !ok {
+				failed[name] = true
+				continue
+			}
+			pkg, err := collectDir(dir)
+			if err != nil {
+				failed[name] = true
+				continue
+			}
+			pkg.Path = name
+			byName[name] = pkg
+			pkgs = append(pkgs, pkg)
+		}
+
+		// Required modules are parsed in the edition of the module as well, editions only add features.
+		d.parse(pkgs, editionOf(res))
+		for _, pkg := range pkgs {
+			g.AddPackage(pkg.Path, importsOf(pkg)...)
+		}
+	}
+}
+
+// order sorts packages by import dependencies.
+func order(g loader.Graph, byName map[string]*Package) ([]*Package, error) {
+	names, err := g.TopoOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Package
+	for _, name := range names {
+		if pkg, ok := byName[name]; ok {
+			result = append(result, pkg)
+		}
+	}
+	return result, nil
+}
+
+// emit writes the artifacts of a package under the output directory, at the path of its canonical name.
+func (d *Driver) emit(pkg *Package) ([]string, error) {
+	decls := pkg.Decls()
+	dir := filepath.Join(d.Options.OutDir, filepath.FromSlash(pkg.Path))
+
+	var (
+		name string
+		src  []byte
+		err  error
+	)
+	switch d.Options.Output {
+	case OutputGo:
+		g := gogen.NewGenerator(pkg.Name)
+		src, err = g.Generate(decls)
+		if err != nil {
+			return nil, err
+		}
+		// Nodes the generator cannot emit fail the package, rather than leaving placeholders in the output.
+		failed := false
+		for _, diag := range d.Options.Severities.Apply(g.Diagnosis) {
+			pkg.report(diag)
+			failed = failed || diag.IsError()
+		}
+		for _, file := range pkg.Files {
+			diagnosis.Sort(file.Diagnosis)
+		}
+		if failed {
+			return nil, nil
+		}
+		name = pkg.Name + ".go"
+	case OutputC:
+		var g ffi.Generator
+		for _, decl := range decls {
+			if decl.Tag == ast.StmtExternDecl {
+				g.Add(decl.Value.(ast.ExternDecl))
+			}
+		}
+		src = g.Header()
+		name = pkg.Name + ".h"
+	default:
+		return nil, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, name)
+	return []string{path}, os.WriteFile(path, src, 0o644)
+}
+
+// Build runs the whole pipeline for every package under root.
+// Diagnostics are collected on the result, the error is reserved for failures preventing the build from completing.
+func (d *Driver) Build(root string) (Result, error) {
+	result, err := d.build(root)
+	return result, d.finished(err)
+}
+
+func (d *Driver) build(root string) (Result, error) {
+	pkgs, err := Collect(root, d.Options.Source)
+	if err != nil {
+		return Result{}, err
+	}
+
+	res, err := d.resolver(root)
+	if err != nil {
+		return Result{}, err
+	}
+	d.parse(pkgs, editionOf(res))
+
+	g, byName, err := graph(root, res, pkgs)
+	if err != nil {
+		return Result{}, err
+	}
+	if res != nil {
+		d.loadImports(res, &g, byName)
+	}
+	pkgs, err = order(g, byName)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Packages: pkgs}
+	err = d.compile(g, byName, pkgs, &result)
+	return result, err
+}
+
+// compile runs the plugins, the checker and the code generator on pkgs, which are parsed packages of the graph.
+// The artifacts are appended to result, which holds every package of the graph.
+func (d *Driver) compile(g loader.Graph, byName map[string]*Package, pkgs []*Package, result *Result) error {
+	if err := d.afterParse(pkgs); err != nil {
+		return err
+	}
+
+	selected := map[*Package]bool{}
+	for _, pkg := range pkgs {
+		selected[pkg] = true
+	}
+
+	// Packages are checked once their imports are, independent packages concurrently.
+	// Diagnostics stay on their files, so the result does not depend on the schedule.
+	err := g.Schedule(d.Options.Parallelism, func(name string) error {
+		pkg, ok := byName[name]
+		if !ok || !selected[pkg] {
+			return nil
+		}
+		d.run(profile.PhaseCheck, pkg.Dir, func() { d.check(pkg, byName) })
+		if err := d.afterCheck(pkg); err != nil {
+			return err
+		}
+		d.emitDiagnostics(pkg)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if result.HasErrors() {
+		return nil
+	}
+
+	for _, pkg := range pkgs {
+		artifacts, err := d.beforeCodegen(pkg)
+		result.addArtifacts(pkg, artifacts)
+		if err != nil {
+			return err
+		}
+		d.run(profile.PhaseCodegen, pkg.Dir, func() { artifacts, err = d.emit(pkg) })
+		if err != nil {
+			return err
+		}
+		if len(artifacts) != 0 {
+			d.emitEvent(Event{Kind: EventArtifacts, Package: pkg.Path, Artifacts: artifacts})
+		}
+		result.addArtifacts(pkg, artifacts)
+	}
+
+	return nil
+}
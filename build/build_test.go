@@ -0,0 +1,409 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package build
+
+import (
+	"cee"
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/token"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckConfusables(t *testing.T) {
+	fset := token.NewFileSet()
+	tok := fset.AddFile("a.cee", fset.Base(), 100)
+	ident := func(offset int, name string) ast.Ident {
+		return ast.Ident{Token: ast.Token{PosRange: ast.PosRange{From: tok.Pos(offset), To: tok.Pos(offset + 3)}, Kind: token.IDENT, Literal: name}}
+	}
+	fn := func(name ast.Ident, params ...ast.Ident) ast.Stmt {
+		decl := ast.FuncDecl{Ident: &name, Type: ast.FuncType{Params: []ast.GenDecl{{Idents: params}}}}
+		return ast.Stmt{Union: cee.Union[ast.StmtKind]{Tag: ast.StmtFuncDecl, Value: decl}}
+	}
+
+	// fun pay(x), fun pаy(pay), with a Cyrillic а
+	file := &File{Path: "a.cee", TokenFile: tok, Decls: []ast.Stmt{
+		fn(ident(0, "pay"), ident(10, "x")),
+		fn(ident(20, "pаy"), ident(30, "pay")),
+	}}
+	CheckConfusables(&Package{Files: []*File{file}})
+
+	if len(file.Diagnosis) != 2 {
+		t.Fatalf("diagnosis %v", file.Diagnosis)
+	}
+	for i, offset := range []int{20, 30} {
+		d := file.Diagnosis[i]
+		e, ok := d.Error.(diagnosis.ConfusableIdentError)
+		if !ok || d.Kind != diagnosis.ConfusableIdent || tok.Offset(e.Ident.From) != offset {
+			t.Errorf("diagnosis %d: %+v", i, d)
+		}
+	}
+}
+
+func TestQualifyFile(t *testing.T) {
+	fset := token.NewFileSet()
+	tok := fset.AddFile("a.cee", fset.Base(), 100)
+	ident := func(offset int, name string) ast.Ident {
+		return ast.Ident{Token: ast.Token{PosRange: ast.PosRange{From: tok.Pos(offset), To: tok.Pos(offset + 1)}, Kind: token.IDENT, Literal: name}}
+	}
+	expr := func(kind ast.ExprKind, value ast.Node) ast.Expr {
+		return ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: kind, Value: value}}
+	}
+	sel := func(x ast.Ident, member ast.Ident) ast.Expr {
+		return expr(ast.ExprMemberSelect, ast.MemberSelectExpr{Expr: expr(ast.ExprIdent, x), Member: member})
+	}
+
+	// import "m/fmt"; fun f(p) { fmt.print(p.x) }
+	imp := ast.ImportDecl{CanonicalName: ast.LiteralValue{Token: ast.Token{PosRange: ast.PosRange{From: tok.Pos(0), To: tok.Pos(7)}, Kind: token.STRING, Literal: `"m/fmt"`}}}
+	call := expr(ast.ExprCall, ast.CallExpr{Callee: sel(ident(10, "fmt"), ident(14, "print")), Params: []ast.Expr{sel(ident(20, "p"), ident(22, "x"))}})
+	name := ident(5, "f")
+	fn := ast.FuncDecl{
+		Ident: &name,
+		Type:  ast.FuncType{Params: []ast.GenDecl{{Idents: []ast.Ident{ident(7, "p")}}}},
+		Stmt:  &ast.StmtBlockExpr{Stmts: []ast.Stmt{{Union: cee.Union[ast.StmtKind]{Tag: ast.StmtExpr, Value: call}}}},
+	}
+	file := &File{Path: "a.cee", TokenFile: tok, Decls: []ast.Stmt{
+		{Union: cee.Union[ast.StmtKind]{Tag: ast.StmtImportDecl, Value: imp}},
+		{Union: cee.Union[ast.StmtKind]{Tag: ast.StmtFuncDecl, Value: fn}},
+	}}
+	original := file.Decls
+	QualifyFile(file)
+
+	qualified := file.Decls[1].Value.(ast.FuncDecl).Stmt.Stmts[0].Value.(ast.Expr).Value.(ast.CallExpr)
+	if q, ok := qualified.Callee.Value.(ast.QualifiedIdent); !ok || q.Package.Literal != "fmt" || q.Member.Literal != "print" {
+		t.Errorf("callee %+v", qualified.Callee)
+	}
+	if qualified.Params[0].Tag != ast.ExprMemberSelect {
+		t.Errorf("field selection rewritten to %+v", qualified.Params[0])
+	}
+	if original[1].Value.(ast.FuncDecl).Stmt.Stmts[0].Value.(ast.Expr).Value.(ast.CallExpr).Callee.Tag != ast.ExprMemberSelect {
+		t.Error("QualifyFile changed the original declarations")
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"main.cee", "lib/lib.cee"} {
+		if err := os.WriteFile(filepath.Join(root, path), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NewDriver(Options{Parallelism: 1, Confusables: true})
+	result, err := d.Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := d.Metadata(root, result)
+	if len(m.Packages) != 2 || len(m.Commands) != 2 {
+		t.Fatalf("packages %+v, commands %+v", m.Packages, m.Commands)
+	}
+	cmd := m.Commands[0]
+	if !filepath.IsAbs(cmd.File) || cmd.Package != m.Packages[0].Path || cmd.Directory != m.Packages[0].Directory {
+		t.Errorf("command %+v", cmd)
+	}
+	if got := strings.Join(cmd.Arguments, " "); !strings.Contains(got, "-o none") || !strings.Contains(got, "-confusables") {
+		t.Errorf("arguments %s", got)
+	}
+
+	var b strings.Builder
+	if err := WriteMetadata(&b, m); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Metadata
+	if err := json.Unmarshal([]byte(b.String()), &decoded); err != nil || decoded.Root != m.Root {
+		t.Errorf("round trip: %v, %+v", err, decoded)
+	}
+}
+
+// writeTree writes the files, by slash-separated path, under a temporary root.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for path, src := range files {
+		path = filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestBuild(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"app/main.cee": "package app\n\nimport \"lib\"\n\nfun run(x i64) i64 {\n\treturn lib.twice(x) + 1\n}\n",
+		"lib/lib.cee":  "package lib\n\npub fun twice(x i64) i64 {\n\treturn x * 2\n}\n",
+		"lib/more.cee": "package lib\n\nfun half(x i64) i64 {\n\treturn x / 2\n}\n",
+	})
+
+	var previous []string
+	for _, jobs := range []int{1, 4} {
+		out := t.TempDir()
+		d := NewDriver(Options{Output: OutputGo, OutDir: out, Parallelism: jobs})
+		result, err := d.Build(root)
+		if err != nil || result.HasErrors() {
+			var b strings.Builder
+			_ = WriteDiagnostics(&b, result, FormatText)
+			t.Fatalf("-j %d: %v\n%s", jobs, err, b.String())
+		}
+
+		// The imported package comes first.
+		var paths []string
+		for _, pkg := range result.Packages {
+			paths = append(paths, pkg.Path)
+			for _, file := range pkg.Files {
+				paths = append(paths, filepath.Base(file.Path))
+			}
+		}
+		if got := strings.Join(paths, " "); got != "lib lib.cee more.cee app main.cee" {
+			t.Errorf("-j %d: packages %s", jobs, got)
+		}
+
+		want := []string{filepath.Join(out, "lib", "lib.go"), filepath.Join(out, "app", "app.go")}
+		if !reflect.DeepEqual(result.Artifacts, want) || len(result.ByPackage["lib"]) != 1 {
+			t.Errorf("-j %d: artifacts %v", jobs, result.Artifacts)
+		}
+		src, err := os.ReadFile(want[0])
+		if err != nil || !strings.Contains(string(src), "func twice(x int64) int64") || !strings.Contains(string(src), "func half(") {
+			t.Errorf("-j %d: lib.go %v\n%s", jobs, err, src)
+		}
+
+		var generated []string
+		for _, path := range result.Artifacts {
+			src, _ := os.ReadFile(path)
+			generated = append(generated, string(src))
+		}
+		if previous != nil && !reflect.DeepEqual(generated, previous) {
+			t.Errorf("-j %d generated other sources", jobs)
+		}
+		previous = generated
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.cee": "package a\n\nfun f() {\n\tval x = (1 +\n}\n",
+	})
+	out := t.TempDir()
+	d := NewDriver(Options{Output: OutputGo, OutDir: out})
+	result, err := d.Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.HasErrors() || len(result.Artifacts) != 0 {
+		t.Fatalf("errors %v, artifacts %v", result.HasErrors(), result.Artifacts)
+	}
+
+	var text strings.Builder
+	if err := WriteDiagnostics(&text, result, FormatText); err != nil {
+		t.Fatal(err)
+	}
+	first, _, _ := strings.Cut(text.String(), "\n")
+	if want := filepath.Join(root, "a.cee") + ":5:1: "; !strings.HasPrefix(first, want) {
+		t.Errorf("text diagnostics %q, want prefix %q", text.String(), want)
+	}
+
+	var js strings.Builder
+	if err := WriteDiagnostics(&js, result, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	line, _, _ := strings.Cut(js.String(), "\n")
+	var entry diagnosticJSON
+	if err := json.Unmarshal([]byte(line), &entry); err != nil || entry.Line != 5 || entry.Column != 1 || entry.Severity != "error" {
+		t.Errorf("json diagnostics %s: %v", js.String(), err)
+	}
+
+	var page strings.Builder
+	if err := WriteDiagnostics(&page, result, FormatHTML); err != nil || !strings.Contains(page.String(), "<h2>") {
+		t.Errorf("html diagnostics %v\n%s", err, page.String())
+	}
+
+	for s, want := range map[string]DiagnosticsFormat{"text": FormatText, "json": FormatJSON, "html": FormatHTML, "xml": 0} {
+		if got, err := ParseDiagnosticsFormat(s); got != want || (err != nil) != (want == 0) {
+			t.Errorf("ParseDiagnosticsFormat(%q) = %v, %v", s, got, err)
+		}
+	}
+}
+
+func TestBuildFeatures(t *testing.T) {
+	d := NewDriver(Options{Parallelism: 1})
+	for name, src := range map[string]string{
+		"switch":    "fun f(x i64) i64 {\n\tswitch x {\n\tcase 0:\n\t\treturn 1\n\tdefault:\n\t\treturn 2\n\t}\n\treturn 0\n}\n",
+		"select":    "fun f(ch chan i64) {\n\tselect {\n\tcase ch <- 1:\n\t}\n\tch <- 2\n}\n",
+		"go":        "fun g(x i64) {}\n\nfun f() {\n\tgo g(1)\n}\n",
+		"composite": "type Point struct {\n\tx i64\n\ty i64\n}\n\nfun f() Point {\n\treturn Point{x: 1, y: 2}\n}\n",
+		"map":       "fun f() map[string]i64 {\n\treturn map[string]i64{\"a\": 1}\n}\n",
+		"closure":   "fun f(v i64) i64 {\n\tval h = fun [&v](y i64) i64 { return y + v }\n\treturn h(1)\n}\n",
+		"match":     "fun f(v i64) i64 {\n\tmatch v {\n\tcase 0: return 1\n\tcase _: return 2\n\t}\n\treturn 0\n}\n",
+	} {
+		root := writeTree(t, map[string]string{"a/a.cee": "package a\n\n" + src})
+		result, err := d.Build(root)
+		if err != nil || result.HasErrors() {
+			var b strings.Builder
+			_ = WriteDiagnostics(&b, result, FormatText)
+			t.Errorf("%s: %v\n%s", name, err, b.String())
+		}
+	}
+}
+
+func TestBuildLiterals(t *testing.T) {
+	// Package-level initializers are checked as those of functions.
+	root := writeTree(t, map[string]string{
+		"a/a.cee": "package a\n\nval x u8 = 300\nvar y u8 = 255\n\nfun f() {\n\tval z u8 = 256\n}\n",
+	})
+	d := NewDriver(Options{Parallelism: 1})
+	result, err := d.Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text strings.Builder
+	if err := WriteDiagnostics(&text, result, FormatText); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(root, "a", "a.cee")
+	if want := path + ":3:12: 300 overflows u8\n" + path + ":7:13: 256 overflows u8\n"; text.String() != want {
+		t.Errorf("text diagnostics %q, want %q", text.String(), want)
+	}
+}
+
+func TestBuildPackageName(t *testing.T) {
+	// The package clause names the package, whatever the directory and the root.
+	root := writeTree(t, map[string]string{
+		"lib/a.cee": "package util\n\nfun f() i64 {\n\treturn 1\n}\n",
+	})
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join(root, "lib")); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	out := t.TempDir()
+	d := NewDriver(Options{Output: OutputGo, OutDir: out, Parallelism: 1})
+	result, err := d.Build(".")
+	if err != nil || result.HasErrors() || len(result.Artifacts) != 1 {
+		t.Fatalf("%v, artifacts %v", err, result.Artifacts)
+	}
+	src, err := os.ReadFile(result.Artifacts[0])
+	if err != nil || filepath.Base(result.Artifacts[0]) != "util.go" || !strings.HasPrefix(string(src), "package util\n") {
+		t.Errorf("%s: %v\n%s", result.Artifacts[0], err, src)
+	}
+
+	// Nodes the generator cannot emit are reported, and nothing is written.
+	if err := os.WriteFile("a.cee", []byte("package util\n\nfun f(v i64) {\n\tmatch v {\n\tcase _: f(0)\n\t}\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out = t.TempDir()
+	d = NewDriver(Options{Output: OutputGo, OutDir: out, Parallelism: 1})
+	if result, err = d.Build("."); err != nil {
+		t.Fatal(err)
+	}
+	var text strings.Builder
+	if err := WriteDiagnostics(&text, result, FormatText); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a.cee:4:2: lowering error: unsupported node\n"; text.String() != want || len(result.Artifacts) != 0 {
+		t.Errorf("text diagnostics %q, want %q, artifacts %v", text.String(), want, result.Artifacts)
+	}
+}
+
+func TestBuildMacros(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a/a.cee": "package a\n\nmacro twice(x) { x + x }\n\nfun f(y i64) i64 {\n\tval z = twice!(y)\n\treturn twice!(z)\n}\n",
+	})
+	out := t.TempDir()
+	d := NewDriver(Options{Output: OutputGo, OutDir: out, Validate: true})
+	result, err := d.Build(root)
+	if err != nil || result.HasErrors() {
+		var b strings.Builder
+		_ = WriteDiagnostics(&b, result, FormatText)
+		t.Fatalf("%v\n%s", err, b.String())
+	}
+	src, err := os.ReadFile(result.Artifacts[0])
+	if err != nil || !strings.Contains(string(src), "z := (y + y)") || strings.Contains(string(src), "unsupported") {
+		t.Errorf("%v\n%s", err, src)
+	}
+
+	// A diagnostic in expanded code is positioned at the invocation and names the token of the body.
+	root = writeTree(t, map[string]string{
+		"b/b.cee": "package b\n\nmacro big() {\n\t300\n}\n\nfun g() {\n\tvar x u8 = big!()\n}\n",
+	})
+	if result, err = d.Build(root); err != nil {
+		t.Fatal(err)
+	}
+	var text strings.Builder
+	if err := WriteDiagnostics(&text, result, FormatText); err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(root, "b", "b.cee") + ":8:13: 300 overflows u8 (in expansion of big!, from 4:2)\n"; text.String() != want {
+		t.Errorf("text diagnostics %q, want %q", text.String(), want)
+	}
+	var js strings.Builder
+	if err := WriteDiagnostics(&js, result, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var entry diagnosticJSON
+	if err := json.Unmarshal([]byte(js.String()), &entry); err != nil || entry.Expansion == nil || *entry.Expansion != (expansionJSON{Macro: "big", Line: 4, Column: 2}) {
+		t.Errorf("json diagnostics %s: %v", js.String(), err)
+	}
+}
+
+func TestCheckImports(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"lib/lib.cee": "package lib\n\npub fun shown() {}\n\nfun hidden() {}\n",
+		"app/app.cee": `package app
+
+import "lib"
+import "missing"
+import run "lib"
+
+fun missing() {}
+
+fun run() {
+	lib.shown()
+	lib.hidden()
+	lib.absent()
+}
+`,
+	})
+	d := NewDriver(Options{})
+	result, err := d.Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, pkg := range result.Packages {
+		for _, file := range pkg.Files {
+			for _, entry := range entries(file) {
+				got = append(got, fmt.Sprintf("%s:%d:%d: %s", pkg.Path, entry.Line, entry.Column, entry.Message))
+			}
+		}
+	}
+	want := []string{
+		"app:4:8: import error: cannot resolve package: missing",
+		"app:4:8: import error: import name collides with a declaration: missing",
+		"app:5:8: import error: import name collides with a declaration: run",
+		"app:11:6: import error: member of package lib is not pub: hidden",
+		"app:12:6: import error: undeclared member of package lib: absent",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diagnostics\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package build
+
+import (
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/highlight"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+type DiagnosticsFormat byte
+
+const (
+	_ DiagnosticsFormat = iota
+
+	FormatText
+	FormatJSON
+	FormatHTML // a page showing the highlighted source of every file with diagnostics
+)
+
+func ParseDiagnosticsFormat(s string) (DiagnosticsFormat, error) {
+	switch s {
+	case "text":
+		return FormatText, nil
+	case "json":
+		return FormatJSON, nil
+	case "html":
+		return FormatHTML, nil
+	}
+	return 0, fmt.Errorf("unknown diagnostics format: %s", s)
+}
+
+type diagnosticJSON struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"` // one-based, 0 if the diagnostic has no position
+	Column   int    `json:"column,omitempty"`
+	Kind     int    `json:"kind"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+
+	Expansion *expansionJSON `json:"expansion,omitempty"` // set for diagnostics in expanded code, positioned at their source
+}
+
+// expansionJSON names the macro a diagnostic was expanded from, the outermost one of nested invocations.
+type expansionJSON struct {
+	Macro  string `json:"macro"`
+	Line   int    `json:"line,omitempty"` // of the token in the macro body, 0 for a token of an argument
+	Column int    `json:"column,omitempty"`
+}
+
+func (entry diagnosticJSON) String() string {
+	message := entry.Message
+	if entry.Severity != diagnosis.SeverityError.String() {
+		message = entry.Severity + ": " + message
+	}
+	if x := entry.Expansion; x != nil {
+		message += " (in expansion of " + x.Macro + "!"
+		if x.Line != 0 {
+			message += fmt.Sprint(", from ", x.Line, ":", x.Column)
+		}
+		message += ")"
+	}
+	if entry.Line == 0 {
+		return fmt.Sprint(entry.File, ": ", message)
+	}
+	return fmt.Sprint(entry.File, ":", entry.Line, ":", entry.Column, ": ", message)
+}
+
+func message(v any) string {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	return fmt.Sprint(v)
+}
+
+// entries lists the fatal error and the diagnostics of a file.
+func entries(file *File) []diagnosticJSON {
+	var entries []diagnosticJSON
+	if file.Err != nil {
+		entries = append(entries, diagnosticJSON{File: file.Path, Severity: diagnosis.SeverityError.String(), Message: file.Err.Error()})
+	}
+	for _, d := range file.Diagnosis {
+		entry := diagnosticJSON{File: file.Path, Kind: d.Kind, Severity: d.Severity.String(), Message: message(d.Error)}
+		if node, ok := d.Error.(ast.Node); ok && file.TokenFile != nil {
+			at, x, body := file.Macros.Source(node.GetPosRange().From)
+			pos := file.TokenFile.Position(at)
+			entry.Line, entry.Column = pos.Line+1, pos.Column+1
+			if x != nil {
+				entry.Expansion = &expansionJSON{Macro: x.Macro.Ident.Literal}
+				if body.IsValid() {
+					pos := file.TokenFile.Position(body)
+					entry.Expansion.Line, entry.Expansion.Column = pos.Line+1, pos.Column+1
+				}
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// WriteDiagnostics prints the diagnostics and fatal errors of all files, one per line.
+func WriteDiagnostics(w io.Writer, result Result, format DiagnosticsFormat) error {
+	if format == FormatHTML {
+		return writeHTML(w, result)
+	}
+	enc := json.NewEncoder(w)
+
+	for _, pkg := range result.Packages {
+		for _, file := range pkg.Files {
+			for _, entry := range entries(file) {
+				var err error
+				if format == FormatJSON {
+					err = enc.Encode(entry)
+				} else {
+					_, err = fmt.Fprintln(w, entry)
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeHTML renders the files with diagnostics, positioned diagnostics are marked in the source
+// and the others listed above it.
+func writeHTML(w io.Writer, result Result) error {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Diagnostics</title>\n<style>\n")
+	b.WriteString(highlight.Stylesheet)
+	b.WriteString("</style>\n</head>\n<body>\n")
+
+	for _, pkg := range result.Packages {
+		for _, file := range pkg.Files {
+			if file.Err == nil && len(file.Diagnosis) == 0 {
+				continue
+			}
+			b.WriteString("<h2>" + html.EscapeString(file.Path) + "</h2>\n")
+			if file.Err != nil {
+				b.WriteString("<p>" + html.EscapeString(file.Err.Error()) + "</p>\n")
+			}
+
+			var markers []highlight.Marker
+			for _, d := range file.Diagnosis {
+				node, ok := d.Error.(ast.Node)
+				if !ok || file.TokenFile == nil {
+					b.WriteString("<p>" + html.EscapeString(message(d.Error)) + "</p>\n")
+					continue
+				}
+				pos, msg := node.GetPosRange(), message(d.Error)
+				if _, x, _ := file.Macros.Source(pos.From); x != nil {
+					// Expanded code is marked at the invocation.
+					pos, msg = x.Call, msg+" (in expansion of "+x.Macro.Ident.Literal+"!)"
+				}
+				markers = append(markers, highlight.Marker{
+					Range:   highlight.Range{From: file.TokenFile.Position(pos.From), To: file.TokenFile.Position(pos.To)},
+					Message: msg,
+				})
+			}
+			if file.TokenFile != nil && file.TokenFile.Content() != nil {
+				if err := highlight.HTML(&b, file.TokenFile.Content(), markers); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	b.WriteString("</body>\n</html>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,8 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+// Package build
+// Build driver orchestrating parsing, lowering and code generation for a whole package tree.
+// Files are parsed and packages checked concurrently, results are ordered deterministically, see Result.
+package build
@@ -0,0 +1,96 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package build
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+type EventKind string
+
+const (
+	EventFileStarted EventKind = "file-started" // File is about to be read and parsed
+	EventPhase       EventKind = "phase"        // Phase ran on Unit, a file or a package directory, for Duration
+	EventDiagnostics EventKind = "diagnostics"  // the final diagnostics of File, sent once the package is checked
+	EventArtifacts   EventKind = "artifacts"    // Artifacts were written for Package
+	EventFinished    EventKind = "finished"     // the build is over, Error is set when it could not complete
+)
+
+// Event is a step of a build, fields not relevant to its Kind are left empty.
+type Event struct {
+	Kind        EventKind        `json:"kind"`
+	Time        time.Time        `json:"time"`
+	File        string           `json:"file,omitempty"`
+	Package     string           `json:"package,omitempty"` // canonical name
+	Phase       string           `json:"phase,omitempty"`
+	Unit        string           `json:"unit,omitempty"`
+	Duration    time.Duration    `json:"duration,omitempty"` // in nanoseconds
+	Diagnostics []diagnosticJSON `json:"diagnostics,omitempty"`
+	Artifacts   []string         `json:"artifacts,omitempty"`
+	Error       string           `json:"error,omitempty"`
+}
+
+// EventSink receives the events of builds as they happen, it must be safe for concurrent use.
+type EventSink interface {
+	Event(e Event)
+}
+
+// EventWriter writes events as newline-delimited JSON, one object per line.
+// Errors of the writer are kept, later events are dropped.
+type EventWriter struct {
+	mutex sync.Mutex
+	enc   *json.Encoder
+	err   error
+}
+
+func NewEventWriter(w io.Writer) *EventWriter { return &EventWriter{enc: json.NewEncoder(w)} }
+
+func (w *EventWriter) Event(e Event) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.err == nil {
+		w.err = w.enc.Encode(e)
+	}
+}
+
+// Err returns the first error of the writer.
+func (w *EventWriter) Err() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.err
+}
+
+// emitEvent sends e to the sink of the options, stamping it with the current time.
+func (d *Driver) emitEvent(e Event) {
+	if d.Options.Events != nil {
+		e.Time = time.Now()
+		d.Options.Events.Event(e)
+	}
+}
+
+// emitDiagnostics sends the diagnostics of every file of pkg with any.
+func (d *Driver) emitDiagnostics(pkg *Package) {
+	if d.Options.Events == nil {
+		return
+	}
+	for _, file := range pkg.Files {
+		if entries := entries(file); len(entries) != 0 {
+			d.emitEvent(Event{Kind: EventDiagnostics, File: file.Path, Package: pkg.Path, Diagnostics: entries})
+		}
+	}
+}
+
+// finished sends the end of a build and passes err through.
+func (d *Driver) finished(err error) error {
+	e := Event{Kind: EventFinished}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	d.emitEvent(e)
+	return err
+}
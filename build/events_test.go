@@ -0,0 +1,58 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package build
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEvents(t *testing.T) {
+	root := t.TempDir()
+	for _, path := range []string{"a.cee", "b.cee"} {
+		if err := os.WriteFile(filepath.Join(root, path), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	w := NewEventWriter(&buf)
+	d := NewDriver(Options{Events: w})
+	if _, err := d.Build(root); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []Event
+	lines := bufio.NewScanner(&buf)
+	for lines.Scan() {
+		var e Event
+		if err := json.Unmarshal(lines.Bytes(), &e); err != nil {
+			t.Fatalf("%q: %v", lines.Text(), err)
+		}
+		events = append(events, e)
+	}
+
+	count := map[EventKind]int{}
+	phases := map[string]int{}
+	for _, e := range events {
+		count[e.Kind]++
+		if e.Kind == EventPhase {
+			phases[e.Phase]++
+		}
+	}
+	if count[EventFileStarted] != 2 || phases["parse"] != 2 || phases["check"] != 1 {
+		t.Errorf("events %v, phases %v", count, phases)
+	}
+	if last := events[len(events)-1]; last.Kind != EventFinished || last.Error != "" {
+		t.Errorf("last event %+v", last)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package build
+
+import (
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/resolve"
+	"cee/token"
+)
+
+// declaredNames returns the names a top-level declaration binds, imports excluded.
+func declaredNames(decl ast.Stmt) []ast.Ident {
+	switch d := decl.Value.(type) {
+	case ast.TypeDecl:
+		return []ast.Ident{d.Ident}
+	case ast.FuncDecl:
+		if d.Ident != nil {
+			return []ast.Ident{*d.Ident}
+		}
+	case ast.ExternDecl:
+		return []ast.Ident{d.Ident}
+	case ast.GenDecl:
+		return d.Idents
+	case ast.ValDecl:
+		return d.Idents()
+	}
+	return nil
+}
+
+// members indexes the top-level declarations of a package by name.
+func members(pkg *Package) map[string]ast.Stmt {
+	decls := map[string]ast.Stmt{}
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			for _, ident := range declaredNames(decl) {
+				decls[ident.Literal] = decl
+			}
+		}
+	}
+	return decls
+}
+
+// CheckImports reports imports of packages missing from the build, import names colliding with
+// declarations of the package, and members of imported packages that are undeclared or not pub.
+// byName holds the packages of the build by canonical name.
+func CheckImports(pkg *Package, byName map[string]*Package) {
+	own := members(pkg)
+	imported := map[string]map[string]ast.Stmt{}
+
+	for _, file := range pkg.Files {
+		if file.TokenFile == nil {
+			continue
+		}
+		report := func(d diagnosis.Diagnosis) { file.Diagnosis = append(file.Diagnosis, d) }
+
+		for _, decl := range file.Decls {
+			d, ok := decl.Value.(ast.ImportDecl)
+			if !ok {
+				continue
+			}
+			name, err := token.Unquote(d.CanonicalName.Literal)
+			if err != nil {
+				continue
+			}
+			if _, ok := byName[name]; !ok {
+				report(diagnosis.Diagnosis{
+					Kind:  diagnosis.UnresolvedImport,
+					Error: diagnosis.UnresolvedImportError{Decl: d, Name: name},
+				})
+			}
+			if local := resolve.ImportName(d).Literal; local != "_" {
+				if _, ok := own[local]; ok {
+					report(diagnosis.Diagnosis{
+						Kind:  diagnosis.ImportCollision,
+						Error: diagnosis.ImportCollisionError{Decl: d, Name: local},
+					})
+				}
+			}
+		}
+
+		info := resolve.Resolve([]resolve.File{{Path: file.Path, TokenFile: file.TokenFile, Decls: file.Decls}})
+		for _, sel := range info.Selections {
+			name, err := token.Unquote(sel.Import.Decl.(ast.ImportDecl).CanonicalName.Literal)
+			if err != nil {
+				continue
+			}
+			dep, ok := byName[name]
+			if !ok {
+				continue
+			}
+			if imported[name] == nil {
+				imported[name] = members(dep)
+			}
+
+			decl, ok := imported[name][sel.Member.Literal]
+			switch {
+			case !ok:
+				report(diagnosis.Diagnosis{
+					Kind:  diagnosis.UndeclaredMember,
+					Error: diagnosis.UndeclaredMemberError{Member: sel.Member, Package: name},
+				})
+			case !decl.Pub:
+				report(diagnosis.Diagnosis{
+					Kind:  diagnosis.UnexportedMember,
+					Error: diagnosis.UnexportedMemberError{Member: sel.Member, Package: name},
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package build
+
+import (
+	"cee/diagnosis"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Metadata describes a build for external indexers, in the spirit of compile_commands.json:
+// one command per source file, and the packages with their dependency edges and artifacts.
+// Paths are absolute.
+type Metadata struct {
+	Root     string            `json:"root"`
+	Edition  string            `json:"edition,omitempty"`
+	Options  MetadataOptions   `json:"options"`
+	Commands []CompileCommand  `json:"commands"`
+	Packages []PackageMetadata `json:"packages"`
+}
+
+// MetadataOptions are the options changing how sources are compiled.
+type MetadataOptions struct {
+	Output      string            `json:"output"`
+	OutDir      string            `json:"outDir,omitempty"`
+	Cfg         map[string]string `json:"cfg"`
+	MaxErrors   int               `json:"maxErrors,omitempty"`
+	Confusables bool              `json:"confusables,omitempty"`
+	Severities  map[string]string `json:"severities,omitempty"`
+}
+
+// CompileCommand is the entry of a source file, Arguments rebuild the project of the file with `cee build`.
+type CompileCommand struct {
+	Directory string   `json:"directory"`
+	File      string   `json:"file"`
+	Package   string   `json:"package"`
+	Arguments []string `json:"arguments"`
+}
+
+type PackageMetadata struct {
+	Path      string   `json:"path"` // canonical name
+	Name      string   `json:"name"`
+	Directory string   `json:"directory"`
+	Files     []string `json:"files"`
+	Imports   []string `json:"imports"` // canonical names, sorted
+	Artifacts []string `json:"artifacts,omitempty"`
+}
+
+func abs(path string) string {
+	if p, err := filepath.Abs(path); err == nil {
+		return p
+	}
+	return path
+}
+
+// Arguments returns the `cee build` flags reproducing the compile options, flags at their default omitted.
+func (o Options) Arguments() []string {
+	args := []string{"-o", o.Output.String()}
+	if o.Output != OutputNone && o.OutDir != "" {
+		args = append(args, "-out", o.OutDir)
+	}
+	if len(o.Cfg) != 0 {
+		pairs := make([]string, 0, len(o.Cfg))
+		for key, value := range o.Cfg {
+			pairs = append(pairs, key+"="+value)
+		}
+		sort.Strings(pairs)
+		args = append(args, "-cfg", strings.Join(pairs, ","))
+	}
+	if o.MaxErrors != 0 {
+		args = append(args, "-maxerrors", strconv.Itoa(o.MaxErrors))
+	}
+	if o.Confusables {
+		args = append(args, "-confusables")
+	}
+	if severities := o.severityNames(); len(severities) != 0 {
+		pairs := make([]string, 0, len(severities))
+		for kind, severity := range severities {
+			pairs = append(pairs, kind+"="+severity)
+		}
+		sort.Strings(pairs)
+		args = append(args, "-severity", strings.Join(pairs, ","))
+	}
+	return args
+}
+
+func (o Options) severityNames() map[string]string {
+	if len(o.Severities) == 0 {
+		return nil
+	}
+	names := map[string]string{}
+	for name, kind := range diagnosis.KindNames {
+		if severity, ok := o.Severities[kind]; ok {
+			names[name] = severity.String()
+		}
+	}
+	return names
+}
+
+// Metadata describes the result of building root with the driver.
+func (d *Driver) Metadata(root string, result Result) Metadata {
+	res, _ := d.resolver(root)
+	m := Metadata{
+		Root:    abs(root),
+		Edition: editionOf(res),
+		Options: MetadataOptions{
+			Output:      d.Options.Output.String(),
+			Cfg:         d.Options.Cfg,
+			MaxErrors:   d.Options.MaxErrors,
+			Confusables: d.Options.Confusables,
+			Severities:  d.Options.severityNames(),
+		},
+		Commands: []CompileCommand{},
+		Packages: []PackageMetadata{},
+	}
+	if d.Options.Output != OutputNone {
+		m.Options.OutDir = abs(d.Options.OutDir)
+	}
+	args := d.Options.Arguments()
+
+	for _, pkg := range result.Packages {
+		dir := abs(pkg.Dir)
+		meta := PackageMetadata{
+			Path:      pkg.Path,
+			Name:      pkg.Name,
+			Directory: dir,
+			Files:     []string{},
+			Imports:   []string{},
+			Artifacts: result.ByPackage[pkg.Path],
+		}
+		seen := map[string]bool{}
+		for _, imp := range importsOf(pkg) {
+			if !seen[imp] {
+				seen[imp] = true
+				meta.Imports = append(meta.Imports, imp)
+			}
+		}
+		sort.Strings(meta.Imports)
+		for _, file := range pkg.Files {
+			path := abs(file.Path)
+			meta.Files = append(meta.Files, path)
+			m.Commands = append(m.Commands, CompileCommand{
+				Directory: dir,
+				File:      path,
+				Package:   pkg.Path,
+				Arguments: append(append([]string{"cee", "build"}, args...), m.Root),
+			})
+		}
+		m.Packages = append(m.Packages, meta)
+	}
+	return m
+}
+
+// WriteMetadata writes the metadata as indented JSON.
+func WriteMetadata(w io.Writer, m Metadata) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package build
+
+import (
+	"cee/format"
+	"cee/resolve"
+	"fmt"
+)
+
+// renamable lists the objects of the package that are visible nowhere else: the locals of function bodies and
+// the top-level types, functions and variables that are not pub. Entry points and declarations with attributes,
+// which may name them for the outside, keep their names.
+func renamable(pkg *Package, info resolve.Info) []*resolve.Object {
+	var objs []*resolve.Object
+	for _, scopes := range info.Scopes {
+		for _, scope := range scopes {
+			for _, obj := range scope.Objects {
+				objs = append(objs, obj)
+			}
+		}
+	}
+
+	for _, obj := range info.Package.Objects {
+		switch obj.Kind {
+		case resolve.ObjType, resolve.ObjFunc, resolve.ObjVar:
+		default:
+			continue
+		}
+		if obj.Name == "main" {
+			continue
+		}
+		exported := false
+		for _, file := range pkg.Files {
+			if file.Path != obj.File {
+				continue
+			}
+			for _, decl := range file.Decls {
+				if decl.GetPosRange().Contains(obj.Ident.From) && (decl.Pub || len(decl.Attrs) != 0) {
+					exported = true
+				}
+			}
+		}
+		if !exported {
+			objs = append(objs, obj)
+		}
+	}
+	return objs
+}
+
+// Minify minifies the sources of the files of a parsed package, see format.Minify.
+// With rename set, the identifiers visible nowhere else than in the package get the shortest free names.
+func Minify(pkg *Package, sources [][]byte, rename bool) ([][]byte, error) {
+	if len(sources) != len(pkg.Files) {
+		return nil, fmt.Errorf("minify: %d sources for %d files", len(sources), len(pkg.Files))
+	}
+
+	var names map[resolve.Ref]string
+	if rename {
+		var files []resolve.File
+		for _, file := range pkg.Files {
+			if file.TokenFile == nil || file.Err != nil {
+				return nil, fmt.Errorf("minify: %s was not parsed", file.Path)
+			}
+			files = append(files, resolve.File{Path: file.Path, TokenFile: file.TokenFile, Decls: file.Decls})
+		}
+		info := resolve.Resolve(files)
+		short := resolve.ShortNames(info, renamable(pkg, info))
+		names = map[resolve.Ref]string{}
+		for _, refs := range []map[resolve.Ref]*resolve.Object{info.Defs, info.Uses} {
+			for ref, obj := range refs {
+				if name, ok := short[obj]; ok {
+					names[ref] = name
+				}
+			}
+		}
+	}
+
+	result := make([][]byte, len(sources))
+	for i, src := range sources {
+		var opts format.MinifyOptions
+		if names != nil {
+			path := pkg.Files[i].Path
+			opts.Rename = func(offset int) (string, bool) {
+				name, ok := names[resolve.Ref{File: path, Offset: offset}]
+				return name, ok
+			}
+		}
+		out, err := format.Minify(src, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pkg.Files[i].Path, err)
+		}
+		result[i] = out
+	}
+	return result, nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package build
+
+import (
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/parser"
+	"cee/token"
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// FileDiagnosis is a diagnostic with the file it was reported in, which decodes its position.
+type FileDiagnosis struct {
+	File *File
+	diagnosis.Diagnosis
+}
+
+// Decls returns the declarations of every file, in file order. Files with a fatal error contribute
+// the declarations parsed before it.
+func (pkg *Package) Decls() []ast.Stmt {
+	var decls []ast.Stmt
+	for _, file := range pkg.Files {
+		decls = append(decls, file.Decls...)
+	}
+	return decls
+}
+
+// Diagnosis merges the diagnostics of the files, ordered by file path, then position.
+func (pkg *Package) Diagnosis() []FileDiagnosis {
+	var diags []FileDiagnosis
+	for _, file := range pkg.Files {
+		for _, d := range file.Diagnosis {
+			diags = append(diags, FileDiagnosis{File: file, Diagnosis: d})
+		}
+	}
+	return diags
+}
+
+// report adds the diagnostic to the file containing the position of its node.
+func (pkg *Package) report(d diagnosis.Diagnosis) {
+	pos := d.Error.(ast.Node).GetPosRange().From
+	for _, file := range pkg.Files {
+		if f := file.TokenFile; f != nil && int(pos) >= f.Base() && int(pos) <= f.Base()+f.Size() {
+			file.Diagnosis = append(file.Diagnosis, d)
+			return
+		}
+	}
+}
+
+// Errors returns the fatal errors of the files, e.g. I/O or scanner errors.
+func (pkg *Package) Errors() []error {
+	var errs []error
+	for _, file := range pkg.Files {
+		if file.Err != nil {
+			errs = append(errs, file.Err)
+		}
+	}
+	return errs
+}
+
+// HasErrors reports whether a file has a fatal error or a diagnostic.
+func (pkg *Package) HasErrors() bool {
+	for _, file := range pkg.Files {
+		if file.Err != nil {
+			return true
+		}
+		for _, d := range file.Diagnosis {
+			if d.IsError() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ParsePackage parses the source files directly in dir that filter, if not nil, accepts, like Build does,
+// with the source, target and cache of the driver, in the edition of the enclosing module, if any. It is the
+// entry point of the package-level ParsePackage and ParseDir.
+// Failures of single files are kept on them, the error is reserved for dir itself.
+func (d *Driver) ParsePackage(dir string, filter func(fs.FileInfo) bool) (*Package, error) {
+	pkg, err := collectDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if filter != nil {
+		files := pkg.Files[:0]
+		for _, file := range pkg.Files {
+			info, err := os.Stat(file.Path)
+			if err != nil {
+				return nil, err
+			}
+			if filter(info) {
+				files = append(files, file)
+			}
+		}
+		pkg.Files = files
+	}
+	res, err := d.resolver(dir)
+	if err != nil {
+		return nil, err
+	}
+	if res != nil {
+		if pkg.Path, err = res.Name(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	d.parse([]*Package{pkg}, editionOf(res))
+	return pkg, nil
+}
+
+// ParsePackage parses the source files directly in dir from the disk, for the host.
+func ParsePackage(dir string) (*Package, error) {
+	d := NewDriver(Options{})
+	return d.ParsePackage(dir, nil)
+}
+
+// ParseDir parses the source files directly in dir whose build constraint holds for the host and that filter,
+// if not nil, accepts, allocating their positions in fset. It mirrors go/parser.ParseDir for tools that need
+// the syntax only: the files are assembled by parser.NewPackage, the package being named by the directory
+// when no file has a package clause. The package is returned with every file, the error joins the failures
+// and diagnostics of the files and the mismatched clauses, each formatted as path:line:column: message.
+func ParseDir(fset *token.FileSet, dir string, filter func(fs.FileInfo) bool) (*ast.Package, error) {
+	d := NewDriver(Options{})
+	d.FileSet = fset
+	pkg, err := d.ParsePackage(dir, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		errs  []error
+		files = make([]*ast.File, 0, len(pkg.Files))
+	)
+	for _, file := range pkg.Files {
+		f := &ast.File{
+			Name:     file.Path,
+			Package:  file.Package,
+			Imports:  ast.Imports(file.Decls),
+			Decls:    file.Decls,
+			Comments: file.Comments,
+		}
+		if file.TokenFile != nil {
+			f.PosRange = ast.PosRange{From: file.TokenFile.Pos(0), To: file.TokenFile.Pos(file.TokenFile.Size())}
+		}
+		files = append(files, f)
+		for _, entry := range entries(file) {
+			errs = append(errs, errors.New(entry.String()))
+		}
+	}
+	syntax, err := parser.NewPackage(fset, files)
+	if syntax.Name == "" {
+		syntax.Name = pkg.Name
+	}
+	return syntax, errors.Join(append(errs, err)...)
+}
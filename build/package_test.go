@@ -0,0 +1,97 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package build
+
+import (
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/parser"
+	"cee/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePackage(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.cee":      "",
+		"b.cee":      "\xff\xfe",
+		"notes.txt":  "not a source file",
+		"c_test.cee": "",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pkg, err := ParsePackage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkg.Files) != 3 || pkg.Files[1].Path != filepath.Join(dir, "b.cee") {
+		t.Fatalf("files %v", pkg.Files)
+	}
+	if errs := pkg.Errors(); len(errs) != 0 || !pkg.HasErrors() {
+		t.Errorf("errors %v", errs)
+	}
+	// The invalid bytes are reported together, ahead of the diagnostics of the parser.
+	diags := pkg.Diagnosis()
+	if len(diags) == 0 || diags[0].File != pkg.Files[1] || diags[0].Kind != diagnosis.InvalidEncoding {
+		t.Errorf("diagnosis %v", diags)
+	}
+	// The undecodable input is kept as bad declarations, the empty files produce none.
+	for _, decl := range pkg.Decls() {
+		if decl.Tag != ast.StmtBadDecl {
+			t.Errorf("unexpected declaration %v", decl)
+		}
+	}
+
+	if _, err := ParsePackage(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+
+	fset := token.NewFileSet()
+	syntax, err := ParseDir(fset, dir, func(info fs.FileInfo) bool { return info.Name() != "b.cee" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(syntax.Files) != 2 || syntax.Files[filepath.Join(dir, "a.cee")] == nil || syntax.Name != filepath.Base(dir) {
+		t.Errorf("package %+v", syntax)
+	}
+	if fset.Base() == 1 {
+		t.Error("positions were not allocated in the file set")
+	}
+
+	// The clauses are checked as parser.ParsePackage checks them.
+	other := t.TempDir()
+	for name, content := range map[string]string{"a.cee": "package a\n", "b.cee": "package b\n"} {
+		if err := os.WriteFile(filepath.Join(other, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	syntax, err = ParseDir(token.NewFileSet(), other, nil)
+	if syntax.Name != "a" || err == nil || !strings.Contains(err.Error(), "package b, want a") {
+		t.Errorf("package %q, err = %v", syntax.Name, err)
+	}
+}
+
+func TestParseFileMaxErrors(t *testing.T) {
+	src := []byte("\x00 a \x00 b \x00 c \x00")
+	f := ParseFile(token.NewFileSet(), "a.cee", src, parser.Options{MaxErrors: 2})
+	if f.Err != nil {
+		t.Fatal(f.Err)
+	}
+	if len(f.Diagnosis) != 3 || f.Diagnosis[2].Kind != diagnosis.TooManyErrors {
+		t.Fatalf("diagnosis %v", f.Diagnosis)
+	}
+	skipped := f.Diagnosis[2].Error.(diagnosis.TooManyErrorsError)
+	// The budget is spent by the second NUL, which is reported before the parser scans anything.
+	if f.TokenFile.Offset(skipped.From) != 5 || f.TokenFile.Offset(skipped.To) != len(src) {
+		t.Errorf("skipped %v", skipped.PosRange)
+	}
+}
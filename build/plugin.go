@@ -0,0 +1,94 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package build
+
+import "fmt"
+
+// Plugin attaches an analysis or a code generator to the pipeline. Embed BasePlugin to implement
+// only some hooks. Diagnostics are reported by appending to the files of the package, errors abort the build.
+type Plugin interface {
+	Name() string
+
+	// AfterParse runs once every package is parsed, with the packages in dependency order.
+	AfterParse(d *Driver, pkgs []*Package) error
+
+	// AfterCheck runs once a package is checked. Hooks of independent packages run concurrently.
+	AfterCheck(d *Driver, pkg *Package) error
+
+	// BeforeCodegen runs before the artifacts of a package are emitted, when the build has no errors.
+	// It may rewrite the declarations, and returns the paths of the artifacts it wrote itself.
+	BeforeCodegen(d *Driver, pkg *Package) ([]string, error)
+}
+
+// BasePlugin implements every hook of Plugin but Name as a no-op.
+type BasePlugin struct{}
+
+func (BasePlugin) AfterParse(*Driver, []*Package) error { return nil }
+
+func (BasePlugin) AfterCheck(*Driver, *Package) error { return nil }
+
+func (BasePlugin) BeforeCodegen(*Driver, *Package) ([]string, error) { return nil, nil }
+
+type DuplicatePluginError struct {
+	Name string
+}
+
+func (e DuplicatePluginError) Error() string { return fmt.Sprint("build: duplicate plugin: ", e.Name) }
+
+// PluginError is an error returned by a hook.
+type PluginError struct {
+	Plugin, Hook string
+	Err          error
+}
+
+func (e PluginError) Error() string {
+	return fmt.Sprint("build: plugin ", e.Plugin, ": ", e.Hook, ": ", e.Err)
+}
+
+func (e PluginError) Unwrap() error { return e.Err }
+
+// Register adds a plugin to the driver, hooks run in registration order.
+func (d *Driver) Register(p Plugin) error {
+	for _, registered := range d.plugins {
+		if registered.Name() == p.Name() {
+			return DuplicatePluginError{Name: p.Name()}
+		}
+	}
+	d.plugins = append(d.plugins, p)
+	return nil
+}
+
+// Plugins returns the registered plugins.
+func (d *Driver) Plugins() []Plugin { return d.plugins }
+
+func (d *Driver) afterParse(pkgs []*Package) error {
+	for _, p := range d.plugins {
+		if err := p.AfterParse(d, pkgs); err != nil {
+			return PluginError{Plugin: p.Name(), Hook: "after parse", Err: err}
+		}
+	}
+	return nil
+}
+
+func (d *Driver) afterCheck(pkg *Package) error {
+	for _, p := range d.plugins {
+		if err := p.AfterCheck(d, pkg); err != nil {
+			return PluginError{Plugin: p.Name(), Hook: "after check", Err: err}
+		}
+	}
+	return nil
+}
+
+func (d *Driver) beforeCodegen(pkg *Package) ([]string, error) {
+	var artifacts []string
+	for _, p := range d.plugins {
+		paths, err := p.BeforeCodegen(d, pkg)
+		if err != nil {
+			return artifacts, PluginError{Plugin: p.Name(), Hook: "before codegen", Err: err}
+		}
+		artifacts = append(artifacts, paths...)
+	}
+	return artifacts, nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package build
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recorder struct {
+	BasePlugin
+	mutex sync.Mutex
+	hooks []string
+	fail  error
+}
+
+func (r *recorder) Name() string { return "recorder" }
+
+func (r *recorder) record(hook string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.hooks = append(r.hooks, hook)
+}
+
+func (r *recorder) AfterParse(_ *Driver, pkgs []*Package) error {
+	r.record("parse")
+	return nil
+}
+
+func (r *recorder) AfterCheck(_ *Driver, pkg *Package) error {
+	r.record("check " + filepath.Base(pkg.Dir))
+	return r.fail
+}
+
+func (r *recorder) BeforeCodegen(_ *Driver, pkg *Package) ([]string, error) {
+	r.record("codegen " + filepath.Base(pkg.Dir))
+	return []string{pkg.Path + ".extra"}, nil
+}
+
+func TestPlugins(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"main.cee", "lib/lib.cee"} {
+		if err := os.WriteFile(filepath.Join(root, path), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := &recorder{}
+	d := NewDriver(Options{Parallelism: 1})
+	if err := d.Register(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Register(&recorder{}); !errors.As(err, &DuplicatePluginError{}) {
+		t.Errorf("registering twice: %v", err)
+	}
+
+	result, err := d.Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Independent packages go by name, the root is ".".
+	base := filepath.Base(root)
+	if got, want := strings.Join(r.hooks, ", "), "parse, check "+base+", check lib, codegen "+base+", codegen lib"; got != want {
+		t.Errorf("hooks %s, want %s", got, want)
+	}
+	if len(result.Artifacts) != 2 {
+		t.Errorf("artifacts %v", result.Artifacts)
+	}
+
+	r.fail = errors.New("boom")
+	if _, err := d.Build(root); !errors.As(err, &PluginError{}) {
+		t.Errorf("failing hook: %v", err)
+	}
+}
@@ -0,0 +1,346 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package build
+
+import (
+	"cee/loader"
+	"context"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+type WatchOp byte
+
+const (
+	_ WatchOp = iota
+
+	WatchCreate
+	WatchWrite
+	WatchRemove
+)
+
+type WatchEvent struct {
+	Path string
+	Op   WatchOp
+}
+
+// Watcher reports the changes to the files under the directory trees it watches, as fsnotify does.
+type Watcher interface {
+	Add(dir string) error
+	Events() <-chan WatchEvent
+	Errors() <-chan error
+	Close() error
+}
+
+type fileStamp struct {
+	ModTime time.Time
+	Size    int64
+}
+
+// PollWatcher is a Watcher comparing the modification time and the size of the files at every interval.
+type PollWatcher struct {
+	Interval time.Duration
+
+	mutex sync.Mutex
+	dirs  []string
+	files map[string]fileStamp
+
+	events chan WatchEvent
+	errors chan error
+	done   chan struct{}
+	once   sync.Once
+}
+
+func NewPollWatcher(interval time.Duration) *PollWatcher {
+	w := &PollWatcher{
+		Interval: interval,
+		files:    map[string]fileStamp{},
+		events:   make(chan WatchEvent),
+		errors:   make(chan error),
+		done:     make(chan struct{}),
+	}
+	go w.loop()
+	return w
+}
+
+func (w *PollWatcher) Events() <-chan WatchEvent { return w.events }
+
+func (w *PollWatcher) Errors() <-chan error { return w.errors }
+
+// Add watches the files under dir, the files present are not reported.
+func (w *PollWatcher) Add(dir string) error {
+	files, err := stampTree(dir)
+	if err != nil {
+		return err
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.dirs = append(w.dirs, dir)
+	for path, stamp := range files {
+		w.files[path] = stamp
+	}
+	return nil
+}
+
+func (w *PollWatcher) Close() error {
+	w.once.Do(func() { close(w.done) })
+	return nil
+}
+
+func stampTree(dir string) (map[string]fileStamp, error) {
+	files := map[string]fileStamp{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files[filepath.Clean(path)] = fileStamp{ModTime: info.ModTime(), Size: info.Size()}
+		return nil
+	})
+	return files, err
+}
+
+func (w *PollWatcher) loop() {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		events, err := w.poll()
+		if err != nil {
+			select {
+			case w.errors <- err:
+			case <-w.done:
+				return
+			}
+		}
+		for _, ev := range events {
+			select {
+			case w.events <- ev:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+// poll diffs the watched trees against the previous poll, events are sorted by path.
+func (w *PollWatcher) poll() ([]WatchEvent, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	current := map[string]fileStamp{}
+	for _, dir := range w.dirs {
+		files, err := stampTree(dir)
+		if err != nil {
+			return nil, err
+		}
+		for path, stamp := range files {
+			current[path] = stamp
+		}
+	}
+
+	var events []WatchEvent
+	for path, stamp := range current {
+		prev, ok := w.files[path]
+		switch {
+		case !ok:
+			events = append(events, WatchEvent{Path: path, Op: WatchCreate})
+		case prev != stamp:
+			events = append(events, WatchEvent{Path: path, Op: WatchWrite})
+		}
+	}
+	for path := range w.files {
+		if _, ok := current[path]; !ok {
+			events = append(events, WatchEvent{Path: path, Op: WatchRemove})
+		}
+	}
+	w.files = current
+
+	sort.Slice(events, func(i, j int) bool { return events[i].Path < events[j].Path })
+	return events, nil
+}
+
+// Update is the outcome of a build in watch mode. Changed holds the packages parsed and checked by the build,
+// the other packages of the result are carried over from the previous one.
+type Update struct {
+	Result
+	Changed []*Package
+	Err     error // the build could not complete
+}
+
+// Watch builds root, then rebuilds it on the changes reported by w until ctx is done, sending every outcome to updates.
+// Only the packages containing a changed file and the packages importing them are parsed and checked again,
+// a change to the manifest rebuilds everything.
+func (d *Driver) Watch(ctx context.Context, root string, w Watcher, updates chan<- Update) error {
+	if err := w.Add(root); err != nil {
+		return err
+	}
+
+	send := func(u Update) bool {
+		select {
+		case updates <- u:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	update := d.rebuildAll(root)
+	if !send(update) {
+		return ctx.Err()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-w.Errors():
+			return err
+		case ev, ok := <-w.Events():
+			if !ok {
+				return nil
+			}
+			// Changes already reported are built together, e.g. the files written by a save-all.
+			paths := []string{ev.Path}
+		drain:
+			for {
+				select {
+				case ev, ok := <-w.Events():
+					if !ok {
+						break drain
+					}
+					paths = append(paths, ev.Path)
+				default:
+					break drain
+				}
+			}
+
+			update = d.rebuild(root, update.Result, paths)
+			if !send(update) {
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+func (d *Driver) rebuildAll(root string) Update {
+	result, err := d.Build(root)
+	return Update{Result: result, Changed: result.Packages, Err: err}
+}
+
+// rebuild updates the previous result with the changes to paths.
+func (d *Driver) rebuild(root string, prev Result, paths []string) (update Update) {
+	for _, path := range paths {
+		if filepath.Base(path) == loader.ManifestFile {
+			return d.rebuildAll(root)
+		}
+	}
+	defer func() { d.finished(update.Err) }()
+
+	res, err := d.resolver(root)
+	if err != nil {
+		return Update{Result: prev, Err: err}
+	}
+
+	byName := map[string]*Package{}
+	byDir := map[string]*Package{}
+	for _, pkg := range prev.Packages {
+		byName[pkg.Path] = pkg
+		byDir[pkg.Dir] = pkg
+	}
+
+	dirs := map[string]bool{}
+	for _, path := range paths {
+		dirs[filepath.Dir(filepath.Clean(path))] = true
+	}
+
+	var changed []string
+	for dir := range dirs {
+		if pkg, ok := byDir[dir]; ok {
+			changed = append(changed, pkg.Path)
+			continue
+		}
+		// A package created under root.
+		rel, err := filepath.Rel(root, dir)
+		if err != nil || !filepath.IsLocal(rel) && rel != "." {
+			continue
+		}
+		pkg, err := collectDir(dir)
+		if err != nil {
+			continue
+		}
+		if res != nil {
+			pkg.Path, err = res.Name(dir)
+			if err != nil {
+				continue
+			}
+		} else {
+			pkg.Path = filepath.ToSlash(rel)
+		}
+		byName[pkg.Path] = pkg
+		changed = append(changed, pkg.Path)
+	}
+
+	g := index(byName)
+	var pkgs []*Package
+	for _, name := range g.Importers(changed...) {
+		pkg, ok := byName[name]
+		if !ok {
+			continue
+		}
+		// Packages are collected afresh, files may have been added or removed.
+		fresh, err := collectDir(pkg.Dir)
+		if err != nil {
+			delete(byName, name)
+			continue
+		}
+		fresh.Path = pkg.Path
+		byName[name] = fresh
+		pkgs = append(pkgs, fresh)
+	}
+
+	d.parse(pkgs, editionOf(res))
+	g = index(byName)
+	if res != nil {
+		d.loadImports(res, &g, byName)
+	}
+	ordered, err := order(g, byName)
+	if err != nil {
+		return Update{Result: prev, Err: err}
+	}
+
+	parsed := map[*Package]bool{}
+	for _, pkg := range pkgs {
+		parsed[pkg] = true
+	}
+	update = Update{Result: Result{Packages: ordered}}
+	for _, pkg := range ordered {
+		if parsed[pkg] {
+			update.Changed = append(update.Changed, pkg)
+		}
+	}
+	update.Err = d.compile(g, byName, update.Changed, &update.Result)
+	return update
+}
+
+// index builds the import graph of parsed packages whose canonical names are known.
+func index(byName map[string]*Package) loader.Graph {
+	g := loader.NewGraph()
+	for name, pkg := range byName {
+		g.AddPackage(name, importsOf(pkg)...)
+	}
+	return g
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package build
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type fakeWatcher struct {
+	events chan WatchEvent
+	errors chan error
+}
+
+func (w *fakeWatcher) Add(string) error { return nil }
+
+func (w *fakeWatcher) Events() <-chan WatchEvent { return w.events }
+
+func (w *fakeWatcher) Errors() <-chan error { return w.errors }
+
+func (w *fakeWatcher) Close() error { return nil }
+
+func TestWatch(t *testing.T) {
+	root := t.TempDir()
+	write := func(name string) string {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("a/a.cee")
+	b := write("b/b.cee")
+
+	w := &fakeWatcher{events: make(chan WatchEvent, 1), errors: make(chan error)}
+	updates := make(chan Update)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d := NewDriver(Options{})
+	go func() { _ = d.Watch(ctx, root, w, updates) }()
+
+	changed := func(u Update) []string {
+		if u.Err != nil {
+			t.Fatal(u.Err)
+		}
+		var names []string
+		for _, pkg := range u.Changed {
+			names = append(names, pkg.Path)
+		}
+		return names
+	}
+	expect := func(want ...string) {
+		t.Helper()
+		u := <-updates
+		got := changed(u)
+		if len(got) != len(want) {
+			t.Fatalf("changed %v, want %v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("changed %v, want %v", got, want)
+			}
+		}
+	}
+
+	expect("a", "b")
+
+	w.events <- WatchEvent{Path: write("a/a2.cee"), Op: WatchCreate}
+	expect("a")
+
+	w.events <- WatchEvent{Path: write("c/c.cee"), Op: WatchCreate}
+	expect("c")
+
+	if err := os.Remove(b); err != nil {
+		t.Fatal(err)
+	}
+	w.events <- WatchEvent{Path: b, Op: WatchRemove}
+	u := <-updates
+	if len(u.Changed) != 0 || len(u.Packages) != 2 {
+		t.Errorf("removed package still built: %v", u.Packages)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package cache
+
+import (
+	"cee"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type Key [sha256.Size]byte
+
+// NewKey derives a key from the file content, the compiler version, the artifact kind
+// (e.g. "ast", "hir") and the options affecting it.
+func NewKey(kind string, content []byte, options ...string) Key {
+	h := sha256.New()
+	for _, s := range append([]string{cee.Version, kind}, options...) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	h.Write(content)
+
+	var key Key
+	h.Sum(key[:0])
+	return key
+}
+
+func (k Key) String() string { return hex.EncodeToString(k[:]) }
+
+type Cache struct {
+	Dir string
+
+	mutex        sync.Mutex
+	hits, misses int
+}
+
+func New(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Cache{Dir: dir}, nil
+}
+
+func (c *Cache) path(key Key) string {
+	s := key.String()
+	return filepath.Join(c.Dir, s[:2], s)
+}
+
+func (c *Cache) count(hit bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if hit {
+		c.hits++
+	} else {
+		c.misses++
+	}
+}
+
+// Get returns the cached data, a missing entry is not an error.
+func (c *Cache) Get(key Key) ([]byte, bool, error) {
+	data, err := os.ReadFile(c.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		c.count(false)
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	c.count(true)
+	return data, true, nil
+}
+
+// Put stores the data atomically, concurrent writers of the same key are safe.
+func (c *Cache) Put(key Key, data []byte) error {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Stats returns the number of hits and misses since the cache was opened.
+func (c *Cache) Stats() (hits, misses int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.hits, c.misses
+}
@@ -0,0 +1,34 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package cache
+
+import (
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	c, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := NewKey("ast", []byte("fun main() {}"))
+	if _, ok, err := c.Get(key); ok || err != nil {
+		t.Fatal("unexpected entry", err)
+	}
+	if err := c.Put(key, []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok, err := c.Get(key); !ok || err != nil || string(data) != "data" {
+		t.Fatal("entry missing", err)
+	}
+
+	if NewKey("ast", []byte("fun main() {}"), "-debug") == key {
+		t.Error("options do not affect the key")
+	}
+	if hits, misses := c.Stats(); hits != 1 || misses != 1 {
+		t.Error("incorrect stats:", hits, misses)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package cfg
+
+import (
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/token"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Attribute is the name of the conditional compilation attribute.
+const Attribute = "cfg"
+
+// Env is the target environment the conditions are evaluated in, its keys are the only valid ones.
+type Env map[string]string
+
+// DefaultEnv targets the host.
+func DefaultEnv() Env {
+	return Env{"os": runtime.GOOS, "arch": runtime.GOARCH}
+}
+
+// Set adds or overrides keys from a comma separated list of key=value pairs, e.g. `os=linux,arch=arm64`.
+func (env Env) Set(pairs string) error {
+	for _, pair := range strings.Split(pairs, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || key == "" {
+			return fmt.Errorf("cfg: invalid pair %q", pair)
+		}
+		env[key] = value
+	}
+	return nil
+}
+
+// Eval reports whether every condition of the cfg attribute holds.
+// Unknown keys and values other than strings are reported and make the condition false.
+func (env Env) Eval(attr ast.Attribute) (bool, []diagnosis.Diagnosis) {
+	var (
+		holds = true
+		diags []diagnosis.Diagnosis
+	)
+	for _, arg := range attr.Args {
+		want, known := env[arg.Key.Literal]
+		if !known {
+			diags = append(diags, diagnosis.Diagnosis{
+				Kind:  diagnosis.UnknownAttributeKey,
+				Error: diagnosis.UnknownAttributeKeyError{Attr: attr.Name.Literal, Key: arg.Key},
+			})
+			holds = false
+			continue
+		}
+
+		value, err := token.Unquote(arg.Value.Literal)
+		if !token.IsString(arg.Value.Kind) || err != nil {
+			diags = append(diags, diagnosis.Diagnosis{
+				Kind:  diagnosis.InvalidAttribute,
+				Error: diagnosis.InvalidAttributeError{Arg: arg},
+			})
+			holds = false
+			continue
+		}
+		holds = holds && value == want
+	}
+	return holds, diags
+}
+
+// Filter drops the declarations whose cfg attributes do not hold in env.
+func Filter(decls []ast.Stmt, env Env) ([]ast.Stmt, []diagnosis.Diagnosis) {
+	var (
+		kept  = decls[:0:0]
+		diags []diagnosis.Diagnosis
+	)
+	for _, decl := range decls {
+		keep := true
+		for _, attr := range decl.Attrs {
+			if attr.Name.Literal != Attribute {
+				continue
+			}
+			holds, d := env.Eval(attr)
+			keep = keep && holds
+			diags = append(diags, d...)
+		}
+		if keep {
+			kept = append(kept, decl)
+		}
+	}
+	return kept, diags
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package cfg
+
+import (
+	"cee"
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/token"
+	"testing"
+)
+
+func ident(name string) ast.Ident {
+	return ast.Ident{Token: ast.Token{Kind: token.IDENT, Literal: name}}
+}
+
+// decl declares a function named name with a cfg attribute of the key and quoted value pairs.
+func decl(name string, pairs ...string) ast.Stmt {
+	attr := ast.Attribute{Name: ident(Attribute)}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		attr.Args = append(attr.Args, ast.AttributeArg{
+			Key:   ident(pairs[i]),
+			Value: ast.LiteralValue{Token: ast.Token{Kind: token.STRING, Literal: pairs[i+1]}},
+		})
+	}
+	id := ident(name)
+	stmt := ast.Stmt{Union: cee.Union[ast.StmtKind]{Tag: ast.StmtFuncDecl, Value: ast.FuncDecl{Ident: &id}}}
+	if len(pairs) != 0 {
+		stmt.Attrs = []ast.Attribute{attr}
+	}
+	return stmt
+}
+
+func TestFilter(t *testing.T) {
+	env := Env{"os": "linux", "arch": "amd64"}
+	decls := []ast.Stmt{
+		decl("always"),
+		decl("linux", "os", `"linux"`),
+		decl("windows", "os", `"windows"`),
+		decl("linuxArm", "os", `"linux"`, "arch", `"arm64"`),
+		decl("linuxAmd", "os", `"linux"`, "arch", `"amd64"`),
+	}
+
+	kept, diags := Filter(decls, env)
+	if len(diags) != 0 {
+		t.Fatalf("unexpected diagnosis %v", diags)
+	}
+	var names []string
+	for _, d := range kept {
+		names = append(names, d.Value.(ast.FuncDecl).Ident.Literal)
+	}
+	if len(names) != 3 || names[0] != "always" || names[1] != "linux" || names[2] != "linuxAmd" {
+		t.Errorf("kept %v", names)
+	}
+	if len(decls) != 5 || decls[2].Value.(ast.FuncDecl).Ident.Literal != "windows" {
+		t.Error("Filter modified its input")
+	}
+}
+
+func TestFilterDiagnosis(t *testing.T) {
+	kept, diags := Filter([]ast.Stmt{decl("f", "platform", `"linux"`), decl("g", "os", "1")}, Env{"os": "linux"})
+	if len(kept) != 0 {
+		t.Errorf("kept %d declarations with invalid conditions", len(kept))
+	}
+	if len(diags) != 2 || diags[0].Kind != diagnosis.UnknownAttributeKey || diags[1].Kind != diagnosis.InvalidAttribute {
+		t.Errorf("diagnosis %v", diags)
+	}
+}
+
+func TestEnvSet(t *testing.T) {
+	env := Env{"os": "linux"}
+	if err := env.Set("os=windows, arch=arm64"); err != nil || env["os"] != "windows" || env["arch"] != "arm64" {
+		t.Errorf("env %v, %v", env, err)
+	}
+	if err := env.Set("os"); err == nil {
+		t.Error("pair without value accepted")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	env := Env{"os": "linux", "arch": "amd64"}
+	for src, want := range map[string]bool{
+		"fun main() {}":                               true,
+		"//cee:build linux\nfun main() {}":            true,
+		"// doc\n\n//cee:build !linux\nfun main() {}": false,
+		"//cee:build linux && !wasm":                  true,
+		"//cee:build darwin || (linux && arm64)":      false,
+		"//cee:build !(darwin || windows) && amd64":   true,
+		"fun main() {}\n//cee:build darwin":           true,
+	} {
+		if got, err := Match([]byte(src), env); err != nil || got != want {
+			t.Errorf("%q: got %v, %v, want %v", src, got, err, want)
+		}
+	}
+
+	for _, expr := range []string{"", "linux &&", "(linux", "linux darwin", "linux & arm"} {
+		if _, err := ParseConstraint(expr); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
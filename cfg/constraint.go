@@ -0,0 +1,191 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package cfg
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// ConstraintPrefix starts the line of a file constraint, e.g. `//cee:build linux && !wasm`.
+// The constraint must precede the first line of code, only blank lines and comments may come before it.
+const ConstraintPrefix = "//cee:build "
+
+// Constraint is a boolean expression over tags, with `!`, `&&`, `||` and parentheses.
+// A tag holds when it is one of the values of the environment, e.g. the os or the arch.
+type Constraint interface {
+	Eval(env Env) bool
+	String() string
+}
+
+type (
+	tagExpr string
+	notExpr struct{ X Constraint }
+	andExpr struct{ X, Y Constraint }
+	orExpr  struct{ X, Y Constraint }
+)
+
+func (t tagExpr) Eval(env Env) bool {
+	for _, value := range env {
+		if value == string(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e notExpr) Eval(env Env) bool { return !e.X.Eval(env) }
+func (e andExpr) Eval(env Env) bool { return e.X.Eval(env) && e.Y.Eval(env) }
+func (e orExpr) Eval(env Env) bool  { return e.X.Eval(env) || e.Y.Eval(env) }
+
+func (t tagExpr) String() string { return string(t) }
+func (e notExpr) String() string { return "!" + e.X.String() }
+func (e andExpr) String() string { return "(" + e.X.String() + " && " + e.Y.String() + ")" }
+func (e orExpr) String() string  { return "(" + e.X.String() + " || " + e.Y.String() + ")" }
+
+type ConstraintError struct {
+	Expr string
+	Msg  string
+}
+
+func (e ConstraintError) Error() string {
+	return fmt.Sprintf("cfg: invalid constraint %q: %s", e.Expr, e.Msg)
+}
+
+type constraintParser struct {
+	expr string
+	toks []string
+	pos  int
+}
+
+func isTagByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_' || c == '.'
+}
+
+func tokenize(expr string) ([]string, error) {
+	var toks []string
+	for i := 0; i < len(expr); {
+		switch c := expr[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '!' || c == '(' || c == ')':
+			toks = append(toks, expr[i:i+1])
+			i++
+		case strings.HasPrefix(expr[i:], "&&"), strings.HasPrefix(expr[i:], "||"):
+			toks = append(toks, expr[i:i+2])
+			i += 2
+		case isTagByte(c):
+			j := i
+			for j < len(expr) && isTagByte(expr[j]) {
+				j++
+			}
+			toks = append(toks, expr[i:j])
+			i = j
+		default:
+			return nil, ConstraintError{Expr: expr, Msg: fmt.Sprintf("unexpected %q", c)}
+		}
+	}
+	return toks, nil
+}
+
+func (p *constraintParser) peek() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return ""
+}
+
+func (p *constraintParser) or() (Constraint, error) {
+	x, err := p.and()
+	for err == nil && p.peek() == "||" {
+		p.pos++
+		var y Constraint
+		if y, err = p.and(); err == nil {
+			x = orExpr{x, y}
+		}
+	}
+	return x, err
+}
+
+func (p *constraintParser) and() (Constraint, error) {
+	x, err := p.unary()
+	for err == nil && p.peek() == "&&" {
+		p.pos++
+		var y Constraint
+		if y, err = p.unary(); err == nil {
+			x = andExpr{x, y}
+		}
+	}
+	return x, err
+}
+
+func (p *constraintParser) unary() (Constraint, error) {
+	switch tok := p.peek(); {
+	case tok == "!":
+		p.pos++
+		x, err := p.unary()
+		return notExpr{x}, err
+	case tok == "(":
+		p.pos++
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, ConstraintError{Expr: p.expr, Msg: "missing )"}
+		}
+		p.pos++
+		return x, nil
+	case tok != "" && isTagByte(tok[0]):
+		p.pos++
+		return tagExpr(tok), nil
+	case tok == "":
+		return nil, ConstraintError{Expr: p.expr, Msg: "unexpected end"}
+	default:
+		return nil, ConstraintError{Expr: p.expr, Msg: "unexpected " + tok}
+	}
+}
+
+// ParseConstraint parses the expression of a constraint, without the prefix.
+func ParseConstraint(expr string) (Constraint, error) {
+	toks, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := constraintParser{expr: expr, toks: toks}
+	x, err := p.or()
+	if err == nil && p.pos != len(toks) {
+		err = ConstraintError{Expr: expr, Msg: "unexpected " + p.peek()}
+	}
+	return x, err
+}
+
+// FileConstraint returns the constraint of a source file, nil if it has none.
+// Only the leading comments are read, not the body of the file.
+func FileConstraint(src []byte) (Constraint, error) {
+	s := bufio.NewScanner(bytes.NewReader(src))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		switch {
+		case strings.HasPrefix(line, ConstraintPrefix):
+			return ParseConstraint(strings.TrimPrefix(line, ConstraintPrefix))
+		case line == "" || strings.HasPrefix(line, "//"):
+			continue
+		}
+		break
+	}
+	return nil, nil
+}
+
+// Match reports whether a source file is included in the build for env.
+func Match(src []byte, env Env) (bool, error) {
+	c, err := FileConstraint(src)
+	if c == nil || err != nil {
+		return err == nil, err
+	}
+	return c.Eval(env), nil
+}
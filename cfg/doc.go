@@ -0,0 +1,9 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+// Package cfg
+// Conditional compilation: declarations annotated with `@cfg(key = "value", ...)` are only kept
+// when every pair holds in the target environment, files with a `//cee:build` constraint only
+// when the constraint does.
+package cfg
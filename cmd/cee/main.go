@@ -0,0 +1,252 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+// Command cee is the entry point of the Ceelang toolchain.
+//
+//	cee build [-o none|go|c] [-out dir] [-j n] [-format text|json|html] [-cache dir] [-profile] [-cpuprofile file] [-cfg key=value,...] [-maxerrors n] [-events file] [-watch] [dir]
+//	cee lsp
+//	cee grammar [-format textmate|tree-sitter]
+package main
+
+import (
+	"cee/build"
+	"cee/cache"
+	"cee/cfg"
+	"cee/diagnosis"
+	"cee/grammar"
+	"cee/lsp"
+	"cee/metrics"
+	"cee/profile"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime/pprof"
+	"time"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: cee build [flags] [dir]")
+	fmt.Fprintln(os.Stderr, "       cee lsp")
+	fmt.Fprintln(os.Stderr, "       cee grammar [-format textmate|tree-sitter]")
+	os.Exit(2)
+}
+
+func runBuild(args []string) int {
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+	output := fs.String("o", "none", "output kind: none, go or c")
+	outDir := fs.String("out", "out", "output directory")
+	jobs := fs.Int("j", 0, "number of files parsed in parallel, 0 for GOMAXPROCS")
+	format := fs.String("format", "text", "diagnostics format: text, json or html")
+	cacheDir := fs.String("cache", "", "build cache directory, empty to disable")
+	printProfile := fs.Bool("profile", false, "print the time and allocations of each phase")
+	printMetrics := fs.Bool("metrics", false, "print the counters and histograms of the build, e.g. the cache hit rate")
+	cpuProfile := fs.String("cpuprofile", "", "write a CPU profile labelled by phase to file")
+	target := fs.String("cfg", "", "conditional compilation keys overriding the host, e.g. os=linux,arch=arm64")
+	maxErrors := fs.Int("maxerrors", 0, "diagnostics of a file after which its parsing stops, 0 for the default, -1 for no limit")
+	confusables := fs.Bool("confusables", false, "report identifiers that look like others visible from them")
+	severity := fs.String("severity", "", "override the severity of diagnostics, e.g. literal-precision=ignore,literal-overflow=warning")
+	events := fs.String("events", "", "write build progress to file as newline-delimited JSON, - for stdout")
+	metadata := fs.String("metadata", "", "write the project model of the build to file as JSON, for indexers")
+	watch := fs.Bool("watch", false, "rebuild on every change to the sources until interrupted")
+	_ = fs.Parse(args)
+
+	opts := build.Options{OutDir: *outDir, Parallelism: *jobs, Cfg: cfg.DefaultEnv(), MaxErrors: *maxErrors, Confusables: *confusables}
+	if *target != "" {
+		if err := opts.Cfg.Set(*target); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	if *severity != "" {
+		severities, err := diagnosis.ParseSeverities(*severity)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		opts.Severities = severities
+	}
+
+	var collector profile.Collector
+	if *printProfile {
+		opts.Profiler = &collector
+		defer collector.Report(os.Stderr)
+	}
+	if *printMetrics {
+		m := &metrics.Memory{}
+		opts.Metrics = m
+		defer m.Report(os.Stderr)
+	}
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer pprof.StopCPUProfile()
+		opts.ProfileLabels = true
+	}
+
+	switch *events {
+	case "":
+	case "-":
+		opts.Events = build.NewEventWriter(os.Stdout)
+	default:
+		f, err := os.Create(*events)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		opts.Events = build.NewEventWriter(f)
+	}
+
+	if *cacheDir != "" {
+		c, err := cache.New(*cacheDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		opts.Cache = c
+	}
+
+	switch *output {
+	case "none":
+		opts.Output = build.OutputNone
+	case "go":
+		opts.Output = build.OutputGo
+	case "c":
+		opts.Output = build.OutputC
+	default:
+		fmt.Fprintln(os.Stderr, "unknown output kind:", *output)
+		return 2
+	}
+
+	var err error
+	if opts.Format, err = build.ParseDiagnosticsFormat(*format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	root := "."
+	if fs.NArg() > 0 {
+		root = fs.Arg(0)
+	}
+
+	d := build.NewDriver(opts)
+	if *watch {
+		return runWatch(&d, root, opts.Format)
+	}
+	result, err := d.Build(root)
+	if err := build.WriteDiagnostics(os.Stderr, result, opts.Format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if *metadata != "" {
+		if err := writeMetadata(*metadata, d.Metadata(root, result)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if result.HasErrors() {
+		return 1
+	}
+	return 0
+}
+
+func writeMetadata(path string, m build.Metadata) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := build.WriteMetadata(f, m); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// runWatch prints the diagnostics of every rebuild until interrupted.
+func runWatch(d *build.Driver, root string, format build.DiagnosticsFormat) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	w := build.NewPollWatcher(500 * time.Millisecond)
+	defer w.Close()
+
+	updates := make(chan build.Update)
+	errs := make(chan error, 1)
+	go func() { errs <- d.Watch(ctx, root, w, updates) }()
+
+	for {
+		select {
+		case u := <-updates:
+			if err := build.WriteDiagnostics(os.Stderr, u.Result, format); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			if u.Err != nil {
+				fmt.Fprintln(os.Stderr, u.Err)
+			}
+			fmt.Fprintf(os.Stderr, "rebuilt %d packages\n", len(u.Changed))
+		case err := <-errs:
+			if err != nil && !errors.Is(err, context.Canceled) {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			return 0
+		}
+	}
+}
+
+func runGrammar(args []string) int {
+	fs := flag.NewFlagSet("grammar", flag.ExitOnError)
+	format := fs.String("format", "textmate", "grammar format: textmate or tree-sitter")
+	_ = fs.Parse(args)
+
+	var err error
+	switch *format {
+	case "textmate":
+		err = grammar.TextMate(os.Stdout)
+	case "tree-sitter":
+		err = grammar.TreeSitter(os.Stdout)
+	default:
+		fmt.Fprintln(os.Stderr, "unknown grammar format:", *format)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "build":
+		os.Exit(runBuild(os.Args[2:]))
+	case "lsp":
+		if err := lsp.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "grammar":
+		os.Exit(runGrammar(os.Args[2:]))
+	default:
+		usage()
+	}
+}
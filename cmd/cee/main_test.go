@@ -0,0 +1,43 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunBuild(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "a")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(root, "a.cee")
+	if err := os.WriteFile(src, []byte("package a\n\nfun f(x i64) i64 {\n\treturn x + 1\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+
+	if code := runBuild([]string{"-o", "go", "-out", out, "-j", "2", root}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if _, err := os.Stat(filepath.Join(out, "a.go")); err != nil {
+		t.Error(err)
+	}
+
+	for _, args := range [][]string{{"-o", "wasm", root}, {"-format", "xml", root}} {
+		if code := runBuild(args); code != 2 {
+			t.Errorf("%v: exit code %d, want 2", args, code)
+		}
+	}
+
+	if err := os.WriteFile(src, []byte("package a\n\nfun f( {\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if code := runBuild([]string{"-format", "json", root}); code != 1 {
+		t.Errorf("exit code %d with syntax errors, want 1", code)
+	}
+}
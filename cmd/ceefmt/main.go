@@ -0,0 +1,114 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+// Command ceefmt formats Ceelang source.
+//
+//	ceefmt [-w] [-l] [-width n] [-minify [-rename]] [file ...]
+//
+// Without files it reads from stdin and writes to stdout. With -width lines longer than n columns are wrapped
+// after the commas of parenthesized lists.
+// With -minify comments are stripped and whitespace is collapsed instead, -rename also shortens the
+// identifiers that are not pub, taking each file as a package of its own.
+package main
+
+import (
+	"bytes"
+	"cee/build"
+	"cee/format"
+	"cee/parser"
+	"cee/token"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+var (
+	write  = flag.Bool("w", false, "write result to the source file instead of stdout")
+	list   = flag.Bool("l", false, "list files whose formatting differs")
+	width  = flag.Int("width", 0, "wrap lines longer than `n` columns, 0 never wraps")
+	minify = flag.Bool("minify", false, "strip comments and collapse whitespace instead of formatting")
+	rename = flag.Bool("rename", false, "with -minify, shorten the identifiers that are not pub")
+)
+
+func minifySource(path string, src []byte) ([]byte, error) {
+	if !*rename {
+		return format.Minify(src, format.MinifyOptions{})
+	}
+	file := build.ParseFile(token.NewFileSet(), path, src, parser.Options{})
+	if file.Err != nil {
+		return nil, file.Err
+	}
+	for _, d := range file.Diagnosis {
+		if d.IsError() {
+			return nil, fmt.Errorf("%s: cannot rename identifiers of a file with syntax errors", path)
+		}
+	}
+	out, err := build.Minify(&build.Package{Path: path, Files: []*build.File{file}}, [][]byte{src}, true)
+	if err != nil {
+		return nil, err
+	}
+	return out[0], nil
+}
+
+func process(path string, in io.Reader, out io.Writer) error {
+	src, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+
+	var res []byte
+	if *minify {
+		res, err = minifySource(path, src)
+	} else {
+		res, err = format.Options{LineLength: *width}.Source(src)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	changed := !bytes.Equal(src, res)
+	if *list && changed {
+		fmt.Fprintln(out, path)
+	}
+	if *write {
+		if path == "" {
+			return fmt.Errorf("cannot use -w with stdin")
+		}
+		if changed {
+			return os.WriteFile(path, res, 0o644)
+		}
+		return nil
+	}
+	if !*list {
+		_, err = out.Write(res)
+	}
+	return err
+}
+
+func main() {
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		if err := process("", os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	code := 0
+	for _, path := range flag.Args() {
+		f, err := os.Open(path)
+		if err == nil {
+			err = process(path, f, os.Stdout)
+			f.Close()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,120 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package constant
+
+import (
+	"cee"
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/token"
+	"testing"
+)
+
+func expr(kind ast.ExprKind, value ast.Node) ast.Expr {
+	return ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: kind, Value: value}}
+}
+
+func ident(name string) ast.Expr {
+	return expr(ast.ExprIdent, ast.Ident{Token: ast.Token{Kind: token.IDENT, Literal: name}})
+}
+
+func lit(kind int, s string) ast.Expr {
+	return expr(ast.ExprLiteralValue, ast.LiteralValue{Token: ast.Token{Kind: kind, Literal: s}})
+}
+
+func binary(op int, x, y ast.Expr) ast.Expr {
+	return expr(ast.ExprBinary, ast.BinaryExpr{Operator: ast.Token{Kind: op}, Exprs: [2]ast.Expr{x, y}})
+}
+
+func unary(op int, x ast.Expr) ast.Expr {
+	return expr(ast.ExprUnary, ast.UnaryExpr{Operator: ast.Token{Kind: op}, Expr: x})
+}
+
+func kinds(diags []diagnosis.Diagnosis) []int {
+	var ks []int
+	for _, d := range diags {
+		ks = append(ks, d.Kind)
+	}
+	return ks
+}
+
+func TestEval(t *testing.T) {
+	one, two := lit(token.INT, "1"), lit(token.INT, "2")
+	tests := []struct {
+		expr  ast.Expr
+		want  string
+		diags []int
+	}{
+		{binary(token.SHL, one, lit(token.INT, "64")), "18446744073709551616", nil},
+		{binary(token.QUO, lit(token.INT, "7"), two), "3", nil},
+		{binary(token.QUO, lit(token.FLOAT, "7.0"), two), "3.5", nil},
+		{binary(token.ADD, lit(token.STRING, `"a"`), lit(token.STRING, `"b"`)), `"ab"`, nil},
+		{binary(token.ADD, lit(token.CHAR, "'a'"), one), "98", nil},
+		{binary(token.LSS, one, two), "true", nil},
+		{unary(token.NOT, ident("true")), "false", nil},
+		{unary(token.SUB, binary(token.MUL, two, two)), "-4", nil},
+		{binary(token.REM, one, lit(token.INT, "0")), "unknown", []int{diagnosis.DivisionByZero}},
+		{binary(token.ADD, one, lit(token.STRING, `"a"`)), "unknown", []int{diagnosis.InvalidConstantOp}},
+		{binary(token.ADD, ident("x"), binary(token.ADD, ident("y"), one)), "unknown", []int{diagnosis.NotConstant, diagnosis.NotConstant}},
+	}
+	for _, test := range tests {
+		v, diags := Eval(test.expr, nil)
+		if got := v.ExactString(); got != test.want {
+			t.Errorf("%s: got %s", test.want, got)
+		}
+		if got := kinds(diags); len(got) != len(test.diags) || len(got) != 0 && got[0] != test.diags[0] {
+			t.Errorf("%s: got diagnostics %v, want %v", test.want, got, test.diags)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	for _, test := range []struct {
+		expr     ast.Expr
+		kind     ast.TypeKind
+		overflow bool
+	}{
+		{binary(token.SUB, lit(token.INT, "256"), lit(token.INT, "1")), ast.TypeU8, false},
+		{binary(token.SHL, lit(token.INT, "1"), lit(token.INT, "8")), ast.TypeU8, true},
+		{unary(token.SUB, lit(token.INT, "129")), ast.TypeI8, true},
+		{lit(token.FLOAT, "2.0"), ast.TypeI32, false},
+		{lit(token.FLOAT, "2.5"), ast.TypeI32, true},
+	} {
+		_, diags := Check(test.expr, nil, test.kind)
+		if overflow := len(diags) == 1 && diags[0].Kind == diagnosis.ConstantOverflow; overflow != test.overflow || !overflow && len(diags) != 0 {
+			t.Errorf("%v: got diagnostics %v", test.expr, kinds(diags))
+		}
+	}
+
+	if n, diags := Length(binary(token.MUL, lit(token.INT, "4"), lit(token.INT, "8")), nil); n != 32 || diags != nil {
+		t.Errorf("Length = %d, %v", n, diags)
+	}
+	if _, diags := Length(unary(token.SUB, lit(token.INT, "1")), nil); len(diags) != 1 || diags[0].Kind != diagnosis.InvalidLength {
+		t.Errorf("negative length got diagnostics %v", kinds(diags))
+	}
+}
+
+func TestDecls(t *testing.T) {
+	decl := func(name string, value ast.Expr) ast.Stmt {
+		return ast.Stmt{Union: cee.Union[ast.StmtKind]{Tag: ast.StmtValDecl, Value: ast.ValDecl{
+			Const: true,
+			Name:  ast.Ident{Token: ast.Token{Kind: token.IDENT, Literal: name}},
+			Value: value,
+		}}}
+	}
+	values, diags := Decls([]ast.Stmt{
+		decl("kib", binary(token.MUL, ident("size"), lit(token.INT, "1024"))),
+		decl("size", lit(token.INT, "4")),
+		decl("a", binary(token.ADD, ident("b"), lit(token.INT, "1"))),
+		decl("b", ident("a")),
+	}, nil)
+
+	if got := values["kib"].ExactString(); got != "4096" {
+		t.Errorf("kib = %s", got)
+	}
+	if len(diags) != 1 || diags[0].Kind != diagnosis.ConstantCycle {
+		t.Errorf("got diagnostics %v, want a cycle", kinds(diags))
+	}
+}
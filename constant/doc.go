@@ -0,0 +1,8 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+// Package constant
+// Exact values of constant expressions, folded at check time, and their validation against the types they are
+// used as.
+package constant
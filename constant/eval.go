@@ -0,0 +1,188 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package constant
+
+import (
+	"cee"
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/literals"
+	"errors"
+)
+
+// Lookup returns the value of a named constant, false for identifiers which are not constants.
+type Lookup func(ident ast.Ident) (Value, bool)
+
+// Eval folds a constant expression: literals, the constants named by lookup, true and false, and the unary and
+// binary operators over them. Expressions which are not constant and invalid operations are reported,
+// their value is Unknown and operations on it yield Unknown without further errors.
+func Eval(expr ast.Expr, lookup Lookup) (Value, []diagnosis.Diagnosis) {
+	var e evaluator
+	v := e.expr(expr, lookup)
+	return v, e.diags
+}
+
+type evaluator struct {
+	diags []diagnosis.Diagnosis
+}
+
+func (e *evaluator) report(kind int, err any) Value {
+	e.diags = append(e.diags, diagnosis.Diagnosis{Kind: kind, Error: err})
+	return Value{}
+}
+
+func (e *evaluator) expr(expr ast.Expr, lookup Lookup) Value {
+	switch v := expr.Value.(type) {
+	case ast.LiteralValue:
+		c, err := Make(v)
+		if errors.Is(err, ErrUnsupported) {
+			return e.report(diagnosis.NotConstant, diagnosis.NotConstantError{Expr: expr})
+		} else if errors.Is(err, literals.ErrNoDigits) {
+			// Reported by the scanner.
+			return Value{}
+		} else if err != nil {
+			return e.report(diagnosis.InvalidLiteral, diagnosis.InvalidLiteralError{Literal: v})
+		}
+		return c
+	case ast.Ident:
+		if lookup != nil {
+			if c, ok := lookup(v); ok {
+				return c
+			}
+		}
+		switch v.Literal {
+		case "true":
+			return MakeBool(true)
+		case "false":
+			return MakeBool(false)
+		}
+	case ast.UnaryExpr:
+		x := e.expr(v.Expr, lookup)
+		c, err := Unary(v.Operator.Kind, x)
+		if err != nil {
+			return e.report(diagnosis.InvalidConstantOp, diagnosis.InvalidConstantOpError{Expr: expr, Reason: err.Error()})
+		}
+		return c
+	case ast.BinaryExpr:
+		x := e.expr(v.Exprs[0], lookup)
+		y := e.expr(v.Exprs[1], lookup)
+		c, err := Binary(x, v.Operator.Kind, y)
+		switch {
+		case errors.Is(err, ErrDivisionByZero):
+			return e.report(diagnosis.DivisionByZero, diagnosis.DivisionByZeroError{Expr: expr})
+		case err != nil:
+			return e.report(diagnosis.InvalidConstantOp, diagnosis.InvalidConstantOpError{Expr: expr, Reason: err.Error()})
+		}
+		return c
+	}
+	return e.report(diagnosis.NotConstant, diagnosis.NotConstantError{Expr: expr})
+}
+
+// Check folds a constant expression used as a value of the builtin integer type kind, reporting it
+// when it overflows the type or is not an integer.
+func Check(expr ast.Expr, lookup Lookup, kind ast.TypeKind) (Value, []diagnosis.Diagnosis) {
+	v, diags := Eval(expr, lookup)
+	if !Representable(v, kind) {
+		diags = append(diags, diagnosis.Diagnosis{
+			Kind: diagnosis.ConstantOverflow,
+			Error: diagnosis.ConstantOverflowError{
+				Expr:  expr,
+				Value: v.ExactString(),
+				Type:  ast.TypeString(ast.Type{Union: cee.Union[ast.TypeKind]{Tag: kind}}),
+			},
+		})
+	}
+	return v, diags
+}
+
+// Length folds the length of an array type, a non-negative integer constant representable as i64.
+func Length(expr ast.Expr, lookup Lookup) (int64, []diagnosis.Diagnosis) {
+	v, diags := Eval(expr, lookup)
+	if v.Kind == Unknown {
+		return 0, diags
+	}
+	i, ok := Exact(v)
+	if !ok || !i.IsInt64() || i.Sign() < 0 {
+		diags = append(diags, diagnosis.Diagnosis{
+			Kind:  diagnosis.InvalidLength,
+			Error: diagnosis.InvalidLengthError{Expr: expr, Value: v.ExactString()},
+		})
+		return 0, diags
+	}
+	return i.Int64(), diags
+}
+
+// Decls folds the values of the const declarations among decls, which may refer to each other in any order
+// and to the constants of enclosing scopes through lookup. The values are keyed by name, constants
+// depending on themselves are reported and Unknown.
+func Decls(decls []ast.Stmt, lookup Lookup) (map[string]Value, []diagnosis.Diagnosis) {
+	d := declEvaluator{
+		decls:  map[string]ast.ValDecl{},
+		values: map[string]Value{},
+		state:  map[string]int{},
+		outer:  lookup,
+	}
+	var order []string
+	for _, stmt := range decls {
+		if decl, ok := stmt.Value.(ast.ValDecl); ok && decl.Const && decl.Pattern == nil {
+			if _, dup := d.decls[decl.Name.Literal]; !dup {
+				order = append(order, decl.Name.Literal)
+			}
+			d.decls[decl.Name.Literal] = decl
+		}
+	}
+	for _, name := range order {
+		d.eval(name)
+	}
+	return d.values, d.diags
+}
+
+const (
+	unvisited = iota
+	evaluating
+	done
+)
+
+type declEvaluator struct {
+	evaluator
+	decls  map[string]ast.ValDecl
+	values map[string]Value
+	state  map[string]int
+	outer  Lookup
+}
+
+func (d *declEvaluator) eval(name string) Value {
+	switch d.state[name] {
+	case done:
+		return d.values[name]
+	case evaluating:
+		d.report(diagnosis.ConstantCycle, diagnosis.ConstantCycleError{Ident: d.decls[name].Name})
+		d.state[name] = done
+		return Value{}
+	}
+
+	d.state[name] = evaluating
+	decl := d.decls[name]
+	v := Value{}
+	if decl.Value.Value != nil {
+		v = d.expr(decl.Value, d.lookup)
+	}
+	if d.state[name] == evaluating {
+		// A cycle through the constant already reported it and left it Unknown.
+		d.values[name] = v
+		d.state[name] = done
+	}
+	return d.values[name]
+}
+
+func (d *declEvaluator) lookup(ident ast.Ident) (Value, bool) {
+	if _, ok := d.decls[ident.Literal]; ok {
+		return d.eval(ident.Literal), true
+	}
+	if d.outer != nil {
+		return d.outer(ident)
+	}
+	return Value{}, false
+}
@@ -0,0 +1,289 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package constant
+
+import (
+	"cee/ast"
+	"cee/literals"
+	"cee/token"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+type Kind int
+
+const (
+	Unknown Kind = iota // the value could not be computed, an error was reported
+
+	Bool
+	String
+	Int
+	Float
+)
+
+var kindNames = [...]string{
+	Unknown: "unknown",
+	Bool:    "bool",
+	String:  "string",
+	Int:     "integer",
+	Float:   "float",
+}
+
+func (k Kind) String() string { return kindNames[k] }
+
+// Value is an exact constant, only the field of its Kind is set. Integers are arbitrary precision, floats
+// have the precision of decoded literals, char literals are integers.
+type Value struct {
+	Kind   Kind
+	Bool   bool
+	String string
+	Int    *big.Int
+	Float  *big.Float
+}
+
+func MakeBool(b bool) Value        { return Value{Kind: Bool, Bool: b} }
+func MakeString(s string) Value    { return Value{Kind: String, String: s} }
+func MakeInt(i *big.Int) Value     { return Value{Kind: Int, Int: i} }
+func MakeInt64(i int64) Value      { return MakeInt(big.NewInt(i)) }
+func MakeFloat(f *big.Float) Value { return Value{Kind: Float, Float: f} }
+
+var (
+	ErrDivisionByZero = errors.New("division by zero")
+	ErrUnsupported    = errors.New("imaginary constants are not supported")
+)
+
+// Make decodes a literal into a constant.
+func Make(lit ast.LiteralValue) (Value, error) {
+	v, err := literals.Decode(lit)
+	if err != nil {
+		return Value{}, err
+	}
+	switch v.Kind {
+	case literals.Int:
+		return MakeInt(v.Int), nil
+	case literals.Float:
+		return MakeFloat(v.Float), nil
+	case literals.String:
+		return MakeString(v.String), nil
+	case literals.Char:
+		return MakeInt64(int64(v.Char)), nil
+	}
+	return Value{}, ErrUnsupported
+}
+
+// ExactString returns the constant as source, strings quoted.
+func (v Value) ExactString() string {
+	switch v.Kind {
+	case Bool:
+		return strconv.FormatBool(v.Bool)
+	case String:
+		return token.Quote(v.String)
+	case Int:
+		return v.Int.String()
+	case Float:
+		return v.Float.Text('g', -1)
+	}
+	return "unknown"
+}
+
+// Int64 returns the integer value and whether it is an integer representable as int64.
+func (v Value) Int64() (int64, bool) {
+	if v.Kind != Int || !v.Int.IsInt64() {
+		return 0, false
+	}
+	return v.Int.Int64(), true
+}
+
+// Exact returns the integer value of an integer constant or of a float constant with an integral value,
+// which converts to integer types as untyped constants do in Go.
+func Exact(v Value) (*big.Int, bool) {
+	switch v.Kind {
+	case Int:
+		return v.Int, true
+	case Float:
+		if v.Float.IsInt() {
+			i, _ := v.Float.Int(nil)
+			return i, true
+		}
+	}
+	return nil, false
+}
+
+// Representable reports whether the constant is a value of the builtin integer type, Unknown values are.
+func Representable(v Value, kind ast.TypeKind) bool {
+	if v.Kind == Unknown {
+		return true
+	}
+	i, ok := Exact(v)
+	return ok && literals.Fits(i, kind)
+}
+
+func toFloat(v Value) *big.Float {
+	if v.Kind == Int {
+		return new(big.Float).SetPrec(literals.FloatPrec).SetInt(v.Int)
+	}
+	return v.Float
+}
+
+func newFloat() *big.Float { return new(big.Float).SetPrec(literals.FloatPrec) }
+
+func opError(op int, x, y Value) error {
+	if x.Kind == y.Kind {
+		return fmt.Errorf("operator %s not defined on %s", token.KeywordLiterals[op], x.Kind)
+	}
+	return fmt.Errorf("mismatched operands %s %s %s", x.Kind, token.KeywordLiterals[op], y.Kind)
+}
+
+// maxShift bounds the shift counts of constants, larger shifts overflow every integer type anyway.
+const maxShift = 1024
+
+// Unary folds `op x`, for the operators +, -, ! and ^, the bitwise complement.
+func Unary(op int, x Value) (Value, error) {
+	switch {
+	case x.Kind == Unknown:
+		return x, nil
+	case op == token.ADD && (x.Kind == Int || x.Kind == Float):
+		return x, nil
+	case op == token.SUB && x.Kind == Int:
+		return MakeInt(new(big.Int).Neg(x.Int)), nil
+	case op == token.SUB && x.Kind == Float:
+		return MakeFloat(newFloat().Neg(x.Float)), nil
+	case op == token.NOT && x.Kind == Bool:
+		return MakeBool(!x.Bool), nil
+	case op == token.XOR && x.Kind == Int:
+		return MakeInt(new(big.Int).Not(x.Int)), nil
+	}
+	return Value{}, fmt.Errorf("operator %s not defined on %s", token.KeywordLiterals[op], x.Kind)
+}
+
+// Binary folds `x op y`. Integers mixed with floats are converted to floats, integer division truncates.
+// Comparisons yield booleans.
+func Binary(x Value, op int, y Value) (Value, error) {
+	if x.Kind == Unknown || y.Kind == Unknown {
+		return Value{}, nil
+	}
+	switch op {
+	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+		return compare(x, op, y)
+	case token.SHL, token.SHR:
+		return shift(x, op, y)
+	}
+
+	if x.Kind == Int && y.Kind == Float || x.Kind == Float && y.Kind == Int {
+		x, y = MakeFloat(toFloat(x)), MakeFloat(toFloat(y))
+	}
+	if x.Kind != y.Kind {
+		return Value{}, opError(op, x, y)
+	}
+
+	switch x.Kind {
+	case Bool:
+		switch op {
+		case token.LAND:
+			return MakeBool(x.Bool && y.Bool), nil
+		case token.LOR:
+			return MakeBool(x.Bool || y.Bool), nil
+		}
+	case String:
+		if op == token.ADD {
+			return MakeString(x.String + y.String), nil
+		}
+	case Int:
+		z := new(big.Int)
+		switch op {
+		case token.ADD:
+			return MakeInt(z.Add(x.Int, y.Int)), nil
+		case token.SUB:
+			return MakeInt(z.Sub(x.Int, y.Int)), nil
+		case token.MUL:
+			return MakeInt(z.Mul(x.Int, y.Int)), nil
+		case token.QUO, token.REM:
+			if y.Int.Sign() == 0 {
+				return Value{}, ErrDivisionByZero
+			}
+			if op == token.QUO {
+				return MakeInt(z.Quo(x.Int, y.Int)), nil
+			}
+			return MakeInt(z.Rem(x.Int, y.Int)), nil
+		case token.AND:
+			return MakeInt(z.And(x.Int, y.Int)), nil
+		case token.OR:
+			return MakeInt(z.Or(x.Int, y.Int)), nil
+		case token.XOR:
+			return MakeInt(z.Xor(x.Int, y.Int)), nil
+		case token.AND_NOT:
+			return MakeInt(z.AndNot(x.Int, y.Int)), nil
+		}
+	case Float:
+		z := newFloat()
+		switch op {
+		case token.ADD:
+			return MakeFloat(z.Add(x.Float, y.Float)), nil
+		case token.SUB:
+			return MakeFloat(z.Sub(x.Float, y.Float)), nil
+		case token.MUL:
+			return MakeFloat(z.Mul(x.Float, y.Float)), nil
+		case token.QUO:
+			if y.Float.Sign() == 0 {
+				return Value{}, ErrDivisionByZero
+			}
+			return MakeFloat(z.Quo(x.Float, y.Float)), nil
+		}
+	}
+	return Value{}, opError(op, x, y)
+}
+
+func compare(x Value, op int, y Value) (Value, error) {
+	var c int
+	switch {
+	case x.Kind == Int && y.Kind == Int:
+		c = x.Int.Cmp(y.Int)
+	case (x.Kind == Int || x.Kind == Float) && (y.Kind == Int || y.Kind == Float):
+		c = toFloat(x).Cmp(toFloat(y))
+	case x.Kind == String && y.Kind == String:
+		switch {
+		case x.String < y.String:
+			c = -1
+		case x.String > y.String:
+			c = 1
+		}
+	case x.Kind == Bool && y.Kind == Bool && (op == token.EQL || op == token.NEQ):
+		if x.Bool != y.Bool {
+			c = 1
+		}
+	default:
+		return Value{}, opError(op, x, y)
+	}
+
+	switch op {
+	case token.EQL:
+		return MakeBool(c == 0), nil
+	case token.NEQ:
+		return MakeBool(c != 0), nil
+	case token.LSS:
+		return MakeBool(c < 0), nil
+	case token.LEQ:
+		return MakeBool(c <= 0), nil
+	case token.GTR:
+		return MakeBool(c > 0), nil
+	}
+	return MakeBool(c >= 0), nil
+}
+
+func shift(x Value, op int, y Value) (Value, error) {
+	if x.Kind != Int || y.Kind != Int {
+		return Value{}, opError(op, x, y)
+	}
+	if y.Int.Sign() < 0 || y.Int.Cmp(big.NewInt(maxShift)) > 0 {
+		return Value{}, fmt.Errorf("invalid shift count %s", y.Int)
+	}
+	n := uint(y.Int.Uint64())
+	if op == token.SHL {
+		return MakeInt(new(big.Int).Lsh(x.Int, n)), nil
+	}
+	return MakeInt(new(big.Int).Rsh(x.Int, n)), nil
+}
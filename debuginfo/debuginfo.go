@@ -0,0 +1,85 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package debuginfo
+
+import (
+	"cee/ast"
+	"cee/token"
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// Range is a decoded source range, tables outlive the file set the positions were allocated in.
+type Range struct {
+	From, To token.Position
+}
+
+func NewRange(file *token.File, pos ast.PosRange) Range {
+	return Range{From: file.Position(pos.From), To: file.Position(pos.To)}
+}
+
+// Line maps a location in generated output to the source range it was generated from.
+// Generated is backend specific: an instruction index for bytecode, a byte offset for Go and wasm.
+type Line struct {
+	Generated int
+	Source    Range
+}
+
+// Var records where a variable lives during its scope.
+// Location is backend specific, e.g. a register, a stack slot or a Go identifier.
+type Var struct {
+	Name     string
+	Scope    Range
+	Location string
+}
+
+type Table struct {
+	File  string
+	Lines []Line
+	Vars  []Var
+}
+
+// AddLine appends a line entry. Entries must be added in increasing generated order,
+// an entry at the same location as the previous one replaces it.
+func (t *Table) AddLine(generated int, source Range) {
+	if n := len(t.Lines); n != 0 && t.Lines[n-1].Generated == generated {
+		t.Lines[n-1].Source = source
+		return
+	}
+	t.Lines = append(t.Lines, Line{Generated: generated, Source: source})
+}
+
+func (t *Table) AddVar(name string, scope Range, location string) {
+	t.Vars = append(t.Vars, Var{Name: name, Scope: scope, Location: location})
+}
+
+// Lookup returns the source range covering the generated location, that is the last entry not after it.
+func (t *Table) Lookup(generated int) (Range, bool) {
+	i := sort.Search(len(t.Lines), func(i int) bool { return t.Lines[i].Generated > generated })
+	if i == 0 {
+		return Range{}, false
+	}
+	return t.Lines[i-1].Source, true
+}
+
+// VarsAt returns the variables whose scope contains the source offset, innermost last.
+func (t *Table) VarsAt(offset int) []Var {
+	var vars []Var
+	for _, v := range t.Vars {
+		if v.Scope.From.Offset <= offset && offset < v.Scope.To.Offset {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
+func (t *Table) Encode(w io.Writer) error { return json.NewEncoder(w).Encode(t) }
+
+func Decode(r io.Reader) (Table, error) {
+	var t Table
+	err := json.NewDecoder(r).Decode(&t)
+	return t, err
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package debuginfo
+
+import (
+	"bytes"
+	"cee/token"
+	"reflect"
+	"testing"
+)
+
+func at(line, offset int) Range {
+	return Range{
+		From: token.Position{Filename: "a.cee", Offset: offset, Line: line},
+		To:   token.Position{Filename: "a.cee", Offset: offset + 5, Line: line},
+	}
+}
+
+func TestLookup(t *testing.T) {
+	var table Table
+	table.AddLine(0, at(0, 0))
+	table.AddLine(4, at(1, 10))
+	table.AddLine(4, at(2, 20)) // replaces the entry at 4
+	table.AddLine(9, at(3, 30))
+
+	if len(table.Lines) != 3 {
+		t.Fatalf("%d line entries, want 3", len(table.Lines))
+	}
+	for generated, want := range map[int]int{0: 0, 3: 0, 4: 2, 8: 2, 9: 3, 100: 3} {
+		if r, ok := table.Lookup(generated); !ok || r.From.Line != want {
+			t.Errorf("Lookup(%d) = %+v, %v, want line %d", generated, r, ok, want)
+		}
+	}
+
+	table = Table{}
+	table.AddLine(3, at(0, 0))
+	if _, ok := table.Lookup(2); ok {
+		t.Errorf("Lookup before the first entry succeeded")
+	}
+}
+
+func TestVarsAt(t *testing.T) {
+	fn := Range{From: token.Position{Offset: 0}, To: token.Position{Offset: 100}}
+	block := Range{From: token.Position{Offset: 40}, To: token.Position{Offset: 60}}
+	var table Table
+	table.AddVar("a", fn, "a")
+	table.AddVar("b", block, "r1")
+
+	for offset, want := range map[int][]string{10: {"a"}, 40: {"a", "b"}, 59: {"a", "b"}, 60: {"a"}, 100: nil} {
+		var got []string
+		for _, v := range table.VarsAt(offset) {
+			got = append(got, v.Name)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("VarsAt(%d) = %v, want %v", offset, got, want)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	table := Table{File: "a.cee"}
+	table.AddLine(7, at(1, 12))
+	table.AddVar("x", at(1, 12), "x")
+
+	var buf bytes.Buffer
+	if err := table.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(&buf)
+	if err != nil || !reflect.DeepEqual(got, table) {
+		t.Errorf("Decode = %+v, %v, want %+v", got, err, table)
+	}
+}
@@ -0,0 +1,361 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package desugar
+
+import (
+	"cee"
+	"cee/ast"
+	"cee/token"
+	"strconv"
+)
+
+// Normalizer rewrites syntax sugar into core forms the checker and the backends can rely on:
+//
+//   - `a op= b` becomes `a = a op b`, the operands a evaluates with effects bound to temporaries beforehand;
+//   - `for [i,] v in expr { ... }` becomes a `for cond { ... }` loop over temporaries, unless expr is declared
+//     a map or a channel, which cannot be indexed and are iterated by the core foreach;
+//   - `else if` becomes an else block holding the nested branch;
+//   - `"a${x}b"` becomes the concatenation `"a" + x + "b"`.
+//
+// Synthesized nodes span the sugar they replace, temporaries are named `$hint<n>`, which no user identifier can be.
+type Normalizer struct {
+	tmp    int
+	scopes []map[string]ast.Type // declared types of the variables in scope
+}
+
+// Normalize returns the normalized copy of decls, decls are left untouched.
+func Normalize(decls []ast.Stmt) []ast.Stmt {
+	var n Normalizer
+	return n.Stmts(ast.Clone(decls))
+}
+
+func (n *Normalizer) temp(hint string) string {
+	n.tmp++
+	return "$" + hint + strconv.Itoa(n.tmp)
+}
+
+func (n *Normalizer) enterScope() { n.scopes = append(n.scopes, map[string]ast.Type{}) }
+
+func (n *Normalizer) exitScope() { n.scopes = n.scopes[:len(n.scopes)-1] }
+
+func (n *Normalizer) declare(name string, typ ast.Type) {
+	if len(n.scopes) == 0 {
+		n.enterScope()
+	}
+	n.scopes[len(n.scopes)-1][name] = typ
+}
+
+// typeOf returns the declared type of a variable, the zero Type for other expressions.
+func (n *Normalizer) typeOf(e ast.Expr) ast.Type {
+	if id, ok := e.Value.(ast.Ident); ok {
+		for i := len(n.scopes) - 1; i >= 0; i-- {
+			if typ, ok := n.scopes[i][id.Literal]; ok {
+				return typ
+			}
+		}
+	}
+	return ast.Type{}
+}
+
+func stmt(kind ast.StmtKind, value ast.Node) ast.Stmt {
+	return ast.Stmt{Union: cee.Union[ast.StmtKind]{Tag: kind, Value: value}}
+}
+
+func expr(kind ast.ExprKind, value ast.Node) ast.Expr {
+	return ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: kind, Value: value}}
+}
+
+func ident(pos ast.PosRange, name string) ast.Ident {
+	return ast.Ident{Token: ast.Token{PosRange: pos, Kind: token.IDENT, Literal: name}}
+}
+
+func identExpr(pos ast.PosRange, name string) ast.Expr { return expr(ast.ExprIdent, ident(pos, name)) }
+
+func operator(pos ast.PosRange, kind int) ast.Token {
+	return ast.Token{PosRange: pos, Kind: kind, Literal: token.KeywordLiterals[kind]}
+}
+
+func intLiteral(pos ast.PosRange, lit string) ast.Expr {
+	return expr(ast.ExprLiteralValue, ast.LiteralValue{Token: ast.Token{PosRange: pos, Kind: token.INT, Literal: lit}})
+}
+
+func binary(pos ast.PosRange, op int, lhs, rhs ast.Expr) ast.Expr {
+	return expr(ast.ExprBinary, ast.BinaryExpr{PosRange: pos, Operator: operator(pos, op), Exprs: [2]ast.Expr{lhs, rhs}})
+}
+
+func stringLiteral(lit ast.LiteralValue) ast.Expr { return expr(ast.ExprLiteralValue, lit) }
+
+func val(pos ast.PosRange, mutable bool, name string, value ast.Expr) ast.Stmt {
+	return stmt(ast.StmtValDecl, ast.ValDecl{PosRange: pos, Mutable: mutable, Name: ident(pos, name), Value: value})
+}
+
+func assign(pos ast.PosRange, lhs, rhs ast.Expr) ast.Stmt {
+	return stmt(ast.StmtAssign, ast.AssignStmt{PosRange: pos, Operator: operator(pos, token.ASSIGN), ExprL: lhs, ExprR: rhs})
+}
+
+// Stmts normalizes a statement list, a statement may expand into several. The nodes are modified,
+// Normalize works on a copy.
+func (n *Normalizer) Stmts(stmts []ast.Stmt) []ast.Stmt {
+	var out []ast.Stmt
+	for _, s := range stmts {
+		out = append(out, n.Stmt(s)...)
+	}
+	return out
+}
+
+func (n *Normalizer) Block(b ast.StmtBlockExpr) ast.StmtBlockExpr {
+	n.enterScope()
+	defer n.exitScope()
+	b.Stmts = n.Stmts(b.Stmts)
+	return b
+}
+
+// body normalizes the body of a function with its parameters in scope.
+func (n *Normalizer) body(t ast.FuncType, b ast.StmtBlockExpr) ast.StmtBlockExpr {
+	n.enterScope()
+	defer n.exitScope()
+	for _, param := range t.Params {
+		for _, id := range param.Idents {
+			n.declare(id.Literal, param.Type)
+		}
+	}
+	return n.Block(b)
+}
+
+func (n *Normalizer) Stmt(s ast.Stmt) []ast.Stmt {
+	switch v := s.Value.(type) {
+	case ast.Expr:
+		s.Value = n.Expr(v)
+	case ast.ValDecl:
+		v.Value = n.Expr(v.Value)
+		typ := v.Type
+		if typ.Value == nil && typ.Tag == 0 && v.Pattern == nil {
+			typ = n.typeOf(v.Value)
+		}
+		for _, id := range v.Idents() {
+			n.declare(id.Literal, typ)
+		}
+		s.Value = v
+	case ast.GenDecl:
+		for _, id := range v.Idents {
+			n.declare(id.Literal, v.Type)
+		}
+	case ast.FuncDecl:
+		if v.Stmt != nil {
+			body := n.body(v.Type, *v.Stmt)
+			v.Stmt = &body
+		}
+		s.Value = v
+	case ast.ReturnStmt:
+		for i := range v.Exprs {
+			v.Exprs[i] = n.Expr(v.Exprs[i])
+		}
+	case ast.AssignStmt:
+		return n.assign(v)
+	case ast.LoopStmt:
+		v.Cond = n.Expr(v.Cond)
+		v.Stmt = n.Block(v.Stmt)
+		s.Value = v
+	case ast.ForeachStmt:
+		return n.foreach(v)
+	case ast.EndlessForStmt:
+		v.Stmt = n.Block(v.Stmt)
+		s.Value = v
+	case ast.DeferStmt:
+		v.Expr = n.Expr(v.Expr)
+		s.Value = v
+	}
+	return []ast.Stmt{s}
+}
+
+func (n *Normalizer) Expr(e ast.Expr) ast.Expr {
+	switch v := e.Value.(type) {
+	case ast.CallExpr:
+		v.Callee = n.Expr(v.Callee)
+		for i := range v.Params {
+			v.Params[i] = n.Expr(v.Params[i])
+		}
+		e.Value = v
+	case ast.UnaryExpr:
+		v.Expr = n.Expr(v.Expr)
+		e.Value = v
+	case ast.BinaryExpr:
+		v.Exprs[0] = n.Expr(v.Exprs[0])
+		v.Exprs[1] = n.Expr(v.Exprs[1])
+		e.Value = v
+	case ast.IndexExpr:
+		v.Expr = n.Expr(v.Expr)
+		v.Index = n.Expr(v.Index)
+		e.Value = v
+	case ast.MemberSelectExpr:
+		v.Expr = n.Expr(v.Expr)
+		e.Value = v
+	case ast.TryExpr:
+		v.Expr = n.Expr(v.Expr)
+		e.Value = v
+	case ast.IntrinsicExpr:
+		for i := range v.Params {
+			v.Params[i] = n.Expr(v.Params[i])
+		}
+	case ast.EllipsisExpr:
+		v.Array = n.Expr(v.Array)
+		e.Value = v
+	case ast.CompositeLit:
+		for i := range v.Elems {
+			v.Elems[i].Key = n.Expr(v.Elems[i].Key)
+			v.Elems[i].Value = n.Expr(v.Elems[i].Value)
+		}
+	case ast.InterpolatedString:
+		return n.interpolation(v)
+	case ast.FuncLitExpr:
+		v.Body = n.body(v.Type, v.Body)
+		e.Value = v
+	case ast.StmtBlockExpr:
+		e.Value = n.Block(v)
+	case ast.BranchExpr:
+		e.Value = n.branch(v)
+	case ast.MatchExpr:
+		v.Subject = n.Expr(v.Subject)
+		for i := range v.Arms {
+			v.Arms[i].Guard = n.Expr(v.Arms[i].Guard)
+			v.Arms[i].Body = n.Block(v.Arms[i].Body)
+		}
+		e.Value = v
+	}
+	return e
+}
+
+// assign rewrites `a op= b` into `a = a op b`, so that `a[f()] += 1` becomes
+//
+//	val $t = f()
+//	a[$t] = a[$t] + 1
+func (n *Normalizer) assign(a ast.AssignStmt) []ast.Stmt {
+	var stmts []ast.Stmt
+	lhs, rhs := n.Expr(a.ExprL), n.Expr(a.ExprR)
+	if a.Operator.Kind != token.ASSIGN && a.Operator.Kind != 0 {
+		lhs = n.place(lhs, &stmts)
+		rhs = binary(a.PosRange, token.CompoundAssignOperators[a.Operator.Kind], ast.Clone(lhs), rhs)
+	}
+	return append(stmts, assign(a.PosRange, lhs, rhs))
+}
+
+// place returns the assignment target e with its operands bound to temporaries by stmts: the index of an
+// index expression, and the operand of an index, a member selection or a dereference unless it is a place itself.
+func (n *Normalizer) place(e ast.Expr, stmts *[]ast.Stmt) ast.Expr {
+	switch v := e.Value.(type) {
+	case ast.IndexExpr:
+		v.Expr = n.place(v.Expr, stmts)
+		v.Index = n.once(v.Index, stmts)
+		e.Value = v
+	case ast.MemberSelectExpr:
+		v.Expr = n.place(v.Expr, stmts)
+		e.Value = v
+	case ast.UnaryExpr:
+		if v.Operator.Kind == token.MUL {
+			v.Expr = n.once(v.Expr, stmts)
+			e.Value = v
+		}
+	case ast.Ident, ast.QualifiedIdent, ast.LiteralValue:
+	default:
+		return n.once(e, stmts)
+	}
+	return e
+}
+
+// once binds e to a temporary by stmts unless evaluating it has no effect.
+func (n *Normalizer) once(e ast.Expr, stmts *[]ast.Stmt) ast.Expr {
+	switch e.Value.(type) {
+	case ast.Ident, ast.QualifiedIdent, ast.LiteralValue:
+		return e
+	}
+	pos := e.GetPosRange()
+	name := n.temp("t")
+	*stmts = append(*stmts, val(pos, false, name, e))
+	return identExpr(pos, name)
+}
+
+// branch rewrites `if a { A } else if b { B }` into `if a { A } else { if b { B } }`, the else block spanning
+// the nested branch from the end of the first.
+func (n *Normalizer) branch(b ast.BranchExpr) ast.BranchExpr {
+	b.Cond = n.Expr(b.Cond)
+	b.Branch = n.Block(b.Branch)
+	if nested, ok := b.ElseIf(); ok {
+		b.ElseBranch.PosRange = ast.PosRange{From: b.Branch.To, To: nested.To}
+	}
+	b.ElseBranch = n.Block(b.ElseBranch)
+	return b
+}
+
+// interpolation rewrites `"a${x}b${y}"` into `"a" + x + "b" + y`. The concatenation starts with the first
+// segment, even empty, so that each embedded value is appended to a string, which converts it as the interpolation does.
+func (n *Normalizer) interpolation(s ast.InterpolatedString) ast.Expr {
+	result := stringLiteral(s.Lits[0])
+	for i, x := range s.Exprs {
+		x = n.Expr(x)
+		result = binary(ast.PosRange{From: s.From, To: x.GetPosRange().To}, token.ADD, result, x)
+		if lit := s.Lits[i+1]; lit.Literal != `""` {
+			result = binary(ast.PosRange{From: s.From, To: lit.To}, token.ADD, result, stringLiteral(lit))
+		}
+	}
+	return result
+}
+
+// foreach rewrites `for [i,] v in expr { ... }` into
+//
+//	val $iter = expr
+//	val $len = len($iter)
+//	var $i = -1
+//	for $i + 1 < $len {
+//		$i = $i + 1
+//		val i = $i
+//		val v = $iter[$i]
+//		...
+//	}
+//
+// The counter is incremented on entry so that `continue` does not skip it. Maps and channels are kept as
+// foreach, with their bindings in scope.
+func (n *Normalizer) foreach(s ast.ForeachStmt) []ast.Stmt {
+	typ := n.typeOf(s.Expr)
+	s.Expr = n.Expr(s.Expr)
+	n.enterScope()
+	defer n.exitScope()
+	for _, id := range s.IdentList {
+		n.declare(id.Literal, ast.Type{})
+	}
+	if typ.Tag == ast.TypeMap || typ.Tag == ast.TypeChan {
+		s.Stmt = n.Block(s.Stmt)
+		return []ast.Stmt{stmt(ast.StmtForeach, s)}
+	}
+
+	pos := s.PosRange
+	iterName, lenName, idxName := n.temp("iter"), n.temp("len"), n.temp("i")
+	iter, length, idx := identExpr(pos, iterName), identExpr(pos, lenName), identExpr(pos, idxName)
+
+	count := expr(ast.ExprCall, ast.CallExpr{PosRange: pos, Callee: identExpr(pos, "len"), Params: []ast.Expr{iter}})
+	start := expr(ast.ExprUnary, ast.UnaryExpr{PosRange: pos, Operator: operator(pos, token.SUB), Expr: intLiteral(pos, "1")})
+	next := binary(pos, token.ADD, idx, intLiteral(pos, "1"))
+
+	header := []ast.Stmt{assign(pos, idx, next)}
+	element := expr(ast.ExprIndex, ast.IndexExpr{PosRange: pos, Expr: iter, Index: idx})
+	bindings := []ast.Expr{element}
+	if len(s.IdentList) == 2 {
+		bindings = []ast.Expr{idx, element}
+	}
+	for i, id := range s.IdentList {
+		if i < len(bindings) {
+			header = append(header, val(id.PosRange, false, id.Literal, bindings[i]))
+		}
+	}
+
+	body := n.Block(s.Stmt)
+	body.Stmts = append(header, body.Stmts...)
+
+	return []ast.Stmt{
+		val(pos, false, iterName, s.Expr),
+		val(pos, false, lenName, count),
+		val(pos, true, idxName, start),
+		stmt(ast.StmtLoop, ast.LoopStmt{PosRange: pos, Cond: binary(pos, token.LSS, next, length), Stmt: body}),
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package desugar
+
+import (
+	"cee/ast"
+	"cee/parser"
+	"cee/token"
+	"strings"
+	"testing"
+)
+
+func print(stmts []ast.Stmt) string {
+	var b strings.Builder
+	for _, s := range stmts {
+		if err := ast.Fprint(&b, s); err != nil {
+			panic(err)
+		}
+	}
+	return b.String()
+}
+
+func TestNormalizeAssign(t *testing.T) {
+	pos := ast.PosRange{From: 1, To: 7}
+	decls := []ast.Stmt{stmt(ast.StmtAssign, ast.AssignStmt{
+		PosRange: pos,
+		Operator: operator(pos, token.ADD_ASSIGN),
+		ExprL:    identExpr(pos, "a"),
+		ExprR:    intLiteral(pos, "2"),
+	})}
+
+	if got, want := print(Normalize(decls)), "a = a + 2\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := print(decls); got != "a += 2\n" {
+		t.Errorf("input modified: %q", got)
+	}
+}
+
+func TestNormalizeForeach(t *testing.T) {
+	pos := ast.PosRange{From: 1, To: 20}
+	body := ast.StmtBlockExpr{PosRange: pos, Stmts: []ast.Stmt{stmt(ast.StmtExpr, ast.Expr(identExpr(pos, "v")))}}
+	decls := []ast.Stmt{stmt(ast.StmtForeach, ast.ForeachStmt{
+		PosRange:  pos,
+		IdentList: []ast.Ident{ident(pos, "v")},
+		Expr:      identExpr(pos, "xs"),
+		Stmt:      body,
+	})}
+
+	want := strings.Join([]string{
+		"val $iter1 = xs",
+		"val $len2 = len($iter1)",
+		"var $i3 = -1",
+		"for $i3 + 1 < $len2 {",
+		"\t$i3 = $i3 + 1",
+		"\tval v = $iter1[$i3]",
+		"\tv",
+		"}",
+		"",
+	}, "\n")
+	if got := print(Normalize(decls)); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestNormalizeSource(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "a.cee", []byte(`package a
+
+fun f(xs []i64, m map[string]i64, n i64) {
+	xs[g()] += n
+	for k, v in m { h(k, v) }
+	if n < 0 { h(0, 0) } else if n < 1 { h(1, 1) }
+	val s = "n = ${n}!"
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"fun f(xs []i64, m map[string]i64, n i64) {",
+		"\tval $t1 = g()",
+		"\txs[$t1] = xs[$t1] + n",
+		"\tfor k, v in m {",
+		"\t\th(k, v)",
+		"\t}",
+		"\tif n < 0 {",
+		"\t\th(0, 0)",
+		"\t} else {",
+		"\t\tif n < 1 {",
+		"\t\t\th(1, 1)",
+		"\t\t}",
+		"\t}",
+		`	val s = "n = " + n + "!"`,
+		"}",
+		"",
+	}, "\n")
+	if got := print(Normalize(f.Decls)); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := print(f.Decls); !strings.Contains(got, "} else if n < 1 {") {
+		t.Errorf("input modified:\n%s", got)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package diagnosis
+
+import (
+	"cee/ast"
+	. "cee/locale"
+	"fmt"
+)
+
+type UnknownAttributeKeyError struct {
+	Attr string
+	Key  ast.Ident
+}
+
+func (e UnknownAttributeKeyError) GetPosRange() ast.PosRange { return e.Key.PosRange }
+
+func (e UnknownAttributeKeyError) Error() string {
+	return fmt.Sprint(Tr("unknown key of attribute "), e.Attr, ": ", e.Key.Literal)
+}
+
+// InvalidAttributeError reports an argument whose value is not of the kind the attribute expects.
+type InvalidAttributeError struct {
+	Arg ast.AttributeArg
+}
+
+func (e InvalidAttributeError) GetPosRange() ast.PosRange { return e.Arg.PosRange }
+
+func (e InvalidAttributeError) Error() string {
+	return fmt.Sprint(Tr("invalid attribute value: "), e.Arg.Value.Literal)
+}
+
+// InvalidEmbedError reports an embed attribute not on a val or var declaration without value,
+// or whose argument is not a single relative path.
+type InvalidEmbedError struct {
+	Attr ast.Attribute
+}
+
+func (e InvalidEmbedError) GetPosRange() ast.PosRange { return e.Attr.PosRange }
+
+func (e InvalidEmbedError) Error() string {
+	return Tr("invalid embed: expected @embed(\"relative/path\") on a val or var declaration without value")
+}
+
+// EmbedFileError reports a file that could not be embedded, e.g. a missing one.
+type EmbedFileError struct {
+	Attr ast.Attribute
+	Path string
+	Err  error
+}
+
+func (e EmbedFileError) GetPosRange() ast.PosRange { return e.Attr.PosRange }
+
+func (e EmbedFileError) Error() string {
+	return fmt.Sprint(Tr("cannot embed "), e.Path, ": ", e.Err)
+}
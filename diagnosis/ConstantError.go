@@ -0,0 +1,81 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package diagnosis
+
+import (
+	"cee/ast"
+	. "cee/locale"
+	"fmt"
+)
+
+// NotConstantError reports an expression required to be constant which is not.
+type NotConstantError struct {
+	Expr ast.Expr
+}
+
+func (e NotConstantError) GetPosRange() ast.PosRange { return e.Expr.GetPosRange() }
+
+func (e NotConstantError) Error() string {
+	return Tr("constant error: not a constant expression")
+}
+
+// ConstantOverflowError reports a constant out of the range of the type it is used as, Value is the constant.
+type ConstantOverflowError struct {
+	Expr  ast.Expr
+	Value string
+	Type  string
+}
+
+func (e ConstantOverflowError) GetPosRange() ast.PosRange { return e.Expr.GetPosRange() }
+
+func (e ConstantOverflowError) Error() string {
+	return fmt.Sprint(Tr("constant error: "), e.Value, Tr(" overflows "), e.Type)
+}
+
+// DivisionByZeroError reports a constant division or remainder by zero.
+type DivisionByZeroError struct {
+	Expr ast.Expr
+}
+
+func (e DivisionByZeroError) GetPosRange() ast.PosRange { return e.Expr.GetPosRange() }
+
+func (e DivisionByZeroError) Error() string {
+	return Tr("constant error: division by zero")
+}
+
+// InvalidConstantOpError reports an operator not defined on its constant operands, Reason tells why.
+type InvalidConstantOpError struct {
+	Expr   ast.Expr
+	Reason string
+}
+
+func (e InvalidConstantOpError) GetPosRange() ast.PosRange { return e.Expr.GetPosRange() }
+
+func (e InvalidConstantOpError) Error() string {
+	return fmt.Sprint(Tr("constant error: invalid operation: "), e.Reason)
+}
+
+// InvalidLengthError reports an array length which is not a non-negative integer constant, Value is the constant.
+type InvalidLengthError struct {
+	Expr  ast.Expr
+	Value string
+}
+
+func (e InvalidLengthError) GetPosRange() ast.PosRange { return e.Expr.GetPosRange() }
+
+func (e InvalidLengthError) Error() string {
+	return fmt.Sprint(Tr("constant error: invalid array length: "), e.Value)
+}
+
+// ConstantCycleError reports a constant whose value depends on itself.
+type ConstantCycleError struct {
+	Ident ast.Ident
+}
+
+func (e ConstantCycleError) GetPosRange() ast.PosRange { return e.Ident.PosRange }
+
+func (e ConstantCycleError) Error() string {
+	return fmt.Sprint(Tr("constant error: initialization cycle: "), e.Ident.Literal)
+}
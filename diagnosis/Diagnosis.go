@@ -4,7 +4,28 @@
 
 package diagnosis
 
+import (
+	"cee/ast"
+	"sort"
+)
+
 type Diagnosis struct {
-	Kind  int
-	Error any
+	Kind     int
+	Error    any
+	Severity Severity
+}
+
+func (d Diagnosis) IsError() bool { return d.Severity == SeverityError }
+
+// Sort orders the diagnostics of a file by position, those without one first.
+// The sort is stable, diagnostics at the same position keep the order they were reported in.
+func Sort(diags []Diagnosis) {
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, aok := diags[i].Error.(ast.Node)
+		b, bok := diags[j].Error.(ast.Node)
+		if !aok || !bok {
+			return !aok && bok
+		}
+		return a.GetPosRange().From < b.GetPosRange().From
+	})
 }
@@ -0,0 +1,51 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package diagnosis
+
+import (
+	"cee/ast"
+	"cee/token"
+	"errors"
+	"testing"
+)
+
+func TestSort(t *testing.T) {
+	at := func(from int) InvalidEncodingError {
+		return InvalidEncodingError{PosRange: ast.PosRange{From: 1 + token.Pos(from), To: 2 + token.Pos(from)}}
+	}
+	diags := []Diagnosis{
+		{Kind: 1, Error: at(5)},
+		{Kind: 2, Error: at(1)},
+		{Kind: 3, Error: errors.New("no position")},
+		{Kind: 4, Error: at(5)},
+	}
+	Sort(diags)
+	for i, kind := range []int{3, 2, 1, 4} {
+		if diags[i].Kind != kind {
+			t.Fatalf("order %v", diags)
+		}
+	}
+}
+
+func TestSeverities(t *testing.T) {
+	severities, err := ParseSeverities("literal-precision=ignore, literal-overflow=warning")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := severities.Apply([]Diagnosis{
+		{Kind: LiteralPrecision, Severity: SeverityWarning},
+		{Kind: LiteralOverflow},
+		{Kind: InvalidLiteral},
+	})
+	if len(diags) != 2 || diags[0].Severity != SeverityWarning || diags[0].IsError() || !diags[1].IsError() {
+		t.Errorf("applied %v", diags)
+	}
+
+	for _, s := range []string{"literal-overflow", "unknown=error", "literal-overflow=fatal"} {
+		if _, err := ParseSeverities(s); err == nil {
+			t.Errorf("ParseSeverities(%s) succeeded", s)
+		}
+	}
+}
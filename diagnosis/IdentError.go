@@ -0,0 +1,24 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package diagnosis
+
+import (
+	"cee/ast"
+	. "cee/locale"
+	"fmt"
+)
+
+// ConfusableIdentError reports a declaration whose name looks like the name of another visible from it.
+type ConfusableIdentError struct {
+	Ident ast.PosRange
+	Name  string
+	Other string
+}
+
+func (e ConfusableIdentError) GetPosRange() ast.PosRange { return e.Ident }
+
+func (e ConfusableIdentError) Error() string {
+	return fmt.Sprintf(Tr("identifier %+q is confusable with %+q"), e.Name, e.Other)
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package diagnosis
+
+import (
+	"cee/ast"
+	. "cee/locale"
+	"fmt"
+)
+
+// UnresolvedImportError reports an import of a package neither in the module nor in its requirements.
+type UnresolvedImportError struct {
+	Decl ast.ImportDecl
+	Name string
+}
+
+func (e UnresolvedImportError) GetPosRange() ast.PosRange { return e.Decl.CanonicalName.PosRange }
+
+func (e UnresolvedImportError) Error() string {
+	return fmt.Sprint(Tr("import error: cannot resolve package: "), e.Name)
+}
+
+// ImportCollisionError reports an import binding the name of a declaration of the package.
+// It is positioned at the alias binding the name, or at the canonical name it derives from.
+type ImportCollisionError struct {
+	Decl ast.ImportDecl
+	Name string
+}
+
+func (e ImportCollisionError) GetPosRange() ast.PosRange {
+	if e.Decl.Alias != nil {
+		return e.Decl.Alias.PosRange
+	}
+	return e.Decl.CanonicalName.PosRange
+}
+
+func (e ImportCollisionError) Error() string {
+	return fmt.Sprint(Tr("import error: import name collides with a declaration: "), e.Name)
+}
+
+type UndeclaredMemberError struct {
+	Member  ast.Ident
+	Package string
+}
+
+func (e UndeclaredMemberError) GetPosRange() ast.PosRange { return e.Member.PosRange }
+
+func (e UndeclaredMemberError) Error() string {
+	return fmt.Sprint(Tr("import error: undeclared member of package "), e.Package, ": ", e.Member.Literal)
+}
+
+// UnexportedMemberError reports the use of a declaration without pub from another package.
+type UnexportedMemberError struct {
+	Member  ast.Ident
+	Package string
+}
+
+func (e UnexportedMemberError) GetPosRange() ast.PosRange { return e.Member.PosRange }
+
+func (e UnexportedMemberError) Error() string {
+	return fmt.Sprint(Tr("import error: member of package "), e.Package, Tr(" is not pub: "), e.Member.Literal)
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package diagnosis
+
+import (
+	"cee/ast"
+	. "cee/locale"
+	"fmt"
+)
+
+type InvalidLiteralError struct {
+	Literal ast.LiteralValue
+}
+
+func (e InvalidLiteralError) GetPosRange() ast.PosRange { return e.Literal.PosRange }
+
+func (e InvalidLiteralError) Error() string {
+	return fmt.Sprint(Tr("invalid literal: "), e.Literal.Literal)
+}
+
+// LiteralDigitsError reports a literal with a base prefix and no digits, as `0x`.
+type LiteralDigitsError struct {
+	Literal ast.LiteralValue
+	Base    string // of the prefix: hexadecimal, octal or binary
+}
+
+func (e LiteralDigitsError) GetPosRange() ast.PosRange { return e.Literal.PosRange }
+
+func (e LiteralDigitsError) Error() string {
+	return fmt.Sprint(Tr(e.Base), Tr(" literal has no digits"))
+}
+
+// LiteralOverflowError reports a literal out of the range of the type it is used as.
+type LiteralOverflowError struct {
+	Literal ast.LiteralValue
+	Type    string
+}
+
+func (e LiteralOverflowError) GetPosRange() ast.PosRange { return e.Literal.PosRange }
+
+func (e LiteralOverflowError) Error() string {
+	return fmt.Sprint(e.Literal.Literal, Tr(" overflows "), e.Type)
+}
+
+// LiteralPrecisionError reports a float literal with more precision than its type holds, Value is the literal
+// as stored.
+type LiteralPrecisionError struct {
+	Literal ast.LiteralValue
+	Type    string
+	Value   string
+}
+
+func (e LiteralPrecisionError) GetPosRange() ast.PosRange { return e.Literal.PosRange }
+
+func (e LiteralPrecisionError) Error() string {
+	return fmt.Sprint(e.Literal.Literal, Tr(" loses precision as "), e.Type, Tr(", it becomes "), e.Value)
+}
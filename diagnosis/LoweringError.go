@@ -0,0 +1,64 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package diagnosis
+
+import (
+	"cee/ast"
+	. "cee/locale"
+	"fmt"
+)
+
+type UnsupportedNodeError struct {
+	Node ast.Node
+}
+
+func (e UnsupportedNodeError) GetPosRange() ast.PosRange { return e.Node.GetPosRange() }
+
+func (e UnsupportedNodeError) Error() string {
+	return Tr("lowering error: unsupported node")
+}
+
+// InvalidTryError reports error propagation in a function whose last result is not an error.
+type InvalidTryError struct {
+	Node ast.Node
+}
+
+func (e InvalidTryError) GetPosRange() ast.PosRange { return e.Node.GetPosRange() }
+
+func (e InvalidTryError) Error() string {
+	return Tr("lowering error: try requires the enclosing function to return an error as its last result")
+}
+
+// InvalidErrdeferError reports errdefer in a function whose last result is not an error.
+type InvalidErrdeferError struct {
+	Node ast.Node
+}
+
+func (e InvalidErrdeferError) GetPosRange() ast.PosRange { return e.Node.GetPosRange() }
+
+func (e InvalidErrdeferError) Error() string {
+	return Tr("lowering error: errdefer requires the enclosing function to return an error as its last result")
+}
+
+type UnknownIntrinsicError struct {
+	Expr ast.IntrinsicExpr
+}
+
+func (e UnknownIntrinsicError) GetPosRange() ast.PosRange { return e.Expr.PosRange }
+
+func (e UnknownIntrinsicError) Error() string {
+	return fmt.Sprint(Tr("lowering error: unknown intrinsic: "), "@", e.Expr.Namespace.Literal, ".", e.Expr.Name.Literal)
+}
+
+type IntrinsicArityError struct {
+	Expr ast.IntrinsicExpr
+	Want int
+}
+
+func (e IntrinsicArityError) GetPosRange() ast.PosRange { return e.Expr.PosRange }
+
+func (e IntrinsicArityError) Error() string {
+	return fmt.Sprint(Tr("lowering error: wrong number of intrinsic arguments: "), e.Expr.Name.Literal, Tr(" takes "), e.Want, Tr(", have "), len(e.Expr.Params))
+}
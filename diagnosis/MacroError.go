@@ -0,0 +1,55 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package diagnosis
+
+import (
+	"cee/ast"
+	. "cee/locale"
+	"fmt"
+)
+
+type UnknownMacroError struct {
+	Name ast.Ident
+}
+
+func (e UnknownMacroError) GetPosRange() ast.PosRange { return e.Name.PosRange }
+
+func (e UnknownMacroError) Error() string {
+	return fmt.Sprint(Tr("unknown macro: "), e.Name.Literal)
+}
+
+type MacroRedefinedError struct {
+	Name ast.Ident
+}
+
+func (e MacroRedefinedError) GetPosRange() ast.PosRange { return e.Name.PosRange }
+
+func (e MacroRedefinedError) Error() string {
+	return fmt.Sprint(Tr("macro redefined: "), e.Name.Literal)
+}
+
+type MacroArityError struct {
+	Call       ast.PosRange
+	Name       string
+	Want, Have int
+}
+
+func (e MacroArityError) GetPosRange() ast.PosRange { return e.Call }
+
+func (e MacroArityError) Error() string {
+	return fmt.Sprint(Tr("wrong number of macro arguments: "), e.Name, Tr(" takes "), e.Want, Tr(", have "), e.Have)
+}
+
+// MacroRecursionError reports an invocation nested too deep, most likely a recursive macro.
+type MacroRecursionError struct {
+	Call ast.PosRange
+	Name string
+}
+
+func (e MacroRecursionError) GetPosRange() ast.PosRange { return e.Call }
+
+func (e MacroRecursionError) Error() string {
+	return fmt.Sprint(Tr("macro expansion too deep: "), e.Name)
+}
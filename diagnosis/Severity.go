@@ -0,0 +1,79 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package diagnosis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity grades a diagnostic, the zero Severity is an error. Only errors fail a build.
+type Severity byte
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+	SeverityIgnore // the diagnostic is dropped
+)
+
+var severityNames = [...]string{
+	SeverityError:   "error",
+	SeverityWarning: "warning",
+	SeverityInfo:    "info",
+	SeverityIgnore:  "ignore",
+}
+
+func (s Severity) String() string { return severityNames[s] }
+
+func ParseSeverity(s string) (Severity, error) {
+	for severity, name := range severityNames {
+		if name == s {
+			return Severity(severity), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity: %s", s)
+}
+
+// KindNames names the kinds of diagnostics whose severity may be configured.
+var KindNames = map[string]int{
+	"literal-overflow":  LiteralOverflow,
+	"literal-precision": LiteralPrecision,
+}
+
+// Severities overrides the severity diagnostics are reported with, by kind.
+type Severities map[int]Severity
+
+// ParseSeverities parses `kind=severity,...` with the kinds of KindNames.
+func ParseSeverities(s string) (Severities, error) {
+	severities := Severities{}
+	for _, pair := range strings.Split(s, ",") {
+		name, level, ok := strings.Cut(pair, "=")
+		kind, known := KindNames[strings.TrimSpace(name)]
+		if !ok || !known {
+			return nil, fmt.Errorf("invalid severity %q, want kind=severity", pair)
+		}
+		severity, err := ParseSeverity(strings.TrimSpace(level))
+		if err != nil {
+			return nil, err
+		}
+		severities[kind] = severity
+	}
+	return severities, nil
+}
+
+// Apply overrides the severity of the diagnostics and drops the ignored ones, in place.
+func (s Severities) Apply(diags []Diagnosis) []Diagnosis {
+	kept := diags[:0]
+	for _, d := range diags {
+		if severity, ok := s[d.Kind]; ok {
+			d.Severity = severity
+		}
+		if d.Severity != SeverityIgnore {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
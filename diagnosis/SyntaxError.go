@@ -7,6 +7,7 @@ package diagnosis
 import (
 	"cee/ast"
 	. "cee/locale"
+	"cee/token"
 	"fmt"
 )
 
@@ -19,18 +20,137 @@ const (
 	_ = iota
 
 	UnexpectedNode
+	UnsupportedNode
+	InvalidTry
+	InvalidErrdefer
+	InvalidLiteral
+	LiteralOverflow
+	UnknownAttributeKey
+	InvalidAttribute
+	UnknownMacro
+	MacroRedefined
+	MacroArity
+	MacroRecursion
+	UnknownIntrinsic
+	IntrinsicArity
+	UnresolvedImport
+	ImportCollision
+	UndeclaredMember
+	UnexportedMember
+	InvalidEmbed
+	EmbedFile
+	EditionRequired
+	IllegalToken
+	MismatchedDelimiter
+	InvalidEncoding
+	TooManyErrors
+	LiteralPrecision
+	ConfusableIdent
+	NotConstant
+	ConstantOverflow
+	DivisionByZero
+	InvalidConstantOp
+	InvalidLength
+	ConstantCycle
+	NonClosedQuote
 )
 
 type UnexpectedNodeError struct {
 	Have ast.Node
-	Want ast.Kind
+	Want int // token kind
 }
 
-func (e UnexpectedNodeError) Error() string {
-	from := e.Have.GetPosRange().From
+func (e UnexpectedNodeError) GetPosRange() ast.PosRange { return e.Have.GetPosRange() }
 
+// Error describes the error without its position, which is decoded by the reporter through the file set.
+func (e UnexpectedNodeError) Error() string {
 	if tok, ok := e.Have.(ast.Token); ok {
-		return fmt.Sprint(from.String(), Tr(" syntax error: unexpected token: "), tok.Literal)
+		return fmt.Sprint(Tr("syntax error: unexpected token: "), tok.Literal)
+	}
+	return Tr("syntax error: unexpected node")
+}
+
+// TooManyErrorsError spans the input skipped once the error budget of a file is spent.
+type TooManyErrorsError struct {
+	ast.PosRange
+	Max int
+}
+
+func (e TooManyErrorsError) Error() string {
+	return fmt.Sprint(Tr("syntax error: too many errors, the rest of the file is skipped after "), e.Max)
+}
+
+// InvalidEncodingError reports bytes of invalid UTF-8 sequences or NULs, which are scanned as U+FFFD.
+type InvalidEncodingError struct {
+	ast.PosRange
+	Bytes []byte
+}
+
+func (e InvalidEncodingError) Error() string {
+	if len(e.Bytes) != 0 && e.Bytes[0] == 0 {
+		return Tr("syntax error: illegal NUL character")
+	}
+	return fmt.Sprintf("%s%q", Tr("syntax error: invalid UTF-8 encoding: "), e.Bytes)
+}
+
+// MismatchedDelimiterError reports a closer that does not match the innermost opener, or an opener
+// left unclosed at the end of the input, in which case Close is the EOF token and the error is positioned at Open.
+// Open is the zero Token for a stray closer without any opener.
+type MismatchedDelimiterError struct {
+	Close ast.Token
+	Open  ast.Token
+}
+
+func (e MismatchedDelimiterError) GetPosRange() ast.PosRange {
+	if e.Close.Kind == token.EOF {
+		return e.Open.PosRange
+	}
+	return e.Close.PosRange
+}
+
+func (e MismatchedDelimiterError) Error() string {
+	switch {
+	case e.Open.Kind == 0:
+		return fmt.Sprint(Tr("syntax error: unexpected closing delimiter: "), e.Close.Literal)
+	case e.Close.Kind == token.EOF:
+		return fmt.Sprint(Tr("syntax error: unclosed delimiter: "), e.Open.Literal)
 	}
-	return fmt.Sprint(from.String(), Tr(" syntax error: unexpected node"))
+	return fmt.Sprint(Tr("syntax error: mismatched closing delimiter: "), e.Close.Literal, Tr(", unclosed "), e.Open.Literal)
+}
+
+// IllegalTokenError reports input the scanner does not recognize, which is skipped as an ILLEGAL token.
+type IllegalTokenError struct {
+	At  ast.Token
+	Err error // of the scanner, nil for a lexeme of no known kind
+}
+
+func (e IllegalTokenError) GetPosRange() ast.PosRange { return e.At.PosRange }
+
+func (e IllegalTokenError) Error() string {
+	return fmt.Sprintf("%s%q", Tr("syntax error: illegal token: "), e.At.Literal)
+}
+
+// NonClosedQuoteError reports a quoted string the end of its line leaves open, see parser.Options.SingleLineStrings.
+type NonClosedQuoteError struct {
+	At ast.Token // from the opening quote to the end of the line
+}
+
+func (e NonClosedQuoteError) GetPosRange() ast.PosRange { return e.At.PosRange }
+
+func (e NonClosedQuoteError) Error() string {
+	return fmt.Sprintf("%s%s", Tr("syntax error: string literal not terminated: "), e.At.Literal)
+}
+
+// EditionRequiredError reports a construct introduced by an edition later than the one of the file.
+type EditionRequiredError struct {
+	At      ast.Token
+	Feature string
+	Since   string // the edition introducing the feature
+	Edition string // the edition of the file
+}
+
+func (e EditionRequiredError) GetPosRange() ast.PosRange { return e.At.PosRange }
+
+func (e EditionRequiredError) Error() string {
+	return fmt.Sprint(e.Feature, Tr(" require edition "), e.Since, Tr(", the file is edition "), e.Edition)
 }
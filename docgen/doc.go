@@ -0,0 +1,7 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+// Package docgen
+// Documentation generator rendering the doc comments of exported declarations as Markdown or HTML.
+package docgen
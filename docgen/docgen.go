@@ -0,0 +1,168 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package docgen
+
+import (
+	"cee/ast"
+	"cee/token"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type Kind byte
+
+const (
+	_ Kind = iota
+
+	KindType
+	KindFunc
+	KindExtern
+)
+
+var kindNames = [...]string{
+	KindType:   "type",
+	KindFunc:   "func",
+	KindExtern: "extern",
+}
+
+func (k Kind) String() string { return kindNames[k] }
+
+type Entry struct {
+	Name      string
+	Kind      Kind
+	Signature string
+	Doc       string
+	PosRange  ast.PosRange
+}
+
+type Package struct {
+	Name    string
+	Doc     string
+	Entries []Entry
+
+	// Symbols maps every documented name to its entry, for resolving cross-links.
+	Symbols map[string]*Entry
+}
+
+type File struct {
+	TokenFile *token.File
+	Decls     []ast.Stmt
+	Comments  []ast.Token
+}
+
+func IsExported(name string) bool {
+	for _, r := range name {
+		return unicode.IsUpper(r)
+	}
+	return false
+}
+
+// CommentText strips the comment markers and the common leading space.
+func CommentText(comments []ast.Token) string {
+	var lines []string
+	for _, c := range comments {
+		text := c.Literal
+		switch {
+		case strings.HasPrefix(text, "//"):
+			lines = append(lines, strings.TrimPrefix(strings.TrimPrefix(text, "//"), " "))
+		case strings.HasPrefix(text, "/*"):
+			text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+			for _, line := range strings.Split(text, "\n") {
+				lines = append(lines, strings.TrimPrefix(strings.TrimSpace(line), "* "))
+			}
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// docComments returns the comment group ending on the line right before the position.
+func docComments(file File, pos ast.PosRange) []ast.Token {
+	comments := file.Comments
+	i := sort.Search(len(comments), func(i int) bool { return comments[i].From >= pos.From })
+
+	lineOf := func(pos token.Pos) int { return file.TokenFile.Position(pos).Line }
+
+	line := lineOf(pos.From)
+	begin := i
+	for begin > 0 && lineOf(comments[begin-1].To) >= line-1 {
+		begin--
+		line = lineOf(comments[begin].From)
+	}
+	return comments[begin:i]
+}
+
+// Collect gathers the exported declarations of a package with their doc comments.
+// The comment group before the first declaration of a file, separated from it by a blank line, documents the package.
+func Collect(name string, files []File) Package {
+	pkg := Package{Name: name, Symbols: map[string]*Entry{}}
+
+	for _, file := range files {
+		if pkg.Doc == "" && len(file.Decls) != 0 {
+			first := file.Decls[0].GetPosRange()
+			group := docComments(file, first)
+
+			var leading []ast.Token
+			for _, c := range file.Comments {
+				if c.From >= first.From || len(group) != 0 && c.From >= group[0].From {
+					break
+				}
+				leading = append(leading, c)
+			}
+			pkg.Doc = CommentText(leading)
+		}
+
+		for _, decl := range file.Decls {
+			entry, ok := entryOf(decl)
+			if !ok || !IsExported(entry.Name) {
+				continue
+			}
+			entry.Doc = CommentText(docComments(file, decl.GetPosRange()))
+			pkg.Entries = append(pkg.Entries, entry)
+		}
+	}
+
+	sort.SliceStable(pkg.Entries, func(i, j int) bool {
+		if pkg.Entries[i].Kind != pkg.Entries[j].Kind {
+			return pkg.Entries[i].Kind < pkg.Entries[j].Kind
+		}
+		return pkg.Entries[i].Name < pkg.Entries[j].Name
+	})
+	for i := range pkg.Entries {
+		pkg.Symbols[pkg.Entries[i].Name] = &pkg.Entries[i]
+	}
+
+	return pkg
+}
+
+func entryOf(decl ast.Stmt) (Entry, bool) {
+	switch d := decl.Value.(type) {
+	case ast.TypeDecl:
+		return Entry{
+			Name:      d.Ident.Literal,
+			Kind:      KindType,
+			Signature: "type " + d.Ident.Literal + " " + ast.TypeString(d.Type),
+			PosRange:  d.PosRange,
+		}, true
+	case ast.FuncDecl:
+		if d.Ident == nil {
+			return Entry{}, false
+		}
+		return Entry{
+			Name:      d.Ident.Literal,
+			Kind:      KindFunc,
+			Signature: ast.FuncString(d.Ident.Literal, d.Type),
+			PosRange:  d.PosRange,
+		}, true
+	case ast.ExternDecl:
+		return Entry{
+			Name:      d.Ident.Literal,
+			Kind:      KindExtern,
+			Signature: "extern " + d.ABI.Literal + " " + ast.FuncString(d.Ident.Literal, d.Type),
+			PosRange:  d.PosRange,
+		}, true
+	}
+	return Entry{}, false
+}
@@ -0,0 +1,109 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package docgen
+
+import (
+	"cee/parser"
+	"cee/token"
+	"strings"
+	"testing"
+)
+
+const src = `package geo
+
+// Package geo measures shapes.
+/* It knows about [Point].
+ */
+
+// Point is a location, see [Dist].
+type Point struct {
+	x i64
+	y i64
+}
+
+// unrelated
+
+// Dist returns the squared distance
+// between p and q.
+fun Dist(p Point, q Point) (i64, bool) {
+	return 0, true
+}
+
+fun helper() {}
+
+fun Index(xs []map[i64]*Point, c chan<- i64) fun(i64) i64 {
+	return nil
+}
+
+extern "C" fun Abs(x i32) i32
+`
+
+func collect(t *testing.T) Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "geo.cee", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Collect("geo", []File{{TokenFile: fset.File(f.From), Decls: f.Decls, Comments: f.Comments}})
+}
+
+func TestCollect(t *testing.T) {
+	pkg := collect(t)
+	if pkg.Doc != "Package geo measures shapes.\nIt knows about [Point]." {
+		t.Errorf("package doc %q", pkg.Doc)
+	}
+
+	want := []Entry{
+		{Name: "Point", Kind: KindType, Signature: "type Point struct { x i64; y i64 }", Doc: "Point is a location, see [Dist]."},
+		{Name: "Dist", Kind: KindFunc, Signature: "fun Dist(p Point, q Point) (i64, bool)", Doc: "Dist returns the squared distance\nbetween p and q."},
+		{Name: "Index", Kind: KindFunc, Signature: "fun Index(xs []map[i64]*Point, c chan<- i64) fun(i64) i64"},
+		{Name: "Abs", Kind: KindExtern, Signature: `extern "C" fun Abs(x i32) i32`},
+	}
+	if len(pkg.Entries) != len(want) {
+		t.Fatalf("entries %+v", pkg.Entries)
+	}
+	for i, entry := range pkg.Entries {
+		entry.PosRange = want[i].PosRange
+		if entry != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entry, want[i])
+		}
+	}
+	if pkg.Symbols["Dist"] != &pkg.Entries[1] || pkg.Symbols["helper"] != nil {
+		t.Errorf("symbols %v", pkg.Symbols)
+	}
+}
+
+func TestRender(t *testing.T) {
+	pkg := collect(t)
+
+	var md strings.Builder
+	if err := pkg.Markdown(&md); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# package geo\n\nPackage geo measures shapes.\nIt knows about [Point](#point).\n\n",
+		"## func Dist\n\n```\nfun Dist(p Point, q Point) (i64, bool)\n```\n\n",
+		"see [Dist](#dist).",
+	} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown has no %q:\n%s", want, md.String())
+		}
+	}
+
+	var page strings.Builder
+	if err := pkg.HTML(&page); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<h2 id="abs">extern Abs</h2>`,
+		`<pre>extern &#34;C&#34; fun Abs(x i32) i32</pre>`,
+		`see <a href="#dist">Dist</a>.`,
+	} {
+		if !strings.Contains(page.String(), want) {
+			t.Errorf("html has no %q:\n%s", want, page.String())
+		}
+	}
+}
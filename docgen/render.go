@@ -0,0 +1,76 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package docgen
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Doc links are written as [Name] and resolved against the package symbols.
+var linkPattern = regexp.MustCompile(`\[([A-Za-z_][A-Za-z0-9_]*)\]`)
+
+func anchor(name string) string { return strings.ToLower(name) }
+
+func (pkg *Package) link(text string, render func(name string) string) string {
+	return linkPattern.ReplaceAllStringFunc(text, func(s string) string {
+		name := s[1 : len(s)-1]
+		if _, ok := pkg.Symbols[name]; !ok {
+			return s
+		}
+		return render(name)
+	})
+}
+
+func (pkg *Package) Markdown(w io.Writer) error {
+	b := bufio.NewWriter(w)
+
+	fmt.Fprint(b, "# package ", pkg.Name, "\n\n")
+	if pkg.Doc != "" {
+		fmt.Fprint(b, pkg.link(pkg.Doc, markdownLink), "\n\n")
+	}
+
+	for _, entry := range pkg.Entries {
+		fmt.Fprint(b, "## ", entry.Kind, " ", entry.Name, "\n\n")
+		fmt.Fprint(b, "```\n", entry.Signature, "\n```\n\n")
+		if entry.Doc != "" {
+			fmt.Fprint(b, pkg.link(entry.Doc, markdownLink), "\n\n")
+		}
+	}
+
+	return b.Flush()
+}
+
+func markdownLink(name string) string { return "[" + name + "](#" + anchor(name) + ")" }
+
+func htmlLink(name string) string {
+	return `<a href="#` + anchor(name) + `">` + html.EscapeString(name) + "</a>"
+}
+
+func (pkg *Package) HTML(w io.Writer) error {
+	b := bufio.NewWriter(w)
+
+	title := html.EscapeString(pkg.Name)
+	fmt.Fprint(b, "<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>package ", title, "</title></head>\n<body>\n")
+	fmt.Fprint(b, "<h1>package ", title, "</h1>\n")
+	if pkg.Doc != "" {
+		fmt.Fprint(b, "<p>", pkg.link(html.EscapeString(pkg.Doc), htmlLink), "</p>\n")
+	}
+
+	for _, entry := range pkg.Entries {
+		fmt.Fprint(b, `<h2 id="`, anchor(entry.Name), `">`, entry.Kind, " ", html.EscapeString(entry.Name), "</h2>\n")
+		fmt.Fprint(b, "<pre>", html.EscapeString(entry.Signature), "</pre>\n")
+		if entry.Doc != "" {
+			fmt.Fprint(b, "<p>", pkg.link(html.EscapeString(entry.Doc), htmlLink), "</p>\n")
+		}
+	}
+
+	fmt.Fprint(b, "</body>\n</html>\n")
+	return b.Flush()
+}
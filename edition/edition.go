@@ -0,0 +1,80 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package edition
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+const (
+	E2024 = "2024"
+	E2025 = "2025"
+)
+
+// Editions lists the known editions from the oldest, editions only ever add features.
+var Editions = []string{E2024, E2025}
+
+// Latest is the edition of files declaring none.
+func Latest() string { return Editions[len(Editions)-1] }
+
+func Valid(edition string) bool {
+	for _, e := range Editions {
+		if e == edition {
+			return true
+		}
+	}
+	return false
+}
+
+// Before reports whether edition a precedes b, editions are years.
+func Before(a, b string) bool {
+	return len(a) < len(b) || len(a) == len(b) && a < b
+}
+
+type Feature int
+
+const (
+	_ Feature = iota
+
+	Macros
+	Generics
+	Interpolation
+)
+
+var features = map[Feature]struct{ name, since string }{
+	Macros:        {"macros", E2025},
+	Generics:      {"generics", E2025},
+	Interpolation: {"string interpolation", E2025},
+}
+
+func (f Feature) String() string { return features[f].name }
+
+// Since returns the edition introducing the feature.
+func (f Feature) Since() string { return features[f].since }
+
+// Supports reports whether the feature is available in the edition.
+func Supports(edition string, f Feature) bool { return !Before(edition, f.Since()) }
+
+// PragmaPrefix starts the line declaring the edition of a single file, e.g. `//cee:edition 2024`,
+// which overrides the edition of the module. Like build constraints, it must precede the first line of code.
+const PragmaPrefix = "//cee:edition "
+
+// FilePragma returns the edition declared by the pragma of a source file, "" if there is none.
+func FilePragma(src []byte) string {
+	s := bufio.NewScanner(bytes.NewReader(src))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		switch {
+		case strings.HasPrefix(line, PragmaPrefix):
+			return strings.TrimSpace(strings.TrimPrefix(line, PragmaPrefix))
+		case line == "" || strings.HasPrefix(line, "//"):
+			continue
+		}
+		break
+	}
+	return ""
+}
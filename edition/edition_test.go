@@ -0,0 +1,19 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package edition
+
+import "testing"
+
+func TestSupports(t *testing.T) {
+	if Supports(E2024, Macros) || !Supports(E2025, Macros) || !Supports(Latest(), Generics) {
+		t.Error("macros are introduced by 2025")
+	}
+	if got := FilePragma([]byte("// doc\n//cee:edition 2024\nfun main() {}")); got != E2024 {
+		t.Errorf("pragma %q", got)
+	}
+	if got := FilePragma([]byte("fun main() {}\n//cee:edition 2024")); got != "" {
+		t.Errorf("pragma after code %q", got)
+	}
+}
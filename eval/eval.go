@@ -0,0 +1,439 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package eval
+
+import (
+	"cee/ast"
+	"cee/literals"
+	"cee/parser"
+	"cee/token"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Value is the result of an evaluation: int64, float64, string, Char, bool, []Value, map[string]Value
+// for the fields of a struct, Func or Unknown.
+type Value any
+
+type Char rune
+
+// Func is a function of the environment, e.g. a builtin of the debugger.
+type Func func(args []Value) (Value, error)
+
+// Unknown stands for a value that could not be computed, operations on it yield it again,
+// so the rest of an expression is still evaluated.
+type Unknown struct {
+	Reason string
+}
+
+func (u Unknown) String() string { return "<unknown: " + u.Reason + ">" }
+
+// Env provides the values of identifiers, e.g. the variables of an interpreter or a snapshot of a frame.
+// Members of imported packages are looked up by their qualified name, `pkg.Member`.
+type Env interface {
+	Lookup(name string) (Value, bool)
+}
+
+// Vars is an environment of fixed values.
+type Vars map[string]Value
+
+func (v Vars) Lookup(name string) (Value, bool) {
+	value, ok := v[name]
+	return value, ok
+}
+
+// Result is the value of an expression and the errors met evaluating it, in source order.
+// Value is Unknown when an error prevented computing it.
+type Result struct {
+	Value  Value
+	Errors []error
+}
+
+// Eval parses the expression and evaluates it. Syntax errors are reported in the result.
+func Eval(src string, env Env) (res Result) {
+	p := parser.NewParser([]rune(src))
+	p.Options.Recover = true
+	defer func() {
+		if r := recover(); r != nil {
+			res = Result{Value: Unknown{Reason: "syntax error"}, Errors: []error{fmt.Errorf("eval: %v", r)}}
+		}
+	}()
+
+	p.Scan()
+	p.SkipNewlines()
+	e := p.ExpectExpr()
+	res = EvalExpr(e, env)
+	for _, d := range p.Diagnosis {
+		res.Errors = append(res.Errors, fmt.Errorf("eval: %v", d.Error))
+	}
+	return res
+}
+
+// EvalExpr evaluates an expression. Identifiers missing from the environment and unsupported operations
+// are reported and become Unknown. true, false and len are predeclared, the environment may shadow them.
+func EvalExpr(e ast.Expr, env Env) Result {
+	ev := evaluator{env: env}
+	v := ev.expr(e)
+	return Result{Value: v, Errors: ev.errors}
+}
+
+type evaluator struct {
+	env    Env
+	errors []error
+}
+
+func (ev *evaluator) unknown(format string, a ...any) Unknown {
+	err := fmt.Errorf("eval: "+format, a...)
+	ev.errors = append(ev.errors, err)
+	return Unknown{Reason: strings.TrimPrefix(err.Error(), "eval: ")}
+}
+
+func (ev *evaluator) expr(e ast.Expr) Value {
+	switch v := e.Value.(type) {
+	case ast.Ident:
+		return ev.ident(v.Literal)
+	case ast.QualifiedIdent:
+		return ev.ident(v.Package.Literal + "." + v.Member.Literal)
+	case ast.LiteralValue:
+		return ev.literal(v)
+	case ast.UnaryExpr:
+		return ev.unary(v.Operator.Kind, ev.expr(v.Expr))
+	case ast.BinaryExpr:
+		return ev.binary(v)
+	case ast.CallExpr:
+		return ev.call(v)
+	case ast.IndexExpr:
+		return ev.index(ev.expr(v.Expr), ev.expr(v.Index))
+	case ast.MemberSelectExpr:
+		// The parser reads `pkg.Member` as a selection.
+		if pkg, ok := v.Expr.Value.(ast.Ident); ok {
+			if value, ok := ev.env.Lookup(pkg.Literal + "." + v.Member.Literal); ok {
+				return value
+			}
+		}
+		return ev.member(ev.expr(v.Expr), v.Member.Literal)
+	case nil:
+		return ev.unknown("missing expression")
+	}
+	return ev.unknown("unsupported expression %T", e.Value)
+}
+
+func (ev *evaluator) ident(name string) Value {
+	if v, ok := ev.env.Lookup(name); ok {
+		return v
+	}
+	switch name {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "len":
+		return Func(length)
+	}
+	return ev.unknown("undefined: %s", name)
+}
+
+func (ev *evaluator) literal(lit ast.LiteralValue) Value {
+	v, err := literals.Decode(lit)
+	if err != nil {
+		return ev.unknown("invalid literal %s", lit.Literal)
+	}
+	switch v.Kind {
+	case literals.Int:
+		if !v.Int.IsInt64() {
+			return ev.unknown("%s overflows i64", lit.Literal)
+		}
+		return v.Int.Int64()
+	case literals.Float:
+		f, _ := v.Float.Float64()
+		return f
+	case literals.String:
+		return v.String
+	case literals.Char:
+		return Char(v.Char)
+	}
+	return ev.unknown("unsupported literal %s", lit.Literal)
+}
+
+func (ev *evaluator) unary(op int, x Value) Value {
+	if _, ok := x.(Unknown); ok {
+		return x
+	}
+	switch x := x.(type) {
+	case int64:
+		switch op {
+		case token.SUB:
+			return -x
+		case token.ADD:
+			return x
+		case token.XOR:
+			return ^x
+		}
+	case float64:
+		switch op {
+		case token.SUB:
+			return -x
+		case token.ADD:
+			return x
+		}
+	case bool:
+		if op == token.NOT {
+			return !x
+		}
+	}
+	return ev.unknown("invalid operation %s%s", token.KeywordLiterals[op], typeName(x))
+}
+
+func (ev *evaluator) binary(b ast.BinaryExpr) Value {
+	op := b.Operator.Kind
+	x := ev.expr(b.Exprs[0])
+	// && and || only evaluate the right operand when it decides, it may be unknown otherwise.
+	if l, ok := x.(bool); ok && (op == token.LAND && !l || op == token.LOR && l) {
+		return l
+	}
+	y := ev.expr(b.Exprs[1])
+	if _, ok := x.(Unknown); ok {
+		return x
+	}
+	if _, ok := y.(Unknown); ok {
+		return y
+	}
+
+	v, err := apply(op, x, y)
+	if err != nil {
+		return ev.unknown("%v", err)
+	}
+	return v
+}
+
+var errDivByZero = errors.New("division by zero")
+
+// apply applies a binary operator to known operands.
+func apply(op int, x, y Value) (Value, error) {
+	// Integers and floats mix as floats.
+	if i, ok := x.(int64); ok {
+		if _, ok := y.(float64); ok {
+			x = float64(i)
+		}
+	}
+	if i, ok := y.(int64); ok {
+		if _, ok := x.(float64); ok {
+			y = float64(i)
+		}
+	}
+
+	switch x := x.(type) {
+	case int64:
+		if y, ok := y.(int64); ok {
+			return intOp(op, x, y)
+		}
+	case float64:
+		if y, ok := y.(float64); ok {
+			return floatOp(op, x, y)
+		}
+	case string:
+		if y, ok := y.(string); ok {
+			return stringOp(op, x, y)
+		}
+	case Char:
+		if y, ok := y.(Char); ok {
+			return intOp(op, int64(x), int64(y))
+		}
+	case bool:
+		if y, ok := y.(bool); ok {
+			switch op {
+			case token.LAND:
+				return x && y, nil
+			case token.LOR:
+				return x || y, nil
+			case token.EQL:
+				return x == y, nil
+			case token.NEQ:
+				return x != y, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("invalid operation %s %s %s", typeName(x), token.KeywordLiterals[op], typeName(y))
+}
+
+func intOp(op int, x, y int64) (Value, error) {
+	switch op {
+	case token.ADD:
+		return x + y, nil
+	case token.SUB:
+		return x - y, nil
+	case token.MUL:
+		return x * y, nil
+	case token.QUO, token.REM:
+		if y == 0 {
+			return nil, errDivByZero
+		}
+		if op == token.QUO {
+			return x / y, nil
+		}
+		return x % y, nil
+	case token.AND:
+		return x & y, nil
+	case token.OR:
+		return x | y, nil
+	case token.XOR:
+		return x ^ y, nil
+	case token.AND_NOT:
+		return x &^ y, nil
+	case token.SHL, token.SHR:
+		if y < 0 {
+			return nil, fmt.Errorf("negative shift count %d", y)
+		}
+		if op == token.SHL {
+			return x << y, nil
+		}
+		return x >> y, nil
+	}
+	return compare(op, x, y)
+}
+
+func floatOp(op int, x, y float64) (Value, error) {
+	switch op {
+	case token.ADD:
+		return x + y, nil
+	case token.SUB:
+		return x - y, nil
+	case token.MUL:
+		return x * y, nil
+	case token.QUO:
+		return x / y, nil
+	case token.REM:
+		return math.Mod(x, y), nil
+	}
+	return compare(op, x, y)
+}
+
+func stringOp(op int, x, y string) (Value, error) {
+	if op == token.ADD {
+		return x + y, nil
+	}
+	return compare(op, x, y)
+}
+
+func compare[T int64 | float64 | string](op int, x, y T) (Value, error) {
+	switch op {
+	case token.EQL:
+		return x == y, nil
+	case token.NEQ:
+		return x != y, nil
+	case token.LSS:
+		return x < y, nil
+	case token.LEQ:
+		return x <= y, nil
+	case token.GTR:
+		return x > y, nil
+	case token.GEQ:
+		return x >= y, nil
+	}
+	return nil, fmt.Errorf("invalid operator %s", token.KeywordLiterals[op])
+}
+
+func (ev *evaluator) call(c ast.CallExpr) Value {
+	callee := ev.expr(c.Callee)
+	args := make([]Value, len(c.Params))
+	unknown := false
+	for i, param := range c.Params {
+		args[i] = ev.expr(param)
+		_, ok := args[i].(Unknown)
+		unknown = unknown || ok
+	}
+	if _, ok := callee.(Unknown); ok {
+		return callee
+	}
+	if unknown {
+		return Unknown{Reason: "unknown argument"}
+	}
+
+	if fn, ok := callee.(Func); ok {
+		v, err := fn(args)
+		if err != nil {
+			return ev.unknown("%v", err)
+		}
+		return v
+	}
+	return ev.unknown("cannot call %s", typeName(callee))
+}
+
+func length(args []Value) (Value, error) {
+	if len(args) == 1 {
+		switch x := args[0].(type) {
+		case string:
+			return int64(len(x)), nil
+		case []Value:
+			return int64(len(x)), nil
+		}
+	}
+	return nil, errors.New("len takes a string or an array")
+}
+
+func (ev *evaluator) index(x, i Value) Value {
+	if _, ok := x.(Unknown); ok {
+		return x
+	}
+	if _, ok := i.(Unknown); ok {
+		return i
+	}
+	n, ok := i.(int64)
+	if !ok {
+		return ev.unknown("invalid index of type %s", typeName(i))
+	}
+	switch x := x.(type) {
+	case []Value:
+		if n < 0 || n >= int64(len(x)) {
+			return ev.unknown("index %d out of range [0, %d)", n, len(x))
+		}
+		return x[n]
+	case string:
+		if n < 0 || n >= int64(len(x)) {
+			return ev.unknown("index %d out of range [0, %d)", n, len(x))
+		}
+		return int64(x[n])
+	}
+	return ev.unknown("cannot index %s", typeName(x))
+}
+
+func (ev *evaluator) member(x Value, name string) Value {
+	if _, ok := x.(Unknown); ok {
+		return x
+	}
+	fields, ok := x.(map[string]Value)
+	if !ok {
+		return ev.unknown("%s has no fields", typeName(x))
+	}
+	v, ok := fields[name]
+	if !ok {
+		return ev.unknown("no field %s", name)
+	}
+	return v
+}
+
+func typeName(v Value) string {
+	switch v.(type) {
+	case int64:
+		return "i64"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case Char:
+		return "char"
+	case bool:
+		return "bool"
+	case []Value:
+		return "array"
+	case map[string]Value:
+		return "struct"
+	case Func:
+		return "function"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package eval
+
+import (
+	"cee"
+	"cee/ast"
+	"cee/token"
+	"testing"
+)
+
+func expr(kind ast.ExprKind, value ast.Node) ast.Expr {
+	return ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: kind, Value: value}}
+}
+
+func ident(name string) ast.Expr {
+	return expr(ast.ExprIdent, ast.Ident{Token: ast.Token{Kind: token.IDENT, Literal: name}})
+}
+
+func lit(kind int, s string) ast.Expr {
+	return expr(ast.ExprLiteralValue, ast.LiteralValue{Token: ast.Token{Kind: kind, Literal: s}})
+}
+
+func binary(op int, x, y ast.Expr) ast.Expr {
+	return expr(ast.ExprBinary, ast.BinaryExpr{Operator: ast.Token{Kind: op}, Exprs: [2]ast.Expr{x, y}})
+}
+
+func member(x ast.Expr, name string) ast.Expr {
+	return expr(ast.ExprMemberSelect, ast.MemberSelectExpr{Expr: x, Member: ast.Ident{Token: ast.Token{Literal: name}}})
+}
+
+func TestEvalExpr(t *testing.T) {
+	env := Vars{
+		"n":      int64(3),
+		"xs":     []Value{int64(10), int64(20)},
+		"p":      map[string]Value{"x": 1.5},
+		"name":   "cee",
+		"fmt.pi": 3.0,
+		"double": Func(func(args []Value) (Value, error) { return args[0].(int64) * 2, nil }),
+	}
+	call := func(callee string, args ...ast.Expr) ast.Expr {
+		return expr(ast.ExprCall, ast.CallExpr{Callee: ident(callee), Params: args})
+	}
+	index := expr(ast.ExprIndex, ast.IndexExpr{Expr: ident("xs"), Index: lit(token.INT, "1")})
+
+	tests := []struct {
+		e      ast.Expr
+		want   Value
+		errors int
+	}{
+		{binary(token.ADD, ident("n"), lit(token.INT, "0x10")), int64(19), 0},
+		{binary(token.MUL, member(ident("p"), "x"), ident("n")), 4.5, 0},
+		{binary(token.LSS, index, call("double", ident("n"))), false, 0},
+		{binary(token.ADD, ident("name"), lit(token.STRING, `"!"`)), "cee!", 0},
+		{call("len", ident("xs")), int64(2), 0},
+		{member(ident("fmt"), "pi"), 3.0, 0},
+		{binary(token.LAND, ident("false"), ident("missing")), false, 0},
+		{binary(token.QUO, ident("n"), lit(token.INT, "0")), nil, 1},
+		{binary(token.ADD, ident("missing"), ident("other")), nil, 2},
+		{call("double", ident("missing")), nil, 1},
+		{member(ident("p"), "y"), nil, 1},
+	}
+	for i, test := range tests {
+		res := EvalExpr(test.e, env)
+		if len(res.Errors) != test.errors {
+			t.Errorf("test %d: errors %v, want %d", i, res.Errors, test.errors)
+		}
+		if test.want == nil {
+			if _, ok := res.Value.(Unknown); !ok {
+				t.Errorf("test %d = %v, want unknown", i, res.Value)
+			}
+		} else if res.Value != test.want {
+			t.Errorf("test %d = %v, want %v", i, res.Value, test.want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ffi
+
+import (
+	"bytes"
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/token"
+	"fmt"
+)
+
+// CType describes how a cee type crosses the C ABI boundary.
+type CType struct {
+	C  string // C spelling, e.g. int32_t
+	Go string // Go spelling used on the cee side of the marshaling
+}
+
+var cTypes = map[ast.TypeKind]CType{
+	ast.TypeI8:  {C: "int8_t", Go: "int8"},
+	ast.TypeI16: {C: "int16_t", Go: "int16"},
+	ast.TypeI32: {C: "int32_t", Go: "int32"},
+	ast.TypeI64: {C: "int64_t", Go: "int64"},
+	ast.TypeU8:  {C: "uint8_t", Go: "uint8"},
+	ast.TypeU16: {C: "uint16_t", Go: "uint16"},
+	ast.TypeU32: {C: "uint32_t", Go: "uint32"},
+	ast.TypeU64: {C: "uint64_t", Go: "uint64"},
+}
+
+type Param struct {
+	Name string
+	Type CType
+}
+
+type Stub struct {
+	Decl    ast.ExternDecl
+	Params  []Param
+	Result  *CType // nil for void
+	Invalid bool
+}
+
+type Generator struct {
+	Stubs []Stub
+
+	Diagnosis []diagnosis.Diagnosis
+}
+
+func (g *Generator) unsupported(node ast.Node) {
+	g.Diagnosis = append(g.Diagnosis, diagnosis.Diagnosis{
+		Kind:  diagnosis.UnsupportedNode,
+		Error: diagnosis.UnsupportedNodeError{Node: node},
+	})
+}
+
+func (g *Generator) cType(t ast.Type) (CType, bool) {
+	typ, ok := cTypes[t.Tag]
+	if !ok {
+		g.unsupported(t)
+	}
+	return typ, ok
+}
+
+// Add records an extern declaration, reporting ABIs and types that cannot be marshaled.
+func (g *Generator) Add(d ast.ExternDecl) {
+	stub := Stub{Decl: d}
+
+	if abi, err := token.Unquote(d.ABI.Literal); err != nil || abi != "C" {
+		g.unsupported(d.ABI)
+		stub.Invalid = true
+	}
+
+	for _, decl := range d.Type.Params {
+		typ, ok := g.cType(decl.Type)
+		stub.Invalid = stub.Invalid || !ok
+		for _, ident := range decl.Idents {
+			stub.Params = append(stub.Params, Param{Name: ident.Literal, Type: typ})
+		}
+	}
+
+	switch len(d.Type.Results) {
+	case 0:
+	case 1:
+		typ, ok := g.cType(d.Type.Results[0])
+		stub.Invalid = stub.Invalid || !ok
+		stub.Result = &typ
+	default:
+		// C functions return at most one value.
+		g.unsupported(d.Type)
+		stub.Invalid = true
+	}
+
+	g.Stubs = append(g.Stubs, stub)
+}
+
+// Header emits the C prototypes of all valid stubs.
+func (g *Generator) Header() []byte {
+	var b bytes.Buffer
+	b.WriteString("#include <stdint.h>\n\n")
+	for _, stub := range g.Stubs {
+		if stub.Invalid {
+			continue
+		}
+		result := "void"
+		if stub.Result != nil {
+			result = stub.Result.C
+		}
+		fmt.Fprint(&b, result, " ", stub.Decl.Ident.Literal, "(")
+		if len(stub.Params) == 0 {
+			b.WriteString("void")
+		}
+		for i, p := range stub.Params {
+			if i != 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprint(&b, p.Type.C, " ", p.Name)
+		}
+		b.WriteString(");\n")
+	}
+	return b.Bytes()
+}
+
+// Bindings emits a cgo file wrapping every valid stub with a Go function converting arguments and result.
+func (g *Generator) Bindings(pkg string) []byte {
+	var b bytes.Buffer
+	fmt.Fprint(&b, "package ", pkg, "\n\n")
+	b.WriteString("/*\n")
+	b.Write(g.Header())
+	b.WriteString("*/\nimport \"C\"\n")
+
+	for _, stub := range g.Stubs {
+		if stub.Invalid {
+			continue
+		}
+		name := stub.Decl.Ident.Literal
+
+		fmt.Fprint(&b, "\nfunc ", name, "(")
+		for i, p := range stub.Params {
+			if i != 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprint(&b, p.Name, " ", p.Type.Go)
+		}
+		b.WriteString(")")
+		if stub.Result != nil {
+			fmt.Fprint(&b, " ", stub.Result.Go)
+		}
+		b.WriteString(" {\n\t")
+
+		if stub.Result != nil {
+			fmt.Fprint(&b, "return ", stub.Result.Go, "(")
+		}
+		fmt.Fprint(&b, "C.", name, "(")
+		for i, p := range stub.Params {
+			if i != 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprint(&b, "C.", p.Type.C, "(", p.Name, ")")
+		}
+		b.WriteString(")")
+		if stub.Result != nil {
+			b.WriteString(")")
+		}
+		b.WriteString("\n}\n")
+	}
+	return b.Bytes()
+}
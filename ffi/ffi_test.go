@@ -0,0 +1,97 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ffi
+
+import (
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/parser"
+	"cee/token"
+	"strings"
+	"testing"
+)
+
+// generate adds the extern declarations of a file to a new generator.
+func generate(t *testing.T, src string) *Generator {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "a.cee", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &Generator{}
+	for _, decl := range f.Decls {
+		if decl.Tag == ast.StmtExternDecl {
+			g.Add(decl.Value.(ast.ExternDecl))
+		}
+	}
+	return g
+}
+
+func TestTypeMapping(t *testing.T) {
+	g := generate(t, `package a
+
+extern fun abs(x i32) i32
+extern "C" fun mix(a i8, b u16, c u64, d i64) u8
+extern fun tick()
+`)
+	if len(g.Diagnosis) != 0 {
+		t.Fatalf("diagnosis %v", g.Diagnosis)
+	}
+
+	header := string(g.Header())
+	for _, want := range []string{
+		"int32_t abs(int32_t x);",
+		"uint8_t mix(int8_t a, uint16_t b, uint64_t c, int64_t d);",
+		"void tick(void);",
+	} {
+		if !strings.Contains(header, want) {
+			t.Errorf("header lacks %q:\n%s", want, header)
+		}
+	}
+
+	bindings := string(g.Bindings("a"))
+	for _, want := range []string{
+		"package a\n",
+		"import \"C\"\n",
+		"func abs(x int32) int32 {\n\treturn int32(C.abs(C.int32_t(x)))\n}",
+		"func tick() {\n\tC.tick()\n}",
+	} {
+		if !strings.Contains(bindings, want) {
+			t.Errorf("bindings lack %q:\n%s", want, bindings)
+		}
+	}
+}
+
+func TestUnsupportedSignatures(t *testing.T) {
+	g := generate(t, `package a
+
+extern "Rust" fun a(x i32)
+extern fun b(s string) i32
+extern fun c(p *i32)
+extern fun d() (i32, i32)
+extern fun ok(x u32) u32
+`)
+	if len(g.Stubs) != 5 {
+		t.Fatalf("%d stubs", len(g.Stubs))
+	}
+	for _, stub := range g.Stubs {
+		if want := stub.Decl.Ident.Literal != "ok"; stub.Invalid != want {
+			t.Errorf("%s invalid = %v", stub.Decl.Ident.Literal, stub.Invalid)
+		}
+	}
+	if len(g.Diagnosis) != 4 {
+		t.Errorf("%d diagnostics, want 4: %v", len(g.Diagnosis), g.Diagnosis)
+	}
+	for _, d := range g.Diagnosis {
+		if d.Kind != diagnosis.UnsupportedNode {
+			t.Errorf("diagnosis %v", d)
+		}
+	}
+
+	// Only the valid stub is emitted.
+	if header := string(g.Header()); strings.Count(header, ";") != 1 || !strings.Contains(header, "uint32_t ok(uint32_t x);") {
+		t.Errorf("header:\n%s", header)
+	}
+}
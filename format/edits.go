@@ -0,0 +1,61 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package format
+
+import (
+	"cee/ast"
+	"cee/parser"
+	"cee/rewrite"
+	"cee/token"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// end returns the rune offset after the token, trailing whitespace of comments excluded as formatting trims it.
+func end(file *token.File, tok ast.Token) int {
+	if tok.Kind == token.COMMENT {
+		return file.Offset(tok.From) + utf8.RuneCountInString(strings.TrimRight(tok.Literal, " \t\r\n"))
+	}
+	return file.Offset(tok.To)
+}
+
+// Edits returns the edits formatting the source, in rune offsets of src and in order.
+// Formatting only changes the whitespace around tokens, so each edit replaces the whitespace between
+// two tokens, or before the first or after the last, and unchanged whitespace is left alone.
+func (o Options) Edits(src []byte) ([]rewrite.Edit, error) {
+	formatted, err := o.Source(src)
+	if err != nil {
+		return nil, err
+	}
+	toks, file, err := Tokens(src)
+	if err != nil {
+		return nil, err
+	}
+	newToks, newFile, err := Tokens(formatted)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) != len(newToks) {
+		return nil, fmt.Errorf("format: formatting changed the tokens")
+	}
+
+	buffer, _ := parser.Decode(src)
+	newBuffer, _ := parser.Decode(formatted)
+	var edits []rewrite.Edit
+	gap := func(from, to, newFrom, newTo int) {
+		if text := string(newBuffer[newFrom:newTo]); string(buffer[from:to]) != text {
+			edits = append(edits, rewrite.Edit{From: from, To: to, Text: text})
+		}
+	}
+
+	last, newLast := 0, 0
+	for i, tok := range toks {
+		gap(last, file.Offset(tok.From), newLast, newFile.Offset(newToks[i].From))
+		last, newLast = end(file, tok), end(newFile, newToks[i])
+	}
+	gap(last, len(buffer), newLast, len(newBuffer))
+	return edits, nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package format_test
+
+import (
+	"cee/format"
+	"cee/rewrite"
+	"cee/testutil"
+	"testing"
+)
+
+// TestEdits checks that applying the edits formats the source, and that formatted sources need none.
+func TestEdits(t *testing.T) {
+	options := format.Options{Indent: "    "}
+	src := testutil.Generate(testutil.GenOptions{Funcs: 3})
+	formatted, err := options.Source(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edits, err := options.Edits(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rewrite.New([]rune(string(src)))
+	for _, edit := range edits {
+		r.Replace(edit.From, edit.To, edit.Text)
+	}
+	out, _, err := r.Apply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != string(formatted) {
+		t.Errorf("edited source differs from the formatted source:\n%s\n%s", string(out), formatted)
+	}
+
+	if edits, err := options.Edits(formatted); err != nil || len(edits) != 0 {
+		t.Errorf("formatted source got %d edits, %v", len(edits), err)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package format
+
+import (
+	"bytes"
+	"cee/ast"
+	"cee/token"
+	"unicode"
+	"unicode/utf8"
+)
+
+type MinifyOptions struct {
+	// Rename, when set, returns the name replacing the identifier at a rune offset of the source.
+	Rename func(offset int) (name string, ok bool)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+func isNumber(kind int) bool { return kind == token.INT || kind == token.FLOAT || kind == token.IMAG }
+
+// separate reports whether two tokens would scan as one, or as others, without a space between them.
+func separate(prev ast.Token, prevLit string, cur ast.Token, curLit string) bool {
+	last, _ := utf8.DecodeLastRuneInString(prevLit)
+	first, _ := utf8.DecodeRuneInString(curLit)
+	switch {
+	case isWordRune(last) && isWordRune(first):
+		return true
+	case token.IsOperator(prev.Kind) && token.IsOperator(cur.Kind):
+		// Runs of operator characters scan as one operator, as comments when they start with `//` or `/*`.
+		return true
+	case isNumber(prev.Kind) && first == '.', last == '.' && isNumber(cur.Kind):
+		return true
+	}
+	return false
+}
+
+// Minify strips the comments of a file and collapses its whitespace to what the grammar requires: a space
+// between tokens that would otherwise scan differently, and a line break where one may terminate a statement,
+// outside of parentheses and brackets. Identifiers are renamed as opts tells.
+func Minify(src []byte, opts MinifyOptions) ([]byte, error) {
+	toks, file, err := Tokens(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		b bytes.Buffer
+		// Open delimiters, line breaks only terminate statements when the innermost is a brace or none.
+		open    []int
+		prev    ast.Token
+		prevLit string
+		started bool
+	)
+	for _, tok := range toks {
+		if tok.Kind == token.COMMENT {
+			continue
+		}
+		lit := tok.Literal
+		if tok.Kind == token.IDENT && opts.Rename != nil {
+			if name, ok := opts.Rename(file.Offset(tok.From)); ok {
+				lit = name
+			}
+		}
+
+		if started {
+			terminates := len(open) == 0 || open[len(open)-1] == token.LBRACE
+			switch {
+			case terminates && file.Position(tok.From).Line > file.Position(prev.To).Line:
+				b.WriteString("\n")
+			case separate(prev, prevLit, tok, lit):
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString(lit)
+
+		switch {
+		case isOpener(tok.Kind):
+			open = append(open, tok.Kind)
+		case isCloser(tok.Kind) && len(open) != 0:
+			open = open[:len(open)-1]
+		}
+		prev, prevLit, started = tok, lit, true
+	}
+
+	if started {
+		b.WriteString("\n")
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package format
+
+import (
+	"bytes"
+	"cee/ast"
+	"cee/testutil"
+	"testing"
+)
+
+// TestMinify checks that minified programs parse to the declarations of the originals, without comments.
+func TestMinify(t *testing.T) {
+	src := testutil.Generate(testutil.GenOptions{Funcs: 5, Depth: 4, Stmts: 3})
+	minified, err := Minify(src, MinifyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(minified) >= len(src) {
+		t.Errorf("minified to %d bytes from %d", len(minified), len(src))
+	}
+
+	in, err := parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := parse(minified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Errors > in.Errors || len(out.Comments) != 0 {
+		t.Errorf("%d diagnostics, %d comments", out.Errors, len(out.Comments))
+	}
+	if d := ast.Diff(in.Decls, out.Decls); d != "" {
+		t.Errorf("decls differ at %s:\n%s", d, minified)
+	}
+
+	again, err := Minify(minified, MinifyOptions{})
+	if err != nil || !bytes.Equal(again, minified) {
+		t.Errorf("minifying again: %v\n%s", err, again)
+	}
+}
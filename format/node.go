@@ -0,0 +1,35 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package format
+
+import (
+	"bytes"
+	"cee/ast"
+	"fmt"
+	"io"
+)
+
+// Node formats a node canonically to w, see Options.Node.
+func Node(w io.Writer, node ast.Node) error { return Options{}.Node(w, node) }
+
+// Node prints the node as source and formats the output as Source does, so formatting the printed node
+// again yields the same bytes. Operands are parenthesized as their precedence requires, the comments of a
+// file are printed between its declarations.
+func (o Options) Node(w io.Writer, node ast.Node) error {
+	p, ok := node.(ast.Printer)
+	if !ok {
+		return fmt.Errorf("format: cannot print %T", node)
+	}
+	var b bytes.Buffer
+	if err := ast.Fprint(&b, p); err != nil {
+		return err
+	}
+	out, err := o.Source(b.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
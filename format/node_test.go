@@ -0,0 +1,53 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package format_test
+
+import (
+	"bytes"
+	"cee/format"
+	"cee/parser"
+	"cee/token"
+	"testing"
+)
+
+// printFile parses src and prints the file with Node.
+func printFile(t *testing.T, src string) string {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "a.cee", []byte(src))
+	if err != nil {
+		t.Fatalf("parse %q: %v", src, err)
+	}
+	var b bytes.Buffer
+	if err := format.Node(&b, f); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestNodeRoundTrip(t *testing.T) {
+	for _, test := range []struct{ src, want string }{
+		{
+			"package a\n\nval x = -(-1)\nval y = !!z\nval w = & &v\n",
+			"package a\n\nval x = - -1\n\nval y = !!z\n\nval w = & &v\n",
+		},
+		{
+			"package a\n\n// f doubles.\nfun f(x i64) i64 {\n\t// twice\n\tval y = x * 2\n\treturn y\n\t// done\n}\n",
+			"package a\n\n// f doubles.\nfun f(x i64) i64 {\n\t// twice\n\tval y = x * 2\n\treturn y\n\t// done\n}\n",
+		},
+		{
+			"package a\n\nfun f(x i64) {\n\tswitch x {\n\tcase 0:\n\t\tg()\n\t// other\n\tdefault:\n\t\tmatch x {\n\t\tcase 1: g()\n\t\t}\n\t}\n}\n",
+			"package a\n\nfun f(x i64) {\n\tswitch x {\n\t\tcase 0:\n\t\t\tg()\n\t\t\t// other\n\t\tdefault:\n\t\t\tmatch x {\n\t\t\t\tcase 1:\n\t\t\t\t\tg()\n\t\t\t}\n\t}\n}\n",
+		},
+	} {
+		out := printFile(t, test.src)
+		if out != test.want {
+			t.Errorf("printed %q\nwant %q", out, test.want)
+			continue
+		}
+		if again := printFile(t, out); again != out {
+			t.Errorf("printed again %q\nwant %q", again, out)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package format
+
+import (
+	"bytes"
+	"cee/ast"
+	"cee/parser"
+	"cee/token"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Tokens scans the whole source, comments included, in source order.
+// The positions of the tokens are decoded by the returned file.
+// Sources with invalid UTF-8 or NULs are rejected, formatting them would write replacement characters back.
+func Tokens(src []byte) ([]ast.Token, *token.File, error) {
+	buffer, bad := parser.Decode(src)
+	file := token.NewFileSet().AddFile("", -1, len(buffer))
+	if len(bad) != 0 {
+		return nil, nil, fmt.Errorf("format: %s: invalid encoding", file.Position(file.Pos(bad[0].Offset)))
+	}
+	toks, err := parser.ScanAll(file, buffer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("format: %w", err)
+	}
+	return toks, file, nil
+}
+
+func isOpener(kind int) bool {
+	return kind == token.LPAREN || kind == token.LBRACK || kind == token.LBRACE
+}
+
+func isCloser(kind int) bool {
+	return kind == token.RPAREN || kind == token.RBRACK || kind == token.RBRACE
+}
+
+// isOperand reports whether the token can end an operand, which makes a following operator binary.
+func isOperand(kind int) bool {
+	return kind == token.IDENT || token.IsLiteralValue(kind) || kind == token.STRING_TAIL || kind == token.RPAREN || kind == token.RBRACK || kind == token.RBRACE ||
+		kind == token.QUESTION
+}
+
+// space decides whether a single space separates two tokens on the same line.
+func space(prev, prevprev, cur ast.Token) bool {
+	switch {
+	case prev.Kind == token.COMMENT || cur.Kind == token.COMMENT:
+		return true
+	case prev.Kind == token.STRING_HEAD || prev.Kind == token.STRING_MID:
+		return false
+	case cur.Kind == token.STRING_MID || cur.Kind == token.STRING_TAIL:
+		return false
+	case cur.Kind == token.COMMA || cur.Kind == token.SEMICOLON || cur.Kind == token.COLON:
+		return false
+	case prev.Kind == token.COMMA || prev.Kind == token.SEMICOLON || prev.Kind == token.COLON:
+		return true
+	case prev.Kind == token.MEMBER_SELECT || cur.Kind == token.MEMBER_SELECT:
+		return false
+	case prev.Kind == token.LPAREN || prev.Kind == token.LBRACK:
+		return false
+	case cur.Kind == token.RPAREN || cur.Kind == token.RBRACK:
+		return false
+	case cur.Kind == token.LBRACE:
+		return true
+	case prev.Kind == token.LBRACE || cur.Kind == token.RBRACE:
+		return true
+	case cur.Kind == token.NOT && prev.Kind == token.IDENT,
+		cur.Kind == token.LPAREN && prev.Kind == token.NOT && prevprev.Kind == token.IDENT:
+		// Macro invocations, as `twice!(x)`.
+		return false
+	case cur.Kind == token.LPAREN || cur.Kind == token.LBRACK:
+		// Calls, indexing and parameter lists stick to the callee, keywords keep their space.
+		return !isOperand(prev.Kind) && prev.Kind != token.FUNC
+	case cur.Kind == token.INC || cur.Kind == token.DEC || cur.Kind == token.ELLIPSIS || cur.Kind == token.QUESTION:
+		return false
+	case prev.Kind == token.AT:
+		// Attributes, as `@cfg(os = "linux")`.
+		return false
+	case token.IsOperator(prev.Kind) && token.IsOperator(cur.Kind) && token.Merges(prev.Kind, cur.Kind):
+		// `- -1` is not `--1`.
+		return true
+	case token.IsOperator(prev.Kind) && !isOperand(prevprev.Kind):
+		// Prefix unary operator.
+		return false
+	}
+	return true
+}
+
+// Options configures formatting, the zero value formats canonically.
+type Options struct {
+	Indent     string // one level of indentation, a tab when empty
+	LineLength int    // lines longer are wrapped after the commas of parenthesized lists, 0 never wraps
+}
+
+// tabWidth is the width of a tab counted against LineLength.
+const tabWidth = 4
+
+// width returns the column reached by writing s from column col, s ending its last line.
+func width(col int, s string) int {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		col, s = 0, s[i+1:]
+	}
+	return col + utf8.RuneCountInString(s) + (tabWidth-1)*strings.Count(s, "\t")
+}
+
+// Source formats a whole file: one tab per open delimiter and case clause, normalized spacing,
+// at most one blank line in a row, and comments kept in place.
+// Formatting the output again yields the same bytes.
+func Source(src []byte) ([]byte, error) { return Options{}.Source(src) }
+
+// Source formats a whole file as the package function does, indenting with o.Indent.
+func (o Options) Source(src []byte) ([]byte, error) {
+	indent := o.Indent
+	if indent == "" {
+		indent = "\t"
+	}
+	toks, file, err := Tokens(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		b     bytes.Buffer
+		depth int
+		// Indentation of the current line, closers on a line only dedent following lines.
+		lineDepth int
+		prev      ast.Token
+		prevprev  ast.Token
+		// Column reached on the current line, and the kinds of the open delimiters.
+		col  int
+		open []int
+		// Whether each open delimiter holds case clauses, whose statements are indented a level deeper.
+		clauses []bool
+	)
+
+	// level is the indentation of the lines inside the open delimiters.
+	level := func() int {
+		n := depth
+		for _, clause := range clauses {
+			if clause {
+				n++
+			}
+		}
+		return n
+	}
+
+	newline := func(depth int) {
+		b.WriteString("\n")
+		col = 0
+		if depth > 0 {
+			b.WriteString(strings.Repeat(indent, depth))
+			col = width(0, strings.Repeat(indent, depth))
+		}
+	}
+
+	for i, tok := range toks {
+		lit := tok.Literal
+		if tok.Kind == token.COMMENT {
+			// Comments are copied from the source as written.
+			lit = strings.TrimRight(file.Text(tok.From, tok.To), " \t\r\n")
+		}
+
+		if i == 0 {
+			lineDepth = depth
+		} else if lines := file.Position(tok.From).Line - file.Position(prev.To).Line; lines > 0 {
+			if lines > 1 {
+				b.WriteString("\n")
+			}
+			lineDepth = level()
+			switch {
+			case isCloser(tok.Kind) && depth > 0:
+				lineDepth--
+				if clauses[len(clauses)-1] {
+					lineDepth--
+				}
+			case (tok.Kind == token.CASE || tok.Kind == token.DEFAULT) && depth > 0 && open[len(open)-1] == token.LBRACE:
+				// Clauses are indented as their block, their statements a level deeper.
+				if !clauses[len(clauses)-1] {
+					clauses[len(clauses)-1] = true
+				} else {
+					lineDepth--
+				}
+			}
+			newline(lineDepth)
+		} else if o.wrap(open, prev, col, lit) {
+			// Line breaks inside parentheses and brackets are not terminators.
+			lineDepth = level()
+			newline(lineDepth)
+		} else if space(prev, prevprev, tok) {
+			b.WriteString(" ")
+			col++
+		}
+
+		b.WriteString(lit)
+		col = width(col, lit)
+
+		switch {
+		case isOpener(tok.Kind):
+			depth++
+			open = append(open, tok.Kind)
+			clauses = append(clauses, false)
+		case isCloser(tok.Kind) && depth > 0:
+			depth--
+			open = open[:len(open)-1]
+			clauses = clauses[:len(clauses)-1]
+		}
+
+		prevprev, prev = prev, tok
+	}
+
+	if len(toks) != 0 {
+		b.WriteString("\n")
+	}
+
+	return b.Bytes(), nil
+}
+
+// wrap decides whether the line breaks before lit, following prev at column col.
+func (o Options) wrap(open []int, prev ast.Token, col int, lit string) bool {
+	if o.LineLength <= 0 || prev.Kind != token.COMMA || len(open) == 0 || open[len(open)-1] == token.LBRACE {
+		return false
+	}
+	return width(col+1, lit) > o.LineLength
+}
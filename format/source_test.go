@@ -0,0 +1,85 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package format_test
+
+import (
+	"cee/format"
+	"testing"
+)
+
+func TestSourceComments(t *testing.T) {
+	src := "package a\n\n// hello\nfun f(a i64) i64 { /* half */ return a / 2 } // done\n"
+	out, err := format.Source([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "package a\n\n// hello\nfun f(a i64) i64 { /* half */ return a / 2 } // done\n"
+	if string(out) != want {
+		t.Errorf("Source = %q, want %q", out, want)
+	}
+}
+
+func TestSourceOperators(t *testing.T) {
+	for _, line := range []string{
+		"val x = - -1",
+		"val x = + +1",
+		"val x = - - -1",
+		"val x = & &y",
+		"val x = & ^y",
+		"val x = a < -b",
+		"val x = a / *p",
+		"val x = <-<-ch",
+		"val x = !!y",
+	} {
+		src := "package a\n\n" + line + "\n"
+		out, err := format.Source([]byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != src {
+			t.Errorf("Source(%q) = %q", line, out)
+		}
+	}
+	out, err := format.Source([]byte("package a\n\nval x = - - 1\n"))
+	if want := "package a\n\nval x = - -1\n"; err != nil || string(out) != want {
+		t.Errorf("Source = %q, %v, want %q", out, err, want)
+	}
+}
+
+func TestSourceAttributes(t *testing.T) {
+	src := "package a\n\n@ cfg(os = \"linux\")\nfun f() i64 {\n\treturn @ intrinsic.len(x)\n}\n"
+	out, err := format.Source([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "package a\n\n@cfg(os = \"linux\")\nfun f() i64 {\n\treturn @intrinsic.len(x)\n}\n"
+	if string(out) != want {
+		t.Errorf("Source = %q, want %q", out, want)
+	}
+}
+
+func TestSourceMacros(t *testing.T) {
+	src := "package a\n\nmacro twice(x) { x + x }\n\nval y = twice ! (2) + twice!(3)\nval z = !y\n"
+	out, err := format.Source([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "package a\n\nmacro twice(x) { x + x }\n\nval y = twice!(2) + twice!(3)\nval z = !y\n"
+	if string(out) != want {
+		t.Errorf("Source = %q, want %q", out, want)
+	}
+}
+
+func TestSourceTry(t *testing.T) {
+	src := "package a\n\nfun f() error {\n\tval x = g() ?\n\treturn g()? - 1 + h(x) ? .y\n}\n"
+	out, err := format.Source([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "package a\n\nfun f() error {\n\tval x = g()?\n\treturn g()? - 1 + h(x)?.y\n}\n"
+	if string(out) != want {
+		t.Errorf("Source = %q, want %q", out, want)
+	}
+}
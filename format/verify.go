@@ -0,0 +1,119 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package format
+
+import (
+	"bytes"
+	"cee/ast"
+	"cee/parser"
+	"cee/token"
+	"fmt"
+	"strings"
+)
+
+// UnstableError reports formatted output that formatting again changes, Offset is the first differing byte.
+type UnstableError struct {
+	Offset        int
+	First, Second []byte
+}
+
+func (e *UnstableError) Error() string {
+	return fmt.Sprintf("format: not idempotent at byte %d: %q became %q",
+		e.Offset, excerpt(e.First, e.Offset), excerpt(e.Second, e.Offset))
+}
+
+// LossError reports formatted output that does not parse to the AST of the input, Path locates the difference
+// as ast.Diff does.
+type LossError struct {
+	Path string
+}
+
+func (e *LossError) Error() string { return "format: output differs from the input at " + e.Path }
+
+func excerpt(b []byte, offset int) []byte {
+	from, to := max(offset-16, 0), min(offset+16, len(b))
+	if from > to {
+		return nil
+	}
+	return b[from:to]
+}
+
+type parsed struct {
+	Decls    []ast.Stmt
+	Comments []string
+	Errors   int
+}
+
+// parse parses the declarations of src the way the build does, the panic of a broken parser is returned.
+func parse(src []byte) (f parsed, err error) {
+	buffer, _ := parser.Decode(src)
+	p := parser.NewFileParser(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+	p.Options.Recover = true
+
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(parser.Bailout); !ok {
+				err = fmt.Errorf("format: %v", r)
+			}
+		}
+		// Formatting trims the trailing space of comments.
+		for _, comment := range p.Comments {
+			f.Comments = append(f.Comments, strings.TrimRight(comment.Literal, " \t\r\n"))
+		}
+		f.Errors = len(p.Diagnosis)
+	}()
+
+	p.Scan()
+	for {
+		p.SkipNewlines()
+		if p.ReachedEOF {
+			break
+		}
+		if decl := p.ExpectDecl(); decl.Tag != 0 {
+			f.Decls = append(f.Decls, decl)
+		}
+	}
+	return f, nil
+}
+
+// Verify formats src and checks the printer against information loss: formatting the output again must yield
+// the same bytes, and the output must parse to the declarations and comments of src with no more diagnostics.
+// It returns the formatted source along with the first violation, an *UnstableError or a *LossError.
+func Verify(src []byte) ([]byte, error) {
+	first, err := Source(src)
+	if err != nil {
+		return nil, err
+	}
+	second, err := Source(first)
+	if err != nil {
+		return first, err
+	}
+	if !bytes.Equal(first, second) {
+		offset := 0
+		for offset < len(first) && offset < len(second) && first[offset] == second[offset] {
+			offset++
+		}
+		return first, &UnstableError{Offset: offset, First: first, Second: second}
+	}
+
+	in, err := parse(src)
+	if err != nil {
+		return first, err
+	}
+	out, err := parse(first)
+	if err != nil {
+		return first, err
+	}
+	if out.Errors > in.Errors {
+		return first, &LossError{Path: fmt.Sprintf("diagnostics, %d instead of %d", out.Errors, in.Errors)}
+	}
+	if d := ast.Diff(in.Comments, out.Comments); d != "" {
+		return first, &LossError{Path: "comments" + strings.TrimPrefix(d, ".")}
+	}
+	if d := ast.Diff(in.Decls, out.Decls); d != "" {
+		return first, &LossError{Path: "decls" + strings.TrimPrefix(d, ".")}
+	}
+	return first, nil
+}
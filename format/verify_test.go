@@ -0,0 +1,32 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package format_test
+
+import (
+	"cee/format"
+	"cee/testutil"
+	"os"
+	"testing"
+)
+
+// TestVerifyCorpus checks the formatter on the sources under $CEE_CORPUS, or on generated programs when it is unset.
+func TestVerifyCorpus(t *testing.T) {
+	sources := []testutil.Source{
+		{Path: "small.cee", Content: testutil.Generate(testutil.GenOptions{Funcs: 10})},
+		{Path: "deep.cee", Content: testutil.Generate(testutil.GenOptions{Funcs: 2, Depth: 8, Stmts: 2})},
+	}
+	if dir := os.Getenv("CEE_CORPUS"); dir != "" {
+		var err error
+		if sources, err = testutil.LoadCorpus(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, src := range sources {
+		if _, err := format.Verify(src.Content); err != nil {
+			t.Errorf("%s: %v", src.Path, err)
+		}
+	}
+}
@@ -0,0 +1,431 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package goast
+
+import (
+	"cee/ast"
+	"cee/token"
+	goast "go/ast"
+	gotoken "go/token"
+)
+
+var goOperators = map[string]gotoken.Token{}
+
+var goLiterals = map[int]gotoken.Token{
+	token.INT:        gotoken.INT,
+	token.FLOAT:      gotoken.FLOAT,
+	token.IMAG:       gotoken.IMAG,
+	token.CHAR:       gotoken.CHAR,
+	token.STRING:     gotoken.STRING,
+	token.STRING_RAW: gotoken.STRING,
+}
+
+var goBuiltinTypes = map[ast.TypeKind]string{
+	ast.TypeI8:  "int8",
+	ast.TypeI16: "int16",
+	ast.TypeI32: "int32",
+	ast.TypeI64: "int64",
+	ast.TypeU8:  "uint8",
+	ast.TypeU16: "uint16",
+	ast.TypeU32: "uint32",
+	ast.TypeU64: "uint64",
+}
+
+func init() {
+	for tok := gotoken.ADD; tok <= gotoken.COLON; tok++ {
+		goOperators[tok.String()] = tok
+	}
+}
+
+// Converter maps cee nodes onto go/ast, positions refer to a file registered in Fset.
+type Converter struct {
+	Fset *gotoken.FileSet
+	File *gotoken.File
+
+	// Source decodes the positions of the converted nodes.
+	Source *token.File
+
+	// byteOffsets maps rune offsets of the source to byte offsets.
+	byteOffsets []int
+}
+
+// NewConverter registers the source parsed in file under the same name in fset.
+func NewConverter(fset *gotoken.FileSet, file *token.File, src []rune) *Converter {
+	offsets := make([]int, len(src)+1)
+	n := 0
+	for i, r := range src {
+		offsets[i] = n
+		n += len(string(r))
+	}
+	offsets[len(src)] = n
+
+	goFile := fset.AddFile(file.Name(), -1, n)
+	goFile.SetLinesForContent([]byte(string(src)))
+
+	return &Converter{Fset: fset, File: goFile, Source: file, byteOffsets: offsets}
+}
+
+func (c *Converter) Pos(pos token.Pos) gotoken.Pos {
+	offset := int(pos) - c.Source.Base()
+	if !pos.IsValid() || offset < 0 || offset >= len(c.byteOffsets) {
+		return gotoken.NoPos
+	}
+	return c.File.Pos(c.byteOffsets[offset])
+}
+
+func (c *Converter) ident(id ast.Ident) *goast.Ident {
+	return &goast.Ident{NamePos: c.Pos(id.From), Name: id.Literal}
+}
+
+func (c *Converter) operator(tok ast.Token) gotoken.Token {
+	if op, ok := goOperators[tok.Literal]; ok {
+		return op
+	}
+	return gotoken.ILLEGAL
+}
+
+func (c *Converter) badExpr(node ast.Node) goast.Expr {
+	pos := node.GetPosRange()
+	return &goast.BadExpr{From: c.Pos(pos.From), To: c.Pos(pos.To)}
+}
+
+func (c *Converter) badStmt(node ast.Node) goast.Stmt {
+	pos := node.GetPosRange()
+	return &goast.BadStmt{From: c.Pos(pos.From), To: c.Pos(pos.To)}
+}
+
+// ConvertFile converts the top-level declarations of a file of the given package.
+func (c *Converter) ConvertFile(pkg string, decls []ast.Stmt) *goast.File {
+	f := &goast.File{Name: goast.NewIdent(pkg), Package: c.Pos(c.Source.Pos(0))}
+
+	for _, decl := range decls {
+		d := c.Decl(decl)
+		f.Decls = append(f.Decls, d)
+		if gen, ok := d.(*goast.GenDecl); ok && gen.Tok == gotoken.IMPORT {
+			f.Imports = append(f.Imports, gen.Specs[0].(*goast.ImportSpec))
+		}
+	}
+	return f
+}
+
+func (c *Converter) Decl(s ast.Stmt) goast.Decl {
+	switch v := s.Value.(type) {
+	case ast.ImportDecl:
+		spec := &goast.ImportSpec{Path: &goast.BasicLit{ValuePos: c.Pos(v.CanonicalName.From), Kind: gotoken.STRING, Value: v.CanonicalName.Literal}}
+		if v.Alias != nil {
+			spec.Name = c.ident(*v.Alias)
+		}
+		return &goast.GenDecl{TokPos: c.Pos(v.From), Tok: gotoken.IMPORT, Specs: []goast.Spec{spec}}
+	case ast.TypeDecl:
+		spec := &goast.TypeSpec{Name: c.ident(v.Ident), TypeParams: c.typeParams(v.TypeParams), Type: c.Type(v.Type)}
+		return &goast.GenDecl{TokPos: c.Pos(v.From), Tok: gotoken.TYPE, Specs: []goast.Spec{spec}}
+	case ast.GenDecl:
+		return &goast.GenDecl{TokPos: c.Pos(v.From), Tok: gotoken.VAR, Specs: []goast.Spec{c.valueSpec(v)}}
+	case ast.ValDecl:
+		if v.Pattern != nil {
+			// Go has no destructuring, the bindings are lowered in HIR instead.
+			return &goast.BadDecl{From: c.Pos(v.From), To: c.Pos(v.To)}
+		}
+		spec := &goast.ValueSpec{Names: []*goast.Ident{c.ident(v.Name)}, Values: []goast.Expr{c.Expr(v.Value)}}
+		if v.Type.Value != nil {
+			spec.Type = c.Type(v.Type)
+		}
+		tok := gotoken.CONST
+		if v.Mutable {
+			tok = gotoken.VAR
+		}
+		return &goast.GenDecl{TokPos: c.Pos(v.From), Tok: tok, Specs: []goast.Spec{spec}}
+	case ast.FuncDecl:
+		d := &goast.FuncDecl{Type: c.FuncType(v.Type)}
+		d.Type.Func = c.Pos(v.From)
+		d.Type.TypeParams = c.typeParams(v.TypeParams)
+		if v.Ident != nil {
+			d.Name = c.ident(*v.Ident)
+		} else {
+			d.Name = goast.NewIdent("_")
+		}
+		if v.Stmt != nil {
+			d.Body = c.Block(*v.Stmt)
+		}
+		return d
+	case ast.ExternDecl:
+		// Externs are functions without body, which is how Go declares assembly or linked functions.
+		d := &goast.FuncDecl{Name: c.ident(v.Ident), Type: c.FuncType(v.Type)}
+		d.Type.Func = c.Pos(v.From)
+		return d
+	default:
+		pos := s.GetPosRange()
+		return &goast.BadDecl{From: c.Pos(pos.From), To: c.Pos(pos.To)}
+	}
+}
+
+func (c *Converter) valueSpec(d ast.GenDecl) *goast.ValueSpec {
+	spec := &goast.ValueSpec{Type: c.Type(d.Type)}
+	for _, ident := range d.Idents {
+		spec.Names = append(spec.Names, c.ident(ident))
+	}
+	return spec
+}
+
+func (c *Converter) field(d ast.GenDecl) *goast.Field {
+	field := &goast.Field{Type: c.Type(d.Type)}
+	for _, ident := range d.Idents {
+		field.Names = append(field.Names, c.ident(ident))
+	}
+	return field
+}
+
+func (c *Converter) Type(t ast.Type) goast.Expr {
+	if t.Value == nil {
+		if name, ok := goBuiltinTypes[t.Tag]; ok {
+			return goast.NewIdent(name)
+		}
+		return &goast.BadExpr{}
+	}
+
+	switch v := t.Value.(type) {
+	case ast.TypeAlias:
+		id := c.ident(v.Ident)
+		if name, ok := goBuiltinTypes[t.Tag]; ok {
+			id.Name = name
+		}
+		return id
+	case ast.StructType:
+		fields := &goast.FieldList{Opening: c.Pos(v.From), Closing: c.Pos(v.To - 1)}
+		for _, field := range v.Fields {
+			fields.List = append(fields.List, c.field(field))
+		}
+		return &goast.StructType{Struct: c.Pos(v.From), Fields: fields}
+	case ast.TraitType:
+		methods := &goast.FieldList{Opening: c.Pos(v.From), Closing: c.Pos(v.To - 1)}
+		for _, embed := range v.Embeds {
+			methods.List = append(methods.List, &goast.Field{Type: c.ident(embed.Ident)})
+		}
+		for _, method := range v.Methods {
+			methods.List = append(methods.List, &goast.Field{
+				Names: []*goast.Ident{c.ident(method.Ident)},
+				Type:  c.FuncType(method.Type),
+			})
+		}
+		return &goast.InterfaceType{Interface: c.Pos(v.From), Methods: methods}
+	case ast.FuncType:
+		return c.FuncType(v)
+	case ast.GenericInstantiationExpr:
+		return c.instantiation(v)
+	case ast.MapType:
+		return &goast.MapType{Map: c.Pos(v.From), Key: c.Type(v.Key), Value: c.Type(v.Value)}
+	case ast.ChanType:
+		dir := goast.SEND | goast.RECV
+		switch v.Dir {
+		case ast.ChanSend:
+			dir = goast.SEND
+		case ast.ChanRecv:
+			dir = goast.RECV
+		}
+		return &goast.ChanType{Begin: c.Pos(v.From), Dir: dir, Value: c.Type(v.Elem)}
+	case ast.PointerType:
+		return &goast.StarExpr{Star: c.Pos(v.From), X: c.Type(v.Elem)}
+	case ast.ArrayType:
+		array := &goast.ArrayType{Lbrack: c.Pos(v.From), Elt: c.Type(v.Elem)}
+		if v.Len.Value != nil {
+			array.Len = c.Expr(v.Len)
+		}
+		return array
+	default:
+		return c.badExpr(t)
+	}
+}
+
+// typeParams converts type parameters, those without constraint are constrained by any.
+func (c *Converter) typeParams(params []ast.TypeParam) *goast.FieldList {
+	if len(params) == 0 {
+		return nil
+	}
+	list := &goast.FieldList{}
+	for _, param := range params {
+		field := &goast.Field{Type: goast.NewIdent("any")}
+		if param.Constraint.Value != nil {
+			field.Type = c.Type(param.Constraint)
+		}
+		for _, ident := range param.Idents {
+			field.Names = append(field.Names, c.ident(ident))
+		}
+		list.List = append(list.List, field)
+	}
+	return list
+}
+
+func (c *Converter) instantiation(e ast.GenericInstantiationExpr) goast.Expr {
+	var args []goast.Expr
+	for _, arg := range e.TypeArgs {
+		args = append(args, c.Type(arg))
+	}
+	return &goast.IndexListExpr{X: c.Expr(e.Expr), Indices: args, Rbrack: c.Pos(e.To - 1)}
+}
+
+// compositeLit converts a composite literal, the type of a nested literal stays elided.
+func (c *Converter) compositeLit(e ast.CompositeLit) goast.Expr {
+	lit := &goast.CompositeLit{Lbrace: c.Pos(e.From), Rbrace: c.Pos(e.To - 1)}
+	if e.Type.Value != nil {
+		lit.Type = c.Type(e.Type)
+		lit.Lbrace = c.Pos(e.Type.GetPosRange().To)
+	}
+	for _, elem := range e.Elems {
+		if elem.Key.Value == nil {
+			lit.Elts = append(lit.Elts, c.Expr(elem.Value))
+			continue
+		}
+		lit.Elts = append(lit.Elts, &goast.KeyValueExpr{Key: c.Expr(elem.Key), Colon: c.Pos(elem.Key.GetPosRange().To), Value: c.Expr(elem.Value)})
+	}
+	return lit
+}
+
+func (c *Converter) FuncType(t ast.FuncType) *goast.FuncType {
+	typ := &goast.FuncType{Params: &goast.FieldList{Opening: c.Pos(t.From)}}
+	for _, param := range t.Params {
+		typ.Params.List = append(typ.Params.List, c.field(param))
+	}
+	if len(t.Results) != 0 {
+		typ.Results = &goast.FieldList{}
+		for _, result := range t.Results {
+			typ.Results.List = append(typ.Results.List, &goast.Field{Type: c.Type(result)})
+		}
+	}
+	return typ
+}
+
+func (c *Converter) Expr(e ast.Expr) goast.Expr {
+	switch v := e.Value.(type) {
+	case ast.Ident:
+		return c.ident(v)
+	case ast.LiteralValue:
+		kind, ok := goLiterals[v.Kind]
+		if !ok {
+			return c.badExpr(v)
+		}
+		return &goast.BasicLit{ValuePos: c.Pos(v.From), Kind: kind, Value: v.Literal}
+	case ast.UnaryExpr:
+		op := c.operator(v.Operator)
+		if op == gotoken.MUL {
+			return &goast.StarExpr{Star: c.Pos(v.From), X: c.Expr(v.Expr)}
+		}
+		return &goast.UnaryExpr{OpPos: c.Pos(v.Operator.From), Op: op, X: c.Expr(v.Expr)}
+	case ast.BinaryExpr:
+		return &goast.BinaryExpr{X: c.Expr(v.Exprs[0]), OpPos: c.Pos(v.Operator.From), Op: c.operator(v.Operator), Y: c.Expr(v.Exprs[1])}
+	case ast.CallExpr:
+		call := &goast.CallExpr{Fun: c.Expr(v.Callee), Rparen: c.Pos(v.To - 1)}
+		for _, param := range v.Params {
+			if ellipsis, ok := param.Value.(ast.EllipsisExpr); ok {
+				call.Ellipsis = c.Pos(ellipsis.To - 3)
+				param = ellipsis.Array
+			}
+			call.Args = append(call.Args, c.Expr(param))
+		}
+		return call
+	case ast.IndexExpr:
+		return &goast.IndexExpr{X: c.Expr(v.Expr), Index: c.Expr(v.Index), Rbrack: c.Pos(v.To - 1)}
+	case ast.GenericInstantiationExpr:
+		return c.instantiation(v)
+	case ast.TypeExpr:
+		return c.Type(v.Type)
+	case ast.CompositeLit:
+		return c.compositeLit(v)
+	case ast.FuncLitExpr:
+		typ := c.FuncType(v.Type)
+		typ.Func = c.Pos(v.From)
+		return &goast.FuncLit{Type: typ, Body: c.Block(v.Body)}
+	case ast.MemberSelectExpr:
+		return &goast.SelectorExpr{X: c.Expr(v.Expr), Sel: c.ident(v.Member)}
+	case ast.QualifiedIdent:
+		return &goast.SelectorExpr{X: c.ident(v.Package), Sel: c.ident(v.Member)}
+	case ast.StmtBlockExpr:
+		// An immediately invoked closure stands in for a block expression.
+		lit := &goast.FuncLit{Type: &goast.FuncType{Func: c.Pos(v.From), Params: &goast.FieldList{}}, Body: c.Block(v)}
+		if v.Type.Tag != 0 {
+			lit.Type.Results = &goast.FieldList{List: []*goast.Field{{Type: c.Type(v.Type)}}}
+		}
+		return &goast.CallExpr{Fun: lit}
+	default:
+		return c.badExpr(e)
+	}
+}
+
+func (c *Converter) Block(b ast.StmtBlockExpr) *goast.BlockStmt {
+	block := &goast.BlockStmt{Lbrace: c.Pos(b.From), Rbrace: c.Pos(b.To - 1)}
+	for _, stmt := range b.Stmts {
+		block.List = append(block.List, c.Stmt(stmt))
+	}
+	return block
+}
+
+func (c *Converter) branch(b ast.BranchExpr) *goast.IfStmt {
+	stmt := &goast.IfStmt{If: c.Pos(b.From), Cond: c.Expr(b.Cond), Body: c.Block(b.Branch)}
+	if len(b.ElseBranch.Stmts) != 0 {
+		stmt.Else = c.Block(b.ElseBranch)
+	}
+	return stmt
+}
+
+func (c *Converter) Stmt(s ast.Stmt) goast.Stmt {
+	switch v := s.Value.(type) {
+	case ast.Expr:
+		if b, ok := v.Value.(ast.BranchExpr); ok {
+			return c.branch(b)
+		}
+		return &goast.ExprStmt{X: c.Expr(v)}
+	case ast.ValDecl:
+		if v.Pattern != nil {
+			return c.badStmt(v)
+		}
+		if v.Type.Value != nil {
+			return &goast.DeclStmt{Decl: c.Decl(s)}
+		}
+		return &goast.AssignStmt{Lhs: []goast.Expr{c.ident(v.Name)}, TokPos: c.Pos(v.Name.To), Tok: gotoken.DEFINE, Rhs: []goast.Expr{c.Expr(v.Value)}}
+	case ast.GenDecl, ast.TypeDecl, ast.FuncDecl:
+		decl, ok := c.Decl(s).(*goast.GenDecl)
+		if !ok {
+			return c.badStmt(s)
+		}
+		return &goast.DeclStmt{Decl: decl}
+	case ast.ReturnStmt:
+		stmt := &goast.ReturnStmt{Return: c.Pos(v.From)}
+		for _, expr := range v.Exprs {
+			stmt.Results = append(stmt.Results, c.Expr(expr))
+		}
+		return stmt
+	case ast.AssignStmt:
+		tok := gotoken.ASSIGN
+		if v.Operator.Kind != 0 {
+			tok = c.operator(v.Operator)
+		}
+		return &goast.AssignStmt{Lhs: []goast.Expr{c.Expr(v.ExprL)}, TokPos: c.Pos(v.Operator.From), Tok: tok, Rhs: []goast.Expr{c.Expr(v.ExprR)}}
+	case ast.BreakStmt:
+		return &goast.BranchStmt{TokPos: c.Pos(v.From), Tok: gotoken.BREAK}
+	case ast.ContinueStmt:
+		return &goast.BranchStmt{TokPos: c.Pos(v.From), Tok: gotoken.CONTINUE}
+	case ast.LoopStmt:
+		return &goast.ForStmt{For: c.Pos(v.From), Cond: c.Expr(v.Cond), Body: c.Block(v.Stmt)}
+	case ast.ForeachStmt:
+		stmt := &goast.RangeStmt{For: c.Pos(v.From), Tok: gotoken.DEFINE, X: c.Expr(v.Expr), Body: c.Block(v.Stmt)}
+		switch len(v.IdentList) {
+		case 1:
+			stmt.Key, stmt.Value = goast.NewIdent("_"), c.ident(v.IdentList[0])
+		case 2:
+			stmt.Key, stmt.Value = c.ident(v.IdentList[0]), c.ident(v.IdentList[1])
+		}
+		return stmt
+	case ast.EndlessForStmt:
+		return &goast.ForStmt{For: c.Pos(v.Stmt.From), Body: c.Block(v.Stmt)}
+	case ast.DeferStmt:
+		// Block expressions convert to calls of closures as well, errdefer has no Go equivalent.
+		call, ok := c.Expr(v.Expr).(*goast.CallExpr)
+		if !ok || v.OnError {
+			return c.badStmt(v)
+		}
+		return &goast.DeferStmt{Defer: c.Pos(v.From), Call: call}
+	default:
+		return c.badStmt(s)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package goast
+
+import (
+	"bytes"
+	"cee/parser"
+	"cee/token"
+	"fmt"
+	goast "go/ast"
+	goprinter "go/printer"
+	gotoken "go/token"
+	"testing"
+)
+
+func convert(t *testing.T, src string) (*gotoken.FileSet, *goast.File) {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.cee", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gofset := gotoken.NewFileSet()
+	c := NewConverter(gofset, fset.File(f.From), []rune(src))
+	return gofset, c.ConvertFile("a", f.Decls)
+}
+
+func TestConvert(t *testing.T) {
+	const src = `package a
+
+import run "lib"
+
+type Point struct {
+	x, y i64
+}
+
+fun len2(p Point, xs i64) i64 {
+	var n = p.x * p.x
+	n += p.y * p.y
+	if n > 0 {
+		return n
+	}
+	for i in xs {
+		n = n - i
+	}
+	return -n
+}
+
+extern "C" fun abs(x i64) i64
+
+val s = run.f("ü", s)
+`
+	gofset, file := convert(t, src)
+
+	var b bytes.Buffer
+	if err := goprinter.Fprint(&b, gofset, file); err != nil {
+		t.Fatal(err)
+	}
+	want := `package a
+
+import run "lib"
+
+type Point struct {
+	x, y int64
+}
+
+func len2(p Point, xs int64) int64 {
+	n := p.x * p.x
+	n += p.y * p.y
+	if n > 0 {
+		return n
+	}
+	for _, i := range xs {
+		n = n - i
+	}
+	return -n
+}
+
+func abs(x int64) int64
+
+const s = run.f("ü", s)
+`
+	if b.String() != want {
+		t.Errorf("converted =\n%s\nwant\n%s", b.String(), want)
+	}
+	if len(file.Imports) != 1 || file.Imports[0].Name.Name != "run" {
+		t.Errorf("imports %v", file.Imports)
+	}
+
+	// Identifiers keep their positions, Go columns count bytes rather than runes: the last s is at rune 20.
+	var idents []string
+	goast.Inspect(file, func(n goast.Node) bool {
+		if id, ok := n.(*goast.Ident); ok && id.Pos().IsValid() && (id.Name == "i" || id.Name == "s" || id.Name == "abs") {
+			p := gofset.Position(id.Pos())
+			idents = append(idents, fmt.Sprintf("%s %s:%d:%d", id.Name, p.Filename, p.Line, p.Column))
+		}
+		return true
+	})
+	if got := fmt.Sprint(idents); got != "[i a.cee:15:6 i a.cee:16:11 abs a.cee:21:16 s a.cee:23:5 s a.cee:23:21]" {
+		t.Errorf("identifiers %s", got)
+	}
+}
+
+func TestConvertBad(t *testing.T) {
+	_, file := convert(t, `package a
+
+val (a, b) = f()
+
+fun g() {
+	val (c, d) = f()
+	errdefer f()
+}
+`)
+	var bad []string
+	goast.Inspect(file, func(n goast.Node) bool {
+		switch n.(type) {
+		case *goast.BadDecl, *goast.BadStmt, *goast.BadExpr:
+			bad = append(bad, fmt.Sprintf("%T", n))
+		}
+		return true
+	})
+	// Go has no destructuring and no errdefer.
+	if got := fmt.Sprint(bad); got != "[*ast.BadDecl *ast.BadStmt *ast.BadStmt]" {
+		t.Errorf("bad nodes %s", got)
+	}
+}
@@ -0,0 +1,7 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+// Package goast
+// Conversion of cee ASTs into the closest go/ast equivalents, so Go analysis tooling can inspect them.
+package goast
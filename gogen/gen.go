@@ -0,0 +1,610 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package gogen
+
+import (
+	"bytes"
+	"cee/ast"
+	"cee/debuginfo"
+	"cee/diagnosis"
+	"cee/intrinsic"
+	"cee/token"
+	"fmt"
+	"go/format"
+	"strconv"
+	"unicode/utf8"
+)
+
+var builtinTypes = map[ast.TypeKind]string{
+	ast.TypeI8:  "int8",
+	ast.TypeI16: "int16",
+	ast.TypeI32: "int32",
+	ast.TypeI64: "int64",
+	ast.TypeU8:  "uint8",
+	ast.TypeU16: "uint16",
+	ast.TypeU32: "uint32",
+	ast.TypeU64: "uint64",
+}
+
+type Generator struct {
+	Package string
+
+	// Debug enables `/*line*/` directives, so panics in the generated code report cee positions,
+	// and collects variable locations into the table. File decodes the positions, it is required with Debug.
+	Debug *debuginfo.Table
+	File  *token.File
+
+	Diagnosis []diagnosis.Diagnosis
+
+	buf    bytes.Buffer
+	scopes []ast.PosRange
+
+	strings map[string]string // names of the pooled string constants by value
+	pooled  []string          // values of the pooled string constants in order of declaration
+}
+
+func NewGenerator(pkg string) Generator {
+	return Generator{Package: pkg}
+}
+
+func (g *Generator) unsupported(node ast.Node) {
+	g.Diagnosis = append(g.Diagnosis, diagnosis.Diagnosis{
+		Kind:  diagnosis.UnsupportedNode,
+		Error: diagnosis.UnsupportedNodeError{Node: node},
+	})
+	g.print("/* unsupported */")
+}
+
+func (g *Generator) print(a ...any) { fmt.Fprint(&g.buf, a...) }
+
+// Generate emits a Go file for the top-level declarations.
+// The result is passed through go/format, the unformatted source is returned along with the error if that fails.
+func (g *Generator) Generate(decls []ast.Stmt) ([]byte, error) {
+	g.buf.Reset()
+	g.pool(decls)
+	g.print("package ", g.Package, "\n\n")
+
+	for _, decl := range decls {
+		if decl.Tag == ast.StmtImportDecl {
+			g.ImportDecl(decl.Value.(ast.ImportDecl))
+		}
+	}
+	for _, decl := range decls {
+		// Macros were expanded by the parser, their declarations generate nothing.
+		if decl.Tag != ast.StmtImportDecl && decl.Tag != ast.StmtMacroDecl {
+			g.Stmt(decl)
+			g.print("\n")
+		}
+	}
+	if len(g.pooled) != 0 {
+		g.print("const (\n")
+		for _, s := range g.pooled {
+			g.print(g.strings[s], " = ", strconv.Quote(s), "\n")
+		}
+		g.print(")\n")
+	}
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return g.buf.Bytes(), err
+	}
+	return src, nil
+}
+
+// pool collects the values of the string literals used more than once, which are declared once as constants
+// named `_str<n>`, prefixed with underscores until no identifier of decls has the name.
+func (g *Generator) pool(decls []ast.Stmt) {
+	g.strings, g.pooled = map[string]string{}, nil
+
+	counts, idents := map[string]int{}, map[string]bool{}
+	var values []string
+	for _, decl := range decls {
+		ast.Inspect(decl, func(node ast.Node) bool {
+			switch v := node.(type) {
+			case ast.Ident:
+				idents[v.Literal] = true
+			case ast.Expr:
+				if lit, ok := v.Value.(ast.LiteralValue); ok && token.IsString(lit.Kind) {
+					if s, err := token.Unquote(lit.Literal); err == nil {
+						if counts[s]++; counts[s] == 2 {
+							values = append(values, s)
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	for i, s := range values {
+		name := "_str" + strconv.Itoa(i)
+		for idents[name] {
+			name = "_" + name
+		}
+		g.strings[s] = name
+		g.pooled = append(g.pooled, s)
+	}
+}
+
+func (g *Generator) ImportDecl(d ast.ImportDecl) {
+	g.print("import ")
+	if d.Alias != nil {
+		g.print(d.Alias.Literal, " ")
+	}
+	g.print(d.CanonicalName.Literal, "\n")
+}
+
+func (g *Generator) Type(t ast.Type) {
+	if name, ok := builtinTypes[t.Tag]; ok {
+		g.print(name)
+		return
+	}
+
+	switch t.Tag {
+	case ast.TypeNone:
+	case ast.TypeIdent:
+		g.print(t.Value.(ast.TypeAlias).Literal)
+	case ast.TypeStruct:
+		g.print("struct {\n")
+		for _, field := range t.Value.(ast.StructType).Fields {
+			g.GenDecl(field)
+			g.print("\n")
+		}
+		g.print("}")
+	case ast.TypeTrait:
+		trait := t.Value.(ast.TraitType)
+		if len(trait.Embeds) == 0 && len(trait.Methods) == 0 {
+			g.print("interface{}")
+			break
+		}
+		g.print("interface {\n")
+		for _, embed := range trait.Embeds {
+			g.print(embed.Literal, "\n")
+		}
+		for _, method := range trait.Methods {
+			g.print(method.Ident.Literal)
+			g.FuncType(method.Type)
+			g.print("\n")
+		}
+		g.print("}")
+	case ast.TypeFunc:
+		g.print("func")
+		g.FuncType(t.Value.(ast.FuncType))
+	case ast.TypeInstance:
+		g.Instantiation(t.Value.(ast.GenericInstantiationExpr))
+	case ast.TypeMap:
+		m := t.Value.(ast.MapType)
+		g.print("map[")
+		g.Type(m.Key)
+		g.print("]")
+		g.Type(m.Value)
+	case ast.TypeChan:
+		c := t.Value.(ast.ChanType)
+		switch c.Dir {
+		case ast.ChanSend:
+			g.print("chan<- ")
+		case ast.ChanRecv:
+			g.print("<-chan ")
+		default:
+			g.print("chan ")
+		}
+		g.Type(c.Elem)
+	case ast.TypePointer:
+		g.print("*")
+		g.Type(t.Value.(ast.PointerType).Elem)
+	case ast.TypeArray:
+		a := t.Value.(ast.ArrayType)
+		g.print("[")
+		if a.Len.Value != nil {
+			g.Expr(a.Len)
+		}
+		g.print("]")
+		g.Type(a.Elem)
+	default:
+		g.unsupported(t)
+	}
+}
+
+// TypeParams emits `[T, U any]`, type parameters without constraint accept any type.
+func (g *Generator) TypeParams(params []ast.TypeParam) {
+	if len(params) == 0 {
+		return
+	}
+	g.print("[")
+	for i, param := range params {
+		if i != 0 {
+			g.print(", ")
+		}
+		for j, ident := range param.Idents {
+			if j != 0 {
+				g.print(", ")
+			}
+			g.print(ident.Literal)
+		}
+		g.print(" ")
+		if param.Constraint.Value == nil {
+			g.print("any")
+		} else {
+			g.Type(param.Constraint)
+		}
+	}
+	g.print("]")
+}
+
+func (g *Generator) Instantiation(e ast.GenericInstantiationExpr) {
+	g.Expr(e.Expr)
+	g.print("[")
+	for i, arg := range e.TypeArgs {
+		if i != 0 {
+			g.print(", ")
+		}
+		g.Type(arg)
+	}
+	g.print("]")
+}
+
+func (g *Generator) GenDecl(d ast.GenDecl) {
+	for i, ident := range d.Idents {
+		if i != 0 {
+			g.print(", ")
+		}
+		g.print(ident.Literal)
+	}
+	g.print(" ")
+	g.Type(d.Type)
+}
+
+func (g *Generator) FuncType(t ast.FuncType) {
+	g.print("(")
+	for i, param := range t.Params {
+		if i != 0 {
+			g.print(", ")
+		}
+		g.GenDecl(param)
+	}
+	g.print(")")
+
+	switch len(t.Results) {
+	case 0:
+	case 1:
+		g.print(" ")
+		g.Type(t.Results[0])
+	default:
+		g.print(" (")
+		for i, result := range t.Results {
+			if i != 0 {
+				g.print(", ")
+			}
+			g.Type(result)
+		}
+		g.print(")")
+	}
+}
+
+// literal prints a literal, or the name of the constant pooling the value of a string.
+// Strings and chars are decoded and quoted again, Go having none of the escapes cee adds, as `\e` and `\0`.
+func (g *Generator) literal(lit ast.LiteralValue) {
+	if token.IsString(lit.Kind) || lit.Kind == token.CHAR {
+		if s, err := token.Unquote(lit.Literal); err == nil {
+			switch name, pooled := g.strings[s]; {
+			case lit.Kind == token.CHAR:
+				r, _ := utf8.DecodeRuneInString(s)
+				g.print(strconv.QuoteRune(r))
+			case pooled:
+				g.print(name)
+			default:
+				g.print(strconv.Quote(s))
+			}
+			return
+		}
+	}
+	g.print(lit.Literal)
+}
+
+func (g *Generator) Expr(e ast.Expr) {
+	switch e.Tag {
+	case ast.ExprIdent:
+		g.print(e.Value.(ast.Ident).Literal)
+	case ast.ExprLiteralValue:
+		g.literal(e.Value.(ast.LiteralValue))
+	case ast.ExprUnary:
+		u := e.Value.(ast.UnaryExpr)
+		g.print(u.Operator.Literal)
+		g.Expr(u.Expr)
+	case ast.ExprBinary:
+		b := e.Value.(ast.BinaryExpr)
+		g.print("(")
+		g.Expr(b.Exprs[0])
+		g.print(" ", b.Operator.Literal, " ")
+		g.Expr(b.Exprs[1])
+		g.print(")")
+	case ast.ExprCall:
+		c := e.Value.(ast.CallExpr)
+		g.Expr(c.Callee)
+		g.print("(")
+		for i, param := range c.Params {
+			if i != 0 {
+				g.print(", ")
+			}
+			g.Expr(param)
+		}
+		g.print(")")
+	case ast.ExprIndex:
+		ie := e.Value.(ast.IndexExpr)
+		g.Expr(ie.Expr)
+		g.print("[")
+		g.Expr(ie.Index)
+		g.print("]")
+	case ast.ExprGenericInstantiation:
+		g.Instantiation(e.Value.(ast.GenericInstantiationExpr))
+	case ast.ExprType:
+		g.Type(e.Value.(ast.TypeExpr).Type)
+	case ast.ExprCompositeLit:
+		g.CompositeLit(e.Value.(ast.CompositeLit))
+	case ast.ExprFuncLit:
+		// Go closures capture by reference, captures by value are not distinguished.
+		f := e.Value.(ast.FuncLitExpr)
+		g.print("func")
+		g.FuncType(f.Type)
+		g.print(" ")
+		g.Block(f.Body)
+	case ast.ExprMemberSelect:
+		m := e.Value.(ast.MemberSelectExpr)
+		g.Expr(m.Expr)
+		g.print(".", m.Member.Literal)
+	case ast.ExprQualifiedIdent:
+		q := e.Value.(ast.QualifiedIdent)
+		g.print(q.Package.Literal, ".", q.Member.Literal)
+	case ast.ExprEllipsis:
+		g.Expr(e.Value.(ast.EllipsisExpr).Array)
+		g.print("...")
+	case ast.ExprStmtBlock:
+		// Go has no block expressions, an immediately invoked closure keeps the semantics.
+		b := e.Value.(ast.StmtBlockExpr)
+		g.print("func() ")
+		if b.Type.Tag == 0 {
+			g.Block(b)
+		} else {
+			g.Type(b.Type)
+			g.print(" ")
+			g.ValueBlock(b)
+		}
+		g.print("()")
+	case ast.ExprIntrinsic:
+		g.Intrinsic(e.Value.(ast.IntrinsicExpr))
+	default:
+		g.unsupported(e)
+	}
+}
+
+// CompositeLit emits `Type{key: value, ...}`, Go elides the types of nested literals alike.
+func (g *Generator) CompositeLit(lit ast.CompositeLit) {
+	if lit.Type.Value != nil {
+		g.Type(lit.Type)
+	}
+	g.print("{")
+	for i, elem := range lit.Elems {
+		if i != 0 {
+			g.print(", ")
+		}
+		if elem.Key.Value != nil {
+			g.Expr(elem.Key)
+			g.print(": ")
+		}
+		g.Expr(elem.Value)
+	}
+	g.print("}")
+}
+
+// Intrinsic maps an intrinsic to the equivalent Go builtin or statement, the memory intrinsics operate on slices.
+func (g *Generator) Intrinsic(e ast.IntrinsicExpr) {
+	in, ok := intrinsic.Lookup(e.Namespace.Literal, e.Name.Literal)
+	if !ok || len(e.Params) != in.Params {
+		g.unsupported(e)
+		return
+	}
+
+	// prefix prints `p[:n]`.
+	prefix := func(p ast.Expr) {
+		g.Expr(p)
+		g.print("[:")
+		g.Expr(e.Params[2])
+		g.print("]")
+	}
+
+	switch in.Op {
+	case intrinsic.Memcpy, intrinsic.Memmove:
+		// copy handles overlapping slices, which is stronger than memcpy requires.
+		g.print("copy(")
+		prefix(e.Params[0])
+		g.print(", ")
+		prefix(e.Params[1])
+		g.print(")")
+	case intrinsic.Memset:
+		g.print("func(s []byte, b byte) { for i := range s { s[i] = b } }(")
+		prefix(e.Params[0])
+		g.print(", ")
+		g.Expr(e.Params[1])
+		g.print(")")
+	case intrinsic.Trap:
+		g.print(`panic("trap")`)
+	case intrinsic.Unreachable:
+		g.print(`panic("unreachable")`)
+	default:
+		g.unsupported(e)
+	}
+}
+
+func (g *Generator) lineDirective(pos ast.PosRange) {
+	// Code expanded from macros lies in the files of the expansions and keeps the directive of the code around it.
+	if g.Debug == nil || !g.File.Contains(pos.From) {
+		return
+	}
+	// Decoded positions are zero-based, line directives are one-based.
+	from := g.File.Position(pos.From)
+	g.print("/*line ", g.Debug.File, ":", from.Line+1, ":", from.Column+1, "*/")
+}
+
+func (g *Generator) declareVar(name string) {
+	if g.Debug == nil || len(g.scopes) == 0 || !g.File.Contains(g.scopes[len(g.scopes)-1].From) {
+		return
+	}
+	g.Debug.AddVar(name, debuginfo.NewRange(g.File, g.scopes[len(g.scopes)-1]), name)
+}
+
+func (g *Generator) Block(b ast.StmtBlockExpr) {
+	g.scopes = append(g.scopes, b.PosRange)
+	defer func() { g.scopes = g.scopes[:len(g.scopes)-1] }()
+
+	g.print("{\n")
+	for _, stmt := range b.Stmts {
+		g.Stmt(stmt)
+		g.print("\n")
+	}
+	g.print("}")
+}
+
+// ValueBlock emits a block of a type, whose value is the trailing expression statement, as the body of a closure
+// returning it.
+func (g *Generator) ValueBlock(b ast.StmtBlockExpr) {
+	n := len(b.Stmts)
+	if n == 0 || b.Stmts[n-1].Tag != ast.StmtExpr || b.Stmts[n-1].Value.(ast.Expr).Tag == ast.ExprBranch {
+		g.unsupported(b)
+		return
+	}
+
+	g.scopes = append(g.scopes, b.PosRange)
+	defer func() { g.scopes = g.scopes[:len(g.scopes)-1] }()
+
+	g.print("{\n")
+	for _, stmt := range b.Stmts[:n-1] {
+		g.Stmt(stmt)
+		g.print("\n")
+	}
+	g.lineDirective(b.Stmts[n-1].GetPosRange())
+	g.print("return ")
+	g.Expr(b.Stmts[n-1].Value.(ast.Expr))
+	g.print("\n}")
+}
+
+func (g *Generator) Branch(b ast.BranchExpr) {
+	g.print("if ")
+	g.Expr(b.Cond)
+	g.print(" ")
+	g.Block(b.Branch)
+	if len(b.ElseBranch.Stmts) == 0 {
+		return
+	}
+	g.print(" else ")
+	if branch, ok := b.ElseIf(); ok {
+		g.Branch(branch)
+		return
+	}
+	g.Block(b.ElseBranch)
+}
+
+func (g *Generator) Stmt(s ast.Stmt) {
+	if s.Tag != ast.StmtTypeDecl && s.Tag != ast.StmtImportDecl {
+		g.lineDirective(s.GetPosRange())
+	}
+
+	switch s.Tag {
+	case ast.StmtExpr:
+		e := s.Value.(ast.Expr)
+		if e.Tag == ast.ExprBranch {
+			g.Branch(e.Value.(ast.BranchExpr))
+		} else {
+			g.Expr(e)
+		}
+	case ast.StmtValDecl:
+		d := s.Value.(ast.ValDecl)
+		if d.Pattern != nil {
+			g.unsupported(d)
+			break
+		}
+		g.declareVar(d.Name.Literal)
+		if d.Type.Value != nil {
+			g.print("var ", d.Name.Literal, " ")
+			g.Type(d.Type)
+			g.print(" = ")
+		} else if len(g.scopes) == 0 {
+			// Short variable declarations are statements, the package scope has none.
+			g.print("var ", d.Name.Literal, " = ")
+		} else {
+			g.print(d.Name.Literal, " := ")
+		}
+		g.Expr(d.Value)
+	case ast.StmtGenDecl:
+		d := s.Value.(ast.GenDecl)
+		for _, ident := range d.Idents {
+			g.declareVar(ident.Literal)
+		}
+		g.print("var ")
+		g.GenDecl(d)
+	case ast.StmtTypeDecl:
+		d := s.Value.(ast.TypeDecl)
+		g.print("type ", d.Ident.Literal)
+		g.TypeParams(d.TypeParams)
+		g.print(" ")
+		g.Type(d.Type)
+	case ast.StmtFuncDecl:
+		d := s.Value.(ast.FuncDecl)
+		g.print("func ")
+		if d.Ident != nil {
+			g.print(d.Ident.Literal)
+		}
+		g.TypeParams(d.TypeParams)
+		g.FuncType(d.Type)
+		if d.Stmt != nil {
+			g.print(" ")
+			g.Block(*d.Stmt)
+		}
+	case ast.StmtReturn:
+		g.print("return ")
+		for i, expr := range s.Value.(ast.ReturnStmt).Exprs {
+			if i != 0 {
+				g.print(", ")
+			}
+			g.Expr(expr)
+		}
+	case ast.StmtAssign:
+		a := s.Value.(ast.AssignStmt)
+		g.Expr(a.ExprL)
+		op := token.KeywordLiterals[token.ASSIGN]
+		if a.Operator.Kind != 0 {
+			op = a.Operator.Literal
+		}
+		g.print(" ", op, " ")
+		g.Expr(a.ExprR)
+	case ast.StmtBreak:
+		g.print("break")
+	case ast.StmtContinue:
+		g.print("continue")
+	case ast.StmtLoop:
+		l := s.Value.(ast.LoopStmt)
+		g.print("for ")
+		g.Expr(l.Cond)
+		g.print(" ")
+		g.Block(l.Stmt)
+	case ast.StmtForeach:
+		f := s.Value.(ast.ForeachStmt)
+		g.print("for ")
+		switch len(f.IdentList) {
+		case 1:
+			g.print("_, ", f.IdentList[0].Literal, " := ")
+		case 2:
+			g.print(f.IdentList[0].Literal, ", ", f.IdentList[1].Literal, " := ")
+		}
+		g.print("range ")
+		g.Expr(f.Expr)
+		g.print(" ")
+		g.Block(f.Stmt)
+	case ast.StmtEndlessFor:
+		g.print("for ")
+		g.Block(s.Value.(ast.EndlessForStmt).Stmt)
+	default:
+		g.unsupported(s)
+	}
+}
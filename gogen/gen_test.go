@@ -0,0 +1,274 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package gogen
+
+import (
+	"cee/ast"
+	"cee/debuginfo"
+	"cee/parser"
+	"cee/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// typeValueBlocks gives the block expressions bound by the top-level val declarations of functions the result
+// type of their function, as the checker would.
+func typeValueBlocks(decls []ast.Stmt) {
+	for _, decl := range decls {
+		fn, ok := decl.Value.(ast.FuncDecl)
+		if !ok || fn.Stmt == nil || len(fn.Type.Results) != 1 {
+			continue
+		}
+		for i, stmt := range fn.Stmt.Stmts {
+			val, ok := stmt.Value.(ast.ValDecl)
+			if !ok || val.Value.Tag != ast.ExprStmtBlock {
+				continue
+			}
+			block := val.Value.Value.(ast.StmtBlockExpr)
+			block.Type = fn.Type.Results[0]
+			val.Value.Value = block
+			fn.Stmt.Stmts[i].Value = val
+		}
+	}
+}
+
+// goCommand runs the go command on a module of the generated Go file.
+func goCommand(t *testing.T, src []byte, args ...string) ([]byte, error) {
+	t.Helper()
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module gen\n\ngo 1.21\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "gen.go"), src, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(gobin, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GOPROXY=off", "GOWORK=off")
+	return cmd.CombinedOutput()
+}
+
+// compile builds a generated Go package.
+func compile(t *testing.T, src []byte) {
+	t.Helper()
+	if out, err := goCommand(t, src, "vet", "."); err != nil {
+		t.Errorf("generated Go does not compile: %v\n%s\n%s", err, out, src)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{
+			name: "func",
+			src: `package a
+
+fun add(a i64, b i64) i64 {
+	return a + b * 2
+}
+`,
+			want: `package gen
+
+func add(a int64, b int64) int64 {
+	return (a + (b * 2))
+}
+`,
+		},
+		{
+			name: "block value",
+			src: `package a
+
+fun scaled(a i64) i64 {
+	val x = {
+		val y = a * 2
+		y + 1
+	}
+	return x
+}
+`,
+			want: `package gen
+
+func scaled(a int64) int64 {
+	x := func() int64 {
+		y := (a * 2)
+		return (y + 1)
+	}()
+	return x
+}
+`,
+		},
+		{
+			name: "loops",
+			src: `package a
+
+fun sum(xs []i64) i64 {
+	var total = xs[0]
+	for _, x in xs {
+		if x > 0 { total += x } else { continue }
+	}
+	return total
+}
+`,
+			want: `package gen
+
+func sum(xs []int64) int64 {
+	total := xs[0]
+	for _, x := range xs {
+		if x > 0 {
+			total += x
+		} else {
+			continue
+		}
+	}
+	return total
+}
+`,
+		},
+		{
+			name: "package values",
+			src: `package a
+
+val name = "a"
+var count i64 = 0
+
+fun next() string {
+	val n = count + 1
+	count = n
+	return name
+}
+`,
+			want: `package gen
+
+var name = "a"
+var count int64 = 0
+
+func next() string {
+	n := (count + 1)
+	count = n
+	return name
+}
+`,
+		},
+		{
+			name: "escapes",
+			src: `package a
+
+fun escapes() (string, rune) {
+	return "\e[0m\0", '\e'
+}
+`,
+			want: `package gen
+
+func escapes() (string, rune) {
+	return "\x1b[0m\x00", '\x1b'
+}
+`,
+		},
+		{
+			name: "pooled strings",
+			src: `package a
+
+fun greet(name string) string {
+	if name == "" { return "hello" }
+	return "hello, " + name + ` + "`hello`" + `
+}
+`,
+			want: `package gen
+
+func greet(name string) string {
+	if name == "" {
+		return _str0
+	}
+	return (("hello, " + name) + _str0)
+}
+
+const (
+	_str0 = "hello"
+)
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := parser.ParseFile(token.NewFileSet(), "a.cee", []byte(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			typeValueBlocks(f.Decls)
+
+			g := NewGenerator("gen")
+			out, err := g.Generate(f.Decls)
+			if err != nil || len(g.Diagnosis) != 0 {
+				t.Fatalf("Generate: %v %v\n%s", err, g.Diagnosis, out)
+			}
+			if string(out) != tt.want {
+				t.Errorf("Generate =\n%s\nwant\n%s", out, tt.want)
+			}
+			compile(t, out)
+		})
+	}
+}
+
+func TestGenerateDebug(t *testing.T) {
+	src := `package main
+
+fun main() {
+	check(1)
+}
+
+fun check(n i64) {
+	val m = n * 2
+	if m > 0 {
+		@intrinsic.trap()
+	}
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.cee", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := NewGenerator("main")
+	g.Debug = &debuginfo.Table{File: "a.cee"}
+	g.File = fset.File(f.From)
+	out, err := g.Generate(f.Decls)
+	if err != nil || len(g.Diagnosis) != 0 {
+		t.Fatalf("Generate: %v %v\n%s", err, g.Diagnosis, out)
+	}
+	for _, directive := range []string{"/*line a.cee:4:", "/*line a.cee:8:", "/*line a.cee:10:"} {
+		if !strings.Contains(string(out), directive) {
+			t.Errorf("no %s in\n%s", directive, out)
+		}
+	}
+
+	if len(g.Debug.Vars) != 1 {
+		t.Fatalf("variables %+v", g.Debug.Vars)
+	}
+	if v := g.Debug.Vars[0]; v.Name != "m" || v.Location != "m" || v.Scope.From.Line != 6 {
+		t.Errorf("variable %+v", v)
+	}
+	if vars := g.Debug.VarsAt(strings.Index(src, "@")); len(vars) != 1 || vars[0].Name != "m" {
+		t.Errorf("variables at the trap %+v", vars)
+	}
+	if vars := g.Debug.VarsAt(strings.Index(src, "check(1)")); len(vars) != 0 {
+		t.Errorf("variables in main %+v", vars)
+	}
+
+	// The panic reports the position of the trap in the cee source.
+	trace, err := goCommand(t, out, "run", ".")
+	if err == nil || !strings.Contains(string(trace), "a.cee:10") {
+		t.Errorf("go run: %v\n%s", err, trace)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package grammar
+
+import (
+	"bufio"
+	"cee/token"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	Name      = "cee"
+	ScopeName = "source.cee"
+)
+
+// Regular expressions shared by both grammars, kept in sync with the scanner by hand.
+const (
+	identPattern  = `[A-Za-z_][A-Za-z0-9_]*`
+	numberPattern = `(0[xX][0-9a-fA-F_]+|0[bB][01_]+|0[oO][0-7_]+|[0-9][0-9_]*(\.[0-9_]+)?([eE][+-]?[0-9_]+)?)i?`
+)
+
+func isWord(lit string) bool {
+	for _, r := range lit {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return lit != ""
+}
+
+// Keywords lists the reserved words, operators spelled with letters (e.g. `as`) included.
+func Keywords() []string {
+	var words []string
+	for kind := token.KEYWORD_BEGIN; kind < token.KEYWORD_END; kind++ {
+		if lit := token.KeywordLiterals[kind]; isWord(lit) {
+			words = append(words, lit)
+		}
+	}
+	var aliases []string
+	for alias := range token.KeywordAliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	return append(words, aliases...)
+}
+
+// Operators lists the symbolic operators, longest first so alternations match greedily.
+func Operators() []string {
+	var ops []string
+	for kind := token.OPERATOR_BEGIN + 1; kind < token.OPERATOR_END; kind++ {
+		if lit := token.KeywordLiterals[kind]; lit != "" && !isWord(lit) {
+			ops = append(ops, lit)
+		}
+	}
+	sort.SliceStable(ops, func(i, j int) bool { return len(ops[i]) > len(ops[j]) })
+	return ops
+}
+
+// Delimiters lists the punctuation, newline excluded.
+func Delimiters() []string {
+	var delims []string
+	for kind := token.DELIMITER_BEGIN + 1; kind < token.DELIMITER_END; kind++ {
+		if lit := token.KeywordLiterals[kind]; lit != "" && lit != "\n" {
+			delims = append(delims, lit)
+		}
+	}
+	return delims
+}
+
+func alternation(lits []string) string {
+	quoted := make([]string, len(lits))
+	for i, lit := range lits {
+		quoted[i] = regexp.QuoteMeta(lit)
+	}
+	return strings.Join(quoted, "|")
+}
+
+type pattern struct {
+	Name     string    `json:"name,omitempty"`
+	Match    string    `json:"match,omitempty"`
+	Begin    string    `json:"begin,omitempty"`
+	End      string    `json:"end,omitempty"`
+	Patterns []pattern `json:"patterns,omitempty"`
+}
+
+// TextMate writes a TextMate grammar in JSON.
+func TextMate(w io.Writer) error {
+	escape := pattern{Name: "constant.character.escape.cee", Match: `\\.`}
+	grammar := struct {
+		Name      string    `json:"name"`
+		ScopeName string    `json:"scopeName"`
+		FileTypes []string  `json:"fileTypes"`
+		Patterns  []pattern `json:"patterns"`
+	}{
+		Name:      Name,
+		ScopeName: ScopeName,
+		FileTypes: []string{Name},
+		Patterns: []pattern{
+			{Name: "comment.line.double-slash.cee", Match: `//.*$`},
+			{Name: "comment.block.cee", Begin: `/\*`, End: `\*/`},
+			{Name: "string.quoted.double.cee", Begin: `"`, End: `"`, Patterns: []pattern{escape}},
+			{Name: "string.quoted.single.cee", Begin: `'`, End: `'`, Patterns: []pattern{escape}},
+			{Name: "constant.numeric.cee", Match: `\b` + numberPattern + `\b`},
+			{Name: "keyword.control.cee", Match: `\b(` + alternation(Keywords()) + `)\b`},
+			{Name: "keyword.operator.cee", Match: alternation(Operators())},
+			{Name: "punctuation.cee", Match: alternation(Delimiters())},
+			{Name: "variable.other.cee", Match: `\b` + identPattern + `\b`},
+		},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(grammar)
+}
+
+func jsStrings(lits []string) string {
+	quoted := make([]string, len(lits))
+	for i, lit := range lits {
+		b, _ := json.Marshal(lit)
+		quoted[i] = string(b)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// TreeSitter writes a tree-sitter grammar.js skeleton recognizing the lexical structure only.
+// Grammar rules are to be filled in by hand, the token rules are regenerated from the token tables.
+func TreeSitter(w io.Writer) error {
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintln(b, "// Code generated by cee grammar. DO NOT EDIT the token rules.")
+	fmt.Fprintln(b, "module.exports = grammar({")
+	fmt.Fprintf(b, "  name: %q,\n", Name)
+	fmt.Fprintln(b, "  extras: $ => [/\\s/, $.comment],")
+	fmt.Fprintln(b, "  word: $ => $.identifier,")
+	fmt.Fprintln(b, "  rules: {")
+	fmt.Fprintln(b, "    source_file: $ => repeat($._token),")
+	fmt.Fprintln(b, "    _token: $ => choice($.keyword, $.operator, $.delimiter, $.number, $.string, $.char, $.identifier),")
+	fmt.Fprintf(b, "    keyword: $ => choice(%s),\n", jsStrings(Keywords()))
+	fmt.Fprintf(b, "    operator: $ => choice(%s),\n", jsStrings(Operators()))
+	fmt.Fprintf(b, "    delimiter: $ => choice(%s),\n", jsStrings(Delimiters()))
+	fmt.Fprintf(b, "    number: $ => /%s/,\n", numberPattern)
+	fmt.Fprintln(b, `    string: $ => /"([^"\\]|\\.)*"/,`)
+	fmt.Fprintln(b, `    char: $ => /'([^'\\]|\\.)*'/,`)
+	fmt.Fprintln(b, `    comment: $ => token(choice(seq("//", /.*/), seq("/*", /[^*]*\*+([^/*][^*]*\*+)*/, "/"))),`)
+	fmt.Fprintf(b, "    identifier: $ => /%s/,\n", identPattern)
+	fmt.Fprintln(b, "  },")
+	fmt.Fprintln(b, "});")
+
+	return b.Flush()
+}
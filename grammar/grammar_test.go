@@ -0,0 +1,54 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package grammar
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTextMate(t *testing.T) {
+	var b bytes.Buffer
+	if err := TextMate(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	var grammar struct {
+		Patterns []struct {
+			Name, Match string
+		}
+	}
+	if err := json.Unmarshal(b.Bytes(), &grammar); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range grammar.Patterns {
+		if p.Match == "" {
+			continue
+		}
+		re, err := regexp.Compile(p.Match)
+		if err != nil {
+			t.Fatal(p.Name, err)
+		}
+		if p.Name == "keyword.control.cee" && !re.MatchString("fun") {
+			t.Error("fun is not a keyword")
+		}
+		if p.Name == "keyword.operator.cee" && re.FindString("<<=") != "<<=" {
+			t.Error("operators do not match greedily")
+		}
+	}
+}
+
+func TestTreeSitter(t *testing.T) {
+	var b bytes.Buffer
+	if err := TreeSitter(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `"extern"`) {
+		t.Error("keyword missing from tree-sitter grammar")
+	}
+}
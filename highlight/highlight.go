@@ -0,0 +1,94 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package highlight
+
+import (
+	"cee/ast"
+	"cee/parser"
+	"cee/token"
+)
+
+type Kind int
+
+const (
+	Invalid Kind = iota
+	Keyword
+	Operator
+	Delimiter
+	Ident
+	Type
+	Number
+	String
+	Char
+	Comment
+)
+
+var kindNames = [...]string{
+	Invalid:   "invalid",
+	Keyword:   "keyword",
+	Operator:  "operator",
+	Delimiter: "delimiter",
+	Ident:     "ident",
+	Type:      "type",
+	Number:    "number",
+	String:    "string",
+	Char:      "char",
+	Comment:   "comment",
+}
+
+func (k Kind) String() string { return kindNames[k] }
+
+// Range is decoded, highlighting consumers have no use for the file set.
+type Range struct {
+	From, To token.Position
+}
+
+type Span struct {
+	Kind  Kind
+	Range Range
+}
+
+func classify(tok ast.Token) Kind {
+	switch {
+	case tok.Kind == token.COMMENT:
+		return Comment
+	case tok.Kind == token.IDENT:
+		if _, ok := parser.BuiltinTypes[tok.Literal]; ok {
+			return Type
+		}
+		return Ident
+	case tok.Kind == token.INT || tok.Kind == token.FLOAT || tok.Kind == token.IMAG:
+		return Number
+	case token.IsString(tok.Kind) || token.IsStringSegment(tok.Kind):
+		return String
+	case tok.Kind == token.CHAR:
+		return Char
+	case token.IsOperator(tok.Kind):
+		return Operator
+	case token.DELIMITER_BEGIN < tok.Kind && tok.Kind < token.DELIMITER_END:
+		return Delimiter
+	case token.IsKeyword(tok.Kind):
+		return Keyword
+	}
+	return Invalid
+}
+
+// Highlight classifies the tokens of src in source order using the scanner only.
+// Input the scanner rejects is reported as Invalid spans, highlighting resumes after each of them.
+func Highlight(src []rune) []Span {
+	file := token.NewFileSet().AddFile("", -1, len(src))
+	toks, _ := parser.ScanRecover(file, src)
+
+	spans := make([]Span, 0, len(toks))
+	add := func(kind Kind, pos ast.PosRange) {
+		spans = append(spans, Span{Kind: kind, Range: Range{From: file.Position(pos.From), To: file.Position(pos.To)}})
+	}
+
+	for _, tok := range toks {
+		add(classify(tok), tok.PosRange)
+	}
+
+	return spans
+}
@@ -0,0 +1,57 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package highlight
+
+import (
+	"strings"
+	"testing"
+)
+
+// spansOf renders the spans of src as kind:text, one per span.
+func spansOf(src string) string {
+	runes := []rune(src)
+	var b strings.Builder
+	for _, span := range Highlight(runes) {
+		b.WriteString(span.Kind.String() + ":" + string(runes[span.Range.From.Offset:span.Range.To.Offset]) + "\n")
+	}
+	return b.String()
+}
+
+func TestHighlight(t *testing.T) {
+	src := "fun f(n i64) { // sum\n\tval s = \"${n}\" + 'c' * 1.5\n} ` x"
+	want := `keyword:fun
+ident:f
+delimiter:(
+ident:n
+type:i64
+delimiter:)
+delimiter:{
+comment:// sum
+keyword:val
+ident:s
+operator:=
+string:"${
+ident:n
+string:}"
+operator:+
+char:'c'
+operator:*
+number:1.5
+delimiter:}
+invalid:` + "` x\n"
+	if got := spansOf(src); got != want {
+		t.Errorf("Highlight =\n%s\nwant\n%s", got, want)
+	}
+
+	// Highlighting resumes after input the scanner rejects.
+	if got := spansOf("/* a */ x /* b"); got != "comment:/* a */\nident:x\ninvalid:/* b\n" {
+		t.Errorf("Highlight =\n%s", got)
+	}
+
+	spans := Highlight([]rune("a\n  bc"))
+	if r := spans[1].Range; r.From.Line != 1 || r.From.Column != 2 || r.To.Column != 4 {
+		t.Errorf("range %+v", r)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package highlight
+
+import (
+	"html"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Marker annotates a range of the source in HTML renderings, e.g. with a diagnostic.
+// Empty ranges mark the rune they start at.
+type Marker struct {
+	Range   Range
+	Message string // shown as a tooltip
+}
+
+// Stylesheet styles the elements written by HTML, pages embedding the rendering may use their own instead.
+const Stylesheet = `pre.cee { font-family: monospace; tab-size: 4; }
+.cee-keyword { color: #8959a8; font-weight: bold; }
+.cee-operator, .cee-delimiter { color: #3e999f; }
+.cee-type { color: #4271ae; }
+.cee-number, .cee-char { color: #f5871f; }
+.cee-string { color: #718c00; }
+.cee-comment { color: #8e908c; font-style: italic; }
+.cee-invalid { color: #c82829; }
+mark.cee-marker { background: none; text-decoration: underline wavy #c82829; cursor: help; }
+`
+
+// HTML writes src highlighted as a <pre class="cee"> element, tokens are <span> elements classed cee-<kind>
+// and markers <mark class="cee-marker"> elements titled with their messages.
+func HTML(w io.Writer, src []rune, markers []Marker) error {
+	return render(w, src, Highlight(src), markers)
+}
+
+type segment struct {
+	from, to int
+	kind     Kind // -1 between tokens
+	title    string
+}
+
+func render(w io.Writer, src []rune, spans []Span, markers []Marker) error {
+	kinds := make([]Kind, len(src))
+	for i := range kinds {
+		kinds[i] = -1
+	}
+	clip := func(offset int) int { return min(max(offset, 0), len(src)) }
+
+	bounds := []int{0, len(src)}
+	for _, span := range spans {
+		from, to := clip(span.Range.From.Offset), clip(span.Range.To.Offset)
+		for i := from; i < to; i++ {
+			kinds[i] = span.Kind
+		}
+		bounds = append(bounds, from, to)
+	}
+	type interval struct {
+		from, to int
+		message  string
+	}
+	var marks []interval
+	for _, m := range markers {
+		from, to := clip(m.Range.From.Offset), clip(m.Range.To.Offset)
+		if to <= from {
+			to = clip(from + 1)
+		}
+		marks = append(marks, interval{from, to, m.Message})
+		bounds = append(bounds, from, to)
+	}
+	sort.Ints(bounds)
+
+	var segments []segment
+	for i := 0; i+1 < len(bounds); i++ {
+		from, to := bounds[i], bounds[i+1]
+		if from == to {
+			continue
+		}
+		var messages []string
+		for _, m := range marks {
+			if m.from <= from && from < m.to {
+				messages = append(messages, m.message)
+			}
+		}
+		seg := segment{from: from, to: to, kind: kinds[from], title: strings.Join(messages, "\n")}
+		if n := len(segments); n != 0 && segments[n-1].kind == seg.kind && segments[n-1].title == seg.title {
+			segments[n-1].to = to
+			continue
+		}
+		segments = append(segments, seg)
+	}
+
+	var b strings.Builder
+	b.WriteString(`<pre class="cee">`)
+	for _, seg := range segments {
+		marked := seg.title != ""
+		if marked {
+			b.WriteString(`<mark class="cee-marker" title="` + html.EscapeString(seg.title) + `">`)
+		}
+		text := html.EscapeString(string(src[seg.from:seg.to]))
+		if seg.kind >= 0 {
+			b.WriteString(`<span class="cee-` + seg.kind.String() + `">` + text + `</span>`)
+		} else {
+			b.WriteString(text)
+		}
+		if marked {
+			b.WriteString(`</mark>`)
+		}
+	}
+	b.WriteString("</pre>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,45 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package highlight
+
+import (
+	"cee/token"
+	"strings"
+	"testing"
+)
+
+func offsets(from, to int) Range {
+	return Range{From: token.Position{Offset: from}, To: token.Position{Offset: to}}
+}
+
+func TestRender(t *testing.T) {
+	src := []rune("val s = \"<a>\"")
+	spans := []Span{
+		{Kind: Keyword, Range: offsets(0, 3)},
+		{Kind: Ident, Range: offsets(4, 5)},
+		{Kind: Operator, Range: offsets(6, 7)},
+		{Kind: String, Range: offsets(8, 13)},
+	}
+	markers := []Marker{
+		{Range: offsets(4, 10), Message: `unused "s"`},
+		{Range: offsets(13, 13), Message: "expected newline"},
+	}
+
+	var b strings.Builder
+	if err := render(&b, src, spans, markers); err != nil {
+		t.Fatal(err)
+	}
+	want := `<pre class="cee"><span class="cee-keyword">val</span> ` +
+		`<mark class="cee-marker" title="unused &#34;s&#34;"><span class="cee-ident">s</span></mark>` +
+		`<mark class="cee-marker" title="unused &#34;s&#34;"> </mark>` +
+		`<mark class="cee-marker" title="unused &#34;s&#34;"><span class="cee-operator">=</span></mark>` +
+		`<mark class="cee-marker" title="unused &#34;s&#34;"> </mark>` +
+		`<mark class="cee-marker" title="unused &#34;s&#34;"><span class="cee-string">&#34;&lt;</span></mark>` +
+		`<span class="cee-string">a&gt;&#34;</span>` +
+		"</pre>\n"
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package hir
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// BasicBlock is a straight-line run of statements. A block ending with a branch has Cond set and two successors,
+// taken when Cond holds and when it does not, the others have at most one successor.
+type BasicBlock struct {
+	Index int
+	Stmts []Stmt
+	Cond  *Expr
+	Succs []*BasicBlock
+	Preds []*BasicBlock
+}
+
+// CFG is the control-flow graph of a function body, returns and the end of the body lead to Exit,
+// which has no statements. Blocks are ordered as they appear in the body, Exit last.
+// Statement-level if expressions branch, if expressions used as values stay within their statement.
+type CFG struct {
+	Entry, Exit *BasicBlock
+	Blocks      []*BasicBlock
+}
+
+type loopTargets struct {
+	head, after *BasicBlock
+}
+
+type cfgBuilder struct {
+	g     *CFG
+	cur   *BasicBlock // nil after a jump, until an unreachable statement starts a block
+	loops []loopTargets
+}
+
+// BuildCFG builds the control-flow graph of a lowered function body.
+func BuildCFG(body Block) *CFG {
+	b := cfgBuilder{g: &CFG{Exit: &BasicBlock{}}}
+	b.g.Entry = b.block()
+	b.cur = b.g.Entry
+	b.stmts(body.Stmts)
+	b.jump(b.g.Exit)
+	b.g.Blocks = append(b.g.Blocks, b.g.Exit)
+	b.finish()
+	return b.g
+}
+
+func (b *cfgBuilder) block() *BasicBlock {
+	block := &BasicBlock{}
+	b.g.Blocks = append(b.g.Blocks, block)
+	return block
+}
+
+func (b *cfgBuilder) jump(to *BasicBlock) {
+	if b.cur != nil {
+		b.cur.Succs = append(b.cur.Succs, to)
+		b.cur = nil
+	}
+}
+
+func (b *cfgBuilder) add(s Stmt) {
+	if b.cur == nil {
+		b.cur = b.block()
+	}
+	b.cur.Stmts = append(b.cur.Stmts, s)
+}
+
+func (b *cfgBuilder) stmts(stmts []Stmt) {
+	for _, s := range stmts {
+		b.stmt(s)
+	}
+}
+
+func (b *cfgBuilder) stmt(s Stmt) {
+	switch v := s.Value.(type) {
+	case Expr:
+		switch e := v.Value.(type) {
+		case IfExpr:
+			b.branch(e)
+			return
+		case Block:
+			b.stmts(e.Stmts)
+			return
+		}
+	case LoopStmt:
+		// The block after the loop follows its body.
+		head, after := b.block(), &BasicBlock{}
+		b.jump(head)
+		b.cur = head
+		b.loops = append(b.loops, loopTargets{head: head, after: after})
+		b.stmts(v.Body.Stmts)
+		b.loops = b.loops[:len(b.loops)-1]
+		b.jump(head)
+		b.g.Blocks = append(b.g.Blocks, after)
+		b.cur = after
+		return
+	case BreakStmt:
+		if n := len(b.loops); n != 0 {
+			b.add(s)
+			b.jump(b.loops[n-1].after)
+			return
+		}
+	case ContinueStmt:
+		if n := len(b.loops); n != 0 {
+			b.add(s)
+			b.jump(b.loops[n-1].head)
+			return
+		}
+	case ReturnStmt:
+		b.add(s)
+		b.jump(b.g.Exit)
+		return
+	}
+	b.add(s)
+}
+
+func (b *cfgBuilder) branch(e IfExpr) {
+	if b.cur == nil {
+		b.cur = b.block()
+	}
+	cond := b.cur
+	cond.Cond = &e.Cond
+
+	then := b.block()
+	cond.Succs = append(cond.Succs, then)
+	b.cur = then
+	b.stmts(e.Then.Stmts)
+	thenEnd := b.cur
+
+	var elseEnd *BasicBlock
+	if e.Else != nil {
+		els := b.block()
+		cond.Succs = append(cond.Succs, els)
+		b.cur = els
+		b.stmts(e.Else.Stmts)
+		elseEnd = b.cur
+	}
+
+	after := b.block()
+	if e.Else == nil {
+		cond.Succs = append(cond.Succs, after)
+	}
+	b.cur = thenEnd
+	b.jump(after)
+	b.cur = elseEnd
+	b.jump(after)
+	b.cur = after
+}
+
+// finish drops the empty blocks no statement leads to, then numbers the blocks and links their predecessors.
+// Unreachable statements are kept, in blocks without predecessors.
+func (b *cfgBuilder) finish() {
+	reached := map[*BasicBlock]bool{}
+	var visit func(block *BasicBlock)
+	visit = func(block *BasicBlock) {
+		if reached[block] {
+			return
+		}
+		reached[block] = true
+		for _, succ := range block.Succs {
+			visit(succ)
+		}
+	}
+	visit(b.g.Entry)
+	for _, block := range b.g.Blocks {
+		if len(block.Stmts) != 0 {
+			visit(block)
+		}
+	}
+
+	blocks := b.g.Blocks[:0]
+	for _, block := range b.g.Blocks {
+		if reached[block] || block == b.g.Exit {
+			blocks = append(blocks, block)
+		}
+	}
+	b.g.Blocks = blocks
+
+	for i, block := range b.g.Blocks {
+		block.Index = i
+		for _, succ := range block.Succs {
+			succ.Preds = append(succ.Preds, block)
+		}
+	}
+}
+
+// ToDot writes the graph as a Graphviz digraph named name, a node per block listing its statements.
+func (g *CFG) ToDot(w io.Writer, name string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n\tnode [shape=box, fontname=monospace];\n", dotQuote(name))
+	for _, block := range g.Blocks {
+		lines := []string{fmt.Sprint("b", block.Index)}
+		switch block {
+		case g.Entry:
+			lines[0] += " (entry)"
+		case g.Exit:
+			lines[0] += " (exit)"
+		}
+		for _, s := range block.Stmts {
+			lines = append(lines, s.String())
+		}
+		if block.Cond != nil {
+			lines = append(lines, "if "+block.Cond.String())
+		}
+		// Left-justified lines.
+		fmt.Fprintf(&b, "\tb%d [label=%s];\n", block.Index, dotQuote(strings.Join(lines, "\n")+"\n"))
+	}
+	for _, block := range g.Blocks {
+		for i, succ := range block.Succs {
+			attrs := ""
+			if block.Cond != nil {
+				attrs = fmt.Sprintf(" [label=%q]", [...]string{"true", "false"}[i])
+			}
+			fmt.Fprintf(&b, "\tb%d -> b%d%s;\n", block.Index, succ.Index, attrs)
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotQuote quotes a label, lines are left-justified.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\l`).Replace(s) + `"`
+}
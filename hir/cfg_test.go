@@ -0,0 +1,57 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package hir
+
+import (
+	"cee/ast"
+	"cee/token"
+	"strings"
+	"testing"
+)
+
+func TestBuildCFG(t *testing.T) {
+	// val x = 0
+	// loop { if !(x < 10) { break }; x = x + 1 }
+	// return x
+	var pos ast.PosRange
+	x := ident(pos, "x", builtin(ast.TypeI64))
+	zero := intLiteral(pos, "0")
+	body := Block{Stmts: []Stmt{
+		NewStmt(StmtLet, LetStmt{Name: "x", Value: &zero}),
+		NewStmt(StmtLoop, LoopStmt{Body: Block{Stmts: []Stmt{
+			breakUnless(pos, binary(pos, token.LSS, x, intLiteral(pos, "10"))),
+			NewStmt(StmtAssign, AssignStmt{Target: x, Value: binary(pos, token.ADD, x, intLiteral(pos, "1"))}),
+		}}}),
+		NewStmt(StmtReturn, ReturnStmt{Exprs: []Expr{x}}),
+	}}
+
+	g := BuildCFG(body)
+	var b strings.Builder
+	if err := g.ToDot(&b, "f"); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph "f" {
+	node [shape=box, fontname=monospace];
+	b0 [label="b0 (entry)\lval x = 0\l"];
+	b1 [label="b1\lif !(x < 10)\l"];
+	b2 [label="b2\lbreak\l"];
+	b3 [label="b3\lx = (x + 1)\l"];
+	b4 [label="b4\lreturn x\l"];
+	b5 [label="b5 (exit)\l"];
+	b0 -> b1;
+	b1 -> b2 [label="true"];
+	b1 -> b3 [label="false"];
+	b2 -> b4;
+	b3 -> b1;
+	b4 -> b5;
+}
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if len(g.Blocks[1].Preds) != 2 || len(g.Exit.Preds) != 1 {
+		t.Errorf("predecessors of the loop head %d, of the exit %d", len(g.Blocks[1].Preds), len(g.Exit.Preds))
+	}
+}
@@ -0,0 +1,8 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+// Package hir
+// Typed high-level IR sitting between the AST and SSA.
+// Syntax sugar (foreach, compound assignment, else-if, ...) is lowered into a small core language.
+package hir
@@ -0,0 +1,679 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package hir
+
+import (
+	"cee"
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/intrinsic"
+	"cee/literals"
+	"cee/token"
+	"strconv"
+)
+
+// Lowerer translates AST statements into HIR.
+// Nodes the core language has no form for yet pass through as SyntaxExpr and SyntaxStmt.
+type Lowerer struct {
+	Scopes []map[string]ast.Type
+
+	Diagnosis []diagnosis.Diagnosis
+
+	tmp int
+	fn  *ast.FuncType // enclosing function, nil outside of LowerFunc
+}
+
+func NewLowerer() Lowerer {
+	return Lowerer{Scopes: []map[string]ast.Type{{}}}
+}
+
+func (l *Lowerer) Report(d diagnosis.Diagnosis) {
+	l.Diagnosis = append(l.Diagnosis, d)
+}
+
+func (l *Lowerer) unsupported(node ast.Node) {
+	l.Report(diagnosis.Diagnosis{
+		Kind:  diagnosis.UnsupportedNode,
+		Error: diagnosis.UnsupportedNodeError{Node: node},
+	})
+}
+
+func (l *Lowerer) enterScope() { l.Scopes = append(l.Scopes, map[string]ast.Type{}) }
+
+func (l *Lowerer) exitScope() { l.Scopes = l.Scopes[:len(l.Scopes)-1] }
+
+func (l *Lowerer) declare(name string, typ ast.Type) { l.Scopes[len(l.Scopes)-1][name] = typ }
+
+func (l *Lowerer) lookup(name string) ast.Type {
+	for i := len(l.Scopes) - 1; i >= 0; i-- {
+		if typ, ok := l.Scopes[i][name]; ok {
+			return typ
+		}
+	}
+	return ast.Type{}
+}
+
+// temp returns a fresh name that cannot collide with user identifiers.
+func (l *Lowerer) temp(hint string) string {
+	l.tmp++
+	return "$" + hint + strconv.Itoa(l.tmp)
+}
+
+func builtin(kind ast.TypeKind) ast.Type {
+	return ast.Type{Union: cee.Union[ast.TypeKind]{Tag: kind}}
+}
+
+// unaryType is the type of the unary expression u on an operand of type typ: the address-of `&v` references
+// the operand, the dereference `*p` yields the element of a pointer, other operators keep the type.
+func unaryType(u ast.UnaryExpr, typ ast.Type) ast.Type {
+	switch u.Operator.Kind {
+	case token.AND:
+		if typ.Tag == 0 {
+			return ast.Type{}
+		}
+		return ast.Type{Union: cee.Union[ast.TypeKind]{Tag: ast.TypePointer, Value: ast.PointerType{PosRange: u.PosRange, Elem: typ}}}
+	case token.MUL:
+		if ptr, ok := typ.Value.(ast.PointerType); ok {
+			return ptr.Elem
+		}
+		return ast.Type{}
+	}
+	return typ
+}
+
+func ident(pos ast.PosRange, name string, typ ast.Type) Expr {
+	return NewExpr(ExprIdent, Ident{PosRange: pos, Name: name}, typ)
+}
+
+func intLiteral(pos ast.PosRange, lit string) Expr {
+	return NewExpr(ExprLiteral, Literal{PosRange: pos, Kind: token.INT, Literal: lit}, builtin(ast.TypeI64))
+}
+
+// stringLiteral returns the value of a string literal expression.
+func stringLiteral(e Expr) (string, bool) {
+	lit, ok := e.Value.(Literal)
+	if !ok || !token.IsString(lit.Kind) {
+		return "", false
+	}
+	s, err := token.Unquote(lit.Literal)
+	return s, err == nil
+}
+
+// concat folds `"a" + "b"` into `"ab"`, and `x + "a" + "b"` into `x + "ab"` as concatenation is associative.
+func concat(pos ast.PosRange, lhs, rhs Expr) (Expr, bool) {
+	r, ok := stringLiteral(rhs)
+	if !ok {
+		return Expr{}, false
+	}
+	if l, ok := stringLiteral(lhs); ok {
+		return NewExpr(ExprLiteral, Literal{PosRange: pos, Kind: token.STRING, Literal: token.Quote(l + r)}, lhs.Type), true
+	}
+	if b, ok := lhs.Value.(BinaryExpr); ok && b.Operator == token.ADD {
+		if lit, ok := b.Exprs[1].Value.(Literal); ok {
+			if folded, ok := concat(ast.PosRange{From: lit.From, To: pos.To}, b.Exprs[1], rhs); ok {
+				return binary(pos, token.ADD, b.Exprs[0], folded), true
+			}
+		}
+	}
+	return Expr{}, false
+}
+
+func binary(pos ast.PosRange, op int, lhs, rhs Expr) Expr {
+	return NewExpr(ExprBinary, BinaryExpr{PosRange: pos, Operator: op, Exprs: [2]Expr{lhs, rhs}}, lhs.Type)
+}
+
+func breakUnless(pos ast.PosRange, cond Expr) Stmt {
+	not := NewExpr(ExprUnary, UnaryExpr{PosRange: pos, Operator: token.NOT, Expr: cond}, cond.Type)
+	brk := Block{PosRange: pos, Stmts: []Stmt{NewStmt(StmtBreak, BreakStmt{PosRange: pos})}}
+	return NewStmt(StmtExpr, NewExpr(ExprIf, IfExpr{PosRange: pos, Cond: not, Then: brk}, ast.Type{}))
+}
+
+func (l *Lowerer) LowerExpr(e ast.Expr) Expr {
+	switch e.Tag {
+	case ast.ExprIdent:
+		id := e.Value.(ast.Ident)
+		return ident(id.PosRange, id.Literal, l.lookup(id.Literal))
+	case ast.ExprLiteralValue:
+		lit := e.Value.(ast.LiteralValue)
+		return l.literal(lit, ast.Type{}, false, lit.PosRange)
+	case ast.ExprUnary:
+		if v, ok := l.lowerValue(e, ast.Type{}); ok {
+			return v
+		}
+		u := e.Value.(ast.UnaryExpr)
+		operand := l.LowerExpr(u.Expr)
+		return NewExpr(ExprUnary, UnaryExpr{PosRange: u.PosRange, Operator: u.Operator.Kind, Expr: operand}, unaryType(u, operand.Type))
+	case ast.ExprBinary:
+		b := e.Value.(ast.BinaryExpr)
+		lhs, rhs := l.LowerExpr(b.Exprs[0]), l.LowerExpr(b.Exprs[1])
+		if b.Operator.Kind == token.ADD {
+			if folded, ok := concat(b.PosRange, lhs, rhs); ok {
+				return folded
+			}
+		}
+		return binary(b.PosRange, b.Operator.Kind, lhs, rhs)
+	case ast.ExprCall:
+		c := e.Value.(ast.CallExpr)
+		params := make([]Expr, len(c.Params))
+		for i, param := range c.Params {
+			params[i] = l.LowerExpr(param)
+		}
+		return NewExpr(ExprCall, CallExpr{PosRange: c.PosRange, Callee: l.LowerExpr(c.Callee), Params: params}, ast.Type{})
+	case ast.ExprIndex:
+		ie := e.Value.(ast.IndexExpr)
+		return NewExpr(ExprIndex, IndexExpr{PosRange: ie.PosRange, Expr: l.LowerExpr(ie.Expr), Index: l.LowerExpr(ie.Index)}, ast.Type{})
+	case ast.ExprMemberSelect:
+		m := e.Value.(ast.MemberSelectExpr)
+		return NewExpr(ExprMember, MemberExpr{PosRange: m.PosRange, Expr: l.LowerExpr(m.Expr), Member: m.Member.Literal}, ast.Type{})
+	case ast.ExprQualifiedIdent:
+		q := e.Value.(ast.QualifiedIdent)
+		pkg := ident(q.Package.PosRange, q.Package.Literal, ast.Type{})
+		return NewExpr(ExprMember, MemberExpr{PosRange: q.PosRange, Expr: pkg, Member: q.Member.Literal}, ast.Type{})
+	case ast.ExprStmtBlock:
+		b := e.Value.(ast.StmtBlockExpr)
+		return NewExpr(ExprBlock, l.LowerBlock(b), b.Type)
+	case ast.ExprBranch:
+		return l.lowerBranch(e.Value.(ast.BranchExpr))
+	case ast.ExprTry:
+		return l.lowerTry(e.Value.(ast.TryExpr))
+	case ast.ExprIntrinsic:
+		return l.lowerIntrinsic(e.Value.(ast.IntrinsicExpr))
+	case ast.ExprInterpolatedString:
+		return l.lowerInterpolation(e.Value.(ast.InterpolatedString))
+	case ast.ExprBad:
+		// The parser reported the broken expression.
+		return NewExpr(ExprBlock, Block{PosRange: e.GetPosRange()}, ast.Type{})
+	default:
+		return NewExpr(ExprSyntax, SyntaxExpr{PosRange: e.GetPosRange(), Expr: e}, ast.Type{})
+	}
+}
+
+// literal lowers a literal, or its negation at pos, stored to a destination of type typ, reporting what does
+// not fit. Without a destination, integers are i64 and only checked to be well-formed.
+func (l *Lowerer) literal(lit ast.LiteralValue, typ ast.Type, neg bool, pos ast.PosRange) Expr {
+	kind := typ.Tag
+	if lit.Kind == token.INT && kind == 0 {
+		typ = builtin(ast.TypeI64)
+	}
+	check := literals.Check
+	if neg {
+		check = literals.CheckNegated
+	}
+	_, diags := check(lit, kind)
+	for _, d := range diags {
+		l.Report(d)
+	}
+
+	e := NewExpr(ExprLiteral, Literal{PosRange: lit.PosRange, Kind: lit.Kind, Literal: lit.Literal}, typ)
+	if neg {
+		e = NewExpr(ExprUnary, UnaryExpr{PosRange: pos, Operator: token.SUB, Expr: e}, typ)
+	}
+	return e
+}
+
+// lowerValue lowers literals and negated literals stored to a destination of type typ, it reports false for
+// other expressions.
+func (l *Lowerer) lowerValue(e ast.Expr, typ ast.Type) (Expr, bool) {
+	switch e.Tag {
+	case ast.ExprLiteralValue:
+		lit := e.Value.(ast.LiteralValue)
+		return l.literal(lit, typ, false, lit.PosRange), true
+	case ast.ExprUnary:
+		u := e.Value.(ast.UnaryExpr)
+		if u.Operator.Kind == token.SUB && u.Expr.Tag == ast.ExprLiteralValue {
+			return l.literal(u.Expr.Value.(ast.LiteralValue), typ, true, u.PosRange), true
+		}
+	}
+	return Expr{}, false
+}
+
+// lowerStored is LowerExpr for a value stored to a destination of type typ.
+func (l *Lowerer) lowerStored(e ast.Expr, typ ast.Type) Expr {
+	if v, ok := l.lowerValue(e, typ); ok {
+		return v
+	}
+	return l.LowerExpr(e)
+}
+
+// lowerDestructure binds the value to a temporary, then each name of the pattern to the element or field
+// of the temporary it matches. Tuple elements are selected by index.
+func (l *Lowerer) lowerDestructure(d ast.ValDecl, value Expr) []Stmt {
+	name := l.temp("val")
+	stmts := []Stmt{NewStmt(StmtLet, LetStmt{PosRange: d.PosRange, Name: name, Type: value.Type, Value: &value})}
+	l.bind(*d.Pattern, ident(d.Pattern.GetPosRange(), name, value.Type), d.Mutable, &stmts)
+	return stmts
+}
+
+func (l *Lowerer) bind(p ast.Pattern, value Expr, mutable bool, stmts *[]Stmt) {
+	switch v := p.Value.(type) {
+	case ast.Ident:
+		if v.Literal == "_" {
+			return
+		}
+		l.declare(v.Literal, value.Type)
+		*stmts = append(*stmts, NewStmt(StmtLet, LetStmt{PosRange: v.PosRange, Name: v.Literal, Type: value.Type, Value: &value, Mutable: mutable}))
+	case ast.TuplePattern:
+		for i, elem := range v.Elems {
+			pos := elem.GetPosRange()
+			element := NewExpr(ExprIndex, IndexExpr{PosRange: pos, Expr: value, Index: intLiteral(pos, strconv.Itoa(i))}, ast.Type{})
+			l.bind(elem, element, mutable, stmts)
+		}
+	case ast.StructPattern:
+		for _, field := range v.Fields {
+			member := NewExpr(ExprMember, MemberExpr{PosRange: field.PosRange, Expr: value, Member: field.Field.Literal}, ast.Type{})
+			if field.Pattern != nil {
+				l.bind(*field.Pattern, member, mutable, stmts)
+			} else {
+				l.bind(ast.Pattern{Union: cee.Union[ast.PatternKind]{Tag: ast.PatternIdent, Value: field.Field}}, member, mutable, stmts)
+			}
+		}
+	}
+}
+
+// isError reports whether the type is the builtin error type.
+func isError(t ast.Type) bool {
+	alias, ok := t.Value.(ast.TypeAlias)
+	return ok && t.Tag == ast.TypeIdent && alias.Literal == "error"
+}
+
+// returnsError reports whether the enclosing function returns an error as its last result.
+func (l *Lowerer) returnsError() bool {
+	return l.fn != nil && len(l.fn.Results) != 0 && isError(l.fn.Results[len(l.fn.Results)-1])
+}
+
+// lowerDefer checks that errdefer is used in a function returning an error, which the deferred body
+// is conditioned on.
+func (l *Lowerer) lowerDefer(d ast.DeferStmt) Stmt {
+	if d.OnError && !l.returnsError() {
+		l.Report(diagnosis.Diagnosis{
+			Kind:  diagnosis.InvalidErrdefer,
+			Error: diagnosis.InvalidErrdeferError{Node: d},
+		})
+	}
+	return NewStmt(StmtDefer, DeferStmt{PosRange: d.PosRange, Body: l.LowerExpr(d.Expr), OnError: d.OnError})
+}
+
+// lowerTry desugars `try expr` into a block evaluating expr, a pair of a value and an error, and returning
+// the error from the enclosing function unless it is nil:
+//
+//	{
+//		let $try = expr
+//		if $try[1] != nil { let $zero T; ...; return $zero, ..., $try[1] }
+//		$try[0]
+//	}
+//
+// The enclosing function must return an error as its last result, the other results are returned zeroed.
+func (l *Lowerer) lowerTry(t ast.TryExpr) Expr {
+	pos := t.PosRange
+	if !l.returnsError() {
+		l.Report(diagnosis.Diagnosis{
+			Kind:  diagnosis.InvalidTry,
+			Error: diagnosis.InvalidTryError{Node: t},
+		})
+		return l.LowerExpr(t.Expr)
+	}
+
+	name := l.temp("try")
+	value := l.LowerExpr(t.Expr)
+	pair := ident(pos, name, value.Type)
+	element := func(i string) Expr {
+		return NewExpr(ExprIndex, IndexExpr{PosRange: pos, Expr: pair, Index: intLiteral(pos, i)}, ast.Type{})
+	}
+	err := element("1")
+
+	var (
+		ret   Block
+		exprs []Expr
+	)
+	ret.PosRange = pos
+	for _, typ := range l.fn.Results[:len(l.fn.Results)-1] {
+		zero := l.temp("zero")
+		ret.Stmts = append(ret.Stmts, NewStmt(StmtLet, LetStmt{PosRange: pos, Name: zero, Type: typ}))
+		exprs = append(exprs, ident(pos, zero, typ))
+	}
+	ret.Stmts = append(ret.Stmts, NewStmt(StmtReturn, ReturnStmt{PosRange: pos, Exprs: append(exprs, err)}))
+
+	failed := binary(pos, token.NEQ, err, ident(pos, "nil", l.fn.Results[len(l.fn.Results)-1]))
+	return NewExpr(ExprBlock, Block{PosRange: pos, Stmts: []Stmt{
+		NewStmt(StmtLet, LetStmt{PosRange: pos, Name: name, Type: value.Type, Value: &value}),
+		NewStmt(StmtExpr, NewExpr(ExprIf, IfExpr{PosRange: pos, Cond: failed, Then: ret}, ast.Type{})),
+		NewStmt(StmtExpr, element("0")),
+	}}, ast.Type{})
+}
+
+// lowerIntrinsic resolves the intrinsic and checks its arity, unknown intrinsics are reported and dropped.
+func (l *Lowerer) lowerIntrinsic(e ast.IntrinsicExpr) Expr {
+	in, ok := intrinsic.Lookup(e.Namespace.Literal, e.Name.Literal)
+	if !ok {
+		l.Report(diagnosis.Diagnosis{
+			Kind:  diagnosis.UnknownIntrinsic,
+			Error: diagnosis.UnknownIntrinsicError{Expr: e},
+		})
+		return NewExpr(ExprBlock, Block{PosRange: e.PosRange}, ast.Type{})
+	}
+	if len(e.Params) != in.Params {
+		l.Report(diagnosis.Diagnosis{
+			Kind:  diagnosis.IntrinsicArity,
+			Error: diagnosis.IntrinsicArityError{Expr: e, Want: in.Params},
+		})
+	}
+
+	params := make([]Expr, len(e.Params))
+	for i, param := range e.Params {
+		params[i] = l.LowerExpr(param)
+	}
+
+	var typ ast.Type
+	if in.Result != ast.TypeNone {
+		typ = builtin(in.Result)
+	}
+	return NewExpr(ExprIntrinsic, IntrinsicExpr{PosRange: e.PosRange, Op: in.Op, Params: params}, typ)
+}
+
+// lowerInterpolation rewrites `"a${x}b${y}"` into the concatenation `"a" + x + "b" + y`, adjacent literals
+// folded. The concatenation starts with the first segment, even empty, so that each embedded value is appended
+// to a string, which converts it as the interpolation does.
+func (l *Lowerer) lowerInterpolation(s ast.InterpolatedString) Expr {
+	result := l.literal(s.Lits[0], ast.Type{}, false, s.Lits[0].PosRange)
+	add := func(e Expr) {
+		pos := ast.PosRange{From: result.GetPosRange().From, To: e.GetPosRange().To}
+		if folded, ok := concat(pos, result, e); ok {
+			result = folded
+		} else {
+			result = binary(pos, token.ADD, result, e)
+		}
+	}
+	for i, expr := range s.Exprs {
+		add(l.LowerExpr(expr))
+		if lit := s.Lits[i+1]; lit.Literal != `""` {
+			add(l.literal(lit, ast.Type{}, false, lit.PosRange))
+		}
+	}
+	return result
+}
+
+// lowerBranch turns if/else-if/else chains into nested IfExpr, each else-if becoming the sole statement of an else block.
+func (l *Lowerer) lowerBranch(b ast.BranchExpr) Expr {
+	expr := IfExpr{
+		PosRange: b.PosRange,
+		Cond:     l.LowerExpr(b.Cond),
+		Then:     l.LowerBlock(b.Branch),
+	}
+	if len(b.ElseBranch.Stmts) != 0 {
+		els := l.LowerBlock(b.ElseBranch)
+		expr.Else = &els
+	}
+	return NewExpr(ExprIf, expr, expr.Then.typeOf())
+}
+
+func (b Block) typeOf() ast.Type {
+	if len(b.Stmts) == 0 {
+		return ast.Type{}
+	}
+	if last := b.Stmts[len(b.Stmts)-1]; last.Tag == StmtExpr {
+		return last.Value.(Expr).Type
+	}
+	return ast.Type{}
+}
+
+// LowerFunc lowers the body of a function with its parameters in scope.
+// Error propagation in the body is checked against the results of the function.
+func (l *Lowerer) LowerFunc(d ast.FuncDecl) Block {
+	outer := l.fn
+	l.fn = &d.Type
+	defer func() { l.fn = outer }()
+
+	l.enterScope()
+	defer l.exitScope()
+	for _, param := range d.Type.Params {
+		for _, id := range param.Idents {
+			l.declare(id.Literal, param.Type)
+		}
+	}
+
+	if d.Stmt == nil {
+		return Block{PosRange: d.PosRange}
+	}
+	return l.LowerBlock(*d.Stmt)
+}
+
+func (l *Lowerer) LowerBlock(b ast.StmtBlockExpr) Block {
+	l.enterScope()
+	defer l.exitScope()
+
+	block := Block{PosRange: b.PosRange}
+	for _, stmt := range b.Stmts {
+		block.Stmts = append(block.Stmts, l.LowerStmt(stmt)...)
+	}
+	return block
+}
+
+// LowerStmt may expand a single AST statement into several core statements.
+func (l *Lowerer) LowerStmt(s ast.Stmt) []Stmt {
+	switch s.Tag {
+	case ast.StmtExpr:
+		return []Stmt{NewStmt(StmtExpr, l.LowerExpr(s.Value.(ast.Expr)))}
+	case ast.StmtValDecl:
+		d := s.Value.(ast.ValDecl)
+		value := l.lowerStored(d.Value, d.Type)
+		if d.Pattern != nil {
+			return l.lowerDestructure(d, value)
+		}
+		typ := d.Type
+		if typ.Value == nil {
+			typ = value.Type
+		}
+		l.declare(d.Name.Literal, typ)
+		return []Stmt{NewStmt(StmtLet, LetStmt{PosRange: d.PosRange, Name: d.Name.Literal, Type: typ, Value: &value, Mutable: d.Mutable})}
+	case ast.StmtGenDecl:
+		d := s.Value.(ast.GenDecl)
+		var stmts []Stmt
+		for _, id := range d.Idents {
+			l.declare(id.Literal, d.Type)
+			stmts = append(stmts, NewStmt(StmtLet, LetStmt{PosRange: d.PosRange, Name: id.Literal, Type: d.Type, Mutable: true}))
+		}
+		return stmts
+	case ast.StmtReturn:
+		r := s.Value.(ast.ReturnStmt)
+		exprs := make([]Expr, len(r.Exprs))
+		for i, expr := range r.Exprs {
+			var typ ast.Type
+			if l.fn != nil && len(l.fn.Results) == len(r.Exprs) {
+				typ = l.fn.Results[i]
+			}
+			exprs[i] = l.lowerStored(expr, typ)
+		}
+		return []Stmt{NewStmt(StmtReturn, ReturnStmt{PosRange: r.PosRange, Exprs: exprs})}
+	case ast.StmtAssign:
+		return l.lowerAssign(s.Value.(ast.AssignStmt))
+	case ast.StmtBreak:
+		return []Stmt{NewStmt(StmtBreak, BreakStmt{PosRange: s.Value.(ast.BreakStmt).PosRange})}
+	case ast.StmtContinue:
+		return []Stmt{NewStmt(StmtContinue, ContinueStmt{PosRange: s.Value.(ast.ContinueStmt).PosRange})}
+	case ast.StmtLoop:
+		return []Stmt{l.lowerLoop(s.Value.(ast.LoopStmt))}
+	case ast.StmtEndlessFor:
+		f := s.Value.(ast.EndlessForStmt)
+		body := l.LowerBlock(f.Stmt)
+		return []Stmt{NewStmt(StmtLoop, LoopStmt{PosRange: body.PosRange, Body: body})}
+	case ast.StmtForeach:
+		return l.lowerForeach(s.Value.(ast.ForeachStmt))
+	case ast.StmtDefer:
+		return []Stmt{l.lowerDefer(s.Value.(ast.DeferStmt))}
+	default:
+		return []Stmt{NewStmt(StmtSyntax, SyntaxStmt{PosRange: s.GetPosRange(), Stmt: s})}
+	}
+}
+
+// lowerAssign rewrites `a op= b` into `a = a op b`. The operands of a that a evaluates are bound to temporaries
+// first, unless they are evaluated without effects, so that `a[f()] += 1` calls f once.
+func (l *Lowerer) lowerAssign(a ast.AssignStmt) []Stmt {
+	var stmts []Stmt
+	target := l.LowerExpr(a.ExprL)
+	compound := a.Operator.Kind != token.ASSIGN && a.Operator.Kind != 0
+	if compound {
+		target = l.place(target, &stmts)
+	}
+	value := l.lowerStored(a.ExprR, target.Type)
+	if compound {
+		value = binary(a.PosRange, token.CompoundAssignOperators[a.Operator.Kind], target, value)
+	}
+	return append(stmts, NewStmt(StmtAssign, AssignStmt{PosRange: a.PosRange, Target: target, Value: value}))
+}
+
+// place returns the assignment target e with its operands bound to temporaries by stmts: the index of an
+// index expression, and the operand of an index, a member selection or a dereference unless it is a place itself.
+func (l *Lowerer) place(e Expr, stmts *[]Stmt) Expr {
+	switch v := e.Value.(type) {
+	case IndexExpr:
+		v.Expr = l.place(v.Expr, stmts)
+		v.Index = l.once(v.Index, stmts)
+		e.Value = v
+	case MemberExpr:
+		v.Expr = l.place(v.Expr, stmts)
+		e.Value = v
+	case UnaryExpr:
+		if v.Operator == token.MUL {
+			v.Expr = l.once(v.Expr, stmts)
+			e.Value = v
+		}
+	case Ident, Literal:
+	default:
+		return l.once(e, stmts)
+	}
+	return e
+}
+
+// once binds e to a temporary by stmts unless evaluating it has no effect.
+func (l *Lowerer) once(e Expr, stmts *[]Stmt) Expr {
+	switch e.Value.(type) {
+	case Ident, Literal:
+		return e
+	}
+	name := l.temp("t")
+	*stmts = append(*stmts, NewStmt(StmtLet, LetStmt{PosRange: e.GetPosRange(), Name: name, Type: e.Type, Value: &e}))
+	return ident(e.GetPosRange(), name, e.Type)
+}
+
+// lowerLoop rewrites `for cond { ... }` into `loop { if !cond { break }; ... }`.
+func (l *Lowerer) lowerLoop(s ast.LoopStmt) Stmt {
+	cond := l.LowerExpr(s.Cond)
+	body := l.LowerBlock(s.Stmt)
+	body.Stmts = append([]Stmt{breakUnless(s.Cond.Value.(ast.Node).GetPosRange(), cond)}, body.Stmts...)
+	return NewStmt(StmtLoop, LoopStmt{PosRange: s.PosRange, Body: body})
+}
+
+// lowerForeach rewrites `for [i,] v in expr { ... }` into
+//
+//	val $iter = expr
+//	val $len = len($iter)
+//	var $i = -1
+//	loop {
+//		$i = $i + 1
+//		if !($i < $len) { break }
+//		val i = $i
+//		val v = $iter[$i]
+//		...
+//	}
+//
+// The counter is incremented on entry so that `continue` does not skip it. Maps and channels are iterated
+// with a NextExpr instead, see lowerNext.
+func (l *Lowerer) lowerForeach(s ast.ForeachStmt) []Stmt {
+	pos := s.PosRange
+	iterValue := l.LowerExpr(s.Expr)
+	if iterValue.Type.Tag == ast.TypeMap || iterValue.Type.Tag == ast.TypeChan {
+		return l.lowerNext(s, iterValue)
+	}
+
+	iterName, lenName, idxName := l.temp("iter"), l.temp("len"), l.temp("i")
+	iter := ident(pos, iterName, iterValue.Type)
+	idx := ident(pos, idxName, builtin(ast.TypeI64))
+
+	length := NewExpr(ExprCall, CallExpr{PosRange: pos, Callee: ident(pos, "len", ast.Type{}), Params: []Expr{iter}}, builtin(ast.TypeI64))
+	start := NewExpr(ExprUnary, UnaryExpr{PosRange: pos, Operator: token.SUB, Expr: intLiteral(pos, "1")}, builtin(ast.TypeI64))
+
+	l.enterScope()
+	defer l.exitScope()
+
+	header := []Stmt{
+		NewStmt(StmtAssign, AssignStmt{PosRange: pos, Target: idx, Value: binary(pos, token.ADD, idx, intLiteral(pos, "1"))}),
+		breakUnless(pos, binary(pos, token.LSS, idx, ident(pos, lenName, builtin(ast.TypeI64)))),
+	}
+
+	element := NewExpr(ExprIndex, IndexExpr{PosRange: pos, Expr: iter, Index: idx}, ast.Type{})
+	bindings := []Expr{element}
+	if len(s.IdentList) == 2 {
+		bindings = []Expr{idx, element}
+	}
+	for i, id := range s.IdentList {
+		if i >= len(bindings) {
+			l.unsupported(id)
+			break
+		}
+		value := bindings[i]
+		l.declare(id.Literal, value.Type)
+		header = append(header, NewStmt(StmtLet, LetStmt{PosRange: id.PosRange, Name: id.Literal, Type: value.Type, Value: &value}))
+	}
+
+	body := l.LowerBlock(s.Stmt)
+	body.Stmts = append(header, body.Stmts...)
+
+	return []Stmt{
+		NewStmt(StmtLet, LetStmt{PosRange: pos, Name: iterName, Type: iterValue.Type, Value: &iterValue}),
+		NewStmt(StmtLet, LetStmt{PosRange: pos, Name: lenName, Type: builtin(ast.TypeI64), Value: &length}),
+		NewStmt(StmtLet, LetStmt{PosRange: pos, Name: idxName, Type: builtin(ast.TypeI64), Value: &start, Mutable: true}),
+		NewStmt(StmtLoop, LoopStmt{PosRange: pos, Body: body}),
+	}
+}
+
+// lowerNext rewrites `for [k,] v in expr { ... }` over a map or a channel, which cannot be indexed, into
+//
+//	val $iter = expr
+//	loop {
+//		if !next k, v in $iter { break }
+//		...
+//	}
+//
+// A single binding takes the values of a map, as for arrays, and the elements of a channel.
+func (l *Lowerer) lowerNext(s ast.ForeachStmt, iterValue Expr) []Stmt {
+	pos := s.PosRange
+	iterName := l.temp("iter")
+
+	var key, value ast.Type
+	switch t := iterValue.Type.Value.(type) {
+	case ast.MapType:
+		key, value = t.Key, t.Value
+	case ast.ChanType:
+		value = t.Elem
+	}
+
+	l.enterScope()
+	defer l.exitScope()
+
+	next := NextExpr{PosRange: pos, Iter: ident(pos, iterName, iterValue.Type)}
+	ids := s.IdentList
+	if len(ids) == 2 && iterValue.Type.Tag == ast.TypeChan {
+		// Channels have no keys.
+		l.unsupported(ids[0])
+		ids = ids[1:]
+	}
+	if len(ids) == 2 {
+		next.Key = ids[0].Literal
+		l.declare(next.Key, key)
+		ids = ids[1:]
+	}
+	if len(ids) == 1 {
+		next.Value = ids[0].Literal
+		l.declare(next.Value, value)
+	}
+
+	body := l.LowerBlock(s.Stmt)
+	body.Stmts = append([]Stmt{breakUnless(pos, NewExpr(ExprNext, next, ast.Type{}))}, body.Stmts...)
+
+	return []Stmt{
+		NewStmt(StmtLet, LetStmt{PosRange: pos, Name: iterName, Type: iterValue.Type, Value: &iterValue}),
+		NewStmt(StmtLoop, LoopStmt{PosRange: pos, Body: body}),
+	}
+}
@@ -0,0 +1,431 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package hir
+
+import (
+	"cee"
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/intrinsic"
+	"cee/parser"
+	"cee/token"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func identOf(name string) ast.Ident {
+	return ast.Ident{Token: ast.Token{Kind: token.IDENT, Literal: name}}
+}
+
+func patternOf(kind ast.PatternKind, value ast.Node) ast.Pattern {
+	return ast.Pattern{Union: cee.Union[ast.PatternKind]{Tag: kind, Value: value}}
+}
+
+func TestLowerDestructure(t *testing.T) {
+	// val (a, _, Point{x, y: b}) = v
+	pattern := patternOf(ast.PatternTuple, ast.TuplePattern{Elems: []ast.Pattern{
+		patternOf(ast.PatternIdent, identOf("a")),
+		patternOf(ast.PatternIdent, identOf("_")),
+		patternOf(ast.PatternStruct, ast.StructPattern{Fields: []ast.FieldPattern{
+			{Field: identOf("x")},
+			{Field: identOf("y"), Pattern: &[]ast.Pattern{patternOf(ast.PatternIdent, identOf("b"))}[0]},
+		}}),
+	}})
+	decl := ast.ValDecl{Pattern: &pattern, Value: ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: ast.ExprIdent, Value: identOf("v")}}}
+
+	l := NewLowerer()
+	stmts := l.LowerStmt(ast.Stmt{Union: cee.Union[ast.StmtKind]{Tag: ast.StmtValDecl, Value: decl}})
+
+	var names []string
+	for _, stmt := range stmts {
+		names = append(names, stmt.Value.(LetStmt).Name)
+	}
+	if len(names) != 4 || names[1] != "a" || names[2] != "x" || names[3] != "b" {
+		t.Fatalf("bindings %v", names)
+	}
+
+	// b is the field y of the third element of the temporary.
+	b := stmts[3].Value.(LetStmt).Value.Value.(MemberExpr)
+	if b.Member != "y" || b.Expr.Value.(IndexExpr).Index.Value.(Literal).Literal != "2" {
+		t.Errorf("b is bound to %+v", b)
+	}
+}
+
+func typeOf(name string) ast.Type {
+	return ast.Type{Union: cee.Union[ast.TypeKind]{Tag: ast.TypeIdent, Value: ast.TypeAlias{Ident: identOf(name)}}}
+}
+
+func funcReturning(results []ast.Type, body ...ast.Stmt) ast.FuncDecl {
+	return ast.FuncDecl{Type: ast.FuncType{Results: results}, Stmt: &ast.StmtBlockExpr{Stmts: body}}
+}
+
+func TestLowerTry(t *testing.T) {
+	call := ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: ast.ExprCall, Value: ast.CallExpr{
+		Callee: ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: ast.ExprIdent, Value: identOf("open")}},
+	}}}
+	try := ast.Stmt{Union: cee.Union[ast.StmtKind]{Tag: ast.StmtExpr, Value: ast.Expr{
+		Union: cee.Union[ast.ExprKind]{Tag: ast.ExprTry, Value: ast.TryExpr{Expr: call}},
+	}}}
+
+	l := NewLowerer()
+	body := l.LowerFunc(funcReturning([]ast.Type{builtin(ast.TypeI32), typeOf("error")}, try))
+	if len(l.Diagnosis) != 0 {
+		t.Fatalf("unexpected diagnosis %v", l.Diagnosis)
+	}
+
+	block := body.Stmts[0].Value.(Expr).Value.(Block)
+	if len(block.Stmts) != 3 {
+		t.Fatalf("try lowered to %d statements", len(block.Stmts))
+	}
+	ret := block.Stmts[1].Value.(Expr).Value.(IfExpr).Then.Stmts
+	if r, ok := ret[len(ret)-1].Value.(ReturnStmt); !ok || len(r.Exprs) != 2 {
+		t.Errorf("error branch ends with %+v", ret[len(ret)-1])
+	}
+
+	for _, results := range [][]ast.Type{nil, {builtin(ast.TypeI32)}} {
+		l := NewLowerer()
+		l.LowerFunc(funcReturning(results, try))
+		if len(l.Diagnosis) != 1 || l.Diagnosis[0].Kind != diagnosis.InvalidTry {
+			t.Errorf("results %v: diagnosis %v", results, l.Diagnosis)
+		}
+	}
+}
+
+func TestLowerErrdefer(t *testing.T) {
+	cleanup := ast.Stmt{Union: cee.Union[ast.StmtKind]{Tag: ast.StmtDefer, Value: ast.DeferStmt{
+		OnError: true,
+		Expr:    ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: ast.ExprStmtBlock, Value: ast.StmtBlockExpr{}}},
+	}}}
+
+	l := NewLowerer()
+	body := l.LowerFunc(funcReturning([]ast.Type{typeOf("error")}, cleanup))
+	if len(l.Diagnosis) != 0 {
+		t.Fatalf("unexpected diagnosis %v", l.Diagnosis)
+	}
+	if d, ok := body.Stmts[0].Value.(DeferStmt); !ok || !d.OnError {
+		t.Errorf("lowered to %+v", body.Stmts[0])
+	}
+
+	l = NewLowerer()
+	l.LowerFunc(funcReturning(nil, cleanup))
+	if len(l.Diagnosis) != 1 || l.Diagnosis[0].Kind != diagnosis.InvalidErrdefer {
+		t.Errorf("diagnosis %v", l.Diagnosis)
+	}
+}
+
+func TestLowerIntrinsic(t *testing.T) {
+	expr := func(name string, params ...ast.Expr) ast.Expr {
+		return ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: ast.ExprIntrinsic, Value: ast.IntrinsicExpr{
+			Namespace: identOf(intrinsic.Namespace),
+			Name:      identOf(name),
+			Params:    params,
+		}}}
+	}
+	arg := ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: ast.ExprIdent, Value: identOf("p")}}
+
+	l := NewLowerer()
+	if e := l.LowerExpr(expr("memcpy", arg, arg, arg)); e.Value.(IntrinsicExpr).Op != intrinsic.Memcpy {
+		t.Errorf("memcpy lowered to %+v", e)
+	}
+	if len(l.Diagnosis) != 0 {
+		t.Fatal(l.Diagnosis)
+	}
+
+	l.LowerExpr(expr("trap", arg))
+	l.LowerExpr(expr("nope"))
+	if len(l.Diagnosis) != 2 || l.Diagnosis[0].Kind != diagnosis.IntrinsicArity || l.Diagnosis[1].Kind != diagnosis.UnknownIntrinsic {
+		t.Errorf("diagnosis %v", l.Diagnosis)
+	}
+}
+
+func TestLowerLiteralOverflow(t *testing.T) {
+	expr := func(kind ast.ExprKind, value ast.Node) ast.Expr {
+		return ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: kind, Value: value}}
+	}
+	lit := func(s string) ast.Expr {
+		return expr(ast.ExprLiteralValue, ast.LiteralValue{Token: ast.Token{Kind: token.INT, Literal: s}})
+	}
+	neg := func(s string) ast.Expr {
+		return expr(ast.ExprUnary, ast.UnaryExpr{Operator: ast.Token{Kind: token.SUB}, Expr: lit(s)})
+	}
+	assign := func(value ast.Expr) ast.Stmt {
+		return ast.Stmt{Union: cee.Union[ast.StmtKind]{Tag: ast.StmtAssign, Value: ast.AssignStmt{
+			ExprL: expr(ast.ExprIdent, identOf("x")), Operator: ast.Token{Kind: token.ASSIGN}, ExprR: value,
+		}}}
+	}
+	u8, i8 := builtin(ast.TypeU8), builtin(ast.TypeI8)
+	decl := func(typ ast.Type) ast.Stmt {
+		return ast.Stmt{Union: cee.Union[ast.StmtKind]{Tag: ast.StmtGenDecl, Value: ast.GenDecl{Idents: []ast.Ident{identOf("x")}, Type: typ}}}
+	}
+	val := func(typ ast.Type, value ast.Expr) ast.Stmt {
+		return ast.Stmt{Union: cee.Union[ast.StmtKind]{Tag: ast.StmtValDecl, Value: ast.ValDecl{Mutable: true, Name: identOf("x"), Type: typ, Value: value}}}
+	}
+	ret := func(value ast.Expr) ast.Stmt {
+		return ast.Stmt{Union: cee.Union[ast.StmtKind]{Tag: ast.StmtReturn, Value: ast.ReturnStmt{Exprs: []ast.Expr{value}}}}
+	}
+
+	tests := []struct {
+		fn   ast.FuncDecl
+		want []int
+	}{
+		{funcReturning(nil, decl(u8), assign(lit("255"))), nil},
+		{funcReturning(nil, decl(u8), assign(lit("300"))), []int{diagnosis.LiteralOverflow}},
+		{funcReturning(nil, decl(u8), assign(neg("1"))), []int{diagnosis.LiteralOverflow}},
+		{funcReturning(nil, decl(i8), assign(neg("128"))), nil},
+		{funcReturning([]ast.Type{i8}, ret(lit("128"))), []int{diagnosis.LiteralOverflow}},
+		{funcReturning(nil, val(u8, lit("300"))), []int{diagnosis.LiteralOverflow}},
+		{funcReturning(nil, val(i8, neg("128"))), nil},
+		{funcReturning(nil, assign(lit("18446744073709551615"))), nil},
+		{funcReturning(nil, assign(lit("0.10000000000000000001"))), []int{diagnosis.LiteralPrecision}},
+	}
+	for i, test := range tests {
+		l := NewLowerer()
+		l.LowerFunc(test.fn)
+		var kinds []int
+		for _, d := range l.Diagnosis {
+			kinds = append(kinds, d.Kind)
+		}
+		if fmt.Sprint(kinds) != fmt.Sprint(test.want) {
+			t.Errorf("test %d: diagnosis %v, want %v", i, kinds, test.want)
+		}
+	}
+
+	// Builtin types spelled in the source carry their identifier.
+	f, err := parser.ParseFile(token.NewFileSet(), "a.cee", []byte("package a\n\nfun f() {\n\tvar x u8 = 300\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewLowerer()
+	l.LowerFunc(f.Decls[0].Value.(ast.FuncDecl))
+	if len(l.Diagnosis) != 1 || l.Diagnosis[0].Kind != diagnosis.LiteralOverflow {
+		t.Errorf("var x u8 = 300: diagnosis %v", l.Diagnosis)
+	}
+}
+
+func TestLowerConcat(t *testing.T) {
+	expr := func(kind ast.ExprKind, value ast.Node) ast.Expr {
+		return ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: kind, Value: value}}
+	}
+	str := func(s string) ast.Expr {
+		return expr(ast.ExprLiteralValue, ast.LiteralValue{Token: ast.Token{Kind: token.STRING, Literal: s}})
+	}
+	add := func(a, b ast.Expr) ast.Expr {
+		return expr(ast.ExprBinary, ast.BinaryExpr{Operator: ast.Token{Kind: token.ADD}, Exprs: [2]ast.Expr{a, b}})
+	}
+	x := expr(ast.ExprIdent, identOf("x"))
+
+	tests := []struct {
+		e    ast.Expr
+		want string
+	}{
+		{add(str(`"a"`), str(`"b\n"`)), `"ab\n"`},
+		{add(add(str(`"a"`), str(`"b"`)), str(`"\""`)), `"ab\""`},
+		{add(add(x, str(`"a"`)), str(`"b"`)), `(x + "ab")`},
+		{add(str(`"a"`), x), `("a" + x)`},
+		{add(add(str(`"a"`), x), str(`"b"`)), `(("a" + x) + "b")`},
+	}
+	for _, test := range tests {
+		l := NewLowerer()
+		if got := l.LowerExpr(test.e).String(); got != test.want {
+			t.Errorf("lowered %s, want %s", got, test.want)
+		}
+	}
+}
+
+func TestLowerAddressOf(t *testing.T) {
+	unary := func(op int, e ast.Expr) ast.Expr {
+		return ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: ast.ExprUnary, Value: ast.UnaryExpr{Operator: ast.Token{Kind: op}, Expr: e}}}
+	}
+	v := ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: ast.ExprIdent, Value: identOf("v")}}
+
+	l := NewLowerer()
+	l.Scopes[0]["v"] = typeOf("T")
+
+	ref := l.LowerExpr(unary(token.AND, v))
+	if s := ast.TypeString(ref.Type); s != "*T" {
+		t.Errorf("&v has type %q", s)
+	}
+	deref := l.LowerExpr(unary(token.MUL, unary(token.AND, v)))
+	if s := ast.TypeString(deref.Type); s != "T" {
+		t.Errorf("*&v has type %q", s)
+	}
+}
+
+// lowerSource parses src and lowers the body of its first function.
+func lowerSource(t *testing.T, src string) []Stmt {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "a.cee", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, decl := range f.Decls {
+		if fn, ok := decl.Value.(ast.FuncDecl); ok {
+			l := NewLowerer()
+			body := l.LowerFunc(fn)
+			if len(l.Diagnosis) != 0 {
+				t.Fatalf("unexpected diagnosis %v", l.Diagnosis)
+			}
+			return body.Stmts
+		}
+	}
+	t.Fatal("no function in source")
+	return nil
+}
+
+// breaksUnless reports whether s is `if !cond { break }`.
+func breaksUnless(s Stmt) bool {
+	e, ok := s.Value.(Expr)
+	if !ok || e.Tag != ExprIf {
+		return false
+	}
+	br := e.Value.(IfExpr)
+	return br.Cond.Tag == ExprUnary && br.Cond.Value.(UnaryExpr).Operator == token.NOT &&
+		len(br.Then.Stmts) == 1 && br.Then.Stmts[0].Tag == StmtBreak && br.Else == nil
+}
+
+func TestLowerSugar(t *testing.T) {
+	stmts := lowerSource(t, `package a
+
+fun f(xs []i64, n i64) {
+	var x = 0
+	x += n
+	for x < 10 { x = x + 1 }
+	for i, v in xs { g(i, v) }
+	if x < 1 { g(0, 0) } else if x < 2 { g(1, 1) } else { g(2, 2) }
+}
+`)
+	if len(stmts) != 8 {
+		t.Fatalf("lowered to %d statements", len(stmts))
+	}
+
+	assign, ok := stmts[1].Value.(AssignStmt)
+	if !ok || assign.Value.Tag != ExprBinary {
+		t.Fatalf("x += n lowered to %+v", stmts[1])
+	}
+	if sum := assign.Value.Value.(BinaryExpr); sum.Operator != token.ADD ||
+		sum.Exprs[0].Value.(Ident).Name != "x" || sum.Exprs[1].Value.(Ident).Name != "n" {
+		t.Errorf("x += n lowered to x = %+v", sum)
+	}
+
+	loop, ok := stmts[2].Value.(LoopStmt)
+	if !ok || len(loop.Body.Stmts) != 2 || !breaksUnless(loop.Body.Stmts[0]) || loop.Body.Stmts[1].Tag != StmtAssign {
+		t.Errorf("for cond lowered to %+v", stmts[2])
+	}
+
+	var names []string
+	for _, s := range stmts[3:6] {
+		let, ok := s.Value.(LetStmt)
+		if !ok {
+			t.Fatalf("foreach header %+v", s)
+		}
+		names = append(names, fmt.Sprintf("%s %t", let.Name, let.Mutable))
+	}
+	if fmt.Sprint(names) != "[$iter1 false $len2 false $i3 true]" {
+		t.Errorf("foreach declares %v", names)
+	}
+	each, ok := stmts[6].Value.(LoopStmt)
+	if !ok || len(each.Body.Stmts) != 5 {
+		t.Fatalf("foreach lowered to %+v", stmts[6])
+	}
+	body := each.Body.Stmts
+	if body[0].Tag != StmtAssign || !breaksUnless(body[1]) ||
+		body[2].Value.(LetStmt).Name != "i" || body[3].Value.(LetStmt).Name != "v" ||
+		body[3].Value.(LetStmt).Value.Tag != ExprIndex || body[4].Tag != StmtExpr {
+		t.Errorf("foreach body %+v", body)
+	}
+
+	outer := stmts[7].Value.(Expr).Value.(IfExpr)
+	if outer.Else == nil || len(outer.Else.Stmts) != 1 {
+		t.Fatalf("else-if lowered to %+v", outer)
+	}
+	inner, ok := outer.Else.Stmts[0].Value.(Expr).Value.(IfExpr)
+	if !ok || inner.Else == nil || len(inner.Else.Stmts) != 1 {
+		t.Errorf("else-if nests %+v", outer.Else.Stmts[0])
+	}
+}
+
+func TestLowerSugarOperands(t *testing.T) {
+	stmts := lowerSource(t, `package a
+
+fun f(xs []i64, m map[string]i64, ch chan i64, n i64) {
+	xs[g()] += n
+	for k, v in m { h(k, v) }
+	for e in ch { h(e, e) }
+	val s = "n = ${n}!"
+}
+`)
+	var got []string
+	for _, s := range stmts {
+		got = append(got, s.String())
+	}
+	want := []string{
+		"val $t1 = g()",
+		"xs[$t1] = (xs[$t1] + n)",
+		"val $iter2 map[string]i64 = m",
+		"loop { ... }",
+		"val $iter3 chan i64 = ch",
+		"loop { ... }",
+		`val s = (("n = " + n) + "!")`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("lowered to\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	for i, want := range map[int]string{3: "next k, v in $iter2", 5: "next e in $iter3"} {
+		cond := stmts[i].Value.(LoopStmt).Body.Stmts[0].Value.(Expr).Value.(IfExpr).Cond
+		if next := cond.Value.(UnaryExpr).Expr; next.Tag != ExprNext || next.String() != want {
+			t.Errorf("loop %d advances with %s, want %s", i, next, want)
+		}
+	}
+}
+
+func TestLowerSyntax(t *testing.T) {
+	stmts := lowerSource(t, `package a
+
+fun f(ch chan i64, v i64, xs []i64) {
+	outer: for i, x in xs {
+		switch x {
+		case 0:
+			continue outer
+		default:
+			go g(i)
+		}
+	}
+	select {
+	case ch <- 1:
+	}
+	ch <- v
+	val p = Point{x: 1}
+	val m = map[string]i64{"a": 1}
+	val h = fun [&v](y i64) i64 { return y + v }
+	match v {
+	case 0: g(0)
+	}
+}
+`)
+	var kinds []string
+	for _, s := range stmts {
+		switch v := s.Value.(type) {
+		case SyntaxStmt:
+			kinds = append(kinds, "stmt")
+		case LetStmt:
+			if v.Value.Tag == ExprSyntax {
+				kinds = append(kinds, "let")
+			}
+		case Expr:
+			if v.Tag == ExprSyntax {
+				kinds = append(kinds, "expr")
+			}
+		}
+	}
+	// The nodes without a core form pass through unchanged.
+	if got := strings.Join(kinds, " "); got != "stmt stmt stmt let let let expr" {
+		t.Errorf("passed through %s", got)
+	}
+	if got := stmts[2].String(); got != "ch <- v" {
+		t.Errorf("send formatted %q", got)
+	}
+}
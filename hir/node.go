@@ -0,0 +1,196 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package hir
+
+import (
+	"cee"
+	"cee/ast"
+	"cee/intrinsic"
+)
+
+type ExprKind int
+
+const (
+	_ = iota
+
+	ExprIdent
+	ExprLiteral
+	ExprUnary
+	ExprBinary
+	ExprCall
+	ExprIndex
+	ExprMember
+	ExprBlock
+	ExprIf
+	ExprIntrinsic
+	ExprNext
+	ExprSyntax
+)
+
+// Expr carries the type inferred during lowering, which is the zero Type when unknown.
+type Expr struct {
+	cee.Union[ExprKind]
+	Type ast.Type
+}
+
+type (
+	Ident struct {
+		ast.PosRange
+		Name string
+	}
+
+	Literal struct {
+		ast.PosRange
+		Kind    int
+		Literal string
+	}
+
+	UnaryExpr struct {
+		ast.PosRange
+		Operator int
+		Expr     Expr
+	}
+
+	BinaryExpr struct {
+		ast.PosRange
+		Operator int
+		Exprs    [2]Expr
+	}
+
+	CallExpr struct {
+		ast.PosRange
+		Callee Expr
+		Params []Expr
+	}
+
+	IndexExpr struct {
+		ast.PosRange
+		Expr  Expr
+		Index Expr
+	}
+
+	MemberExpr struct {
+		ast.PosRange
+		Expr   Expr
+		Member string
+	}
+
+	Block struct {
+		ast.PosRange
+		Stmts []Stmt
+	}
+
+	IfExpr struct {
+		ast.PosRange
+		Cond Expr
+		Then Block
+		Else *Block
+	}
+
+	// IntrinsicExpr is a checked intrinsic call, backends map Op directly.
+	IntrinsicExpr struct {
+		ast.PosRange
+		Op     intrinsic.Op
+		Params []Expr
+	}
+
+	// NextExpr advances the iteration of the map or the channel Iter and reports whether an element remained,
+	// binding its key and value, or the element of the channel, to Key and Value unless they are "".
+	// Each NextExpr iterates from the start when its loop is entered.
+	NextExpr struct {
+		ast.PosRange
+		Iter       Expr
+		Key, Value string
+	}
+
+	// SyntaxExpr is an expression the core language has no form for yet, e.g. a closure or a composite literal,
+	// passed through unchanged. Backends translate it from the syntax.
+	SyntaxExpr struct {
+		ast.PosRange
+		Expr ast.Expr
+	}
+)
+
+type StmtKind byte
+
+const (
+	_ = iota
+
+	StmtExpr
+	StmtLet
+	StmtAssign
+	StmtReturn
+	StmtBreak
+	StmtContinue
+	StmtLoop
+	StmtDefer
+	StmtSyntax
+)
+
+type Stmt struct {
+	cee.Union[StmtKind]
+}
+
+type (
+	LetStmt struct {
+		ast.PosRange
+		Name    string
+		Type    ast.Type
+		Value   *Expr // nil for zero value
+		Mutable bool
+	}
+
+	AssignStmt struct {
+		ast.PosRange
+		Target Expr
+		Value  Expr
+	}
+
+	ReturnStmt struct {
+		ast.PosRange
+		Exprs []Expr
+	}
+
+	BreakStmt struct {
+		ast.PosRange
+	}
+
+	ContinueStmt struct {
+		ast.PosRange
+	}
+
+	// LoopStmt is the only loop form of the core language, conditions are lowered into an IfExpr that breaks.
+	LoopStmt struct {
+		ast.PosRange
+		Body Block
+	}
+
+	// DeferStmt registers Body to run when the function exits, with OnError only on an error return
+	// or a panic. Backends test the last result of the function when it returns.
+	DeferStmt struct {
+		ast.PosRange
+		Body    Expr
+		OnError bool
+	}
+
+	// SyntaxStmt is a statement the core language has no form for yet, e.g. a switch or a send, passed through
+	// unchanged as SyntaxExpr is.
+	SyntaxStmt struct {
+		ast.PosRange
+		Stmt ast.Stmt
+	}
+)
+
+func NewExpr(kind ExprKind, value ast.Node, typ ast.Type) Expr {
+	return Expr{Union: cee.Union[ExprKind]{Tag: kind, Value: value}, Type: typ}
+}
+
+func NewStmt(kind StmtKind, value ast.Node) Stmt {
+	return Stmt{Union: cee.Union[StmtKind]{Tag: kind, Value: value}}
+}
+
+func (e Expr) GetPosRange() ast.PosRange { return e.Value.(ast.Node).GetPosRange() }
+
+func (s Stmt) GetPosRange() ast.PosRange { return s.Value.(ast.Node).GetPosRange() }
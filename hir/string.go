@@ -0,0 +1,120 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package hir
+
+import (
+	"cee/ast"
+	"cee/intrinsic"
+	"cee/token"
+	"fmt"
+	"strings"
+)
+
+func exprList(exprs []Expr) string {
+	s := make([]string, len(exprs))
+	for i, e := range exprs {
+		s[i] = e.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+// syntax formats a node passed through on its first line, the rest abbreviated.
+func syntax(node ast.Printer) string {
+	var b strings.Builder
+	if err := ast.Fprint(&b, node); err != nil {
+		return fmt.Sprintf("<%T>", node)
+	}
+	line, rest, _ := strings.Cut(strings.TrimSpace(b.String()), "\n")
+	switch {
+	case rest == "":
+	case strings.HasSuffix(line, "{"):
+		line += " ... }"
+	default:
+		line += " ..."
+	}
+	return line
+}
+
+// String formats the expression on a single line, blocks are abbreviated.
+func (e Expr) String() string {
+	switch v := e.Value.(type) {
+	case Ident:
+		return v.Name
+	case Literal:
+		return v.Literal
+	case UnaryExpr:
+		return token.KeywordLiterals[v.Operator] + v.Expr.String()
+	case BinaryExpr:
+		return fmt.Sprint("(", v.Exprs[0], " ", token.KeywordLiterals[v.Operator], " ", v.Exprs[1], ")")
+	case CallExpr:
+		return v.Callee.String() + "(" + exprList(v.Params) + ")"
+	case IndexExpr:
+		return fmt.Sprint(v.Expr, "[", v.Index, "]")
+	case MemberExpr:
+		return v.Expr.String() + "." + v.Member
+	case Block:
+		return "{ ... }"
+	case IfExpr:
+		return "if " + v.Cond.String() + " { ... }"
+	case IntrinsicExpr:
+		return fmt.Sprint("@", intrinsic.Namespace, ".", v.Op, "(", exprList(v.Params), ")")
+	case NextExpr:
+		var names []string
+		for _, name := range []string{v.Key, v.Value} {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return "next in " + v.Iter.String()
+		}
+		return "next " + strings.Join(names, ", ") + " in " + v.Iter.String()
+	case SyntaxExpr:
+		return syntax(v.Expr)
+	}
+	return fmt.Sprintf("<%T>", e.Value)
+}
+
+// String formats the statement on a single line, nested blocks are abbreviated.
+func (s Stmt) String() string {
+	switch v := s.Value.(type) {
+	case Expr:
+		return v.String()
+	case LetStmt:
+		kw := "val "
+		if v.Mutable {
+			kw = "var "
+		}
+		decl := kw + v.Name
+		if v.Type.Value != nil || v.Type.Tag != 0 {
+			decl += " " + ast.TypeString(v.Type)
+		}
+		if v.Value != nil {
+			decl += " = " + v.Value.String()
+		}
+		return decl
+	case AssignStmt:
+		return v.Target.String() + " = " + v.Value.String()
+	case ReturnStmt:
+		if len(v.Exprs) == 0 {
+			return "return"
+		}
+		return "return " + exprList(v.Exprs)
+	case BreakStmt:
+		return "break"
+	case ContinueStmt:
+		return "continue"
+	case LoopStmt:
+		return "loop { ... }"
+	case DeferStmt:
+		if v.OnError {
+			return "errdefer " + v.Body.String()
+		}
+		return "defer " + v.Body.String()
+	case SyntaxStmt:
+		return syntax(v.Stmt)
+	}
+	return fmt.Sprintf("<%T>", s.Value)
+}
@@ -0,0 +1,32 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ide
+
+import (
+	"cee/ast"
+	"cee/resolve"
+	"strings"
+)
+
+// Annotate renders the source of a resolved file with the types inferred for `val` declarations, as
+// `x /*: i64*/`, and the declarations calls resolve to, as `f /*-> lib.cee:3:5*/(x)`.
+// The annotations are comments, the result parses to the same declarations.
+func Annotate(src []rune, decls []ast.Stmt, info *resolve.Info, file string) string {
+	c := newInlayCollector(info, file, 0, len(src))
+	c.targets = true
+
+	var b strings.Builder
+	last := 0
+	for _, hint := range c.collect(decls) {
+		if hint.Kind == InlayParameter || hint.Offset > len(src) {
+			continue
+		}
+		b.WriteString(string(src[last:hint.Offset]))
+		b.WriteString(" /*" + hint.Label + "*/")
+		last = hint.Offset
+	}
+	b.WriteString(string(src[last:]))
+	return b.String()
+}
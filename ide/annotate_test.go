@@ -0,0 +1,85 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ide
+
+import (
+	"cee/ast"
+	"cee/resolve"
+	"fmt"
+	"testing"
+)
+
+// resolveSource parses and resolves a single file.
+func resolveSource(t *testing.T, path, src string) ([]ast.Stmt, *resolve.Info) {
+	t.Helper()
+	files, info := resolveFiles(t, path, src)
+	return files[0].Decls, info
+}
+
+const annotated = `package a
+
+fun add(a i64, b i64) i64 {
+	return a + b
+}
+
+fun f(b i64) i64 {
+	val x = add(1, b)
+	val y = b * 2
+	val z = 1
+	return add(y, z)
+}
+`
+
+func TestAnnotate(t *testing.T) {
+	decls, info := resolveSource(t, "a.cee", annotated)
+	got := Annotate([]rune(annotated), decls, info, "a.cee")
+	want := `package a
+
+fun add(a i64, b i64) i64 {
+	return a + b
+}
+
+fun f(b i64) i64 {
+	val x = add /*-> a.cee:3:5*/(1, b)
+	val y /*: i64*/ = b * 2
+	val z /*: i64*/ = 1
+	return add /*-> a.cee:3:5*/(y, z)
+}
+`
+	if got != want {
+		t.Errorf("Annotate =\n%s\nwant\n%s", got, want)
+	}
+
+	// The annotations are comments.
+	if again, _ := resolveSource(t, "a.cee", got); len(again) != len(decls) {
+		t.Errorf("annotated source parses to %d declarations", len(again))
+	}
+}
+
+func TestInlayHints(t *testing.T) {
+	decls, info := resolveSource(t, "a.cee", annotated)
+	for _, test := range []struct {
+		from, to int
+		want     string
+	}{
+		// b is passed as b, which needs no hint. The result of add is not inferred.
+		{0, len(annotated), "[1 a:   : i64   : i64 y a: z b:]"},
+		{at(annotated, "val z", 0), len(annotated), "[  : i64 y a: z b:]"},
+		{at(annotated, "1, b", 0), at(annotated, "val z", 0), "[1 a:   : i64]"},
+		{at(annotated, "y, z", 0), at(annotated, "y, z", 0), "[y a:]"},
+		{0, at(annotated, "1, b", 0) - 1, "[]"},
+	} {
+		var got []string
+		for _, hint := range InlayHints(decls, info, "a.cee", test.from, test.to) {
+			got = append(got, string([]rune(annotated)[hint.Offset:hint.Offset+1])+" "+hint.Label)
+			if hint.Kind == InlayTarget {
+				t.Errorf("call target hinted %+v", hint)
+			}
+		}
+		if s := fmt.Sprint(got); s != test.want {
+			t.Errorf("hints in [%d, %d] %s, want %s", test.from, test.to, s, test.want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ide
+
+import (
+	"cee/ast"
+	"cee/resolve"
+	"cee/xref"
+	"sort"
+)
+
+type CallSite struct {
+	Caller *resolve.Object
+	Callee *resolve.Object
+	Span   xref.Span // the callee identifier at the call
+}
+
+type CallGraph struct {
+	Info  *resolve.Info
+	Sites []CallSite
+}
+
+// ObjectRef identifies an object by its declaring identifier, stable across resolutions of the same text.
+func (g *CallGraph) ObjectRef(obj *resolve.Object) resolve.Ref {
+	return g.Info.Ref(obj.File, obj.Ident.From)
+}
+
+// Call groups the call sites between a pair of functions.
+type Call struct {
+	Object *resolve.Object // the caller for incoming calls, the callee for outgoing calls
+	Sites  []xref.Span
+}
+
+type callCollector struct {
+	info   *resolve.Info
+	file   string
+	caller *resolve.Object
+	graph  *CallGraph
+}
+
+// BuildCallGraph records every call whose callee resolves to a function or extern declaration.
+func BuildCallGraph(files []resolve.File, info *resolve.Info) CallGraph {
+	g := CallGraph{Info: info}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.Value.(ast.FuncDecl)
+			if !ok || fn.Ident == nil || fn.Stmt == nil {
+				continue
+			}
+			caller, ok := info.Defs[info.Ref(file.Path, fn.Ident.From)]
+			if !ok {
+				continue
+			}
+			c := callCollector{info: info, file: file.Path, caller: caller, graph: &g}
+			c.block(*fn.Stmt)
+		}
+	}
+	return g
+}
+
+func (g *CallGraph) group(sites []CallSite, key func(CallSite) *resolve.Object) []Call {
+	var (
+		calls []Call
+		index = map[resolve.Ref]int{}
+	)
+	for _, site := range sites {
+		obj := key(site)
+		ref := g.ObjectRef(obj)
+		i, ok := index[ref]
+		if !ok {
+			i = len(calls)
+			index[ref] = i
+			calls = append(calls, Call{Object: obj})
+		}
+		calls[i].Sites = append(calls[i].Sites, site.Span)
+	}
+	sort.SliceStable(calls, func(i, j int) bool { return calls[i].Object.Name < calls[j].Object.Name })
+	return calls
+}
+
+// Incoming returns the callers of the function, each with its call sites.
+func (g *CallGraph) Incoming(callee resolve.Ref) []Call {
+	var sites []CallSite
+	for _, site := range g.Sites {
+		if g.ObjectRef(site.Callee) == callee {
+			sites = append(sites, site)
+		}
+	}
+	return g.group(sites, func(site CallSite) *resolve.Object { return site.Caller })
+}
+
+// Outgoing returns the functions called by the function, each with its call sites.
+func (g *CallGraph) Outgoing(caller resolve.Ref) []Call {
+	var sites []CallSite
+	for _, site := range g.Sites {
+		if g.ObjectRef(site.Caller) == caller {
+			sites = append(sites, site)
+		}
+	}
+	return g.group(sites, func(site CallSite) *resolve.Object { return site.Callee })
+}
+
+func (c *callCollector) block(b ast.StmtBlockExpr) {
+	for _, stmt := range b.Stmts {
+		c.stmt(stmt)
+	}
+}
+
+func (c *callCollector) stmt(s ast.Stmt) {
+	switch v := s.Value.(type) {
+	case ast.Expr:
+		c.expr(v)
+	case ast.ValDecl:
+		c.expr(v.Value)
+	case ast.ReturnStmt:
+		for _, expr := range v.Exprs {
+			c.expr(expr)
+		}
+	case ast.AssignStmt:
+		c.expr(v.ExprL)
+		c.expr(v.ExprR)
+	case ast.LoopStmt:
+		c.expr(v.Cond)
+		c.block(v.Stmt)
+	case ast.ForeachStmt:
+		c.expr(v.Expr)
+		c.block(v.Stmt)
+	case ast.EndlessForStmt:
+		c.block(v.Stmt)
+	case ast.DeferStmt:
+		c.expr(v.Expr)
+	case ast.FuncDecl:
+		// Calls in nested functions are attributed to the enclosing declaration.
+		if v.Stmt != nil {
+			c.block(*v.Stmt)
+		}
+	}
+}
+
+func (c *callCollector) expr(e ast.Expr) {
+	switch v := e.Value.(type) {
+	case ast.CallExpr:
+		if ident, ok := v.Callee.Value.(ast.Ident); ok {
+			if obj, ok := c.info.Uses[c.info.Ref(c.file, ident.From)]; ok {
+				if obj.Kind == resolve.ObjFunc || obj.Kind == resolve.ObjExtern {
+					c.graph.Sites = append(c.graph.Sites, CallSite{
+						Caller: c.caller,
+						Callee: obj,
+						Span:   xref.NewSpan(c.info.Files[c.file], ident.PosRange),
+					})
+				}
+			}
+		}
+		c.expr(v.Callee)
+		for _, param := range v.Params {
+			c.expr(param)
+		}
+	case ast.UnaryExpr:
+		c.expr(v.Expr)
+	case ast.BinaryExpr:
+		c.expr(v.Exprs[0])
+		c.expr(v.Exprs[1])
+	case ast.IndexExpr:
+		c.expr(v.Expr)
+		c.expr(v.Index)
+	case ast.MemberSelectExpr:
+		c.expr(v.Expr)
+	case ast.TryExpr:
+		c.expr(v.Expr)
+	case ast.IntrinsicExpr:
+		for _, param := range v.Params {
+			c.expr(param)
+		}
+	case ast.EllipsisExpr:
+		c.expr(v.Array)
+	case ast.CompositeLit:
+		for _, elem := range v.Elems {
+			c.expr(elem.Key)
+			c.expr(elem.Value)
+		}
+	case ast.InterpolatedString:
+		for _, expr := range v.Exprs {
+			c.expr(expr)
+		}
+	case ast.FuncLitExpr:
+		// Calls in closures are attributed to the enclosing function.
+		c.block(v.Body)
+	case ast.StmtBlockExpr:
+		c.block(v)
+	case ast.BranchExpr:
+		c.expr(v.Cond)
+		c.block(v.Branch)
+		c.block(v.ElseBranch)
+	case ast.MatchExpr:
+		c.expr(v.Subject)
+		for _, arm := range v.Arms {
+			c.expr(arm.Guard)
+			c.block(arm.Body)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ide
+
+import (
+	"cee/resolve"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const callsA = `package a
+
+fun h(y i64) i64 {
+	val k = fun [y]() i64 {
+		return f(y)
+	}
+	if y > 0 {
+		return abs(y)
+	}
+	return f(1) + k()
+}
+
+extern "C" fun abs(x i64) i64
+`
+
+func TestCallGraph(t *testing.T) {
+	files, info := resolveFiles(t, "a.cee", defA, "b.cee", defB, "c.cee", callsA)
+	g := BuildCallGraph(files, info)
+
+	ref := func(file, src, name string) resolve.Ref {
+		return g.ObjectRef(info.Defs[info.Ref(file, info.Pos(file, at(src, "fun "+name, 0)+4))])
+	}
+	calls := func(calls []Call) string {
+		var s []string
+		for _, call := range calls {
+			var sites []string
+			for _, site := range call.Sites {
+				sites = append(sites, fmt.Sprintf("%s:%d:%d", site.File, site.From.Line, site.From.Column))
+			}
+			s = append(s, call.Object.Name+" "+strings.Join(sites, " "))
+		}
+		return strings.Join(s, ", ")
+	}
+
+	// The variable k is not a function, calls in the closure belong to h.
+	for _, test := range []struct {
+		name, got, want string
+	}{
+		{"outgoing f", calls(g.Outgoing(ref("a.cee", defA, "f"))), "g a.cee:3:8 a.cee:3:15"},
+		{"outgoing h", calls(g.Outgoing(ref("c.cee", callsA, "h"))), "abs c.cee:7:9, f c.cee:4:9 c.cee:9:8"},
+		{"outgoing g", calls(g.Outgoing(ref("b.cee", defB, "g"))), ""},
+		{"incoming f", calls(g.Incoming(ref("a.cee", defA, "f"))), "h c.cee:4:9 c.cee:9:8"},
+		{"incoming abs", calls(g.Incoming(ref("c.cee", callsA, "abs"))), "h c.cee:7:9"},
+		{"incoming h", calls(g.Incoming(ref("c.cee", callsA, "h"))), ""},
+	} {
+		if test.got != test.want {
+			t.Errorf("%s = %q, want %q", test.name, test.got, test.want)
+		}
+	}
+}
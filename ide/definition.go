@@ -0,0 +1,76 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ide
+
+import (
+	"cee/ast"
+	"cee/resolve"
+	"cee/token"
+	"cee/xref"
+)
+
+// IdentAt finds the identifier of the file covering the offset, the end of an identifier counts as inside it.
+func IdentAt(info *resolve.Info, file string, offset int) (resolve.Ref, bool) {
+	for ref, pos := range info.Spans {
+		if ref.File == file && info.Offset(file, pos.From) <= offset && offset <= info.Offset(file, pos.To) {
+			return ref, true
+		}
+	}
+	return resolve.Ref{}, false
+}
+
+// ObjectAt returns the object an identifier at the offset declares or refers to.
+func ObjectAt(info *resolve.Info, file string, offset int) (*resolve.Object, bool) {
+	ref, ok := IdentAt(info, file, offset)
+	if !ok {
+		return nil, false
+	}
+	if obj, ok := info.Defs[ref]; ok {
+		return obj, true
+	}
+	obj, ok := info.Uses[ref]
+	return obj, ok
+}
+
+// Definition maps a cursor position to the declaring identifier of the symbol under it.
+func Definition(info *resolve.Info, file string, offset int) (xref.Span, bool) {
+	obj, ok := ObjectAt(info, file, offset)
+	if !ok {
+		return xref.Span{}, false
+	}
+	return xref.NewSpan(info.Files[obj.File], obj.Ident), true
+}
+
+// MemberDefinition maps a cursor on the member of a qualified identifier, `pkg.Member`, to the declaration
+// of the member in the index. The index keeps packages by directory, dir returns the directory of a package
+// by canonical name. Only types and functions are looked up.
+func MemberDefinition(idx *xref.Index, info *resolve.Info, file string, offset int, dir func(name string) (string, bool)) (xref.Span, bool) {
+	tok := info.Files[file]
+	for _, sel := range info.Selections {
+		pos := sel.Member.PosRange
+		if int(pos.From) < tok.Base() || int(pos.To) > tok.Base()+tok.Size() { // in another file
+			continue
+		}
+		if offset < tok.Offset(pos.From) || tok.Offset(pos.To) < offset {
+			continue
+		}
+		name, err := token.Unquote(sel.Import.Decl.(ast.ImportDecl).CanonicalName.Literal)
+		if err != nil {
+			return xref.Span{}, false
+		}
+		pkg, ok := dir(name)
+		if !ok {
+			return xref.Span{}, false
+		}
+		for _, id := range idx.ByName[sel.Member.Literal] {
+			sym := idx.Symbols[id]
+			if sym.Package == pkg && (sym.Kind == resolve.ObjType || sym.Kind == resolve.ObjFunc || sym.Kind == resolve.ObjExtern) {
+				return sym.Def, true
+			}
+		}
+		return xref.Span{}, false
+	}
+	return xref.Span{}, false
+}
@@ -0,0 +1,89 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ide
+
+import (
+	"cee/parser"
+	"cee/resolve"
+	"cee/token"
+	"cee/xref"
+	"strings"
+	"testing"
+)
+
+// resolveFiles parses and resolves the files of a package, given as path and source pairs.
+func resolveFiles(t *testing.T, files ...string) ([]resolve.File, *resolve.Info) {
+	t.Helper()
+	fset := token.NewFileSet()
+	var resolved []resolve.File
+	for i := 0; i < len(files); i += 2 {
+		f, err := parser.ParseFile(fset, files[i], []byte(files[i+1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resolved = append(resolved, resolve.File{Path: files[i], TokenFile: fset.File(f.From), Decls: f.Decls})
+	}
+	info := resolve.Resolve(resolved)
+	return resolved, &info
+}
+
+// at returns the offset of the n-th occurrence of s in src.
+func at(src, s string, n int) int {
+	offset := -1
+	for ; n >= 0; n-- {
+		offset += 1 + strings.Index(src[offset+1:], s)
+	}
+	return offset
+}
+
+const (
+	defA = "package a\n\nfun f(x i64) i64 {\n\treturn g(x) + g(1)\n}\n"
+	defB = "package a\n\nfun g(x i64) i64 { return x }\n"
+)
+
+func TestDefinition(t *testing.T) {
+	_, info := resolveFiles(t, "a.cee", defA, "b.cee", defB)
+
+	for _, test := range []struct {
+		offset     int
+		file       string
+		line, col  int
+		unresolved bool
+	}{
+		{offset: at(defA, "g(", 0), file: "b.cee", line: 2, col: 4},
+		{offset: at(defA, "g(", 1) + 1, file: "b.cee", line: 2, col: 4}, // the end of the identifier
+		{offset: at(defA, "x)", 0), file: "a.cee", line: 2, col: 6},
+		{offset: at(defA, "f(", 0), file: "a.cee", line: 2, col: 4}, // a declaration is its own definition
+		{offset: at(defA, "return", 0), unresolved: true},
+	} {
+		span, ok := Definition(info, "a.cee", test.offset)
+		if ok == test.unresolved || ok && (span.File != test.file || span.From.Line != test.line || span.From.Column != test.col) {
+			t.Errorf("Definition at %d = %+v, %v", test.offset, span, ok)
+		}
+	}
+}
+
+func TestMemberDefinition(t *testing.T) {
+	const (
+		lib  = "package lib\n\npub fun twice(x i64) i64 { return x * 2 }\n"
+		main = "package app\n\nimport \"m/lib\"\n\nfun f() i64 { return lib.twice(1) }\n"
+	)
+	_, libInfo := resolveFiles(t, "lib/lib.cee", lib)
+	_, info := resolveFiles(t, "app/main.cee", main)
+	idx := xref.NewIndex()
+	idx.Add("lib", *libInfo)
+	dir := func(name string) (string, bool) { return strings.TrimPrefix(name, "m/"), strings.HasPrefix(name, "m/") }
+
+	span, ok := MemberDefinition(idx, info, "app/main.cee", at(main, "twice", 0)+2, dir)
+	if !ok || span.File != "lib/lib.cee" || span.From.Line != 2 || span.From.Column != 8 {
+		t.Errorf("MemberDefinition = %+v, %v", span, ok)
+	}
+	if _, ok := MemberDefinition(idx, info, "app/main.cee", at(main, "lib.", 0), dir); ok {
+		t.Error("the package name resolved as a member")
+	}
+	if _, ok := MemberDefinition(idx, info, "app/main.cee", at(main, "twice", 0), func(string) (string, bool) { return "", false }); ok {
+		t.Error("member of an unknown package resolved")
+	}
+}
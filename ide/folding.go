@@ -0,0 +1,180 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ide
+
+import (
+	"cee/ast"
+	"cee/token"
+	"sort"
+	"strings"
+)
+
+type FoldingKind byte
+
+const (
+	_ FoldingKind = iota
+
+	FoldRegion
+	FoldComment
+	FoldImports
+)
+
+type FoldingRange struct {
+	From, To token.Position
+	Kind     FoldingKind
+}
+
+type folder struct {
+	file   *token.File
+	ranges []FoldingRange
+}
+
+// add records the range if it spans several lines, a single line is not worth folding.
+func (f *folder) add(pos ast.PosRange, kind FoldingKind) {
+	from, to := f.file.Position(pos.From), f.file.Position(pos.To)
+	// Ranges extend past the newline that follows them, they end on the line before.
+	if to.Column == 0 && pos.To > pos.From {
+		to = f.file.Position(pos.To - 1)
+	}
+	if to.Line > from.Line {
+		f.ranges = append(f.ranges, FoldingRange{From: from, To: to, Kind: kind})
+	}
+}
+
+// FoldingRanges computes the foldable regions of a file: blocks, struct and trait bodies,
+// runs of imports and multi-line comments. Ranges are ordered by start offset.
+func FoldingRanges(file *token.File, decls []ast.Stmt, comments []ast.Token) []FoldingRange {
+	f := folder{file: file}
+
+	var imports []ast.PosRange
+	flushImports := func() {
+		if len(imports) > 1 {
+			f.add(ast.PosRange{From: imports[0].From, To: imports[len(imports)-1].To}, FoldImports)
+		}
+		imports = imports[:0]
+	}
+	for _, decl := range decls {
+		if d, ok := decl.Value.(ast.ImportDecl); ok {
+			imports = append(imports, d.PosRange)
+			continue
+		}
+		flushImports()
+		f.stmt(decl)
+	}
+	flushImports()
+
+	f.comments(comments)
+
+	sort.SliceStable(f.ranges, func(i, j int) bool { return f.ranges[i].From.Offset < f.ranges[j].From.Offset })
+	return f.ranges
+}
+
+func (f *folder) comments(comments []ast.Token) {
+	for i := 0; i < len(comments); {
+		c := comments[i]
+		if strings.HasPrefix(c.Literal, "/*") {
+			f.add(c.PosRange, FoldComment)
+			i++
+			continue
+		}
+		// Consecutive line comments fold together.
+		j := i + 1
+		for j < len(comments) && strings.HasPrefix(comments[j].Literal, "//") &&
+			f.file.Position(comments[j].From).Line == f.file.Position(comments[j-1].From).Line+1 {
+			j++
+		}
+		f.add(ast.PosRange{From: c.From, To: comments[j-1].To}, FoldComment)
+		i = j
+	}
+}
+
+func (f *folder) typ(t ast.Type) {
+	switch v := t.Value.(type) {
+	case ast.StructType:
+		f.add(v.PosRange, FoldRegion)
+		for _, field := range v.Fields {
+			f.typ(field.Type)
+		}
+	case ast.TraitType:
+		f.add(v.PosRange, FoldRegion)
+	case ast.MapType:
+		f.typ(v.Key)
+		f.typ(v.Value)
+	case ast.ChanType:
+		f.typ(v.Elem)
+	case ast.PointerType:
+		f.typ(v.Elem)
+	case ast.ArrayType:
+		f.typ(v.Elem)
+	}
+}
+
+func (f *folder) block(b ast.StmtBlockExpr) {
+	f.add(b.PosRange, FoldRegion)
+	for _, stmt := range b.Stmts {
+		f.stmt(stmt)
+	}
+}
+
+func (f *folder) stmt(s ast.Stmt) {
+	switch v := s.Value.(type) {
+	case ast.Expr:
+		f.expr(v)
+	case ast.TypeDecl:
+		f.typ(v.Type)
+	case ast.GenDecl:
+		f.typ(v.Type)
+	case ast.ValDecl:
+		f.expr(v.Value)
+	case ast.FuncDecl:
+		if v.Stmt != nil {
+			f.block(*v.Stmt)
+		}
+	case ast.ReturnStmt:
+		for _, expr := range v.Exprs {
+			f.expr(expr)
+		}
+	case ast.AssignStmt:
+		f.expr(v.ExprR)
+	case ast.LoopStmt:
+		f.block(v.Stmt)
+	case ast.ForeachStmt:
+		f.block(v.Stmt)
+	case ast.EndlessForStmt:
+		f.block(v.Stmt)
+	case ast.DeferStmt:
+		f.expr(v.Expr)
+	}
+}
+
+func (f *folder) expr(e ast.Expr) {
+	switch v := e.Value.(type) {
+	case ast.StmtBlockExpr:
+		f.block(v)
+	case ast.BranchExpr:
+		f.block(v.Branch)
+		f.block(v.ElseBranch)
+	case ast.MatchExpr:
+		for _, arm := range v.Arms {
+			f.block(arm.Body)
+		}
+	case ast.CallExpr:
+		for _, param := range v.Params {
+			f.expr(param)
+		}
+	case ast.BinaryExpr:
+		f.expr(v.Exprs[0])
+		f.expr(v.Exprs[1])
+	case ast.UnaryExpr:
+		f.expr(v.Expr)
+	case ast.FuncLitExpr:
+		f.block(v.Body)
+	case ast.CompositeLit:
+		f.add(v.PosRange, FoldRegion)
+		for _, elem := range v.Elems {
+			f.expr(elem.Value)
+		}
+	}
+}
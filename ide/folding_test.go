@@ -0,0 +1,56 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ide
+
+import (
+	"cee/parser"
+	"cee/token"
+	"fmt"
+	"testing"
+)
+
+func TestFoldingRanges(t *testing.T) {
+	const src = `package a
+
+import "x"
+import "y"
+
+// one
+// two
+
+/* single */
+
+/*
+block
+*/
+type Point struct {
+	x i64
+}
+
+type ID struct { x i64 }
+
+fun f() i64 {
+	if true {
+		return 1
+	}
+	return 2
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.cee", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, r := range FoldingRanges(fset.File(f.From), f.Decls, f.Comments) {
+		got = append(got, fmt.Sprintf("%d:%d-%d", r.Kind, r.From.Line, r.To.Line))
+	}
+	// Lines are zero-based, the single-line struct and comment do not fold.
+	want := fmt.Sprint([]string{"3:2-3", "2:5-6", "2:10-12", "1:13-15", "1:19-24", "1:20-22"})
+	if fmt.Sprint(got) != want {
+		t.Errorf("FoldingRanges = %v, want %s", got, want)
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ide
+
+import (
+	"cee/ast"
+	"cee/hir"
+	"cee/resolve"
+	"cee/token"
+	"sort"
+)
+
+type InlayKind byte
+
+const (
+	_ InlayKind = iota
+
+	InlayType
+	InlayParameter
+	InlayTarget // the declaration a call resolves to, only rendered by Annotate
+)
+
+type InlayHint struct {
+	Offset int // the hint is displayed before the rune at this offset
+	Line   int
+	Column int
+	Label  string
+	Kind   InlayKind
+}
+
+type inlayCollector struct {
+	info       *resolve.Info
+	file       string
+	from, to   int
+	hints      []InlayHint
+	valDecls   map[token.Pos]ast.ValDecl
+	inferTypes map[token.Pos]ast.Type
+	targets    bool // hint the declarations calls resolve to
+}
+
+func (c *inlayCollector) add(pos ast.PosRange, label string, kind InlayKind, atEnd bool) {
+	p := c.info.Files[c.file].Position(pos.From)
+	if atEnd {
+		p = c.info.Files[c.file].Position(pos.To)
+	}
+	if p.Offset < c.from || p.Offset > c.to {
+		return
+	}
+	c.hints = append(c.hints, InlayHint{Offset: p.Offset, Line: p.Line, Column: p.Column, Label: label, Kind: kind})
+}
+
+// InlayHints emits parameter names at call arguments and the inferred type after `val x = …` names,
+// limited to hints positioned within [from, to].
+func InlayHints(decls []ast.Stmt, info *resolve.Info, file string, from, to int) []InlayHint {
+	c := newInlayCollector(info, file, from, to)
+	return c.collect(decls)
+}
+
+func newInlayCollector(info *resolve.Info, file string, from, to int) *inlayCollector {
+	return &inlayCollector{
+		info:       info,
+		file:       file,
+		from:       from,
+		to:         to,
+		valDecls:   map[token.Pos]ast.ValDecl{},
+		inferTypes: map[token.Pos]ast.Type{},
+	}
+}
+
+func (c *inlayCollector) collect(decls []ast.Stmt) []InlayHint {
+	for _, decl := range decls {
+		fn, ok := decl.Value.(ast.FuncDecl)
+		if !ok || fn.Stmt == nil {
+			continue
+		}
+		l := hir.NewLowerer()
+		c.lets(l.LowerFunc(fn))
+		c.block(*fn.Stmt)
+	}
+
+	for pos, d := range c.valDecls {
+		if typ, ok := c.inferTypes[pos]; ok && typ.Tag != 0 {
+			c.add(d.Name.PosRange, ": "+ast.TypeString(typ), InlayType, true)
+		}
+	}
+
+	sort.SliceStable(c.hints, func(i, j int) bool { return c.hints[i].Offset < c.hints[j].Offset })
+	return c.hints
+}
+
+// lets collects the types inferred by the lowering, keyed by the position of the declaration.
+func (c *inlayCollector) lets(b hir.Block) {
+	for _, stmt := range b.Stmts {
+		switch v := stmt.Value.(type) {
+		case hir.LetStmt:
+			c.inferTypes[v.From] = v.Type
+		case hir.LoopStmt:
+			c.lets(v.Body)
+		case hir.Expr:
+			c.letsExpr(v)
+		}
+	}
+}
+
+func (c *inlayCollector) letsExpr(e hir.Expr) {
+	switch v := e.Value.(type) {
+	case hir.Block:
+		c.lets(v)
+	case hir.IfExpr:
+		c.lets(v.Then)
+		if v.Else != nil {
+			c.lets(*v.Else)
+		}
+	}
+}
+
+func (c *inlayCollector) block(b ast.StmtBlockExpr) {
+	for _, stmt := range b.Stmts {
+		c.stmt(stmt)
+	}
+}
+
+func (c *inlayCollector) stmt(s ast.Stmt) {
+	switch v := s.Value.(type) {
+	case ast.Expr:
+		c.expr(v)
+	case ast.ValDecl:
+		if v.Type.Value == nil {
+			c.valDecls[v.From] = v
+		}
+		c.expr(v.Value)
+	case ast.ReturnStmt:
+		for _, expr := range v.Exprs {
+			c.expr(expr)
+		}
+	case ast.AssignStmt:
+		c.expr(v.ExprL)
+		c.expr(v.ExprR)
+	case ast.LoopStmt:
+		c.expr(v.Cond)
+		c.block(v.Stmt)
+	case ast.ForeachStmt:
+		c.expr(v.Expr)
+		c.block(v.Stmt)
+	case ast.EndlessForStmt:
+		c.block(v.Stmt)
+	case ast.DeferStmt:
+		c.expr(v.Expr)
+	}
+}
+
+func (c *inlayCollector) expr(e ast.Expr) {
+	switch v := e.Value.(type) {
+	case ast.CallExpr:
+		c.call(v)
+		c.expr(v.Callee)
+		for _, param := range v.Params {
+			c.expr(param)
+		}
+	case ast.UnaryExpr:
+		c.expr(v.Expr)
+	case ast.BinaryExpr:
+		c.expr(v.Exprs[0])
+		c.expr(v.Exprs[1])
+	case ast.IndexExpr:
+		c.expr(v.Expr)
+		c.expr(v.Index)
+	case ast.MemberSelectExpr:
+		c.expr(v.Expr)
+	case ast.TryExpr:
+		c.expr(v.Expr)
+	case ast.IntrinsicExpr:
+		for _, param := range v.Params {
+			c.expr(param)
+		}
+	case ast.StmtBlockExpr:
+		c.block(v)
+	case ast.BranchExpr:
+		c.expr(v.Cond)
+		c.block(v.Branch)
+		c.block(v.ElseBranch)
+	}
+}
+
+func (c *inlayCollector) call(e ast.CallExpr) {
+	callee, ok := e.Callee.Value.(ast.Ident)
+	if !ok {
+		return
+	}
+	obj, ok := c.info.Uses[c.info.Ref(c.file, callee.From)]
+	if !ok {
+		return
+	}
+	if c.targets {
+		if tf, ok := c.info.Files[obj.File]; ok {
+			c.add(callee.PosRange, "-> "+tf.Position(obj.Ident.From).String(), InlayTarget, true)
+		}
+	}
+	typ, ok := funcTypeOf(obj)
+	if !ok {
+		return
+	}
+
+	var names []string
+	for _, param := range typ.Params {
+		for _, ident := range param.Idents {
+			names = append(names, ident.Literal)
+		}
+	}
+
+	for i, arg := range e.Params {
+		if i >= len(names) {
+			break
+		}
+		// An argument named like the parameter needs no hint.
+		if ident, ok := arg.Value.(ast.Ident); ok && ident.Literal == names[i] {
+			continue
+		}
+		c.add(arg.GetPosRange(), names[i]+":", InlayParameter, false)
+	}
+}
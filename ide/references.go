@@ -0,0 +1,42 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ide
+
+import (
+	"cee/resolve"
+	"cee/xref"
+	"sort"
+)
+
+// SymbolAt returns the index identifier of the symbol under the cursor.
+func SymbolAt(info *resolve.Info, pkg string, file string, offset int) (xref.SymbolID, bool) {
+	obj, ok := ObjectAt(info, file, offset)
+	if !ok {
+		return "", false
+	}
+	return xref.NewSymbolID(pkg, obj.File, info.Offset(obj.File, obj.Ident.From)), true
+}
+
+// References returns every reference to the symbol across the index, ordered by file and offset.
+// The declaring identifier is included first when includeDecl is set.
+func References(idx *xref.Index, id xref.SymbolID, includeDecl bool) []xref.Span {
+	sym, ok := idx.Symbols[id]
+	if !ok {
+		return nil
+	}
+
+	refs := append([]xref.Span{}, sym.Refs...)
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].File != refs[j].File {
+			return refs[i].File < refs[j].File
+		}
+		return refs[i].From.Offset < refs[j].From.Offset
+	})
+
+	if includeDecl {
+		refs = append([]xref.Span{sym.Def}, refs...)
+	}
+	return refs
+}
@@ -0,0 +1,57 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ide
+
+import (
+	"cee/xref"
+	"fmt"
+	"testing"
+)
+
+func TestReferences(t *testing.T) {
+	// b.cee sorts after a.cee, but is resolved first.
+	_, info := resolveFiles(t, "b.cee", defB, "a.cee", defA)
+	idx := xref.NewIndex()
+	idx.Add("a", *info)
+
+	positions := func(spans []xref.Span) string {
+		var s []string
+		for _, span := range spans {
+			s = append(s, fmt.Sprintf("%s:%d:%d", span.File, span.From.Line, span.From.Column))
+		}
+		return fmt.Sprint(s)
+	}
+
+	// From a reference and from the declaration alike.
+	for _, offset := range []int{at(defA, "g(", 1), -1} {
+		file := "a.cee"
+		if offset < 0 {
+			file, offset = "b.cee", at(defB, "g(", 0)
+		}
+		id, ok := SymbolAt(info, "a", file, offset)
+		if !ok {
+			t.Fatalf("no symbol at %s:%d", file, offset)
+		}
+		if got := positions(References(idx, id, false)); got != "[a.cee:3:8 a.cee:3:15]" {
+			t.Errorf("references from %s:%d = %s", file, offset, got)
+		}
+		if got := positions(References(idx, id, true)); got != "[b.cee:2:4 a.cee:3:8 a.cee:3:15]" {
+			t.Errorf("references with the declaration from %s:%d = %s", file, offset, got)
+		}
+	}
+
+	// Each parameter x is a symbol of its own.
+	id, _ := SymbolAt(info, "a", "a.cee", at(defA, "x)", 0))
+	if got := positions(References(idx, id, true)); got != "[a.cee:2:6 a.cee:3:10]" {
+		t.Errorf("references to x = %s", got)
+	}
+
+	if _, ok := SymbolAt(info, "a", "a.cee", at(defA, "return", 0)); ok {
+		t.Error("symbol at a keyword")
+	}
+	if refs := References(idx, "a:c.cee:0", true); refs != nil {
+		t.Errorf("references to an unknown symbol %v", refs)
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ide
+
+import (
+	"cee/ast"
+	"cee/token"
+	"sort"
+)
+
+type selector struct {
+	pos    token.Pos
+	ranges []ast.PosRange
+}
+
+func (s *selector) add(pos ast.PosRange) bool {
+	if pos.From <= s.pos && s.pos <= pos.To && pos.From < pos.To {
+		s.ranges = append(s.ranges, pos)
+		return true
+	}
+	return false
+}
+
+// SelectionRanges returns the ranges enclosing the position, innermost first:
+// the token, the enclosing expressions, statements and declarations, and finally the whole file.
+func SelectionRanges(decls []ast.Stmt, toks []ast.Token, pos token.Pos) []ast.PosRange {
+	s := selector{pos: pos}
+
+	// The token under the position, or the one ending there.
+	for i, tok := range toks {
+		if tok.From <= pos && pos < tok.To || tok.To == pos && (i+1 == len(toks) || toks[i+1].From != pos) {
+			s.add(tok.PosRange)
+			break
+		}
+	}
+	for _, decl := range decls {
+		s.stmt(decl)
+	}
+	if len(toks) != 0 {
+		s.add(ast.PosRange{From: toks[0].From, To: toks[len(toks)-1].To})
+	}
+
+	sort.SliceStable(s.ranges, func(i, j int) bool {
+		return s.ranges[i].To-s.ranges[i].From < s.ranges[j].To-s.ranges[j].From
+	})
+
+	// Nodes sharing a range, e.g. an identifier and its token, are a single step.
+	var result []ast.PosRange
+	for _, pos := range s.ranges {
+		if n := len(result); n == 0 || result[n-1] != pos {
+			result = append(result, pos)
+		}
+	}
+	return result
+}
+
+func (s *selector) typ(t ast.Type) {
+	if t.Value == nil || !s.add(t.GetPosRange()) {
+		return
+	}
+	switch v := t.Value.(type) {
+	case ast.StructType:
+		for _, field := range v.Fields {
+			s.genDecl(field)
+		}
+	case ast.TraitType:
+		for _, method := range v.Methods {
+			if s.add(method.PosRange) {
+				s.add(method.Ident.PosRange)
+				s.funcType(method.Type)
+			}
+		}
+	case ast.FuncType:
+		s.funcType(v)
+	case ast.MapType:
+		s.typ(v.Key)
+		s.typ(v.Value)
+	case ast.ChanType:
+		s.typ(v.Elem)
+	case ast.PointerType:
+		s.typ(v.Elem)
+	case ast.ArrayType:
+		s.expr(v.Len)
+		s.typ(v.Elem)
+	}
+}
+
+func (s *selector) genDecl(d ast.GenDecl) {
+	if !s.add(d.PosRange) {
+		return
+	}
+	for _, ident := range d.Idents {
+		s.add(ident.PosRange)
+	}
+	s.typ(d.Type)
+}
+
+func (s *selector) funcType(t ast.FuncType) {
+	if !s.add(t.PosRange) {
+		return
+	}
+	for _, param := range t.Params {
+		s.genDecl(param)
+	}
+	for _, result := range t.Results {
+		s.typ(result)
+	}
+}
+
+func (s *selector) block(b ast.StmtBlockExpr) {
+	if !s.add(b.PosRange) {
+		return
+	}
+	for _, stmt := range b.Stmts {
+		s.stmt(stmt)
+	}
+}
+
+func (s *selector) stmt(stmt ast.Stmt) {
+	if stmt.Value == nil {
+		return
+	}
+	if e, ok := stmt.Value.(ast.Expr); ok {
+		s.expr(e)
+		return
+	}
+	if !s.add(stmt.GetPosRange()) {
+		return
+	}
+
+	switch v := stmt.Value.(type) {
+	case ast.ImportDecl:
+		s.add(v.CanonicalName.PosRange)
+	case ast.ValDecl:
+		if v.Pattern != nil {
+			s.add(v.Pattern.GetPosRange())
+		}
+		for _, ident := range v.Idents() {
+			s.add(ident.PosRange)
+		}
+		s.expr(v.Value)
+	case ast.GenDecl:
+		s.genDecl(v)
+	case ast.TypeDecl:
+		s.add(v.Ident.PosRange)
+		s.typ(v.Type)
+	case ast.FuncDecl:
+		if v.Ident != nil {
+			s.add(v.Ident.PosRange)
+		}
+		for _, capture := range v.Captures {
+			if s.add(capture.PosRange) {
+				s.add(capture.Ident.PosRange)
+			}
+		}
+		s.funcType(v.Type)
+		if v.Stmt != nil {
+			s.block(*v.Stmt)
+		}
+	case ast.ExternDecl:
+		s.add(v.Ident.PosRange)
+		s.funcType(v.Type)
+	case ast.ReturnStmt:
+		for _, expr := range v.Exprs {
+			s.expr(expr)
+		}
+	case ast.AssignStmt:
+		s.expr(v.ExprL)
+		s.expr(v.ExprR)
+	case ast.LoopStmt:
+		s.expr(v.Cond)
+		s.block(v.Stmt)
+	case ast.ForeachStmt:
+		for _, ident := range v.IdentList {
+			s.add(ident.PosRange)
+		}
+		s.expr(v.Expr)
+		s.block(v.Stmt)
+	case ast.EndlessForStmt:
+		s.block(v.Stmt)
+	case ast.DeferStmt:
+		s.expr(v.Expr)
+	}
+}
+
+func (s *selector) expr(e ast.Expr) {
+	if e.Value == nil || !s.add(e.GetPosRange()) {
+		return
+	}
+
+	switch v := e.Value.(type) {
+	case ast.UnaryExpr:
+		s.expr(v.Expr)
+	case ast.BinaryExpr:
+		s.expr(v.Exprs[0])
+		s.expr(v.Exprs[1])
+	case ast.EllipsisExpr:
+		s.expr(v.Array)
+	case ast.CallExpr:
+		s.expr(v.Callee)
+		for _, param := range v.Params {
+			s.expr(param)
+		}
+	case ast.IndexExpr:
+		s.expr(v.Expr)
+		s.expr(v.Index)
+	case ast.BranchExpr:
+		s.expr(v.Cond)
+		s.block(v.Branch)
+		s.block(v.ElseBranch)
+	case ast.MatchExpr:
+		s.expr(v.Subject)
+		for _, arm := range v.Arms {
+			if !s.add(arm.PosRange) {
+				continue
+			}
+			s.add(arm.Pattern.GetPosRange())
+			s.expr(arm.Guard)
+			s.block(arm.Body)
+		}
+	case ast.StmtBlockExpr:
+		for _, stmt := range v.Stmts {
+			s.stmt(stmt)
+		}
+	case ast.MemberSelectExpr:
+		s.expr(v.Expr)
+		s.add(v.Member.PosRange)
+	case ast.QualifiedIdent:
+		s.add(v.Package.PosRange)
+		s.add(v.Member.PosRange)
+	case ast.TryExpr:
+		s.expr(v.Expr)
+	case ast.IntrinsicExpr:
+		for _, param := range v.Params {
+			s.expr(param)
+		}
+	case ast.TypeExpr:
+		s.typ(v.Type)
+	case ast.InterpolatedString:
+		for _, expr := range v.Exprs {
+			s.expr(expr)
+		}
+	case ast.FuncLitExpr:
+		for _, capture := range v.Captures {
+			if s.add(capture.PosRange) {
+				s.add(capture.Ident.PosRange)
+			}
+		}
+		s.funcType(v.Type)
+		s.block(v.Body)
+	case ast.CompositeLit:
+		s.typ(v.Type)
+		for _, elem := range v.Elems {
+			if s.add(elem.PosRange) {
+				s.expr(elem.Key)
+				s.expr(elem.Value)
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ide
+
+import (
+	"cee/parser"
+	"cee/token"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const (
+	fBlock = "{\n\tval b = g(a + 1, -p.x)\n\tif b > 0 {\n\t\treturn b * 2\n\t}\n\treturn b\n}"
+	fBody  = "fun f(a i64, p Point) i64 " + fBlock
+	hBlock = "{\n\t@intrinsic.trap()\n\tval k = fun [&a]() {}\n}"
+)
+
+func TestSelectionRanges(t *testing.T) {
+	const src = `package a
+
+import "fmt"
+
+type Point struct {
+	x i64
+	y i64
+}
+
+fun f(a i64, p Point) i64 {
+	val b = g(a + 1, -p.x)
+	if b > 0 {
+		return b * 2
+	}
+	return b
+}
+
+fun h() {
+	@intrinsic.trap()
+	val k = fun [&a]() {}
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.cee", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tf := fset.File(f.From)
+	toks, err := parser.ScanAll(tf, []rune(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each position expands from its token to the declaration, the whole file is the last step.
+	for _, test := range []struct {
+		at   string
+		want []string
+	}{
+		{`"fmt"`, []string{`"fmt"`, `import "fmt"`}},
+		{"y i64", []string{"y", "y i64", "struct {\n\tx i64\n\ty i64\n}", "type Point struct {\n\tx i64\n\ty i64\n}"}},
+		{"p Point", []string{"p", "p Point", "(a i64, p Point) i64", fBody}},
+		{"a + 1", []string{"a", "a + 1", "g(a + 1, -p.x)", "val b = g(a + 1, -p.x)", fBlock, fBody}},
+		{"x)", []string{"x", "p.x", "-p.x", "g(a + 1, -p.x)", "val b = g(a + 1, -p.x)", fBlock, fBody}},
+		{"> 0", []string{">", "b > 0", "if b > 0 {\n\t\treturn b * 2\n\t}", fBlock, fBody}},
+		{"b * 2", []string{"b", "b * 2", "return b * 2", "{\n\t\treturn b * 2\n\t}", "if b > 0 {\n\t\treturn b * 2\n\t}", fBlock, fBody}},
+		{"trap", []string{"trap", "@intrinsic.trap()", hBlock, "fun h() " + hBlock}},
+		{"a]", []string{"a", "&a", "fun [&a]() {}", "val k = fun [&a]() {}", hBlock, "fun h() " + hBlock}},
+	} {
+		var got []string
+		for _, r := range SelectionRanges(f.Decls, toks, tf.Pos(at(src, test.at, 0))) {
+			got = append(got, src[tf.Offset(r.From):tf.Offset(r.To)])
+		}
+		want := append(test.want, strings.TrimSpace(src))
+		if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", want) {
+			t.Errorf("selection ranges at %q =\n%q\nwant\n%q", test.at, got, want)
+		}
+	}
+}
+
+// The ranges of nodes are those of their tokens, parenthesized operands and statements starting with a
+// keyword included.
+func TestSelectionRanges_NodeRanges(t *testing.T) {
+	const src = `package a
+
+fun f(xs []i64) i64 {
+	var n = 0
+	for x in xs {
+		n += (x + 1) * 2
+	}
+	return n
+}
+`
+	const (
+		loop  = "for x in xs {\n\t\tn += (x + 1) * 2\n\t}"
+		block = "{\n\tvar n = 0\n\t" + loop + "\n\treturn n\n}"
+		body  = "fun f(xs []i64) i64 " + block
+	)
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.cee", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tf := fset.File(f.From)
+	toks, err := parser.ScanAll(tf, []rune(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		at   string
+		want []string
+	}{
+		{"x + 1", []string{"x", "x + 1", "(x + 1) * 2", "n += (x + 1) * 2", "{\n\t\tn += (x + 1) * 2\n\t}", loop, block, body}},
+		{"2\n", []string{"2", "(x + 1) * 2", "n += (x + 1) * 2", "{\n\t\tn += (x + 1) * 2\n\t}", loop, block, body}},
+		{"0\n", []string{"0", "var n = 0", block, body}},
+		{"n\n}", []string{"n", "return n", block, body}},
+	} {
+		var got []string
+		for _, r := range SelectionRanges(f.Decls, toks, tf.Pos(at(src, test.at, 0))) {
+			got = append(got, src[tf.Offset(r.From):tf.Offset(r.To)])
+		}
+		want := append(test.want, strings.TrimSpace(src))
+		if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", want) {
+			t.Errorf("selection ranges at %q =\n%q\nwant\n%q", test.at, got, want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ide
+
+import (
+	"cee/ast"
+	"cee/parser"
+	"cee/resolve"
+	"cee/token"
+)
+
+type ParamInfo struct {
+	Label string
+}
+
+type SignatureHelp struct {
+	Label           string
+	Params          []ParamInfo
+	ActiveParameter int
+}
+
+type call struct {
+	callee ast.Token // last identifier before the parenthesis
+	commas int
+}
+
+// enclosingCall finds the innermost unclosed call parenthesis before the position.
+// Only tokens are used, so it works on code the parser cannot make sense of.
+func enclosingCall(toks []ast.Token, pos token.Pos) (call, bool) {
+	type open struct {
+		kind int
+		call call
+		ok   bool
+	}
+
+	var stack []open
+	for i, tok := range toks {
+		if tok.From >= pos {
+			break
+		}
+		switch tok.Kind {
+		case token.LPAREN, token.LBRACK, token.LBRACE:
+			o := open{kind: tok.Kind}
+			if tok.Kind == token.LPAREN && i > 0 && toks[i-1].Kind == token.IDENT {
+				o.call, o.ok = call{callee: toks[i-1]}, true
+			}
+			stack = append(stack, o)
+		case token.RPAREN, token.RBRACK, token.RBRACE:
+			if len(stack) != 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case token.COMMA:
+			if len(stack) != 0 {
+				stack[len(stack)-1].call.commas++
+			}
+		}
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].kind == token.LPAREN {
+			return stack[i].call, stack[i].ok
+		}
+		if stack[i].kind == token.LBRACE {
+			// A block between the cursor and a parenthesis means the call is not the innermost context.
+			break
+		}
+	}
+	return call{}, false
+}
+
+func funcTypeOf(obj *resolve.Object) (ast.FuncType, bool) {
+	switch d := obj.Decl.(type) {
+	case ast.FuncDecl:
+		return d.Type, true
+	case ast.ExternDecl:
+		return d.Type, true
+	}
+	return ast.FuncType{}, false
+}
+
+// Signature reports the parameters of the call enclosing the offset and the parameter being typed.
+func Signature(src []rune, info *resolve.Info, file string, offset int) (SignatureHelp, bool) {
+	tf, ok := info.Files[file]
+	if !ok {
+		return SignatureHelp{}, false
+	}
+	// Input the scanner rejects is skipped, so calls following broken code are still found.
+	toks, _ := parser.ScanRecover(tf, src)
+
+	c, ok := enclosingCall(toks, tf.Pos(offset))
+	if !ok {
+		return SignatureHelp{}, false
+	}
+	obj, ok := ObjectAt(info, file, tf.Offset(c.callee.From))
+	if !ok {
+		return SignatureHelp{}, false
+	}
+	typ, ok := funcTypeOf(obj)
+	if !ok {
+		return SignatureHelp{}, false
+	}
+
+	help := SignatureHelp{Label: ast.FuncString(obj.Name, typ), ActiveParameter: c.commas}
+	for _, param := range typ.Params {
+		for _, ident := range param.Idents {
+			help.Params = append(help.Params, ParamInfo{Label: ident.Literal + " " + ast.TypeString(param.Type)})
+		}
+	}
+	if help.ActiveParameter >= len(help.Params) && len(help.Params) != 0 {
+		help.ActiveParameter = len(help.Params) - 1
+	}
+	return help, true
+}
@@ -0,0 +1,56 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ide
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSignature(t *testing.T) {
+	const src = `package a
+
+fun add(a i64, b i64) i64 { return a + b }
+
+extern "C" fun abs(x i64) i64
+
+fun f(n i64) i64 {
+	val m = add(1, add(n, 3), 5)
+	return abs({ val y = 2
+		y }) + n
+}
+`
+	_, info := resolveFiles(t, "a.cee", src)
+	for _, test := range []struct {
+		offset int
+		want   string // label, parameters and the active one, empty for none
+	}{
+		{at(src, "(1,", 0) + 1, "fun add(a i64, b i64) i64 [a i64 b i64] 0"},
+		{at(src, " add(n", 0), "fun add(a i64, b i64) i64 [a i64 b i64] 1"},
+		{at(src, "3)", 0), "fun add(a i64, b i64) i64 [a i64 b i64] 1"}, // the inner call
+		{at(src, "5)", 0), "fun add(a i64, b i64) i64 [a i64 b i64] 1"}, // past the last parameter
+		{at(src, "({", 0) + 1, "fun abs(x i64) i64 [x i64] 0"},
+		{at(src, "val y", 0), ""}, // in a block within the call
+		{at(src, "val m", 0), ""},
+		{at(src, "+ n", 0), ""},
+	} {
+		help, ok := Signature([]rune(src), info, "a.cee", test.offset)
+		got := ""
+		if ok {
+			var params []string
+			for _, p := range help.Params {
+				params = append(params, p.Label)
+			}
+			got = fmt.Sprintf("%s %v %d", help.Label, params, help.ActiveParameter)
+		}
+		if got != test.want {
+			t.Errorf("Signature at %q = %q, want %q", src[test.offset:test.offset+5], got, test.want)
+		}
+	}
+
+	if _, ok := Signature([]rune(src), info, "b.cee", 0); ok {
+		t.Error("signature in an unknown file")
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ide
+
+import (
+	"cee/ast"
+)
+
+type SymbolKind byte
+
+const (
+	_ SymbolKind = iota
+
+	SymbolType
+	SymbolStruct
+	SymbolTrait
+	SymbolField
+	SymbolFunc
+	SymbolExtern
+	SymbolVar
+)
+
+type DocumentSymbol struct {
+	Name   string
+	Kind   SymbolKind
+	Detail string
+
+	Range     ast.PosRange // the whole declaration
+	Selection ast.PosRange // the declaring identifier
+
+	Children []DocumentSymbol
+}
+
+// DocumentSymbols produces the outline of a file: declarations in source order, struct fields nested in their types.
+func DocumentSymbols(decls []ast.Stmt) []DocumentSymbol {
+	var symbols []DocumentSymbol
+	for _, decl := range decls {
+		symbols = append(symbols, declSymbols(decl)...)
+	}
+	return symbols
+}
+
+func declSymbols(decl ast.Stmt) []DocumentSymbol {
+	switch d := decl.Value.(type) {
+	case ast.TypeDecl:
+		sym := DocumentSymbol{
+			Name:      d.Ident.Literal,
+			Kind:      SymbolType,
+			Range:     d.PosRange,
+			Selection: d.Ident.PosRange,
+		}
+		switch t := d.Type.Value.(type) {
+		case ast.StructType:
+			sym.Kind = SymbolStruct
+			sym.Children = fieldSymbols(t)
+		case ast.TraitType:
+			sym.Kind = SymbolTrait
+			sym.Children = methodSymbols(t)
+		default:
+			sym.Detail = ast.TypeString(d.Type)
+		}
+		return []DocumentSymbol{sym}
+	case ast.FuncDecl:
+		if d.Ident == nil {
+			return nil
+		}
+		return []DocumentSymbol{{
+			Name:      d.Ident.Literal,
+			Kind:      SymbolFunc,
+			Detail:    ast.FuncString("", d.Type),
+			Range:     d.PosRange,
+			Selection: d.Ident.PosRange,
+		}}
+	case ast.ExternDecl:
+		return []DocumentSymbol{{
+			Name:      d.Ident.Literal,
+			Kind:      SymbolExtern,
+			Detail:    ast.FuncString("", d.Type),
+			Range:     d.PosRange,
+			Selection: d.Ident.PosRange,
+		}}
+	case ast.GenDecl:
+		var symbols []DocumentSymbol
+		for _, ident := range d.Idents {
+			symbols = append(symbols, DocumentSymbol{
+				Name:      ident.Literal,
+				Kind:      SymbolVar,
+				Detail:    ast.TypeString(d.Type),
+				Range:     d.PosRange,
+				Selection: ident.PosRange,
+			})
+		}
+		return symbols
+	case ast.ValDecl:
+		var symbols []DocumentSymbol
+		for _, ident := range d.Idents() {
+			symbols = append(symbols, DocumentSymbol{
+				Name:      ident.Literal,
+				Kind:      SymbolVar,
+				Range:     d.PosRange,
+				Selection: ident.PosRange,
+			})
+		}
+		return symbols
+	}
+	return nil
+}
+
+func methodSymbols(t ast.TraitType) []DocumentSymbol {
+	var symbols []DocumentSymbol
+	for _, method := range t.Methods {
+		symbols = append(symbols, DocumentSymbol{
+			Name:      method.Ident.Literal,
+			Kind:      SymbolFunc,
+			Detail:    ast.FuncString("", method.Type),
+			Range:     method.PosRange,
+			Selection: method.Ident.PosRange,
+		})
+	}
+	return symbols
+}
+
+func fieldSymbols(t ast.StructType) []DocumentSymbol {
+	var symbols []DocumentSymbol
+	for _, field := range t.Fields {
+		if len(field.Idents) == 0 {
+			// Embedded field, named after its type.
+			symbols = append(symbols, DocumentSymbol{
+				Name:      ast.TypeString(field.Type),
+				Kind:      SymbolField,
+				Range:     field.PosRange,
+				Selection: field.PosRange,
+			})
+			continue
+		}
+		for _, ident := range field.Idents {
+			sym := DocumentSymbol{
+				Name:      ident.Literal,
+				Kind:      SymbolField,
+				Detail:    ast.TypeString(field.Type),
+				Range:     field.PosRange,
+				Selection: ident.PosRange,
+			}
+			if nested, ok := field.Type.Value.(ast.StructType); ok {
+				sym.Detail = "struct"
+				sym.Children = fieldSymbols(nested)
+			}
+			symbols = append(symbols, sym)
+		}
+	}
+	return symbols
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ide
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDocumentSymbols(t *testing.T) {
+	const src = `package a
+
+type Point struct {
+	x, y i64
+	inner struct {
+		z i64
+	}
+	Base
+}
+
+type Reader trait {
+	Read(n i64) i64
+}
+
+type ID i64
+
+fun area(p Point) i64 { return p.x * p.y }
+
+extern "C" fun abs(x i64) i64
+
+val origin = 1
+`
+	decls, info := resolveSource(t, "a.cee", src)
+
+	var b strings.Builder
+	var outline func(symbols []DocumentSymbol, indent string)
+	outline = func(symbols []DocumentSymbol, indent string) {
+		for _, sym := range symbols {
+			fmt.Fprintf(&b, "%s%d %s %s\n", indent, sym.Kind, sym.Name, sym.Detail)
+			outline(sym.Children, indent+"\t")
+		}
+	}
+	symbols := DocumentSymbols(decls)
+	outline(symbols, "")
+
+	want := fmt.Sprintf(`%d Point 
+	%d x i64
+	%d y i64
+	%d inner struct
+		%d z i64
+	%d Base 
+%d Reader 
+	%d Read fun (n i64) i64
+%d ID i64
+%d area fun (p Point) i64
+%d abs fun (x i64) i64
+%d origin 
+`, SymbolStruct, SymbolField, SymbolField, SymbolField, SymbolField, SymbolField,
+		SymbolTrait, SymbolFunc, SymbolType, SymbolFunc, SymbolExtern, SymbolVar)
+	if got := b.String(); got != want {
+		t.Errorf("outline\n%s\nwant\n%s", got, want)
+	}
+
+	// The selection is the declaring identifier, within the range of the declaration.
+	tf := info.Files["a.cee"]
+	for _, sym := range []DocumentSymbol{symbols[0], symbols[0].Children[1], symbols[3]} {
+		from, to := tf.Offset(sym.Selection.From), tf.Offset(sym.Selection.To)
+		if src[from:to] != sym.Name || sym.Selection.From < sym.Range.From || sym.Selection.To > sym.Range.To {
+			t.Errorf("%s selects %q of range %+v", sym.Name, src[from:to], sym.Range)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ide
+
+import (
+	"cee/resolve"
+	"cee/xref"
+	"sort"
+	"unicode"
+)
+
+// FuzzyScore matches the query as a case-insensitive subsequence of the name.
+// Matches at the start, at word boundaries and consecutive runs score higher, exact case adds a little.
+// It returns false if the query is not a subsequence.
+func FuzzyScore(query, name string) (int, bool) {
+	q, n := []rune(query), []rune(name)
+	score, qi, prev := 0, 0, -2
+
+	for ni := 0; ni < len(n) && qi < len(q); ni++ {
+		if unicode.ToLower(n[ni]) != unicode.ToLower(q[qi]) {
+			continue
+		}
+		switch {
+		case ni == 0:
+			score += 8
+		case ni == prev+1:
+			score += 5
+		case n[ni-1] == '_' || unicode.IsUpper(n[ni]) && unicode.IsLower(n[ni-1]):
+			score += 4
+		default:
+			score += 1
+		}
+		if n[ni] == q[qi] {
+			score++
+		}
+		prev = ni
+		qi++
+	}
+
+	if qi != len(q) {
+		return 0, false
+	}
+	// Shorter names are closer to the query.
+	return score*16 - len(n), true
+}
+
+func searchable(kind resolve.ObjKind) bool {
+	return kind == resolve.ObjType || kind == resolve.ObjFunc || kind == resolve.ObjExtern
+}
+
+// WorkspaceSymbols searches the declarations of the index by fuzzy name match, best matches first.
+// A limit of zero returns every match.
+func WorkspaceSymbols(idx *xref.Index, query string, limit int) []*xref.Symbol {
+	type match struct {
+		sym   *xref.Symbol
+		score int
+	}
+
+	var matches []match
+	for name, ids := range idx.ByName {
+		score, ok := FuzzyScore(query, name)
+		if !ok {
+			continue
+		}
+		for _, id := range ids {
+			if sym := idx.Symbols[id]; sym != nil && searchable(sym.Kind) {
+				matches = append(matches, match{sym: sym, score: score})
+			}
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.sym.Name != b.sym.Name {
+			return a.sym.Name < b.sym.Name
+		}
+		return a.sym.ID < b.sym.ID
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	result := make([]*xref.Symbol, len(matches))
+	for i, m := range matches {
+		result[i] = m.sym
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package ide
+
+import (
+	"cee/xref"
+	"fmt"
+	"testing"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	if _, ok := FuzzyScore("pz", "parse"); ok {
+		t.Errorf("pz matches parse")
+	}
+	if _, ok := FuzzyScore("", "parse"); !ok {
+		t.Errorf("empty query does not match")
+	}
+
+	// Each name scores above the next one.
+	for _, c := range []struct {
+		query string
+		names []string
+	}{
+		{"parse", []string{"parse", "parser", "parseInt"}},
+		{"pi", []string{"parse_int", "parseInt", "pxi"}},
+		{"Pi", []string{"Pi", "pi"}},
+	} {
+		last := 0
+		for i, name := range c.names {
+			score, ok := FuzzyScore(c.query, name)
+			if !ok {
+				t.Fatalf("%q does not match %q", c.query, name)
+			}
+			if i > 0 && score >= last {
+				t.Errorf("%q: %s scores %d, not below %s %d", c.query, name, score, c.names[i-1], last)
+			}
+			last = score
+		}
+	}
+}
+
+const workspaceSrc = `package a
+
+type Point struct {
+	x i64
+}
+
+fun parseInt(s i64) i64 { return s }
+fun parse_line(s i64) i64 { return s }
+fun paint() {
+	val pi = 1
+}
+`
+
+func TestWorkspaceSymbols(t *testing.T) {
+	_, info := resolveFiles(t, "a.cee", workspaceSrc)
+	idx := xref.NewIndex()
+	idx.Add("a", *info)
+
+	names := func(syms []*xref.Symbol) string {
+		var s []string
+		for _, sym := range syms {
+			s = append(s, sym.Name)
+		}
+		return fmt.Sprint(s)
+	}
+
+	// Locals are not searched.
+	for _, c := range []struct {
+		query string
+		limit int
+		want  string
+	}{
+		{"pi", 0, "[parseInt paint parse_line Point]"},
+		{"pi", 1, "[parseInt]"},
+		{"pt", 0, "[paint parseInt Point]"},
+		{"zz", 0, "[]"},
+	} {
+		if got := names(WorkspaceSymbols(idx, c.query, c.limit)); got != c.want {
+			t.Errorf("WorkspaceSymbols(%q, %d) = %s, want %s", c.query, c.limit, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package internal
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StringBuffer writes text line by line, starting every line with Prefix and the current indentation.
+// The zero StringBuffer builds a string, NewStringBuffer writes to a writer instead.
+type StringBuffer struct {
+	Prefix      string // starts every line, before the indentation
+	Indentation string // one level of indentation, a tab when empty
+
+	// Notes, e.g. comments, by position, printed on lines of their own by Flush.
+	Notes []Note
+
+	w      io.Writer
+	b      strings.Builder
+	level  int
+	inLine bool
+	err    error
+}
+
+func NewStringBuffer(w io.Writer) *StringBuffer { return &StringBuffer{w: w} }
+
+// Note is a text positioned in the source of what is printed, as a comment is in a syntax tree.
+type Note struct {
+	Pos  int
+	Text string
+}
+
+// Flush prints the notes positioned before pos, each on lines of its own, all of them when pos is negative.
+// Only the first line of a note is indented, the others are written as they are.
+func (b *StringBuffer) Flush(pos int) {
+	for len(b.Notes) != 0 && (pos < 0 || b.Notes[0].Pos < pos) {
+		first, rest, multiline := strings.Cut(b.Notes[0].Text, "\n")
+		_, _ = b.WriteString(first)
+		if multiline {
+			b.write("\n" + rest)
+		}
+		_, _ = b.WriteString("\n")
+		b.Notes = b.Notes[1:]
+	}
+}
+
+// Indent deepens the indentation of the lines started from now on.
+func (b *StringBuffer) Indent() { b.level++ }
+
+// Dedent undoes an Indent.
+func (b *StringBuffer) Dedent() {
+	if b.level > 0 {
+		b.level--
+	}
+}
+
+func (b *StringBuffer) write(s string) {
+	if b.err != nil {
+		return
+	}
+	if b.w == nil {
+		b.b.WriteString(s)
+		return
+	}
+	_, b.err = io.WriteString(b.w, s)
+}
+
+// WriteString writes s, prefixing and indenting the lines it starts. Empty lines are left unindented.
+func (b *StringBuffer) WriteString(s string) (int, error) {
+	n := len(s)
+	for len(s) > 0 {
+		line, rest, newline := strings.Cut(s, "\n")
+		if !b.inLine && line != "" {
+			indentation := b.Indentation
+			if indentation == "" {
+				indentation = "\t"
+			}
+			b.write(b.Prefix + strings.Repeat(indentation, b.level))
+		}
+		b.write(line)
+		b.inLine = line != "" || b.inLine
+		if newline {
+			b.write("\n")
+			b.inLine = false
+		}
+		s = rest
+	}
+	return n, b.err
+}
+
+// Print writes its operands formatted as fmt.Sprint does.
+func (b *StringBuffer) Print(a ...any) { _, _ = b.WriteString(fmt.Sprint(a...)) }
+
+// Println is Print ending the line.
+func (b *StringBuffer) Println(a ...any) { _, _ = b.WriteString(fmt.Sprint(a...) + "\n") }
+
+func (b *StringBuffer) Printf(format string, a ...any) {
+	_, _ = b.WriteString(fmt.Sprintf(format, a...))
+}
+
+// String returns the text built by a zero StringBuffer, which is empty when writing to a writer.
+func (b *StringBuffer) String() string { return b.b.String() }
+
+// Err returns the first error of the writer, after which nothing is written.
+func (b *StringBuffer) Err() error { return b.err }
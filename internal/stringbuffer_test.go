@@ -0,0 +1,57 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStringBufferIndent(t *testing.T) {
+	var b StringBuffer
+	b.Prefix = "// "
+	b.Println("{")
+	b.Indent()
+	b.Print("a")
+	b.Println(" b")
+	b.Println()
+	b.Println("c\nd")
+	b.Dedent()
+	b.Print("}")
+
+	want := "// {\n// \ta b\n\n// \tc\n// \td\n// }"
+	if got := b.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestStringBufferWriter(t *testing.T) {
+	var w strings.Builder
+	b := NewStringBuffer(&w)
+	b.Indentation = "  "
+	b.Indent()
+	b.Printf("%d\n%d", 1, 2)
+	if want := "  1\n  2"; w.String() != want {
+		t.Errorf("got %q, want %q", w.String(), want)
+	}
+	if b.String() != "" || b.Err() != nil {
+		t.Errorf("unexpected buffer state %q, %v", b.String(), b.Err())
+	}
+}
+
+func TestStringBufferFlush(t *testing.T) {
+	var b StringBuffer
+	b.Notes = []Note{{Pos: 1, Text: "// a"}, {Pos: 5, Text: "/* b\n c */"}, {Pos: 9, Text: "// c"}}
+	b.Indent()
+	b.Flush(5)
+	b.Println("x")
+	b.Flush(6)
+	b.Flush(-1)
+
+	want := "\t// a\n\tx\n\t/* b\n c */\n\t// c\n"
+	if got := b.String(); got != want || len(b.Notes) != 0 {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package intrinsic
+
+import (
+	"cee/ast"
+	"strconv"
+)
+
+// Namespace is the only namespace of intrinsics for now, others are reserved.
+const Namespace = "intrinsic"
+
+type Op int
+
+const (
+	_ Op = iota
+
+	Memcpy      // memcpy(dst, src, n), the ranges must not overlap
+	Memmove     // memmove(dst, src, n)
+	Memset      // memset(dst, b, n)
+	Trap        // trap(), aborts the program
+	Unreachable // unreachable(), undefined if executed
+)
+
+func (op Op) String() string {
+	for name, in := range intrinsics {
+		if in.Op == op {
+			return name
+		}
+	}
+	return "Op(" + strconv.Itoa(int(op)) + ")"
+}
+
+type Intrinsic struct {
+	Op     Op
+	Name   string
+	Params int
+	Result ast.TypeKind // TypeNone for no result
+}
+
+var intrinsics = map[string]Intrinsic{}
+
+func init() {
+	for _, in := range []Intrinsic{
+		{Op: Memcpy, Name: "memcpy", Params: 3, Result: ast.TypeNone},
+		{Op: Memmove, Name: "memmove", Params: 3, Result: ast.TypeNone},
+		{Op: Memset, Name: "memset", Params: 3, Result: ast.TypeNone},
+		{Op: Trap, Name: "trap", Result: ast.TypeNone},
+		{Op: Unreachable, Name: "unreachable", Result: ast.TypeNone},
+	} {
+		intrinsics[in.Name] = in
+	}
+}
+
+// Lookup returns the intrinsic named `@namespace.name`.
+func Lookup(namespace, name string) (Intrinsic, bool) {
+	if namespace != Namespace {
+		return Intrinsic{}, false
+	}
+	in, ok := intrinsics[name]
+	return in, ok
+}
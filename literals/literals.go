@@ -0,0 +1,284 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package literals
+
+import (
+	"cee"
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/token"
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+type Kind int
+
+const (
+	_ Kind = iota
+
+	Int
+	Float
+	Imag // the imaginary part is in Float
+	String
+	Char
+)
+
+// FloatPrec is the precision of decoded floats, enough for float64 constants and their folding.
+const FloatPrec = 128
+
+// Value is a decoded literal, only the field of its Kind is set.
+type Value struct {
+	Kind   Kind
+	Int    *big.Int
+	Float  *big.Float
+	String string
+	Char   rune
+}
+
+// Float64 returns the float value rounded to float64, and whether it is exact.
+func (v Value) Float64() (float64, big.Accuracy) {
+	if v.Kind == Int {
+		return new(big.Float).SetInt(v.Int).Float64()
+	}
+	return v.Float.Float64()
+}
+
+var ErrSyntax = errors.New("invalid literal")
+
+// ErrNoDigits is returned for a base prefix followed by no digit, as `0x`, which the scanner already reports.
+var ErrNoDigits = errors.New("literal has no digits")
+
+// digits validates a run of digits of the base with single underscores between them,
+// leading says if an underscore may precede the first digit, as it may follow a base prefix.
+func digits(s string, base int, leading bool) bool {
+	if s == "" || s[len(s)-1] == '_' || !leading && s[0] == '_' {
+		return false
+	}
+	prev := byte(0)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_':
+			if prev == '_' {
+				return false
+			}
+		case base <= 10 && '0' <= c && c < '0'+byte(base):
+		case base == 16 && ('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'):
+		default:
+			return false
+		}
+		prev = c
+	}
+	return true
+}
+
+// DecodeInt decodes a decimal, `0x`, `0o` or `0b` integer with underscores between digits.
+func DecodeInt(lit string) (*big.Int, error) {
+	base, body := 10, lit
+	if len(lit) >= 2 && lit[0] == '0' {
+		switch lit[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+		if base != 10 {
+			body = lit[2:]
+		}
+	}
+	if base != 10 && strings.Trim(body, "_") == "" {
+		return nil, ErrNoDigits
+	}
+	if !digits(body, base, base != 10) {
+		return nil, ErrSyntax
+	}
+	v, ok := new(big.Int).SetString(strings.ReplaceAll(body, "_", ""), base)
+	if !ok {
+		return nil, ErrSyntax
+	}
+	return v, nil
+}
+
+// DecodeFloat decodes a decimal float `int[.frac][e[+-]exp]`, or a hexadecimal float `0xint[.frac]p[+-]exp`,
+// with underscores between digits.
+func DecodeFloat(lit string) (*big.Float, error) {
+	base, body, marker := 10, lit, "eE"
+	if len(lit) > 2 && lit[0] == '0' && (lit[1] == 'x' || lit[1] == 'X') {
+		base, body, marker = 16, lit[2:], "pP"
+	}
+	mantissa, exp := body, ""
+	if i := strings.IndexAny(body, marker); i >= 0 {
+		mantissa, exp = body[:i], body[i+1:]
+		if exp != "" && (exp[0] == '+' || exp[0] == '-') {
+			exp = exp[1:]
+		}
+		if !digits(exp, 10, false) {
+			return nil, ErrSyntax
+		}
+	} else if base == 16 {
+		// The binary exponent of hexadecimal floats is mandatory, as in Go.
+		return nil, ErrSyntax
+	}
+	if base == 16 && strings.Trim(mantissa, "_.") == "" {
+		return nil, ErrNoDigits
+	}
+	intPart, frac, dot := strings.Cut(mantissa, ".")
+	if !digits(intPart, base, base != 10) || dot && !digits(frac, base, false) {
+		return nil, ErrSyntax
+	}
+
+	parseBase := 10
+	if base == 16 {
+		parseBase = 0 // the prefix selects base 16 and the exponent is binary
+	}
+	f, _, err := big.ParseFloat(strings.ReplaceAll(lit, "_", ""), parseBase, FloatPrec, big.ToNearestEven)
+	if err != nil {
+		return nil, ErrSyntax
+	}
+	return f, nil
+}
+
+func isFloat(lit string) bool {
+	if len(lit) > 1 && lit[0] == '0' && strings.ContainsAny(lit[1:2], "xX") {
+		return strings.ContainsAny(lit, ".pP")
+	}
+	if len(lit) > 1 && lit[0] == '0' && strings.ContainsAny(lit[1:2], "oObB") {
+		return false
+	}
+	return strings.ContainsAny(lit, ".eE")
+}
+
+// Decode converts a literal token. Numbers are decoded according to their spelling, whatever the kind of
+// their token, as desugared and generated literals are all INT tokens.
+func Decode(lit ast.LiteralValue) (Value, error) {
+	switch lit.Kind {
+	case token.INT, token.FLOAT, token.IMAG:
+		text := lit.Literal
+		if strings.HasSuffix(text, "i") {
+			var (
+				f   *big.Float
+				err error
+			)
+			if isFloat(text[:len(text)-1]) {
+				f, err = DecodeFloat(text[:len(text)-1])
+			} else if i, ierr := DecodeInt(text[:len(text)-1]); ierr == nil {
+				f = new(big.Float).SetPrec(FloatPrec).SetInt(i)
+			} else {
+				err = ierr
+			}
+			return Value{Kind: Imag, Float: f}, err
+		}
+		if isFloat(text) {
+			f, err := DecodeFloat(text)
+			return Value{Kind: Float, Float: f}, err
+		}
+		i, err := DecodeInt(text)
+		return Value{Kind: Int, Int: i}, err
+	case token.STRING, token.STRING_RAW:
+		s, err := token.Unquote(lit.Literal)
+		return Value{Kind: String, String: s}, err
+	case token.CHAR:
+		s, err := token.Unquote(lit.Literal)
+		if err != nil {
+			return Value{}, err
+		}
+		return Value{Kind: Char, Char: []rune(s)[0]}, nil
+	}
+	return Value{}, ErrSyntax
+}
+
+type bounds struct{ min, max *big.Int }
+
+func signed(bits uint) bounds {
+	max := new(big.Int).Lsh(big.NewInt(1), bits-1)
+	return bounds{min: new(big.Int).Neg(max), max: max.Sub(max, big.NewInt(1))}
+}
+
+func unsigned(bits uint) bounds {
+	max := new(big.Int).Lsh(big.NewInt(1), bits)
+	return bounds{min: new(big.Int), max: max.Sub(max, big.NewInt(1))}
+}
+
+var intBounds = map[ast.TypeKind]bounds{
+	ast.TypeI8:  signed(8),
+	ast.TypeI16: signed(16),
+	ast.TypeI32: signed(32),
+	ast.TypeI64: signed(64),
+	ast.TypeU8:  unsigned(8),
+	ast.TypeU16: unsigned(16),
+	ast.TypeU32: unsigned(32),
+	ast.TypeU64: unsigned(64),
+}
+
+// Fits reports whether the integer is representable by the builtin integer type.
+func Fits(i *big.Int, kind ast.TypeKind) bool {
+	b, ok := intBounds[kind]
+	return ok && b.min.Cmp(i) <= 0 && i.Cmp(b.max) <= 0
+}
+
+// Check decodes the literal for a use as a value of the builtin type kind, 0 if the type is not known yet.
+// Malformed literals, integers out of the range of kind and floats overflowing float64 are reported as errors,
+// floats with more digits than float64 holds as warnings.
+func Check(lit ast.LiteralValue, kind ast.TypeKind) (Value, []diagnosis.Diagnosis) {
+	return check(lit, kind, false)
+}
+
+// CheckNegated is Check for the literal negated, as in `-128`, the returned value is negated.
+func CheckNegated(lit ast.LiteralValue, kind ast.TypeKind) (Value, []diagnosis.Diagnosis) {
+	return check(lit, kind, true)
+}
+
+func check(lit ast.LiteralValue, kind ast.TypeKind, neg bool) (Value, []diagnosis.Diagnosis) {
+	v, err := Decode(lit)
+	if err == ErrNoDigits {
+		// Reported by the scanner.
+		return v, nil
+	}
+	if err != nil {
+		return v, []diagnosis.Diagnosis{{
+			Kind:  diagnosis.InvalidLiteral,
+			Error: diagnosis.InvalidLiteralError{Literal: lit},
+		}}
+	}
+
+	switch v.Kind {
+	case Int:
+		if neg {
+			v.Int.Neg(v.Int)
+		}
+		if _, ok := intBounds[kind]; ok && !Fits(v.Int, kind) {
+			return v, []diagnosis.Diagnosis{{
+				Kind:  diagnosis.LiteralOverflow,
+				Error: diagnosis.LiteralOverflowError{Literal: lit, Type: ast.TypeString(ast.Type{Union: cee.Union[ast.TypeKind]{Tag: kind}})},
+			}}
+		}
+	case Float, Imag:
+		if neg {
+			v.Float.Neg(v.Float)
+		}
+		f, _ := v.Float.Float64()
+		if math.IsInf(f, 0) {
+			return v, []diagnosis.Diagnosis{{
+				Kind:  diagnosis.LiteralOverflow,
+				Error: diagnosis.LiteralOverflowError{Literal: lit, Type: "float64"},
+			}}
+		}
+		// Decimal fractions are rarely exact, only digits beyond the shortest spelling of the float64 are lost.
+		shortest := strconv.FormatFloat(f, 'g', -1, 64)
+		if back, _, _ := big.ParseFloat(shortest, 10, FloatPrec, big.ToNearestEven); back.Cmp(v.Float) != 0 {
+			return v, []diagnosis.Diagnosis{{
+				Kind:     diagnosis.LiteralPrecision,
+				Error:    diagnosis.LiteralPrecisionError{Literal: lit, Type: "float64", Value: shortest},
+				Severity: diagnosis.SeverityWarning,
+			}}
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package literals
+
+import (
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/token"
+	"math/big"
+	"testing"
+)
+
+func literal(kind int, lit string) ast.LiteralValue {
+	return ast.LiteralValue{Token: ast.Token{Kind: kind, Literal: lit}}
+}
+
+func TestDecodeInt(t *testing.T) {
+	tests := []struct {
+		lit  string
+		want string
+	}{
+		{"0", "0"},
+		{"1_000_000", "1000000"},
+		{"0xFF", "255"},
+		{"0x_ff_ff", "65535"},
+		{"0o17", "15"},
+		{"0b1010", "10"},
+		{"0755", "755"},
+		{"340282366920938463463374607431768211456", "340282366920938463463374607431768211456"},
+	}
+	for _, test := range tests {
+		v, err := Decode(literal(token.INT, test.lit))
+		if err != nil || v.Kind != Int || v.Int.String() != test.want {
+			t.Errorf("Decode(%s) = %v, %v, want %s", test.lit, v.Int, err, test.want)
+		}
+	}
+
+	for _, lit := range []string{"1__0", "_1", "1_", "0x", "0b2", "0xg", "12a"} {
+		if _, err := Decode(literal(token.INT, lit)); err == nil {
+			t.Errorf("Decode(%s) succeeded", lit)
+		}
+	}
+
+	// The scanner reports prefixes with no digits, Check does not report them again.
+	for _, lit := range []string{"0x", "0b_", "0o", "0xp1"} {
+		if _, err := Decode(literal(token.INT, lit)); err != ErrNoDigits {
+			t.Errorf("Decode(%s) = %v, want ErrNoDigits", lit, err)
+		}
+		if _, diags := Check(literal(token.INT, lit), ast.TypeI64); len(diags) != 0 {
+			t.Errorf("Check(%s) = %v", lit, diags)
+		}
+	}
+}
+
+func TestDecodeFloat(t *testing.T) {
+	tests := []struct {
+		lit  string
+		kind Kind
+		want float64
+	}{
+		{"1.5", Float, 1.5},
+		{"1_000.25", Float, 1000.25},
+		{"2e3", Float, 2000},
+		{"1.5E-1", Float, 0.15},
+		{"2i", Imag, 2},
+		{"0.5i", Imag, 0.5},
+		{"0x1.8p3", Float, 12},
+		{"0X_1p-2", Float, 0.25},
+		{"0x1p4i", Imag, 16},
+	}
+	for _, test := range tests {
+		v, err := Decode(literal(token.INT, test.lit))
+		if err != nil || v.Kind != test.kind {
+			t.Errorf("Decode(%s) = %v, %v", test.lit, v, err)
+			continue
+		}
+		if f, _ := v.Float64(); f != test.want {
+			t.Errorf("Decode(%s) = %v, want %v", test.lit, f, test.want)
+		}
+	}
+
+	for _, lit := range []string{"1._5", "1.", "1e", "1e+_1", ".5", "0x1.8", "0x1p", "0xp1"} {
+		if _, err := Decode(literal(token.INT, lit)); err == nil {
+			t.Errorf("Decode(%s) succeeded", lit)
+		}
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	if v, err := Decode(literal(token.STRING, `"a\tb\e"`)); err != nil || v.String != "a\tb\x1b" {
+		t.Errorf("string = %q, %v", v.String, err)
+	}
+	if v, err := Decode(literal(token.CHAR, `'\n'`)); err != nil || v.Char != '\n' {
+		t.Errorf("char = %q, %v", v.Char, err)
+	}
+	if _, err := Decode(literal(token.CHAR, `'ab'`)); err == nil {
+		t.Error("two runes decoded as a char")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		lit  string
+		kind ast.TypeKind
+		want int // diagnosis kind, 0 for none
+	}{
+		{"127", ast.TypeI8, 0},
+		{"128", ast.TypeI8, diagnosis.LiteralOverflow},
+		{"255", ast.TypeU8, 0},
+		{"0x1_0000_0000", ast.TypeU32, diagnosis.LiteralOverflow},
+		{"18446744073709551615", ast.TypeU64, 0},
+		{"18446744073709551615", ast.TypeI64, diagnosis.LiteralOverflow},
+		{"18446744073709551616", 0, 0},
+		{"1e400", 0, diagnosis.LiteralOverflow},
+		{"1__2", ast.TypeI32, diagnosis.InvalidLiteral},
+		{"0.1", 0, 0},
+		{"1e300", 0, 0},
+		{"0.10000000000000000001", 0, diagnosis.LiteralPrecision},
+		{"3.14159265358979323846264338327950288", 0, diagnosis.LiteralPrecision},
+	}
+	for _, test := range tests {
+		_, diags := Check(literal(token.INT, test.lit), test.kind)
+		switch {
+		case test.want == 0 && len(diags) != 0:
+			t.Errorf("Check(%s) reported %v", test.lit, diags[0].Error)
+		case test.want != 0 && (len(diags) != 1 || diags[0].Kind != test.want):
+			t.Errorf("Check(%s) = %v, want kind %d", test.lit, diags, test.want)
+		}
+	}
+
+	if _, diags := CheckNegated(literal(token.INT, "128"), ast.TypeI8); len(diags) != 0 {
+		t.Errorf("CheckNegated(128) reported %v", diags[0].Error)
+	}
+	if v, diags := CheckNegated(literal(token.INT, "129"), ast.TypeI8); len(diags) != 1 || v.Int.Int64() != -129 {
+		t.Errorf("CheckNegated(129) = %v, %v", v.Int, diags)
+	}
+
+	if !Fits(big.NewInt(-128), ast.TypeI8) || Fits(big.NewInt(-1), ast.TypeU64) {
+		t.Error("Fits")
+	}
+}
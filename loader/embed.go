@@ -0,0 +1,70 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package loader
+
+import (
+	"cee"
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/parser"
+	"cee/token"
+	"errors"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+var errNotUTF8 = errors.New("not valid UTF-8")
+
+// embedPath returns the path argument of an embed attribute, relative to the directory of the file.
+func embedPath(attr ast.Attribute) (string, bool) {
+	if len(attr.Args) != 1 || attr.Args[0].Key.Literal != "" || !token.IsString(attr.Args[0].Value.Kind) {
+		return "", false
+	}
+	path, err := token.Unquote(attr.Args[0].Value.Literal)
+	if err != nil || !filepath.IsLocal(path) {
+		return "", false
+	}
+	return filepath.FromSlash(path), true
+}
+
+// Embed reads the files named by the embed attributes of the declarations of the source file at path,
+// and sets the values of the declarations to string literals of their contents. The literals span the
+// attributes. Embedded files must be valid UTF-8.
+func Embed(src FileSource, path string, decls []ast.Stmt) []diagnosis.Diagnosis {
+	var diags []diagnosis.Diagnosis
+	for i, decl := range decls {
+		attr, ok := decl.Attr(parser.EmbedAttribute)
+		if !ok {
+			continue
+		}
+
+		d, ok := decl.Value.(ast.ValDecl)
+		rel, valid := embedPath(attr)
+		if !ok || d.Value.Tag != 0 || d.Pattern != nil || !valid {
+			diags = append(diags, diagnosis.Diagnosis{
+				Kind:  diagnosis.InvalidEmbed,
+				Error: diagnosis.InvalidEmbedError{Attr: attr},
+			})
+			continue
+		}
+
+		data, err := ReadFile(src, filepath.Join(filepath.Dir(path), rel))
+		if err == nil && !utf8.Valid(data) {
+			err = errNotUTF8
+		}
+		if err != nil {
+			diags = append(diags, diagnosis.Diagnosis{
+				Kind:  diagnosis.EmbedFile,
+				Error: diagnosis.EmbedFileError{Attr: attr, Path: rel, Err: err},
+			})
+			continue
+		}
+
+		lit := ast.LiteralValue{Token: ast.Token{PosRange: attr.PosRange, Kind: token.STRING, Literal: token.Quote(string(data))}}
+		d.Value = ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: ast.ExprLiteralValue, Value: lit}}
+		decls[i].Value = d
+	}
+	return diags
+}
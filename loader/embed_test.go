@@ -0,0 +1,47 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package loader
+
+import (
+	"cee"
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/parser"
+	"cee/token"
+	"path/filepath"
+	"testing"
+)
+
+func embedded(name, path string) ast.Stmt {
+	ident := ast.Ident{Token: ast.Token{Kind: token.IDENT, Literal: name}}
+	return ast.Stmt{
+		Union: cee.Union[ast.StmtKind]{Tag: ast.StmtValDecl, Value: ast.ValDecl{Name: ident}},
+		Attrs: []ast.Attribute{{
+			Name: ast.Ident{Token: ast.Token{Kind: token.IDENT, Literal: parser.EmbedAttribute}},
+			Args: []ast.AttributeArg{{Value: ast.LiteralValue{Token: ast.Token{Kind: token.STRING, Literal: token.Quote(path)}}}},
+		}},
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	dir := t.TempDir()
+	o := NewOverlay(DiskSource{})
+	o.Set(filepath.Join(dir, "assets", "hello.txt"), []byte("hello\n\"world\""))
+
+	decls := []ast.Stmt{
+		embedded("hello", "assets/hello.txt"),
+		embedded("missing", "assets/missing.txt"),
+		embedded("outside", "../hello.txt"),
+	}
+	diags := Embed(o, filepath.Join(dir, "main.cee"), decls)
+
+	value := decls[0].Value.(ast.ValDecl).Value.Value.(ast.LiteralValue)
+	if s, err := token.Unquote(value.Literal); err != nil || s != "hello\n\"world\"" {
+		t.Errorf("embedded %s, %v", value.Literal, err)
+	}
+	if len(diags) != 2 || diags[0].Kind != diagnosis.EmbedFile || diags[1].Kind != diagnosis.InvalidEmbed {
+		t.Errorf("diagnosis %v", diags)
+	}
+}
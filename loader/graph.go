@@ -0,0 +1,190 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package loader
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Graph is the import graph keyed by canonical package name.
+type Graph struct {
+	Imports map[string][]string
+}
+
+func NewGraph() Graph {
+	return Graph{Imports: map[string][]string{}}
+}
+
+// AddPackage records the imports of a package, duplicated imports are ignored.
+func (g *Graph) AddPackage(name string, imports ...string) {
+	existing := g.Imports[name]
+	for _, imp := range imports {
+		dup := false
+		for _, e := range existing {
+			if e == imp {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			existing = append(existing, imp)
+		}
+	}
+	g.Imports[name] = existing
+}
+
+func (g *Graph) Packages() []string {
+	names := make([]string, 0, len(g.Imports))
+	for name := range g.Imports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Importers returns the given packages and every package importing one of them, directly or not, sorted by name.
+func (g *Graph) Importers(names ...string) []string {
+	importers := map[string][]string{}
+	for name, imports := range g.Imports {
+		for _, imp := range imports {
+			importers[imp] = append(importers[imp], name)
+		}
+	}
+
+	seen := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		for _, importer := range importers[name] {
+			visit(importer)
+		}
+	}
+	for _, name := range names {
+		visit(name)
+	}
+
+	result := make([]string, 0, len(seen))
+	for name := range seen {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+type ImportCycleError struct {
+	Chain []string // first and last elements are the same package
+}
+
+func (e ImportCycleError) Error() string {
+	return fmt.Sprint("import cycle not allowed: ", strings.Join(e.Chain, " -> "))
+}
+
+// TopoOrder returns packages ordered so that every package follows its imports.
+// Ties are broken by name, so the result is deterministic.
+// Imported packages never added to the graph are included as leaves.
+func (g *Graph) TopoOrder() ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	var (
+		order []string
+		state = map[string]int{}
+		chain []string
+	)
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			for i, n := range chain {
+				if n == name {
+					return ImportCycleError{Chain: append(append([]string{}, chain[i:]...), name)}
+				}
+			}
+		}
+
+		state[name] = visiting
+		chain = append(chain, name)
+
+		imports := append([]string{}, g.Imports[name]...)
+		sort.Strings(imports)
+		for _, imp := range imports {
+			if err := visit(imp); err != nil {
+				return err
+			}
+		}
+
+		chain = chain[:len(chain)-1]
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range g.Packages() {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// Cycles reports every elementary import cycle once, each starting from its smallest package name.
+func (g *Graph) Cycles() []ImportCycleError {
+	var (
+		cycles []ImportCycleError
+		seen   = map[string]bool{}
+	)
+
+	for _, root := range g.Packages() {
+		var (
+			chain   []string
+			onChain = map[string]bool{}
+		)
+
+		var visit func(name string)
+		visit = func(name string) {
+			if onChain[name] {
+				if name == root {
+					cycle := append(append([]string{}, chain...), root)
+					key := strings.Join(cycle, "\x00")
+					if !seen[key] {
+						seen[key] = true
+						cycles = append(cycles, ImportCycleError{Chain: cycle})
+					}
+				}
+				return
+			}
+			// Cycles through packages smaller than root were already reported from them.
+			if name < root {
+				return
+			}
+
+			onChain[name] = true
+			chain = append(chain, name)
+			imports := append([]string{}, g.Imports[name]...)
+			sort.Strings(imports)
+			for _, imp := range imports {
+				visit(imp)
+			}
+			chain = chain[:len(chain)-1]
+			onChain[name] = false
+		}
+
+		visit(root)
+	}
+
+	return cycles
+}
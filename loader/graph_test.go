@@ -0,0 +1,62 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestGraph_TopoOrder(t *testing.T) {
+	g := NewGraph()
+	g.AddPackage("app", "std/io", "lib")
+	g.AddPackage("lib", "std/io")
+	g.AddPackage("std/io")
+
+	order, err := g.TopoOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"std/io", "lib", "app"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatal("incorrect order:", order)
+		}
+	}
+}
+
+func TestGraph_Importers(t *testing.T) {
+	g := NewGraph()
+	g.AddPackage("app", "lib")
+	g.AddPackage("lib", "std/io")
+	g.AddPackage("tool", "std/fmt")
+
+	got := fmt.Sprint(g.Importers("std/io"))
+	if got != "[app lib std/io]" {
+		t.Error("incorrect importers:", got)
+	}
+}
+
+func TestGraph_Cycles(t *testing.T) {
+	g := NewGraph()
+	g.AddPackage("a", "b")
+	g.AddPackage("b", "c")
+	g.AddPackage("c", "a", "d")
+	g.AddPackage("d", "d")
+
+	var cycle ImportCycleError
+	if _, err := g.TopoOrder(); !errors.As(err, &cycle) || len(cycle.Chain) != 4 {
+		t.Fatal("want import cycle, have", err)
+	}
+
+	cycles := g.Cycles()
+	if len(cycles) != 2 {
+		t.Fatal("incorrect cycles:", cycles)
+	}
+	if cycles[0].Error() != "import cycle not allowed: a -> b -> c -> a" {
+		t.Error("incorrect chain:", cycles[0].Error())
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package loader
+
+import (
+	"cee/edition"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ManifestFile names the manifest at the root of a module:
+//
+//	module example.com/app
+//	edition 2024
+//
+//	require example.com/lib v1.2.0
+//	require (
+//		example.com/util v0.3.1
+//	)
+//
+// Lines are directives, `//` starts a comment.
+const ManifestFile = "cee.mod"
+
+type Requirement struct {
+	Path    string
+	Version string
+}
+
+type Manifest struct {
+	Module  string
+	Edition string
+	Require []Requirement
+}
+
+type ManifestError struct {
+	Path string
+	Line int // one-based
+	Msg  string
+}
+
+func (e ManifestError) Error() string {
+	return fmt.Sprint(e.Path, ":", e.Line, ": ", e.Msg)
+}
+
+func validModulePath(p string) bool {
+	if p == "" || strings.HasPrefix(p, "/") || strings.HasSuffix(p, "/") || path.Clean(p) != p {
+		return false
+	}
+	for _, elem := range strings.Split(p, "/") {
+		if elem == "." || elem == ".." {
+			return false
+		}
+	}
+	return !strings.ContainsAny(p, " \t\"\\@")
+}
+
+// validVersion accepts semantic versions `vMAJOR.MINOR.PATCH` with an optional pre-release.
+func validVersion(v string) bool {
+	if !strings.HasPrefix(v, "v") {
+		return false
+	}
+	core, _, _ := strings.Cut(v[1:], "-")
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	for _, part := range parts {
+		if part == "" || len(part) > 1 && part[0] == '0' || strings.Trim(part, "0123456789") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseManifest parses the content of the manifest at path, which is only used in errors.
+func ParseManifest(path string, data []byte) (*Manifest, error) {
+	var (
+		m       Manifest
+		block   bool
+		seen    = map[string]bool{}
+		errorAt = func(line int, format string, a ...any) error {
+			return ManifestError{Path: path, Line: line, Msg: fmt.Sprintf(format, a...)}
+		}
+	)
+
+	require := func(line int, fields []string) error {
+		if len(fields) != 2 {
+			return errorAt(line, "usage: require path version")
+		}
+		if !validModulePath(fields[0]) {
+			return errorAt(line, "invalid module path %q", fields[0])
+		}
+		if !validVersion(fields[1]) {
+			return errorAt(line, "invalid version %q", fields[1])
+		}
+		if seen[fields[0]] {
+			return errorAt(line, "%s required twice", fields[0])
+		}
+		seen[fields[0]] = true
+		m.Require = append(m.Require, Requirement{Path: fields[0], Version: fields[1]})
+		return nil
+	}
+
+	for i, line := range strings.Split(string(data), "\n") {
+		lineno := i + 1
+		line, _, _ = strings.Cut(line, "//")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if block {
+			if len(fields) == 1 && fields[0] == ")" {
+				block = false
+				continue
+			}
+			if err := require(lineno, fields); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		switch fields[0] {
+		case "module":
+			if m.Module != "" {
+				return nil, errorAt(lineno, "repeated module directive")
+			}
+			if len(fields) != 2 || !validModulePath(fields[1]) {
+				return nil, errorAt(lineno, "usage: module path")
+			}
+			m.Module = fields[1]
+		case "edition":
+			if m.Edition != "" {
+				return nil, errorAt(lineno, "repeated edition directive")
+			}
+			if len(fields) != 2 {
+				return nil, errorAt(lineno, "usage: edition year")
+			}
+			if !edition.Valid(fields[1]) {
+				return nil, errorAt(lineno, "unknown edition %s", fields[1])
+			}
+			m.Edition = fields[1]
+		case "require":
+			if len(fields) == 2 && fields[1] == "(" {
+				block = true
+				continue
+			}
+			if err := require(lineno, fields[1:]); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, errorAt(lineno, "unknown directive %s", fields[0])
+		}
+	}
+
+	if block {
+		return nil, ManifestError{Path: path, Line: strings.Count(string(data), "\n") + 1, Msg: "unterminated require block"}
+	}
+	if m.Module == "" {
+		return nil, ManifestError{Path: path, Line: 1, Msg: "missing module directive"}
+	}
+	if m.Edition == "" {
+		m.Edition = edition.Latest()
+	}
+	return &m, nil
+}
+
+// FindManifest returns the path of the manifest in dir or its closest parent, "" if there is none.
+func FindManifest(src FileSource, dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		p := filepath.Join(dir, ManifestFile)
+		_, err := src.Stat(p)
+		switch {
+		case err == nil:
+			return p, nil
+		case !errors.Is(err, fs.ErrNotExist):
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// DefaultModCache returns $CEE_MODCACHE, or cee/mod under the user cache directory.
+func DefaultModCache() string {
+	if dir := os.Getenv("CEE_MODCACHE"); dir != "" {
+		return dir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "cee", "mod")
+}
+
+// Resolver maps canonical names to package directories. Packages of the module live under Root,
+// those of a required module under ModCache in a directory `path@version`, so the same manifest always
+// resolves to the same sources. Requirements of dependencies must be listed in the manifest as well.
+type Resolver struct {
+	Root     string // absolute directory of the manifest
+	Manifest *Manifest
+	ModCache string
+}
+
+// LoadResolver reads the manifest at path.
+func LoadResolver(src FileSource, path, modCache string) (*Resolver, error) {
+	data, err := ReadFile(src, path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ParseManifest(path, data)
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	return &Resolver{Root: root, Manifest: m, ModCache: modCache}, nil
+}
+
+// within reports whether name is the module path or below it, and the rest of the name.
+func within(name, module string) (string, bool) {
+	if name == module {
+		return "", true
+	}
+	rest, ok := strings.CutPrefix(name, module+"/")
+	return rest, ok
+}
+
+// Name returns the canonical name of a package directory of the module.
+func (r *Resolver) Name(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(r.Root, dir)
+	if err != nil || !filepath.IsLocal(rel) && rel != "." {
+		return "", fmt.Errorf("%s is outside the module %s", dir, r.Manifest.Module)
+	}
+	if rel == "." {
+		return r.Manifest.Module, nil
+	}
+	return r.Manifest.Module + "/" + filepath.ToSlash(rel), nil
+}
+
+// Dir returns the directory of the package with the canonical name, the longest matching module wins.
+func (r *Resolver) Dir(name string) (string, bool) {
+	var (
+		dir  string
+		best = -1
+	)
+	if rest, ok := within(name, r.Manifest.Module); ok {
+		dir, best = filepath.Join(r.Root, filepath.FromSlash(rest)), len(r.Manifest.Module)
+	}
+	for _, req := range r.Manifest.Require {
+		if rest, ok := within(name, req.Path); ok && len(req.Path) > best {
+			dir, best = filepath.Join(r.ModCache, filepath.FromSlash(req.Path)+"@"+req.Version, filepath.FromSlash(rest)), len(req.Path)
+		}
+	}
+	return dir, best >= 0
+}
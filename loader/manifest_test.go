@@ -0,0 +1,81 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package loader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const manifest = `module example.com/app // the application
+edition 2024
+
+require example.com/lib v1.2.0
+require (
+	example.com/lib/sub v0.1.0-rc.1
+)
+`
+
+func TestParseManifest(t *testing.T) {
+	m, err := ParseManifest(ManifestFile, []byte(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Module != "example.com/app" || m.Edition != "2024" || len(m.Require) != 2 || m.Require[1].Version != "v0.1.0-rc.1" {
+		t.Fatalf("got %+v", m)
+	}
+
+	for _, src := range []string{
+		"edition 2024",
+		"module a\nmodule b",
+		"module a\nedition 1999",
+		"module a\nrequire b 1.0.0",
+		"module a\nrequire b v1.02.0",
+		"module a\nrequire ../b v1.0.0",
+		"module a\nrequire (\nb v1.0.0",
+		"module a\nfrobnicate",
+	} {
+		if _, err := ParseManifest(ManifestFile, []byte(src)); err == nil {
+			t.Errorf("%q: expected an error", src)
+		}
+	}
+}
+
+func TestResolver(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, ManifestFile), []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(root, "util", "strings")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := FindManifest(DiskSource{}, sub)
+	if err != nil || path != filepath.Join(root, ManifestFile) {
+		t.Fatalf("found %q, %v", path, err)
+	}
+	r, err := LoadResolver(DiskSource{}, path, "/cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name, err := r.Name(sub); err != nil || name != "example.com/app/util/strings" {
+		t.Errorf("name %q, %v", name, err)
+	}
+
+	for name, want := range map[string]string{
+		"example.com/app/util":   filepath.Join(root, "util"),
+		"example.com/lib/x":      filepath.Join("/cache", "example.com", "lib@v1.2.0", "x"),
+		"example.com/lib/sub/y":  filepath.Join("/cache", "example.com", "lib", "sub@v0.1.0-rc.1", "y"),
+		"example.com/library/xy": "",
+	} {
+		dir, ok := r.Dir(name)
+		if dir != want || ok != (want != "") {
+			t.Errorf("%s: got %q, %v, want %q", name, dir, ok, want)
+		}
+	}
+}
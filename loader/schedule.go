@@ -0,0 +1,67 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package loader
+
+// Schedule runs task for every package of TopoOrder once the tasks of all its imports completed,
+// with at most workers tasks running at a time. Independent packages run concurrently.
+// After the first failure no further task is started, the error is returned once running tasks completed.
+// Import cycles are reported before anything runs.
+func (g *Graph) Schedule(workers int, task func(name string) error) error {
+	order, err := g.TopoOrder()
+	if err != nil {
+		return err
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+
+	var (
+		pending    = map[string]int{}
+		dependents = map[string][]string{}
+		ready      []string
+	)
+	for _, name := range order {
+		for _, imp := range g.Imports[name] {
+			pending[name]++
+			dependents[imp] = append(dependents[imp], name)
+		}
+		if pending[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	type result struct {
+		name string
+		err  error
+	}
+
+	var (
+		done     = make(chan result)
+		running  int
+		firstErr error
+	)
+	for {
+		for firstErr == nil && len(ready) != 0 && running < workers {
+			name := ready[0]
+			ready = ready[1:]
+			running++
+			go func() { done <- result{name: name, err: task(name)} }()
+		}
+		if running == 0 {
+			return firstErr
+		}
+
+		r := <-done
+		running--
+		if r.err != nil && firstErr == nil {
+			firstErr = r.err
+		}
+		for _, dependent := range dependents[r.name] {
+			if pending[dependent]--; pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package loader
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestGraph_Schedule(t *testing.T) {
+	g := NewGraph()
+	g.AddPackage("app", "lib", "util")
+	g.AddPackage("lib", "std/io")
+	g.AddPackage("util", "std/io")
+	g.AddPackage("std/io")
+
+	var (
+		mutex   sync.Mutex
+		done    = map[string]bool{}
+		running int
+		peak    int
+	)
+	err := g.Schedule(2, func(name string) error {
+		mutex.Lock()
+		for _, imp := range g.Imports[name] {
+			if !done[imp] {
+				t.Errorf("%s started before its import %s completed", name, imp)
+			}
+		}
+		running++
+		peak = max(peak, running)
+		mutex.Unlock()
+
+		mutex.Lock()
+		running--
+		done[name] = true
+		mutex.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 4 {
+		t.Error("not every package ran:", done)
+	}
+	if peak > 2 {
+		t.Error("more tasks than workers:", peak)
+	}
+}
+
+func TestGraph_ScheduleError(t *testing.T) {
+	g := NewGraph()
+	g.AddPackage("app", "lib")
+	g.AddPackage("lib")
+
+	failure := errors.New("failure")
+	err := g.Schedule(4, func(name string) error {
+		if name == "app" {
+			t.Error("dependent of a failed package ran")
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatal("want failure, have", err)
+	}
+}
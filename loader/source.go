@@ -0,0 +1,134 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package loader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type Hash [sha256.Size]byte
+
+// FileSource provides the contents of source files, abstracting over the disk.
+type FileSource interface {
+	Open(path string) (io.ReadCloser, error)
+	Stat(path string) (fs.FileInfo, error)
+	Hash(path string) (Hash, error)
+}
+
+// ReadFile reads the whole content of a file from the source.
+func ReadFile(src FileSource, path string) ([]byte, error) {
+	f, err := src.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// DiskSource reads files from the operating system.
+type DiskSource struct{}
+
+func (DiskSource) Open(path string) (io.ReadCloser, error) { return os.Open(path) }
+
+func (DiskSource) Stat(path string) (fs.FileInfo, error) { return os.Stat(path) }
+
+func (s DiskSource) Hash(path string) (Hash, error) {
+	data, err := ReadFile(s, path)
+	if err != nil {
+		return Hash{}, err
+	}
+	return sha256.Sum256(data), nil
+}
+
+type overlayFile struct {
+	content []byte
+	hash    Hash
+	modTime time.Time
+}
+
+type overlayInfo struct {
+	name string
+	file *overlayFile
+}
+
+func (i overlayInfo) Name() string       { return i.name }
+func (i overlayInfo) Size() int64        { return int64(len(i.file.content)) }
+func (i overlayInfo) Mode() fs.FileMode  { return 0o444 }
+func (i overlayInfo) ModTime() time.Time { return i.file.modTime }
+func (i overlayInfo) IsDir() bool        { return false }
+func (i overlayInfo) Sys() any           { return nil }
+
+// Overlay layers in-memory contents, e.g. unsaved editor buffers, over a base source.
+// Paths are cleaned before lookup, it is safe for concurrent use.
+type Overlay struct {
+	Base FileSource
+
+	mutex sync.RWMutex
+	files map[string]*overlayFile
+}
+
+func NewOverlay(base FileSource) *Overlay {
+	return &Overlay{Base: base, files: map[string]*overlayFile{}}
+}
+
+// Set shadows the file at path with content, the content must not be modified afterwards.
+func (o *Overlay) Set(path string, content []byte) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.files[filepath.Clean(path)] = &overlayFile{content: content, hash: sha256.Sum256(content), modTime: time.Now()}
+}
+
+// Delete drops the overlay of path, revealing the base content again.
+func (o *Overlay) Delete(path string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	delete(o.files, filepath.Clean(path))
+}
+
+// Paths returns the overlaid paths.
+func (o *Overlay) Paths() []string {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	paths := make([]string, 0, len(o.files))
+	for path := range o.files {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func (o *Overlay) lookup(path string) (*overlayFile, bool) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	f, ok := o.files[filepath.Clean(path)]
+	return f, ok
+}
+
+func (o *Overlay) Open(path string) (io.ReadCloser, error) {
+	if f, ok := o.lookup(path); ok {
+		return io.NopCloser(bytes.NewReader(f.content)), nil
+	}
+	return o.Base.Open(path)
+}
+
+func (o *Overlay) Stat(path string) (fs.FileInfo, error) {
+	if f, ok := o.lookup(path); ok {
+		return overlayInfo{name: filepath.Base(path), file: f}, nil
+	}
+	return o.Base.Stat(path)
+}
+
+func (o *Overlay) Hash(path string) (Hash, error) {
+	if f, ok := o.lookup(path); ok {
+		return f.hash, nil
+	}
+	return o.Base.Hash(path)
+}
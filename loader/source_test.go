@@ -0,0 +1,38 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package loader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOverlay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.cee")
+	if err := os.WriteFile(path, []byte("disk"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	o := NewOverlay(DiskSource{})
+	o.Set(path, []byte("buffer"))
+
+	data, err := ReadFile(o, path)
+	if err != nil || string(data) != "buffer" {
+		t.Fatalf("got %q, %v, want overlay content", data, err)
+	}
+	if info, err := o.Stat(path); err != nil || info.Size() != int64(len("buffer")) {
+		t.Fatalf("stat: %v, %v", info, err)
+	}
+	overlaid, _ := o.Hash(path)
+
+	o.Delete(path)
+	if data, _ := ReadFile(o, path); string(data) != "disk" {
+		t.Fatalf("got %q after delete, want disk content", data)
+	}
+	if disk, _ := o.Hash(path); disk == overlaid {
+		t.Error("hash did not change after delete")
+	}
+}
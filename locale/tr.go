@@ -4,4 +4,5 @@
 
 package locale
 
-func Tr(str string) string {}
+// Tr translates a message into the locale of the user. Messages are English until catalogs are added.
+func Tr(str string) string { return str }
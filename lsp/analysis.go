@@ -0,0 +1,84 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package lsp
+
+import (
+	"cee/ast"
+	"cee/build"
+	"cee/diagnosis"
+	"cee/hir"
+	"cee/parser"
+	"cee/query"
+	"cee/resolve"
+	"cee/token"
+)
+
+// Analysis memoizes the passes over the open documents, keyed by path.
+// An edit re-runs the passes of the edited document only, requests on an unchanged document reuse the memos.
+// It is driven from the dispatch loop, which serializes the handlers.
+type Analysis struct {
+	DB *query.Database
+
+	Text *query.Input[string, string]
+
+	File    *query.Query[string, *build.File]
+	Tokens  *query.Query[string, []ast.Token]
+	Symbols *query.Query[string, *resolve.Info]
+	// Types holds the diagnostics of lowering the function bodies, which infers their types.
+	Types     *query.Query[string, []diagnosis.Diagnosis]
+	Diagnosis *query.Query[string, []diagnosis.Diagnosis]
+}
+
+func NewAnalysis() *Analysis {
+	a := &Analysis{DB: query.NewDatabase()}
+	a.Text = query.NewInput[string, string](a.DB, "text")
+
+	a.File = query.NewQuery(a.DB, "file", func(ctx *query.Context, path string) *build.File {
+		text, _ := a.Text.Get(ctx, path)
+		// Each text gets a file set of its own, positions of a document never outlive its text.
+		return build.ParseFile(token.NewFileSet(), path, []byte(text), parser.Options{})
+	})
+
+	a.Tokens = query.NewQuery(a.DB, "tokens", func(ctx *query.Context, path string) []ast.Token {
+		text, _ := a.Text.Get(ctx, path)
+		toks, _ := parser.ScanRecover(a.File.Get(ctx, path).TokenFile, []rune(text))
+		return toks
+	})
+
+	a.Symbols = query.NewQuery(a.DB, "symbols", func(ctx *query.Context, path string) *resolve.Info {
+		file := a.File.Get(ctx, path)
+		info := resolve.Resolve([]resolve.File{{Path: path, TokenFile: file.TokenFile, Decls: file.Decls}})
+		return &info
+	})
+
+	a.Types = query.NewQuery(a.DB, "types", func(ctx *query.Context, path string) []diagnosis.Diagnosis {
+		var diags []diagnosis.Diagnosis
+		for _, decl := range a.File.Get(ctx, path).Decls {
+			l := hir.NewLowerer()
+			switch decl.Tag {
+			case ast.StmtFuncDecl:
+				fn := decl.Value.(ast.FuncDecl)
+				if fn.Stmt == nil {
+					continue
+				}
+				l.LowerFunc(fn)
+			case ast.StmtValDecl:
+				l.LowerStmt(decl)
+			}
+			diags = append(diags, l.Diagnosis...)
+		}
+		return diags
+	})
+
+	a.Diagnosis = query.NewQuery(a.DB, "diagnosis", func(ctx *query.Context, path string) []diagnosis.Diagnosis {
+		file := a.File.Get(ctx, path)
+		diags := append([]diagnosis.Diagnosis(nil), file.Diagnosis...)
+		diags = append(diags, a.Types.Get(ctx, path)...)
+		diagnosis.Sort(diags)
+		return diags
+	})
+
+	return a
+}
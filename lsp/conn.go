@@ -0,0 +1,92 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MaxContentLength bounds the body of the messages read, a larger Content-Length is an error.
+const MaxContentLength = 64 << 20
+
+// Conn frames JSON-RPC messages with Content-Length headers.
+type Conn struct {
+	r *bufio.Reader
+	w io.Writer
+
+	mutex sync.Mutex
+}
+
+func NewConn(r io.Reader, w io.Writer) *Conn {
+	return &Conn{r: bufio.NewReader(r), w: w}
+}
+
+func (c *Conn) Read() (Message, error) {
+	length := -1
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return Message{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil || length < 0 {
+				return Message{}, fmt.Errorf("lsp: invalid Content-Length: %s", value)
+			}
+			if length > MaxContentLength {
+				return Message{}, fmt.Errorf("lsp: Content-Length %d exceeds %d", length, MaxContentLength)
+			}
+		}
+	}
+	if length < 0 {
+		return Message{}, fmt.Errorf("lsp: missing Content-Length")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return Message{}, err
+	}
+
+	var msg Message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return Message{}, &ResponseError{Code: CodeParseError, Message: err.Error()}
+	}
+	return msg, nil
+}
+
+func (c *Conn) Write(msg Message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(body)
+	return err
+}
+
+func (c *Conn) Notify(method string, params any) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.Write(Message{Method: method, Params: raw})
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// frame wraps a body in the headers of a message.
+func frame(headers, body string) string {
+	return fmt.Sprintf("%sContent-Length: %d\r\n\r\n%s", headers, len(body), body)
+}
+
+func TestConnRead(t *testing.T) {
+	in := frame("", `{"jsonrpc":"2.0","id":1,"method":"initialize"}`) +
+		frame("Content-Type: application/vscode-jsonrpc; charset=utf-8\r\n", `{"jsonrpc":"2.0","method":"initialized","params":{}}`) +
+		strings.Replace(frame("", `{"jsonrpc":"2.0","method":"exit"}`), "Content-Length", "content-length", 1)
+
+	// Messages arrive in pieces, the body included.
+	c := NewConn(iotest.OneByteReader(strings.NewReader(in)), nil)
+	for _, want := range []string{"initialize", "initialized", "exit"} {
+		msg, err := c.Read()
+		if err != nil || msg.Method != want {
+			t.Fatalf("Read = %+v, %v, want %s", msg, err, want)
+		}
+	}
+	if _, err := c.Read(); !errors.Is(err, io.EOF) {
+		t.Errorf("Read at the end = %v", err)
+	}
+}
+
+func TestConnReadErrors(t *testing.T) {
+	tests := []struct {
+		name, in string
+		check    func(error) bool
+	}{
+		{"missing length", "Content-Type: x\r\n\r\n{}", func(err error) bool { return strings.Contains(err.Error(), "missing Content-Length") }},
+		{"invalid length", "Content-Length: ten\r\n\r\n{}", func(err error) bool { return strings.Contains(err.Error(), "invalid Content-Length") }},
+		{"negative length", "Content-Length: -1\r\n\r\n{}", func(err error) bool { return strings.Contains(err.Error(), "invalid Content-Length") }},
+		{"oversized length", "Content-Length: 1000000000000\r\n\r\n{}", func(err error) bool { return strings.Contains(err.Error(), "exceeds") }},
+		{"truncated body", "Content-Length: 10\r\n\r\n{}", func(err error) bool { return errors.Is(err, io.ErrUnexpectedEOF) }},
+		{"invalid json", frame("", "{"), func(err error) bool {
+			var rerr *ResponseError
+			return errors.As(err, &rerr) && rerr.Code == CodeParseError
+		}},
+	}
+	for _, test := range tests {
+		if _, err := NewConn(strings.NewReader(test.in), nil).Read(); err == nil || !test.check(err) {
+			t.Errorf("%s: Read = %v", test.name, err)
+		}
+	}
+}
+
+func TestConnWrite(t *testing.T) {
+	var out bytes.Buffer
+	c := NewConn(nil, &out)
+	if err := c.Notify("window/logMessage", map[string]string{"message": "héllo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	header, body, ok := strings.Cut(out.String(), "\r\n\r\n")
+	if !ok || header != fmt.Sprintf("Content-Length: %d", len(body)) {
+		t.Fatalf("framed as %q", out.String())
+	}
+	var msg Message
+	if err := json.Unmarshal([]byte(body), &msg); err != nil || msg.JSONRPC != "2.0" || msg.Method != "window/logMessage" {
+		t.Errorf("body %s: %v", body, err)
+	}
+
+	// What is written reads back.
+	read, err := NewConn(&out, nil).Read()
+	if err != nil || read.Method != msg.Method || string(read.Params) != string(msg.Params) {
+		t.Errorf("Read = %+v, %v", read, err)
+	}
+}
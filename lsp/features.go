@@ -0,0 +1,420 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package lsp
+
+import (
+	"cee/format"
+	"cee/ide"
+	"cee/loader"
+	"cee/resolve"
+	"cee/token"
+	"cee/xref"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+func PathURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// spanRange converts a span of the text of its file, nil if the file is not open.
+func spanRange(text []rune, span xref.Span) Range {
+	return Range{Start: NewPosition(text, span.From), End: NewPosition(text, span.To)}
+}
+
+func (s *Server) location(span xref.Span) Location {
+	for _, doc := range s.Documents() {
+		if doc.Path() == span.File {
+			return Location{URI: doc.URI, Range: spanRange([]rune(doc.Text), span)}
+		}
+	}
+	return Location{URI: PathURI(span.File), Range: spanRange(nil, span)}
+}
+
+// positionParams decodes the params and locates the open document and the rune offset of the position.
+func positionParams[T any](s *Server, params json.RawMessage, pos func(T) TextDocumentPositionParams) (T, *Document, int, error) {
+	p, err := decode[T](params)
+	if err != nil {
+		return p, nil, 0, err
+	}
+	tdp := pos(p)
+	doc, ok := s.Document(tdp.TextDocument.URI)
+	if !ok || doc.Info == nil {
+		return p, nil, 0, fmt.Errorf("lsp: document not open: %s", tdp.TextDocument.URI)
+	}
+	return p, doc, Offset([]rune(doc.Text), tdp.Position), nil
+}
+
+func definition(s *Server, params json.RawMessage) (any, error) {
+	_, doc, offset, err := positionParams(s, params, func(p TextDocumentPositionParams) TextDocumentPositionParams { return p })
+	if err != nil {
+		return nil, err
+	}
+	span, ok := ide.Definition(doc.Info, doc.Path(), offset)
+	if !ok {
+		s.mutex.Lock()
+		span, ok = ide.MemberDefinition(s.Index, doc.Info, doc.Path(), offset, s.packageDir(doc))
+		s.mutex.Unlock()
+	}
+	if !ok {
+		return nil, nil
+	}
+	return s.location(span), nil
+}
+
+// packageDir returns the directory of imported packages, by the manifest of the module of the document.
+func (s *Server) packageDir(doc *Document) func(name string) (string, bool) {
+	return func(name string) (string, bool) {
+		path, err := loader.FindManifest(s.Files, doc.Package())
+		if err != nil || path == "" {
+			return "", false
+		}
+		res, err := loader.LoadResolver(s.Files, path, loader.DefaultModCache())
+		if err != nil {
+			return "", false
+		}
+		return res.Dir(name)
+	}
+}
+
+func references(s *Server, params json.RawMessage) (any, error) {
+	p, doc, offset, err := positionParams(s, params, func(p ReferenceParams) TextDocumentPositionParams { return p.TextDocumentPositionParams })
+	if err != nil {
+		return nil, err
+	}
+	id, ok := ide.SymbolAt(doc.Info, doc.Package(), doc.Path(), offset)
+	if !ok {
+		return []Location{}, nil
+	}
+
+	s.mutex.Lock()
+	spans := ide.References(s.Index, id, p.Context.IncludeDeclaration)
+	s.mutex.Unlock()
+
+	locations := make([]Location, len(spans))
+	for i, span := range spans {
+		locations[i] = s.location(span)
+	}
+	return locations, nil
+}
+
+func signatureHelp(s *Server, params json.RawMessage) (any, error) {
+	_, doc, offset, err := positionParams(s, params, func(p TextDocumentPositionParams) TextDocumentPositionParams { return p })
+	if err != nil {
+		return nil, err
+	}
+	help, ok := ide.Signature([]rune(doc.Text), doc.Info, doc.Path(), offset)
+	if !ok {
+		return nil, nil
+	}
+
+	sig := SignatureInformation{Label: help.Label, Parameters: []ParameterInformation{}}
+	for _, param := range help.Params {
+		sig.Parameters = append(sig.Parameters, ParameterInformation{Label: param.Label})
+	}
+	return SignatureHelp{Signatures: []SignatureInformation{sig}, ActiveParameter: help.ActiveParameter}, nil
+}
+
+var symbolKinds = map[ide.SymbolKind]int{
+	ide.SymbolType:   LSPSymbolClass,
+	ide.SymbolStruct: LSPSymbolStruct,
+	ide.SymbolTrait:  LSPSymbolInterface,
+	ide.SymbolField:  LSPSymbolField,
+	ide.SymbolFunc:   LSPSymbolFunction,
+	ide.SymbolExtern: LSPSymbolFunction,
+	ide.SymbolVar:    LSPSymbolVariable,
+}
+
+func convertSymbols(file *token.File, symbols []ide.DocumentSymbol) []DocumentSymbol {
+	result := make([]DocumentSymbol, len(symbols))
+	for i, sym := range symbols {
+		result[i] = DocumentSymbol{
+			Name:           sym.Name,
+			Detail:         sym.Detail,
+			Kind:           symbolKinds[sym.Kind],
+			Range:          NewRange(file, sym.Range),
+			SelectionRange: NewRange(file, sym.Selection),
+			Children:       convertSymbols(file, sym.Children),
+		}
+	}
+	return result
+}
+
+// document locates an open and parsed document.
+func document(s *Server, uri string) (*Document, error) {
+	doc, ok := s.Document(uri)
+	if !ok || doc.File == nil {
+		return nil, fmt.Errorf("lsp: document not open: %s", uri)
+	}
+	return doc, nil
+}
+
+func documentSymbol(s *Server, params json.RawMessage) (any, error) {
+	p, err := decode[DocumentSymbolParams](params)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := document(s, p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return convertSymbols(doc.File.TokenFile, ide.DocumentSymbols(doc.File.Decls)), nil
+}
+
+var objectKinds = map[resolve.ObjKind]int{
+	resolve.ObjType:   LSPSymbolClass,
+	resolve.ObjFunc:   LSPSymbolFunction,
+	resolve.ObjExtern: LSPSymbolFunction,
+	resolve.ObjVar:    LSPSymbolVariable,
+	resolve.ObjParam:  LSPSymbolVariable,
+
+	resolve.ObjTypeParam: LSPSymbolTypeParam,
+}
+
+const workspaceSymbolLimit = 256
+
+func workspaceSymbol(s *Server, params json.RawMessage) (any, error) {
+	p, err := decode[WorkspaceSymbolParams](params)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	symbols := ide.WorkspaceSymbols(s.Index, p.Query, workspaceSymbolLimit)
+	s.mutex.Unlock()
+
+	result := make([]SymbolInformation, len(symbols))
+	for i, sym := range symbols {
+		result[i] = SymbolInformation{
+			Name:          sym.Name,
+			Kind:          objectKinds[sym.Kind],
+			Location:      s.location(sym.Def),
+			ContainerName: sym.Package,
+		}
+	}
+	return result, nil
+}
+
+var foldingKinds = map[ide.FoldingKind]string{
+	ide.FoldRegion:  "region",
+	ide.FoldComment: "comment",
+	ide.FoldImports: "imports",
+}
+
+func foldingRange(s *Server, params json.RawMessage) (any, error) {
+	p, err := decode[FoldingRangeParams](params)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := document(s, p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	text := []rune(doc.Text)
+	ranges := ide.FoldingRanges(doc.File.TokenFile, doc.File.Decls, doc.File.Comments)
+	result := make([]FoldingRange, len(ranges))
+	for i, r := range ranges {
+		from, to := NewPosition(text, r.From), NewPosition(text, r.To)
+		result[i] = FoldingRange{
+			StartLine:      from.Line,
+			StartCharacter: from.Character,
+			EndLine:        to.Line,
+			EndCharacter:   to.Character,
+			Kind:           foldingKinds[r.Kind],
+		}
+	}
+	return result, nil
+}
+
+func inlayHint(s *Server, params json.RawMessage) (any, error) {
+	p, err := decode[InlayHintParams](params)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := document(s, p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	text := []rune(doc.Text)
+	hints := ide.InlayHints(doc.File.Decls, doc.Info, doc.Path(), Offset(text, p.Range.Start), Offset(text, p.Range.End))
+
+	result := make([]InlayHint, len(hints))
+	for i, hint := range hints {
+		result[i] = InlayHint{
+			Position: PositionAt(text, hint.Offset),
+			Label:    hint.Label,
+			Kind:     int(hint.Kind), // the protocol uses 1 for types and 2 for parameters as well
+		}
+		if hint.Kind == ide.InlayParameter {
+			result[i].PaddingRight = true
+		}
+	}
+	return result, nil
+}
+
+func (s *Server) callHierarchyItem(info *resolve.Info, obj *resolve.Object) CallHierarchyItem {
+	file := info.Files[obj.File]
+	loc := s.location(xref.NewSpan(file, obj.Ident))
+	item := CallHierarchyItem{
+		Name:           obj.Name,
+		Kind:           objectKinds[obj.Kind],
+		URI:            loc.URI,
+		Range:          NewRange(file, obj.Decl.GetPosRange()),
+		SelectionRange: loc.Range,
+	}
+	item.Data.Path = obj.File
+	item.Data.Offset = file.Offset(obj.Ident.From)
+	return item
+}
+
+// callGraphs builds the call graph of every open document.
+func (s *Server) callGraphs() []ide.CallGraph {
+	var graphs []ide.CallGraph
+	for _, doc := range s.Documents() {
+		if doc.File == nil || doc.Info == nil {
+			continue
+		}
+		graphs = append(graphs, ide.BuildCallGraph([]resolve.File{{Path: doc.Path(), TokenFile: doc.File.TokenFile, Decls: doc.File.Decls}}, doc.Info))
+	}
+	return graphs
+}
+
+func prepareCallHierarchy(s *Server, params json.RawMessage) (any, error) {
+	_, doc, offset, err := positionParams(s, params, func(p TextDocumentPositionParams) TextDocumentPositionParams { return p })
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := ide.ObjectAt(doc.Info, doc.Path(), offset)
+	if !ok || obj.Kind != resolve.ObjFunc && obj.Kind != resolve.ObjExtern {
+		return nil, nil
+	}
+	return []CallHierarchyItem{s.callHierarchyItem(doc.Info, obj)}, nil
+}
+
+func (s *Server) ranges(spans []xref.Span) []Range {
+	ranges := make([]Range, len(spans))
+	for i, span := range spans {
+		ranges[i] = s.location(span).Range
+	}
+	return ranges
+}
+
+func incomingCalls(s *Server, params json.RawMessage) (any, error) {
+	p, err := decode[CallHierarchyCallsParams](params)
+	if err != nil {
+		return nil, err
+	}
+	ref := resolve.Ref{File: p.Item.Data.Path, Offset: p.Item.Data.Offset}
+
+	result := []CallHierarchyIncomingCall{}
+	for _, g := range s.callGraphs() {
+		for _, call := range g.Incoming(ref) {
+			result = append(result, CallHierarchyIncomingCall{From: s.callHierarchyItem(g.Info, call.Object), FromRanges: s.ranges(call.Sites)})
+		}
+	}
+	return result, nil
+}
+
+func outgoingCalls(s *Server, params json.RawMessage) (any, error) {
+	p, err := decode[CallHierarchyCallsParams](params)
+	if err != nil {
+		return nil, err
+	}
+	ref := resolve.Ref{File: p.Item.Data.Path, Offset: p.Item.Data.Offset}
+
+	result := []CallHierarchyOutgoingCall{}
+	for _, g := range s.callGraphs() {
+		for _, call := range g.Outgoing(ref) {
+			result = append(result, CallHierarchyOutgoingCall{To: s.callHierarchyItem(g.Info, call.Object), FromRanges: s.ranges(call.Sites)})
+		}
+	}
+	return result, nil
+}
+
+func selectionRange(s *Server, params json.RawMessage) (any, error) {
+	p, err := decode[SelectionRangeParams](params)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := document(s, p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	text := []rune(doc.Text)
+	file := doc.File.TokenFile
+	toks := s.Analysis.Tokens.Get(nil, doc.Path())
+
+	result := make([]SelectionRange, len(p.Positions))
+	for i, pos := range p.Positions {
+		ranges := ide.SelectionRanges(doc.File.Decls, toks, file.Pos(Offset(text, pos)))
+		if len(ranges) == 0 {
+			result[i] = SelectionRange{Range: Range{Start: pos, End: pos}}
+			continue
+		}
+		// Build the chain from the outermost range in.
+		var parent *SelectionRange
+		for j := len(ranges) - 1; j >= 0; j-- {
+			parent = &SelectionRange{Range: NewRange(file, ranges[j]), Parent: parent}
+		}
+		result[i] = *parent
+	}
+	return result, nil
+}
+
+// textEdits formats the document with the client's options and returns the edits intersecting the rune
+// offsets from and to, the whole document when to is negative.
+func textEdits(doc *Document, options FormattingOptions, from, to int) ([]TextEdit, error) {
+	var o format.Options
+	if options.InsertSpaces {
+		o.Indent = strings.Repeat(" ", max(options.TabSize, 1))
+	}
+	edits, err := o.Edits([]byte(doc.Text))
+	if err != nil {
+		return nil, err
+	}
+
+	text := []rune(doc.Text)
+	result := []TextEdit{}
+	for _, edit := range edits {
+		if to >= 0 && (edit.To < from || edit.From > to) {
+			continue
+		}
+		result = append(result, TextEdit{
+			Range:   Range{Start: PositionAt(text, edit.From), End: PositionAt(text, edit.To)},
+			NewText: edit.Text,
+		})
+	}
+	return result, nil
+}
+
+func formatting(s *Server, params json.RawMessage) (any, error) {
+	p, err := decode[DocumentFormattingParams](params)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := document(s, p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return textEdits(doc, p.Options, 0, -1)
+}
+
+func rangeFormatting(s *Server, params json.RawMessage) (any, error) {
+	p, err := decode[DocumentRangeFormattingParams](params)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := document(s, p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	text := []rune(doc.Text)
+	return textEdits(doc, p.Options, Offset(text, p.Range.Start), Offset(text, p.Range.End))
+}
@@ -0,0 +1,361 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package lsp
+
+import (
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/token"
+	"encoding/json"
+	"strings"
+)
+
+// Subset of the protocol types, see https://microsoft.github.io/language-server-protocol/.
+
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// NewPosition converts a decoded zero-based position of text. Decoded columns count runes while the protocol
+// counts UTF-16 code units, the column is kept as it is when the text is not known.
+func NewPosition(text []rune, pos token.Position) Position {
+	character := pos.Column
+	if begin := pos.Offset - pos.Column; begin >= 0 && pos.Offset <= len(text) {
+		character = utf16Len(text[begin:pos.Offset])
+	}
+	return Position{Line: pos.Line, Character: character}
+}
+
+// NewRange decodes a range of the file.
+func NewRange(file *token.File, pos ast.PosRange) Range {
+	text := file.Content()
+	return Range{Start: NewPosition(text, file.Position(pos.From)), End: NewPosition(text, file.Position(pos.To))}
+}
+
+// utf16Units is the number of UTF-16 code units encoding r.
+func utf16Units(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+func utf16Len(runes []rune) int {
+	n := 0
+	for _, r := range runes {
+		n += utf16Units(r)
+	}
+	return n
+}
+
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+type Severity int
+
+const (
+	_ Severity = iota
+
+	SeverityError
+	SeverityWarning
+	SeverityInformation
+	SeverityHint
+)
+
+// severities maps the severity of diagnostics to the protocol.
+var severities = map[diagnosis.Severity]Severity{
+	diagnosis.SeverityError:   SeverityError,
+	diagnosis.SeverityWarning: SeverityWarning,
+	diagnosis.SeverityInfo:    SeverityInformation,
+}
+
+type Diagnostic struct {
+	Range              Range                          `json:"range"`
+	Severity           Severity                       `json:"severity"`
+	Source             string                         `json:"source"`
+	Message            string                         `json:"message"`
+	RelatedInformation []DiagnosticRelatedInformation `json:"relatedInformation,omitempty"`
+}
+
+type DiagnosticRelatedInformation struct {
+	Location Location `json:"location"`
+	Message  string   `json:"message"`
+}
+
+type PublishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Version     int          `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+type TextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type VersionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+}
+
+type TextDocumentContentChangeEvent struct {
+	Range *Range `json:"range,omitempty"`
+	Text  string `json:"text"`
+}
+
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+type DidChangeTextDocumentParams struct {
+	TextDocument   VersionedTextDocumentIdentifier  `json:"textDocument"`
+	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type TextDocumentSyncKind int
+
+const (
+	SyncNone TextDocumentSyncKind = iota
+	SyncFull
+	SyncIncremental
+)
+
+type ServerCapabilities map[string]any
+
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+	ServerInfo   struct {
+		Name string `json:"name"`
+	} `json:"serverInfo"`
+}
+
+// Offset converts a protocol position, whose character counts UTF-16 code units, into a rune offset of the text.
+// A character inside a surrogate pair is past the rune.
+func Offset(text []rune, pos Position) int {
+	line, offset := 0, 0
+	for offset < len(text) && line < pos.Line {
+		if text[offset] == '\n' {
+			line++
+		}
+		offset++
+	}
+	for units := 0; units < pos.Character && offset < len(text) && text[offset] != '\n'; offset++ {
+		units += utf16Units(text[offset])
+	}
+	return offset
+}
+
+// PositionAt converts a rune offset of the text into a protocol position.
+func PositionAt(text []rune, offset int) Position {
+	var pos Position
+	for _, r := range text[:min(offset, len(text))] {
+		if r == '\n' {
+			pos.Line++
+			pos.Character = 0
+		} else {
+			pos.Character += utf16Units(r)
+		}
+	}
+	return pos
+}
+
+// applyChange applies a content change, a change without range replaces the whole text.
+func applyChange(text string, change TextDocumentContentChangeEvent) string {
+	if change.Range == nil {
+		return change.Text
+	}
+	runes := []rune(text)
+	from, to := Offset(runes, change.Range.Start), Offset(runes, change.Range.End)
+	var b strings.Builder
+	b.WriteString(string(runes[:from]))
+	b.WriteString(change.Text)
+	b.WriteString(string(runes[to:]))
+	return b.String()
+}
+
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ResponseError) Error() string { return e.Message }
+
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+type Message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  any              `json:"result,omitempty"`
+	Error   *ResponseError   `json:"error,omitempty"`
+}
+
+type ReferenceParams struct {
+	TextDocumentPositionParams
+	Context struct {
+		IncludeDeclaration bool `json:"includeDeclaration"`
+	} `json:"context"`
+}
+
+type ParameterInformation struct {
+	Label string `json:"label"`
+}
+
+type SignatureInformation struct {
+	Label      string                 `json:"label"`
+	Parameters []ParameterInformation `json:"parameters"`
+}
+
+type SignatureHelp struct {
+	Signatures      []SignatureInformation `json:"signatures"`
+	ActiveSignature int                    `json:"activeSignature"`
+	ActiveParameter int                    `json:"activeParameter"`
+}
+
+// Symbol kinds of the protocol.
+const (
+	LSPSymbolClass     = 5
+	LSPSymbolField     = 8
+	LSPSymbolInterface = 11
+	LSPSymbolFunction  = 12
+	LSPSymbolVariable  = 13
+	LSPSymbolStruct    = 23
+	LSPSymbolTypeParam = 26
+)
+
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           int              `json:"kind"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type WorkspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
+type SymbolInformation struct {
+	Name          string   `json:"name"`
+	Kind          int      `json:"kind"`
+	Location      Location `json:"location"`
+	ContainerName string   `json:"containerName,omitempty"`
+}
+
+type FoldingRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type FoldingRange struct {
+	StartLine      int    `json:"startLine"`
+	StartCharacter int    `json:"startCharacter"`
+	EndLine        int    `json:"endLine"`
+	EndCharacter   int    `json:"endCharacter"`
+	Kind           string `json:"kind,omitempty"`
+}
+
+type InlayHintParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+}
+
+type InlayHint struct {
+	Position     Position `json:"position"`
+	Label        string   `json:"label"`
+	Kind         int      `json:"kind"`
+	PaddingRight bool     `json:"paddingRight,omitempty"`
+}
+
+type CallHierarchyItem struct {
+	Name           string `json:"name"`
+	Kind           int    `json:"kind"`
+	URI            string `json:"uri"`
+	Range          Range  `json:"range"`
+	SelectionRange Range  `json:"selectionRange"`
+	Data           struct {
+		Path   string `json:"path"`
+		Offset int    `json:"offset"`
+	} `json:"data"`
+}
+
+type CallHierarchyCallsParams struct {
+	Item CallHierarchyItem `json:"item"`
+}
+
+type CallHierarchyIncomingCall struct {
+	From       CallHierarchyItem `json:"from"`
+	FromRanges []Range           `json:"fromRanges"`
+}
+
+type CallHierarchyOutgoingCall struct {
+	To         CallHierarchyItem `json:"to"`
+	FromRanges []Range           `json:"fromRanges"`
+}
+
+type SelectionRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Positions    []Position             `json:"positions"`
+}
+
+type SelectionRange struct {
+	Range  Range           `json:"range"`
+	Parent *SelectionRange `json:"parent,omitempty"`
+}
+
+type FormattingOptions struct {
+	TabSize      int  `json:"tabSize"`
+	InsertSpaces bool `json:"insertSpaces"`
+}
+
+type DocumentFormattingParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Options      FormattingOptions      `json:"options"`
+}
+
+type DocumentRangeFormattingParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Options      FormattingOptions      `json:"options"`
+}
+
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
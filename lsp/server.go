@@ -0,0 +1,340 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package lsp
+
+import (
+	"cee/ast"
+	"cee/build"
+	"cee/diagnosis"
+	"cee/loader"
+	"cee/metrics"
+	"cee/resolve"
+	"cee/token"
+	"cee/xref"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Document is the server side state of an open text document.
+type Document struct {
+	URI     string
+	Version int
+	Text    string
+
+	File *build.File
+	Info *resolve.Info
+}
+
+func (d *Document) Package() string { return filepath.Dir(d.Path()) }
+
+func (d *Document) Path() string {
+	if u, err := url.Parse(d.URI); err == nil && u.Scheme == "file" {
+		return u.Path
+	}
+	return d.URI
+}
+
+// Handler serves a request or a notification, the result of notifications is discarded.
+type Handler func(s *Server, params json.RawMessage) (any, error)
+
+type Server struct {
+	Conn *Conn
+
+	Handlers     map[string]Handler
+	Capabilities ServerCapabilities
+
+	// Index spans every open document, keyed by package directory.
+	Index *xref.Index
+
+	// Files layers the open documents over the disk, builds run by the server see unsaved changes.
+	Files *loader.Overlay
+
+	Analysis *Analysis
+
+	// Metrics receives the duration of every request and the memo hits of the analysis.
+	Metrics metrics.Metrics
+
+	mutex     sync.Mutex
+	documents map[string]*Document
+
+	shutdown bool
+}
+
+func NewServer(r io.Reader, w io.Writer) *Server {
+	s := &Server{
+		Conn:         NewConn(r, w),
+		Handlers:     map[string]Handler{},
+		Capabilities: ServerCapabilities{"textDocumentSync": SyncFull},
+		documents:    map[string]*Document{},
+		Index:        xref.NewIndex(),
+		Files:        loader.NewOverlay(loader.DiskSource{}),
+		Analysis:     NewAnalysis(),
+		Metrics:      metrics.Nop{},
+	}
+
+	s.Handle("initialize", initialize)
+	s.Handle("initialized", func(*Server, json.RawMessage) (any, error) { return nil, nil })
+	s.Handle("shutdown", func(s *Server, _ json.RawMessage) (any, error) {
+		s.shutdown = true
+		return nil, nil
+	})
+	s.Handle("textDocument/didOpen", didOpen)
+	s.Handle("textDocument/didChange", didChange)
+	s.Handle("textDocument/didClose", didClose)
+	s.Handle("textDocument/definition", definition)
+	s.Capabilities["definitionProvider"] = true
+	s.Handle("textDocument/references", references)
+	s.Capabilities["referencesProvider"] = true
+	s.Handle("textDocument/signatureHelp", signatureHelp)
+	s.Capabilities["signatureHelpProvider"] = map[string]any{"triggerCharacters": []string{"(", ","}}
+	s.Handle("textDocument/documentSymbol", documentSymbol)
+	s.Capabilities["documentSymbolProvider"] = true
+	s.Handle("workspace/symbol", workspaceSymbol)
+	s.Capabilities["workspaceSymbolProvider"] = true
+	s.Handle("textDocument/foldingRange", foldingRange)
+	s.Capabilities["foldingRangeProvider"] = true
+	s.Handle("textDocument/inlayHint", inlayHint)
+	s.Capabilities["inlayHintProvider"] = true
+	s.Handle("textDocument/prepareCallHierarchy", prepareCallHierarchy)
+	s.Handle("callHierarchy/incomingCalls", incomingCalls)
+	s.Handle("callHierarchy/outgoingCalls", outgoingCalls)
+	s.Capabilities["callHierarchyProvider"] = true
+	s.Handle("textDocument/selectionRange", selectionRange)
+	s.Capabilities["selectionRangeProvider"] = true
+	s.Handle("textDocument/formatting", formatting)
+	s.Capabilities["documentFormattingProvider"] = true
+	s.Handle("textDocument/rangeFormatting", rangeFormatting)
+	s.Capabilities["documentRangeFormattingProvider"] = true
+
+	return s
+}
+
+func (s *Server) Handle(method string, h Handler) { s.Handlers[method] = h }
+
+func (s *Server) Document(uri string) (*Document, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	doc, ok := s.documents[uri]
+	return doc, ok
+}
+
+// Documents returns a snapshot of the open documents.
+func (s *Server) Documents() []*Document {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	docs := make([]*Document, 0, len(s.documents))
+	for _, doc := range s.documents {
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func decode[T any](params json.RawMessage) (T, error) {
+	var v T
+	if err := json.Unmarshal(params, &v); err != nil {
+		return v, &ResponseError{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return v, nil
+}
+
+func initialize(s *Server, _ json.RawMessage) (any, error) {
+	var result InitializeResult
+	result.Capabilities = s.Capabilities
+	result.ServerInfo.Name = "cee"
+	return result, nil
+}
+
+func didOpen(s *Server, params json.RawMessage) (any, error) {
+	p, err := decode[DidOpenTextDocumentParams](params)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{URI: p.TextDocument.URI, Version: p.TextDocument.Version, Text: p.TextDocument.Text}
+	s.mutex.Lock()
+	s.documents[doc.URI] = doc
+	s.mutex.Unlock()
+	return nil, s.update(doc)
+}
+
+func didChange(s *Server, params json.RawMessage) (any, error) {
+	p, err := decode[DidChangeTextDocumentParams](params)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := s.Document(p.TextDocument.URI)
+	if !ok {
+		return nil, fmt.Errorf("lsp: document not open: %s", p.TextDocument.URI)
+	}
+	text := doc.Text
+	for _, change := range p.ContentChanges {
+		text = applyChange(text, change)
+	}
+
+	updated := &Document{URI: doc.URI, Version: p.TextDocument.Version, Text: text}
+	s.mutex.Lock()
+	s.documents[doc.URI] = updated
+	s.mutex.Unlock()
+	return nil, s.update(updated)
+}
+
+func didClose(s *Server, params json.RawMessage) (any, error) {
+	p, err := decode[DidCloseTextDocumentParams](params)
+	if err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	if doc, ok := s.documents[p.TextDocument.URI]; ok {
+		s.Index.RemoveFile(doc.Path())
+		s.Files.Delete(doc.Path())
+		s.Analysis.Text.Remove(doc.Path())
+	}
+	delete(s.documents, p.TextDocument.URI)
+	s.mutex.Unlock()
+	// Clear the diagnostics of the closed document.
+	return nil, s.Conn.Notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}})
+}
+
+// update re-analyzes a document and publishes its diagnostics.
+func (s *Server) update(doc *Document) error {
+	s.Files.Set(doc.Path(), []byte(doc.Text))
+	s.Analysis.Text.Set(doc.Path(), doc.Text)
+	doc.File = s.Analysis.File.Get(nil, doc.Path())
+	doc.Info = s.Analysis.Symbols.Get(nil, doc.Path())
+
+	s.mutex.Lock()
+	s.Index.RemoveFile(doc.Path())
+	s.Index.Add(doc.Package(), *doc.Info)
+	s.mutex.Unlock()
+
+	diagnostics := []Diagnostic{}
+	if doc.File.Err != nil {
+		diagnostics = append(diagnostics, Diagnostic{Severity: SeverityError, Source: "cee", Message: doc.File.Err.Error()})
+	}
+	for _, d := range s.Analysis.Diagnosis.Get(nil, doc.Path()) {
+		if d.Severity == diagnosis.SeverityIgnore {
+			continue
+		}
+		diagnostic := Diagnostic{Severity: severities[d.Severity], Source: "cee", Message: fmt.Sprint(d.Error)}
+		if err, ok := d.Error.(error); ok {
+			diagnostic.Message = err.Error()
+		}
+		if node, ok := d.Error.(ast.Node); ok {
+			pos := node.GetPosRange()
+			if _, x, body := doc.File.Macros.Source(pos.From); x != nil {
+				// Expanded code is reported at the invocation, with the token of the body it comes from.
+				pos = x.Call
+				diagnostic.Message += " (in expansion of " + x.Macro.Ident.Literal + "!)"
+				if body.IsValid() {
+					diagnostic.RelatedInformation = []DiagnosticRelatedInformation{{
+						Location: Location{URI: doc.URI, Range: NewRange(doc.File.TokenFile, ast.PosRange{From: body, To: body})},
+						Message:  "expanded from the body of " + x.Macro.Ident.Literal,
+					}}
+				}
+			}
+			diagnostic.Range = NewRange(doc.File.TokenFile, pos)
+		}
+		if e, ok := d.Error.(diagnosis.MismatchedDelimiterError); ok && e.Open.Kind != 0 && e.Close.Kind != token.EOF {
+			diagnostic.RelatedInformation = []DiagnosticRelatedInformation{{
+				Location: Location{URI: doc.URI, Range: NewRange(doc.File.TokenFile, e.Open.PosRange)},
+				Message:  "unclosed " + e.Open.Literal,
+			}}
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+
+	return s.Conn.Notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         doc.URI,
+		Version:     doc.Version,
+		Diagnostics: diagnostics,
+	})
+}
+
+// Serve runs the server loop until the exit notification or the end of input.
+func (s *Server) Serve() error {
+	for {
+		msg, err := s.Conn.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			var rerr *ResponseError
+			if errors.As(err, &rerr) {
+				_ = s.Conn.Write(Message{Error: rerr})
+				continue
+			}
+			return err
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("lsp: exit before shutdown")
+			}
+			return nil
+		}
+
+		s.dispatch(msg)
+	}
+}
+
+func (s *Server) dispatch(msg Message) {
+	if s.shutdown {
+		// Only exit is expected after shutdown, notifications are dropped.
+		if msg.ID != nil {
+			_ = s.Conn.Write(Message{ID: msg.ID, Error: &ResponseError{Code: CodeInvalidRequest, Message: "server is shut down: " + msg.Method}})
+		}
+		return
+	}
+
+	h, ok := s.Handlers[msg.Method]
+	if !ok {
+		if msg.ID != nil {
+			_ = s.Conn.Write(Message{ID: msg.ID, Error: &ResponseError{Code: CodeMethodNotFound, Message: "method not found: " + msg.Method}})
+		}
+		return
+	}
+
+	hits, misses := s.Analysis.DB.Stats()
+	start := time.Now()
+	result, err := s.call(h, msg.Params)
+	metrics.Since(s.Metrics, metrics.RequestSeconds+msg.Method, start)
+	if err != nil {
+		s.Metrics.Add(metrics.RequestErrors, 1)
+	}
+	newHits, newMisses := s.Analysis.DB.Stats()
+	s.Metrics.Add(metrics.QueryHits, int64(newHits-hits))
+	s.Metrics.Add(metrics.QueryMisses, int64(newMisses-misses))
+	if msg.ID == nil {
+		return
+	}
+
+	resp := Message{ID: msg.ID, Result: result}
+	if err != nil {
+		var rerr *ResponseError
+		if !errors.As(err, &rerr) {
+			rerr = &ResponseError{Code: CodeInternalError, Message: err.Error()}
+		}
+		resp.Error = rerr
+		resp.Result = nil
+	} else if result == nil {
+		resp.Result = json.RawMessage("null")
+	}
+	_ = s.Conn.Write(resp)
+}
+
+// call runs the handler, a panic fails the request instead of the server.
+func (s *Server) call(h Handler, params json.RawMessage) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &ResponseError{Code: CodeInternalError, Message: fmt.Sprint("lsp: panic: ", r)}
+		}
+	}()
+	return h(s, params)
+}
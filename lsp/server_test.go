@@ -0,0 +1,217 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package lsp
+
+import (
+	"bytes"
+	"cee/token"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+)
+
+// session serves the requests and notifications, given as method and params pairs, numbering the requests
+// in ids, and returns what the server wrote.
+func session(t *testing.T, ids map[string]int, calls ...any) []Message {
+	t.Helper()
+	var in bytes.Buffer
+	client := NewConn(nil, &in)
+	for i := 0; i < len(calls); i += 2 {
+		method := calls[i].(string)
+		params, err := json.Marshal(calls[i+1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := Message{Method: method, Params: params}
+		if id, ok := ids[method]; ok {
+			raw := json.RawMessage(strconv.Itoa(id))
+			msg.ID = &raw
+		}
+		if err := client.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := NewServer(&in, &out).Serve(); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	var msgs []Message
+	server := NewConn(&out, nil)
+	for {
+		msg, err := server.Read()
+		if errors.Is(err, io.EOF) {
+			return msgs
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+func TestServerSession(t *testing.T) {
+	const uri = "file:///work/a/a.cee"
+	msgs := session(t, map[string]int{"initialize": 1, "textDocument/unknown": 2, "shutdown": 3, "textDocument/definition": 4},
+		"initialize", map[string]any{"processId": nil},
+		"initialized", map[string]any{},
+		"textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{
+			URI: uri, LanguageID: "cee", Version: 1, Text: "package a\n\nfun f() {\n\tval x = (1\n}\n",
+		}},
+		"textDocument/didChange", DidChangeTextDocumentParams{
+			TextDocument: VersionedTextDocumentIdentifier{URI: uri, Version: 2},
+			ContentChanges: []TextDocumentContentChangeEvent{{
+				Range: &Range{Start: Position{Line: 3, Character: 11}, End: Position{Line: 3, Character: 11}},
+				Text:  ")",
+			}},
+		},
+		"textDocument/unknown", map[string]any{},
+		"shutdown", nil,
+		"textDocument/definition", TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}},
+		"exit", nil,
+	)
+
+	var (
+		results     = map[string]Message{}
+		diagnostics []PublishDiagnosticsParams
+	)
+	for _, msg := range msgs {
+		switch {
+		case msg.Method == "textDocument/publishDiagnostics":
+			var p PublishDiagnosticsParams
+			if err := json.Unmarshal(msg.Params, &p); err != nil {
+				t.Fatal(err)
+			}
+			diagnostics = append(diagnostics, p)
+		case msg.ID != nil:
+			results[string(*msg.ID)] = msg
+		}
+	}
+
+	init, _ := json.Marshal(results["1"].Result)
+	var result InitializeResult
+	if err := json.Unmarshal(init, &result); err != nil || result.ServerInfo.Name != "cee" {
+		t.Errorf("initialize = %s, %v", init, err)
+	}
+	if sync, _ := result.Capabilities["textDocumentSync"].(float64); TextDocumentSyncKind(sync) != SyncFull {
+		t.Errorf("textDocumentSync = %v", result.Capabilities["textDocumentSync"])
+	}
+	if rerr := results["2"].Error; rerr == nil || rerr.Code != CodeMethodNotFound {
+		t.Errorf("unknown method answered %+v", results["2"])
+	}
+	if resp, ok := results["3"]; !ok || resp.Error != nil {
+		t.Errorf("shutdown answered %+v", resp)
+	}
+	if rerr := results["4"].Error; rerr == nil || rerr.Code != CodeInvalidRequest {
+		t.Errorf("request after shutdown answered %+v", results["4"])
+	}
+
+	if len(diagnostics) != 2 {
+		t.Fatalf("published %d diagnostics, want 2", len(diagnostics))
+	}
+	if d := diagnostics[0]; d.URI != uri || d.Version != 1 || len(d.Diagnostics) == 0 {
+		t.Errorf("diagnostics of the open document %+v", d)
+	} else if diag := d.Diagnostics[0]; diag.Severity != SeverityError || diag.Range.Start.Line != 4 ||
+		len(diag.RelatedInformation) != 1 || diag.RelatedInformation[0].Location.Range.Start != (Position{Line: 3, Character: 9}) {
+		// The brace closing f is reported, along with the parenthesis it leaves open.
+		t.Errorf("diagnostic %+v", diag)
+	}
+	if d := diagnostics[1]; d.Version != 2 || len(d.Diagnostics) != 0 {
+		t.Errorf("diagnostics after the fix %+v", d)
+	}
+}
+
+func TestServerMacroDiagnostics(t *testing.T) {
+	const uri = "file:///work/a/a.cee"
+	msgs := session(t, map[string]int{"initialize": 1, "shutdown": 2},
+		"initialize", map[string]any{"processId": nil},
+		"textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{
+			URI: uri, LanguageID: "cee", Version: 1, Text: "package a\n\nmacro big() {\n\t300\n}\n\nfun g() {\n\tvar x u8 = big!()\n}\n",
+		}},
+		"shutdown", nil,
+		"exit", nil,
+	)
+
+	for _, msg := range msgs {
+		if msg.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var p PublishDiagnosticsParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			t.Fatal(err)
+		}
+		// The overflow is reported at the invocation, along with the literal in the body.
+		if len(p.Diagnostics) != 1 {
+			t.Fatalf("diagnostics %+v", p.Diagnostics)
+		}
+		d := p.Diagnostics[0]
+		if d.Range != (Range{Start: Position{Line: 7, Character: 12}, End: Position{Line: 7, Character: 18}}) ||
+			len(d.RelatedInformation) != 1 || d.RelatedInformation[0].Location.Range.Start != (Position{Line: 3, Character: 1}) {
+			t.Errorf("diagnostic %+v", d)
+		}
+		return
+	}
+	t.Fatal("no diagnostics published")
+}
+
+func TestServerExitBeforeShutdown(t *testing.T) {
+	var in, out bytes.Buffer
+	if err := NewConn(nil, &in).Write(Message{Method: "exit"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewServer(&in, &out).Serve(); err == nil {
+		t.Error("exit before shutdown accepted")
+	}
+}
+
+func TestServerRecover(t *testing.T) {
+	var in, out bytes.Buffer
+	client := NewConn(nil, &in)
+	for i, method := range []string{"panic", "shutdown", "exit"} {
+		msg := Message{Method: method}
+		if method != "exit" {
+			raw := json.RawMessage(strconv.Itoa(i + 1))
+			msg.ID = &raw
+		}
+		if err := client.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewServer(&in, &out)
+	s.Handle("panic", func(*Server, json.RawMessage) (any, error) { panic("boom") })
+	if err := s.Serve(); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	server := NewConn(&out, nil)
+	resp, err := server.Read()
+	if err != nil || resp.Error == nil || resp.Error.Code != CodeInternalError {
+		t.Errorf("panicking request answered %+v, %v", resp, err)
+	}
+	if resp, err := server.Read(); err != nil || resp.Error != nil {
+		t.Errorf("shutdown after a panic answered %+v, %v", resp, err)
+	}
+}
+
+func TestPositionUTF16(t *testing.T) {
+	text := []rune("a😀b\nc")
+	// The emoji takes two UTF-16 code units.
+	if offset := Offset(text, Position{Line: 0, Character: 3}); offset != 2 {
+		t.Errorf("Offset of b = %d", offset)
+	}
+	if pos := PositionAt(text, 2); pos != (Position{Line: 0, Character: 3}) {
+		t.Errorf("PositionAt of b = %+v", pos)
+	}
+	if pos := NewPosition(text, token.Position{Offset: 2, Line: 0, Column: 2}); pos != (Position{Line: 0, Character: 3}) {
+		t.Errorf("NewPosition of b = %+v", pos)
+	}
+	if offset := Offset(text, Position{Line: 1, Character: 1}); offset != 5 {
+		t.Errorf("Offset past c = %d", offset)
+	}
+}
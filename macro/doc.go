@@ -0,0 +1,13 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+// Package macro
+// Hygienic expansion of declarative macros on token streams.
+//
+// A macro is declared by `macro name(params) { body }` and invoked by `name!(args)`.
+// Each expansion is laid out in a file of its own in the file set, whose positions map back to both
+// the invocation and the macro body or argument the token comes from. The parser expands the invocations
+// as it scans them, see parser.Parser.Macros, and diagnostics in expanded code are reported at their source,
+// see Expander.Source.
+package macro
@@ -0,0 +1,300 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package macro
+
+import (
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxDepth bounds nested expansions, which catches recursive macros.
+const DefaultMaxDepth = 64
+
+// HygienePrefix starts the names of the variables a macro body declares once renamed,
+// identifiers starting with it are reserved.
+const HygienePrefix = "__"
+
+// Expansion is a single invocation of a macro, laid out in File.
+type Expansion struct {
+	Macro *ast.MacroDecl
+	Call  ast.PosRange // from the name of the macro to the closing parenthesis
+	File  *token.File  // holds the text of the expansion
+
+	froms   []token.Pos    // positions of the expanded tokens in File, increasing
+	origins []ast.PosRange // where each expanded token comes from, in the body or in an argument
+}
+
+type Expander struct {
+	FileSet  *token.FileSet
+	MaxDepth int
+
+	Macros     map[string]*ast.MacroDecl
+	Expansions map[*token.File]*Expansion
+
+	Diagnosis []diagnosis.Diagnosis
+
+	fresh int
+}
+
+func NewExpander(fset *token.FileSet) *Expander {
+	return &Expander{
+		FileSet:    fset,
+		MaxDepth:   DefaultMaxDepth,
+		Macros:     map[string]*ast.MacroDecl{},
+		Expansions: map[*token.File]*Expansion{},
+	}
+}
+
+func (e *Expander) Report(d diagnosis.Diagnosis) {
+	e.Diagnosis = append(e.Diagnosis, d)
+}
+
+// Define registers a macro, redefinitions are reported and ignored.
+func (e *Expander) Define(d ast.MacroDecl) {
+	if _, ok := e.Macros[d.Ident.Literal]; ok {
+		e.Report(diagnosis.Diagnosis{
+			Kind:  diagnosis.MacroRedefined,
+			Error: diagnosis.MacroRedefinedError{Name: d.Ident},
+		})
+		return
+	}
+	e.Macros[d.Ident.Literal] = &d
+}
+
+// DefineAll registers the macros among the declarations.
+func (e *Expander) DefineAll(decls []ast.Stmt) {
+	for _, decl := range decls {
+		if d, ok := decl.Value.(ast.MacroDecl); ok {
+			e.Define(d)
+		}
+	}
+}
+
+// Expand replaces every invocation in the tokens by its expansion.
+func (e *Expander) Expand(toks []ast.Token) []ast.Token {
+	return e.expand(toks, 0)
+}
+
+// isCall reports whether an invocation `name!(` starts at i.
+func isCall(toks []ast.Token, i int) bool {
+	return i+2 < len(toks) && toks[i].Kind == token.IDENT && toks[i+1].Kind == token.NOT && toks[i+2].Kind == token.LPAREN
+}
+
+// splitArgs splits the arguments of the parenthesis at open by the commas at its level,
+// and returns the index of the closing parenthesis, -1 if it is missing.
+func splitArgs(toks []ast.Token, open int) ([][]ast.Token, int) {
+	var (
+		args  [][]ast.Token
+		arg   []ast.Token
+		depth int
+	)
+	for i := open + 1; i < len(toks); i++ {
+		switch toks[i].Kind {
+		case token.LPAREN, token.LBRACK, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACK, token.RBRACE:
+			if depth == 0 {
+				if len(arg) != 0 || len(args) != 0 {
+					args = append(args, arg)
+				}
+				return args, i
+			}
+			depth--
+		case token.COMMA:
+			if depth == 0 {
+				args = append(args, arg)
+				arg = nil
+				continue
+			}
+		}
+		arg = append(arg, toks[i])
+	}
+	return nil, -1
+}
+
+func (e *Expander) expand(toks []ast.Token, depth int) []ast.Token {
+	var out []ast.Token
+	for i := 0; i < len(toks); i++ {
+		if !isCall(toks, i) {
+			out = append(out, toks[i])
+			continue
+		}
+
+		name := ast.Ident{Token: toks[i]}
+		m, ok := e.Macros[name.Literal]
+		if !ok {
+			e.Report(diagnosis.Diagnosis{
+				Kind:  diagnosis.UnknownMacro,
+				Error: diagnosis.UnknownMacroError{Name: name},
+			})
+			out = append(out, toks[i])
+			continue
+		}
+
+		args, end := splitArgs(toks, i+2)
+		if end < 0 {
+			e.Report(diagnosis.Diagnosis{
+				Kind:  diagnosis.UnexpectedNode,
+				Error: diagnosis.UnexpectedNodeError{Have: toks[len(toks)-1], Want: token.RPAREN},
+			})
+			return append(out, toks[i:]...)
+		}
+		call := ast.PosRange{From: toks[i].From, To: toks[end].To}
+
+		switch {
+		case len(args) != len(m.Params):
+			e.Report(diagnosis.Diagnosis{
+				Kind:  diagnosis.MacroArity,
+				Error: diagnosis.MacroArityError{Call: call, Name: m.Ident.Literal, Want: len(m.Params), Have: len(args)},
+			})
+		case depth >= e.MaxDepth:
+			e.Report(diagnosis.Diagnosis{
+				Kind:  diagnosis.MacroRecursion,
+				Error: diagnosis.MacroRecursionError{Call: call, Name: m.Ident.Literal},
+			})
+		default:
+			out = append(out, e.instantiate(m, args, call, depth)...)
+		}
+		i = end
+	}
+	return out
+}
+
+// declared collects the variables the body declares: the names following val and var,
+// and the names between for and in.
+func declared(body []ast.Token) map[string]bool {
+	names := map[string]bool{}
+	for i, tok := range body {
+		switch tok.Kind {
+		case token.VAL, token.VAR:
+			if i+1 < len(body) && body[i+1].Kind == token.IDENT {
+				names[body[i+1].Literal] = true
+			}
+		case token.FOR:
+			for j := i + 1; j < len(body) && body[j].Kind != token.IN && body[j].Kind != token.LBRACE; j++ {
+				if body[j].Kind == token.IDENT {
+					names[body[j].Literal] = true
+				}
+			}
+		}
+	}
+	return names
+}
+
+// instantiate substitutes the arguments for the parameters in the body, renames the variables
+// the body declares so they cannot capture or shadow the names of the call site, expands nested
+// invocations and lays the result out in a new file.
+func (e *Expander) instantiate(m *ast.MacroDecl, args [][]ast.Token, call ast.PosRange, depth int) []ast.Token {
+	params := map[string][]ast.Token{}
+	for i, param := range m.Params {
+		params[param.Literal] = args[i]
+	}
+
+	e.fresh++
+	suffix := "_" + strconv.Itoa(e.fresh)
+	locals := declared(m.Body)
+
+	var toks []ast.Token
+	for _, tok := range m.Body {
+		if tok.Kind == token.IDENT {
+			if arg, ok := params[tok.Literal]; ok {
+				toks = append(toks, arg...)
+				continue
+			}
+			if locals[tok.Literal] {
+				tok.Literal = HygienePrefix + tok.Literal + suffix
+			}
+		}
+		toks = append(toks, tok)
+	}
+
+	return e.layout(m, call, e.expand(toks, depth+1))
+}
+
+// layout renders the tokens into the text of a new file, one space apart, and moves them into it.
+func (e *Expander) layout(m *ast.MacroDecl, call ast.PosRange, toks []ast.Token) []ast.Token {
+	var (
+		b       strings.Builder
+		offsets = make([]int, len(toks))
+		size    int
+	)
+	for i, tok := range toks {
+		if i != 0 && tok.Kind != token.NEWLINE && toks[i-1].Kind != token.NEWLINE {
+			b.WriteByte(' ')
+			size++
+		}
+		offsets[i] = size
+		b.WriteString(tok.Literal)
+		size += len([]rune(tok.Literal))
+	}
+
+	text := []rune(b.String())
+	file := e.FileSet.AddFile(m.Ident.Literal+"!", -1, len(text))
+	file.SetContent(text)
+
+	x := &Expansion{Macro: m, Call: call, File: file}
+	out := make([]ast.Token, len(toks))
+	for i, tok := range toks {
+		from := file.Pos(offsets[i])
+		x.froms = append(x.froms, from)
+		x.origins = append(x.origins, tok.PosRange)
+		tok.PosRange = ast.PosRange{From: from, To: file.Pos(offsets[i] + len([]rune(tok.Literal)))}
+		out[i] = tok
+	}
+	e.Expansions[file] = x
+	return out
+}
+
+// Origin maps a position in an expansion to the invocation and to the range of the token it was
+// expanded from, in the macro body or in an argument. The latter may lie in an enclosing expansion.
+func (e *Expander) Origin(pos token.Pos) (call, origin ast.PosRange, ok bool) {
+	file := e.FileSet.File(pos)
+	x, ok := e.Expansions[file]
+	if !ok || len(x.froms) == 0 {
+		return ast.PosRange{}, ast.PosRange{}, false
+	}
+	i := sort.Search(len(x.froms), func(i int) bool { return x.froms[i] > pos }) - 1
+	if i < 0 {
+		i = 0
+	}
+	return x.Call, x.origins[i], true
+}
+
+// Trace follows a position out of nested expansions. It returns the expansions from the innermost
+// out and the position in the source the token was written in.
+func (e *Expander) Trace(pos token.Pos) (expansions []*Expansion, source token.Pos) {
+	for {
+		x := e.Expansions[e.FileSet.File(pos)]
+		_, origin, ok := e.Origin(pos)
+		if !ok {
+			return expansions, pos
+		}
+		expansions = append(expansions, x)
+		pos = origin.From
+	}
+}
+
+// Source maps a position in expanded code to the source. A token expanded from an argument maps to where
+// the argument was written, any other to the outermost invocation, whose expansion is returned along with
+// the position in the macro body the token comes from, NoPos for an argument. Positions out of expansions,
+// or of a nil Expander, are returned as they are.
+func (e *Expander) Source(pos token.Pos) (at token.Pos, x *Expansion, body token.Pos) {
+	if e == nil || len(e.Expansions) == 0 {
+		return pos, nil, token.NoPos
+	}
+	expansions, source := e.Trace(pos)
+	if len(expansions) == 0 {
+		return pos, nil, token.NoPos
+	}
+	x = expansions[len(expansions)-1]
+	if x.Call.From <= source && source < x.Call.To {
+		return source, x, token.NoPos
+	}
+	return x.Call.From, x, source
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package macro
+
+import (
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/token"
+	"strings"
+	"testing"
+)
+
+// tokens lays out the space separated words in a new file, every word is scanned as an identifier
+// unless it is one of the punctuation marks or keywords used in the tests.
+func tokens(fset *token.FileSet, name, src string) []ast.Token {
+	kinds := map[string]int{
+		"!": token.NOT, "(": token.LPAREN, ")": token.RPAREN, ",": token.COMMA,
+		"=": token.ASSIGN, "+": token.ADD, "val": token.VAL,
+	}
+
+	file := fset.AddFile(name, -1, len(src))
+	file.SetContent([]rune(src))
+
+	var (
+		toks   []ast.Token
+		offset int
+	)
+	for _, word := range strings.Split(src, " ") {
+		kind, ok := kinds[word]
+		if !ok {
+			kind = token.IDENT
+		}
+		toks = append(toks, ast.Token{
+			PosRange: ast.PosRange{From: file.Pos(offset), To: file.Pos(offset + len(word))},
+			Kind:     kind,
+			Literal:  word,
+		})
+		offset += len(word) + 1
+	}
+	return toks
+}
+
+func literals(toks []ast.Token) string {
+	var words []string
+	for _, tok := range toks {
+		words = append(words, tok.Literal)
+	}
+	return strings.Join(words, " ")
+}
+
+func TestExpand(t *testing.T) {
+	fset := token.NewFileSet()
+	e := NewExpander(fset)
+
+	body := tokens(fset, "def", "val t = x + x")
+	e.Define(ast.MacroDecl{
+		Ident:  ast.Ident{Token: ast.Token{Kind: token.IDENT, Literal: "twice"}},
+		Params: []ast.Ident{{Token: ast.Token{Kind: token.IDENT, Literal: "x"}}},
+		Body:   body,
+	})
+
+	src := tokens(fset, "use", "t + twice ! ( t )")
+	out := e.Expand(src)
+	if len(e.Diagnosis) != 0 {
+		t.Fatal(e.Diagnosis)
+	}
+
+	if got, want := literals(out), "t + val __t_1 = t + t"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// The last token is the argument, written at the call site.
+	calls, source := e.Trace(out[len(out)-1].From)
+	if len(calls) != 1 || calls[0].Call.From != src[2].From || calls[0].Call.To != src[6].To {
+		t.Errorf("calls %v, want the invocation", calls)
+	}
+	if source != src[5].From {
+		t.Errorf("source %v, want the argument at %v", source, src[5].From)
+	}
+
+	// The renamed variable comes from the body.
+	if _, origin, ok := e.Origin(out[3].From); !ok || origin != body[1].PosRange {
+		t.Errorf("origin %v, want %v", origin, body[1].PosRange)
+	}
+	if got := fset.Text(out[3].From, out[3].To); got != "__t_1" {
+		t.Errorf("expanded text %q", got)
+	}
+
+	// The argument maps to the call site, the body to the invocation and the token in the body.
+	if at, x, pos := e.Source(out[len(out)-1].From); at != src[5].From || x != calls[0] || pos.IsValid() {
+		t.Errorf("source of the argument %v %v %v", at, x, pos)
+	}
+	if at, x, pos := e.Source(out[3].From); at != src[2].From || x != calls[0] || pos != body[1].From {
+		t.Errorf("source of the body %v %v %v", at, x, pos)
+	}
+	if at, x, _ := e.Source(src[0].From); at != src[0].From || x != nil {
+		t.Errorf("source out of expansions %v %v", at, x)
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	fset := token.NewFileSet()
+	e := NewExpander(fset)
+	e.MaxDepth = 4
+
+	e.Define(ast.MacroDecl{
+		Ident: ast.Ident{Token: ast.Token{Kind: token.IDENT, Literal: "loop"}},
+		Body:  tokens(fset, "def", "loop ! ( )"),
+	})
+
+	e.Expand(tokens(fset, "use", "loop ! ( ) undefined ! ( ) loop ! ( x )"))
+
+	var kinds []int
+	for _, d := range e.Diagnosis {
+		kinds = append(kinds, d.Kind)
+	}
+	want := []int{diagnosis.MacroRecursion, diagnosis.UnknownMacro, diagnosis.MacroArity}
+	if len(kinds) != len(want) {
+		t.Fatalf("got %v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("got %v, want %v", kinds, want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Names of the metrics reported by the build driver and the language server.
+// Durations are in seconds, cache hit rates are hits / (hits + misses).
+const (
+	PhaseSeconds = "phase.seconds." // followed by the phase, e.g. phase.seconds.parse
+	CacheHits    = "cache.hits"
+	CacheMisses  = "cache.misses"
+
+	RequestSeconds = "lsp.request.seconds." // followed by the method
+	RequestErrors  = "lsp.request.errors"
+	QueryHits      = "lsp.query.hits"
+	QueryMisses    = "lsp.query.misses"
+)
+
+// Metrics receives measurements by name, it must be safe for concurrent use.
+type Metrics interface {
+	// Add adds delta to a counter.
+	Add(name string, delta int64)
+	// Observe adds a sample to a histogram.
+	Observe(name string, value float64)
+}
+
+// Nop discards every measurement.
+type Nop struct{}
+
+func (Nop) Add(string, int64)       {}
+func (Nop) Observe(string, float64) {}
+
+// Since observes the seconds elapsed since start.
+func Since(m Metrics, name string, start time.Time) {
+	m.Observe(name, time.Since(start).Seconds())
+}
+
+// Bounds are the upper bounds of the histogram buckets of Memory, from a microsecond to about 17 minutes
+// in powers of 4 when the samples are seconds.
+var Bounds = func() []float64 {
+	bounds := make([]float64, 16)
+	for i := range bounds {
+		bounds[i] = 1e-6 * math.Pow(4, float64(i))
+	}
+	return bounds
+}()
+
+type Histogram struct {
+	Count    int
+	Sum      float64
+	Min, Max float64
+	Buckets  []int // samples at most each of Bounds, the last one above them all
+}
+
+func (h Histogram) Mean() float64 {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / float64(h.Count)
+}
+
+// Quantile estimates the q-quantile, 0 <= q <= 1, as the upper bound of its bucket, clamped to the samples.
+func (h Histogram) Quantile(q float64) float64 {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(q * float64(h.Count)))
+	seen := 0
+	for i, n := range h.Buckets {
+		seen += n
+		if seen >= rank && i < len(Bounds) {
+			return math.Max(h.Min, math.Min(Bounds[i], h.Max))
+		}
+	}
+	return h.Max
+}
+
+// Memory keeps the measurements in memory, its zero value is ready to use.
+type Memory struct {
+	mutex      sync.Mutex
+	counters   map[string]int64
+	histograms map[string]*Histogram
+}
+
+func (m *Memory) Add(name string, delta int64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.counters == nil {
+		m.counters = map[string]int64{}
+	}
+	m.counters[name] += delta
+}
+
+func (m *Memory) Observe(name string, value float64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.histograms == nil {
+		m.histograms = map[string]*Histogram{}
+	}
+	h, ok := m.histograms[name]
+	if !ok {
+		h = &Histogram{Min: value, Max: value, Buckets: make([]int, len(Bounds)+1)}
+		m.histograms[name] = h
+	}
+	h.Count++
+	h.Sum += value
+	h.Min, h.Max = math.Min(h.Min, value), math.Max(h.Max, value)
+	h.Buckets[sort.SearchFloat64s(Bounds, value)]++
+}
+
+func (m *Memory) Counter(name string) int64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.counters[name]
+}
+
+// Histogram returns a copy of the histogram of name, empty when nothing was observed.
+func (m *Memory) Histogram(name string) Histogram {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	h, ok := m.histograms[name]
+	if !ok {
+		return Histogram{}
+	}
+	c := *h
+	c.Buckets = append([]int{}, h.Buckets...)
+	return c
+}
+
+// Rate returns hits / (hits + misses) of two counters, 0 when both are 0.
+func (m *Memory) Rate(hits, misses string) float64 {
+	h, n := m.Counter(hits), m.Counter(misses)
+	if h+n == 0 {
+		return 0
+	}
+	return float64(h) / float64(h+n)
+}
+
+// Names returns the names of the counters and of the histograms, sorted.
+func (m *Memory) Names() (counters, histograms []string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for name := range m.counters {
+		counters = append(counters, name)
+	}
+	for name := range m.histograms {
+		histograms = append(histograms, name)
+	}
+	sort.Strings(counters)
+	sort.Strings(histograms)
+	return counters, histograms
+}
+
+// Report writes a table of the counters and one of the histograms.
+func (m *Memory) Report(w io.Writer) error {
+	counters, histograms := m.Names()
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "counter\tvalue\t")
+	for _, name := range counters {
+		fmt.Fprintf(tw, "%s\t%d\t\n", name, m.Counter(name))
+	}
+	fmt.Fprintln(tw, "\t\t")
+	fmt.Fprintln(tw, "histogram\tcount\tmean\tp50\tp99\tmax\t")
+	for _, name := range histograms {
+		h := m.Histogram(name)
+		fmt.Fprintf(tw, "%s\t%d\t%.3g\t%.3g\t%.3g\t%.3g\t\n", name, h.Count, h.Mean(), h.Quantile(0.5), h.Quantile(0.99), h.Max)
+	}
+	return tw.Flush()
+}
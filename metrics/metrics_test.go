@@ -0,0 +1,38 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMemory(t *testing.T) {
+	var m Memory
+	m.Add(CacheHits, 3)
+	m.Add(CacheMisses, 1)
+	if rate := m.Rate(CacheHits, CacheMisses); rate != 0.75 {
+		t.Errorf("rate = %v", rate)
+	}
+
+	for _, v := range []float64{0.5e-6, 2e-6, 3e-6, 1} {
+		m.Observe(PhaseSeconds+"parse", v)
+	}
+	h := m.Histogram(PhaseSeconds + "parse")
+	if h.Count != 4 || h.Min != 0.5e-6 || h.Max != 1 || h.Buckets[0] != 1 || h.Buckets[1] != 2 {
+		t.Errorf("histogram %+v", h)
+	}
+	if q := h.Quantile(0.5); q != Bounds[1] {
+		t.Errorf("median = %v", q)
+	}
+	if q := h.Quantile(1); q != 1 {
+		t.Errorf("max quantile = %v", q)
+	}
+
+	var b strings.Builder
+	if err := m.Report(&b); err != nil || !strings.Contains(b.String(), "phase.seconds.parse") {
+		t.Errorf("report %q, %v", b.String(), err)
+	}
+}
@@ -0,0 +1,397 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package parser
+
+import (
+	"cee"
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/edition"
+	"cee/token"
+)
+
+func (p *Parser) ExpectFuncDecl() ast.FuncDecl {
+	begin := p.Token.From
+
+	p.MatchTerm(token.FUNC)
+	p.Scan()
+
+	var (
+		captures []ast.Capture
+		ident    *ast.Ident
+	)
+	switch p.Token.Kind {
+	case token.LBRACK:
+		// Only closures capture, a capture list is never followed by a name.
+		captures = p.ExpectCaptures()
+	case token.IDENT:
+		id := p.ExpectIdent()
+		ident = &id
+	}
+
+	var params []ast.TypeParam
+	if ident != nil && p.Token.Kind == token.LBRACK {
+		params = p.ExpectTypeParams()
+	}
+
+	typ := p.ExpectFuncType()
+
+	var stmt *ast.StmtBlockExpr
+	if p.Token.Kind == token.LBRACE {
+		block := p.ExpectStmtBlock()
+		stmt = &block
+	}
+
+	return ast.FuncDecl{
+		PosRange:   p.rangeFrom(begin),
+		Captures:   captures,
+		TypeParams: params,
+		Type:       typ,
+		Ident:      ident,
+		Stmt:       stmt,
+	}
+}
+
+// ExpectFuncLitExpr parses the closure `fun [captures](params) results { body }` in operand position.
+func (p *Parser) ExpectFuncLitExpr() ast.FuncLitExpr {
+	begin := p.Token.From
+
+	p.MatchTerm(token.FUNC)
+	p.Scan()
+	var captures []ast.Capture
+	if p.Token.Kind == token.LBRACK {
+		captures = p.ExpectCaptures()
+	}
+	typ := p.ExpectFuncType()
+	body := p.ExpectStmtBlock()
+
+	return ast.FuncLitExpr{
+		PosRange: p.rangeFrom(begin),
+		Captures: captures,
+		Type:     typ,
+		Body:     body,
+	}
+}
+
+// ExpectCaptures parses the capture list `[x, &y]` of a closure.
+func (p *Parser) ExpectCaptures() []ast.Capture {
+	p.MatchTerm(token.LBRACK)
+	p.Scan()
+
+	var captures []ast.Capture
+	for {
+		p.SkipNewlines()
+		if p.Token.Kind == token.RBRACK || p.ReachedEOF {
+			break
+		}
+
+		begin := p.Token.From
+		byRef := p.Token.Kind == token.AND
+		if byRef {
+			p.Scan()
+		}
+		ident := p.ExpectIdent()
+		captures = append(captures, ast.Capture{
+			PosRange: p.rangeFrom(begin),
+			Ident:    ident,
+			ByRef:    byRef,
+		})
+
+		p.SkipNewlines()
+		if p.Token.Kind != token.COMMA {
+			break
+		}
+		p.Scan()
+	}
+	p.MatchTerm(token.RBRACK)
+	p.Scan()
+
+	return captures
+}
+
+// ExpectMacroDecl parses `macro name(params) { body }`, the body is collected up to the matching brace.
+func (p *Parser) ExpectMacroDecl() ast.MacroDecl {
+	begin := p.Token.From
+
+	p.MatchTerm(token.MACRO)
+	p.Scan()
+	decl := ast.MacroDecl{Ident: p.ExpectIdent()}
+
+	p.MatchTerm(token.LPAREN)
+	p.Scan()
+	for p.Token.Kind == token.IDENT {
+		decl.Params = append(decl.Params, p.ExpectIdent())
+		if p.Token.Kind != token.COMMA {
+			break
+		}
+		p.Scan()
+	}
+	p.MatchTerm(token.RPAREN)
+	p.Scan()
+
+	// The invocations in the body are expanded with the body.
+	p.verbatim = true
+	p.MatchTerm(token.LBRACE)
+	p.Scan()
+	for depth := 0; !p.ReachedEOF; p.Scan() {
+		switch p.Token.Kind {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			depth--
+		}
+		if depth < 0 {
+			break
+		}
+		decl.Body = append(decl.Body, p.Token)
+	}
+	p.MatchTerm(token.RBRACE)
+	p.verbatim = false
+	p.Scan()
+
+	decl.PosRange = p.rangeFrom(begin)
+	return decl
+}
+
+// ExpectExternDecl parses `extern ["ABI"] fun name(params) results`, a body is not allowed.
+func (p *Parser) ExpectExternDecl() ast.ExternDecl {
+	begin := p.Token.From
+
+	p.MatchTerm(token.EXTERN)
+	p.Scan()
+
+	abi := ast.LiteralValue{Token: ast.Token{Kind: token.STRING, Literal: `"C"`}}
+	if token.IsString(p.Token.Kind) {
+		abi = ast.LiteralValue{Token: p.Token}
+		p.Scan()
+	}
+
+	p.MatchTerm(token.FUNC)
+	p.Scan()
+	ident := p.ExpectIdent()
+	typ := p.ExpectFuncType()
+
+	return ast.ExternDecl{
+		PosRange: p.rangeFrom(begin),
+		ABI:      abi,
+		Ident:    ident,
+		Type:     typ,
+	}
+}
+
+func newStmt(kind ast.StmtKind, value ast.Node) ast.Stmt {
+	return ast.Stmt{Union: cee.Union[ast.StmtKind]{Tag: kind, Value: value}}
+}
+
+// ExpectImportDecl parses `import [alias] "canonical/name"`.
+func (p *Parser) ExpectImportDecl() ast.ImportDecl {
+	begin := p.Token.From
+
+	p.MatchTerm(token.IMPORT)
+	p.Scan()
+	return p.expectImportSpec(begin)
+}
+
+// ExpectImportGroup parses `import ( specs )`, each spec being `[alias] "canonical/name"`.
+func (p *Parser) ExpectImportGroup() []ast.ImportDecl {
+	p.MatchTerm(token.IMPORT)
+	p.Scan()
+	p.MatchTerm(token.LPAREN)
+	p.Scan()
+
+	var decls []ast.ImportDecl
+	for {
+		p.skipSeparators()
+		if p.Token.Kind == token.RPAREN || p.ReachedEOF {
+			break
+		}
+		begin := p.Token
+		decls = append(decls, p.expectImportSpec(p.Token.From))
+		if p.Token == begin {
+			p.Scan()
+		}
+	}
+	p.MatchTerm(token.RPAREN)
+	p.Scan()
+
+	return decls
+}
+
+func (p *Parser) expectImportSpec(begin token.Pos) ast.ImportDecl {
+	var alias *ast.Ident
+	if p.Token.Kind == token.IDENT {
+		ident := p.ExpectIdent()
+		alias = &ident
+	}
+
+	if !token.IsString(p.Token.Kind) {
+		p.MatchTerm(token.STRING)
+	}
+	name := ast.LiteralValue{Token: p.Token}
+	p.Scan()
+
+	return ast.ImportDecl{
+		PosRange:      p.rangeFrom(begin),
+		CanonicalName: name,
+		Alias:         alias,
+	}
+}
+
+// ExpectValDecl parses `val|var|const pattern [type] [= value]`, a plain identifier is kept as the Name of the declaration.
+// The value may only be omitted when an attribute provides it, which ExpectDecl checks.
+func (p *Parser) ExpectValDecl() ast.ValDecl {
+	begin := p.Token.From
+
+	decl := ast.ValDecl{Mutable: p.Token.Kind == token.VAR, Const: p.Token.Kind == token.CONST}
+	if !decl.Mutable && !decl.Const {
+		p.MatchTerm(token.VAL)
+	}
+	p.Scan()
+
+	if pattern := p.ExpectPattern(); pattern.Tag == ast.PatternIdent {
+		decl.Name = pattern.Value.(ast.Ident)
+	} else {
+		if lit, ok := pattern.Refutable(); ok {
+			// Bindings cannot fail to match.
+			p.Report(diagnosis.Diagnosis{
+				Kind:  diagnosis.UnexpectedNode,
+				Error: diagnosis.UnexpectedNodeError{Have: lit, Want: token.IDENT},
+			})
+		}
+		decl.Pattern = &pattern
+	}
+
+	if p.Token.Kind != token.ASSIGN && IsTypeBegin(p.Token.Kind) {
+		decl.Type = p.ExpectType()
+	}
+
+	if p.Token.Kind == token.ASSIGN {
+		p.Scan()
+		decl.Value = p.ExpectExpr()
+	}
+	decl.PosRange = p.rangeFrom(begin)
+
+	return decl
+}
+
+// ExpectDeferStmt parses `defer expr` and `errdefer expr`, expr is a call or a block.
+func (p *Parser) ExpectDeferStmt() ast.DeferStmt {
+	begin := p.Token.From
+
+	onError := p.Token.Kind == token.ERRDEFER
+	if !onError {
+		p.MatchTerm(token.DEFER)
+	}
+	p.Scan()
+	expr := p.ExpectExpr()
+
+	return ast.DeferStmt{
+		PosRange: p.rangeFrom(begin),
+		OnError:  onError,
+		Expr:     expr,
+	}
+}
+
+// ExpectAttribute parses `@name` or `@name(args)`, args being `key = "value"` or positional values.
+func (p *Parser) ExpectAttribute() ast.Attribute {
+	begin := p.Token.From
+
+	p.MatchTerm(token.AT)
+	p.Scan()
+	attr := ast.Attribute{Name: p.ExpectIdent()}
+
+	if p.Token.Kind == token.LPAREN {
+		p.Scan()
+		for {
+			p.SkipNewlines()
+			if p.Token.Kind == token.RPAREN || p.ReachedEOF {
+				break
+			}
+
+			argBegin := p.Token.From
+			var arg ast.AttributeArg
+			if p.Token.Kind == token.IDENT {
+				arg.Key = p.ExpectIdent()
+				p.MatchTerm(token.ASSIGN)
+				p.Scan()
+			}
+			if !token.IsLiteralValue(p.Token.Kind) {
+				p.MatchTerm(token.STRING)
+			}
+			arg.Value = ast.LiteralValue{Token: p.Token}
+			p.Scan()
+			arg.PosRange = p.rangeFrom(argBegin)
+			attr.Args = append(attr.Args, arg)
+
+			p.SkipNewlines()
+			if p.Token.Kind != token.COMMA {
+				break
+			}
+			p.Scan()
+		}
+		p.MatchTerm(token.RPAREN)
+		p.Scan()
+	}
+
+	attr.PosRange = p.rangeFrom(begin)
+	return attr
+}
+
+// ExpectDecl parses a single top-level declaration with the attributes and the pub modifier preceding it.
+func (p *Parser) ExpectDecl() ast.Stmt {
+	var attrs []ast.Attribute
+	for p.Token.Kind == token.AT {
+		attrs = append(attrs, p.ExpectAttribute())
+		p.SkipNewlines()
+	}
+	pub := p.Token.Kind == token.PUB
+	if pub {
+		p.Scan()
+	}
+
+	decl := p.expectDecl()
+	decl.Attrs = attrs
+	decl.Pub = pub
+
+	// Only embedded values are provided by an attribute.
+	if d, ok := decl.Value.(ast.ValDecl); ok && d.Value.Tag == 0 {
+		if _, embedded := decl.Attr(EmbedAttribute); !embedded {
+			p.Report(diagnosis.Diagnosis{
+				Kind: diagnosis.UnexpectedNode,
+				Error: diagnosis.UnexpectedNodeError{
+					Have: p.Token,
+					Want: token.ASSIGN,
+				},
+			})
+		}
+	}
+	return decl
+}
+
+// EmbedAttribute names the attribute `@embed("path")`, which provides the value of a val or var
+// declaration from the content of a file. See loader.Embed.
+const EmbedAttribute = "embed"
+
+func (p *Parser) expectDecl() ast.Stmt {
+	switch p.Token.Kind {
+	case token.IMPORT:
+		return newStmt(ast.StmtImportDecl, p.ExpectImportDecl())
+	case token.FUNC:
+		return newStmt(ast.StmtFuncDecl, p.ExpectFuncDecl())
+	case token.EXTERN:
+		return newStmt(ast.StmtExternDecl, p.ExpectExternDecl())
+	case token.VAL, token.VAR, token.CONST:
+		return newStmt(ast.StmtValDecl, p.ExpectValDecl())
+	case token.TYPE:
+		return newStmt(ast.StmtTypeDecl, p.ExpectTypeDecl())
+	case token.MACRO:
+		p.Require(edition.Macros)
+		decl := p.ExpectMacroDecl()
+		p.define(decl)
+		return newStmt(ast.StmtMacroDecl, decl)
+	default:
+		return newStmt(ast.StmtBadDecl, ast.BadDecl{PosRange: p.recoverBad(token.FUNC)})
+	}
+}
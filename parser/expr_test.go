@@ -0,0 +1,160 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package parser
+
+import (
+	"cee/ast"
+	"strings"
+	"testing"
+)
+
+// parseExpr parses src as an expression, printed back with the parentheses its precedence requires.
+func parseExpr(t *testing.T, src string) (ast.Expr, string) {
+	t.Helper()
+	p := NewParser([]rune(src))
+	p.Scan()
+	expr := p.ExpectExpr()
+	if len(p.Diagnosis) != 0 || !p.ReachedEOF {
+		t.Errorf("ExpectExpr(%q): %v, stopped at %q", src, p.Diagnosis, p.Token.Literal)
+	}
+	var b strings.Builder
+	if err := ast.Fprint(&b, expr); err != nil {
+		t.Fatal(err)
+	}
+	return expr, b.String()
+}
+
+func TestExpectExpr(t *testing.T) {
+	for src, want := range map[string]string{
+		"a + b * c":         "a + b * c",
+		"(a + b) * c":       "(a + b) * c",
+		"a - b - c":         "a - b - c",
+		"a - (b - c)":       "a - (b - c)",
+		"a &&\n\tb || c":    "a && b || c",
+		"-f(x, 1).y + *p":   "-f(x, 1).y + *p",
+		"!ok == (a < b)":    "!ok == (a < b)",
+		"<-ch":              "<-ch",
+		"g(xs...)":          "g(xs...)",
+		"f(\n\ta,\n\tb,\n)": "f(a, b)",
+		`f("a" "b\t", 'c')`: `f("ab\t", 'c')`,
+	} {
+		if _, got := parseExpr(t, src); got != want {
+			t.Errorf("ExpectExpr(%q) = %q, want %q", src, got, want)
+		}
+	}
+
+	expr, _ := parseExpr(t, "a + b * c")
+	sum := expr.Value.(ast.BinaryExpr)
+	if sum.Operator.Literal != "+" || sum.Exprs[1].Tag != ast.ExprBinary {
+		t.Errorf("a + b * c parsed as %+v", sum)
+	}
+}
+
+func TestExpectCallExpr(t *testing.T) {
+	p := NewParser([]rune("obj.method(a)(b)"))
+	p.Scan()
+	call := p.ExpectCallExpr()
+	inner, ok := call.Callee.Value.(ast.CallExpr)
+	if !ok || len(call.Params) != 1 || inner.Callee.Tag != ast.ExprMemberSelect || len(p.Diagnosis) != 0 {
+		t.Errorf("ExpectCallExpr = %+v", call)
+	}
+}
+
+func TestExpectInterpolatedString(t *testing.T) {
+	src := `"sum ${a + b}, ${f(Point{x: 1})}!"`
+	expr, got := parseExpr(t, src)
+	if got != src {
+		t.Errorf("ExpectExpr(%q) = %q", src, got)
+	}
+	str, ok := expr.Value.(ast.InterpolatedString)
+	if !ok || len(str.Lits) != 3 || len(str.Exprs) != 2 || str.Exprs[1].Tag != ast.ExprCall {
+		t.Errorf("ExpectExpr(%q) = %+v", src, expr)
+	}
+}
+
+func TestExpectBadExpr(t *testing.T) {
+	for src, want := range map[string]string{
+		"f(a, , b)": "f(a, BadExpr, b)",
+		"(a + )":    "a + BadExpr",
+		"x * = 2":   "x * BadExpr",
+		"g(1, ])":   "g(1, BadExpr)",
+		"[]int{1,}": "[]int{1}",
+	} {
+		p := NewParser([]rune(src))
+		p.Options.MaxErrors = -1
+		p.Scan()
+		expr := p.ExpectExpr()
+		var b strings.Builder
+		if err := ast.Fprint(&b, expr); err != nil {
+			t.Fatal(err)
+		}
+		bad := strings.Contains(want, "BadExpr")
+		if got := b.String(); got != want || (len(p.Diagnosis) != 0) != bad {
+			t.Errorf("ExpectExpr(%q) = %q with %v, want %q", src, got, p.Diagnosis, want)
+		}
+	}
+}
+
+// TestRanges checks the ranges of nodes against the source text they span, from their first token to the end of
+// their last one.
+func TestRanges(t *testing.T) {
+	text := func(p *Parser, node ast.Node) string {
+		r := node.GetPosRange()
+		return p.File.Text(r.From, r.To)
+	}
+
+	p := newParser("-a + b")
+	p.Scan()
+	sum := p.ExpectExpr().Value.(ast.BinaryExpr)
+	if got := text(&p, sum.Exprs[0]); got != "-a" {
+		t.Errorf("unary range spans %q, want %q", got, "-a")
+	}
+	if got := text(&p, sum); got != "-a + b" {
+		t.Errorf("binary range spans %q, want %q", got, "-a + b")
+	}
+
+	p = newParser("f(x)[0]")
+	p.Scan()
+	index := p.ExpectExpr().Value.(ast.IndexExpr)
+	if got := text(&p, index.Expr); got != "f(x)" {
+		t.Errorf("call range spans %q, want %q", got, "f(x)")
+	}
+
+	p = newParser("(x + 1) * (*q).n")
+	p.Scan()
+	product := p.ExpectExpr().Value.(ast.BinaryExpr)
+	if got := text(&p, product); got != "(x + 1) * (*q).n" {
+		t.Errorf("range of a product of parenthesized operands spans %q", got)
+	}
+	if got := text(&p, product.Exprs[0]); got != "x + 1" {
+		t.Errorf("parenthesized sum spans %q, want %q", got, "x + 1")
+	}
+
+	p = newParser("struct {\n\ta i32\n\tb, c u8\n}")
+	p.Scan()
+	typ := p.ExpectStructType()
+	for i, want := range []string{"a i32", "b, c u8"} {
+		if got := text(&p, typ.Fields[i]); got != want {
+			t.Errorf("field %d range spans %q, want %q", i, got, want)
+		}
+	}
+
+	p = newParser("fun add(a i32, b i32) i32 { return a + b }\n")
+	p.Scan()
+	decl := p.ExpectDecl()
+	if got, want := text(&p, decl), "fun add(a i32, b i32) i32 { return a + b }"; got != want {
+		t.Errorf("function range spans %q, want %q", got, want)
+	}
+
+	p = newParser("import \"fmt\"\nval x = 1\n")
+	p.Scan()
+	if got, want := text(&p, p.ExpectDecl()), `import "fmt"`; got != want {
+		t.Errorf("import range spans %q, want %q", got, want)
+	}
+	p.SkipNewlines()
+	if got, want := text(&p, p.ExpectDecl()), "val x = 1"; got != want {
+		t.Errorf("value range spans %q, want %q", got, want)
+	}
+}
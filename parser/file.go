@@ -0,0 +1,158 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package parser
+
+import (
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/macro"
+	"cee/token"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// ExpectPackageClause parses the optional `package name` opening a file.
+func (p *Parser) ExpectPackageClause() *ast.Ident {
+	p.SkipNewlines()
+	if p.Token.Kind != token.PACKAGE {
+		return nil
+	}
+	p.Scan()
+	name := p.ExpectIdent()
+	return &name
+}
+
+// ExpectDecls parses a top-level declaration, or the declarations of an import group.
+func (p *Parser) ExpectDecls() []ast.Stmt {
+	if p.Token.Kind == token.IMPORT && p.Peek(1).Kind == token.LPAREN {
+		var decls []ast.Stmt
+		for _, decl := range p.ExpectImportGroup() {
+			decls = append(decls, newStmt(ast.StmtImportDecl, decl))
+		}
+		return decls
+	}
+	if decl := p.ExpectDecl(); decl.Tag != 0 {
+		return []ast.Stmt{decl}
+	}
+	return nil
+}
+
+// ParseFile parses a whole source file, allocating its positions in fset: the optional package clause and the
+// top-level declarations. Input the scanner rejects is skipped as ILLEGAL tokens. The file is returned with
+// the declarations parsed, even past errors, the error joins the diagnostics, each formatted as
+// path:line:column: message.
+func ParseFile(fset *token.FileSet, filename string, src []byte) (f *ast.File, err error) {
+	buffer, bad := Decode(src)
+	file := fset.AddFile(filename, -1, len(buffer))
+	f = &ast.File{PosRange: ast.PosRange{From: file.Pos(0), To: file.Pos(len(buffer))}, Name: filename}
+
+	p := NewFileParser(file, buffer)
+	p.Options.Recover = true
+	p.Macros = macro.NewExpander(fset)
+
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(Bailout); !ok {
+				panic(r)
+			}
+		}
+		f.Imports = ast.Imports(f.Decls)
+		f.Comments = p.Comments
+		diagnosis.Sort(p.Diagnosis)
+		err = diagnosisError(file, p.Macros, p.Diagnosis)
+	}()
+
+	p.ReportBadRunes(bad)
+	p.Scan()
+	f.Package = p.ExpectPackageClause()
+	for {
+		p.SkipNewlines()
+		if p.ReachedEOF {
+			break
+		}
+		for _, decl := range p.ExpectDecls() {
+			p.ValidateDecl(&decl)
+			f.Decls = append(f.Decls, decl)
+		}
+	}
+	return f, nil
+}
+
+// diagnosisError joins the errors among the diagnostics of file. Diagnostics in expanded code are positioned
+// at their source, see macro.Expander.Source, and name the macro.
+func diagnosisError(file *token.File, macros *macro.Expander, diags []diagnosis.Diagnosis) error {
+	var errs []error
+	for _, d := range diags {
+		if !d.IsError() {
+			continue
+		}
+		msg := fmt.Sprint(d.Error)
+		if node, ok := d.Error.(ast.Node); ok {
+			at, x, _ := macros.Source(node.GetPosRange().From)
+			if x != nil {
+				msg += " (in expansion of " + x.Macro.Ident.Literal + "!)"
+			}
+			pos := file.Position(at)
+			errs = append(errs, fmt.Errorf("%s:%d:%d: %s", file.Name(), pos.Line+1, pos.Column+1, msg))
+		} else {
+			errs = append(errs, fmt.Errorf("%s: %s", file.Name(), msg))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ParsePackage parses the sources of a package by file name, see ParseFile, and assembles them with NewPackage.
+// The package is returned with every file, the error joins the errors of the files and the mismatched clauses.
+func ParsePackage(fset *token.FileSet, sources map[string][]byte) (*ast.Package, error) {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var (
+		files = make([]*ast.File, 0, len(names))
+		errs  []error
+	)
+	for _, name := range names {
+		f, err := ParseFile(fset, name, sources[name])
+		files = append(files, f)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	pkg, err := NewPackage(fset, files)
+	return pkg, errors.Join(append(errs, err)...)
+}
+
+// NewPackage assembles the parsed files of a package, in file name order. The package clauses of the files
+// must name the same package, files without one belong to the package the others name. The package is
+// returned with every file, the error joins the mismatched clauses.
+func NewPackage(fset *token.FileSet, files []*ast.File) (*ast.Package, error) {
+	files = slices.Clone(files)
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	var (
+		pkg   = &ast.Package{Files: map[string]*ast.File{}}
+		first *ast.File // declaring the name of the package
+		errs  []error
+	)
+	for _, f := range files {
+		pkg.Files[f.Name] = f
+		switch {
+		case f.Package == nil:
+		case first == nil:
+			first = f
+			pkg.Name = f.Package.Literal
+		case f.Package.Literal != pkg.Name:
+			pos := fset.Position(f.Package.From)
+			errs = append(errs, fmt.Errorf("%s:%d:%d: package %s, want %s as declared by %s",
+				f.Name, pos.Line+1, pos.Column+1, f.Package.Literal, pkg.Name, first.Name))
+		}
+	}
+	return pkg, errors.Join(errs...)
+}
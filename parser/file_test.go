@@ -0,0 +1,189 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package parser
+
+import (
+	"cee/ast"
+	"cee/token"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParsePackage(t *testing.T) {
+	pkg, err := ParsePackage(token.NewFileSet(), map[string][]byte{
+		"a.cee": []byte("package a"),
+		"b.cee": []byte("package b"),
+		"c.cee": nil,
+	})
+	if pkg.Name != "a" || len(pkg.Files) != 3 || pkg.Files["c.cee"].Package != nil {
+		t.Errorf("package %q of %d files", pkg.Name, len(pkg.Files))
+	}
+	if err == nil || !strings.Contains(err.Error(), "b.cee:1:9: package b, want a as declared by a.cee") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestParseFileMacros(t *testing.T) {
+	src := "macro twice(x) {\n\tx + x\n}\n\nmacro quad(x) { twice!(x) * 2 }\n\n" +
+		"fun f(y i64) i64 {\n\tval z = twice!(y)\n\treturn g(quad!(z),\n\t\t1)\n}\n"
+	fset := token.NewFileSet()
+	f, err := ParseFile(fset, "a.cee", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := ast.Fprint(&b, f.Decls[2]); err != nil {
+		t.Fatal(err)
+	}
+	if want := "fun f(y i64) i64 {\n\tval z = y + y\n\treturn g(z + z * 2, 1)\n}\n"; b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+	// The declaration straddling the expansion spans the invocation, the expanded value lies in the expansion.
+	decl := f.Decls[2].Value.(ast.FuncDecl).Stmt.Stmts[0]
+	if r := decl.GetPosRange(); fset.Text(r.From, r.To) != "val z = twice!(y)" {
+		t.Errorf("declaration spans %q", fset.Text(r.From, r.To))
+	}
+	if value := decl.Value.(ast.ValDecl).Value.GetPosRange(); fset.File(value.From).Name() != "twice!" {
+		t.Errorf("value in %s", fset.File(value.From).Name())
+	}
+
+	_, err = ParseFile(token.NewFileSet(), "b.cee", []byte("macro bad() { 1 + val }\n\nfun g() {\n\tval z = bad!()\n\tundefined!(1)\n}\n"))
+	if err == nil || !strings.Contains(err.Error(), "b.cee:5:2: unknown macro: undefined") ||
+		!strings.Contains(err.Error(), "b.cee:4:10: syntax error: unexpected token: val (in expansion of bad!)") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	src := "package a // the package\n\nimport \"x\"\nimport (\n\ty \"lib/y\"\n\t\"z\"\n)\n\nfun f() {}\n"
+	fset := token.NewFileSet()
+	fset.AddFile("other.cee", -1, 10)
+	f, err := ParseFile(fset, "a.cee", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tf := fset.File(f.From); tf == nil || tf.Name() != "a.cee" || tf.Offset(f.From) != 0 || tf.Offset(f.To) != len(src) {
+		t.Errorf("file spans %+v", f.PosRange)
+	}
+	if f.Name != "a.cee" || f.Package == nil || f.Package.Literal != "a" {
+		t.Errorf("file %q of package %+v", f.Name, f.Package)
+	}
+	var imports []string
+	for _, imp := range f.Imports {
+		imports = append(imports, imp.CanonicalName.Literal)
+	}
+	if strings.Join(imports, " ") != `"x" "lib/y" "z"` || f.Imports[1].Alias == nil || len(ast.Imports(f.Decls)) != 3 {
+		t.Errorf("imports %v", imports)
+	}
+	if len(f.Decls) != 4 || f.Decls[3].Tag != ast.StmtFuncDecl {
+		t.Errorf("declarations %+v", f.Decls)
+	}
+	if len(f.Comments) != 1 || f.Comments[0].Literal != "// the package" {
+		t.Errorf("comments %+v", f.Comments)
+	}
+
+	f, err = ParseFile(token.NewFileSet(), "b.cee", []byte("fun g() {}\n"))
+	if err != nil || f.Package != nil || len(f.Decls) != 1 {
+		t.Errorf("file without package clause %+v, %v", f, err)
+	}
+}
+
+// exprKinds parses a file and counts its expressions by kind.
+func exprKinds(t *testing.T, src string) map[ast.ExprKind]int {
+	t.Helper()
+	f, err := ParseFile(token.NewFileSet(), "a.cee", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := map[ast.ExprKind]int{}
+	for _, decl := range f.Decls {
+		ast.Inspect(decl, func(n ast.Node) bool {
+			if expr, ok := n.(ast.Expr); ok {
+				kinds[expr.Tag]++
+				if expr.Tag == ast.ExprTry && expr.Value.(ast.TryExpr).Expr.Tag == ast.ExprBinary {
+					t.Errorf("try binds the binary expression %+v", expr)
+				}
+			}
+			return true
+		})
+	}
+	return kinds
+}
+
+func TestParseFileTry(t *testing.T) {
+	kinds := exprKinds(t, `package a
+
+fun f() i64 {
+	val n = parse(s)?
+	return try m.get("k") + n
+}
+`)
+	if kinds[ast.ExprTry] != 2 {
+		t.Errorf("%d try expressions, want 2", kinds[ast.ExprTry])
+	}
+}
+
+func TestParseFileIntrinsic(t *testing.T) {
+	kinds := exprKinds(t, `package a
+
+fun f(x i64) i64 {
+	return @math.abs(x - 1) + 1
+}
+`)
+	if kinds[ast.ExprIntrinsic] != 1 || kinds[ast.ExprBinary] != 2 {
+		t.Errorf("expressions by kind %v", kinds)
+	}
+}
+
+func TestParseFileIndexOrInstantiation(t *testing.T) {
+	kinds := exprKinds(t, `package a
+
+fun f(xs []i64, m map[string]i64) i64 {
+	val n = m["k"]
+	return max[i64](xs[n], xs[0])
+}
+`)
+	if kinds[ast.ExprIndex] != 3 || kinds[ast.ExprGenericInstantiation] != 1 {
+		t.Errorf("expressions by kind %v", kinds)
+	}
+}
+
+func TestParseFileRecover(t *testing.T) {
+	f, err := ParseFile(token.NewFileSet(), "a.cee", []byte("package a\n\nfun f() i64 {\n\treturn 1\n}\n\nval s = `abc\n"))
+	if err == nil || !strings.Contains(err.Error(), "a.cee:7:9: ") {
+		t.Errorf("err = %v", err)
+	}
+	if len(f.Decls) == 0 || f.Decls[0].Tag != ast.StmtFuncDecl {
+		t.Errorf("declarations before the illegal token %+v", f.Decls)
+	}
+}
+
+func TestParseFileTruncated(t *testing.T) {
+	// Each input ends its lists at the end of the input or at the enclosing closer, the declarations following
+	// them are kept.
+	for _, src := range []string{
+		"package a\nfun",
+		"fun f(",
+		"fun f(a, ) {}\nval y = 1\n",
+		"fun f[",
+		"extern",
+		"type T struct {",
+		"type T struct { a, }\nval y = 1\n",
+		"type T trait { f(a, }\nval y = 1\n",
+		"fun f() (i64, ",
+	} {
+		f, err := ParseFile(token.NewFileSet(), "a.cee", []byte(src))
+		if n := strings.Count(fmt.Sprint(err), "\n"); n > 5 {
+			t.Errorf("%q: %d errors: %v", src, n+1, err)
+		}
+		if !strings.HasSuffix(src, "val y = 1\n") {
+			continue
+		}
+		if len(f.Decls) == 0 || f.Decls[len(f.Decls)-1].Tag != ast.StmtValDecl {
+			t.Errorf("%q: declarations %+v", src, f.Decls)
+		}
+	}
+}
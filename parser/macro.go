@@ -0,0 +1,98 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package parser
+
+import (
+	"cee/ast"
+	"cee/edition"
+	"cee/token"
+)
+
+// Invocations are expanded as they are scanned: the tokens of `name!(args)` are replaced by the tokens of
+// the expansion, which the parser reads as if they were written in place. Expanded tokens keep their
+// positions in the files of the expansions, see macro.Expander.
+
+// define registers a macro declaration for the invocations following it.
+func (p *Parser) define(decl ast.MacroDecl) {
+	if p.Macros == nil {
+		return
+	}
+	p.Macros.Define(decl)
+	p.reportMacros()
+}
+
+// reportMacros moves the diagnostics of the expander to the parser.
+func (p *Parser) reportMacros() {
+	diags := p.Macros.Diagnosis
+	p.Macros.Diagnosis = nil
+	for _, d := range diags {
+		p.Report(d)
+	}
+}
+
+// invokes reports whether tok starts an invocation, the name of the macro being followed by `!(`
+// without space in between.
+func (p *Parser) invokes(tok ast.Token) bool {
+	if p.Macros == nil || p.verbatim || tok.Kind != token.IDENT || !p.supports(edition.Macros) {
+		return false
+	}
+	offset := p.Position.Offset
+	return offset+1 < len(p.Buffer) && p.Buffer[offset] == '!' && p.Buffer[offset+1] == '('
+}
+
+// expand scans the invocation name starts and returns the first token of its expansion, queueing the others.
+// The line breaks at the ends of the expansion are dropped, and all of them inside parentheses as scanned
+// line breaks are. An invocation left open at the end of the input is read unexpanded.
+func (p *Parser) expand(name ast.Token) ast.Token {
+	toks := []ast.Token{name}
+	for depth := 0; ; {
+		tok := p.lex()
+		toks = append(toks, tok)
+		switch tok.Kind {
+		case token.LPAREN, token.LBRACK, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACK, token.RBRACE:
+			depth--
+		}
+		if tok.Kind == token.EOF {
+			p.expanded = toks[1:]
+			return name
+		}
+		if depth == 0 && tok.Kind == token.RPAREN {
+			break
+		}
+	}
+
+	toks = p.Macros.Expand(toks)
+	p.reportMacros()
+	p.expansions++
+
+	inside := p.InsideParens()
+	for _, tok := range toks {
+		if tok.Kind == token.NEWLINE && (inside || len(p.expanded) == 0) {
+			continue
+		}
+		p.expanded = append(p.expanded, tok)
+	}
+	for len(p.expanded) != 0 && p.expanded[len(p.expanded)-1].Kind == token.NEWLINE {
+		p.expanded = p.expanded[:len(p.expanded)-1]
+	}
+	return p.next()
+}
+
+// unexpanded maps the bounds of a range straddling an expansion and the tokens around it to the source:
+// a bound in an expansion moves to the bound of the outermost invocation.
+func (p *Parser) unexpanded(r ast.PosRange) ast.PosRange {
+	if p.expansions == 0 || p.Macros.FileSet.File(r.From) == p.Macros.FileSet.File(r.To) {
+		return r
+	}
+	if expansions, _ := p.Macros.Trace(r.From); len(expansions) != 0 {
+		r.From = expansions[len(expansions)-1].Call.From
+	}
+	if expansions, _ := p.Macros.Trace(r.To); len(expansions) != 0 {
+		r.To = expansions[len(expansions)-1].Call.To
+	}
+	return r
+}
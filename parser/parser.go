@@ -5,12 +5,19 @@
 package parser
 
 import (
+	"cee"
 	"cee/ast"
 	"cee/diagnosis"
+	"cee/edition"
+	"cee/macro"
 	"cee/stack"
 	"cee/token"
+	"errors"
+	"fmt"
 	scanner "github.com/langvm/go-cee-scanner"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 func ParsePackageName(canonicalName string) string {
@@ -21,18 +28,88 @@ func ParsePackageName(canonicalName string) string {
 // NOTICE:
 // ALL Expect* functions start from the cursor position and end at the NEXT token.
 
+type Options struct {
+	Edition string // the latest when empty
+
+	// Recover turns input the scanner rejects into ILLEGAL tokens and diagnostics instead of panicking,
+	// scanning continues after the offending lexeme.
+	Recover bool
+
+	// MaxErrors is the number of diagnostics after which the parser bails out, the rest of the input is skipped.
+	// DefaultMaxErrors when zero, no limit when negative.
+	MaxErrors int
+
+	// Validate checks the positions of every token and declaration, a debug mode for the parser itself:
+	// violations panic with an *ast.PositionError.
+	Validate bool
+
+	// SingleLineStrings ends quoted strings at the end of their line, where an open one is reported as a
+	// NonClosedQuoteError and taken as closed. A backslash before the line break continues the string.
+	SingleLineStrings bool
+}
+
+const DefaultMaxErrors = 100
+
+func (o Options) maxErrors() int {
+	if o.MaxErrors == 0 {
+		return DefaultMaxErrors
+	}
+	return o.MaxErrors
+}
+
+// Bailout is the panic value of a parser out of its error budget, see Options.MaxErrors.
+// Callers recovering it keep the declarations and diagnostics parsed so far.
+type Bailout struct{}
+
 type Parser struct {
 	scanner.Scanner
 	ReachedEOF bool
 
+	Options Options
+
+	File *token.File
+
 	Token ast.Token
 
-	QuoteStack []int
+	QuoteStack stack.Stack[Quote]
+
+	lastToken token.Pos // end of the previous token, for Options.Validate
+	end       token.Pos // end of the last token consumed by Scan, line breaks excepted
+	lastDecl  token.Pos
+
+	Comments []ast.Token
 
 	Diagnosis []diagnosis.Diagnosis
+
+	stream []ast.Token // tokens scanned ahead of Token or since the outermost mark, see Peek and Mark
+	cursor int         // index of the token following Token in stream
+	marks  int
+
+	exprLev int // < 0 in a control clause header, incremented inside parentheses, brackets and braces
+
+	// Macros expands the invocations of the macros declared in the file so far, nil leaves them unexpanded.
+	Macros     *macro.Expander
+	expanded   []ast.Token // rest of the expansion being read, see next
+	expansions int         // invocations expanded
+	validated  int         // expansions when the last declaration was validated
+	verbatim   bool        // reading a macro body, whose invocations are expanded with the body
+}
+
+// Quote is an open delimiter awaiting its closer.
+type Quote struct {
+	Open ast.Token
+	Want int // kind of the closer
 }
 
+// NewParser parses a buffer registered as an anonymous file of its own file set.
 func NewParser(buffer []rune) Parser {
+	return NewFileParser(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+}
+
+// NewFileParser parses a buffer whose positions are allocated in file.
+// The file retains the buffer as its content, literals of compact tokens are materialized from it.
+func NewFileParser(file *token.File, buffer []rune) Parser {
+	file.SetContent(buffer)
 	return Parser{
 		Scanner: scanner.Scanner{
 			BufferScanner: scanner.BufferScanner{
@@ -41,50 +118,547 @@ func NewParser(buffer []rune) Parser {
 			Whitespaces: token.Whitespaces,
 			Delimiters:  token.Delimiters,
 		},
+		File: file,
 	}
 }
 
-func (p *Parser) Scan() {
-	begin := p.Position
+// Require reports the current token unless the edition of the file supports the feature it starts.
+func (p *Parser) Require(f edition.Feature) {
+	if p.supports(f) {
+		return
+	}
+	e := p.Options.Edition
+	p.Report(diagnosis.Diagnosis{
+		Kind:  diagnosis.EditionRequired,
+		Error: diagnosis.EditionRequiredError{At: p.Token, Feature: f.String(), Since: f.Since(), Edition: e},
+	})
+}
 
-	bt, err := p.Scanner.Scan()
+// supports reports whether the edition of the file supports the feature, files without edition support all.
+func (p *Parser) supports(f edition.Feature) bool {
+	e := p.Options.Edition
+	return e == "" || edition.Supports(e, f)
+}
+
+// pos returns the compact position of the scanner cursor, which is past the lookahead Token. Nodes are
+// positioned with rangeFrom instead.
+func (p *Parser) pos() token.Pos { return p.File.Pos(p.Position.Offset) }
+
+// rangeFrom returns the range of a node from begin, the From of its first token, to the end of the last token
+// consumed. A node which consumed nothing is empty at begin, one straddling an expansion spans the invocation.
+func (p *Parser) rangeFrom(begin token.Pos) ast.PosRange {
+	r := p.unexpanded(ast.PosRange{From: begin, To: p.end})
+	return ast.PosRange{From: r.From, To: max(r.From, r.To)}
+}
+
+func (p *Parser) reachEOF() ast.Token {
+	eof := ast.Token{
+		PosRange: ast.PosRange{From: p.pos(), To: p.pos()},
+		Kind:     token.EOF,
+	}
+	p.validateToken(eof)
+	return eof
+}
+
+// validateToken checks that a token lies in the file and follows the previous one.
+func (p *Parser) validateToken(tok ast.Token) {
+	if !p.Options.Validate {
+		return
+	}
+	node := fmt.Sprintf("token %d %q", tok.Kind, tok.Literal)
+	err := ast.CheckRange(p.File, node, tok.PosRange)
+	if err == nil && tok.From < p.lastToken {
+		err = &ast.PositionError{File: p.File, Node: node, Range: tok.PosRange, Reason: "before the end of the previous token"}
+	}
+	if err != nil {
+		panic(err)
+	}
+	p.lastToken = tok.To
+}
+
+// ValidateDecl checks the ranges of a declaration and that it follows the previous one, see Options.Validate.
+func (p *Parser) ValidateDecl(decl *ast.Stmt) {
+	if !p.Options.Validate {
+		return
+	}
+	if p.validated != p.expansions {
+		// The nodes expanded from macros lie in the files of their expansions.
+		p.validated = p.expansions
+		return
+	}
+	err := ast.Validate(p.File, decl)
+	if r := decl.GetPosRange(); err == nil && r.From < p.lastDecl {
+		err = &ast.PositionError{File: p.File, Node: "declaration", Range: r, Reason: "before the end of the previous declaration"}
+	}
 	if err != nil {
 		panic(err)
 	}
+	p.lastDecl = decl.GetPosRange().To
+}
 
+// lookup maps a literal to its keyword, operator or delimiter kind without allocating, 0 if there is none.
+func lookup(lit []rune) int {
 	var (
-		kind = 0
-		lit  = string(bt.Literal)
+		buf [16]byte
+		n   int
 	)
+	for _, r := range lit {
+		if r >= utf8.RuneSelf || n == len(buf) {
+			return 0
+		}
+		buf[n] = byte(r)
+		n++
+	}
+	return token.Keyword2Enum[string(buf[:n])]
+}
+
+// skipWhitespace advances the cursor over whitespace, which never contains line breaks.
+func (p *Parser) skipWhitespace() {
+	for p.Position.Offset < len(p.Buffer) && p.Whitespaces[p.Buffer[p.Position.Offset]] != 0 {
+		p.Position.Offset++
+		p.Position.Column++
+	}
+}
+
+// isIdentRune accepts combining marks after the first rune, so that identifiers in decomposed form scan whole
+// and normalize to their precomposed spelling.
+func isIdentRune(r rune, first bool) bool {
+	return r == '_' || unicode.IsLetter(r) || !first && (unicode.IsDigit(r) || unicode.In(r, unicode.Mn, unicode.Mc))
+}
+
+// scanIdent is the fast path for identifiers and keywords, which are the bulk of the tokens.
+// The literal slices the buffer instead of being copied.
+func (p *Parser) scanIdent() ([]rune, bool) {
+	begin := p.Position.Offset
+	end := begin
+	for end < len(p.Buffer) && isIdentRune(p.Buffer[end], end == begin) {
+		end++
+	}
+	if end == begin {
+		return nil, false
+	}
+	p.Position.Offset = end
+	p.Position.Column += end - begin
+	return p.Buffer[begin:end:end], true
+}
+
+var (
+	errUnclosedRaw     = errors.New("raw string not terminated")
+	errUnclosedSegment = errors.New("interpolated string not terminated")
+	errUnclosedComment = errors.New("comment not terminated")
+)
+
+func isDigit(r rune, base int) bool {
+	switch {
+	case base <= 10:
+		return '0' <= r && r < '0'+rune(base)
+	default:
+		return '0' <= r && r <= '9' || 'a' <= r && r <= 'f' || 'A' <= r && r <= 'F'
+	}
+}
+
+// scanNumber is the path of numeric literals, which the scanner only knows as integers: decimal, hexadecimal,
+// octal and binary integers, decimal floats with a fraction or an exponent, hexadecimal floats with a binary
+// exponent, and any of them followed by `i` as an imaginary literal. A dot only belongs to the literal when
+// a digit follows, so that `1.max` selects a member. Underscores are validated by package literals.
+func (p *Parser) scanNumber() (int, []rune, bool) {
+	begin := p.Position.Offset
+	end := begin
+	at := func(i int) rune {
+		if i < len(p.Buffer) {
+			return p.Buffer[i]
+		}
+		return 0
+	}
+	digits := func(base int) {
+		for isDigit(at(end), base) || at(end) == '_' {
+			end++
+		}
+	}
+	if !isDigit(at(begin), 10) {
+		return 0, nil, false
+	}
+	from := p.pos()
+
+	kind, base, exp := token.INT, 10, "eE"
+	if at(begin) == '0' {
+		switch at(begin + 1) {
+		case 'x', 'X':
+			base, exp = 16, "pP"
+		case 'o', 'O':
+			base, exp = 8, ""
+		case 'b', 'B':
+			base, exp = 2, ""
+		}
+		if base != 10 {
+			end += 2
+		}
+	}
+	mantissa := end
+	digits(base)
+	if exp != "" && at(end) == '.' && isDigit(at(end+1), base) {
+		kind = token.FLOAT
+		end++
+		digits(base)
+	}
+	empty := strings.Trim(string(p.Buffer[mantissa:end]), "_.") == ""
+	if exp != "" && at(end) != 0 && strings.ContainsRune(exp, at(end)) {
+		digit := end + 1
+		if at(digit) == '+' || at(digit) == '-' {
+			digit++
+		}
+		if isDigit(at(digit), 10) {
+			kind = token.FLOAT
+			end = digit
+			digits(10)
+		}
+	}
+	if at(end) == 'i' && !isIdentRune(at(end+1), false) {
+		kind = token.IMAG
+		end++
+	}
+
+	p.Position.Offset = end
+	p.Position.Column += end - begin
+	if base != 10 && empty {
+		p.Report(diagnosis.Diagnosis{
+			Kind: diagnosis.InvalidLiteral,
+			Error: diagnosis.LiteralDigitsError{
+				Literal: ast.LiteralValue{Token: ast.Token{PosRange: ast.PosRange{From: from, To: p.pos()}, Kind: kind, Literal: string(p.Buffer[begin:end])}},
+				Base:    baseNames[base],
+			},
+		})
+	}
+	return kind, p.Buffer[begin:end:end], true
+}
+
+// maxMark is the length of the longest operator, as `<<=` and `...`.
+const maxMark = 3
+
+// scanMark is the fast path for operators and delimiters: the longest one at the cursor, whose literal slices
+// the buffer as the literal of scanIdent does. Marks of no known kind are left to the scanner.
+func (p *Parser) scanMark() (int, []rune, bool) {
+	begin := p.Position.Offset
+	for end := min(begin+maxMark, len(p.Buffer)); end > begin; end-- {
+		kind := lookup(p.Buffer[begin:end])
+		if !token.IsOperator(kind) && (kind <= token.DELIMITER_BEGIN || kind >= token.DELIMITER_END) {
+			continue
+		}
+		p.Position.Offset = end
+		if kind == token.NEWLINE {
+			p.Position.Line++
+			p.Position.Column = 0
+		} else {
+			p.Position.Column += end - begin
+		}
+		return kind, p.Buffer[begin:end:end], true
+	}
+	return 0, nil, false
+}
+
+// baseNames names the bases of the prefixed number literals in diagnostics.
+var baseNames = map[int]string{2: "binary", 8: "octal", 16: "hexadecimal"}
+
+// scanRawString scans a raw string between backticks, which spans lines and has no escapes, see token.Unquote.
+// An unclosed raw string runs to the end of the buffer and is reported false.
+func (p *Parser) scanRawString() (lit []rune, closed bool) {
+	begin := p.Position.Offset
+	end := begin + 1
+	p.Position.Column++
+	for end < len(p.Buffer) && p.Buffer[end] != '`' {
+		if p.Buffer[end] == '\n' {
+			p.Position.Line++
+			p.Position.Column = 0
+		} else {
+			p.Position.Column++
+		}
+		end++
+	}
+	closed = end < len(p.Buffer)
+	if closed {
+		end++
+		p.Position.Column++
+	}
+	p.Position.Offset = end
+	return p.Buffer[begin:end:end], closed
+}
+
+// scanSlash scans the lexemes starting with a slash: a line comment up to the end of its line, a block comment
+// up to its closing `*/`, which spans lines, or the operators `/` and `/=`. Comments keep their slashes.
+// An unclosed block comment runs to the end of the buffer and is reported false.
+func (p *Parser) scanSlash() (kind int, lit []rune, closed bool) {
+	begin := p.Position.Offset
+	at := func(i int) rune {
+		if i < len(p.Buffer) {
+			return p.Buffer[i]
+		}
+		return 0
+	}
+
+	end := begin + 1
+	kind, closed = token.QUO, true
+	switch at(end) {
+	case '=':
+		kind = token.QUO_ASSIGN
+		end++
+	case '/':
+		kind = token.COMMENT
+		for end < len(p.Buffer) && p.Buffer[end] != '\n' {
+			end++
+		}
+	case '*':
+		kind, closed = token.COMMENT, false
+		for end++; end < len(p.Buffer) && !closed; end++ {
+			closed = p.Buffer[end] == '*' && at(end+1) == '/'
+		}
+		if closed {
+			end++
+		}
+	}
+
+	for _, r := range p.Buffer[begin:end] {
+		if r == '\n' {
+			p.Position.Line++
+			p.Position.Column = 0
+		} else {
+			p.Position.Column++
+		}
+	}
+	p.Position.Offset = end
+	return kind, p.Buffer[begin:end:end], closed
+}
+
+// interpolates reports whether the cursor is at the start of a string segment: a quote opening a string that
+// embeds `${expr}` before its end, or the brace closing an embedded expression.
+func (p *Parser) interpolates() bool {
+	switch p.Buffer[p.Position.Offset] {
+	case '}':
+		return !p.QuoteStack.Empty() && p.QuoteStack.Peek().Want == token.STRING_TAIL
+	case '"':
+		if !p.supports(edition.Interpolation) {
+			return false
+		}
+		for i := p.Position.Offset + 1; i < len(p.Buffer); i++ {
+			switch p.Buffer[i] {
+			case '\\':
+				i++
+			case '"', '\n':
+				return false
+			case '$':
+				if i+1 < len(p.Buffer) && p.Buffer[i+1] == '{' {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// scanSegment scans a segment of an interpolated string up to the next `${` or the closing quote. The quote
+// stack holds the string while its expressions are scanned, so that their braces balance.
+func (p *Parser) scanSegment(begin token.Pos) (int, ast.PosRange, []rune) {
+	first := p.Position.Offset
+	end := first + 1
+	kind := token.ILLEGAL
+	for kind == token.ILLEGAL && end < len(p.Buffer) && p.Buffer[end] != '\n' {
+		switch p.Buffer[end] {
+		case '\\':
+			end++
+		case '"':
+			kind = token.STRING_TAIL
+		case '$':
+			if end+1 < len(p.Buffer) && p.Buffer[end+1] == '{' {
+				kind = token.STRING_MID
+				end++
+			}
+		}
+		end++
+	}
+	if end > len(p.Buffer) {
+		end = len(p.Buffer)
+	}
+	if kind == token.STRING_MID && p.Buffer[first] == '"' {
+		kind = token.STRING_HEAD
+	}
+	p.Position.Column += end - first
+	p.Position.Offset = end
+	pos := ast.PosRange{From: begin, To: p.pos()}
+	lit := p.Buffer[first:end:end]
+
+	tok := ast.Token{PosRange: pos, Kind: kind, Literal: string(lit)}
+	switch kind {
+	case token.STRING_HEAD:
+		p.QuoteStack.Push(Quote{Open: tok, Want: token.STRING_TAIL})
+	case token.STRING_TAIL:
+		p.QuoteStack.Pop()
+	case token.ILLEGAL:
+		// The line ends before the string, which is closed here.
+		p.QuoteStack.Pop()
+		p.reportIllegal(pos, lit, errUnclosedSegment)
+	}
+	return kind, pos, lit
+}
+
+// scanQuotedString scans a string or a char quoted by quote up to its closing quote, or up to the end of its
+// line when singleLine is set, escaped line breaks excepted. The literal keeps its quotes, see token.Unquote.
+func (p *Parser) scanQuotedString(quote rune, singleLine bool) (lit []rune, closed bool) {
+	begin := p.Position.Offset
+	end := begin + 1
+	p.Position.Column++
+	for end < len(p.Buffer) && !(singleLine && p.Buffer[end] == '\n') {
+		r := p.Buffer[end]
+		end++
+		p.Position.Column++
+		if r == quote {
+			closed = true
+			break
+		}
+		if r == '\n' {
+			p.Position.Line++
+			p.Position.Column = 0
+		}
+		if r != '\\' || end == len(p.Buffer) {
+			continue
+		}
+		if p.Buffer[end] == '\r' && end+1 < len(p.Buffer) && p.Buffer[end+1] == '\n' {
+			end++
+		}
+		if p.Buffer[end] == '\n' {
+			p.Position.Line++
+			p.Position.Column = 0
+		} else {
+			p.Position.Column++
+		}
+		end++
+	}
+	p.Position.Offset = end
+	return p.Buffer[begin:end:end], closed
+}
+
+// scan reads the next lexeme and maintains the quote stack, kind is EOF at the end of the buffer.
+// Identifiers, keywords, operators and delimiters take the fast paths, whose literals slice the buffer, numbers,
+// strings and comments are scanned here as well, other lexemes go through the scanner. Identifiers are normalized to NFC, converting other literals is left to the caller.
+func (p *Parser) scan() (kind int, pos ast.PosRange, lit []rune) {
+	p.skipWhitespace()
+	begin := p.pos()
+	if p.Position.Offset >= len(p.Buffer) {
+		p.unclosed(ast.Token{PosRange: ast.PosRange{From: begin, To: begin}, Kind: token.EOF})
+		return token.EOF, ast.PosRange{From: begin, To: begin}, nil
+	}
+
+	if ident, ok := p.scanIdent(); ok {
+		ident = token.NFC(ident)
+		kind = lookup(ident)
+		if kind == 0 {
+			kind = token.IDENT
+		}
+		return kind, ast.PosRange{From: begin, To: p.pos()}, ident
+	}
+	if kind, number, ok := p.scanNumber(); ok {
+		return kind, ast.PosRange{From: begin, To: p.pos()}, number
+	}
+	if p.Buffer[p.Position.Offset] == '`' {
+		lit, closed := p.scanRawString()
+		pos = ast.PosRange{From: begin, To: p.pos()}
+		if !closed {
+			if p.Options.Recover {
+				p.reportIllegal(pos, lit, errUnclosedRaw)
+				return token.ILLEGAL, pos, lit
+			}
+			p.unclosed(ast.Token{PosRange: ast.PosRange{From: p.pos(), To: p.pos()}, Kind: token.EOF})
+			return token.EOF, ast.PosRange{From: p.pos(), To: p.pos()}, nil
+		}
+		return token.STRING_RAW, pos, lit
+	}
+
+	if p.Buffer[p.Position.Offset] == '/' {
+		// The scanner takes any slash for a comment.
+		kind, lit, closed := p.scanSlash()
+		pos = ast.PosRange{From: begin, To: p.pos()}
+		if !closed {
+			if p.Options.Recover {
+				p.reportIllegal(pos, lit, errUnclosedComment)
+				return token.ILLEGAL, pos, lit
+			}
+			p.unclosed(ast.Token{PosRange: ast.PosRange{From: p.pos(), To: p.pos()}, Kind: token.EOF})
+			return token.EOF, ast.PosRange{From: p.pos(), To: p.pos()}, nil
+		}
+		return kind, pos, lit
+	}
+	if p.interpolates() {
+		return p.scanSegment(begin)
+	}
+	if quote := p.Buffer[p.Position.Offset]; quote == '"' || quote == '\'' {
+		// The scanner would unquote the literal, which keeps its quotes here for token.Unquote.
+		kind = token.STRING
+		if quote == '\'' {
+			kind = token.CHAR
+		}
+		lit, closed := p.scanQuotedString(quote, p.Options.SingleLineStrings || kind == token.CHAR)
+		pos = ast.PosRange{From: begin, To: p.pos()}
+		if !closed {
+			p.Report(diagnosis.Diagnosis{
+				Kind:  diagnosis.NonClosedQuote,
+				Error: diagnosis.NonClosedQuoteError{At: ast.Token{PosRange: pos, Kind: kind, Literal: string(lit)}},
+			})
+			lit = append(lit, quote)
+		}
+		return kind, pos, lit
+	}
+	if kind, lit, ok := p.scanMark(); ok {
+		pos = ast.PosRange{From: begin, To: p.pos()}
+		p.quote(kind, pos)
+		return kind, pos, lit
+	}
+
+	start := p.Position
+	bt, err := p.scanLexeme()
+	if _, eof := err.(scanner.EOFError); eof && p.Position.Offset > start.Offset {
+		// The scanner fails on an operator ending the buffer, which is complete nonetheless.
+		bt, err = scanner.Token{Kind: scanner.OPERATOR, Literal: p.Buffer[start.Offset:p.Position.Offset]}, nil
+	}
+	if err != nil {
+		if p.Options.Recover {
+			return p.illegal(start, err)
+		}
+		if p.Position.Offset >= len(p.Buffer) {
+			p.unclosed(ast.Token{PosRange: ast.PosRange{From: p.pos(), To: p.pos()}, Kind: token.EOF})
+			return token.EOF, ast.PosRange{From: p.pos(), To: p.pos()}, nil
+		}
+		panic(err)
+	}
+
+	pos = ast.PosRange{From: begin, To: p.pos()}
+	lit = bt.Literal
 
 	switch bt.Kind {
 	case scanner.IDENT:
-		kind = token.Keyword2Enum[lit]
+		lit = token.NFC(lit)
+		kind = lookup(lit)
 		if kind == 0 {
 			kind = token.IDENT
 		}
 	case scanner.OPERATOR:
-		kind = token.Keyword2Enum[lit]
+		kind = lookup(lit)
+		if kind == 0 {
+			// The scanner reads a run of marks as one operator, e.g. `**p` or `x=-1`: the run is split
+			// at its longest known operator and the rest is scanned again.
+			for n := len(lit) - 1; n > 0 && kind == 0; n-- {
+				if kind = lookup(lit[:n]); kind != 0 {
+					p.Position = start
+					p.Position.Offset += n
+					p.Position.Column += n
+					lit = lit[:n]
+					pos.To = p.pos()
+				}
+			}
+		}
 		if kind == 0 {
 			kind = token.IDENT
 		}
 	case scanner.DELIMITER:
-		kind = token.Keyword2Enum[lit]
-		switch kind {
-		case token.LBRACE:
-			p.QuoteStack = append(p.QuoteStack, token.RBRACE)
-		case token.LPAREN:
-			p.QuoteStack = append(p.QuoteStack, token.RPAREN)
-		case token.LBRACK:
-			p.QuoteStack = append(p.QuoteStack, token.RBRACK)
-		case token.RBRACE:
-			fallthrough
-		case token.RPAREN:
-			fallthrough
-		case token.RBRACK:
-			p.QuoteStack = stack.Pop(p.QuoteStack)
-		default:
-		}
+		kind = lookup(lit)
+		p.quote(kind, pos)
 	case scanner.INT:
 		kind = token.INT
 	case scanner.CHAR:
@@ -92,34 +666,253 @@ func (p *Parser) Scan() {
 	case scanner.STRING:
 		kind = token.STRING
 	case scanner.COMMENT:
-		p.Scan()
-		return
+		kind = token.COMMENT
 	default:
 		// TODO
 	}
+	if kind == token.ILLEGAL && p.Options.Recover {
+		if p.Position.Offset == start.Offset {
+			return p.illegal(start, nil)
+		}
+		p.reportIllegal(pos, lit, nil)
+	}
+	return kind, pos, lit
+}
 
-	p.Token = ast.Token{
-		PosRange: ast.PosRange{From: begin, To: p.Position},
-		Kind:     kind,
-		Literal:  lit,
+// quote maintains the quote stack for a delimiter, pushing openers and closing closers.
+// The literal of the token is the spelling of its kind, which does not allocate.
+func (p *Parser) quote(kind int, pos ast.PosRange) {
+	tok := ast.Token{PosRange: pos, Kind: kind, Literal: token.KeywordLiterals[kind]}
+	switch kind {
+	case token.LBRACE:
+		p.QuoteStack.Push(Quote{Open: tok, Want: token.RBRACE})
+	case token.LPAREN:
+		p.QuoteStack.Push(Quote{Open: tok, Want: token.RPAREN})
+	case token.LBRACK:
+		p.QuoteStack.Push(Quote{Open: tok, Want: token.RBRACK})
+	case token.RBRACE, token.RPAREN, token.RBRACK:
+		p.close(tok)
 	}
 }
 
+// close pops the opener matched by a closer. A closer matching an opener deeper in the stack closes it
+// and reports the openers above it as unclosed, a closer matching none is reported as stray and ignored.
+func (p *Parser) close(closer ast.Token) {
+	quotes := p.QuoteStack.Items()
+	for i := len(quotes) - 1; i >= 0; i-- {
+		if quotes[i].Want != closer.Kind {
+			continue
+		}
+		for _, q := range quotes[i+1:] {
+			p.reportMismatched(closer, q.Open)
+		}
+		for p.QuoteStack.Len() > i {
+			p.QuoteStack.Pop()
+		}
+		return
+	}
+
+	var open ast.Token
+	if !p.QuoteStack.Empty() {
+		open = p.QuoteStack.Peek().Open
+	}
+	p.reportMismatched(closer, open)
+}
+
+// unclosed reports every opener left at the end of the input.
+func (p *Parser) unclosed(eof ast.Token) {
+	for !p.QuoteStack.Empty() {
+		p.reportMismatched(eof, p.QuoteStack.Pop().Open)
+	}
+}
+
+func (p *Parser) reportMismatched(closer, open ast.Token) {
+	p.Report(diagnosis.Diagnosis{
+		Kind:  diagnosis.MismatchedDelimiter,
+		Error: diagnosis.MismatchedDelimiterError{Close: closer, Open: open},
+	})
+}
+
+// scanLexeme runs the scanner, whose panics are turned into errors in recovery mode.
+func (p *Parser) scanLexeme() (bt scanner.Token, err error) {
+	if p.Options.Recover {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%v", r)
+			}
+		}()
+	}
+	return p.Scanner.Scan()
+}
+
+// illegal rewinds the cursor to start, where the scanner failed, and skips the lexeme up to the next whitespace
+// or delimiter as an ILLEGAL token. A broken string or char literal, e.g. an unterminated one, is skipped up to
+// its closing quote or the end of the line, which quoted literals do not span. At least one rune is skipped,
+// so scanning always progresses.
+func (p *Parser) illegal(start scanner.Position, err error) (int, ast.PosRange, []rune) {
+	p.Position = start
+	begin := p.Position.Offset
+	end := begin + 1
+	if quote := p.Buffer[begin]; quote == '"' || quote == '\'' {
+		for end < len(p.Buffer) && p.Buffer[end] != '\n' {
+			end++
+			if p.Buffer[end-1] == quote && p.Buffer[end-2] != '\\' {
+				break
+			}
+		}
+	} else {
+		for end < len(p.Buffer) && p.Whitespaces[p.Buffer[end]] == 0 && p.Delimiters[p.Buffer[end]] == 0 {
+			end++
+		}
+	}
+	if p.Buffer[begin] == '\n' {
+		p.Position.Line++
+		p.Position.Column = 0
+	} else {
+		p.Position.Column += end - begin
+	}
+	p.Position.Offset = end
+
+	pos := ast.PosRange{From: p.File.Pos(begin), To: p.pos()}
+	lit := p.Buffer[begin:end:end]
+	p.reportIllegal(pos, lit, err)
+	return token.ILLEGAL, pos, lit
+}
+
+func (p *Parser) reportIllegal(pos ast.PosRange, lit []rune, err error) {
+	p.Report(diagnosis.Diagnosis{
+		Kind: diagnosis.IllegalToken,
+		Error: diagnosis.IllegalTokenError{
+			At:  ast.Token{PosRange: pos, Kind: token.ILLEGAL, Literal: string(lit)},
+			Err: err,
+		},
+	})
+}
+
+// Scan advances to the next token, from the tokens buffered by Peek and Mark when there are any.
+func (p *Parser) Scan() {
+	if p.Token.Kind != token.NEWLINE && p.Token.To.IsValid() {
+		p.end = p.Token.To
+	}
+	if p.cursor < len(p.stream) {
+		p.Token = p.stream[p.cursor]
+		p.cursor++
+	} else {
+		p.Token = p.next()
+		if len(p.stream) != 0 {
+			p.stream = append(p.stream, p.Token)
+			p.cursor++
+		}
+	}
+	p.ReachedEOF = p.Token.Kind == token.EOF
+	p.trim()
+}
+
+// next returns the next token of the expansion being read, or scans one, expanding the macro it invokes.
+func (p *Parser) next() ast.Token {
+	if len(p.expanded) != 0 {
+		tok := p.expanded[0]
+		p.expanded = p.expanded[1:]
+		return tok
+	}
+	tok := p.lex()
+	if p.invokes(tok) {
+		return p.expand(tok)
+	}
+	return tok
+}
+
+// lex scans the next token, collecting comments and skipping line breaks inside parentheses.
+func (p *Parser) lex() ast.Token {
+	for {
+		kind, pos, lit := p.scan()
+
+		switch kind {
+		case token.EOF:
+			return p.reachEOF()
+		case token.COMMENT:
+			p.Comments = append(p.Comments, ast.Token{PosRange: pos, Kind: kind, Literal: string(lit)})
+			p.validateToken(p.Comments[len(p.Comments)-1])
+			continue
+		case token.NEWLINE:
+			// Line breaks terminate nothing inside parentheses and brackets, e.g. in multi-line calls and parameter lists.
+			if p.InsideParens() {
+				continue
+			}
+		}
+
+		tok := ast.Token{PosRange: pos, Kind: kind, Literal: string(lit)}
+		p.validateToken(tok)
+		return tok
+	}
+}
+
+// InsideParens reports whether the innermost open delimiter is a parenthesis or a bracket, braces open blocks
+// where line breaks are terminators again.
+func (p *Parser) InsideParens() bool {
+	if p.QuoteStack.Empty() {
+		return false
+	}
+	want := p.QuoteStack.Peek().Want
+	return want == token.RPAREN || want == token.RBRACK
+}
+
+// Report records a diagnostic. Once the error budget is spent the rest of the input is reported as skipped
+// and the parser bails out with a Bailout panic.
 func (p *Parser) Report(d diagnosis.Diagnosis) {
 	p.Diagnosis = append(p.Diagnosis, d)
+
+	if max := p.Options.maxErrors(); max > 0 && len(p.Diagnosis) >= max {
+		// The input is skipped from the cursor, or from the last diagnostic when it lies ahead of the cursor.
+		from := p.Token.From
+		if node, ok := d.Error.(ast.Node); ok && node.GetPosRange().To > from {
+			from = node.GetPosRange().To
+		}
+		p.Diagnosis = append(p.Diagnosis, diagnosis.Diagnosis{
+			Kind: diagnosis.TooManyErrors,
+			Error: diagnosis.TooManyErrorsError{
+				PosRange: ast.PosRange{From: from, To: p.File.Pos(len(p.Buffer))},
+				Max:      max,
+			},
+		})
+		p.ReachedEOF = true
+		panic(Bailout{})
+	}
 }
 
 func (p *Parser) ReportAndRecover(d diagnosis.Diagnosis) {
-	p.Diagnosis = append(p.Diagnosis, d)
+	p.Report(d)
 
-	if len(p.QuoteStack) != 0 {
-		term := stack.Top(p.QuoteStack)
-		for p.Token.Kind != term {
+	if !p.QuoteStack.Empty() {
+		term := p.QuoteStack.Peek().Want
+		for p.Token.Kind != term && !p.ReachedEOF {
 			p.Scan()
 		}
 	}
 }
 
+// recoverBad reports the token as unexpected where a want was and skips to where parsing resumes, at least
+// past the token. It returns the skipped range, spanned by the Bad node standing in for it.
+func (p *Parser) recoverBad(want int) ast.PosRange {
+	begin := p.Token
+	p.ReportAndRecover(diagnosis.Diagnosis{
+		Kind: diagnosis.UnexpectedNode,
+		Error: diagnosis.UnexpectedNodeError{
+			Have: begin,
+			Want: want,
+		},
+	})
+	if p.Token == begin {
+		p.Scan()
+	}
+	return ast.PosRange{From: begin.From, To: p.Token.From}
+}
+
+// ExpectBadExpr skips the tokens that cannot begin an expression, see recoverBad.
+func (p *Parser) ExpectBadExpr() ast.BadExpr {
+	return ast.BadExpr{PosRange: p.recoverBad(token.IDENT)}
+}
+
 func (p *Parser) MatchTerm(term int) {
 	if p.Token.Kind != term {
 		p.Report(diagnosis.Diagnosis{
@@ -132,8 +925,25 @@ func (p *Parser) MatchTerm(term int) {
 	}
 }
 
+// expectCloser expects the closer ending a list, skipping the unexpected tokens before it as ReportAndRecover
+// does, and consumes it. A closer missing at the end of the input is reported only.
+func (p *Parser) expectCloser(term int) {
+	if p.Token.Kind != term {
+		p.ReportAndRecover(diagnosis.Diagnosis{
+			Kind: diagnosis.UnexpectedNode,
+			Error: diagnosis.UnexpectedNodeError{
+				Have: p.Token,
+				Want: term,
+			},
+		})
+	}
+	if p.Token.Kind == term {
+		p.Scan()
+	}
+}
+
 func ExpectList[T any](p *Parser, expectFunc func(p *Parser) T, kind int, delimiter int, terminate int) ast.List[T] {
-	begin := p.Position
+	begin := p.Token.From
 
 	var list []T
 
@@ -154,7 +964,7 @@ func ExpectList[T any](p *Parser, expectFunc func(p *Parser) T, kind int, delimi
 		case terminate:
 			p.Scan()
 			return ast.List[T]{
-				PosRange: ast.PosRange{From: begin, To: p.Position},
+				PosRange: p.rangeFrom(begin),
 				List:     list,
 			}
 		default:
@@ -163,17 +973,528 @@ func ExpectList[T any](p *Parser, expectFunc func(p *Parser) T, kind int, delimi
 	}
 }
 
-func (p *Parser) ExpectIdent() ast.Ident {}
+func (p *Parser) ExpectIdent() ast.Ident {
+	p.MatchTerm(token.IDENT)
+	ident := ast.Ident{Token: p.Token}
+	p.Scan()
+	return ident
+}
+
+func newExpr(kind ast.ExprKind, value ast.Node) ast.Expr {
+	return ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: kind, Value: value}}
+}
 
-func (p *Parser) ExpectBranchExpr() ast.BranchExpr {}
+// ExpectUnaryExpr parses an operator of token.PrefixUnaryOperators applied to its operand, e.g. the negation
+// `-x`, the dereference `*p`, the address-of `&v` or the receive `<-ch`.
+func (p *Parser) ExpectUnaryExpr() ast.UnaryExpr {
+	begin := p.Token.From
 
-func (p *Parser) ExpectCallExpr() ast.CallExpr {
+	op := p.Token
+	if !token.PrefixUnaryOperators[op.Kind] {
+		p.Report(diagnosis.Diagnosis{
+			Kind: diagnosis.UnexpectedNode,
+			Error: diagnosis.UnexpectedNodeError{
+				Have: op,
+				Want: token.MUL,
+			},
+		})
+	}
+	p.Scan()
+	operand := p.expectUnaryExpr()
+
+	return ast.UnaryExpr{
+		PosRange: p.rangeFrom(begin),
+		Operator: op,
+		Expr:     operand,
+	}
+}
+
+// ExpectTryExpr parses the prefix form `try expr`, which binds as tightly as the other prefix operators.
+func (p *Parser) ExpectTryExpr() ast.TryExpr {
+	begin := p.Token.From
+
+	p.MatchTerm(token.TRY)
+	p.Scan()
+	expr := p.expectUnaryExpr()
+
+	return ast.TryExpr{
+		PosRange: p.rangeFrom(begin),
+		Expr:     expr,
+	}
+}
+
+// ExpectPostfixTry wraps the operand parsed so far in a TryExpr for each following `?`, as in `f()?`.
+func (p *Parser) ExpectPostfixTry(operand ast.Expr) ast.Expr {
+	return p.expectPostfixTry(operand.GetPosRange().From, operand)
+}
+
+// expectPostfixTry is ExpectPostfixTry for an operand starting at begin, before the parentheses around it.
+func (p *Parser) expectPostfixTry(begin token.Pos, operand ast.Expr) ast.Expr {
+	for p.Token.Kind == token.QUESTION {
+		p.Scan()
+		operand = newExpr(ast.ExprTry, ast.TryExpr{
+			PosRange: p.rangeFrom(begin),
+			Expr:     operand,
+		})
+	}
+	return operand
+}
+
+// ExpectPostfixCompositeLit parses the composite literal whose type is the operand parsed so far, as in
+// `Point{x: 1}`, when a brace follows an operand naming a type. Other operands are returned as is.
+func (p *Parser) ExpectPostfixCompositeLit(operand ast.Expr) ast.Expr {
+	typ, ok := p.compositeLitType(operand)
+	if !ok {
+		return operand
+	}
+	return newExpr(ast.ExprCompositeLit, p.ExpectCompositeLit(typ))
+}
+
+// compositeLitType returns the type of the composite literal opened by the brace following the operand,
+// false when the operand names no type or the brace opens a block.
+func (p *Parser) compositeLitType(operand ast.Expr) (ast.Type, bool) {
+	if p.Token.Kind != token.LBRACE || !p.compositeLitAllowed() {
+		return ast.Type{}, false
+	}
+	switch v := operand.Value.(type) {
+	case ast.Ident:
+		typ := newType(ast.TypeIdent, ast.TypeAlias{Ident: v})
+		if kind, ok := BuiltinTypes[v.Literal]; ok {
+			typ.Tag = kind
+		}
+		return typ, true
+	case ast.GenericInstantiationExpr:
+		return newType(ast.TypeInstance, v), true
+	case ast.TypeExpr:
+		return v.Type, true
+	}
+	return ast.Type{}, false
+}
+
+// compositeLitAllowed reports whether a brace following a type opens a composite literal. In the header of
+// a control clause it opens the body, as in Go, unless the literal is parenthesized: `if v == (Point{}) {`.
+// The state follows the rules being parsed rather than the quote stack, which runs ahead with Peek.
+func (p *Parser) compositeLitAllowed() bool {
+	return p.exprLev >= 0
+}
+
+// expectHeaderExpr parses the expression of a control clause header, e.g. the condition of `if`.
+func (p *Parser) expectHeaderExpr() ast.Expr {
+	outer := p.exprLev
+	p.exprLev = -1
+	defer func() { p.exprLev = outer }()
+
+	return p.ExpectExpr()
+}
+
+// expectNestedExpr parses an expression inside parentheses, brackets or braces, where composite literals are
+// allowed even within a control clause header.
+func (p *Parser) expectNestedExpr() ast.Expr {
+	outer := p.exprLev
+	p.exprLev = max(outer, 0) + 1
+	defer func() { p.exprLev = outer }()
+
+	return p.ExpectExpr()
+}
+
+// ExpectCompositeLit parses the braces `{elems}` of a composite literal of type typ, zero when elided.
+// Elements are keyed `key: value` or positional, and a nested `{elems}` is a literal of elided type.
+func (p *Parser) ExpectCompositeLit(typ ast.Type) ast.CompositeLit {
+	begin := p.Token.From
+	if typ.Value != nil {
+		begin = typ.GetPosRange().From
+	}
+
+	p.MatchTerm(token.LBRACE)
+	p.Scan()
+	var elems []ast.KeyedElement
+	for {
+		p.SkipNewlines()
+		if p.Token.Kind == token.RBRACE || p.ReachedEOF {
+			break
+		}
+		elems = append(elems, p.expectKeyedElement())
+
+		p.SkipNewlines()
+		if p.Token.Kind != token.COMMA {
+			break
+		}
+		p.Scan()
+	}
+	p.SkipNewlines()
+	p.MatchTerm(token.RBRACE)
+	p.Scan()
+
+	return ast.CompositeLit{
+		PosRange: p.rangeFrom(begin),
+		Type:     typ,
+		Elems:    elems,
+	}
+}
+
+func (p *Parser) expectKeyedElement() ast.KeyedElement {
+	begin := p.Token.From
+
+	elem := ast.KeyedElement{Value: p.expectElementValue()}
+	if p.Token.Kind == token.COLON {
+		p.Scan()
+		elem.Key = elem.Value
+		elem.Value = p.expectElementValue()
+	}
+
+	elem.PosRange = p.rangeFrom(begin)
+	return elem
+}
+
+func (p *Parser) expectElementValue() ast.Expr {
+	if p.Token.Kind == token.LBRACE {
+		return newExpr(ast.ExprCompositeLit, p.ExpectCompositeLit(ast.Type{}))
+	}
+	return p.expectNestedExpr()
+}
+
+// ExpectIndexOrInstantiation parses the brackets following the operand parsed so far, either the index
+// `x[i]` or the type arguments `f[T, U]` of an instantiation. Both are tried: the brackets instantiate when
+// they hold types only and one of them cannot be an expression, a builtin or a composite type, or when they
+// hold several. A single name is parsed as an index, resolve.Qualify rewrites it when the name is a type.
+func (p *Parser) ExpectIndexOrInstantiation(operand ast.Expr) ast.Expr {
+	return p.expectIndexOrInstantiation(operand.GetPosRange().From, operand)
+}
+
+// expectIndexOrInstantiation is ExpectIndexOrInstantiation for an operand starting at begin, before the
+// parentheses around it.
+func (p *Parser) expectIndexOrInstantiation(begin token.Pos, operand ast.Expr) ast.Expr {
+	m := p.Mark()
+	if args, ok := p.tryTypeArgs(); ok && instantiates(args) {
+		p.Commit(m)
+		return newExpr(ast.ExprGenericInstantiation, ast.GenericInstantiationExpr{
+			PosRange: p.rangeFrom(begin),
+			Expr:     operand,
+			TypeArgs: args,
+		})
+	}
+	p.Backtrack(m)
+
+	p.MatchTerm(token.LBRACK)
+	p.Scan()
+	p.SkipNewlines()
+	index := p.expectNestedExpr()
+	p.SkipNewlines()
+	p.MatchTerm(token.RBRACK)
+	p.Scan()
+
+	return newExpr(ast.ExprIndex, ast.IndexExpr{
+		PosRange: p.rangeFrom(begin),
+		Expr:     operand,
+		Index:    index,
+	})
+}
+
+// tryTypeArgs parses type arguments, reporting whether the brackets held types only.
+func (p *Parser) tryTypeArgs() ([]ast.Type, bool) {
+	reported := len(p.Diagnosis)
+
+	p.Scan()
+	var args []ast.Type
+	for {
+		p.SkipNewlines()
+		if !IsTypeBegin(p.Token.Kind) {
+			return nil, false
+		}
+		args = append(args, p.ExpectType())
+
+		p.SkipNewlines()
+		if p.Token.Kind != token.COMMA {
+			break
+		}
+		p.Scan()
+	}
+	if p.Token.Kind != token.RBRACK || len(p.Diagnosis) != reported {
+		return nil, false
+	}
+	p.Scan()
+
+	return args, true
+}
+
+// instantiates reports whether type arguments cannot be parsed as an index. A name, or a pointer to one,
+// may be the index `x[i]` or `x[*p]`.
+func instantiates(args []ast.Type) bool {
+	if len(args) != 1 {
+		return true
+	}
+	arg := args[0]
+	for arg.Tag == ast.TypePointer {
+		arg = arg.Value.(ast.PointerType).Elem
+	}
+	return arg.Tag != ast.TypeIdent
+}
+
+// ExpectIntrinsicExpr parses `@namespace.name(params)`.
+func (p *Parser) ExpectIntrinsicExpr() ast.IntrinsicExpr {
+	begin := p.Token.From
+
+	p.MatchTerm(token.AT)
+	p.Scan()
+	expr := ast.IntrinsicExpr{Namespace: p.ExpectIdent()}
+	p.MatchTerm(token.MEMBER_SELECT)
+	p.Scan()
+	expr.Name = p.ExpectIdent()
+
+	p.MatchTerm(token.LPAREN)
+	p.Scan()
+	for {
+		p.SkipNewlines()
+		if p.Token.Kind == token.RPAREN || p.ReachedEOF {
+			break
+		}
+		expr.Params = append(expr.Params, p.expectNestedExpr())
+
+		p.SkipNewlines()
+		if p.Token.Kind != token.COMMA {
+			break
+		}
+		p.Scan()
+	}
+	p.MatchTerm(token.RPAREN)
+	p.Scan()
+
+	expr.PosRange = p.rangeFrom(begin)
+	return expr
+}
+
+// ExpectInterpolatedString parses `"text${expr}text"` from its segments, the embedded expressions are parsed
+// from the tokens between them.
+func (p *Parser) ExpectInterpolatedString() ast.InterpolatedString {
+	begin := p.Token.From
 
+	p.MatchTerm(token.STRING_HEAD)
+	str := ast.InterpolatedString{Lits: []ast.LiteralValue{segmentLit(p.Token)}}
+	p.Scan()
+	for {
+		str.Exprs = append(str.Exprs, p.expectNestedExpr())
+		if p.Token.Kind != token.STRING_MID {
+			break
+		}
+		str.Lits = append(str.Lits, segmentLit(p.Token))
+		p.Scan()
+	}
+	p.MatchTerm(token.STRING_TAIL)
+	if p.Token.Kind == token.STRING_TAIL {
+		str.Lits = append(str.Lits, segmentLit(p.Token))
+	}
+	p.Scan()
+
+	str.PosRange = p.rangeFrom(begin)
+	return str
 }
 
-func (p *Parser) ExpectAssignStmt() ast.AssignStmt {}
+// segmentLit quotes the text of a string segment, between its `"` or `}` and its `${` or `"`.
+func segmentLit(tok ast.Token) ast.LiteralValue {
+	text := tok.Literal[1:]
+	if tok.Kind == token.STRING_TAIL {
+		text = text[:len(text)-1]
+	} else {
+		text = text[:len(text)-2]
+	}
+	return ast.LiteralValue{Token: ast.Token{PosRange: tok.PosRange, Kind: token.STRING, Literal: `"` + text + `"`}}
+}
 
-func (p *Parser) ExpectStmtBlock() ast.StmtBlockExpr {}
+// ExpectStringLit parses a string literal, adjacent literals are concatenated into one: `"a" "b"` is `"ab"`.
+// A single raw string keeps its kind, a concatenation is an interpreted string.
+func (p *Parser) ExpectStringLit() ast.LiteralValue {
+	begin := p.Token.From
 
+	if !token.IsString(p.Token.Kind) {
+		p.MatchTerm(token.STRING)
+	}
+	lit := ast.LiteralValue{Token: p.Token}
+	if !token.IsString(p.Peek(1).Kind) {
+		p.Scan()
+		return lit
+	}
+	var text strings.Builder
+	for token.IsString(p.Token.Kind) {
+		s, err := token.Unquote(p.Token.Literal)
+		if err != nil {
+			p.Report(diagnosis.Diagnosis{
+				Kind:  diagnosis.InvalidLiteral,
+				Error: diagnosis.InvalidLiteralError{Literal: ast.LiteralValue{Token: p.Token}},
+			})
+		}
+		text.WriteString(s)
+		p.Scan()
+	}
+
+	lit.PosRange = p.rangeFrom(begin)
+	lit.Kind = token.STRING
+	lit.Literal = token.Quote(text.String())
+	return lit
+}
+
+// ExpectCallExpr parses a call `callee(params)`, the callee being an operand with its postfix operations.
+func (p *Parser) ExpectCallExpr() ast.CallExpr {
+	begin := p.Token.From
+	callee := p.expectPostfixExpr(begin, p.expectOperand())
+	if call, ok := callee.Value.(ast.CallExpr); ok {
+		return call
+	}
+	return p.expectCall(begin, callee)
+}
+
+// expectCall parses the parenthesized arguments of a call to the operand parsed so far, which starts at begin.
+func (p *Parser) expectCall(begin token.Pos, callee ast.Expr) ast.CallExpr {
+	p.MatchTerm(token.LPAREN)
+	p.Scan()
+	var params []ast.Expr
+	for {
+		p.SkipNewlines()
+		if p.Token.Kind == token.RPAREN || p.ReachedEOF {
+			break
+		}
+		params = append(params, p.expectNestedExpr())
+
+		p.SkipNewlines()
+		if p.Token.Kind != token.COMMA {
+			break
+		}
+		p.Scan()
+	}
+	p.MatchTerm(token.RPAREN)
+	p.Scan()
+
+	return ast.CallExpr{
+		PosRange: p.rangeFrom(begin),
+		Callee:   callee,
+		Params:   params,
+	}
+}
+
+// ExpectExpr parses an expression: binary operators by precedence, see token.Precedences, over operands with
+// their prefix and postfix operations.
 func (p *Parser) ExpectExpr() ast.Expr {
+	return p.expectBinaryExpr(1)
+}
+
+// expectBinaryExpr parses the left-associative operators binding with prec or tighter. A line break may follow
+// an operator, the operand continues on the next line.
+func (p *Parser) expectBinaryExpr(prec int) ast.Expr {
+	begin := p.Token.From
+	x := p.expectUnaryExpr()
+	for {
+		op := p.Token
+		opPrec := token.Precedences[op.Kind]
+		if opPrec < prec || opPrec == 0 {
+			return x
+		}
+		p.Scan()
+		p.SkipNewlines()
+		y := p.expectBinaryExpr(opPrec + 1)
+		x = newExpr(ast.ExprBinary, ast.BinaryExpr{
+			PosRange: p.rangeFrom(begin),
+			Operator: op,
+			Exprs:    [2]ast.Expr{x, y},
+		})
+	}
+}
+
+// expectUnaryExpr parses an operand with its postfix operations, or a prefix operator applied to one. Prefix
+// operators bind looser than postfix ones: `-f()` negates the call.
+func (p *Parser) expectUnaryExpr() ast.Expr {
+	begin := p.Token.From
+	switch {
+	case p.Token.Kind == token.ARROW && p.Peek(1).Kind == token.CHAN:
+		return p.expectPostfixExpr(begin, newExpr(ast.ExprType, p.ExpectTypeExpr()))
+	case token.PrefixUnaryOperators[p.Token.Kind]:
+		return newExpr(ast.ExprUnary, p.ExpectUnaryExpr())
+	case p.Token.Kind == token.TRY:
+		return newExpr(ast.ExprTry, p.ExpectTryExpr())
+	}
+	return p.expectPostfixExpr(begin, p.expectOperand())
+}
+
+// expectOperand parses an identifier, a literal, a parenthesized expression, a block, a branch or a match,
+// a function literal, an intrinsic call, or a map, chan or array type.
+func (p *Parser) expectOperand() ast.Expr {
+	switch p.Token.Kind {
+	case token.IDENT:
+		return newExpr(ast.ExprIdent, p.ExpectIdent())
+	case token.INT, token.FLOAT, token.IMAG, token.CHAR:
+		lit := ast.LiteralValue{Token: p.Token}
+		p.Scan()
+		return newExpr(ast.ExprLiteralValue, lit)
+	case token.STRING, token.STRING_RAW:
+		return newExpr(ast.ExprLiteralValue, p.ExpectStringLit())
+	case token.STRING_HEAD:
+		return newExpr(ast.ExprInterpolatedString, p.ExpectInterpolatedString())
+	case token.LPAREN:
+		p.Scan()
+		x := p.expectNestedExpr()
+		p.MatchTerm(token.RPAREN)
+		p.Scan()
+		return x
+	case token.LBRACE:
+		return newExpr(ast.ExprStmtBlock, p.ExpectStmtBlock())
+	case token.IF:
+		return newExpr(ast.ExprBranch, p.ExpectBranchExpr())
+	case token.MATCH:
+		return newExpr(ast.ExprMatch, p.ExpectMatchExpr())
+	case token.FUNC:
+		return newExpr(ast.ExprFuncLit, p.ExpectFuncLitExpr())
+	case token.AT:
+		return newExpr(ast.ExprIntrinsic, p.ExpectIntrinsicExpr())
+	case token.MAP, token.CHAN, token.LBRACK:
+		return newExpr(ast.ExprType, p.ExpectTypeExpr())
+	case token.RPAREN, token.RBRACK, token.RBRACE, token.COMMA, token.SEMICOLON, token.NEWLINE, token.EOF:
+		// The expression is missing after the last token, what follows is left to the enclosing rule.
+		p.MatchTerm(token.IDENT)
+		return newExpr(ast.ExprBad, ast.BadExpr{PosRange: ast.PosRange{From: p.end, To: p.end}})
+	default:
+		return newExpr(ast.ExprBad, p.ExpectBadExpr())
+	}
+}
+
+// expectPostfixExpr parses the calls, selections, indexes, instantiations, composite literals and postfix
+// operators following the operand parsed so far, which starts at begin: a parenthesized operand starts at its
+// parenthesis.
+func (p *Parser) expectPostfixExpr(begin token.Pos, x ast.Expr) ast.Expr {
+	for {
+		switch p.Token.Kind {
+		case token.LPAREN:
+			x = newExpr(ast.ExprCall, p.expectCall(begin, x))
+		case token.MEMBER_SELECT:
+			p.Scan()
+			member := p.ExpectIdent()
+			x = newExpr(ast.ExprMemberSelect, ast.MemberSelectExpr{
+				PosRange: p.rangeFrom(begin),
+				Member:   member,
+				Expr:     x,
+			})
+		case token.INC, token.DEC:
+			op := p.Token
+			p.Scan()
+			x = newExpr(ast.ExprUnary, ast.UnaryExpr{
+				PosRange: p.rangeFrom(begin),
+				Operator: op,
+				Expr:     x,
+			})
+		case token.ELLIPSIS:
+			p.Scan()
+			x = newExpr(ast.ExprEllipsis, ast.EllipsisExpr{
+				PosRange: p.rangeFrom(begin),
+				Array:    x,
+			})
+		case token.LBRACK:
+			x = p.expectIndexOrInstantiation(begin, x)
+		case token.QUESTION:
+			x = p.expectPostfixTry(begin, x)
+		case token.LBRACE:
+			if _, ok := p.compositeLitType(x); !ok {
+				return x
+			}
+			x = p.ExpectPostfixCompositeLit(x)
+		default:
+			return x
+		}
+	}
 }
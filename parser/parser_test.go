@@ -7,29 +7,13 @@ package parser
 import (
 	"cee/ast"
 	"cee/diagnosis"
-	"cee/scanner"
 	"cee/token"
 	"runtime/debug"
 	"testing"
 )
 
 func newParser(src string) Parser {
-	p := Parser{
-		Scanner: Scanner{
-			Scanner: scanner.Scanner{
-				Delimiters: token.Delimiters,
-				BufferScanner: scanner.BufferScanner{
-					Buffer: []rune(src)}}}}
-	p.Setup()
-	return p
-}
-
-func catch() {
-	switch v := recover().(type) {
-	case nil:
-	case UnexpectedNodeError:
-		println(v.Error())
-	}
+	return NewParser([]rune(src))
 }
 
 func assert(t *testing.T, msg string, cond bool) {
@@ -52,6 +36,152 @@ struct {
 	assert(t, "field gen decls number incorrect", len(typ.Fields) == 3)
 }
 
+func TestParser_ExpectTraitType(t *testing.T) {
+	p := newParser(`
+trait {
+	Closer
+	Read(buf Bytes, n i64) (i64, Error)
+	Reset(); Len() i64
+}
+`)
+	p.Scan()
+	typ := p.ExpectTraitType()
+	assert(t, "unexpected diagnosis", len(p.Diagnosis) == 0)
+	assert(t, "embeds incorrect", len(typ.Embeds) == 1 && typ.Embeds[0].Literal == "Closer")
+	assert(t, "methods incorrect", len(typ.Methods) == 3 && typ.Methods[0].Ident.Literal == "Read")
+	assert(t, "signature incorrect", len(typ.Methods[0].Type.Params) == 2 && len(typ.Methods[0].Type.Results) == 2)
+}
+
+func TestParser_ExpectMatchExpr(t *testing.T) {
+	p := newParser(`
+match v {
+case 0: a()
+case -1: b()
+case Point{x, y: 0} if x > 1: c()
+case n: d(n)
+}
+`)
+	p.Scan()
+	p.SkipNewlines()
+	expr := p.ExpectMatchExpr()
+	assert(t, "unexpected diagnosis", len(p.Diagnosis) == 0)
+	assert(t, "arms incorrect", len(expr.Arms) == 4)
+	lit, ok := expr.Arms[1].Pattern.Refutable()
+	assert(t, "literal pattern incorrect", ok && lit.Negative)
+	assert(t, "guard incorrect", expr.Arms[2].Guard.Value != nil && expr.Arms[0].Guard.Value == nil)
+	assert(t, "binding incorrect", len(expr.Arms[3].Pattern.Idents()) == 1)
+}
+
+func TestParser_ExpectMapChanType(t *testing.T) {
+	p := newParser(`map[string]<-chan chan<- i64`)
+	p.Scan()
+	typ := p.ExpectType()
+	assert(t, "unexpected diagnosis", len(p.Diagnosis) == 0)
+	assert(t, "map type incorrect", typ.Tag == ast.TypeMap)
+	recv := typ.Value.(ast.MapType).Value.Value.(ast.ChanType)
+	assert(t, "receive direction incorrect", recv.Dir == ast.ChanRecv)
+	assert(t, "send direction incorrect", recv.Elem.Value.(ast.ChanType).Dir == ast.ChanSend)
+	assert(t, "type string incorrect", ast.TypeString(typ) == "map[string]<-chan chan<- i64")
+}
+
+func TestParser_ExpectPointerType(t *testing.T) {
+	p := newParser(`**map[string]*Node`)
+	p.Scan()
+	typ := p.ExpectType()
+	assert(t, "unexpected diagnosis", len(p.Diagnosis) == 0)
+	assert(t, "pointer type incorrect", typ.Tag == ast.TypePointer)
+	assert(t, "type string incorrect", ast.TypeString(typ) == "**map[string]*Node")
+}
+
+func TestParser_ExpectCompositeLit(t *testing.T) {
+	p := newParser(`[2]Point{
+	{x: 1, y: 2},
+	{3, 4},
+}`)
+	p.Scan()
+	lit := p.ExpectCompositeLit(p.ExpectType())
+	assert(t, "unexpected diagnosis", len(p.Diagnosis) == 0)
+	assert(t, "type incorrect", lit.Type.Tag == ast.TypeArray)
+	assert(t, "elements incorrect", len(lit.Elems) == 2)
+	keyed := lit.Elems[0].Value.Value.(ast.CompositeLit)
+	assert(t, "nested literal incorrect", keyed.Type.Value == nil && len(keyed.Elems) == 2)
+	assert(t, "key incorrect", keyed.Elems[0].Key.Value.(ast.Ident).Literal == "x")
+	positional := lit.Elems[1].Value.Value.(ast.CompositeLit)
+	assert(t, "positional element incorrect", positional.Elems[0].Key.Value == nil)
+}
+
+func TestParser_ExpectPostfixCompositeLit(t *testing.T) {
+	p := newParser(`f(Point{x: 1}, []int{1, 2}, map[string]i64{})`)
+	p.Scan()
+	call := p.ExpectCallExpr()
+	assert(t, "unexpected diagnosis", len(p.Diagnosis) == 0)
+	for _, param := range call.Params {
+		assert(t, "composite literal expected", param.Tag == ast.ExprCompositeLit)
+	}
+	assert(t, "keyed element incorrect", len(call.Params[0].Value.(ast.CompositeLit).Elems) == 1)
+
+	p = newParser(`{
+	if v == (Point{}) { x = Point{y: 2} }
+	for x in list { f() }
+}`)
+	p.Scan()
+	block := p.ExpectStmtBlock()
+	assert(t, "unexpected diagnosis", len(p.Diagnosis) == 0)
+	branch := block.Stmts[0].Value.(ast.Expr).Value.(ast.BranchExpr)
+	cond := branch.Cond.Value.(ast.BinaryExpr)
+	assert(t, "parenthesized literal expected", cond.Exprs[1].Tag == ast.ExprCompositeLit)
+	assert(t, "literal in body expected", branch.Branch.Stmts[0].Tag == ast.StmtAssign)
+	foreach := block.Stmts[1].Value.(ast.ForeachStmt)
+	assert(t, "brace after header must open the body", foreach.Expr.Tag == ast.ExprIdent && len(foreach.Stmt.Stmts) == 1)
+}
+
+func TestParser_ExpectFuncLitExpr(t *testing.T) {
+	p := newParser(`fun [n, &total](x i64) i64 {
+	return x + n
+}`)
+	p.Scan()
+	lit := p.ExpectFuncLitExpr()
+	assert(t, "unexpected diagnosis", len(p.Diagnosis) == 0)
+	assert(t, "captures incorrect", len(lit.Captures) == 2 && lit.Captures[1].ByRef)
+	assert(t, "signature incorrect", len(lit.Type.Params) == 1 && len(lit.Type.Results) == 1)
+	assert(t, "body incorrect", len(lit.Body.Stmts) == 1)
+}
+
+func TestParser_ExpectCaptures(t *testing.T) {
+	p := newParser(`[]`)
+	p.Scan()
+	assert(t, "empty capture list expected", len(p.ExpectCaptures()) == 0 && len(p.Diagnosis) == 0)
+
+	p = newParser(`[a,
+	&b,
+] x`)
+	p.Scan()
+	captures := p.ExpectCaptures()
+	assert(t, "unexpected diagnosis", len(p.Diagnosis) == 0)
+	assert(t, "captures incorrect", len(captures) == 2 && captures[0].Ident.Literal == "a" && !captures[0].ByRef &&
+		captures[1].Ident.Literal == "b" && captures[1].ByRef)
+	assert(t, "list must be consumed", p.Token.Literal == "x")
+
+	p = newParser(`[a b]`)
+	p.Scan()
+	p.ExpectCaptures()
+	assert(t, "missing comma must be reported", len(p.Diagnosis) != 0)
+
+	p = newParser(`fun [&n]() { n = 1 }`)
+	p.Scan()
+	decl := p.ExpectFuncDecl()
+	assert(t, "closure declaration incorrect", decl.Ident == nil && len(decl.Captures) == 1 && decl.Captures[0].ByRef && decl.Stmt != nil)
+}
+
+func TestParser_ExpectBadDecl(t *testing.T) {
+	p := newParser(`123 val x = 1`)
+	p.Scan()
+	decl := p.ExpectDecl()
+	assert(t, "bad declaration expected", decl.Tag == ast.StmtBadDecl && len(p.Diagnosis) == 1)
+	bad := decl.Value.(ast.BadDecl)
+	assert(t, "skipped range incorrect", bad.To == p.Token.From && p.Token.Kind == token.VAL)
+}
+
 func TestParser_ExpectGenDecl(t *testing.T) {
 	p := newParser(`
 ident, aa struct {
@@ -62,27 +192,32 @@ ident, aa struct {
 	fieldB int
 }
 `)
-	func() {
-		defer func() {
-			switch v := recover().(type) {
-			case UnexpectedNodeError:
-				println(v.Error())
-				diagnosis.Print(&p.BufferScanner, v.Node)
-			case nil:
-				return
-			default:
-				panic(v)
-			}
-		}()
-
-		p.Scan()
-		genDecl := p.ExpectGenDecl()
-
-		assert(t, "idents are incorrect", len(genDecl.Idents) == 2)
-		assert(t, "ident name incorrect", genDecl.Idents[0].Literal == "ident")
-		assert(t, "type name incorrect", len(genDecl.Type.(ast.StructType).Fields) == 3)
-		assert(t, "nested fields are incorrect", len(genDecl.Type.(ast.StructType).Fields[1].Type.(ast.StructType).Fields) == 1)
-	}()
+	p.Scan()
+	p.SkipNewlines()
+	genDecl := p.ExpectGenDecl()
+
+	assert(t, "unexpected diagnosis", len(p.Diagnosis) == 0)
+	assert(t, "idents are incorrect", len(genDecl.Idents) == 2)
+	assert(t, "ident name incorrect", genDecl.Idents[0].Literal == "ident")
+	fields := genDecl.Type.Value.(ast.StructType).Fields
+	assert(t, "type name incorrect", len(fields) == 3)
+	assert(t, "nested fields are incorrect", len(fields[1].Type.Value.(ast.StructType).Fields) == 1)
+}
+
+func TestParser_ExpectValDecl(t *testing.T) {
+	p := newParser(`var x u8 = 300`)
+	p.Scan()
+	decl := p.ExpectValDecl()
+
+	assert(t, "unexpected diagnosis", len(p.Diagnosis) == 0)
+	assert(t, "name incorrect", decl.Name.Literal == "x" && decl.Mutable)
+	assert(t, "type incorrect", decl.Type.Tag == ast.TypeU8)
+	assert(t, "value incorrect", decl.Value.Tag == ast.ExprLiteralValue)
+
+	p = newParser(`val y = 1`)
+	p.Scan()
+	decl = p.ExpectValDecl()
+	assert(t, "inferred declaration has a type", decl.Type.Value == nil)
 }
 
 func TestParser_ExpectFuncType(t *testing.T) {
@@ -90,25 +225,44 @@ func TestParser_ExpectFuncType(t *testing.T) {
 (paramA, paramB int, paramC int) (int, int, struct {})
 `)
 	p.Scan()
+	p.SkipNewlines()
 	typ := p.ExpectFuncType()
 	assert(t, "params are incorrect", len(typ.Params) == 2)
 	assert(t, "results are incorrect", len(typ.Results) == 3)
 }
 
+func TestParser_ExpectTypeParams(t *testing.T) {
+	p := newParser(`fun Map[T, U Ord, V](xs List[T], f fun(T) U) List[U] {}`)
+	p.Scan()
+	decl := p.ExpectFuncDecl()
+	assert(t, "unexpected diagnosis", len(p.Diagnosis) == 0)
+	assert(t, "type params are incorrect", len(decl.TypeParams) == 2 && len(decl.TypeParams[0].Idents) == 2)
+	assert(t, "constraint is incorrect", ast.TypeString(decl.TypeParams[0].Constraint) == "Ord")
+	assert(t, "unconstrained param is incorrect", decl.TypeParams[1].Constraint.Value == nil)
+	assert(t, "instantiated type is incorrect", ast.TypeString(decl.Type.Params[0].Type) == "List[T]")
+
+	p = newParser(`fun Id[T](x T) T { return x }`)
+	p.Options.Edition = "2024"
+	p.Scan()
+	p.ExpectFuncDecl()
+	assert(t, "type params allowed before edition 2025", len(p.Diagnosis) == 1 && p.Diagnosis[0].Kind == diagnosis.EditionRequired)
+}
+
 func TestParser_ExpectFuncDecl(t *testing.T) {
 	p := newParser(`
 fun Idents(paramA, paramB int, paramC string) (int, int, string) {
 	return 0, 0, paramC
 }
 `)
-	defer catch()
-
 	p.Scan()
+	p.SkipNewlines()
 	funcDecl := p.ExpectFuncDecl()
 	typ := funcDecl.Type
+	assert(t, "unexpected diagnosis", len(p.Diagnosis) == 0)
 	assert(t, "function name incorrect", funcDecl.Ident.Literal == "Idents")
 	assert(t, "paramB incorrect", typ.Params[0].Idents[1].Literal == "paramB")
-	assert(t, "3rd result incorrect", typ.Results[2].(ast.TypeAlias).Literal == "string")
+	assert(t, "3rd result incorrect", typ.Results[2].Value.(ast.TypeAlias).Literal == "string")
+	assert(t, "body incorrect", len(funcDecl.Stmt.Stmts) == 1)
 }
 
 func TestParser_ExpectLeftAssociativeExpr(t *testing.T) {
@@ -116,8 +270,9 @@ func TestParser_ExpectLeftAssociativeExpr(t *testing.T) {
 base.A.B + 1
 `)
 	p.Scan()
-	expr := p.ExpectLeftAssociativeExpr()
-	assert(t, "member incorrect", expr.(ast.MemberSelectExpr).Member.Literal == "B")
+	p.SkipNewlines()
+	expr := p.ExpectExpr().Value.(ast.BinaryExpr).Exprs[0]
+	assert(t, "member incorrect", expr.Value.(ast.MemberSelectExpr).Member.Literal == "B")
 }
 
 func TestParser_ExpectExpr(t *testing.T) {
@@ -125,8 +280,10 @@ func TestParser_ExpectExpr(t *testing.T) {
 identA * identC + identB * identC * (identA + identB)
 `)
 	p.Scan()
+	p.SkipNewlines()
 	expr := p.ExpectExpr()
-	println(expr.(ast.BinaryExpr).Operator.Literal)
+	assert(t, "unexpected diagnosis", len(p.Diagnosis) == 0)
+	assert(t, "operator incorrect", expr.Value.(ast.BinaryExpr).Operator.Literal == "+")
 }
 
 func Test_ExpectBinaryExpr(t *testing.T) {
@@ -134,7 +291,86 @@ func Test_ExpectBinaryExpr(t *testing.T) {
 a + a * b * c
 `)
 	p.Scan()
+	p.SkipNewlines()
 	expr := p.ExpectExpr()
+	product := expr.Value.(ast.BinaryExpr).Exprs[1].Value.(ast.BinaryExpr)
+	assert(t, "left associativity incorrect", product.Exprs[0].Tag == ast.ExprBinary)
+	assert(t, "terminator incorrect", p.Token.Kind == token.NEWLINE)
+}
+
+func TestParser_NewlinesInsideParens(t *testing.T) {
+	p := NewParser([]rune("f(\n\ta,\n\tb[\n\t\t0\n\t],\n\tfun() {\n\t\tg()\n\t}\n)\n"))
+	var kinds []int
+	for p.Scan(); !p.ReachedEOF; p.Scan() {
+		kinds = append(kinds, p.Token.Kind)
+	}
+	newlines := 0
+	for _, kind := range kinds {
+		if kind == token.NEWLINE {
+			newlines++
+		}
+	}
+	// The lines of the block inside the call keep their terminators, as does the line of the call.
+	assert(t, "newlines incorrect", newlines == 3)
+	assert(t, "last token incorrect", kinds[len(kinds)-1] == token.NEWLINE && kinds[len(kinds)-2] == token.RPAREN)
+}
+
+func TestParser_ExpectMultilineFuncType(t *testing.T) {
+	p := NewParser([]rune(`(
+	paramA, paramB int,
+	paramC int,
+) (
+	int,
+	int,
+)
+`))
+	p.Scan()
+	typ := p.ExpectFuncType()
+	assert(t, "params are incorrect", len(typ.Params) == 2)
+	assert(t, "results are incorrect", len(typ.Results) == 2)
+	assert(t, "terminator incorrect", p.Token.Kind == token.NEWLINE)
+	assert(t, "unexpected diagnosis", len(p.Diagnosis) == 0)
+}
+
+func TestParser_ExpectStmtBlock(t *testing.T) {
+	p := NewParser([]rune(`{
+	outer: for i, v in list {
+		switch v {
+		case 0, 1:
+			continue outer
+		case 2:
+			fallthrough
+		default:
+			break
+		}
+	}
+	if a { go f() } else if b { goto outer } else { x += 1 }
+	select {
+	case val v = recv():
+		return v
+	case ch <- 1:
+	}
+}
+`))
+	p.Scan()
+	block := p.ExpectStmtBlock()
+	assert(t, "unexpected diagnosis", len(p.Diagnosis) == 0)
+	assert(t, "statements are incorrect", len(block.Stmts) == 3)
+
+	labeled := block.Stmts[0].Value.(ast.LabeledStmt)
+	foreach := labeled.Stmt.Value.(ast.ForeachStmt)
+	assert(t, "label incorrect", labeled.Label.Literal == "outer")
+	assert(t, "bindings incorrect", len(foreach.IdentList) == 2)
+	switchStmt := foreach.Stmt.Stmts[0].Value.(ast.SwitchStmt)
+	assert(t, "cases incorrect", len(switchStmt.Cases) == 3 && switchStmt.Cases[2].Default)
+	assert(t, "case exprs incorrect", len(switchStmt.Cases[0].Exprs) == 2)
+
+	branch := block.Stmts[1].Value.(ast.Expr).Value.(ast.BranchExpr)
+	elseIf := branch.ElseBranch.Stmts[0].Value.(ast.Expr).Value.(ast.BranchExpr)
+	assert(t, "else branch incorrect", elseIf.ElseBranch.Stmts[0].Tag == ast.StmtAssign)
 
-	println(expr.GetPosRange().To.String())
+	selectStmt := block.Stmts[2].Value.(ast.SelectStmt)
+	assert(t, "comm incorrect", selectStmt.Cases[0].Comm.Tag == ast.StmtValDecl)
+	send, ok := selectStmt.Cases[1].Comm.Value.(ast.SendStmt)
+	assert(t, "send incorrect", ok && send.Chan.Tag == ast.ExprIdent && send.Value.Tag == ast.ExprLiteralValue)
 }
@@ -0,0 +1,147 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package parser
+
+import (
+	"cee"
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/token"
+)
+
+func newPattern(kind ast.PatternKind, value ast.Node) ast.Pattern {
+	return ast.Pattern{Union: cee.Union[ast.PatternKind]{Tag: kind, Value: value}}
+}
+
+// ExpectPattern parses an identifier, `_`, a literal optionally negated, a tuple pattern `(p, ...)` or a struct
+// pattern `[T]{field[: p], ...}`. The grammar is shared by bindings and match arms, bindings may not
+// hold literals.
+func (p *Parser) ExpectPattern() ast.Pattern {
+	if token.IsLiteralValue(p.Token.Kind) || p.Token.Kind == token.SUB && isNumber(p.Peek(1).Kind) {
+		return newPattern(ast.PatternLiteral, p.ExpectLiteralPattern())
+	}
+
+	switch p.Token.Kind {
+	case token.IDENT:
+		ident := p.ExpectIdent()
+		if p.Token.Kind == token.LBRACE {
+			return newPattern(ast.PatternStruct, p.ExpectStructPattern(&ident))
+		}
+		return newPattern(ast.PatternIdent, ident)
+	case token.LPAREN:
+		return newPattern(ast.PatternTuple, p.ExpectTuplePattern())
+	case token.LBRACE:
+		return newPattern(ast.PatternStruct, p.ExpectStructPattern(nil))
+	default:
+		begin := p.Token
+		p.ReportAndRecover(diagnosis.Diagnosis{
+			Kind: diagnosis.UnexpectedNode,
+			Error: diagnosis.UnexpectedNodeError{
+				Have: p.Token,
+				Want: token.IDENT,
+			},
+		})
+		if p.Token == begin {
+			p.Scan()
+		}
+		return ast.Pattern{}
+	}
+}
+
+func isNumber(kind int) bool {
+	return kind == token.INT || kind == token.FLOAT || kind == token.IMAG
+}
+
+// ExpectLiteralPattern parses a literal, numbers may be negated by a leading `-`.
+func (p *Parser) ExpectLiteralPattern() ast.LiteralPattern {
+	begin := p.Token.From
+
+	negative := p.Token.Kind == token.SUB
+	if negative {
+		p.Scan()
+	}
+	if !token.IsLiteralValue(p.Token.Kind) {
+		p.MatchTerm(token.INT)
+	}
+	value := ast.LiteralValue{Token: p.Token}
+	p.Scan()
+
+	return ast.LiteralPattern{
+		PosRange: p.rangeFrom(begin),
+		Value:    value,
+		Negative: negative,
+	}
+}
+
+func (p *Parser) ExpectTuplePattern() ast.TuplePattern {
+	begin := p.Token.From
+
+	p.MatchTerm(token.LPAREN)
+	p.Scan()
+
+	var elems []ast.Pattern
+	for {
+		p.SkipNewlines()
+		if p.Token.Kind == token.RPAREN || p.ReachedEOF {
+			break
+		}
+		elems = append(elems, p.ExpectPattern())
+		p.SkipNewlines()
+		if p.Token.Kind != token.COMMA {
+			break
+		}
+		p.Scan()
+	}
+	p.MatchTerm(token.RPAREN)
+	p.Scan()
+
+	return ast.TuplePattern{
+		PosRange: p.rangeFrom(begin),
+		Elems:    elems,
+	}
+}
+
+// ExpectStructPattern parses the fields of a struct pattern, typ is the type name already parsed if any.
+func (p *Parser) ExpectStructPattern(typ *ast.Ident) ast.StructPattern {
+	begin := p.Token.From
+	if typ != nil {
+		begin = typ.From
+	}
+
+	p.MatchTerm(token.LBRACE)
+	p.Scan()
+
+	var fields []ast.FieldPattern
+	for {
+		p.SkipNewlines()
+		if p.Token.Kind == token.RBRACE || p.ReachedEOF {
+			break
+		}
+
+		fieldBegin := p.Token.From
+		field := ast.FieldPattern{Field: p.ExpectIdent()}
+		if p.Token.Kind == token.COLON {
+			p.Scan()
+			pattern := p.ExpectPattern()
+			field.Pattern = &pattern
+		}
+		field.PosRange = p.rangeFrom(fieldBegin)
+		fields = append(fields, field)
+
+		p.SkipNewlines()
+		if p.Token.Kind != token.COMMA {
+			break
+		}
+		p.Scan()
+	}
+	p.MatchTerm(token.RBRACE)
+	p.Scan()
+
+	return ast.StructPattern{
+		PosRange: p.rangeFrom(begin),
+		Type:     typ,
+		Fields:   fields,
+	}
+}
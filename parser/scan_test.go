@@ -0,0 +1,367 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package parser
+
+import (
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/token"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanNumber(t *testing.T) {
+	tests := []struct {
+		src  string
+		kind int
+		lit  string
+	}{
+		{"12345", token.INT, "12345"},
+		{"1_000", token.INT, "1_000"},
+		{"0xFF", token.INT, "0xFF"},
+		{"0b101", token.INT, "0b101"},
+		{"1.5", token.FLOAT, "1.5"},
+		{"1e9", token.FLOAT, "1e9"},
+		{"1.5e-3", token.FLOAT, "1.5e-3"},
+		{"0x1.8p3", token.FLOAT, "0x1.8p3"},
+		{"2i", token.IMAG, "2i"},
+		{"1.5e3i", token.IMAG, "1.5e3i"},
+		// A dot without digits selects a member, an exponent without digits is an identifier.
+		{"1.max", token.INT, "1"},
+		{"1e", token.INT, "1"},
+		{"2if", token.INT, "2"},
+	}
+	for _, test := range tests {
+		buffer := []rune(test.src)
+		p := NewFileParser(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+		kind, lit, ok := p.scanNumber()
+		if !ok || kind != test.kind || string(lit) != test.lit {
+			t.Errorf("scanNumber(%s) = %d %q, want %d %q", test.src, kind, string(lit), test.kind, test.lit)
+		}
+		if p.Position.Offset != len(lit) || len(p.Diagnosis) != 0 {
+			t.Errorf("scanNumber(%s) advanced to %d, %v", test.src, p.Position.Offset, p.Diagnosis)
+		}
+	}
+
+	buffer := []rune("x1")
+	p := NewFileParser(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+	if _, _, ok := p.scanNumber(); ok {
+		t.Errorf("scanNumber(x1) succeeded")
+	}
+}
+
+func TestScanNumberNoDigits(t *testing.T) {
+	for src, want := range map[string]string{
+		"0x":   "hexadecimal literal has no digits",
+		"0X_":  "hexadecimal literal has no digits",
+		"0xp1": "hexadecimal literal has no digits",
+		"0xi":  "hexadecimal literal has no digits",
+		"0b":   "binary literal has no digits",
+		"0o)":  "octal literal has no digits",
+	} {
+		buffer := []rune(src)
+		p := NewFileParser(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+		_, lit, ok := p.scanNumber()
+		if !ok || len(p.Diagnosis) != 1 || p.Diagnosis[0].Kind != diagnosis.InvalidLiteral {
+			t.Fatalf("scanNumber(%s) = %q, %v", src, string(lit), p.Diagnosis)
+		}
+		e := p.Diagnosis[0].Error.(diagnosis.LiteralDigitsError)
+		if e.Error() != want || e.Literal.Literal != string(lit) || int(e.Literal.To-e.Literal.From) != len(lit) {
+			t.Errorf("scanNumber(%s): %q at %v, want %q", src, e.Error(), e.Literal.PosRange, want)
+		}
+	}
+}
+
+func TestScanRawString(t *testing.T) {
+	buffer := []rune("`a\\n\nb` x")
+	p := NewFileParser(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+	lit, closed := p.scanRawString()
+	if !closed || string(lit) != "`a\\n\nb`" || p.Position.Line != 1 || p.Position.Column != 2 {
+		t.Errorf("scanRawString = %q, %v at %+v", string(lit), closed, p.Position)
+	}
+
+	buffer = []rune("`a")
+	p = NewFileParser(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+	if lit, closed := p.scanRawString(); closed || string(lit) != "`a" {
+		t.Errorf("unclosed scanRawString = %q, %v", string(lit), closed)
+	}
+}
+
+func TestIllegalQuoted(t *testing.T) {
+	for src, want := range map[string]string{
+		"\"abc def\nx": "\"abc def",
+		"'\\q' x":      "'\\q'",
+		"\"a\\\"b\" x": "\"a\\\"b\"",
+		"#!x y":        "#!x",
+	} {
+		buffer := []rune(src)
+		p := NewFileParser(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+		p.Options.Recover = true
+		kind, _, lit := p.illegal(p.Position, nil)
+		if kind != token.ILLEGAL || string(lit) != want || len(p.Diagnosis) != 1 {
+			t.Errorf("illegal(%q) = %d %q, want %q", src, kind, string(lit), want)
+		}
+	}
+}
+
+func TestScanOperatorRun(t *testing.T) {
+	for src, want := range map[string][]int{
+		"**p":    {token.MUL, token.MUL, token.IDENT},
+		"x=-1":   {token.IDENT, token.ASSIGN, token.SUB, token.INT},
+		"a<-b":   {token.IDENT, token.ARROW, token.IDENT},
+		"!*&^=x": {token.NOT, token.MUL, token.AND_NOT_ASSIGN, token.IDENT},
+	} {
+		buffer := []rune(src)
+		toks, err := ScanAll(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+		if err != nil || len(toks) != len(want) {
+			t.Errorf("ScanAll(%q) = %v, %v", src, toks, err)
+			continue
+		}
+		for i, tok := range toks {
+			if tok.Kind != want[i] {
+				t.Errorf("ScanAll(%q)[%d] = %d %q, want %d", src, i, tok.Kind, tok.Literal, want[i])
+			}
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	buffer := []rune("a 1 b 2")
+	p := NewFileParser(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+	p.Scan()
+	if tok := p.Peek(2); tok.Literal != "b" {
+		t.Errorf("Peek(2) = %q", tok.Literal)
+	}
+	if tok := p.Peek(9); tok.Kind != token.EOF {
+		t.Errorf("Peek(9) = %q", tok.Literal)
+	}
+
+	m := p.Mark()
+	p.Scan()
+	p.Scan()
+	p.Report(diagnosis.Diagnosis{Kind: diagnosis.UnexpectedNode})
+	p.Backtrack(m)
+	if p.Token.Literal != "a" || len(p.Diagnosis) != 0 {
+		t.Errorf("backtracked to %q with %d diagnostics", p.Token.Literal, len(p.Diagnosis))
+	}
+	if r := p.rangeFrom(p.Token.From); r.From != r.To {
+		t.Errorf("range after backtracking spans %q", p.File.Text(r.From, r.To))
+	}
+
+	m = p.Mark()
+	p.Scan()
+	p.Commit(m)
+	var lits string
+	for ; !p.ReachedEOF; p.Scan() {
+		lits += p.Token.Literal
+	}
+	if lits != "1b2" || len(p.stream) != 0 {
+		t.Errorf("scanned %q after commit, %d tokens buffered", lits, len(p.stream))
+	}
+}
+
+func TestScanInterpolatedString(t *testing.T) {
+	buffer := []rune(`"a\"${x}${y}\${z}"`)
+	p := NewFileParser(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+	var kinds []int
+	var lits []string
+	for {
+		kind, _, lit := p.scan()
+		if kind == token.EOF {
+			break
+		}
+		kinds = append(kinds, kind)
+		lits = append(lits, string(lit))
+	}
+	want := []string{`"a\"${`, "x", "}${", "y", `}\${z}"`}
+	if len(lits) != len(want) {
+		t.Fatalf("scanned %q", lits)
+	}
+	for i := range want {
+		if lits[i] != want[i] {
+			t.Errorf("segment %d = %q, want %q", i, lits[i], want[i])
+		}
+	}
+	if kinds[0] != token.STRING_HEAD || kinds[2] != token.STRING_MID || kinds[4] != token.STRING_TAIL {
+		t.Errorf("kinds %v", kinds)
+	}
+	if !p.QuoteStack.Empty() {
+		t.Errorf("interpolation left open")
+	}
+
+	buffer = []rune(`"${x}`)
+	p = NewFileParser(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+	p.Options.Edition = "2024"
+	if kind, _, _ := p.scan(); kind == token.STRING_HEAD {
+		t.Errorf("interpolated before edition 2025")
+	}
+}
+
+func TestScanQuotedString(t *testing.T) {
+	buffer := []rune("\"a\\\nb\" x")
+	p := NewFileParser(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+	p.Options.SingleLineStrings = true
+	kind, _, lit := p.scan()
+	if kind != token.STRING || string(lit) != "\"a\\\nb\"" || p.Position.Line != 1 || p.Position.Column != 2 {
+		t.Errorf("scan = %d %q at %+v", kind, string(lit), p.Position)
+	}
+
+	buffer = []rune("\"ab\nx")
+	p = NewFileParser(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+	p.Options.SingleLineStrings = true
+	kind, pos, lit := p.scan()
+	if kind != token.STRING || string(lit) != "\"ab\"" || len(p.Diagnosis) != 1 || p.Diagnosis[0].Kind != diagnosis.NonClosedQuote {
+		t.Fatalf("unclosed scan = %d %q, %v", kind, string(lit), p.Diagnosis)
+	}
+	if p.Position.Offset != 3 || p.Diagnosis[0].Error.(diagnosis.NonClosedQuoteError).At.PosRange != pos {
+		t.Errorf("unclosed string at %+v", pos)
+	}
+}
+
+func TestExpectStringLit(t *testing.T) {
+	buffer := []rune(`"a" "b\n" x`)
+	p := NewFileParser(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+	p.Options.SingleLineStrings = true
+	p.Scan()
+	if lit := p.ExpectStringLit(); lit.Literal != `"ab\n"` || p.Token.Literal != "x" {
+		t.Errorf("ExpectStringLit = %q before %q", lit.Literal, p.Token.Literal)
+	}
+
+	buffer = []rune("`a\\` x")
+	p = NewFileParser(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+	p.Scan()
+	if lit := p.ExpectStringLit(); lit.Kind != token.STRING_RAW || lit.Literal != "`a\\`" {
+		t.Errorf("raw ExpectStringLit = %d %q", lit.Kind, lit.Literal)
+	}
+
+	buffer = []rune("`a\\` \"b\" x")
+	p = NewFileParser(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+	p.Scan()
+	if lit := p.ExpectStringLit(); lit.Kind != token.STRING || lit.Literal != `"a\\b"` {
+		t.Errorf("concatenated raw ExpectStringLit = %d %q", lit.Kind, lit.Literal)
+	}
+}
+
+func TestScanSlash(t *testing.T) {
+	buffer := []rune("a / b /= 2 // hello\n/* x\ny */ c")
+	p := NewFileParser(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+	var lits []string
+	var kinds []int
+	for {
+		kind, _, lit := p.scan()
+		if kind == token.EOF {
+			break
+		}
+		kinds = append(kinds, kind)
+		lits = append(lits, string(lit))
+	}
+	want := []int{token.IDENT, token.QUO, token.IDENT, token.QUO_ASSIGN, token.INT, token.COMMENT, token.NEWLINE, token.COMMENT, token.IDENT}
+	if len(kinds) != len(want) {
+		t.Fatalf("scanned %q", lits)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Errorf("token %d %q of kind %d, want %d", i, lits[i], kinds[i], want[i])
+		}
+	}
+	if lits[5] != "// hello" || lits[7] != "/* x\ny */" || p.Position.Line != 2 {
+		t.Errorf("comments %q and %q, line %d", lits[5], lits[7], p.Position.Line)
+	}
+
+	buffer = []rune("/* x")
+	p = NewFileParser(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+	p.Options.Recover = true
+	if kind, _, _ := p.scan(); kind != token.ILLEGAL || len(p.Diagnosis) != 1 {
+		t.Errorf("unclosed comment scanned as %d, %v", kind, p.Diagnosis)
+	}
+}
+
+// kindsOf lists the kinds of toks.
+func kindsOf(toks []ast.Token) []int {
+	var kinds []int
+	for _, tok := range toks {
+		kinds = append(kinds, tok.Kind)
+	}
+	return kinds
+}
+
+func TestScanRecover(t *testing.T) {
+	for src, want := range map[string][]int{
+		"x = 1 + y /* c": {token.IDENT, token.ASSIGN, token.INT, token.ADD, token.IDENT, token.ILLEGAL},
+		"f(a) ` b":       {token.IDENT, token.LPAREN, token.IDENT, token.RPAREN, token.ILLEGAL},
+		"a +":            {token.IDENT, token.ADD},
+		"x <-":           {token.IDENT, token.ARROW},
+	} {
+		buffer := []rune(src)
+		toks, diags := ScanRecover(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+		illegal := 0
+		if kinds := kindsOf(toks); kinds[len(kinds)-1] == token.ILLEGAL {
+			illegal = 1
+		}
+		if !reflect.DeepEqual(kindsOf(toks), want) || len(diags) != illegal {
+			t.Errorf("ScanRecover(%q) = %v, %v, want %v", src, kindsOf(toks), diags, want)
+			continue
+		}
+		if illegal != 0 {
+			last := toks[len(toks)-1]
+			if e, ok := diags[0].Error.(diagnosis.IllegalTokenError); !ok || diags[0].Kind != diagnosis.IllegalToken || e.At.PosRange != last.PosRange {
+				t.Errorf("ScanRecover(%q): diagnosis %+v for %+v", src, diags[0], last)
+			}
+		}
+
+		// Without recovery, scanning stops at the rejected input.
+		toks, err := ScanAll(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+		if err != nil || len(toks) != len(want)-illegal {
+			t.Errorf("ScanAll(%q) = %v, %v", src, kindsOf(toks), err)
+		}
+	}
+
+	// Scanning stops once the error budget is spent, which is reported.
+	buffer := []rune(strings.Repeat("'a\n", 2*DefaultMaxErrors))
+	toks, diags := ScanRecover(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+	if len(diags) != DefaultMaxErrors+1 || diags[DefaultMaxErrors].Kind != diagnosis.TooManyErrors || len(toks) > DefaultMaxErrors {
+		t.Errorf("%d tokens and %d diagnostics past the budget", len(toks), len(diags))
+	}
+}
+
+func TestMismatchedDelimiters(t *testing.T) {
+	type report struct {
+		msg         string
+		close, open int // offsets, -1 for none
+	}
+	for src, want := range map[string][]report{
+		"f(a]":        {{"syntax error: mismatched closing delimiter: ], unclosed (", 3, 1}, {"syntax error: unclosed delimiter: (", 4, 1}},
+		"{ g( }":      {{"syntax error: mismatched closing delimiter: }, unclosed (", 5, 3}},
+		"a)":          {{"syntax error: unexpected closing delimiter: )", 1, -1}},
+		"x[1] {\n[2]": {{"syntax error: unclosed delimiter: {", 10, 5}},
+	} {
+		buffer := []rune(src)
+		p := NewFileParser(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+		for p.Scan(); !p.ReachedEOF; p.Scan() {
+		}
+
+		var got []report
+		for _, d := range p.Diagnosis {
+			e, ok := d.Error.(diagnosis.MismatchedDelimiterError)
+			if !ok || d.Kind != diagnosis.MismatchedDelimiter {
+				t.Errorf("%q: diagnosis %+v", src, d)
+				continue
+			}
+			r := report{msg: e.Error(), close: p.File.Offset(e.Close.From), open: -1}
+			if e.Open.Kind != 0 {
+				r.open = p.File.Offset(e.Open.From)
+			}
+			// Unclosed openers are reported at the opener, others at the closer.
+			if at := e.GetPosRange().From; e.Close.Kind == token.EOF && at != e.Open.From || e.Close.Kind != token.EOF && at != e.Close.From {
+				t.Errorf("%q: %s reported at %d", src, r.msg, p.File.Offset(at))
+			}
+			got = append(got, r)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: reported %+v, want %+v", src, got, want)
+		}
+	}
+}
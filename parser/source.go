@@ -0,0 +1,56 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package parser
+
+import (
+	"cee/ast"
+	"cee/diagnosis"
+	"unicode/utf8"
+)
+
+// BadRune is a byte of an invalid UTF-8 sequence or a NUL in a source, replaced by utf8.RuneError in the buffer.
+type BadRune struct {
+	Offset int // in runes
+	Byte   byte
+}
+
+// Decode converts a source into the buffer parsers scan. Every byte of an invalid UTF-8 sequence and every NUL
+// takes a single utf8.RuneError, as []rune(string(src)) does, so positions are the same either way.
+func Decode(src []byte) ([]rune, []BadRune) {
+	var (
+		buffer = make([]rune, 0, len(src))
+		bad    []BadRune
+	)
+	for len(src) > 0 {
+		r, size := utf8.DecodeRune(src)
+		if r == utf8.RuneError && size == 1 || r == 0 {
+			bad = append(bad, BadRune{Offset: len(buffer), Byte: src[0]})
+			r = utf8.RuneError
+		}
+		buffer = append(buffer, r)
+		src = src[size:]
+	}
+	return buffer, bad
+}
+
+// ReportBadRunes reports the bad runes Decode returned for the buffer of the parser.
+// Adjacent bad runes of the same kind are reported together.
+func (p *Parser) ReportBadRunes(bad []BadRune) {
+	for i := 0; i < len(bad); {
+		j := i + 1
+		for j < len(bad) && bad[j].Offset == bad[j-1].Offset+1 && (bad[j].Byte == 0) == (bad[i].Byte == 0) {
+			j++
+		}
+
+		e := diagnosis.InvalidEncodingError{
+			PosRange: ast.PosRange{From: p.File.Pos(bad[i].Offset), To: p.File.Pos(bad[j-1].Offset + 1)},
+		}
+		for _, b := range bad[i:j] {
+			e.Bytes = append(e.Bytes, b.Byte)
+		}
+		p.Report(diagnosis.Diagnosis{Kind: diagnosis.InvalidEncoding, Error: e})
+		i = j
+	}
+}
@@ -0,0 +1,445 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package parser
+
+import (
+	"cee/ast"
+	"cee/token"
+)
+
+// skipSeparators skips the line breaks and semicolons between statements.
+func (p *Parser) skipSeparators() {
+	for p.Token.Kind == token.NEWLINE || p.Token.Kind == token.SEMICOLON {
+		p.Scan()
+	}
+}
+
+// ExpectStmtBlock parses `{ stmts }`.
+func (p *Parser) ExpectStmtBlock() ast.StmtBlockExpr {
+	begin := p.Token.From
+
+	p.MatchTerm(token.LBRACE)
+	p.Scan()
+	outer := p.exprLev
+	p.exprLev = 0
+	stmts := p.expectStmts(false)
+	p.exprLev = outer
+	p.MatchTerm(token.RBRACE)
+	p.Scan()
+
+	return ast.StmtBlockExpr{
+		PosRange: p.rangeFrom(begin),
+		Stmts:    stmts,
+	}
+}
+
+// expectStmts parses the statements of a block up to its closing brace, or those of a case clause up to
+// the next clause. Statements are terminated by a line break or a semicolon, the last one of a block may not be.
+func (p *Parser) expectStmts(clause bool) []ast.Stmt {
+	var stmts []ast.Stmt
+	for {
+		p.skipSeparators()
+		switch p.Token.Kind {
+		case token.RBRACE, token.EOF:
+			return stmts
+		case token.CASE, token.DEFAULT:
+			if clause {
+				return stmts
+			}
+		}
+
+		if stmt := p.ExpectStmt(); stmt.Tag != 0 {
+			stmts = append(stmts, stmt)
+		}
+
+		switch p.Token.Kind {
+		case token.NEWLINE, token.SEMICOLON, token.RBRACE, token.EOF:
+		default:
+			stmts = append(stmts, newStmt(ast.StmtBad, ast.BadStmt{PosRange: p.recoverBad(token.NEWLINE)}))
+		}
+	}
+}
+
+// ExpectStmt parses a statement of a block, local declarations included.
+func (p *Parser) ExpectStmt() ast.Stmt {
+	switch p.Token.Kind {
+	case token.VAL, token.VAR, token.CONST:
+		return newStmt(ast.StmtValDecl, p.ExpectValDecl())
+	case token.TYPE:
+		return newStmt(ast.StmtTypeDecl, p.ExpectTypeDecl())
+	case token.FUNC:
+		// Closures are expressions.
+		if p.Peek(1).Kind == token.IDENT {
+			return newStmt(ast.StmtFuncDecl, p.ExpectFuncDecl())
+		}
+	case token.IF:
+		return newStmt(ast.StmtExpr, newExpr(ast.ExprBranch, p.ExpectBranchExpr()))
+	case token.MATCH:
+		return newStmt(ast.StmtExpr, newExpr(ast.ExprMatch, p.ExpectMatchExpr()))
+	case token.LBRACE:
+		return newStmt(ast.StmtExpr, newExpr(ast.ExprStmtBlock, p.ExpectStmtBlock()))
+	case token.FOR:
+		return p.ExpectForStmt()
+	case token.SWITCH:
+		return newStmt(ast.StmtSwitch, p.ExpectSwitchStmt())
+	case token.SELECT:
+		return newStmt(ast.StmtSelect, p.ExpectSelectStmt())
+	case token.RETURN:
+		return newStmt(ast.StmtReturn, p.ExpectReturnStmt())
+	case token.BREAK:
+		begin := p.Token.From
+		p.Scan()
+		label := p.expectLabel()
+		return newStmt(ast.StmtBreak, ast.BreakStmt{PosRange: p.rangeFrom(begin), Label: label})
+	case token.CONTINUE:
+		begin := p.Token.From
+		p.Scan()
+		label := p.expectLabel()
+		return newStmt(ast.StmtContinue, ast.ContinueStmt{PosRange: p.rangeFrom(begin), Label: label})
+	case token.FALLTHROUGH:
+		begin := p.Token.From
+		p.Scan()
+		return newStmt(ast.StmtFallthrough, ast.FallthroughStmt{PosRange: p.rangeFrom(begin)})
+	case token.GOTO:
+		begin := p.Token.From
+		p.Scan()
+		label := p.ExpectIdent()
+		return newStmt(ast.StmtGoto, ast.GotoStmt{PosRange: p.rangeFrom(begin), Label: label})
+	case token.GO:
+		begin := p.Token.From
+		p.Scan()
+		expr := p.ExpectExpr()
+		return newStmt(ast.StmtGo, ast.GoStmt{PosRange: p.rangeFrom(begin), Expr: expr})
+	case token.DEFER, token.ERRDEFER:
+		return newStmt(ast.StmtDefer, p.ExpectDeferStmt())
+	case token.IDENT:
+		if p.Peek(1).Kind == token.COLON {
+			return newStmt(ast.StmtLabeled, p.ExpectLabeledStmt())
+		}
+	}
+	return p.expectSimpleStmt()
+}
+
+// expectLabel parses the optional label of break and continue.
+func (p *Parser) expectLabel() *ast.Ident {
+	if p.Token.Kind != token.IDENT {
+		return nil
+	}
+	label := p.ExpectIdent()
+	return &label
+}
+
+// expectSimpleStmt parses an expression statement, an assignment or a send.
+func (p *Parser) expectSimpleStmt() ast.Stmt {
+	expr := p.ExpectExpr()
+	if p.Token.Kind == token.ARROW {
+		return newStmt(ast.StmtSend, p.expectSend(expr))
+	}
+	if p.Token.Kind == token.ASSIGN || token.CompoundAssignOperators[p.Token.Kind] != 0 {
+		return newStmt(ast.StmtAssign, p.expectAssign(expr))
+	}
+	return newStmt(ast.StmtExpr, expr)
+}
+
+// ExpectAssignStmt parses `target = value` and the compound assignments `target op= value`.
+func (p *Parser) ExpectAssignStmt() ast.AssignStmt {
+	return p.expectAssign(p.ExpectExpr())
+}
+
+func (p *Parser) expectAssign(target ast.Expr) ast.AssignStmt {
+	op := p.Token
+	if op.Kind != token.ASSIGN && token.CompoundAssignOperators[op.Kind] == 0 {
+		p.MatchTerm(token.ASSIGN)
+	}
+	p.Scan()
+	value := p.ExpectExpr()
+
+	return ast.AssignStmt{
+		PosRange: p.rangeFrom(target.GetPosRange().From),
+		Operator: op,
+		ExprL:    target,
+		ExprR:    value,
+	}
+}
+
+// expectSend parses the `<- value` sent on the channel parsed so far.
+func (p *Parser) expectSend(ch ast.Expr) ast.SendStmt {
+	p.MatchTerm(token.ARROW)
+	p.Scan()
+	value := p.ExpectExpr()
+
+	return ast.SendStmt{
+		PosRange: p.rangeFrom(ch.GetPosRange().From),
+		Chan:     ch,
+		Value:    value,
+	}
+}
+
+// ExpectReturnStmt parses `return [exprs]`, the results end with the line.
+func (p *Parser) ExpectReturnStmt() ast.ReturnStmt {
+	begin := p.Token.From
+
+	p.MatchTerm(token.RETURN)
+	p.Scan()
+
+	var exprs []ast.Expr
+	switch p.Token.Kind {
+	case token.NEWLINE, token.SEMICOLON, token.RBRACE, token.EOF:
+	default:
+		for {
+			exprs = append(exprs, p.ExpectExpr())
+			if p.Token.Kind != token.COMMA {
+				break
+			}
+			p.Scan()
+		}
+	}
+
+	return ast.ReturnStmt{
+		PosRange: p.rangeFrom(begin),
+		Exprs:    exprs,
+	}
+}
+
+// ExpectBranchExpr parses `if cond { ... } [else { ... }]`, the else branch of `else if` holds the nested branch.
+func (p *Parser) ExpectBranchExpr() ast.BranchExpr {
+	begin := p.Token.From
+
+	p.MatchTerm(token.IF)
+	p.Scan()
+	expr := ast.BranchExpr{Cond: p.expectHeaderExpr()}
+	expr.Branch = p.ExpectStmtBlock()
+
+	if p.Token.Kind == token.ELSE {
+		p.Scan()
+		if p.Token.Kind == token.IF {
+			nested := p.ExpectBranchExpr()
+			expr.ElseBranch = ast.StmtBlockExpr{
+				PosRange: nested.PosRange,
+				Stmts:    []ast.Stmt{newStmt(ast.StmtExpr, newExpr(ast.ExprBranch, nested))},
+			}
+		} else {
+			expr.ElseBranch = p.ExpectStmtBlock()
+		}
+	}
+
+	expr.PosRange = p.rangeFrom(begin)
+	return expr
+}
+
+// ExpectForStmt parses the loops `for { ... }`, `for cond { ... }`, `for [i,] v in expr { ... }`
+// and `for range expr { ... }`, which iterates without bindings.
+func (p *Parser) ExpectForStmt() ast.Stmt {
+	begin := p.Token.From
+
+	p.MatchTerm(token.FOR)
+	p.Scan()
+
+	switch {
+	case p.Token.Kind == token.LBRACE:
+		body := p.ExpectStmtBlock()
+		return newStmt(ast.StmtEndlessFor, ast.EndlessForStmt{
+			PosRange: p.rangeFrom(begin),
+			Stmt:     body,
+		})
+	case p.Token.Kind == token.RANGE,
+		p.Token.Kind == token.IDENT && (p.Peek(1).Kind == token.COMMA || p.Peek(1).Kind == token.IN):
+		var idents []ast.Ident
+		if p.Token.Kind == token.RANGE {
+			p.Scan()
+		} else {
+			for {
+				idents = append(idents, p.ExpectIdent())
+				if p.Token.Kind != token.COMMA {
+					break
+				}
+				p.Scan()
+			}
+			p.MatchTerm(token.IN)
+			p.Scan()
+		}
+		expr := p.expectHeaderExpr()
+		body := p.ExpectStmtBlock()
+		return newStmt(ast.StmtForeach, ast.ForeachStmt{
+			PosRange:  p.rangeFrom(begin),
+			IdentList: idents,
+			Expr:      expr,
+			Stmt:      body,
+		})
+	}
+
+	cond := p.expectHeaderExpr()
+	body := p.ExpectStmtBlock()
+	return newStmt(ast.StmtLoop, ast.LoopStmt{
+		PosRange: p.rangeFrom(begin),
+		Cond:     cond,
+		Stmt:     body,
+	})
+}
+
+// ExpectSwitchStmt parses `switch [tag] { case exprs: stmts ... default: stmts }`.
+func (p *Parser) ExpectSwitchStmt() ast.SwitchStmt {
+	begin := p.Token.From
+
+	p.MatchTerm(token.SWITCH)
+	p.Scan()
+
+	var stmt ast.SwitchStmt
+	if p.Token.Kind != token.LBRACE {
+		stmt.Tag = p.expectHeaderExpr()
+	}
+
+	p.MatchTerm(token.LBRACE)
+	p.Scan()
+	for {
+		p.skipSeparators()
+		if p.Token.Kind == token.RBRACE || p.ReachedEOF {
+			break
+		}
+		stmt.Cases = append(stmt.Cases, p.expectCaseClause())
+	}
+	p.MatchTerm(token.RBRACE)
+	p.Scan()
+
+	stmt.PosRange = p.rangeFrom(begin)
+	return stmt
+}
+
+func (p *Parser) expectCaseClause() ast.CaseClause {
+	begin := p.Token.From
+
+	clause := ast.CaseClause{Default: p.Token.Kind == token.DEFAULT}
+	if !clause.Default {
+		p.MatchTerm(token.CASE)
+	}
+	p.Scan()
+	if !clause.Default {
+		for {
+			clause.Exprs = append(clause.Exprs, p.ExpectExpr())
+			if p.Token.Kind != token.COMMA {
+				break
+			}
+			p.Scan()
+		}
+	}
+	p.MatchTerm(token.COLON)
+	p.Scan()
+	clause.Stmts = p.expectStmts(true)
+
+	clause.PosRange = p.rangeFrom(begin)
+	return clause
+}
+
+// ExpectMatchExpr parses `match subject { case pattern [if guard]: stmts ... }`.
+func (p *Parser) ExpectMatchExpr() ast.MatchExpr {
+	begin := p.Token.From
+
+	p.MatchTerm(token.MATCH)
+	p.Scan()
+	expr := ast.MatchExpr{Subject: p.expectHeaderExpr()}
+
+	p.MatchTerm(token.LBRACE)
+	p.Scan()
+	for {
+		p.skipSeparators()
+		if p.Token.Kind == token.RBRACE || p.ReachedEOF {
+			break
+		}
+		start := p.Token
+		expr.Arms = append(expr.Arms, p.expectMatchArm())
+		if p.Token == start {
+			p.Scan()
+		}
+	}
+	p.MatchTerm(token.RBRACE)
+	p.Scan()
+
+	expr.PosRange = p.rangeFrom(begin)
+	return expr
+}
+
+func (p *Parser) expectMatchArm() ast.MatchArm {
+	begin := p.Token.From
+
+	p.MatchTerm(token.CASE)
+	p.Scan()
+	arm := ast.MatchArm{Pattern: p.ExpectPattern()}
+	if p.Token.Kind == token.IF {
+		p.Scan()
+		arm.Guard = p.ExpectExpr()
+	}
+	p.MatchTerm(token.COLON)
+	p.Scan()
+
+	bodyBegin := p.Token.From
+	arm.Body.Stmts = p.expectStmts(true)
+	arm.Body.PosRange = p.rangeFrom(bodyBegin)
+
+	arm.PosRange = p.rangeFrom(begin)
+	return arm
+}
+
+// ExpectSelectStmt parses `select { case comm: stmts ... default: stmts }`.
+func (p *Parser) ExpectSelectStmt() ast.SelectStmt {
+	begin := p.Token.From
+
+	p.MatchTerm(token.SELECT)
+	p.Scan()
+
+	var stmt ast.SelectStmt
+	p.MatchTerm(token.LBRACE)
+	p.Scan()
+	for {
+		p.skipSeparators()
+		if p.Token.Kind == token.RBRACE || p.ReachedEOF {
+			break
+		}
+		stmt.Cases = append(stmt.Cases, p.expectCommClause())
+	}
+	p.MatchTerm(token.RBRACE)
+	p.Scan()
+
+	stmt.PosRange = p.rangeFrom(begin)
+	return stmt
+}
+
+func (p *Parser) expectCommClause() ast.CommClause {
+	begin := p.Token.From
+
+	clause := ast.CommClause{Default: p.Token.Kind == token.DEFAULT}
+	if !clause.Default {
+		p.MatchTerm(token.CASE)
+	}
+	p.Scan()
+	if !clause.Default {
+		if p.Token.Kind == token.VAL || p.Token.Kind == token.VAR {
+			clause.Comm = newStmt(ast.StmtValDecl, p.ExpectValDecl())
+		} else {
+			clause.Comm = p.expectSimpleStmt()
+		}
+	}
+	p.MatchTerm(token.COLON)
+	p.Scan()
+	clause.Stmts = p.expectStmts(true)
+
+	clause.PosRange = p.rangeFrom(begin)
+	return clause
+}
+
+// ExpectLabeledStmt parses `label: stmt`, the statement may start on the next line.
+func (p *Parser) ExpectLabeledStmt() ast.LabeledStmt {
+	begin := p.Token.From
+
+	stmt := ast.LabeledStmt{Label: p.ExpectIdent()}
+	p.MatchTerm(token.COLON)
+	p.Scan()
+	p.SkipNewlines()
+	if p.Token.Kind != token.RBRACE && !p.ReachedEOF {
+		stmt.Stmt = p.ExpectStmt()
+	}
+
+	stmt.PosRange = p.rangeFrom(begin)
+	return stmt
+}
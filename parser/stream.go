@@ -0,0 +1,85 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package parser
+
+import (
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/token"
+)
+
+// Every token is scanned once. Scanning ahead of Token updates the quote stack, the comments and the lexical
+// diagnostics as scanning the tokens one by one does, rewinding to a mark replays the buffered tokens
+// without scanning them again.
+
+// Mark is a position in the token stream to backtrack to, see Parser.Mark.
+type Mark struct {
+	token     int       // index of the marked token in the stream
+	diagnosis int       // diagnostics reported before the mark
+	end       token.Pos // end of the last token consumed before the mark
+}
+
+// buffer starts buffering tokens from Token.
+func (p *Parser) buffer() {
+	if len(p.stream) == 0 {
+		p.stream = append(p.stream, p.Token)
+		p.cursor = 1
+	}
+}
+
+// trim drops the buffered tokens once no mark is open and every token was consumed.
+func (p *Parser) trim() {
+	if p.marks == 0 && p.cursor == len(p.stream) {
+		p.stream = p.stream[:0]
+		p.cursor = 0
+	}
+}
+
+// Peek returns the token n tokens after Token, Peek(0) is Token. Tokens past the end of the input are EOF.
+func (p *Parser) Peek(n int) ast.Token {
+	p.buffer()
+	for p.cursor-1+n >= len(p.stream) {
+		if last := p.stream[len(p.stream)-1]; last.Kind == token.EOF {
+			return last
+		}
+		p.stream = append(p.stream, p.next())
+	}
+	return p.stream[p.cursor-1+n]
+}
+
+// Mark marks Token for a speculative parse, which ends with either Backtrack or Commit of the mark.
+// Marks nest, inner marks end first.
+func (p *Parser) Mark() Mark {
+	p.buffer()
+	p.marks++
+	return Mark{token: p.cursor - 1, diagnosis: len(p.Diagnosis), end: p.end}
+}
+
+// Backtrack rewinds the parser to the marked token and ends the mark. The diagnostics reported since the mark
+// are dropped, except those of scanning, whose tokens are not scanned again. The scanner cursor stays ahead,
+// nodes take their positions from the tokens.
+func (p *Parser) Backtrack(m Mark) {
+	p.Token = p.stream[m.token]
+	p.cursor = m.token + 1
+	p.end = m.end
+	p.ReachedEOF = p.Token.Kind == token.EOF
+
+	kept := p.Diagnosis[:m.diagnosis]
+	for _, d := range p.Diagnosis[m.diagnosis:] {
+		if d.Kind == diagnosis.IllegalToken || d.Kind == diagnosis.MismatchedDelimiter || d.Kind == diagnosis.NonClosedQuote {
+			kept = append(kept, d)
+		}
+	}
+	p.Diagnosis = kept
+
+	p.marks--
+	p.trim()
+}
+
+// Commit ends the mark, keeping the tokens consumed since.
+func (p *Parser) Commit(m Mark) {
+	p.marks--
+	p.trim()
+}
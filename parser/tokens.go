@@ -0,0 +1,85 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package parser
+
+import (
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/token"
+	"fmt"
+	"sort"
+)
+
+// ScanAll scans the whole buffer, comments included, in source order. Newline tokens are dropped,
+// line breaks are recoverable from positions.
+// Positions are allocated in file, pass the file the buffer was parsed with to relate tokens to the AST.
+// On a scanner failure the tokens scanned so far are returned along with the error.
+func ScanAll(file *token.File, buffer []rune) (toks []ast.Token, err error) {
+	p := NewFileParser(file, buffer)
+	p.Options.MaxErrors = -1 // diagnostics are dropped
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: %v", file.Position(p.pos()), r)
+		}
+		toks = append(toks, p.Comments...)
+		sort.SliceStable(toks, func(i, j int) bool { return toks[i].From < toks[j].From })
+	}()
+
+	for p.Scan(); !p.ReachedEOF; p.Scan() {
+		if p.Token.Kind != token.NEWLINE {
+			toks = append(toks, p.Token)
+		}
+	}
+	return toks, nil
+}
+
+// ScanRecover is ScanAll in recovery mode: input the scanner rejects is returned as ILLEGAL tokens,
+// each with a diagnostic, and scanning never fails. Scanning stops once the default error budget is spent.
+func ScanRecover(file *token.File, buffer []rune) (toks []ast.Token, diags []diagnosis.Diagnosis) {
+	p := NewFileParser(file, buffer)
+	p.Options.Recover = true
+
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(Bailout); !ok {
+				panic(r)
+			}
+		}
+		toks = append(toks, p.Comments...)
+		sort.SliceStable(toks, func(i, j int) bool { return toks[i].From < toks[j].From })
+		diags = p.Diagnosis
+	}()
+
+	for p.Scan(); !p.ReachedEOF; p.Scan() {
+		if p.Token.Kind != token.NEWLINE {
+			toks = append(toks, p.Token)
+		}
+	}
+	return toks, nil
+}
+
+// ScanCompact is ScanAll without literals, they are materialized from the content file retains.
+// Comments are in place rather than collected, so the tokens are in source order without sorting.
+func ScanCompact(file *token.File, buffer []rune) (toks []ast.CompactToken, err error) {
+	p := NewFileParser(file, buffer)
+	p.Options.MaxErrors = -1 // diagnostics are dropped
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: %v", file.Position(p.pos()), r)
+		}
+	}()
+
+	for {
+		kind, pos, _ := p.scan()
+		if kind == token.EOF {
+			return toks, nil
+		}
+		if kind != token.NEWLINE {
+			toks = append(toks, ast.CompactToken{PosRange: pos, Kind: kind})
+		}
+	}
+}
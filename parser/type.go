@@ -0,0 +1,412 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package parser
+
+import (
+	"cee"
+	"cee/ast"
+	"cee/diagnosis"
+	"cee/edition"
+	"cee/token"
+)
+
+var BuiltinTypes = map[string]ast.TypeKind{
+	"i8":  ast.TypeI8,
+	"i16": ast.TypeI16,
+	"i32": ast.TypeI32,
+	"i64": ast.TypeI64,
+	"u8":  ast.TypeU8,
+	"u16": ast.TypeU16,
+	"u32": ast.TypeU32,
+	"u64": ast.TypeU64,
+}
+
+func newType(kind ast.TypeKind, value ast.Node) ast.Type {
+	return ast.Type{Union: cee.Union[ast.TypeKind]{Tag: kind, Value: value}}
+}
+
+func (p *Parser) SkipNewlines() {
+	for p.Token.Kind == token.NEWLINE {
+		p.Scan()
+	}
+}
+
+func IsTypeBegin(kind int) bool {
+	switch kind {
+	case token.IDENT, token.STRUCT, token.TRAIT, token.FUNC, token.MAP, token.CHAN, token.ARROW, token.MUL, token.LBRACK:
+		return true
+	}
+	return false
+}
+
+func (p *Parser) ExpectType() ast.Type {
+	switch p.Token.Kind {
+	case token.IDENT:
+		begin := p.Token.From
+		alias := ast.TypeAlias{Ident: p.ExpectIdent()}
+		if kind, ok := BuiltinTypes[alias.Literal]; ok {
+			return newType(kind, alias)
+		}
+		if p.Token.Kind == token.LBRACK {
+			args := p.ExpectTypeArgs()
+			return newType(ast.TypeInstance, ast.GenericInstantiationExpr{
+				PosRange: p.rangeFrom(begin),
+				Expr:     newExpr(ast.ExprIdent, alias.Ident),
+				TypeArgs: args,
+			})
+		}
+		return newType(ast.TypeIdent, alias)
+	case token.STRUCT:
+		return newType(ast.TypeStruct, p.ExpectStructType())
+	case token.TRAIT:
+		return newType(ast.TypeTrait, p.ExpectTraitType())
+	case token.FUNC:
+		p.Scan()
+		return newType(ast.TypeFunc, p.ExpectFuncType())
+	case token.MUL:
+		return newType(ast.TypePointer, p.ExpectPointerType())
+	case token.LBRACK:
+		return newType(ast.TypeArray, p.ExpectArrayType())
+	case token.MAP:
+		return newType(ast.TypeMap, p.ExpectMapType())
+	case token.CHAN, token.ARROW:
+		return newType(ast.TypeChan, p.ExpectChanType())
+	default:
+		p.ReportAndRecover(diagnosis.Diagnosis{
+			Kind: diagnosis.UnexpectedNode,
+			Error: diagnosis.UnexpectedNodeError{
+				Have: p.Token,
+				Want: token.IDENT,
+			},
+		})
+		return ast.Type{}
+	}
+}
+
+// ExpectPointerType parses `*Elem`.
+func (p *Parser) ExpectPointerType() ast.PointerType {
+	begin := p.Token.From
+
+	p.MatchTerm(token.MUL)
+	p.Scan()
+	elem := p.ExpectType()
+
+	return ast.PointerType{
+		PosRange: p.rangeFrom(begin),
+		Elem:     elem,
+	}
+}
+
+// ExpectArrayType parses `[Len]Elem` and the slice `[]Elem`.
+func (p *Parser) ExpectArrayType() ast.ArrayType {
+	begin := p.Token.From
+
+	p.MatchTerm(token.LBRACK)
+	p.Scan()
+	var length ast.Expr
+	if p.Token.Kind != token.RBRACK {
+		length = p.ExpectExpr()
+	}
+	p.MatchTerm(token.RBRACK)
+	p.Scan()
+	elem := p.ExpectType()
+
+	return ast.ArrayType{
+		PosRange: p.rangeFrom(begin),
+		Len:      length,
+		Elem:     elem,
+	}
+}
+
+// ExpectMapType parses `map[Key]Value`.
+func (p *Parser) ExpectMapType() ast.MapType {
+	begin := p.Token.From
+
+	p.MatchTerm(token.MAP)
+	p.Scan()
+	p.MatchTerm(token.LBRACK)
+	p.Scan()
+	key := p.ExpectType()
+	p.MatchTerm(token.RBRACK)
+	p.Scan()
+	value := p.ExpectType()
+
+	return ast.MapType{
+		PosRange: p.rangeFrom(begin),
+		Key:      key,
+		Value:    value,
+	}
+}
+
+// ExpectChanType parses `chan Elem`, `chan<- Elem` and `<-chan Elem`. As in Go, the arrow binds to the
+// leftmost chan, `chan<- chan T` sends channels.
+func (p *Parser) ExpectChanType() ast.ChanType {
+	begin := p.Token.From
+
+	dir := ast.ChanBoth
+	if p.Token.Kind == token.ARROW {
+		dir = ast.ChanRecv
+		p.Scan()
+	}
+	p.MatchTerm(token.CHAN)
+	p.Scan()
+	if dir == ast.ChanBoth && p.Token.Kind == token.ARROW {
+		dir = ast.ChanSend
+		p.Scan()
+	}
+	elem := p.ExpectType()
+
+	return ast.ChanType{
+		PosRange: p.rangeFrom(begin),
+		Dir:      dir,
+		Elem:     elem,
+	}
+}
+
+// ExpectTypeExpr parses a map, chan or array type in operand position, e.g. the argument of `make(map[K]V)`
+// or the type of a composite literal. Operands starting with MAP, CHAN, LBRACK, or ARROW followed by CHAN
+// are types.
+func (p *Parser) ExpectTypeExpr() ast.TypeExpr {
+	begin := p.Token.From
+	typ := p.ExpectType()
+	return ast.TypeExpr{
+		PosRange: p.rangeFrom(begin),
+		Type:     typ,
+	}
+}
+
+// ExpectTypeDecl parses `type Name[type params] Type`, the type parameters being optional.
+func (p *Parser) ExpectTypeDecl() ast.TypeDecl {
+	begin := p.Token.From
+
+	p.MatchTerm(token.TYPE)
+	p.Scan()
+	ident := p.ExpectIdent()
+	var params []ast.TypeParam
+	if p.Token.Kind == token.LBRACK && p.Peek(1).Kind == token.IDENT {
+		// A bracket opening a name declares type parameters, `type Buf [(N)]u8` parenthesizes a named length.
+		params = p.ExpectTypeParams()
+	}
+	typ := p.ExpectType()
+
+	return ast.TypeDecl{
+		PosRange:   p.rangeFrom(begin),
+		Ident:      ident,
+		TypeParams: params,
+		Type:       typ,
+	}
+}
+
+// ExpectTypeParams parses `[T, U Constraint, V]`. As in Go, a constraint applies to the names preceding it
+// back to the previous constraint, names after the last constraint accept any type.
+func (p *Parser) ExpectTypeParams() []ast.TypeParam {
+	p.MatchTerm(token.LBRACK)
+	p.Require(edition.Generics)
+	p.Scan()
+
+	var (
+		params []ast.TypeParam
+		group  ast.TypeParam
+	)
+	for {
+		p.SkipNewlines()
+		if p.Token.Kind == token.RBRACK || p.ReachedEOF {
+			break
+		}
+
+		if len(group.Idents) == 0 {
+			group.From = p.Token.From
+		}
+		group.Idents = append(group.Idents, p.ExpectIdent())
+		if IsTypeBegin(p.Token.Kind) {
+			group.Constraint = p.ExpectType()
+			group.To = p.end
+			params = append(params, group)
+			group = ast.TypeParam{}
+		} else {
+			group.To = p.end
+		}
+
+		p.SkipNewlines()
+		if p.Token.Kind != token.COMMA {
+			break
+		}
+		p.Scan()
+	}
+	if len(group.Idents) != 0 {
+		params = append(params, group)
+	}
+	p.MatchTerm(token.RBRACK)
+	p.Scan()
+
+	return params
+}
+
+// ExpectTypeArgs parses the type arguments `[T, U]` of an instantiation.
+func (p *Parser) ExpectTypeArgs() []ast.Type {
+	p.MatchTerm(token.LBRACK)
+	p.Scan()
+
+	var args []ast.Type
+	for {
+		p.SkipNewlines()
+		if p.Token.Kind == token.RBRACK || p.ReachedEOF {
+			break
+		}
+		args = append(args, p.ExpectType())
+
+		p.SkipNewlines()
+		if p.Token.Kind != token.COMMA {
+			break
+		}
+		p.Scan()
+	}
+	p.MatchTerm(token.RBRACK)
+	p.Scan()
+
+	return args
+}
+
+// ExpectGenDecl parses `a, b Type`.
+// A single identifier not followed by a type, e.g. an embedded struct field, is taken as the type itself.
+func (p *Parser) ExpectGenDecl() ast.GenDecl {
+	begin := p.Token.From
+
+	var idents []ast.Ident
+	for {
+		idents = append(idents, p.ExpectIdent())
+		// A comma followed by no name ends the list the declaration is an element of, e.g. `(a, )`.
+		if p.Token.Kind != token.COMMA || p.Peek(1).Kind != token.IDENT {
+			break
+		}
+		p.Scan()
+	}
+
+	if len(idents) == 1 && !IsTypeBegin(p.Token.Kind) {
+		return ast.GenDecl{
+			PosRange: p.rangeFrom(begin),
+			Type:     newType(ast.TypeIdent, ast.TypeAlias{Ident: idents[0]}),
+		}
+	}
+
+	typ := p.ExpectType()
+
+	return ast.GenDecl{
+		PosRange: p.rangeFrom(begin),
+		Idents:   idents,
+		Type:     typ,
+	}
+}
+
+// ExpectStructType parses `struct { fields }`, leading line breaks are skipped.
+func (p *Parser) ExpectStructType() ast.StructType {
+	p.SkipNewlines()
+	begin := p.Token.From
+
+	p.MatchTerm(token.STRUCT)
+	p.Scan()
+	p.MatchTerm(token.LBRACE)
+	p.Scan()
+
+	var fields []ast.GenDecl
+	for {
+		p.SkipNewlines()
+		if p.Token.Kind == token.RBRACE || p.ReachedEOF {
+			break
+		}
+		fields = append(fields, p.ExpectGenDecl())
+		if p.Token.Kind != token.RBRACE {
+			p.MatchTerm(token.NEWLINE)
+		}
+	}
+	p.expectCloser(token.RBRACE)
+
+	return ast.StructType{
+		PosRange: p.rangeFrom(begin),
+		Fields:   fields,
+	}
+}
+
+// ExpectTraitType parses `trait { elems }`, each elem being a method signature `name(params) results`
+// or the name of an embedded trait, terminated by a line break or a semicolon. The `interface` spelling
+// of the keyword is accepted too. Leading line breaks are skipped.
+func (p *Parser) ExpectTraitType() ast.TraitType {
+	p.SkipNewlines()
+	begin := p.Token.From
+
+	p.MatchTerm(token.TRAIT)
+	p.Scan()
+	p.MatchTerm(token.LBRACE)
+	p.Scan()
+
+	var t ast.TraitType
+	for {
+		p.skipSeparators()
+		if p.Token.Kind == token.RBRACE || p.ReachedEOF {
+			break
+		}
+
+		elemBegin := p.Token.From
+		ident := p.ExpectIdent()
+		if p.Token.Kind == token.LPAREN {
+			typ := p.ExpectFuncType()
+			t.Methods = append(t.Methods, ast.TraitMethod{
+				PosRange: p.rangeFrom(elemBegin),
+				Ident:    ident,
+				Type:     typ,
+			})
+		} else if ident.Kind == token.IDENT {
+			t.Embeds = append(t.Embeds, ast.TypeAlias{Ident: ident})
+		}
+
+		if p.Token.Kind != token.RBRACE && p.Token.Kind != token.SEMICOLON {
+			p.MatchTerm(token.NEWLINE)
+		}
+	}
+	p.expectCloser(token.RBRACE)
+
+	t.PosRange = p.rangeFrom(begin)
+	return t
+}
+
+// ExpectFuncType parses `(params) results` after the `fun` keyword and the optional name.
+func (p *Parser) ExpectFuncType() ast.FuncType {
+	begin := p.Token.From
+
+	p.MatchTerm(token.LPAREN)
+	p.Scan()
+
+	var params []ast.GenDecl
+	for p.Token.Kind == token.IDENT {
+		params = append(params, p.ExpectGenDecl())
+		if p.Token.Kind != token.COMMA {
+			break
+		}
+		p.Scan()
+	}
+	p.expectCloser(token.RPAREN)
+
+	var results []ast.Type
+	switch {
+	case p.Token.Kind == token.LPAREN:
+		p.Scan()
+		for IsTypeBegin(p.Token.Kind) {
+			results = append(results, p.ExpectType())
+			if p.Token.Kind != token.COMMA {
+				break
+			}
+			p.Scan()
+		}
+		p.expectCloser(token.RPAREN)
+	case IsTypeBegin(p.Token.Kind):
+		results = append(results, p.ExpectType())
+	}
+
+	return ast.FuncType{
+		PosRange: p.rangeFrom(begin),
+		Params:   params,
+		Results:  results,
+	}
+}
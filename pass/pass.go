@@ -0,0 +1,209 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package pass
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Kind byte
+
+const (
+	_ Kind = iota
+
+	Analysis
+	Transform
+)
+
+type Pass struct {
+	Name     string
+	Kind     Kind
+	Requires []string // names of passes that must run before this one
+	Run      func(ctx *Context) error
+}
+
+// Context is shared by all passes of a single run.
+// Analysis passes publish their results under their own name.
+type Context struct {
+	Results map[string]any
+}
+
+func (ctx *Context) Result(name string) any { return ctx.Results[name] }
+
+type Timing struct {
+	Name     string
+	Duration time.Duration
+}
+
+type Manager struct {
+	Passes   []Pass
+	Disabled map[string]bool
+
+	Timings []Timing
+
+	index map[string]int
+}
+
+func NewManager() Manager {
+	return Manager{
+		Disabled: map[string]bool{},
+		index:    map[string]int{},
+	}
+}
+
+type DuplicatePassError struct {
+	Name string
+}
+
+func (e DuplicatePassError) Error() string { return fmt.Sprint("pass: duplicate pass: ", e.Name) }
+
+type UnknownPassError struct {
+	Name, RequiredBy string
+}
+
+func (e UnknownPassError) Error() string {
+	if e.RequiredBy == "" {
+		return fmt.Sprint("pass: unknown pass: ", e.Name)
+	}
+	return fmt.Sprint("pass: unknown pass ", e.Name, " required by ", e.RequiredBy)
+}
+
+type DisabledDependencyError struct {
+	Name, RequiredBy string
+}
+
+func (e DisabledDependencyError) Error() string {
+	return fmt.Sprint("pass: ", e.RequiredBy, " requires disabled pass ", e.Name)
+}
+
+type CycleError struct {
+	Chain []string
+}
+
+func (e CycleError) Error() string {
+	return fmt.Sprint("pass: dependency cycle: ", strings.Join(e.Chain, " -> "))
+}
+
+type PassError struct {
+	Name string
+	Err  error
+}
+
+func (e PassError) Error() string { return fmt.Sprint("pass ", e.Name, ": ", e.Err.Error()) }
+
+func (e PassError) Unwrap() error { return e.Err }
+
+func (m *Manager) Register(p Pass) error {
+	if _, ok := m.index[p.Name]; ok {
+		return DuplicatePassError{Name: p.Name}
+	}
+	m.index[p.Name] = len(m.Passes)
+	m.Passes = append(m.Passes, p)
+	return nil
+}
+
+func (m *Manager) Enable(name string) error {
+	if _, ok := m.index[name]; !ok {
+		return UnknownPassError{Name: name}
+	}
+	delete(m.Disabled, name)
+	return nil
+}
+
+func (m *Manager) Disable(name string) error {
+	if _, ok := m.index[name]; !ok {
+		return UnknownPassError{Name: name}
+	}
+	m.Disabled[name] = true
+	return nil
+}
+
+// Order returns the enabled passes sorted so that every pass follows its dependencies.
+// Independent passes keep their registration order, so the schedule is deterministic.
+func (m *Manager) Order() ([]Pass, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	var (
+		order []Pass
+		state = make([]int, len(m.Passes))
+		chain []string
+	)
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		p := m.Passes[i]
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			for j, name := range chain {
+				if name == p.Name {
+					return CycleError{Chain: append(append([]string{}, chain[j:]...), p.Name)}
+				}
+			}
+		}
+
+		state[i] = visiting
+		chain = append(chain, p.Name)
+		for _, req := range p.Requires {
+			j, ok := m.index[req]
+			if !ok {
+				return UnknownPassError{Name: req, RequiredBy: p.Name}
+			}
+			if m.Disabled[req] {
+				return DisabledDependencyError{Name: req, RequiredBy: p.Name}
+			}
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		chain = chain[:len(chain)-1]
+		state[i] = visited
+
+		order = append(order, p)
+		return nil
+	}
+
+	for i, p := range m.Passes {
+		if m.Disabled[p.Name] {
+			continue
+		}
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// Run executes the scheduled passes and records their timings, stopping at the first failing pass.
+func (m *Manager) Run(ctx *Context) error {
+	order, err := m.Order()
+	if err != nil {
+		return err
+	}
+
+	if ctx.Results == nil {
+		ctx.Results = map[string]any{}
+	}
+
+	m.Timings = m.Timings[:0]
+	for _, p := range order {
+		begin := time.Now()
+		err := p.Run(ctx)
+		m.Timings = append(m.Timings, Timing{Name: p.Name, Duration: time.Since(begin)})
+		if err != nil {
+			return PassError{Name: p.Name, Err: err}
+		}
+	}
+
+	return nil
+}
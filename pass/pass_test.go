@@ -0,0 +1,82 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package pass
+
+import (
+	"errors"
+	"testing"
+)
+
+func newManager(t *testing.T, ran *[]string, passes ...Pass) Manager {
+	m := NewManager()
+	for _, p := range passes {
+		name := p.Name
+		p.Run = func(ctx *Context) error {
+			*ran = append(*ran, name)
+			return nil
+		}
+		if err := m.Register(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func TestManager_Run(t *testing.T) {
+	var ran []string
+	m := newManager(t, &ran,
+		Pass{Name: "check", Requires: []string{"resolve"}},
+		Pass{Name: "lint"},
+		Pass{Name: "resolve"},
+	)
+
+	if err := m.Run(&Context{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"resolve", "check", "lint"}
+	for i := range want {
+		if ran[i] != want[i] {
+			t.Fatal("incorrect order:", ran)
+		}
+	}
+	if len(m.Timings) != 3 || m.Timings[0].Name != "resolve" {
+		t.Error("incorrect timings:", m.Timings)
+	}
+}
+
+func TestManager_Disable(t *testing.T) {
+	var ran []string
+	m := newManager(t, &ran,
+		Pass{Name: "resolve"},
+		Pass{Name: "lint"},
+		Pass{Name: "check", Requires: []string{"resolve"}},
+	)
+
+	_ = m.Disable("lint")
+	if err := m.Run(&Context{}); err != nil || len(ran) != 2 {
+		t.Fatal("disabled pass ran:", ran, err)
+	}
+
+	_ = m.Disable("resolve")
+	var dep DisabledDependencyError
+	if err := m.Run(&Context{}); !errors.As(err, &dep) || dep.RequiredBy != "check" {
+		t.Error("want disabled dependency error, have", err)
+	}
+}
+
+func TestManager_Cycle(t *testing.T) {
+	var ran []string
+	m := newManager(t, &ran,
+		Pass{Name: "a", Requires: []string{"b"}},
+		Pass{Name: "b", Requires: []string{"c"}},
+		Pass{Name: "c", Requires: []string{"a"}},
+	)
+
+	var cycle CycleError
+	if _, err := m.Order(); !errors.As(err, &cycle) || len(cycle.Chain) != 4 {
+		t.Error("want cycle error, have", err)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package profile
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime/metrics"
+	"runtime/pprof"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+type Phase byte
+
+const (
+	_ Phase = iota
+
+	PhaseScan
+	PhaseParse
+	PhaseResolve
+	PhaseCheck
+	PhaseCodegen
+
+	phaseEnd
+)
+
+var phaseNames = [...]string{
+	PhaseScan:    "scan",
+	PhaseParse:   "parse",
+	PhaseResolve: "resolve",
+	PhaseCheck:   "check",
+	PhaseCodegen: "codegen",
+}
+
+func (p Phase) String() string { return phaseNames[p] }
+
+// Profiler observes the phases of the pipeline, it must be safe for concurrent use.
+type Profiler interface {
+	// Begin is called when a phase starts on a unit, a file or a package, the returned function when it ends.
+	Begin(phase Phase, unit string) (end func())
+}
+
+// Run runs fn as a phase on a unit, a nil profiler only runs it.
+// With labels set, samples of a pprof profile taken meanwhile are labelled with the phase and the unit.
+func Run(ctx context.Context, p Profiler, labels bool, phase Phase, unit string, fn func(ctx context.Context)) {
+	if p != nil {
+		defer p.Begin(phase, unit)()
+	}
+	if labels {
+		pprof.Do(ctx, pprof.Labels("phase", phase.String(), "unit", unit), fn)
+		return
+	}
+	fn(ctx)
+}
+
+type Stats struct {
+	Count    int // runs of the phase
+	Duration time.Duration
+	Allocs   uint64 // heap objects allocated
+	Bytes    uint64 // heap bytes allocated
+}
+
+// Collector sums the stats of each phase.
+// Allocation counters are process wide, they are exact only when phases do not run concurrently.
+type Collector struct {
+	mutex sync.Mutex
+	stats [phaseEnd]Stats
+}
+
+var allocMetrics = []string{"/gc/heap/allocs:objects", "/gc/heap/allocs:bytes"}
+
+func readAllocs() (objects, bytes uint64) {
+	samples := []metrics.Sample{{Name: allocMetrics[0]}, {Name: allocMetrics[1]}}
+	metrics.Read(samples)
+	return samples[0].Value.Uint64(), samples[1].Value.Uint64()
+}
+
+func (c *Collector) Begin(phase Phase, _ string) func() {
+	begin := time.Now()
+	objects, bytes := readAllocs()
+
+	return func() {
+		duration := time.Since(begin)
+		endObjects, endBytes := readAllocs()
+
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		s := &c.stats[phase]
+		s.Count++
+		s.Duration += duration
+		s.Allocs += endObjects - objects
+		s.Bytes += endBytes - bytes
+	}
+}
+
+func (c *Collector) Stats(phase Phase) Stats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.stats[phase]
+}
+
+// Report writes a table of the phases that ran.
+func (c *Collector) Report(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "phase\tcount\ttime\tallocs\tbytes\t")
+	for phase := PhaseScan; phase < phaseEnd; phase++ {
+		if s := c.Stats(phase); s.Count != 0 {
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%d\t%d\t\n", phase, s.Count, s.Duration.Round(time.Microsecond), s.Allocs, s.Bytes)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,39 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package profile
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"strings"
+	"testing"
+)
+
+var sink []byte
+
+func TestCollector(t *testing.T) {
+	var c Collector
+
+	Run(context.Background(), &c, true, PhaseParse, "main.cee", func(ctx context.Context) {
+		if phase, _ := pprof.Label(ctx, "phase"); phase != "parse" {
+			t.Errorf("phase label = %q", phase)
+		}
+		sink = make([]byte, 1<<16)
+	})
+
+	s := c.Stats(PhaseParse)
+	if s.Count != 1 || s.Bytes < 1<<16 {
+		t.Errorf("stats = %+v", s)
+	}
+
+	var b bytes.Buffer
+	if err := c.Report(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "parse") || strings.Contains(b.String(), "codegen") {
+		t.Errorf("report:\n%s", b.String())
+	}
+}
@@ -0,0 +1,8 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+// Package query
+// Incremental computation: derived queries are memoized over inputs with their dependencies recorded,
+// an input edit only re-runs the queries it reaches.
+package query
@@ -0,0 +1,197 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package query
+
+import "fmt"
+
+// Revision counts the input edits of a database.
+type Revision uint64
+
+type key struct {
+	id  int
+	arg any
+}
+
+// slot is the memo of an input or a derived query at one argument.
+type slot struct {
+	value   any
+	present bool
+
+	changed  Revision // the revision the value last changed in
+	verified Revision // the revision the value was last known to be up to date in
+	deps     []key
+
+	compute func(ctx *Context) any // nil for inputs
+	equal   func(a, b any) bool    // reports a recomputed value as unchanged
+}
+
+// Database holds the inputs and the memos of the queries defined on it.
+// It is not safe for concurrent use, callers serialize edits and reads.
+type Database struct {
+	revision Revision
+	names    []string
+	slots    map[key]*slot
+	active   map[key]bool
+
+	hits, misses int
+}
+
+func NewDatabase() *Database {
+	return &Database{slots: map[key]*slot{}, active: map[key]bool{}}
+}
+
+// Stats returns the number of derived values read from their memo and recomputed.
+func (db *Database) Stats() (hits, misses int) { return db.hits, db.misses }
+
+// Revision returns the current revision, which is bumped by every input edit.
+func (db *Database) Revision() Revision { return db.revision }
+
+func (db *Database) define(name string) int {
+	db.names = append(db.names, name)
+	return len(db.names) - 1
+}
+
+// Context records the dependencies of the query being computed, nil outside of a query.
+type Context struct {
+	db   *Database
+	deps []key
+	seen map[key]bool
+}
+
+func (ctx *Context) depend(k key) {
+	if ctx == nil || ctx.seen[k] {
+		return
+	}
+	ctx.seen[k] = true
+	ctx.deps = append(ctx.deps, k)
+}
+
+// CycleError is the panic value of a query that depends on itself.
+type CycleError struct {
+	Query string
+	Arg   any
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("query: cycle in %s(%v)", e.Query, e.Arg)
+}
+
+func (db *Database) slot(k key) *slot {
+	s, ok := db.slots[k]
+	if !ok {
+		s = &slot{}
+		db.slots[k] = s
+	}
+	return s
+}
+
+// refresh brings a derived slot up to date with the current revision.
+// The memo is kept when none of its dependencies changed since it was verified, otherwise it is recomputed.
+// A recomputed value equal to the memo keeps its changed revision, so the dependents are not re-run.
+func (db *Database) refresh(k key, s *slot) {
+	if s.compute == nil {
+		return
+	}
+	if s.present && (s.verified == db.revision || db.unchanged(s)) {
+		s.verified = db.revision
+		db.hits++
+		return
+	}
+	db.misses++
+
+	if db.active[k] {
+		panic(&CycleError{Query: db.names[k.id], Arg: k.arg})
+	}
+	db.active[k] = true
+	defer delete(db.active, k)
+
+	ctx := &Context{db: db, seen: map[key]bool{}}
+	value := s.compute(ctx)
+	if !s.present || !s.equal(s.value, value) {
+		s.changed = db.revision
+	}
+	s.value, s.present = value, true
+	s.deps = ctx.deps
+	s.verified = db.revision
+}
+
+func (db *Database) unchanged(s *slot) bool {
+	for _, dep := range s.deps {
+		d := db.slots[dep]
+		db.refresh(dep, d)
+		if d.changed > s.verified {
+			return false
+		}
+	}
+	return true
+}
+
+// Input is a value set from outside of the database, e.g. the text of a file keyed by its path.
+type Input[K comparable, V any] struct {
+	db *Database
+	id int
+}
+
+func NewInput[K comparable, V any](db *Database, name string) *Input[K, V] {
+	return &Input[K, V]{db: db, id: db.define(name)}
+}
+
+// Set replaces the value at k, which invalidates every query that read it.
+func (in *Input[K, V]) Set(k K, v V) {
+	in.db.revision++
+	s := in.db.slot(key{in.id, k})
+	s.value, s.present = v, true
+	s.changed = in.db.revision
+}
+
+// Remove unsets the value at k, queries that read it see it missing.
+func (in *Input[K, V]) Remove(k K) {
+	s, ok := in.db.slots[key{in.id, k}]
+	if !ok || !s.present {
+		return
+	}
+	in.db.revision++
+	s.value, s.present = nil, false
+	s.changed = in.db.revision
+}
+
+// Get returns the value at k and whether it is set, ctx records the read.
+func (in *Input[K, V]) Get(ctx *Context, k K) (v V, ok bool) {
+	s := in.db.slot(key{in.id, k})
+	ctx.depend(key{in.id, k})
+	if !s.present {
+		return v, false
+	}
+	return s.value.(V), true
+}
+
+// Query is a function of inputs and other queries, memoized per argument.
+type Query[K comparable, V any] struct {
+	db *Database
+	id int
+	fn func(ctx *Context, k K) V
+
+	// Equal, when set, cuts recomputation short: dependents of a value equal to its memo are not re-run.
+	Equal func(a, b V) bool
+}
+
+// NewQuery defines a query computed by fn, which must read the database through the context it is given.
+func NewQuery[K comparable, V any](db *Database, name string, fn func(ctx *Context, k K) V) *Query[K, V] {
+	return &Query[K, V]{db: db, id: db.define(name), fn: fn}
+}
+
+// Get returns the value of the query at k, computing it unless the memo is up to date.
+// ctx is the context of the calling query, nil for reads from outside of the database.
+func (q *Query[K, V]) Get(ctx *Context, k K) V {
+	kk := key{q.id, k}
+	s := q.db.slot(kk)
+	if s.compute == nil {
+		s.compute = func(ctx *Context) any { return q.fn(ctx, k) }
+		s.equal = func(a, b any) bool { return q.Equal != nil && q.Equal(a.(V), b.(V)) }
+	}
+	q.db.refresh(kk, s)
+	ctx.depend(kk)
+	return s.value.(V)
+}
@@ -0,0 +1,72 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package query
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	db := NewDatabase()
+	text := NewInput[string, string](db, "text")
+
+	var runs map[string]int
+	words := NewQuery(db, "words", func(ctx *Context, path string) []string {
+		runs["words"]++
+		s, _ := text.Get(ctx, path)
+		return strings.Fields(s)
+	})
+	words.Equal = func(a, b []string) bool { return strings.Join(a, " ") == strings.Join(b, " ") }
+	count := NewQuery(db, "count", func(ctx *Context, path string) int {
+		runs["count"]++
+		return len(words.Get(ctx, path))
+	})
+
+	check := func(path string, want int, wantRuns map[string]int) {
+		t.Helper()
+		runs = map[string]int{}
+		if got := count.Get(nil, path); got != want {
+			t.Errorf("count(%s) = %d, want %d", path, got, want)
+		}
+		for q, n := range wantRuns {
+			if runs[q] != n {
+				t.Errorf("%s ran %d times, want %d", q, runs[q], n)
+			}
+		}
+	}
+
+	text.Set("a", "x y")
+	text.Set("b", "z")
+	check("a", 2, map[string]int{"words": 1, "count": 1})
+	check("a", 2, map[string]int{"words": 0, "count": 0})
+
+	// Other inputs do not invalidate the memo.
+	text.Set("b", "z w")
+	check("a", 2, map[string]int{"words": 0, "count": 0})
+
+	// Equal words cut the recomputation short.
+	text.Set("a", "x  y ")
+	check("a", 2, map[string]int{"words": 1, "count": 0})
+
+	text.Set("a", "x y z")
+	check("a", 3, map[string]int{"words": 1, "count": 1})
+
+	text.Remove("a")
+	check("a", 0, map[string]int{"words": 1, "count": 1})
+}
+
+func TestCycle(t *testing.T) {
+	db := NewDatabase()
+	var q *Query[int, int]
+	q = NewQuery(db, "loop", func(ctx *Context, n int) int { return q.Get(ctx, n) })
+
+	defer func() {
+		if _, ok := recover().(*CycleError); !ok {
+			t.Error("expected a cycle error")
+		}
+	}()
+	q.Get(nil, 0)
+}
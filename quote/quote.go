@@ -0,0 +1,312 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package quote
+
+import (
+	"cee"
+	"cee/ast"
+	"cee/parser"
+	"cee/token"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// holePrefix starts the identifiers holes are parsed as, `$x` becomes `__quote_x`.
+const holePrefix = "__quote_"
+
+// Hole returns the identifier a hole is parsed as.
+func Hole(name string) string { return holePrefix + name }
+
+// Args maps the names of holes to the nodes spliced into them: an ast.Expr, ast.Ident, ast.LiteralValue,
+// ast.Type, ast.Stmt or []ast.Stmt, the latter only for holes standing as a statement of a block.
+type Args map[string]any
+
+// rewrite replaces the holes of the template by identifiers and returns their names.
+func rewrite(src string) (string, []string, error) {
+	var (
+		b     strings.Builder
+		holes []string
+		rs    = []rune(src)
+	)
+	for i := 0; i < len(rs); i++ {
+		if rs[i] != '$' {
+			b.WriteRune(rs[i])
+			continue
+		}
+		j := i + 1
+		for j < len(rs) && (rs[j] == '_' || unicode.IsLetter(rs[j]) || j > i+1 && unicode.IsDigit(rs[j])) {
+			j++
+		}
+		if j == i+1 {
+			return "", nil, fmt.Errorf("quote: $ without a hole name at offset %d", i)
+		}
+		name := string(rs[i+1 : j])
+		holes = append(holes, name)
+		b.WriteString(Hole(name))
+		i = j - 1
+	}
+	return b.String(), holes, nil
+}
+
+// checkArgs reports holes without argument and arguments without hole.
+func checkArgs(holes []string, args Args) error {
+	used := map[string]bool{}
+	for _, hole := range holes {
+		if _, ok := args[hole]; !ok {
+			return fmt.Errorf("quote: no argument for $%s", hole)
+		}
+		used[hole] = true
+	}
+	var unused []string
+	for name := range args {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) != 0 {
+		sort.Strings(unused)
+		return fmt.Errorf("quote: no hole for %s", strings.Join(unused, ", "))
+	}
+	return nil
+}
+
+// parse parses the rewritten template with fn, turning syntax errors and scanner panics into an error.
+func parse(src string, args Args, fn func(p *parser.Parser)) (err error) {
+	text, holes, err := rewrite(src)
+	if err != nil {
+		return err
+	}
+	if err := checkArgs(holes, args); err != nil {
+		return err
+	}
+
+	p := parser.NewParser([]rune(text))
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("quote: %v", r)
+		}
+		if err == nil && len(p.Diagnosis) != 0 {
+			err = fmt.Errorf("quote: %v", p.Diagnosis[0].Error)
+		}
+	}()
+
+	p.Scan()
+	p.SkipNewlines()
+	fn(&p)
+	return nil
+}
+
+// Decls builds the top-level declarations of a template, e.g.
+//
+//	quote.Decls("fun $name() { return $value }", quote.Args{"name": ident, "value": expr})
+//
+// The nodes of the template have positions in a file of their own, spliced nodes keep theirs.
+func Decls(src string, args Args) ([]ast.Stmt, error) {
+	var decls []ast.Stmt
+	err := parse(src, args, func(p *parser.Parser) {
+		for ; !p.ReachedEOF; p.SkipNewlines() {
+			if decl := p.ExpectDecl(); decl.Tag != 0 {
+				decls = append(decls, decl)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decls, Splice(&decls, args)
+}
+
+// Expr builds an expression from a template.
+func Expr(src string, args Args) (ast.Expr, error) {
+	var expr ast.Expr
+	err := parse(src, args, func(p *parser.Parser) { expr = p.ExpectExpr() })
+	if err != nil {
+		return ast.Expr{}, err
+	}
+	return expr, Splice(&expr, args)
+}
+
+// Tokens scans a template into tokens, each hole being replaced by the tokens of its argument,
+// which must be an []ast.Token. It builds the token streams of macro expansions.
+func Tokens(src string, args Args) ([]ast.Token, error) {
+	text, holes, err := rewrite(src)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkArgs(holes, args); err != nil {
+		return nil, err
+	}
+
+	buffer := []rune(text)
+	toks, err := parser.ScanAll(token.NewFileSet().AddFile("", -1, len(buffer)), buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []ast.Token
+	for _, tok := range toks {
+		name, ok := strings.CutPrefix(tok.Literal, holePrefix)
+		if !ok || tok.Kind != token.IDENT {
+			out = append(out, tok)
+			continue
+		}
+		arg, ok := args[name].([]ast.Token)
+		if !ok {
+			return nil, fmt.Errorf("quote: cannot splice %T into tokens for $%s", args[name], name)
+		}
+		out = append(out, arg...)
+	}
+	return out, nil
+}
+
+var (
+	exprType  = reflect.TypeOf(ast.Expr{})
+	identType = reflect.TypeOf(ast.Ident{})
+	typeType  = reflect.TypeOf(ast.Type{})
+	stmtType  = reflect.TypeOf(ast.Stmt{})
+	stmtsType = reflect.TypeOf([]ast.Stmt{})
+)
+
+// holeName returns the name of the hole an identifier stands for.
+func holeName(ident ast.Ident) (string, bool) {
+	return strings.CutPrefix(ident.Literal, holePrefix)
+}
+
+// exprHole returns the name of the hole an expression consists of.
+func exprHole(e ast.Expr) (string, bool) {
+	if ident, ok := e.Value.(ast.Ident); ok && e.Tag == ast.ExprIdent {
+		return holeName(ident)
+	}
+	return "", false
+}
+
+// stmtHole returns the name of the hole a statement consists of.
+func stmtHole(s ast.Stmt) (string, bool) {
+	if e, ok := s.Value.(ast.Expr); ok && s.Tag == ast.StmtExpr {
+		return exprHole(e)
+	}
+	return "", false
+}
+
+func asExpr(arg any) (ast.Expr, bool) {
+	switch v := arg.(type) {
+	case ast.Expr:
+		return v, true
+	case ast.Ident:
+		return ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: ast.ExprIdent, Value: v}}, true
+	case ast.LiteralValue:
+		return ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: ast.ExprLiteralValue, Value: v}}, true
+	}
+	return ast.Expr{}, false
+}
+
+// Splice replaces the holes in the AST value pointed to by v by the nodes of args.
+// Holes are the identifiers Hole returns, wherever an identifier, expression, type or statement may be.
+func Splice(v any, args Args) error {
+	return splice(reflect.ValueOf(v).Elem(), args)
+}
+
+func mismatch(name string, arg any, want string) error {
+	return fmt.Errorf("quote: cannot splice %T as %s for $%s", arg, want, name)
+}
+
+func splice(v reflect.Value, args Args) error {
+	switch v.Type() {
+	case stmtsType:
+		var stmts []ast.Stmt
+		for _, stmt := range v.Interface().([]ast.Stmt) {
+			if name, ok := stmtHole(stmt); ok {
+				if arg, ok := args[name].([]ast.Stmt); ok {
+					stmts = append(stmts, arg...)
+					continue
+				}
+			}
+			if err := splice(reflect.ValueOf(&stmt).Elem(), args); err != nil {
+				return err
+			}
+			stmts = append(stmts, stmt)
+		}
+		v.Set(reflect.ValueOf(stmts))
+		return nil
+	case stmtType:
+		if name, ok := stmtHole(v.Interface().(ast.Stmt)); ok {
+			switch arg := args[name].(type) {
+			case ast.Stmt:
+				v.Set(reflect.ValueOf(arg))
+				return nil
+			case []ast.Stmt:
+				return mismatch(name, arg, "a single statement")
+			}
+		}
+	case exprType:
+		if name, ok := exprHole(v.Interface().(ast.Expr)); ok {
+			expr, ok := asExpr(args[name])
+			if !ok {
+				return mismatch(name, args[name], "an expression")
+			}
+			v.Set(reflect.ValueOf(expr))
+			return nil
+		}
+	case identType:
+		if name, ok := holeName(v.Interface().(ast.Ident)); ok {
+			ident, ok := args[name].(ast.Ident)
+			if !ok {
+				return mismatch(name, args[name], "an identifier")
+			}
+			v.Set(reflect.ValueOf(ident))
+			return nil
+		}
+	case typeType:
+		t := v.Interface().(ast.Type)
+		if alias, ok := t.Value.(ast.TypeAlias); ok && t.Tag == ast.TypeIdent {
+			if name, ok := holeName(alias.Ident); ok {
+				// A type name is spliced as an identifier, by the identType case.
+				if typ, ok := args[name].(ast.Type); ok {
+					v.Set(reflect.ValueOf(typ))
+					return nil
+				}
+				if _, ok := args[name].(ast.Ident); !ok {
+					return mismatch(name, args[name], "a type")
+				}
+			}
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			return splice(v.Elem(), args)
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		// Values held by interfaces are not addressable, splice into a copy and store it back.
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		if err := splice(elem, args); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				if err := splice(v.Field(i), args); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := splice(v.Index(i), args); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
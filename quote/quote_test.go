@@ -0,0 +1,76 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package quote
+
+import (
+	"cee"
+	"cee/ast"
+	"cee/token"
+	"testing"
+)
+
+func ident(name string) ast.Ident {
+	return ast.Ident{Token: ast.Token{Kind: token.IDENT, Literal: name}}
+}
+
+func identExpr(name string) ast.Expr {
+	return ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: ast.ExprIdent, Value: ident(name)}}
+}
+
+func exprStmt(e ast.Expr) ast.Stmt {
+	return ast.Stmt{Union: cee.Union[ast.StmtKind]{Tag: ast.StmtExpr, Value: e}}
+}
+
+func TestRewrite(t *testing.T) {
+	text, holes, err := rewrite("fun $name() { return $value1 + x }")
+	if err != nil || text != "fun __quote_name() { return __quote_value1 + x }" || len(holes) != 2 {
+		t.Fatalf("got %q, %v, %v", text, holes, err)
+	}
+	if _, _, err := rewrite("a $ b"); err == nil {
+		t.Error("expected an error for a hole without name")
+	}
+	if err := checkArgs(holes, Args{"name": ident("f")}); err == nil {
+		t.Error("expected an error for a missing argument")
+	}
+	if err := checkArgs(holes, Args{"name": ident("f"), "value": nil, "value1": nil}); err == nil {
+		t.Error("expected an error for an unused argument")
+	}
+}
+
+func TestSplice(t *testing.T) {
+	// fun $name() { $body; $value + x }
+	name := ident(Hole("name"))
+	sum := ast.Expr{Union: cee.Union[ast.ExprKind]{Tag: ast.ExprBinary, Value: ast.BinaryExpr{
+		Operator: ast.Token{Kind: token.ADD, Literal: "+"},
+		Exprs:    [2]ast.Expr{identExpr(Hole("value")), identExpr("x")},
+	}}}
+	decl := ast.FuncDecl{Ident: &name, Stmt: &ast.StmtBlockExpr{Stmts: []ast.Stmt{
+		exprStmt(identExpr(Hole("body"))),
+		exprStmt(sum),
+	}}}
+
+	body := []ast.Stmt{exprStmt(identExpr("a")), exprStmt(identExpr("b"))}
+	err := Splice(&decl, Args{"name": ident("f"), "value": ast.LiteralValue{Token: ast.Token{Kind: token.INT, Literal: "1"}}, "body": body})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decl.Ident.Literal != "f" {
+		t.Errorf("name %q", decl.Ident.Literal)
+	}
+	stmts := decl.Stmt.Stmts
+	if len(stmts) != 3 || stmts[1].Value.(ast.Expr).Value.(ast.Ident).Literal != "b" {
+		t.Fatalf("statements %+v", stmts)
+	}
+	lhs := stmts[2].Value.(ast.Expr).Value.(ast.BinaryExpr).Exprs[0]
+	if lhs.Tag != ast.ExprLiteralValue || lhs.Value.(ast.LiteralValue).Literal != "1" {
+		t.Errorf("value spliced as %+v", lhs)
+	}
+
+	hole := identExpr(Hole("x"))
+	if err := Splice(&hole, Args{"x": ast.Type{}}); err == nil {
+		t.Error("expected an error splicing a type as an expression")
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package refactor
+
+import (
+	"cee/ast"
+	"cee/rewrite"
+	"cee/token"
+)
+
+// TextEdit replaces the runes in PosRange with NewText, an empty range inserts.
+type TextEdit struct {
+	ast.PosRange
+	NewText string
+}
+
+// Apply applies non-overlapping edits to the source, whose positions are allocated in file.
+func Apply(file *token.File, src []rune, edits []TextEdit) (string, error) {
+	r := rewrite.New(src)
+	for _, edit := range edits {
+		r.ReplaceRange(file, edit.PosRange, edit.NewText)
+	}
+	out, _, err := r.Apply()
+	return string(out), err
+}
@@ -0,0 +1,32 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package refactor
+
+import (
+	"cee/ast"
+	"cee/token"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	src := []rune("val x = 1")
+	fset := token.NewFileSet()
+	file := fset.AddFile("a.cee", fset.Base(), len(src))
+	span := func(from, to int) ast.PosRange { return ast.PosRange{From: file.Pos(from), To: file.Pos(to)} }
+
+	// Edits apply in any order, an empty range inserts.
+	got, err := Apply(file, src, []TextEdit{
+		{PosRange: span(8, 9), NewText: "2"},
+		{PosRange: span(4, 5), NewText: "y"},
+		{PosRange: span(0, 0), NewText: "// v\n"},
+	})
+	if err != nil || got != "// v\nval y = 2" {
+		t.Errorf("Apply = %q, %v", got, err)
+	}
+
+	if _, err := Apply(file, src, []TextEdit{{PosRange: span(0, 5)}, {PosRange: span(4, 9)}}); err == nil {
+		t.Errorf("overlapping edits applied")
+	}
+}
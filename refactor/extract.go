@@ -0,0 +1,191 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package refactor
+
+import (
+	"cee/ast"
+	"cee/hir"
+	"cee/parser"
+	"cee/resolve"
+	"cee/token"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var ErrNoStatements = errors.New("refactor: selection does not cover whole statements")
+
+func contains(pos ast.PosRange, from, to token.Pos) bool {
+	return pos.From <= from && to <= pos.To
+}
+
+func nestedBlocks(s ast.Stmt) []ast.StmtBlockExpr {
+	switch v := s.Value.(type) {
+	case ast.LoopStmt:
+		return []ast.StmtBlockExpr{v.Stmt}
+	case ast.ForeachStmt:
+		return []ast.StmtBlockExpr{v.Stmt}
+	case ast.EndlessForStmt:
+		return []ast.StmtBlockExpr{v.Stmt}
+	case ast.Expr:
+		switch e := v.Value.(type) {
+		case ast.StmtBlockExpr:
+			return []ast.StmtBlockExpr{e}
+		case ast.BranchExpr:
+			return []ast.StmtBlockExpr{e.Branch, e.ElseBranch}
+		}
+	}
+	return nil
+}
+
+// selectStmts finds the innermost block with statements inside the selection and returns them.
+func selectStmts(b ast.StmtBlockExpr, from, to token.Pos) []ast.Stmt {
+	for _, stmt := range b.Stmts {
+		if !contains(stmt.GetPosRange(), from, to) {
+			continue
+		}
+		for _, nested := range nestedBlocks(stmt) {
+			if contains(nested.PosRange, from, to) {
+				if stmts := selectStmts(nested, from, to); len(stmts) != 0 {
+					return stmts
+				}
+			}
+		}
+	}
+
+	var stmts []ast.Stmt
+	for _, stmt := range b.Stmts {
+		pos := stmt.GetPosRange()
+		if from <= pos.From && pos.To <= to {
+			stmts = append(stmts, stmt)
+		}
+	}
+	return stmts
+}
+
+func letTypes(b hir.Block, types map[token.Pos]ast.Type) {
+	for _, stmt := range b.Stmts {
+		switch v := stmt.Value.(type) {
+		case hir.LetStmt:
+			types[v.From] = v.Type
+		case hir.LoopStmt:
+			letTypes(v.Body, types)
+		case hir.Expr:
+			switch e := v.Value.(type) {
+			case hir.Block:
+				letTypes(e, types)
+			case hir.IfExpr:
+				letTypes(e.Then, types)
+				if e.Else != nil {
+					letTypes(*e.Else, types)
+				}
+			}
+		}
+	}
+}
+
+func paramType(obj *resolve.Object, inferred map[token.Pos]ast.Type) (string, error) {
+	switch d := obj.Decl.(type) {
+	case ast.GenDecl:
+		return ast.TypeString(d.Type), nil
+	case ast.ValDecl:
+		if typ, ok := inferred[d.From]; ok && typ.Tag != 0 {
+			return ast.TypeString(typ), nil
+		}
+	}
+	return "", fmt.Errorf("refactor: cannot infer the type of %s", obj.Name)
+}
+
+// ExtractFunction moves the statements covered by [from, to) into a new function declared after the
+// enclosing one, and replaces them by a call. Variables of the enclosing function used by the statements
+// become parameters. Selections containing control flow leaving them, or defining variables used
+// afterwards, are rejected.
+func ExtractFunction(src []rune, decls []ast.Stmt, info *resolve.Info, file string, from, to int, name string) ([]TextEdit, error) {
+	tf, ok := info.Files[file]
+	if !ok {
+		return nil, fmt.Errorf("refactor: %s is not resolved", file)
+	}
+	posFrom, posTo := tf.Pos(from), tf.Pos(to)
+
+	var fn *ast.FuncDecl
+	for _, decl := range decls {
+		if d, ok := decl.Value.(ast.FuncDecl); ok && d.Stmt != nil && contains(d.PosRange, posFrom, posTo) {
+			fn = &d
+			break
+		}
+	}
+	if fn == nil {
+		return nil, ErrNoStatements
+	}
+
+	stmts := selectStmts(*fn.Stmt, posFrom, posTo)
+	if len(stmts) == 0 {
+		return nil, ErrNoStatements
+	}
+	sel := ast.PosRange{From: stmts[0].GetPosRange().From, To: stmts[len(stmts)-1].GetPosRange().To}
+
+	toks, err := parser.ScanAll(tf, src)
+	if err != nil {
+		return nil, err
+	}
+	for _, tok := range toks {
+		if sel.Contains(tok.From) && (tok.Kind == token.RETURN || tok.Kind == token.BREAK || tok.Kind == token.CONTINUE) {
+			return nil, fmt.Errorf("refactor: selection contains %s", tok.Literal)
+		}
+	}
+
+	refs := make([]resolve.Ref, 0, len(info.Uses))
+	for ref := range info.Uses {
+		if ref.File == file {
+			refs = append(refs, ref)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Offset < refs[j].Offset })
+
+	var (
+		params []*resolve.Object
+		seen   = map[*resolve.Object]bool{}
+	)
+	for _, ref := range refs {
+		obj := info.Uses[ref]
+		local := obj.File == file && contains(fn.PosRange, obj.Ident.From, obj.Ident.From) &&
+			(obj.Kind == resolve.ObjVar || obj.Kind == resolve.ObjParam)
+		if !local {
+			continue
+		}
+		pos := tf.Pos(ref.Offset)
+		switch {
+		case sel.Contains(pos) && !sel.Contains(obj.Ident.From) && !seen[obj]:
+			seen[obj] = true
+			params = append(params, obj)
+		case pos >= sel.To && sel.Contains(obj.Ident.From):
+			return nil, fmt.Errorf("refactor: %s is used after the selection", obj.Name)
+		}
+	}
+
+	inferred := map[token.Pos]ast.Type{}
+	l := hir.NewLowerer()
+	letTypes(l.LowerFunc(*fn), inferred)
+
+	var args, decl []string
+	for _, obj := range params {
+		typ, err := paramType(obj, inferred)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, obj.Name)
+		decl = append(decl, obj.Name+" "+typ)
+	}
+
+	end := fn.PosRange.To
+	body := string(src[tf.Offset(sel.From):tf.Offset(sel.To)])
+	extracted := fmt.Sprint("\n\nfun ", name, "(", strings.Join(decl, ", "), ") {\n\t", body, "\n}")
+
+	return []TextEdit{
+		{PosRange: sel, NewText: name + "(" + strings.Join(args, ", ") + ")"},
+		{PosRange: ast.PosRange{From: end, To: end}, NewText: extracted},
+	}, nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package refactor
+
+import (
+	"cee/ast"
+	"cee/parser"
+	"cee/resolve"
+	"cee/token"
+	"strings"
+	"testing"
+)
+
+// parseSource parses and resolves a single file.
+func parseSource(t *testing.T, src string) (*token.File, []ast.Stmt, *resolve.Info) {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.cee", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tf := fset.File(f.From)
+	info := resolve.Resolve([]resolve.File{{Path: "a.cee", TokenFile: tf, Decls: f.Decls}})
+	return tf, f.Decls, &info
+}
+
+const extractSrc = `package a
+
+fun f(a i64) i64 {
+	var b = 1
+	val c = a * 2
+	if a > 0 {
+		print(a + b)
+		print(c)
+	}
+	return b
+}
+`
+
+func TestExtractFunction(t *testing.T) {
+	tf, decls, info := parseSource(t, extractSrc)
+	from := strings.Index(extractSrc, "print(a")
+	to := strings.Index(extractSrc, "print(c)") + len("print(c)")
+	edits, err := ExtractFunction([]rune(extractSrc), decls, info, "a.cee", from, to, "show")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Apply(tf, []rune(extractSrc), edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The type of c is inferred from the parameter a.
+	want := `package a
+
+fun f(a i64) i64 {
+	var b = 1
+	val c = a * 2
+	if a > 0 {
+		show(a, b, c)
+	}
+	return b
+}
+
+fun show(a i64, b i64, c i64) {
+	print(a + b)
+		print(c)
+}
+`
+	if got != want {
+		t.Errorf("extracted =\n%s\nwant\n%s", got, want)
+	}
+
+	// The keyword introducing a statement moves with it.
+	const src = `package a
+
+fun f(a i64) {
+	val d = a + 1
+	print(d)
+}
+`
+	tf, decls, info = parseSource(t, src)
+	edits, err = ExtractFunction([]rune(src), decls, info, "a.cee", strings.Index(src, "val"), strings.Index(src, "(d)")+3, "show")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = Apply(tf, []rune(src), edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `package a
+
+fun f(a i64) {
+	show(a)
+}
+
+fun show(a i64) {
+	val d = a + 1
+	print(d)
+}
+`
+	if got != want {
+		t.Errorf("extracted =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExtractFunctionErrors(t *testing.T) {
+	_, decls, info := parseSource(t, extractSrc)
+	span := func(from, to string) (int, int) {
+		return strings.Index(extractSrc, from), strings.Index(extractSrc, to) + len(to)
+	}
+
+	for _, test := range []struct {
+		from, to string
+		err      string
+	}{
+		{"var b", "val c = a * 2", "refactor: b is used after the selection"},
+		{"print(c)", "return b", "refactor: selection contains return"},
+		{"+ b", "+ b", ErrNoStatements.Error()},
+		{"package", "package a", ErrNoStatements.Error()},
+	} {
+		from, to := span(test.from, test.to)
+		_, err := ExtractFunction([]rune(extractSrc), decls, info, "a.cee", from, to, "g")
+		if err == nil || err.Error() != test.err {
+			t.Errorf("extracting %q to %q: error %v, want %s", test.from, test.to, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package refactor
+
+import (
+	"cee/ast"
+	"cee/parser"
+	"cee/token"
+	"sort"
+	"strings"
+)
+
+type importSpec struct {
+	decl  ast.ImportDecl
+	path  string
+	local string
+}
+
+func (spec importSpec) String() string {
+	if spec.decl.Alias != nil {
+		return spec.decl.Alias.Literal + " " + token.Quote(spec.path)
+	}
+	return token.Quote(spec.path)
+}
+
+// wholeLine extends the range over its indentation and line break when nothing else is on its line.
+func wholeLine(src []rune, file *token.File, pos ast.PosRange) ast.PosRange {
+	from, to := file.Offset(pos.From), file.Offset(pos.To)
+	for from > 0 && (src[from-1] == ' ' || src[from-1] == '\t') {
+		from--
+	}
+	if from > 0 && src[from-1] != '\n' || to < len(src) && src[to] != '\n' {
+		return pos
+	}
+	if to < len(src) {
+		to++
+	}
+	return ast.PosRange{From: file.Pos(from), To: file.Pos(to)}
+}
+
+// usedNames collects the identifiers used as the operand of a member selection outside imports.
+func usedNames(toks []ast.Token, imports []importSpec) map[string]bool {
+	inImport := func(pos token.Pos) bool {
+		for _, spec := range imports {
+			if spec.decl.Contains(pos) {
+				return true
+			}
+		}
+		return false
+	}
+
+	used := map[string]bool{}
+	for i := 0; i+1 < len(toks); i++ {
+		if toks[i].Kind == token.IDENT && toks[i+1].Kind == token.MEMBER_SELECT && !inImport(toks[i].From) {
+			used[toks[i].Literal] = true
+		}
+	}
+	return used
+}
+
+// OrganizeImports sorts imports by path, removes duplicates and imports whose package name is never used.
+// The resulting block replaces the first import, in a group if the first import is in one, the other imports
+// are deleted along with their lines.
+// The declarations are those of src parsed in file.
+func OrganizeImports(file *token.File, src []rune, decls []ast.Stmt) ([]TextEdit, error) {
+	var imports []importSpec
+	for _, decl := range decls {
+		d, ok := decl.Value.(ast.ImportDecl)
+		if !ok {
+			continue
+		}
+		path, err := token.Unquote(d.CanonicalName.Literal)
+		if err != nil {
+			return nil, err
+		}
+		spec := importSpec{decl: d, path: path, local: parser.ParsePackageName(path)}
+		if d.Alias != nil {
+			spec.local = d.Alias.Literal
+		}
+		imports = append(imports, spec)
+	}
+	if len(imports) == 0 {
+		return nil, nil
+	}
+
+	toks, err := parser.ScanAll(file, src)
+	if err != nil {
+		return nil, err
+	}
+	used := usedNames(toks, imports)
+
+	var (
+		kept []importSpec
+		seen = map[string]bool{}
+	)
+	for _, spec := range imports {
+		key := spec.String()
+		if seen[key] || !used[spec.local] && spec.local != "_" {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, spec)
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].path < kept[j].path })
+
+	// A standalone import starts with its keyword, a spec of a group does not.
+	prefix, sep := "import ", "\n"
+	for _, tok := range toks {
+		if tok.From == imports[0].decl.From && tok.Kind != token.IMPORT {
+			prefix, sep = "", "\n\t"
+		}
+	}
+
+	lines := make([]string, len(kept))
+	for i, spec := range kept {
+		lines[i] = prefix + spec.String()
+	}
+
+	var edits []TextEdit
+	if len(lines) != 0 {
+		edits = append(edits, TextEdit{PosRange: imports[0].decl.PosRange, NewText: strings.Join(lines, sep)})
+	} else {
+		edits = append(edits, TextEdit{PosRange: wholeLine(src, file, imports[0].decl.PosRange)})
+	}
+	for _, spec := range imports[1:] {
+		edits = append(edits, TextEdit{PosRange: wholeLine(src, file, spec.decl.PosRange)})
+	}
+	return edits, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package refactor
+
+import (
+	"testing"
+)
+
+func TestOrganizeImports(t *testing.T) {
+	for _, test := range []struct {
+		src, want string
+	}{
+		{
+			// Sorted by path, the duplicate and unused imports are removed, the blank import is kept.
+			src: `package a
+
+import "z/zed"
+import "fmt"
+import "unused"
+import run "lib"
+import "fmt"
+import _ "init"
+
+fun f() {
+	fmt.print(zed.x, run.y)
+}
+`,
+			want: `package a
+
+import "fmt"
+import _ "init"
+import run "lib"
+import "z/zed"
+
+fun f() {
+	fmt.print(zed.x, run.y)
+}
+`,
+		},
+		{
+			src: `package a
+
+import (
+	"z/zed"
+	"unused"
+	"fmt"
+)
+
+fun f() {
+	fmt.print(zed.x)
+}
+`,
+			want: `package a
+
+import (
+	"fmt"
+	"z/zed"
+)
+
+fun f() {
+	fmt.print(zed.x)
+}
+`,
+		},
+		{
+			src: `package a
+
+import "fmt"
+import "os"
+
+fun f() {}
+`,
+			want: `package a
+
+
+fun f() {}
+`,
+		},
+		{
+			src:  "package a\n\nfun f() {}\n",
+			want: "package a\n\nfun f() {}\n",
+		},
+	} {
+		tf, decls, _ := parseSource(t, test.src)
+		edits, err := OrganizeImports(tf, []rune(test.src), decls)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Apply(tf, []rune(test.src), edits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("organized =\n%s\nwant\n%s", got, test.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package resolve
+
+import (
+	"cee/token"
+	"sort"
+)
+
+// Confusable is a pair of objects visible together whose names look alike, Object declared after Other.
+type Confusable struct {
+	Object, Other *Object
+}
+
+// Confusables finds the objects whose names have the same skeleton, see token.Skeleton, as another visible
+// from their scope. Pairs are ordered by the declaration of Object, then of Other.
+func Confusables(info Info) []Confusable {
+	var pairs []Confusable
+	seen := map[[2]*Object]bool{}
+	check := func(scope *Scope) {
+		for _, obj := range scope.Objects {
+			skeleton := token.Skeleton(obj.Name)
+			for s := scope; s != nil; s = s.Parent {
+				for _, other := range s.Objects {
+					if other.Name == obj.Name || token.Skeleton(other.Name) != skeleton {
+						continue
+					}
+					pair := Confusable{Object: obj, Other: other}
+					if before(obj, other) {
+						pair = Confusable{Object: other, Other: obj}
+					}
+					if key := [2]*Object{pair.Object, pair.Other}; !seen[key] {
+						seen[key] = true
+						pairs = append(pairs, pair)
+					}
+				}
+			}
+		}
+	}
+
+	check(info.Package)
+	for _, scopes := range info.Scopes {
+		for _, scope := range scopes {
+			check(scope)
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Object != pairs[j].Object {
+			return before(pairs[i].Object, pairs[j].Object)
+		}
+		return before(pairs[i].Other, pairs[j].Other)
+	})
+	return pairs
+}
+
+func before(a, b *Object) bool {
+	if a.File != b.File {
+		return a.File < b.File
+	}
+	return a.Ident.From < b.Ident.From
+}
@@ -0,0 +1,61 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package resolve
+
+import (
+	"cee"
+	"cee/ast"
+	"cee/token"
+)
+
+// Qualify returns a copy of the declarations where the selections of members of imported packages, recorded
+// in info by resolving them, are qualified identifiers. Selections of fields are kept.
+// The index expressions whose index names a type are rewritten into instantiations too.
+func Qualify(decls []ast.Stmt, info Info) []ast.Stmt {
+	members := map[token.Pos]bool{}
+	for _, sel := range info.Selections {
+		members[sel.Member.From] = true
+	}
+	if len(members) == 0 && len(info.Instantiations) == 0 {
+		return decls
+	}
+	return ast.Rewrite(decls, func(e ast.Expr) ast.Expr {
+		if index, ok := e.Value.(ast.IndexExpr); ok && info.Instantiations[index.From] {
+			return ast.Expr{Union: cee.Union[ast.ExprKind]{
+				Tag: ast.ExprGenericInstantiation,
+				Value: ast.GenericInstantiationExpr{
+					PosRange: index.PosRange,
+					Expr:     index.Expr,
+					TypeArgs: []ast.Type{indexType(index.Index)},
+				},
+			}}
+		}
+
+		m, ok := e.Value.(ast.MemberSelectExpr)
+		if !ok || !members[m.Member.From] {
+			return e
+		}
+		pkg, ok := m.Expr.Value.(ast.Ident)
+		if !ok {
+			return e
+		}
+		return ast.Expr{Union: cee.Union[ast.ExprKind]{
+			Tag:   ast.ExprQualifiedIdent,
+			Value: ast.QualifiedIdent{PosRange: m.PosRange, Package: pkg, Member: m.Member},
+		}}
+	})
+}
+
+// indexType is the type argument named by the index `x[T]` or `x[*T]` of an instantiation.
+func indexType(e ast.Expr) ast.Type {
+	if u, ok := e.Value.(ast.UnaryExpr); ok {
+		return ast.Type{Union: cee.Union[ast.TypeKind]{
+			Tag:   ast.TypePointer,
+			Value: ast.PointerType{PosRange: u.PosRange, Elem: indexType(u.Expr)},
+		}}
+	}
+	ident := e.Value.(ast.Ident)
+	return ast.Type{Union: cee.Union[ast.TypeKind]{Tag: ast.TypeIdent, Value: ast.TypeAlias{Ident: ident}}}
+}
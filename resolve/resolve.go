@@ -0,0 +1,654 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package resolve
+
+import (
+	"cee/ast"
+	"cee/parser"
+	"cee/token"
+	"sort"
+)
+
+type ObjKind byte
+
+const (
+	_ ObjKind = iota
+
+	ObjType
+	ObjFunc
+	ObjExtern
+	ObjVar
+	ObjParam
+	ObjImport
+	ObjTypeParam
+)
+
+type Object struct {
+	Name  string
+	Kind  ObjKind
+	File  string
+	Ident ast.PosRange // the declaring identifier
+	Decl  ast.Node     // the declaring node
+}
+
+type Scope struct {
+	ast.PosRange
+	Parent  *Scope
+	Objects map[string]*Object
+}
+
+func NewScope(parent *Scope, pos ast.PosRange) *Scope {
+	return &Scope{PosRange: pos, Parent: parent, Objects: map[string]*Object{}}
+}
+
+func (s *Scope) Lookup(name string) *Object {
+	for ; s != nil; s = s.Parent {
+		if obj, ok := s.Objects[name]; ok {
+			return obj
+		}
+	}
+	return nil
+}
+
+// Ref identifies an identifier occurrence by file and offset.
+type Ref struct {
+	File   string
+	Offset int
+}
+
+// Less orders refs by file path, then offset. Ordered results of resolution follow it, whatever the order
+// the files were resolved in.
+func (r Ref) Less(other Ref) bool {
+	if r.File != other.File {
+		return r.File < other.File
+	}
+	return r.Offset < other.Offset
+}
+
+// SortedRefs returns the keys of Defs, Uses or Spans ordered by Ref.Less.
+func SortedRefs[V any](m map[Ref]V) []Ref {
+	refs := make([]Ref, 0, len(m))
+	for ref := range m {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Less(refs[j]) })
+	return refs
+}
+
+type Use struct {
+	Ref
+	ast.PosRange
+	Name string
+}
+
+// Selection is a member selected from an imported package, as in `pkg.Member`.
+type Selection struct {
+	Import *Object
+	Member ast.Ident
+}
+
+type Info struct {
+	Package *Scope
+
+	Defs map[Ref]*Object
+	Uses map[Ref]*Object
+
+	// Spans of every identifier recorded in Defs and Uses.
+	Spans map[Ref]ast.PosRange
+
+	// Scopes of all function bodies and blocks, per file in source order.
+	Scopes map[string][]*Scope
+
+	Unresolved []Use
+
+	// Selections of members of imported packages, in source order per file.
+	Selections []Selection
+
+	// Instantiations are the positions of the index expressions `x[T]` whose index names a type,
+	// which Qualify rewrites into instantiations.
+	Instantiations map[token.Pos]bool
+
+	// Captures lists, for each function literal keyed by its position, the variables of the enclosing
+	// functions its body uses, in order of first use.
+	Captures map[token.Pos][]*Object
+
+	// Files decodes the positions of each resolved file.
+	Files map[string]*token.File
+}
+
+// Offset returns the rune offset of a position in a resolved file.
+func (info *Info) Offset(file string, pos token.Pos) int { return info.Files[file].Offset(pos) }
+
+// Ref identifies the identifier at a position in a resolved file.
+func (info *Info) Ref(file string, pos token.Pos) Ref {
+	return Ref{File: file, Offset: info.Offset(file, pos)}
+}
+
+// Pos returns the position of a rune offset in a resolved file.
+func (info *Info) Pos(file string, offset int) token.Pos { return info.Files[file].Pos(offset) }
+
+type File struct {
+	Path      string
+	TokenFile *token.File
+	Decls     []ast.Stmt
+}
+
+type resolver struct {
+	info     *Info
+	file     string
+	tok      *token.File
+	scope    *Scope
+	closures []*Scope // scopes of the function literals enclosing the current scope, innermost last
+}
+
+// Resolve binds the identifiers of a package.
+// Top-level declarations are visible from every file, locals from their declaration on.
+func Resolve(files []File) Info {
+	info := Info{
+		Package: NewScope(nil, ast.PosRange{}),
+		Defs:    map[Ref]*Object{},
+		Uses:    map[Ref]*Object{},
+		Spans:   map[Ref]ast.PosRange{},
+		Scopes:  map[string][]*Scope{},
+		Files:   map[string]*token.File{},
+
+		Instantiations: map[token.Pos]bool{},
+		Captures:       map[token.Pos][]*Object{},
+	}
+
+	for _, file := range files {
+		info.Files[file.Path] = file.TokenFile
+		r := resolver{info: &info, file: file.Path, tok: file.TokenFile, scope: info.Package}
+		for _, decl := range file.Decls {
+			r.declareTopLevel(decl)
+		}
+	}
+
+	for _, file := range files {
+		r := resolver{info: &info, file: file.Path, tok: file.TokenFile, scope: info.Package}
+		for _, decl := range file.Decls {
+			r.topLevel(decl)
+		}
+	}
+
+	return info
+}
+
+// ResolvePackage resolves the files of a parsed package, whose positions fset decodes, in path order.
+// The package scope is recorded in the package.
+func ResolvePackage(fset *token.FileSet, pkg *ast.Package) Info {
+	paths := make([]string, 0, len(pkg.Files))
+	for path := range pkg.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var files []File
+	for _, path := range paths {
+		f := pkg.Files[path]
+		files = append(files, File{Path: path, TokenFile: fset.File(f.From), Decls: f.Decls})
+	}
+	info := Resolve(files)
+	pkg.Scope = info.Package
+	return info
+}
+
+// offset returns the rune offset of a position in the file. Identifiers expanded from macros lie in the files of
+// the expansions, which follow the file in its set: their offsets lie past its end, apart from each other.
+func (r *resolver) offset(pos token.Pos) int { return int(pos) - r.tok.Base() }
+
+func (r *resolver) define(ident ast.Ident, kind ObjKind, decl ast.Node) *Object {
+	obj := &Object{Name: ident.Literal, Kind: kind, File: r.file, Ident: ident.PosRange, Decl: decl}
+	r.scope.Objects[ident.Literal] = obj
+	ref := Ref{File: r.file, Offset: r.offset(ident.From)}
+	r.info.Defs[ref] = obj
+	r.info.Spans[ref] = ident.PosRange
+	return obj
+}
+
+func (r *resolver) use(ident ast.Ident) {
+	ref := Ref{File: r.file, Offset: r.offset(ident.From)}
+	if obj, scope := r.lookup(ident.Literal); obj != nil {
+		r.info.Uses[ref] = obj
+		r.info.Spans[ref] = ident.PosRange
+		r.capture(obj, scope)
+		return
+	}
+	r.info.Unresolved = append(r.info.Unresolved, Use{Ref: ref, PosRange: ident.PosRange, Name: ident.Literal})
+}
+
+// lookup returns the object named name and the scope declaring it.
+func (r *resolver) lookup(name string) (*Object, *Scope) {
+	for s := r.scope; s != nil; s = s.Parent {
+		if obj, ok := s.Objects[name]; ok {
+			return obj, s
+		}
+	}
+	return nil, nil
+}
+
+// capture records obj, declared in scope, as captured by the function literals enclosing the use but not
+// the declaration. Objects of the package are not captured.
+func (r *resolver) capture(obj *Object, scope *Scope) {
+	if scope == r.info.Package {
+		return
+	}
+	for i := len(r.closures) - 1; i >= 0; i-- {
+		closure := r.closures[i]
+		if encloses(closure, scope) {
+			break
+		}
+		captures := r.info.Captures[closure.From]
+		if !contains(captures, obj) {
+			r.info.Captures[closure.From] = append(captures, obj)
+		}
+	}
+}
+
+// encloses reports whether scope is outer or nested in it.
+func encloses(outer, scope *Scope) bool {
+	for ; scope != nil; scope = scope.Parent {
+		if scope == outer {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(objs []*Object, obj *Object) bool {
+	for _, o := range objs {
+		if o == obj {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *resolver) selection(pkg, member ast.Ident) {
+	if obj := r.scope.Lookup(pkg.Literal); obj != nil && obj.Kind == ObjImport {
+		r.info.Selections = append(r.info.Selections, Selection{Import: obj, Member: member})
+	}
+}
+
+func (r *resolver) openScope(pos ast.PosRange) {
+	r.scope = NewScope(r.scope, pos)
+	r.info.Scopes[r.file] = append(r.info.Scopes[r.file], r.scope)
+}
+
+func (r *resolver) closeScope() { r.scope = r.scope.Parent }
+
+func (r *resolver) declareTopLevel(decl ast.Stmt) {
+	switch d := decl.Value.(type) {
+	case ast.TypeDecl:
+		r.define(d.Ident, ObjType, d)
+	case ast.FuncDecl:
+		if d.Ident != nil {
+			r.define(*d.Ident, ObjFunc, d)
+		}
+	case ast.ExternDecl:
+		r.define(d.Ident, ObjExtern, d)
+	case ast.ImportDecl:
+		r.define(ImportName(d), ObjImport, d)
+	case ast.GenDecl:
+		for _, ident := range d.Idents {
+			r.define(ident, ObjVar, d)
+		}
+	case ast.ValDecl:
+		for _, ident := range d.Idents() {
+			r.define(ident, ObjVar, d)
+		}
+	}
+}
+
+// ImportName returns the identifier an import binds: the alias, or else the last element of the
+// canonical name spanning the quoted name.
+func ImportName(d ast.ImportDecl) ast.Ident {
+	if d.Alias != nil {
+		return *d.Alias
+	}
+	name, err := token.Unquote(d.CanonicalName.Literal)
+	if err != nil {
+		name = d.CanonicalName.Literal
+	}
+	return ast.Ident{Token: ast.Token{PosRange: d.CanonicalName.PosRange, Kind: token.IDENT, Literal: parser.ParsePackageName(name)}}
+}
+
+func (r *resolver) topLevel(decl ast.Stmt) {
+	switch d := decl.Value.(type) {
+	case ast.TypeDecl:
+		r.typeDecl(d)
+	case ast.FuncDecl:
+		r.funcDecl(d)
+	case ast.ExternDecl:
+		r.funcType(d.Type)
+	case ast.GenDecl:
+		r.typ(d.Type)
+	case ast.ValDecl:
+		r.typ(d.Type)
+		r.expr(d.Value)
+		if d.Pattern != nil {
+			r.pattern(*d.Pattern)
+		}
+	}
+}
+
+func (r *resolver) funcDecl(d ast.FuncDecl) {
+	// Captures refer to the variables of the enclosing scope.
+	for _, capture := range d.Captures {
+		r.use(capture.Ident)
+	}
+
+	r.openScope(d.PosRange)
+	defer r.closeScope()
+
+	r.typeParams(d.TypeParams)
+	r.signature(d.Type)
+	if d.Stmt != nil {
+		r.block(*d.Stmt)
+	}
+}
+
+// funcLit resolves a function literal, whose body may use the variables of the enclosing scopes.
+func (r *resolver) funcLit(e ast.FuncLitExpr) {
+	for _, capture := range e.Captures {
+		r.use(capture.Ident)
+	}
+
+	r.openScope(e.PosRange)
+	r.closures = append(r.closures, r.scope)
+	defer func() {
+		r.closures = r.closures[:len(r.closures)-1]
+		r.closeScope()
+	}()
+
+	r.signature(e.Type)
+	r.block(e.Body)
+}
+
+// signature resolves the types of a function and defines its parameters in the current scope.
+func (r *resolver) signature(t ast.FuncType) {
+	r.funcType(t)
+	for _, param := range t.Params {
+		for _, ident := range param.Idents {
+			r.define(ident, ObjParam, param)
+		}
+	}
+}
+
+// typeDecl resolves the type of a declaration, in a scope of its own when it has type parameters.
+func (r *resolver) typeDecl(d ast.TypeDecl) {
+	if len(d.TypeParams) != 0 {
+		r.openScope(d.PosRange)
+		defer r.closeScope()
+		r.typeParams(d.TypeParams)
+	}
+	r.typ(d.Type)
+}
+
+// typeParams defines the type parameters in the current scope, constraints may refer to any of them.
+func (r *resolver) typeParams(params []ast.TypeParam) {
+	for _, param := range params {
+		for _, ident := range param.Idents {
+			r.define(ident, ObjTypeParam, param)
+		}
+	}
+	for _, param := range params {
+		r.typ(param.Constraint)
+	}
+}
+
+func (r *resolver) funcType(t ast.FuncType) {
+	for _, param := range t.Params {
+		r.typ(param.Type)
+	}
+	for _, result := range t.Results {
+		r.typ(result)
+	}
+}
+
+func (r *resolver) typ(t ast.Type) {
+	switch v := t.Value.(type) {
+	case ast.TypeAlias:
+		if t.Tag == ast.TypeIdent {
+			r.use(v.Ident)
+		}
+	case ast.StructType:
+		for _, field := range v.Fields {
+			r.typ(field.Type)
+		}
+	case ast.TraitType:
+		for _, embed := range v.Embeds {
+			r.use(embed.Ident)
+		}
+		for _, method := range v.Methods {
+			r.funcType(method.Type)
+		}
+	case ast.FuncType:
+		r.funcType(v)
+	case ast.GenericInstantiationExpr:
+		r.expr(v.Expr)
+		for _, arg := range v.TypeArgs {
+			r.typ(arg)
+		}
+	case ast.MapType:
+		r.typ(v.Key)
+		r.typ(v.Value)
+	case ast.ChanType:
+		r.typ(v.Elem)
+	case ast.PointerType:
+		r.typ(v.Elem)
+	case ast.ArrayType:
+		r.expr(v.Len)
+		r.typ(v.Elem)
+	}
+}
+
+func (r *resolver) block(b ast.StmtBlockExpr) {
+	r.openScope(b.PosRange)
+	defer r.closeScope()
+
+	for _, stmt := range b.Stmts {
+		r.stmt(stmt)
+	}
+}
+
+func (r *resolver) stmt(s ast.Stmt) {
+	switch v := s.Value.(type) {
+	case ast.Expr:
+		r.expr(v)
+	case ast.ValDecl:
+		r.typ(v.Type)
+		r.expr(v.Value)
+		if v.Pattern != nil {
+			r.pattern(*v.Pattern)
+		}
+		for _, ident := range v.Idents() {
+			r.define(ident, ObjVar, v)
+		}
+	case ast.GenDecl:
+		r.typ(v.Type)
+		for _, ident := range v.Idents {
+			r.define(ident, ObjVar, v)
+		}
+	case ast.TypeDecl:
+		r.define(v.Ident, ObjType, v)
+		r.typeDecl(v)
+	case ast.FuncDecl:
+		if v.Ident != nil {
+			r.define(*v.Ident, ObjFunc, v)
+		}
+		r.funcDecl(v)
+	case ast.ReturnStmt:
+		for _, expr := range v.Exprs {
+			r.expr(expr)
+		}
+	case ast.AssignStmt:
+		r.expr(v.ExprL)
+		r.expr(v.ExprR)
+	case ast.LoopStmt:
+		r.expr(v.Cond)
+		r.block(v.Stmt)
+	case ast.ForeachStmt:
+		r.expr(v.Expr)
+		r.openScope(v.PosRange)
+		for _, ident := range v.IdentList {
+			r.define(ident, ObjVar, v)
+		}
+		r.block(v.Stmt)
+		r.closeScope()
+	case ast.EndlessForStmt:
+		r.block(v.Stmt)
+	case ast.DeferStmt:
+		r.expr(v.Expr)
+	case ast.GoStmt:
+		r.expr(v.Expr)
+	case ast.SendStmt:
+		r.expr(v.Chan)
+		r.expr(v.Value)
+	case ast.SwitchStmt:
+		r.expr(v.Tag)
+		for _, clause := range v.Cases {
+			for _, expr := range clause.Exprs {
+				r.expr(expr)
+			}
+			r.clause(clause.PosRange, clause.Stmts)
+		}
+	case ast.SelectStmt:
+		for _, clause := range v.Cases {
+			// Values received by the communication are scoped to the clause.
+			r.clause(clause.PosRange, append([]ast.Stmt{clause.Comm}, clause.Stmts...))
+		}
+	case ast.LabeledStmt:
+		// Labels are not objects, the checker matches them within the function.
+		r.stmt(v.Stmt)
+	}
+}
+
+// clause resolves the statements of a case clause in a scope of their own.
+func (r *resolver) clause(pos ast.PosRange, stmts []ast.Stmt) {
+	r.openScope(pos)
+	defer r.closeScope()
+
+	for _, stmt := range stmts {
+		r.stmt(stmt)
+	}
+}
+
+// indexedName returns the name of an index `x[T]` or `x[*T]`, which instantiates x when the name is a type.
+func indexedName(e ast.Expr) (ast.Ident, bool) {
+	for {
+		u, ok := e.Value.(ast.UnaryExpr)
+		if !ok || u.Operator.Kind != token.MUL {
+			break
+		}
+		e = u.Expr
+	}
+	ident, ok := e.Value.(ast.Ident)
+	return ident, ok
+}
+
+// pattern resolves the type names of struct patterns, the bound names are defined by the caller.
+func (r *resolver) pattern(p ast.Pattern) {
+	switch v := p.Value.(type) {
+	case ast.TuplePattern:
+		for _, elem := range v.Elems {
+			r.pattern(elem)
+		}
+	case ast.StructPattern:
+		if v.Type != nil {
+			r.use(*v.Type)
+		}
+		for _, field := range v.Fields {
+			// Field names are resolved by the checker once the type of the value is known.
+			if field.Pattern != nil {
+				r.pattern(*field.Pattern)
+			}
+		}
+	}
+}
+
+func (r *resolver) expr(e ast.Expr) {
+	switch v := e.Value.(type) {
+	case ast.Ident:
+		r.use(v)
+	case ast.UnaryExpr:
+		r.expr(v.Expr)
+	case ast.BinaryExpr:
+		r.expr(v.Exprs[0])
+		r.expr(v.Exprs[1])
+	case ast.EllipsisExpr:
+		r.expr(v.Array)
+	case ast.CallExpr:
+		r.expr(v.Callee)
+		for _, param := range v.Params {
+			r.expr(param)
+		}
+	case ast.IndexExpr:
+		r.expr(v.Expr)
+		r.expr(v.Index)
+		if ident, ok := indexedName(v.Index); ok {
+			if obj := r.scope.Lookup(ident.Literal); obj != nil && (obj.Kind == ObjType || obj.Kind == ObjTypeParam) {
+				r.info.Instantiations[v.From] = true
+			}
+		}
+	case ast.GenericInstantiationExpr:
+		r.expr(v.Expr)
+		for _, arg := range v.TypeArgs {
+			r.typ(arg)
+		}
+	case ast.TypeExpr:
+		r.typ(v.Type)
+	case ast.FuncLitExpr:
+		r.funcLit(v)
+	case ast.InterpolatedString:
+		for _, expr := range v.Exprs {
+			r.expr(expr)
+		}
+	case ast.CompositeLit:
+		r.typ(v.Type)
+		for _, elem := range v.Elems {
+			// Names keying struct literals are fields, resolved by the checker once the type is known.
+			if _, ok := elem.Key.Value.(ast.Ident); !ok {
+				r.expr(elem.Key)
+			}
+			r.expr(elem.Value)
+		}
+	case ast.BranchExpr:
+		r.expr(v.Cond)
+		r.block(v.Branch)
+		r.block(v.ElseBranch)
+	case ast.MatchExpr:
+		r.expr(v.Subject)
+		for _, arm := range v.Arms {
+			// Names bound by the pattern are visible in the guard and the body of the arm.
+			r.openScope(arm.PosRange)
+			r.pattern(arm.Pattern)
+			for _, ident := range arm.Pattern.Idents() {
+				r.define(ident, ObjVar, arm)
+			}
+			r.expr(arm.Guard)
+			r.block(arm.Body)
+			r.closeScope()
+		}
+	case ast.StmtBlockExpr:
+		r.block(v)
+	case ast.MemberSelectExpr:
+		// Members of packages are recorded for the checker, other members are resolved by it
+		// once the type of the operand is known.
+		r.expr(v.Expr)
+		if ident, ok := v.Expr.Value.(ast.Ident); ok {
+			r.selection(ident, v.Member)
+		}
+	case ast.QualifiedIdent:
+		r.use(v.Package)
+		r.selection(v.Package, v.Member)
+	case ast.TryExpr:
+		r.expr(v.Expr)
+	case ast.IntrinsicExpr:
+		for _, param := range v.Params {
+			r.expr(param)
+		}
+	}
+}
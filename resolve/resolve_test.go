@@ -0,0 +1,209 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package resolve
+
+import (
+	"cee/parser"
+	"cee/token"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+// resolveFiles parses and resolves the files of a package, given as path and source pairs.
+func resolveFiles(t *testing.T, files ...string) Info {
+	t.Helper()
+	fset := token.NewFileSet()
+	var resolved []File
+	for i := 0; i < len(files); i += 2 {
+		f, err := parser.ParseFile(fset, files[i], []byte(files[i+1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resolved = append(resolved, File{Path: files[i], TokenFile: fset.File(f.From), Decls: f.Decls})
+	}
+	return Resolve(resolved)
+}
+
+// useAt returns the object the n-th occurrence of the word name in the source of file refers to.
+func useAt(info Info, file, src, name string, n int) *Object {
+	word := func(i int) bool { return i < 0 || i >= len(src) || !unicode.IsLetter(rune(src[i])) }
+	for offset := 0; offset < len(src); offset++ {
+		if strings.HasPrefix(src[offset:], name) && word(offset-1) && word(offset+len(name)) {
+			if n == 0 {
+				return info.Uses[Ref{File: file, Offset: offset}]
+			}
+			n--
+		}
+	}
+	return nil
+}
+
+func TestShadowing(t *testing.T) {
+	const src = `package a
+
+var x = 1
+
+fun f(x i64) i64 {
+	val y = x
+	{
+		val x = y
+		return x
+	}
+}
+
+fun g() i64 { return x }
+`
+	info := resolveFiles(t, "a.cee", src)
+	if len(info.Unresolved) != 0 {
+		t.Errorf("unresolved %v", info.Unresolved)
+	}
+
+	global := info.Package.Objects["x"]
+	param := useAt(info, "a.cee", src, "x", 2)
+	local := useAt(info, "a.cee", src, "x", 4)
+	if global == nil || global.Kind != ObjVar {
+		t.Fatalf("package scope x = %+v", global)
+	}
+	if param == nil || param.Kind != ObjParam {
+		t.Errorf("x in `val y = x` refers to %+v, want the parameter", param)
+	}
+	if local == nil || local.Kind != ObjVar || local == global {
+		t.Errorf("x in `return x` refers to %+v, want the local", local)
+	}
+	if outer := useAt(info, "a.cee", src, "x", 5); outer != global {
+		t.Errorf("x in g refers to %+v, want the package variable", outer)
+	}
+}
+
+func TestUnresolved(t *testing.T) {
+	info := resolveFiles(t, "a.cee", `package a
+
+fun f(a i64) {
+	g(a, b)
+	{
+		val b = 1
+	}
+	b
+}
+`)
+	var names []string
+	for _, use := range info.Unresolved {
+		names = append(names, use.Name)
+	}
+	// The b declared in the block is out of scope before and after it.
+	if strings.Join(names, " ") != "g b b" {
+		t.Errorf("unresolved %v, want [g b b]", names)
+	}
+}
+
+func TestCrossFile(t *testing.T) {
+	const a = `package a
+
+fun f() i64 { return g() + limit }
+`
+	info := resolveFiles(t, "a.cee", a, "b.cee", `package a
+
+val limit = 10
+
+fun g() i64 { return f() }
+`)
+	if len(info.Unresolved) != 0 {
+		t.Errorf("unresolved %v", info.Unresolved)
+	}
+	g := useAt(info, "a.cee", a, "g", 0)
+	limit := useAt(info, "a.cee", a, "limit", 0)
+	if g == nil || g.File != "b.cee" || g.Kind != ObjFunc {
+		t.Errorf("g refers to %+v, want the function of b.cee", g)
+	}
+	if limit == nil || limit.File != "b.cee" {
+		t.Errorf("limit refers to %+v, want the value of b.cee", limit)
+	}
+	if f := info.Package.Objects["f"]; f == nil || f.File != "a.cee" {
+		t.Errorf("package scope f = %+v", f)
+	}
+}
+
+func TestCaptures(t *testing.T) {
+	info := resolveFiles(t, "a.cee", `package a
+
+var global = 1
+
+fun counter(step i64) {
+	var n = 0
+	val inc = fun () i64 {
+		n = n + step
+		return n + global
+	}
+	inc()
+}
+`)
+	if len(info.Unresolved) != 0 {
+		t.Errorf("unresolved %v", info.Unresolved)
+	}
+	if len(info.Captures) != 1 {
+		t.Fatalf("captures of %d literals, want 1", len(info.Captures))
+	}
+	for _, captures := range info.Captures {
+		var names []string
+		for _, obj := range captures {
+			names = append(names, obj.Name)
+		}
+		if len(names) != 2 || names[0] != "n" || names[1] != "step" {
+			t.Errorf("captures = %v, want [n step]", names)
+		}
+	}
+}
+
+func TestCaptureLists(t *testing.T) {
+	const src = `package a
+
+fun f(a i64) {
+	var b = 0
+	val g = fun [a, &b, c]() {
+		b = a
+	}
+	g()
+}
+`
+	info := resolveFiles(t, "a.cee", src)
+	if len(info.Unresolved) != 1 || info.Unresolved[0].Name != "c" {
+		t.Errorf("unresolved %v, want [c]", info.Unresolved)
+	}
+	// The listed variables are those of the enclosing function, which the body uses.
+	for _, c := range []struct {
+		name string
+		n    int
+		kind ObjKind
+	}{{"a", 2, ObjParam}, {"b", 1, ObjVar}} {
+		capture, use := useAt(info, "a.cee", src, c.name, c.n), useAt(info, "a.cee", src, c.name, c.n+1)
+		if capture == nil || capture != use || capture.Kind != c.kind {
+			t.Errorf("%s: capture %+v, use %+v", c.name, capture, use)
+		}
+	}
+}
+
+func TestResolvePackage(t *testing.T) {
+	fset := token.NewFileSet()
+	pkg, err := parser.ParsePackage(fset, map[string][]byte{
+		"b.cee": []byte("package a\n\nfun g() i64 { return f() }\n"),
+		"a.cee": []byte("package a\n\nfun f() i64 { return 1 }\n"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := ResolvePackage(fset, pkg)
+
+	scope, ok := pkg.Scope.(*Scope)
+	if !ok || scope != info.Package || scope.Objects["f"] == nil || scope.Objects["g"] == nil {
+		t.Fatalf("package scope %+v", pkg.Scope)
+	}
+	if obj := info.Uses[Ref{File: "b.cee", Offset: len("package a\n\nfun g() i64 { return ")}]; obj != scope.Objects["f"] || obj.File != "a.cee" {
+		t.Errorf("f used in b.cee refers to %+v", obj)
+	}
+	if len(info.Unresolved) != 0 || info.Files["a.cee"] == nil || info.Files["b.cee"] == nil {
+		t.Errorf("unresolved %v, files %v", info.Unresolved, info.Files)
+	}
+}
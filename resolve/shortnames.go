@@ -0,0 +1,64 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package resolve
+
+import (
+	"cee/token"
+	"sort"
+)
+
+// shortName returns the n-th name of the sequence a, b, ..., z, aa, ab, ...
+func shortName(n int) string {
+	var name []byte
+	for n++; n > 0; n = (n - 1) / 26 {
+		name = append([]byte{byte('a' + (n-1)%26)}, name...)
+	}
+	return string(name)
+}
+
+func isKeyword(name string) bool {
+	_, ok := token.Keyword2Enum[name]
+	return ok
+}
+
+// ShortNames gives the objects the shortest names not otherwise in use in the resolved files, the most
+// referenced objects getting the shortest. Every object gets a name of its own, so renaming the objects
+// cannot make a reference see another declaration.
+func ShortNames(info Info, objs []*Object) map[*Object]string {
+	used := map[string]bool{}
+	for _, obj := range info.Defs {
+		used[obj.Name] = true
+	}
+	for _, use := range info.Unresolved {
+		used[use.Name] = true
+	}
+	for _, sel := range info.Selections {
+		used[sel.Member.Literal] = true
+	}
+
+	refs := map[*Object]int{}
+	for _, obj := range info.Uses {
+		refs[obj]++
+	}
+	objs = append([]*Object{}, objs...)
+	sort.SliceStable(objs, func(i, j int) bool {
+		if refs[objs[i]] != refs[objs[j]] {
+			return refs[objs[i]] > refs[objs[j]]
+		}
+		return before(objs[i], objs[j])
+	})
+
+	names := map[*Object]string{}
+	n := 0
+	for _, obj := range objs {
+		name := shortName(n)
+		for ; used[name] || isKeyword(name); name = shortName(n) {
+			n++
+		}
+		n++
+		names[obj] = name
+	}
+	return names
+}
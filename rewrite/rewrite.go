@@ -0,0 +1,158 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package rewrite
+
+import (
+	"cee/ast"
+	"cee/token"
+	"fmt"
+	"sort"
+)
+
+// Edit replaces the runes from From to To, rune offsets, with Text. An empty range inserts.
+type Edit struct {
+	From, To int
+	Text     string
+}
+
+// Rewriter collects the edits of a buffer. Edits may be added in any order but must not overlap,
+// insertions at the same offset are applied in the order they were added, before a replacement starting there.
+type Rewriter struct {
+	src   []rune
+	edits []Edit
+}
+
+func New(src []rune) *Rewriter { return &Rewriter{src: src} }
+
+func (r *Rewriter) Replace(from, to int, text string) {
+	r.edits = append(r.edits, Edit{From: from, To: to, Text: text})
+}
+
+func (r *Rewriter) Insert(at int, text string) { r.Replace(at, at, text) }
+
+func (r *Rewriter) Delete(from, to int) { r.Replace(from, to, "") }
+
+// ReplaceRange replaces a range of the buffer, whose positions are allocated in file.
+func (r *Rewriter) ReplaceRange(file *token.File, pos ast.PosRange, text string) {
+	r.Replace(file.Offset(pos.From), file.Offset(pos.To), text)
+}
+
+// Apply returns the edited buffer and the map of its positions. The rewriter is left as it is.
+func (r *Rewriter) Apply() ([]rune, Map, error) {
+	// Insertions go before a replacement starting at their offset, whatever order they were added in.
+	edits := append([]Edit{}, r.edits...)
+	sort.SliceStable(edits, func(i, j int) bool {
+		a, b := edits[i], edits[j]
+		return a.From < b.From || a.From == b.From && a.From == a.To && b.From < b.To
+	})
+	for i, edit := range edits {
+		if edit.From < 0 || edit.To < edit.From || edit.To > len(r.src) {
+			return nil, Map{}, fmt.Errorf("rewrite: invalid edit [%d, %d) of a buffer of %d runes", edit.From, edit.To, len(r.src))
+		}
+		if i > 0 && edits[i-1].To > edit.From {
+			prev := edits[i-1]
+			return nil, Map{}, fmt.Errorf("rewrite: edit [%d, %d) overlaps [%d, %d)", edit.From, edit.To, prev.From, prev.To)
+		}
+	}
+
+	var (
+		out   []rune
+		spans []span
+		last  int
+	)
+	for _, edit := range edits {
+		out = append(out, r.src[last:edit.From]...)
+		text := []rune(edit.Text)
+		spans = append(spans, span{from: edit.From, to: edit.To, newFrom: len(out), newTo: len(out) + len(text)})
+		out = append(out, text...)
+		last = edit.To
+	}
+	out = append(out, r.src[last:]...)
+	return out, Map{steps: [][]span{spans}}, nil
+}
+
+// span is an edit with its place in the edited buffer.
+type span struct {
+	from, to       int
+	newFrom, newTo int
+}
+
+// Bias decides where positions in replaced text, or at insertions, go.
+type Bias byte
+
+const (
+	Left  Bias = iota // to the start of the new text
+	Right             // to its end
+)
+
+// Map maps rune offsets of a buffer to offsets of the buffer after one or more rounds of edits.
+// The zero Map is the identity.
+type Map struct {
+	steps [][]span
+}
+
+// Then returns the map of the edits of m followed by those of next, made to the buffer m maps to.
+func (m Map) Then(next Map) Map {
+	return Map{steps: append(append([][]span{}, m.steps...), next.steps...)}
+}
+
+// Offset maps an offset. Offsets before an edit stay, those after shift by the change of length, the start of
+// replaced text goes to the start of the new text. Offsets inside replaced text or at an insertion go to the
+// start or the end of the new text according to bias.
+func (m Map) Offset(offset int, bias Bias) int {
+	for _, spans := range m.steps {
+		offset = mapOffset(spans, offset, bias)
+	}
+	return offset
+}
+
+func mapOffset(spans []span, offset int, bias Bias) int {
+	delta := 0
+	for _, s := range spans {
+		switch {
+		case offset < s.from:
+			return offset + delta
+		case offset == s.from && s.from < s.to:
+			return s.newFrom
+		case offset < s.to:
+			if bias == Left {
+				return s.newFrom
+			}
+			return s.newTo
+		case offset == s.from && bias == Left:
+			// At an insertion, right goes past every insertion at the offset.
+			return s.newFrom
+		}
+		delta = s.newTo - s.to
+	}
+	return offset + delta
+}
+
+// Range maps a range so that it keeps covering the text it covered, text inserted at its ends stays out.
+// It reports false when all the text of a non-empty range was replaced.
+func (m Map) Range(from, to int) (int, int, bool) {
+	for _, spans := range m.steps {
+		for _, s := range spans {
+			if s.from <= from && to <= s.to && from < to {
+				return 0, 0, false
+			}
+		}
+		newFrom, newTo := mapOffset(spans, from, Right), mapOffset(spans, to, Left)
+		if from == to {
+			newTo = newFrom
+		}
+		from, to = newFrom, max(newFrom, newTo)
+	}
+	return from, to, true
+}
+
+// PosRange maps a range of old, the file of the buffer before the edits, to new, the file after them.
+func (m Map) PosRange(old, new *token.File, pos ast.PosRange) (ast.PosRange, bool) {
+	from, to, ok := m.Range(old.Offset(pos.From), old.Offset(pos.To))
+	if !ok {
+		return ast.PosRange{}, false
+	}
+	return ast.PosRange{From: new.Pos(from), To: new.Pos(to)}, true
+}
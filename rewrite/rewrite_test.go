@@ -0,0 +1,105 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package rewrite
+
+import "testing"
+
+func TestApply(t *testing.T) {
+	r := New([]rune("val x = f(a, b)"))
+	r.Replace(10, 11, "first")
+	r.Insert(4, "mut ")
+	r.Delete(11, 14)
+	r.Insert(4, "pub ")
+	out, m, err := r.Apply()
+	if err != nil || string(out) != "val mut pub x = f(first)" {
+		t.Fatalf("Apply = %q, %v", string(out), err)
+	}
+
+	tests := []struct {
+		offset int
+		bias   Bias
+		want   int
+	}{
+		{0, Left, 0},
+		{4, Left, 4},   // before both insertions
+		{4, Right, 12}, // after both
+		{10, Right, 18},
+		{12, Left, 23}, // inside the deletion
+		{14, Left, 23},
+		{15, Left, 24},
+	}
+	for _, test := range tests {
+		if got := m.Offset(test.offset, test.bias); got != test.want {
+			t.Errorf("Offset(%d, %d) = %d, want %d", test.offset, test.bias, got, test.want)
+		}
+	}
+
+	// x keeps covering x, the text inserted before it stays out.
+	if from, to, ok := m.Range(4, 5); !ok || from != 12 || to != 13 {
+		t.Errorf("Range(4, 5) = %d, %d, %v", from, to, ok)
+	}
+	if _, _, ok := m.Range(11, 13); ok {
+		t.Error("Range of deleted text")
+	}
+
+	r.Insert(5, "!")
+	r.Replace(3, 6, "")
+	if _, _, err := r.Apply(); err == nil {
+		t.Error("overlapping edits applied")
+	}
+}
+
+func TestThen(t *testing.T) {
+	first := New([]rune("abc"))
+	first.Insert(0, "xx")
+	out, m1, _ := first.Apply()
+
+	second := New(out)
+	second.Replace(2, 3, "AA")
+	out, m2, _ := second.Apply()
+	if string(out) != "xxAAbc" {
+		t.Fatalf("second = %q", string(out))
+	}
+
+	m := m1.Then(m2)
+	if got := m.Offset(2, Left); got != 5 {
+		t.Errorf("Offset(2) = %d", got)
+	}
+	if from, to, ok := m.Range(1, 3); !ok || from != 4 || to != 6 {
+		t.Errorf("Range(1, 3) = %d, %d, %v", from, to, ok)
+	}
+	if got := (Map{}).Offset(7, Right); got != 7 {
+		t.Error("zero Map is not the identity")
+	}
+}
+
+func TestApplyOverlap(t *testing.T) {
+	tests := []struct {
+		name  string
+		edits []Edit
+		want  string // empty when the edits overlap
+	}{
+		{"reversed", []Edit{{8, 10, "B"}, {0, 2, "A"}}, "AcdefghB"},
+		{"insertion after replacement", []Edit{{2, 5, "R"}, {2, 2, "I"}}, "abIRfghij"},
+		{"insertion at end", []Edit{{2, 5, "R"}, {5, 5, "I"}}, "abRIfghij"},
+		{"nested", []Edit{{1, 9, "X"}, {0, 1, "A"}, {4, 5, "Y"}}, ""},
+		{"overlap added later", []Edit{{6, 8, "X"}, {0, 1, "A"}, {3, 7, "Y"}}, ""},
+		{"out of range", []Edit{{9, 11, "X"}}, ""},
+	}
+	for _, test := range tests {
+		r := New([]rune("abcdefghij"))
+		for _, edit := range test.edits {
+			r.Replace(edit.From, edit.To, edit.Text)
+		}
+		out, _, err := r.Apply()
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("%s: applied as %q", test.name, string(out))
+			}
+		} else if err != nil || string(out) != test.want {
+			t.Errorf("%s: Apply = %q, %v, want %q", test.name, string(out), err, test.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package schema
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Migration converts an encoding of one version of a schema into the next version.
+type Migration func(data []byte) ([]byte, error)
+
+// Schema versions an encoding. Encoded data starts with a header of the name, a NUL and the version
+// as a uvarint, data without the header is of version 0, written before the encoding was versioned.
+type Schema struct {
+	Name    string
+	Version int // bumped by every change decoders of the previous version cannot read
+
+	migrations map[int]Migration
+}
+
+func New(name string, version int) *Schema {
+	return &Schema{Name: name, Version: version, migrations: map[int]Migration{}}
+}
+
+// Migrate registers the migration of data of version from to version from+1.
+// Migrations are registered at initialization, they are not synchronized.
+func (s *Schema) Migrate(from int, m Migration) { s.migrations[from] = m }
+
+// VersionError reports data of a version that cannot be migrated to the version of the schema, callers
+// regenerate such data rather than decoding it.
+type VersionError struct {
+	Schema     string
+	Have, Want int
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("%s: encoding version %d, want %d", e.Schema, e.Have, e.Want)
+}
+
+// WriteHeader writes the header of the current version.
+func (s *Schema) WriteHeader(w io.Writer) error {
+	header := append([]byte(s.Name), 0)
+	header = binary.AppendUvarint(header, uint64(s.Version))
+	_, err := w.Write(header)
+	return err
+}
+
+// ReadHeader reads the header of data from r and returns its version and the reader of the payload.
+func (s *Schema) ReadHeader(r io.Reader) (int, io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic := append([]byte(s.Name), 0)
+	if peek, err := br.Peek(len(magic)); err != nil || !bytes.Equal(peek, magic) {
+		return 0, br, nil
+	}
+	_, _ = br.Discard(len(magic))
+	version, err := binary.ReadUvarint(br)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s: reading header: %w", s.Name, err)
+	}
+	return int(version), br, nil
+}
+
+// Open checks the header of data from r and returns the reader of its payload in the current version,
+// running the migrations from the version of the data. Data of a later version, or of an earlier version
+// missing a migration, is a *VersionError.
+func (s *Schema) Open(r io.Reader) (io.Reader, error) {
+	version, payload, err := s.ReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if version == s.Version {
+		return payload, nil
+	}
+	if version > s.Version {
+		return nil, &VersionError{Schema: s.Name, Have: version, Want: s.Version}
+	}
+	for v := version; v < s.Version; v++ {
+		if s.migrations[v] == nil {
+			return nil, &VersionError{Schema: s.Name, Have: version, Want: s.Version}
+		}
+	}
+
+	data, err := io.ReadAll(payload)
+	if err != nil {
+		return nil, err
+	}
+	for v := version; v < s.Version; v++ {
+		if data, err = s.migrations[v](data); err != nil {
+			return nil, fmt.Errorf("%s: migrating from version %d: %w", s.Name, v, err)
+		}
+	}
+	return bytes.NewReader(data), nil
+}
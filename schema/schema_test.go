@@ -0,0 +1,52 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func encode(s *Schema, payload string) []byte {
+	var b bytes.Buffer
+	_ = s.WriteHeader(&b)
+	b.WriteString(payload)
+	return b.Bytes()
+}
+
+func open(s *Schema, data []byte) (string, error) {
+	r, err := s.Open(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	payload, err := io.ReadAll(r)
+	return string(payload), err
+}
+
+func TestSchema(t *testing.T) {
+	v1 := New("test", 1)
+	v3 := New("test", 3)
+	v3.Migrate(1, func(data []byte) ([]byte, error) { return append(data, '2'), nil })
+	v3.Migrate(2, func(data []byte) ([]byte, error) { return []byte(strings.ToUpper(string(data))), nil })
+
+	if payload, err := open(v1, encode(v1, "data")); err != nil || payload != "data" {
+		t.Errorf("same version: %q, %v", payload, err)
+	}
+	if payload, err := open(v3, encode(v1, "data")); err != nil || payload != "DATA2" {
+		t.Errorf("migrated: %q, %v", payload, err)
+	}
+
+	var verr *VersionError
+	if _, err := open(v1, encode(v3, "data")); !errors.As(err, &verr) || verr.Have != 3 || verr.Want != 1 {
+		t.Errorf("later version: %v", err)
+	}
+	// Data without header is of version 0, which has no migration.
+	if _, err := open(v3, []byte("legacy")); !errors.As(err, &verr) || verr.Have != 0 {
+		t.Errorf("unversioned: %v", err)
+	}
+}
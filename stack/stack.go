@@ -4,10 +4,37 @@
 
 package stack
 
-func Pop[T any](arr []T) []T {
-	return arr[:len(arr)-2]
+// Stack is a LIFO of values, the zero Stack is empty and ready to use.
+type Stack[T any] struct {
+	items []T
 }
 
-func Top[T any](arr []T) T {
-	return arr[len(arr)-1]
+func (s *Stack[T]) Push(v T) { s.items = append(s.items, v) }
+
+// Pop removes and returns the top value, panics if the stack is empty.
+func (s *Stack[T]) Pop() T {
+	if len(s.items) == 0 {
+		panic("stack: pop of an empty stack")
+	}
+	top := s.items[len(s.items)-1]
+	var zero T
+	s.items[len(s.items)-1] = zero // drop the reference
+	s.items = s.items[:len(s.items)-1]
+	return top
+}
+
+// Peek returns the top value without removing it, panics if the stack is empty.
+func (s *Stack[T]) Peek() T {
+	if len(s.items) == 0 {
+		panic("stack: peek of an empty stack")
+	}
+	return s.items[len(s.items)-1]
 }
+
+func (s *Stack[T]) Len() int { return len(s.items) }
+
+func (s *Stack[T]) Empty() bool { return len(s.items) == 0 }
+
+// Items returns the values from the bottom to the top. The slice is shared with the stack, it is valid until
+// the stack is modified.
+func (s *Stack[T]) Items() []T { return s.items }
@@ -0,0 +1,40 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package stack
+
+import "testing"
+
+func TestStack(t *testing.T) {
+	var s Stack[int]
+	if !s.Empty() || s.Len() != 0 {
+		t.Fatal("zero stack not empty")
+	}
+
+	s.Push(1)
+	s.Push(2)
+	s.Push(3)
+	if s.Len() != 3 || s.Peek() != 3 {
+		t.Fatalf("len %d, top %d", s.Len(), s.Peek())
+	}
+	if items := s.Items(); len(items) != 3 || items[0] != 1 {
+		t.Errorf("items %v", items)
+	}
+
+	for _, want := range []int{3, 2, 1} {
+		if got := s.Pop(); got != want {
+			t.Errorf("popped %d, want %d", got, want)
+		}
+	}
+	if !s.Empty() {
+		t.Error("stack not empty after popping every value")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic popping an empty stack")
+		}
+	}()
+	s.Pop()
+}
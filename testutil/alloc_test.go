@@ -0,0 +1,68 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package testutil_test
+
+import (
+	"cee/parser"
+	"cee/token"
+	"strings"
+	"testing"
+)
+
+// idents is a single line of identifiers and keywords, all of it is scanned on the fast path.
+var idents = []rune(strings.Repeat("fun main return value_1 if else x ", 1000))
+
+// mixed is a realistic line of code: identifiers, operators, delimiters, numbers, strings and a comment.
+var mixed = []rune(strings.Repeat("\tval total = xs[i] * 2 + f(a, \"b\") <<= 0x1F // sum\n", 1000))
+
+// Scanning must not allocate per token: what remains is the parser, the file and the growth of the result.
+func TestScanCompactAllocs(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		src    []rune
+		tokens int
+	}{
+		{"idents", idents, 7 * 1000},
+		{"mixed", mixed, 19 * 1000},
+	} {
+		fset := token.NewFileSet()
+		var n int
+		allocs := testing.AllocsPerRun(10, func() {
+			toks, err := parser.ScanCompact(fset.AddFile("", -1, len(test.src)), test.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n = len(toks)
+		})
+		if n != test.tokens {
+			t.Errorf("%s: %d tokens, want %d", test.name, n, test.tokens)
+		}
+		if allocs > 64 {
+			t.Errorf("%s: %v allocations for %d tokens", test.name, allocs, n)
+		}
+	}
+}
+
+func BenchmarkScanCompactIdents(b *testing.B) {
+	b.SetBytes(int64(len(idents)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		fset := token.NewFileSet()
+		if _, err := parser.ScanCompact(fset.AddFile("", -1, len(idents)), idents); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkScanCompactMixed(b *testing.B) {
+	b.SetBytes(int64(len(mixed)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		fset := token.NewFileSet()
+		if _, err := parser.ScanCompact(fset.AddFile("", -1, len(mixed)), mixed); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package testutil_test
+
+import (
+	"cee/build"
+	"cee/parser"
+	"cee/testutil"
+	"cee/token"
+	"fmt"
+	"os"
+	"runtime"
+	"testing"
+)
+
+// corpus returns the sources under $CEE_CORPUS, or a generated program when it is unset.
+func corpus(b *testing.B, opts testutil.GenOptions) []testutil.Source {
+	if dir := os.Getenv("CEE_CORPUS"); dir != "" {
+		sources, err := testutil.LoadCorpus(dir)
+		if err != nil {
+			b.Fatal(err)
+		}
+		return sources
+	}
+	src := testutil.Generate(opts)
+	if len(src) > maxGenerated {
+		b.Fatalf("generated %d bytes for %+v, more than the %d a benchmark may scan", len(src), opts, maxGenerated)
+	}
+	return []testutil.Source{{Path: "generated.cee", Content: src}}
+}
+
+// maxGenerated bounds the size of a generated program, the parser holds several times as much in memory.
+const maxGenerated = 16 << 20
+
+// sizes of the generated programs. The size grows with Funcs × Stmts^Depth × ExprLen, the large counts
+// come with shallow functions.
+var sizes = []testutil.GenOptions{
+	{Funcs: 100},
+	{Funcs: 1000, Stmts: 4, Depth: 2},
+	{Funcs: 10, Depth: 12, Stmts: 2},
+	{Funcs: 100, Stmts: 4, Depth: 2, ExprLen: 256},
+}
+
+// benchmarkScan runs scan over the sources and reports the memory allocated per million tokens.
+func benchmarkScan(b *testing.B, scan func(file *token.File, buffer []rune) (int, error)) {
+	for _, opts := range sizes {
+		sources := corpus(b, opts)
+		b.Run(fmt.Sprintf("funcs=%d,depth=%d,expr=%d", opts.Funcs, opts.Depth, opts.ExprLen), func(b *testing.B) {
+			b.SetBytes(testutil.Size(sources))
+			b.ReportAllocs()
+
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			tokens := 0
+			for i := 0; i < b.N; i++ {
+				fset := token.NewFileSet()
+				for _, src := range sources {
+					buffer := []rune(string(src.Content))
+					n, err := scan(fset.AddFile(src.Path, -1, len(buffer)), buffer)
+					if err != nil {
+						b.Fatal(err)
+					}
+					tokens += n
+				}
+			}
+			runtime.ReadMemStats(&after)
+
+			if tokens != 0 {
+				b.ReportMetric(float64(after.TotalAlloc-before.TotalAlloc)/float64(tokens)*1e6, "B/Mtok")
+			}
+		})
+	}
+}
+
+func BenchmarkScan(b *testing.B) {
+	benchmarkScan(b, func(file *token.File, buffer []rune) (int, error) {
+		toks, err := parser.ScanAll(file, buffer)
+		return len(toks), err
+	})
+}
+
+func BenchmarkScanCompact(b *testing.B) {
+	benchmarkScan(b, func(file *token.File, buffer []rune) (int, error) {
+		toks, err := parser.ScanCompact(file, buffer)
+		return len(toks), err
+	})
+}
+
+func BenchmarkParse(b *testing.B) {
+	for _, opts := range sizes {
+		sources := corpus(b, opts)
+		b.Run(fmt.Sprintf("funcs=%d,depth=%d,expr=%d", opts.Funcs, opts.Depth, opts.ExprLen), func(b *testing.B) {
+			b.SetBytes(testutil.Size(sources))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				fset := token.NewFileSet()
+				for _, src := range sources {
+					if f := build.ParseFile(fset, src.Path, src.Content, parser.Options{}); f.Err != nil {
+						b.Fatal(f.Err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package testutil
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const SourceExt = ".cee"
+
+type Source struct {
+	Path    string
+	Content []byte
+}
+
+// Size returns the total size of the sources in bytes, e.g. for testing.B.SetBytes.
+func Size(sources []Source) int64 {
+	var n int64
+	for _, src := range sources {
+		n += int64(len(src.Content))
+	}
+	return n
+}
+
+// LoadCorpus reads every source file under dir, ordered by path.
+func LoadCorpus(dir string) ([]Source, error) {
+	var sources []Source
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != SourceExt {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, Source{Path: path, Content: content})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Path < sources[j].Path })
+	return sources, nil
+}
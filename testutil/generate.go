@@ -0,0 +1,137 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package testutil
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// GenOptions shapes a synthetic program, zero fields take the defaults of DefaultGenOptions.
+type GenOptions struct {
+	Funcs   int   // top-level functions
+	Stmts   int   // statements per block
+	Depth   int   // nesting of branches and loops, the size grows exponentially with it
+	ExprLen int   // operands of the longest expression
+	Seed    int64 // the same options always yield the same program
+}
+
+var DefaultGenOptions = GenOptions{Funcs: 100, Stmts: 8, Depth: 3, ExprLen: 8, Seed: 1}
+
+type generator struct {
+	GenOptions
+	rand  *rand.Rand
+	buf   bytes.Buffer
+	vars  []string // variables in scope
+	fresh int      // functions declared so far
+}
+
+// Generate writes a syntactically valid program exercising declarations, calls, branches, loops
+// and long binary expressions. Functions call each other, so resolution has work to do as well.
+func Generate(opts GenOptions) []byte {
+	if opts.Funcs <= 0 {
+		opts.Funcs = DefaultGenOptions.Funcs
+	}
+	if opts.Stmts <= 0 {
+		opts.Stmts = DefaultGenOptions.Stmts
+	}
+	if opts.Depth <= 0 {
+		opts.Depth = DefaultGenOptions.Depth
+	}
+	if opts.ExprLen <= 0 {
+		opts.ExprLen = DefaultGenOptions.ExprLen
+	}
+
+	g := generator{GenOptions: opts, rand: rand.New(rand.NewSource(opts.Seed))}
+	for i := 0; i < opts.Funcs; i++ {
+		g.fun(i)
+	}
+	return g.buf.Bytes()
+}
+
+func (g *generator) line(depth int, a ...any) {
+	g.buf.WriteString(strings.Repeat("\t", depth))
+	fmt.Fprint(&g.buf, a...)
+	g.buf.WriteByte('\n')
+}
+
+func (g *generator) fun(i int) {
+	g.vars = []string{"a", "b"}
+	g.fresh = i
+	g.line(0, "// f", i, " is generated.")
+	g.line(0, "fun f", i, "(a i64, b i64) i64 {")
+	g.block(1, g.Depth)
+	g.line(1, "return ", g.expr(g.ExprLen))
+	g.line(0, "}")
+	g.line(0)
+}
+
+func (g *generator) operand() string {
+	switch g.rand.Intn(4) {
+	case 0:
+		return fmt.Sprint(g.rand.Intn(1000))
+	case 1:
+		if g.fresh > 0 {
+			// Calls only go to earlier functions, there is no recursion.
+			return fmt.Sprint("f", g.rand.Intn(g.fresh), "(", g.vars[g.rand.Intn(len(g.vars))], ", 1)")
+		}
+	}
+	return g.vars[g.rand.Intn(len(g.vars))]
+}
+
+var binaryOperators = []string{"+", "-", "*", "/", "%", "<<", ">>"}
+
+func (g *generator) expr(n int) string {
+	var b strings.Builder
+	b.WriteString(g.operand())
+	for i := 1; i < n; i++ {
+		b.WriteString(" " + binaryOperators[g.rand.Intn(len(binaryOperators))] + " ")
+		if g.rand.Intn(4) == 0 && n-i > 2 {
+			// Parenthesized sub-expressions add depth to the expression tree.
+			m := 2 + g.rand.Intn(n-i-1)
+			b.WriteString("(" + g.expr(m) + ")")
+			i += m - 1
+			continue
+		}
+		b.WriteString(g.operand())
+	}
+	return b.String()
+}
+
+func (g *generator) block(indent, depth int) {
+	scope := len(g.vars)
+	defer func() { g.vars = g.vars[:scope] }()
+
+	for i := 0; i < g.Stmts; i++ {
+		kind := g.rand.Intn(5)
+		if depth == 0 {
+			kind %= 2
+		}
+		switch kind {
+		case 0:
+			name := fmt.Sprint("v", len(g.vars))
+			g.line(indent, "var ", name, " = ", g.expr(1+g.rand.Intn(g.ExprLen)))
+			g.vars = append(g.vars, name)
+		case 1:
+			g.line(indent, g.vars[g.rand.Intn(len(g.vars))], " += ", g.expr(1+g.rand.Intn(g.ExprLen)))
+		case 2:
+			g.line(indent, "if ", g.expr(2), " < ", g.expr(2), " {")
+			g.block(indent+1, depth-1)
+			g.line(indent, "} else {")
+			g.block(indent+1, depth-1)
+			g.line(indent, "}")
+		case 3:
+			g.line(indent, "for ", g.vars[g.rand.Intn(len(g.vars))], " < ", g.expr(2), " {")
+			g.block(indent+1, depth-1)
+			g.line(indent, "}")
+		case 4:
+			g.line(indent, "{")
+			g.block(indent+1, depth-1)
+			g.line(indent, "}")
+		}
+	}
+}
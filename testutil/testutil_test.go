@@ -0,0 +1,65 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package testutil
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateDeterministic(t *testing.T) {
+	opts := GenOptions{Funcs: 20, Seed: 42}
+	a, b := Generate(opts), Generate(opts)
+	if !bytes.Equal(a, b) {
+		t.Fatal("same options generated different programs")
+	}
+	if got := strings.Count(string(a), "fun f"); got != opts.Funcs {
+		t.Errorf("generated %d functions, want %d", got, opts.Funcs)
+	}
+	if bytes.Equal(a, Generate(GenOptions{Funcs: 20, Seed: 43})) {
+		t.Error("different seeds generated the same program")
+	}
+}
+
+func TestGenerateBalanced(t *testing.T) {
+	src := string(Generate(GenOptions{Funcs: 10, Depth: 5, Stmts: 4, ExprLen: 16}))
+	for _, pair := range []string{"{}", "()"} {
+		if open, closed := strings.Count(src, pair[:1]), strings.Count(src, pair[1:]); open != closed {
+			t.Errorf("%d %q but %d %q", open, pair[:1], closed, pair[1:])
+		}
+	}
+}
+
+func TestLoadCorpus(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"b.cee":       "fun b() {}",
+		"sub/a.cee":   "fun a() {}",
+		"sub/skip.go": "package skip",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sources, err := LoadCorpus(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 2 || filepath.Base(sources[0].Path) != "b.cee" || filepath.Base(sources[1].Path) != "a.cee" {
+		t.Fatalf("unexpected corpus %v", sources)
+	}
+	if Size(sources) != int64(len(files["b.cee"])+len(files["sub/a.cee"])) {
+		t.Errorf("size %d", Size(sources))
+	}
+}
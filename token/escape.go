@@ -0,0 +1,189 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package token
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Escapes maps the character following a backslash to the character it stands for.
+// Numeric escapes are not in the table: octal `\0`-`\377`, `\xhh`, `\uhhhh` and `\Uhhhhhhhh`.
+// `\0` is the shortest octal escape.
+var Escapes = map[rune]rune{
+	'a':  '\a',
+	'b':  '\b',
+	'e':  0x1b,
+	'f':  '\f',
+	'n':  '\n',
+	'r':  '\r',
+	't':  '\t',
+	'v':  '\v',
+	'\\': '\\',
+	'\'': '\'',
+	'"':  '"',
+}
+
+var ErrSyntax = errors.New("invalid syntax")
+
+func digitVal(r rune) int {
+	switch {
+	case '0' <= r && r <= '9':
+		return int(r - '0')
+	case 'a' <= r && r <= 'f':
+		return int(r - 'a' + 10)
+	case 'A' <= r && r <= 'F':
+		return int(r - 'A' + 10)
+	}
+	return 16
+}
+
+// UnescapeChar decodes the escape sequence at the start of s, which begins after the backslash,
+// and returns the character and the number of runes consumed.
+func UnescapeChar(s []rune) (rune, int, error) {
+	if len(s) == 0 {
+		return 0, 0, ErrSyntax
+	}
+	if r, ok := Escapes[s[0]]; ok {
+		return r, 1, nil
+	}
+
+	var base, max, n int
+	switch s[0] {
+	case 'x':
+		base, max, n = 16, 2, 1
+	case 'u':
+		base, max, n = 16, 4, 1
+	case 'U':
+		base, max, n = 16, 8, 1
+	default:
+		if s[0] < '0' || s[0] > '7' {
+			return 0, 0, ErrSyntax
+		}
+		base, max = 8, 3
+	}
+
+	var (
+		value  rune
+		digits int
+	)
+	for ; digits < max && n < len(s) && digitVal(s[n]) < base; digits, n = digits+1, n+1 {
+		value = value*rune(base) + rune(digitVal(s[n]))
+	}
+	switch {
+	case base == 16 && digits != max, base == 8 && value > 0377:
+		return 0, 0, ErrSyntax
+	case value > unicode.MaxRune || 0xd800 <= value && value < 0xe000:
+		return 0, 0, ErrSyntax
+	}
+	return value, n, nil
+}
+
+// Unquote decodes a quoted string or char literal. Raw strings between backticks are taken as they are,
+// carriage returns excepted so that their value does not depend on the line endings of the file.
+func Unquote(lit string) (string, error) {
+	if len(lit) >= 2 && lit[0] == '`' {
+		if lit[len(lit)-1] != '`' || strings.ContainsRune(lit[1:len(lit)-1], '`') {
+			return "", ErrSyntax
+		}
+		return strings.ReplaceAll(lit[1:len(lit)-1], "\r", ""), nil
+	}
+	s := []rune(lit)
+	if len(s) < 2 || s[0] != s[len(s)-1] || s[0] != '"' && s[0] != '\'' {
+		return "", ErrSyntax
+	}
+	quote, s := s[0], s[1:len(s)-1]
+
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '\\':
+			if n := continuation(s[i+1:]); n != 0 {
+				i += 1 + n
+				break
+			}
+			r, n, err := UnescapeChar(s[i+1:])
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+			i += 1 + n
+		case quote, '\n':
+			return "", ErrSyntax
+		default:
+			b.WriteRune(s[i])
+			i++
+		}
+	}
+	if quote == '\'' && utf8.RuneCountInString(b.String()) != 1 {
+		return "", ErrSyntax
+	}
+	return b.String(), nil
+}
+
+// continuation returns the length of the line break following a backslash, which continues a string on the
+// next line and is dropped from its value, 0 for other escapes.
+func continuation(s []rune) int {
+	switch {
+	case len(s) >= 1 && s[0] == '\n':
+		return 1
+	case len(s) >= 2 && s[0] == '\r' && s[1] == '\n':
+		return 2
+	}
+	return 0
+}
+
+// shortEscape finds the escape of Escapes standing for r, the least one if there are several.
+func shortEscape(r rune) (rune, bool) {
+	var (
+		escape rune
+		found  bool
+	)
+	for c, v := range Escapes {
+		if v == r && (!found || c < escape) {
+			escape, found = c, true
+		}
+	}
+	return escape, found
+}
+
+// Quote returns the double-quoted literal of s, which Unquote decodes back to s.
+// Control characters use the short escapes of Escapes when there is one.
+func Quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case unicode.IsPrint(r):
+			b.WriteRune(r)
+		default:
+			if c, ok := shortEscape(r); ok {
+				b.WriteByte('\\')
+				b.WriteRune(c)
+				continue
+			}
+			var digits string
+			switch {
+			case r < 0x100:
+				digits = "\\x" + pad(strconv.FormatInt(int64(r), 16), 2)
+			case r < 0x10000:
+				digits = "\\u" + pad(strconv.FormatInt(int64(r), 16), 4)
+			default:
+				digits = "\\U" + pad(strconv.FormatInt(int64(r), 16), 8)
+			}
+			b.WriteString(digits)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func pad(s string, n int) string { return strings.Repeat("0", n-len(s)) + s }
@@ -0,0 +1,46 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package token
+
+import "testing"
+
+func TestUnquote(t *testing.T) {
+	tests := []struct {
+		lit, want string
+	}{
+		{`"a\tb"`, "a\tb"},
+		{`"\0"`, "\x00"},
+		{`"\a\b\f\v\e"`, "\a\b\f\v\x1b"},
+		{`"\101\0101"`, "A\b1"},
+		{`"\x41é\U0001F600"`, "Aé😀"},
+		{`'\''`, "'"},
+		{`"\""`, `"`},
+		{"`a\\n\r\nb`", "a\\n\nb"},
+		{"\"a\\\nb\\\r\nc\"", "abc"},
+	}
+	for _, test := range tests {
+		if got, err := Unquote(test.lit); err != nil || got != test.want {
+			t.Errorf("Unquote(%s) = %q, %v, want %q", test.lit, got, err, test.want)
+		}
+	}
+
+	for _, lit := range []string{`"\400"`, `"\x4"`, `"\q"`, `"a`, `'ab'`, `"\ud800"`, "`a", "`a`b`"} {
+		if _, err := Unquote(lit); err == nil {
+			t.Errorf("Unquote(%s) succeeded", lit)
+		}
+	}
+}
+
+func TestQuoteRoundTrip(t *testing.T) {
+	for _, s := range []string{"", "plain", "tab\tnew\nline", "\x00\a\b\f\v\x1b", `"quoted" \ back`, "é😀", "​\U000e0001"} {
+		lit := Quote(s)
+		if got, err := Unquote(lit); err != nil || got != s {
+			t.Errorf("Unquote(Quote(%q)) = %q, %v via %s", s, got, err, lit)
+		}
+	}
+	if got := Quote("\x1b[0m\a"); got != `"\e[0m\a"` {
+		t.Errorf("Quote = %s", got)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+//go:build ignore
+
+// Generates nfc_tables.go from the Unicode Character Database:
+//
+//	go run gen_nfc.go UnicodeData.txt CompositionExclusions.txt > nfc_tables.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func fields(path string, f func(fields []string)) {
+	file, err := os.Open(path)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+	s := bufio.NewScanner(file)
+	for s.Scan() {
+		line, _, _ := strings.Cut(s.Text(), "#")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		parts := strings.Split(line, ";")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		f(parts)
+	}
+	if err := s.Err(); err != nil {
+		panic(err)
+	}
+}
+
+func hex(s string) rune {
+	r, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		panic(err)
+	}
+	return rune(r)
+}
+
+func main() {
+	var (
+		decompositions = map[rune][]rune{}
+		classes        = map[rune]uint64{}
+		exclusions     = map[rune]bool{}
+	)
+	fields(os.Args[1], func(f []string) {
+		r := hex(f[0])
+		if ccc, _ := strconv.ParseUint(f[3], 10, 8); ccc != 0 {
+			classes[r] = ccc
+		}
+		// Compatibility decompositions are tagged, as in `<compat> 0020 0308`.
+		if f[5] != "" && !strings.HasPrefix(f[5], "<") {
+			for _, d := range strings.Fields(f[5]) {
+				decompositions[r] = append(decompositions[r], hex(d))
+			}
+		}
+	})
+	fields(os.Args[2], func(f []string) { exclusions[hex(f[0])] = true })
+
+	var b bytes.Buffer
+	fmt.Fprintln(&b, "// Code generated by gen_nfc.go from the Unicode Character Database. DO NOT EDIT.")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "package token")
+	fmt.Fprintln(&b)
+	write := func(name, typ, doc string, keys []rune, entry func(r rune) string) {
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		fmt.Fprintf(&b, "// %s\nvar %s = %s{\n", doc, name, typ)
+		for i, r := range keys {
+			fmt.Fprintf(&b, "0x%04X: %s,", r, entry(r))
+			if i%4 == 3 {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+		fmt.Fprintln(&b, "\n}")
+		fmt.Fprintln(&b)
+	}
+
+	var keys []rune
+	for r := range decompositions {
+		keys = append(keys, r)
+	}
+	write("decompositions", "map[rune]string", "decompositions maps runes to their canonical decomposition, Hangul syllables excepted.",
+		keys, func(r rune) string { return strconv.QuoteToASCII(string(decompositions[r])) })
+
+	keys = nil
+	for r := range classes {
+		keys = append(keys, r)
+	}
+	write("combiningClasses", "map[rune]uint8", "combiningClasses holds the canonical combining class of the runes with one.",
+		keys, func(r rune) string { return strconv.FormatUint(classes[r], 10) })
+
+	keys = nil
+	for r := range exclusions {
+		keys = append(keys, r)
+	}
+	write("compositionExclusions", "map[rune]bool", "compositionExclusions lists the runes canonical composition never produces, besides singletons and non-starter decompositions.",
+		keys, func(r rune) string { return "true" })
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		panic(err)
+	}
+	os.Stdout.Write(src)
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package token
+
+//go:generate go run gen_nfc.go UnicodeData.txt CompositionExclusions.txt > nfc_tables.go
+
+import "sort"
+
+// Hangul syllables are decomposed and composed arithmetically.
+const (
+	hangulBase  = 0xAC00
+	hangulL     = 0x1100
+	hangulV     = 0x1161
+	hangulT     = 0x11A7
+	hangulVN    = 21
+	hangulTN    = 28
+	hangulNN    = hangulVN * hangulTN
+	hangulCount = 19 * hangulNN
+)
+
+// compositions maps the pairs composing into the runes canonical composition produces.
+var compositions = map[[2]rune]rune{}
+
+func init() {
+	for r, d := range decompositions {
+		pair := []rune(d)
+		if len(pair) != 2 || compositionExclusions[r] || combiningClasses[r] != 0 || combiningClasses[pair[0]] != 0 {
+			continue
+		}
+		compositions[[2]rune{pair[0], pair[1]}] = r
+	}
+}
+
+func decompose(r rune, to []rune) []rune {
+	if s := r - hangulBase; 0 <= s && s < hangulCount {
+		to = append(to, hangulL+s/hangulNN, hangulV+s%hangulNN/hangulTN)
+		if t := s % hangulTN; t != 0 {
+			to = append(to, hangulT+t)
+		}
+		return to
+	}
+	d, ok := decompositions[r]
+	if !ok {
+		return append(to, r)
+	}
+	for _, r := range d {
+		to = decompose(r, to)
+	}
+	return to
+}
+
+func compose(a, b rune) (rune, bool) {
+	if l, v := a-hangulL, b-hangulV; 0 <= l && l < 19 && 0 <= v && v < hangulVN {
+		return hangulBase + (l*hangulVN+v)*hangulTN, true
+	}
+	if s, t := a-hangulBase, b-hangulT; 0 <= s && s < hangulCount && s%hangulTN == 0 && 0 < t && t < hangulTN {
+		return a + t, true
+	}
+	r, ok := compositions[[2]rune{a, b}]
+	return r, ok
+}
+
+// NFC returns the identifier in Normalization Form C, so that identifiers spelled with precomposed
+// characters and with combining sequences are the same. ASCII identifiers are returned as they are,
+// others are returned as they are when already normalized.
+func NFC(ident []rune) []rune {
+	ascii := true
+	for _, r := range ident {
+		if r >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return ident
+	}
+
+	var d []rune
+	for _, r := range ident {
+		d = decompose(r, d)
+	}
+	// Canonical ordering sorts each run of combining marks by class.
+	for i := 0; i < len(d); {
+		j := i
+		for j < len(d) && combiningClasses[d[j]] != 0 {
+			j++
+		}
+		if j > i {
+			run := d[i:j]
+			sort.SliceStable(run, func(a, b int) bool { return combiningClasses[run[a]] < combiningClasses[run[b]] })
+			i = j
+		} else {
+			i++
+		}
+	}
+
+	// A mark composes with the last starter unless a mark of the same or no class is between them.
+	out := d[:0]
+	starter := -1
+	var last uint8
+	for _, r := range d {
+		class := combiningClasses[r]
+		if starter >= 0 && (last < class || last == 0 && len(out) == starter+1) {
+			if c, ok := compose(out[starter], r); ok {
+				out[starter] = c
+				continue
+			}
+		}
+		if class == 0 {
+			starter = len(out)
+		}
+		last = class
+		out = append(out, r)
+	}
+
+	if string(out) == string(ident) {
+		return ident
+	}
+	return out
+}
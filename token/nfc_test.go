@@ -0,0 +1,42 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package token
+
+import "testing"
+
+func TestNFC(t *testing.T) {
+	tests := []struct {
+		ident, want string
+	}{
+		{"name", "name"},
+		{"café", "café"},
+		{"café", "café"},
+		{"Å", "Å"},   // singleton decomposition
+		{"ậ", "ậ"}, // marks reordered by class
+		{"ậ", "ậ"},
+		{"각", "각"},  // Hangul jamo
+		{"क़", "क़"},   // composition exclusion
+		{"é́", "é́"}, // a mark of the same class is blocked
+	}
+	for _, test := range tests {
+		if got := string(NFC([]rune(test.ident))); got != test.want {
+			t.Errorf("NFC(%+q) = %+q, want %+q", test.ident, got, test.want)
+		}
+	}
+
+	ident := []rune("café")
+	if got := NFC(ident); &got[0] != &ident[0] {
+		t.Error("NFC copied a normalized identifier")
+	}
+}
+
+func TestSkeleton(t *testing.T) {
+	if Skeleton("pаy") != Skeleton("pay") || Skeleton("СОДE") == Skeleton("CODE") {
+		t.Error("Skeleton of Cyrillic letters")
+	}
+	if Skeleton("οk") != "ok" || Skeleton("café") != "café" {
+		t.Error("Skeleton")
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package token
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Pos is a compact encoding of a source position, the base of its file plus the rune offset within it.
+// Pos values of the same file compare like offsets, decoding to line and column goes through the FileSet.
+type Pos int32
+
+const NoPos Pos = 0
+
+func (p Pos) IsValid() bool { return p != NoPos }
+
+// Position is a decoded position, Offset, Line and Column are zero-based.
+type Position struct {
+	Filename string
+	Offset   int
+	Line     int
+	Column   int
+}
+
+func (pos Position) IsValid() bool { return pos.Line >= 0 && pos.Offset >= 0 }
+
+// String formats the position as file:line:column, one-based as editors expect.
+func (pos Position) String() string {
+	s := fmt.Sprint(pos.Line+1, ":", pos.Column+1)
+	if pos.Filename != "" {
+		s = pos.Filename + ":" + s
+	}
+	return s
+}
+
+// File is a source file in a FileSet, positions [Base, Base+Size] belong to it.
+type File struct {
+	name string
+	base int
+	size int
+
+	mutex   sync.Mutex
+	lines   []int  // offsets of the first rune of each line
+	content []rune // optional, retained to materialize literals of compact tokens
+}
+
+func (f *File) Name() string { return f.name }
+func (f *File) Base() int    { return f.base }
+func (f *File) Size() int    { return f.size }
+
+func (f *File) LineCount() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return len(f.lines)
+}
+
+// AddLine records the offset of a line start, offsets must be added in increasing order.
+func (f *File) AddLine(offset int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if n := len(f.lines); (n == 0 || f.lines[n-1] < offset) && offset <= f.size {
+		f.lines = append(f.lines, offset)
+	}
+}
+
+// SetLinesForContent replaces the line table with the line starts of content.
+func (f *File) SetLinesForContent(content []rune) {
+	lines := []int{0}
+	for offset, r := range content {
+		if r == '\n' && offset+1 <= f.size {
+			lines = append(lines, offset+1)
+		}
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.lines = lines
+}
+
+// SetContent retains content, which is not copied, and sets the line table from it.
+func (f *File) SetContent(content []rune) {
+	f.SetLinesForContent(content)
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.content = content
+}
+
+// Content returns the content set with SetContent, nil if there is none.
+func (f *File) Content() []rune {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.content
+}
+
+// Text returns the source text of [from, to), both positions of the file.
+// The text is empty if the content of the file is not retained.
+func (f *File) Text(from, to Pos) string {
+	content := f.Content()
+	if content == nil {
+		return ""
+	}
+	return string(content[f.Offset(from):f.Offset(to)])
+}
+
+// Pos returns the Pos of a rune offset, panics if the offset is out of the file.
+func (f *File) Pos(offset int) Pos {
+	if offset < 0 || offset > f.size {
+		panic(fmt.Sprintf("token: offset %d out of range [0, %d]", offset, f.size))
+	}
+	return Pos(f.base + offset)
+}
+
+// Offset returns the rune offset of a Pos, panics if the Pos does not belong to the file.
+func (f *File) Offset(p Pos) int {
+	if int(p) < f.base || int(p) > f.base+f.size {
+		panic(fmt.Sprintf("token: pos %d out of file range [%d, %d]", p, f.base, f.base+f.size))
+	}
+	return int(p) - f.base
+}
+
+// Contains reports whether p is a position of the file, the end of file position included.
+func (f *File) Contains(p Pos) bool { return int(p) >= f.base && int(p) <= f.base+f.size }
+
+// Position decodes a Pos of the file, the zero Position is returned for NoPos.
+func (f *File) Position(p Pos) Position {
+	if !p.IsValid() {
+		return Position{}
+	}
+	offset := f.Offset(p)
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	line := sort.Search(len(f.lines), func(i int) bool { return f.lines[i] > offset }) - 1
+	column := offset
+	if line >= 0 {
+		column -= f.lines[line]
+	} else {
+		line = 0
+	}
+	return Position{Filename: f.name, Offset: offset, Line: line, Column: column}
+}
+
+// FileSet allocates disjoint position ranges to files, it is safe for concurrent use.
+type FileSet struct {
+	mutex sync.RWMutex
+	base  int
+	files []*File // sorted by base
+}
+
+func NewFileSet() *FileSet {
+	return &FileSet{base: 1}
+}
+
+// Base returns the minimum base of the next added file.
+func (s *FileSet) Base() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.base
+}
+
+// AddFile adds a file of size runes, a negative base takes the current Base of the set.
+// The end of file position is valid, so the next base is at least base+size+1. AddFile panics when the
+// positions of the file would not fit into a Pos.
+func (s *FileSet) AddFile(name string, base, size int) *File {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if base < 0 {
+		base = s.base
+	}
+	if base < s.base || size < 0 {
+		panic(fmt.Sprintf("token: invalid base %d or size %d for file %s", base, size, name))
+	}
+	if base > math.MaxInt32-size {
+		panic(fmt.Sprintf("token: file %s of %d runes at base %d overflows Pos, the FileSet is full", name, size, base))
+	}
+
+	f := &File{name: name, base: base, size: size, lines: []int{0}}
+	s.base = base + size + 1
+	s.files = append(s.files, f)
+	return f
+}
+
+// File returns the file containing p, nil if there is none.
+func (s *FileSet) File(p Pos) *File {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	i := sort.Search(len(s.files), func(i int) bool { return s.files[i].base > int(p) }) - 1
+	if i >= 0 && int(p) <= s.files[i].base+s.files[i].size {
+		return s.files[i]
+	}
+	return nil
+}
+
+// Text returns the source text of [from, to), the empty string if the positions are of no file retaining its content.
+func (s *FileSet) Text(from, to Pos) string {
+	if f := s.File(from); f != nil && int(to) <= f.base+f.size {
+		return f.Text(from, to)
+	}
+	return ""
+}
+
+func (s *FileSet) Position(p Pos) Position {
+	if f := s.File(p); f != nil {
+		return f.Position(p)
+	}
+	return Position{}
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package token
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestFileSet(t *testing.T) {
+	fset := NewFileSet()
+
+	a := []rune("fun main() {\n\tval x = 1\n}\n")
+	fa := fset.AddFile("a.cee", -1, len(a))
+	fa.SetLinesForContent(a)
+
+	b := []rune("import \"io\"\n")
+	fb := fset.AddFile("b.cee", -1, len(b))
+	fb.SetLinesForContent(b)
+
+	tests := []struct {
+		pos  Pos
+		want Position
+	}{
+		{fa.Pos(0), Position{Filename: "a.cee", Offset: 0, Line: 0, Column: 0}},
+		{fa.Pos(14), Position{Filename: "a.cee", Offset: 14, Line: 1, Column: 1}},
+		{fa.Pos(len(a)), Position{Filename: "a.cee", Offset: len(a), Line: 3, Column: 0}},
+		{fb.Pos(7), Position{Filename: "b.cee", Offset: 7, Line: 0, Column: 7}},
+	}
+	for _, test := range tests {
+		if got := fset.Position(test.pos); got != test.want {
+			t.Errorf("Position(%d) = %v, want %v", test.pos, got, test.want)
+		}
+	}
+
+	if fset.File(NoPos) != nil {
+		t.Error("NoPos belongs to a file")
+	}
+	if got := fset.Position(fb.Pos(0)).String(); got != "b.cee:1:1" {
+		t.Errorf("String() = %s", got)
+	}
+}
+
+func TestFileSetText(t *testing.T) {
+	fset := NewFileSet()
+
+	a := []rune("val 名前 = 1\n")
+	fa := fset.AddFile("a.cee", -1, len(a))
+	fa.SetContent(a)
+
+	b := []rune("val y = 2\n")
+	fb := fset.AddFile("b.cee", -1, len(b))
+	fb.SetLinesForContent(b)
+
+	if got := fset.Text(fa.Pos(4), fa.Pos(6)); got != "名前" {
+		t.Errorf("Text = %q", got)
+	}
+	if got := fset.Text(fb.Pos(4), fb.Pos(5)); got != "" {
+		t.Errorf("Text of a file without content = %q", got)
+	}
+	if got := fset.Text(fa.Pos(4), fb.Pos(5)); got != "" {
+		t.Errorf("Text across files = %q", got)
+	}
+}
+
+func TestFileSetOverflow(t *testing.T) {
+	fset := NewFileSet()
+	f := fset.AddFile("full.cee", -1, math.MaxInt32-fset.Base())
+	if end := f.Pos(f.Size()); end != math.MaxInt32 {
+		t.Errorf("end of file at %d", end)
+	}
+
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "overflows Pos") {
+			t.Errorf("recovered %v", r)
+		}
+	}()
+	fset.AddFile("next.cee", -1, 0)
+	t.Error("file past math.MaxInt32 added")
+}
@@ -6,16 +6,19 @@ package token
 
 const (
 	ILLEGAL = iota
+	EOF
+	COMMENT
 
 	IDENT // main
 
 	LITERAL_BEGIN
 
-	INT    // 12345
-	FLOAT  // 123.45
-	IMAG   // 123.45i
-	CHAR   // 'a'
-	STRING // "abc"
+	INT        // 12345
+	FLOAT      // 123.45
+	IMAG       // 123.45i
+	CHAR       // 'a'
+	STRING     // "abc"
+	STRING_RAW // `abc`
 
 	LITERAL_END
 
@@ -67,8 +70,12 @@ const (
 
 	ELLIPSIS // ...
 
-	INC // ++
-	DEC // --
+	INC      // ++
+	DEC      // --
+	QUESTION // ?
+
+	AT    // @
+	ARROW // <-
 
 	AS // as
 	IN // in
@@ -83,7 +90,9 @@ const (
 
 	DEFAULT
 	DEFER
+	ERRDEFER
 	ELSE
+	EXTERN
 	FALLTHROUGH
 	FOR
 
@@ -94,8 +103,11 @@ const (
 	IMPORT
 
 	TRAIT
+	MACRO
 	MAP
+	MATCH
 	PACKAGE
+	PUB
 	RANGE
 	RETURN
 
@@ -103,6 +115,7 @@ const (
 	SELECT
 	STRUCT
 	TYPE
+	TRY
 	VAR
 	VAL
 
@@ -124,6 +137,11 @@ const (
 
 	DELIMITER_END
 
+	// Segments of an interpolated string, split around the embedded expressions.
+	STRING_HEAD // "abc${
+	STRING_MID  // }abc${
+	STRING_TAIL // }abc"
+
 	token_end
 )
 
@@ -159,8 +177,12 @@ var KeywordLiterals = [...]string{
 	LAND: "&&",
 	LOR:  "||",
 
-	INC: "++",
-	DEC: "--",
+	INC:      "++",
+	DEC:      "--",
+	QUESTION: "?",
+
+	AT:    "@",
+	ARROW: "<-",
 
 	EQL:    "==",
 	LSS:    "<",
@@ -191,10 +213,12 @@ var KeywordLiterals = [...]string{
 	CONST:    "const",
 	CONTINUE: "continue",
 
-	DEFAULT: "default",
-	DEFER:   "defer",
-	ELSE:    "else",
-	FOR:     "for",
+	DEFAULT:  "default",
+	DEFER:    "defer",
+	ERRDEFER: "errdefer",
+	ELSE:     "else",
+	EXTERN:   "extern",
+	FOR:      "for",
 
 	FUNC:   "fun",
 	GO:     "go",
@@ -203,8 +227,11 @@ var KeywordLiterals = [...]string{
 	IMPORT: "import",
 
 	TRAIT:   "interface",
+	MACRO:   "macro",
 	MAP:     "map",
+	MATCH:   "match",
 	PACKAGE: "package",
+	PUB:     "pub",
 	RANGE:   "range",
 	RETURN:  "return",
 
@@ -212,6 +239,7 @@ var KeywordLiterals = [...]string{
 	SELECT: "select",
 	STRUCT: "struct",
 	TYPE:   "type",
+	TRY:    "try",
 	VAR:    "var",
 	VAL:    "val",
 
@@ -223,16 +251,25 @@ var KeywordLiterals = [...]string{
 
 func IsLiteralValue(kind int) bool { return LITERAL_BEGIN < kind && kind < LITERAL_END }
 
+// IsString reports whether the kind is a string literal, interpreted or raw.
+func IsString(kind int) bool { return kind == STRING || kind == STRING_RAW }
+
 var PrefixUnaryOperators = [...]bool{
-	MUL: true,
-	AND: true,
+	MUL:   true,
+	AND:   true,
+	ADD:   true,
+	SUB:   true,
+	NOT:   true,
+	XOR:   true,
+	ARROW: true,
 
 	token_end: false,
 }
 
 var PostfixUnaryOperators = [...]bool{
-	INC: true,
-	DEC: true,
+	INC:      true,
+	DEC:      true,
+	QUESTION: true,
 
 	token_end: false,
 }
@@ -250,8 +287,74 @@ var BinaryOperators = [...]int{
 	token_end: 0,
 }
 
+// Precedences of the binary operators, higher binds tighter, 0 for other tokens.
+var Precedences = [...]int{
+	MUL:     5,
+	QUO:     5,
+	REM:     5,
+	SHL:     5,
+	SHR:     5,
+	AND:     5,
+	AND_NOT: 5,
+
+	ADD: 4,
+	SUB: 4,
+	OR:  4,
+	XOR: 4,
+
+	EQL: 3,
+	NEQ: 3,
+	LSS: 3,
+	LEQ: 3,
+	GTR: 3,
+	GEQ: 3,
+
+	LAND: 2,
+	LOR:  1,
+
+	token_end: 0,
+}
+
+// CompoundAssignOperators maps each compound assignment operator to its binary operator.
+var CompoundAssignOperators = [...]int{
+	ADD_ASSIGN: ADD,
+	SUB_ASSIGN: SUB,
+	MUL_ASSIGN: MUL,
+	QUO_ASSIGN: QUO,
+	REM_ASSIGN: REM,
+
+	AND_ASSIGN:     AND,
+	OR_ASSIGN:      OR,
+	XOR_ASSIGN:     XOR,
+	SHL_ASSIGN:     SHL,
+	SHR_ASSIGN:     SHR,
+	AND_NOT_ASSIGN: AND_NOT,
+
+	token_end: 0,
+}
+
+// IsStringSegment reports whether the token is a segment of an interpolated string.
+func IsStringSegment(kind int) bool { return STRING_HEAD <= kind && kind <= STRING_TAIL }
+
 func IsOperator(kind int) bool { return OPERATOR_BEGIN < kind && kind < OPERATOR_END }
 
+// Merges reports whether the operators a and b, written with no space between them, scan as other tokens,
+// as `-` `-` scans as `--` and `/` `*` as the start of a comment.
+func Merges(a, b int) bool {
+	left, right := KeywordLiterals[a], KeywordLiterals[b]
+	if left == "/" && (right[0] == '/' || right[0] == '*') {
+		return true
+	}
+	joined := left + right
+	for kind := OPERATOR_BEGIN + 1; kind < OPERATOR_END; kind++ {
+		lit := KeywordLiterals[kind]
+		if len(lit) > len(left) && len(lit) <= len(joined) && joined[:len(lit)] == lit {
+			return true
+		}
+	}
+	return false
+}
+
 var Keyword2Enum = map[string]int{}
 
 func IsKeyword(term int) bool { return KEYWORD_BEGIN <= term && term <= KEYWORD_END }
@@ -275,12 +378,21 @@ var Delimiters = map[rune]int{
 
 	'"':  1,
 	'\'': 1,
+	'`':  1,
 
 	'\n': NEWLINE, // Newline, might be a statement terminator.
 }
 
+// KeywordAliases are the other spellings of keywords.
+var KeywordAliases = map[string]int{
+	"trait": TRAIT,
+}
+
 func init() {
 	for i := 0; i < token_end; i++ {
 		Keyword2Enum[KeywordLiterals[i]] = i
 	}
+	for alias, kind := range KeywordAliases {
+		Keyword2Enum[alias] = kind
+	}
 }
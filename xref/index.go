@@ -0,0 +1,175 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package xref
+
+import (
+	"cee/ast"
+	"cee/resolve"
+	"cee/schema"
+	"cee/token"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Span is a decoded source range, it stays meaningful when the index is saved and loaded again.
+type Span struct {
+	File     string
+	From, To token.Position
+}
+
+func NewSpan(file *token.File, pos ast.PosRange) Span {
+	return Span{File: file.Name(), From: file.Position(pos.From), To: file.Position(pos.To)}
+}
+
+// SymbolID is unique within an index: package, file and offset of the declaring identifier.
+type SymbolID string
+
+func NewSymbolID(pkg string, file string, offset int) SymbolID {
+	return SymbolID(fmt.Sprint(pkg, ":", file, ":", offset))
+}
+
+type Symbol struct {
+	ID      SymbolID
+	Package string
+	Name    string
+	Kind    resolve.ObjKind
+	Def     Span
+	Refs    []Span
+}
+
+type Index struct {
+	Symbols map[SymbolID]*Symbol
+
+	// ByName lists the symbols declared with each name, for symbol search.
+	ByName map[string][]SymbolID
+	// ByFile lists the symbols defined or referenced in each file, for invalidation on edit.
+	ByFile map[string][]SymbolID
+}
+
+func NewIndex() *Index {
+	return &Index{
+		Symbols: map[SymbolID]*Symbol{},
+		ByName:  map[string][]SymbolID{},
+		ByFile:  map[string][]SymbolID{},
+	}
+}
+
+func (idx *Index) symbol(pkg string, info resolve.Info, obj *resolve.Object) *Symbol {
+	def := NewSpan(info.Files[obj.File], obj.Ident)
+
+	id := NewSymbolID(pkg, obj.File, def.From.Offset)
+	if sym, ok := idx.Symbols[id]; ok {
+		return sym
+	}
+	sym := &Symbol{
+		ID:      id,
+		Package: pkg,
+		Name:    obj.Name,
+		Kind:    obj.Kind,
+		Def:     def,
+	}
+	idx.Symbols[id] = sym
+	idx.ByName[sym.Name] = append(idx.ByName[sym.Name], id)
+	idx.addFile(obj.File, id)
+	return sym
+}
+
+func (idx *Index) addFile(file string, id SymbolID) {
+	ids := idx.ByFile[file]
+	for _, existing := range ids {
+		if existing == id {
+			return
+		}
+	}
+	idx.ByFile[file] = append(ids, id)
+}
+
+// Add merges the resolution results of a package.
+func (idx *Index) Add(pkg string, info resolve.Info) {
+	// Symbols are added in source order, so are their names and files.
+	for _, ref := range resolve.SortedRefs(info.Defs) {
+		idx.symbol(pkg, info, info.Defs[ref])
+	}
+
+	for _, ref := range resolve.SortedRefs(info.Uses) {
+		obj := info.Uses[ref]
+		sym := idx.symbol(pkg, info, obj)
+		sym.Refs = append(sym.Refs, NewSpan(info.Files[ref.File], info.Spans[ref]))
+		idx.addFile(ref.File, sym.ID)
+	}
+}
+
+// RemoveFile drops every definition in the file and every reference from it, before the file is re-indexed.
+func (idx *Index) RemoveFile(file string) {
+	for _, id := range idx.ByFile[file] {
+		sym, ok := idx.Symbols[id]
+		if !ok {
+			continue
+		}
+		if sym.Def.File == file {
+			delete(idx.Symbols, id)
+			ids := idx.ByName[sym.Name]
+			for i, other := range ids {
+				if other == id {
+					idx.ByName[sym.Name] = append(ids[:i:i], ids[i+1:]...)
+					break
+				}
+			}
+			continue
+		}
+		refs := sym.Refs[:0]
+		for _, ref := range sym.Refs {
+			if ref.File != file {
+				refs = append(refs, ref)
+			}
+		}
+		sym.Refs = refs
+	}
+	delete(idx.ByFile, file)
+}
+
+// Schema versions the encoding of saved indexes, see ast.Schema.
+var Schema = schema.New("cee-xref", 1)
+
+func (idx *Index) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Schema.WriteHeader(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(idx); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads an index written by Save, an index of another version of the schema is a *schema.VersionError
+// unless it can be migrated.
+func Load(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	payload, err := Schema.Open(f)
+	if err != nil {
+		return nil, err
+	}
+	idx := NewIndex()
+	if err := gob.NewDecoder(payload).Decode(idx); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 LangVM Project
+// This Source Code Form is subject to the terms of the Mozilla Public License, v. 2.0
+// that can be found in the LICENSE file and https://mozilla.org/MPL/2.0/.
+
+package xref
+
+import (
+	"cee/parser"
+	"cee/resolve"
+	"cee/token"
+	"path/filepath"
+	"testing"
+)
+
+// index resolves the files of package a, given as path and source pairs, into a new index.
+func index(t *testing.T, files ...string) *Index {
+	t.Helper()
+	fset := token.NewFileSet()
+	var resolved []resolve.File
+	for i := 0; i < len(files); i += 2 {
+		f, err := parser.ParseFile(fset, files[i], []byte(files[i+1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resolved = append(resolved, resolve.File{Path: files[i], TokenFile: fset.File(f.From), Decls: f.Decls})
+	}
+	idx := NewIndex()
+	idx.Add("a", resolve.Resolve(resolved))
+	return idx
+}
+
+// lookup returns the only symbol declared with name.
+func lookup(t *testing.T, idx *Index, name string) *Symbol {
+	t.Helper()
+	ids := idx.ByName[name]
+	if len(ids) != 1 {
+		t.Fatalf("%d symbols named %s", len(ids), name)
+	}
+	return idx.Symbols[ids[0]]
+}
+
+func TestIndex(t *testing.T) {
+	idx := index(t,
+		"a.cee", "package a\n\nfun f(x i64) i64 { return g(x) + g(1) }\n",
+		"b.cee", "package a\n\nfun g(x i64) i64 { return x }\n",
+	)
+
+	g := lookup(t, idx, "g")
+	if g.Kind != resolve.ObjFunc || g.Def.File != "b.cee" || g.Def.From.Line != 2 || g.Def.From.Column != 4 {
+		t.Errorf("g defined at %+v", g.Def)
+	}
+	if len(g.Refs) != 2 || g.Refs[0].File != "a.cee" || g.Refs[0].From.Column >= g.Refs[1].From.Column {
+		t.Errorf("g referenced at %+v", g.Refs)
+	}
+	// Each parameter x is a symbol of its own.
+	if ids := idx.ByName["x"]; len(ids) != 2 {
+		t.Errorf("%d symbols named x, want 2", len(ids))
+	}
+	if len(idx.ByFile["a.cee"]) != 3 {
+		t.Errorf("a.cee indexes %v", idx.ByFile["a.cee"])
+	}
+
+	// Re-indexing a.cee starts by dropping its references to g and its own symbols.
+	idx.RemoveFile("a.cee")
+	if len(g.Refs) != 0 || len(idx.ByName["f"]) != 0 {
+		t.Errorf("after removing a.cee: refs %v, f %v", g.Refs, idx.ByName["f"])
+	}
+	if _, ok := idx.ByFile["a.cee"]; ok || len(idx.ByName["x"]) != 1 {
+		t.Errorf("after removing a.cee: files %v, x %v", idx.ByFile, idx.ByName["x"])
+	}
+	idx.RemoveFile("b.cee")
+	if len(idx.Symbols) != 0 {
+		t.Errorf("symbols left %v", idx.Symbols)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	idx := index(t, "a.cee", "package a\n\nval n = 1\n\nfun f() i64 { return n }\n")
+	path := filepath.Join(t.TempDir(), "index", "a.xref")
+	if err := idx.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := lookup(t, loaded, "n")
+	if want := lookup(t, idx, "n"); n.ID != want.ID || n.Def != want.Def || len(n.Refs) != 1 || n.Refs[0] != want.Refs[0] {
+		t.Errorf("loaded %+v, want %+v", n, want)
+	}
+}